@@ -2,6 +2,7 @@ package entities
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,6 +15,39 @@ type Agent struct {
 	Tools        []string  `json:"tools,omitempty" bson:"tools,omitempty"`
 	CreatedAt    time.Time `json:"created_at" bson:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at" bson:"updated_at"`
+	// ReasoningStats tracks token usage per reasoning effort level
+	ReasoningStats map[string]*ReasoningEffortStats `json:"reasoning_stats,omitempty" bson:"reasoning_stats,omitempty"`
+	// Fallbacks lists model IDs to try in order when the chat's model fails
+	// with a rate limit, server error or timeout
+	Fallbacks []string `json:"fallbacks,omitempty" bson:"fallbacks,omitempty"`
+	// ResponseFormat constrains the final answer to JSON, optionally matching a schema
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty" bson:"response_format,omitempty"`
+	// OutputLimit opts the agent into a hard cap on the length of its answers
+	OutputLimit *OutputLimit `json:"output_limit,omitempty" bson:"output_limit,omitempty"`
+	// PlanMode runs only read-only tools and collects the mutating tool calls
+	// into a plan the user approves before anything is changed
+	PlanMode bool `json:"plan_mode,omitempty" bson:"plan_mode,omitempty"`
+	// VerifyLoop runs the build and test commands after file changes and
+	// hands failures back to the model until they pass
+	VerifyLoop *VerifyLoop `json:"verify_loop,omitempty" bson:"verify_loop,omitempty"`
+	// Compression chooses how the chat history is shortened to fit the
+	// context window; nil summarizes older messages
+	Compression *CompressionConfig `json:"compression,omitempty" bson:"compression,omitempty"`
+	// SystemPromptPrefix and SystemPromptSuffix are placed before and after
+	// the system prompt, for shared boilerplate kept apart from the persona
+	SystemPromptPrefix string `json:"system_prompt_prefix,omitempty" bson:"system_prompt_prefix,omitempty"`
+	SystemPromptSuffix string `json:"system_prompt_suffix,omitempty" bson:"system_prompt_suffix,omitempty"`
+	// MaxConsecutiveFailures stops a response after this many tool calls in
+	// a row fail; searches that find nothing do not count. Zero uses the
+	// default of 3.
+	MaxConsecutiveFailures int `json:"max_consecutive_failures,omitempty" bson:"max_consecutive_failures,omitempty"`
+	// Env holds environment variables for the agent's tools. They take
+	// precedence over the process environment, both in #{NAME}# references
+	// of tool configurations and for the commands tools run.
+	Env map[string]string `json:"env,omitempty" bson:"env,omitempty"`
+	// CompletionWebhook is POSTed the outcome of each of the agent's
+	// responses, in place of the global webhook
+	CompletionWebhook string `json:"completion_webhook,omitempty" bson:"completion_webhook,omitempty"`
 }
 
 func NewAgent(name, systemPrompt string, tools []string) *Agent {
@@ -27,6 +61,32 @@ func NewAgent(name, systemPrompt string, tools []string) *Agent {
 	}
 }
 
+// ValidateEnv checks that the names of the agent's environment variables can
+// be set in a process environment.
+func (a *Agent) ValidateEnv() error {
+	for name := range a.Env {
+		if !validEnvName(name) {
+			return fmt.Errorf("invalid environment variable name %q", name)
+		}
+	}
+	return nil
+}
+
+func validEnvName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_', c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 // Implement the list.Item interface
 func (a *Agent) FilterValue() string {
 	return a.Name
@@ -37,15 +97,35 @@ func (a *Agent) Title() string {
 }
 
 func (a *Agent) Description() string {
-	toolCount := len(a.Tools)
-	if toolCount > 0 {
-		return fmt.Sprintf("Tools: %d", toolCount)
+	description := "No tools configured"
+	if toolCount := len(a.Tools); toolCount > 0 {
+		description = fmt.Sprintf("Tools: %d", toolCount)
 	}
-	return "No tools configured"
+	if recommendation := a.ReasoningRecommendation(); recommendation != "" {
+		description += " | " + recommendation
+	}
+	return description
 }
 
 func (a *Agent) FullSystemPrompt() string {
 	currentTime := time.Now()
 	formattedTime := currentTime.Format("2006-01-02 15:04:05")
-	return "Your name is " + a.Name + "\nCurrent date and time is " + formattedTime + "\n" + a.SystemPrompt
+	return "Your name is " + a.Name + "\nCurrent date and time is " + formattedTime + "\n" + a.promptBody()
+}
+
+// StableSystemPrompt is the system prompt without the current time, so it is
+// identical between requests and can be served from a provider's prompt cache.
+func (a *Agent) StableSystemPrompt() string {
+	return "Your name is " + a.Name + "\n" + a.promptBody()
+}
+
+// promptBody is the system prompt between the agent's prefix and suffix.
+func (a *Agent) promptBody() string {
+	var parts []string
+	for _, part := range []string{a.SystemPromptPrefix, a.SystemPrompt, a.SystemPromptSuffix} {
+		if strings.TrimSpace(part) != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "\n\n")
 }
@@ -0,0 +1,68 @@
+package entities
+
+import (
+	"encoding/json"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ApprovalScope string
+
+const (
+	ApprovalScopeSession ApprovalScope = "session" // in memory, lasts until the process exits
+	ApprovalScopeChat    ApprovalScope = "chat"    // persisted on the chat
+	ApprovalScopeGlobal  ApprovalScope = "global"  // persisted in the global config
+)
+
+// ApprovalPolicy pre-approves tool calls so the user is not asked again for
+// safe, repetitive operations. An empty ToolName matches every tool. Pattern,
+// when set, is a regular expression matched against the call's "command"
+// argument, or against the raw arguments for tools without a command.
+type ApprovalPolicy struct {
+	ID        string        `json:"id" bson:"id"`
+	Scope     ApprovalScope `json:"scope" bson:"scope"`
+	ChatID    string        `json:"chat_id,omitempty" bson:"chat_id,omitempty"`
+	ToolName  string        `json:"tool_name,omitempty" bson:"tool_name,omitempty"`
+	Pattern   string        `json:"pattern,omitempty" bson:"pattern,omitempty"`
+	CreatedAt time.Time     `json:"created_at" bson:"created_at"`
+}
+
+func NewApprovalPolicy(scope ApprovalScope, chatID, toolName, pattern string) *ApprovalPolicy {
+	return &ApprovalPolicy{
+		ID:        uuid.New().String(),
+		Scope:     scope,
+		ChatID:    chatID,
+		ToolName:  toolName,
+		Pattern:   pattern,
+		CreatedAt: time.Now(),
+	}
+}
+
+// Matches reports whether the policy approves the given tool call.
+func (p *ApprovalPolicy) Matches(chatID, toolName, arguments string) bool {
+	if p.Scope == ApprovalScopeChat && p.ChatID != chatID {
+		return false
+	}
+	if p.ToolName != "" && p.ToolName != toolName {
+		return false
+	}
+	if p.Pattern == "" {
+		return true
+	}
+
+	re, err := regexp.Compile(p.Pattern)
+	if err != nil {
+		return false
+	}
+
+	subject := arguments
+	var args struct {
+		Command string `json:"command"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err == nil && args.Command != "" {
+		subject = args.Command
+	}
+	return re.MatchString(subject)
+}
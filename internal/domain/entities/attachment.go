@@ -0,0 +1,62 @@
+package entities
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// MaxAttachmentBytes is the largest file that can be attached to a message.
+const MaxAttachmentBytes = 10 << 20
+
+// AttachmentImage is the type of image attachments, the only kind so far.
+const AttachmentImage = "image"
+
+// Attachment is a file sent with a user message, such as an image for a
+// model that reads them. It is stored with the message, so Data holds the
+// file itself, base64 encoded, unless it is referenced by URL.
+type Attachment struct {
+	Type     string `json:"type" bson:"type"`
+	MimeType string `json:"mime_type,omitempty" bson:"mime_type,omitempty"`
+	Name     string `json:"name,omitempty" bson:"name,omitempty"`
+	Data     string `json:"data,omitempty" bson:"data,omitempty"`
+	URL      string `json:"url,omitempty" bson:"url,omitempty"`
+}
+
+// NewImageAttachment attaches the image data read from the file called name.
+// The MIME type is detected from the data, falling back to the extension.
+func NewImageAttachment(name string, data []byte) (Attachment, error) {
+	if len(data) == 0 {
+		return Attachment{}, fmt.Errorf("%s is empty", name)
+	}
+	if len(data) > MaxAttachmentBytes {
+		return Attachment{}, fmt.Errorf("%s is %d bytes; attachments are limited to %d MB", name, len(data), MaxAttachmentBytes>>20)
+	}
+
+	mimeType := http.DetectContentType(data)
+	if !strings.HasPrefix(mimeType, "image/") {
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".svg":
+			mimeType = "image/svg+xml"
+		default:
+			return Attachment{}, fmt.Errorf("%s is not an image (%s)", name, mimeType)
+		}
+	}
+
+	return Attachment{
+		Type:     AttachmentImage,
+		MimeType: mimeType,
+		Name:     filepath.Base(name),
+		Data:     base64.StdEncoding.EncodeToString(data),
+	}, nil
+}
+
+// DataURL is the attachment as a data: URL, or its URL when it is remote.
+func (a Attachment) DataURL() string {
+	if a.URL != "" {
+		return a.URL
+	}
+	return "data:" + a.MimeType + ";base64," + a.Data
+}
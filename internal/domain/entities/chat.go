@@ -1,7 +1,11 @@
 package entities
 
 import (
+	"fmt"
+	"slices"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/google/uuid"
 )
@@ -11,19 +15,109 @@ type ChatUsage struct {
 	TotalCompletionTokens int     `json:"total_completion_tokens" bson:"total_completion_tokens"`
 	TotalTokens           int     `json:"total_tokens" bson:"total_tokens"`
 	TotalCost             float64 `json:"total_cost" bson:"total_cost"` // Cost in USD
+	TotalCachedTokens     int     `json:"total_cached_tokens,omitempty" bson:"total_cached_tokens,omitempty"`
+	TotalCacheSavings     float64 `json:"total_cache_savings,omitempty" bson:"total_cache_savings,omitempty"` // USD saved by prompt caching
+}
+
+// ContextUsage estimates how much of the model's context window a chat
+// fills. Its history is compressed once Tokens reaches TriggerRatio of Limit.
+type ContextUsage struct {
+	Tokens       int     `json:"tokens"`
+	Limit        int     `json:"limit"`
+	TriggerRatio float64 `json:"trigger_ratio"`
+}
+
+// ChatBudget caps what a chat may spend. A zero limit means no limit.
+type ChatBudget struct {
+	MaxCost   float64 `json:"max_cost,omitempty" bson:"max_cost,omitempty"` // USD
+	MaxTokens int     `json:"max_tokens,omitempty" bson:"max_tokens,omitempty"`
+}
+
+// Check reports why spending the additional tokens and cost on top of the
+// usage would exceed the budget, or an empty string when it fits.
+func (b *ChatBudget) Check(usage *ChatUsage, additionalTokens int, additionalCost float64) string {
+	if b == nil {
+		return ""
+	}
+	var tokens int
+	var cost float64
+	if usage != nil {
+		tokens, cost = usage.TotalTokens, usage.TotalCost
+	}
+	if b.MaxTokens > 0 && tokens+additionalTokens > b.MaxTokens {
+		return fmt.Sprintf("token budget of %d would be exceeded (%d used)", b.MaxTokens, tokens)
+	}
+	if b.MaxCost > 0 && cost+additionalCost > b.MaxCost {
+		return fmt.Sprintf("cost budget of $%.2f would be exceeded ($%.4f used)", b.MaxCost, cost)
+	}
+	return ""
+}
+
+// Remaining returns the tokens and cost left in the budget. Unlimited values
+// are returned as -1.
+func (b *ChatBudget) Remaining(usage *ChatUsage) (tokens int, cost float64) {
+	tokens, cost = -1, -1
+	if b == nil {
+		return tokens, cost
+	}
+	var usedTokens int
+	var usedCost float64
+	if usage != nil {
+		usedTokens, usedCost = usage.TotalTokens, usage.TotalCost
+	}
+	if b.MaxTokens > 0 {
+		tokens = max(b.MaxTokens-usedTokens, 0)
+	}
+	if b.MaxCost > 0 {
+		cost = max(b.MaxCost-usedCost, 0)
+	}
+	return tokens, cost
 }
 
 type Chat struct {
-	ID           string     `json:"id" bson:"_id"`
-	AgentID      string     `json:"agent_id" bson:"agent_id"`
-	ModelID      string     `json:"model_id" bson:"model_id"`
-	Name         string     `json:"name" bson:"name"`
-	Messages     []Message  `json:"messages" bson:"messages"`
-	Usage        *ChatUsage `json:"usage,omitempty" bson:"usage,omitempty"`
-	CreatedAt    time.Time  `json:"created_at" bson:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at" bson:"updated_at"`
-	Active       bool       `json:"active" bson:"active"`
-	ParentChatID string     `json:"parent_chat_id,omitempty" bson:"parent_chat_id,omitempty"`
+	ID               string           `json:"id" bson:"_id"`
+	AgentID          string           `json:"agent_id" bson:"agent_id"`
+	ModelID          string           `json:"model_id" bson:"model_id"`
+	Name             string           `json:"name" bson:"name"`
+	Messages         []Message        `json:"messages" bson:"messages"`
+	Usage            *ChatUsage       `json:"usage,omitempty" bson:"usage,omitempty"`
+	CreatedAt        time.Time        `json:"created_at" bson:"created_at"`
+	UpdatedAt        time.Time        `json:"updated_at" bson:"updated_at"`
+	Active           bool             `json:"active" bson:"active"`
+	ParentChatID     string           `json:"parent_chat_id,omitempty" bson:"parent_chat_id,omitempty"`
+	ApprovalPolicies []ApprovalPolicy `json:"approval_policies,omitempty" bson:"approval_policies,omitempty"`
+	Budget           *ChatBudget      `json:"budget,omitempty" bson:"budget,omitempty"`
+	ProjectID        string           `json:"project_id,omitempty" bson:"project_id,omitempty"`
+	// Instructions are added to the system prompt for this chat only.
+	Instructions string `json:"instructions,omitempty" bson:"instructions,omitempty"`
+	// PendingPlan holds the tool calls of the last plan mode turn until the
+	// user approves or discards them.
+	PendingPlan []PlannedToolCall `json:"pending_plan,omitempty" bson:"pending_plan,omitempty"`
+	// ReviewEdits holds the file edits of turns sent from the Web UI until
+	// the user applies or rejects their diff.
+	ReviewEdits bool `json:"review_edits,omitempty" bson:"review_edits,omitempty"`
+	// ToolsOverride replaces the agent's tools for this chat when it is not
+	// nil. An empty list runs the chat without tools, so it is stored even
+	// when empty.
+	ToolsOverride []string `json:"tools_override" bson:"tools_override"`
+	// Workspace is the directory the chat's tools work in, in place of the
+	// one they are configured with.
+	Workspace string `json:"workspace,omitempty" bson:"workspace,omitempty"`
+	// PinnedFiles are the files and directories, relative to the workspace,
+	// whose contents are sent with every turn until they are unpinned.
+	PinnedFiles []string `json:"pinned_files,omitempty" bson:"pinned_files,omitempty"`
+	// Downgrade records the model the chat was switched from because of rate
+	// or quota limits, until it is switched back.
+	Downgrade *ModelDowngrade `json:"downgrade,omitempty" bson:"downgrade,omitempty"`
+}
+
+// ModelDowngrade is a switch to a cheaper model because of rate or quota
+// limits: the model switched from, the model switched to and when to switch
+// back.
+type ModelDowngrade struct {
+	PreferredModelID string    `json:"preferred_model_id" bson:"preferred_model_id"`
+	ModelID          string    `json:"model_id" bson:"model_id"`
+	Until            time.Time `json:"until" bson:"until"`
 }
 
 func NewChat(agentID, modelID, name string) *Chat {
@@ -46,14 +140,16 @@ func (c *Chat) UpdateUsage() {
 		c.Usage = &ChatUsage{}
 	}
 
-	var totalPromptTokens, totalCompletionTokens int
-	var totalCost float64
+	var totalPromptTokens, totalCompletionTokens, totalCachedTokens int
+	var totalCost, totalCacheSavings float64
 
 	for _, msg := range c.Messages {
 		if msg.Usage != nil {
 			totalPromptTokens += msg.Usage.PromptTokens
 			totalCompletionTokens += msg.Usage.CompletionTokens
 			totalCost += msg.Usage.Cost
+			totalCachedTokens += msg.Usage.CachedTokens
+			totalCacheSavings += msg.Usage.CacheSavings
 		}
 	}
 
@@ -61,6 +157,8 @@ func (c *Chat) UpdateUsage() {
 	c.Usage.TotalCompletionTokens = totalCompletionTokens
 	c.Usage.TotalTokens = totalPromptTokens + totalCompletionTokens
 	c.Usage.TotalCost = totalCost
+	c.Usage.TotalCachedTokens = totalCachedTokens
+	c.Usage.TotalCacheSavings = totalCacheSavings
 }
 
 // GetLastRequestUsage returns the token count and cost from the most recent API request
@@ -81,6 +179,48 @@ func (c *Chat) GetLastRequestUsage() (int, float64) {
 	return 0, 0.0
 }
 
+// ToolNames returns the tools the chat's requests may use: its override when
+// set, otherwise the agent's.
+func (c *Chat) ToolNames(agent *Agent) []string {
+	if c.ToolsOverride != nil {
+		return c.ToolsOverride
+	}
+	if agent == nil {
+		return nil
+	}
+	return agent.Tools
+}
+
+// ParseToolNames reads a comma or space separated list of tool names as typed
+// by the user. "none" is an empty, non-nil list; blank text is nil.
+func ParseToolNames(text string) []string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	if strings.EqualFold(text, "none") {
+		return []string{}
+	}
+	names := []string{}
+	for _, name := range strings.FieldsFunc(text, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// FormatToolNames writes names the way ParseToolNames reads them.
+func FormatToolNames(names []string) string {
+	if names == nil {
+		return ""
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
 // Implement list.Item interface for Bubble Tea
 func (c *Chat) FilterValue() string {
 	return c.Name
@@ -0,0 +1,42 @@
+package entities
+
+import (
+	"strings"
+	"time"
+)
+
+// ChatSearchFilter narrows a chat search to one agent and a range of update
+// times. Zero values leave the corresponding bound open.
+type ChatSearchFilter struct {
+	AgentID string
+	From    time.Time
+	To      time.Time
+}
+
+// Matches reports whether the chat passes the filter and, when query is not
+// empty, contains it in its name or message content, ignoring case.
+func (f ChatSearchFilter) Matches(chat *Chat, query string) bool {
+	if f.AgentID != "" && chat.AgentID != f.AgentID {
+		return false
+	}
+	if !f.From.IsZero() && chat.UpdatedAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && chat.UpdatedAt.After(f.To) {
+		return false
+	}
+	if query == "" {
+		return true
+	}
+
+	query = strings.ToLower(query)
+	if strings.Contains(strings.ToLower(chat.Name), query) {
+		return true
+	}
+	for _, message := range chat.Messages {
+		if strings.Contains(strings.ToLower(message.Content), query) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package entities
+
+import "fmt"
+
+type CompressionStrategy string
+
+const (
+	// CompressionSummarize replaces older messages with a model-written
+	// summary and keeps the recent ones.
+	CompressionSummarize CompressionStrategy = "summarize"
+	// CompressionTruncateOldest drops the oldest messages until the rest fit
+	// the kept share of the context window, without a model call.
+	CompressionTruncateOldest CompressionStrategy = "truncate-oldest"
+	// CompressionSlidingWindow sends only the most recent share of the
+	// messages, without a model call.
+	CompressionSlidingWindow CompressionStrategy = "sliding-window"
+)
+
+// KeepLatest as a KeepRatio keeps only the most recent message, as clearing
+// a chat's context does. Agents can't be configured with it.
+const KeepLatest = -1.0
+
+// DefaultCompressionTrigger is the share of the context window the history
+// may fill before it is compressed.
+const DefaultCompressionTrigger = 0.7
+
+// CompressionConfig sets how an agent's chat history is shortened when it
+// grows too large for the context window. Truncating strategies only change
+// what is sent; the stored chat keeps every message.
+type CompressionConfig struct {
+	Strategy CompressionStrategy `json:"strategy,omitempty" bson:"strategy,omitempty"`
+	// KeepRatio is the share of the messages kept, or of the context window
+	// for truncate-oldest. Zero keeps less the further over the limit the
+	// history is.
+	KeepRatio float64 `json:"keep_ratio,omitempty" bson:"keep_ratio,omitempty"`
+	// Trigger is the share of the context window that starts compression.
+	Trigger float64 `json:"trigger,omitempty" bson:"trigger,omitempty"`
+}
+
+func (c *CompressionConfig) Validate() error {
+	switch c.Strategy {
+	case "", CompressionSummarize, CompressionTruncateOldest, CompressionSlidingWindow:
+	default:
+		return fmt.Errorf("unknown compression strategy: %s", c.Strategy)
+	}
+	if c.KeepRatio < 0 || c.KeepRatio >= 1 {
+		return fmt.Errorf("compression keep ratio must be at least 0 and below 1")
+	}
+	if c.Trigger < 0 || c.Trigger > 1 {
+		return fmt.Errorf("compression trigger must be between 0 and 1")
+	}
+	return nil
+}
+
+// EffectiveStrategy is the configured strategy, summarize by default.
+func (c *CompressionConfig) EffectiveStrategy() CompressionStrategy {
+	if c == nil || c.Strategy == "" {
+		return CompressionSummarize
+	}
+	return c.Strategy
+}
+
+// TriggerRatio is the share of the context window that starts compression.
+func (c *CompressionConfig) TriggerRatio() float64 {
+	if c == nil || c.Trigger == 0 {
+		return DefaultCompressionTrigger
+	}
+	return c.Trigger
+}
+
+// KeepRatioFor is the share to keep when the history uses tokens of a
+// tokenLimit budget, or KeepLatest.
+func (c *CompressionConfig) KeepRatioFor(tokens, tokenLimit int) float64 {
+	if c != nil && (c.KeepRatio > 0 || c.KeepRatio == KeepLatest) {
+		return c.KeepRatio
+	}
+	switch {
+	case tokens > tokenLimit*2:
+		return 0.3
+	case tokens > int(float64(tokenLimit)*1.5):
+		return 0.4
+	}
+	return 0.5
+}
+
+// CompactionPreview is what compacting a chat would do: its first To of
+// Total messages would be replaced by a summary.
+type CompactionPreview struct {
+	To    int `json:"to"`
+	Total int `json:"total"`
+	// SummarizedTokens estimates the tokens of the messages summarized; the
+	// summary takes some of them back.
+	SummarizedTokens int `json:"summarized_tokens"`
+}
@@ -0,0 +1,16 @@
+package entities
+
+// ContextStrategy controls the order in which the system prompt, history and
+// current query are assembled into the messages sent to the model.
+type ContextStrategy string
+
+const (
+	// ContextStrategyChronological sends the system prompt followed by the
+	// history in the order it happened.
+	ContextStrategyChronological ContextStrategy = "chronological"
+	// ContextStrategyOptimized keeps the stable content first so it can be
+	// cached, moves summaries of older history ahead of the recent messages
+	// and attaches volatile details such as the current time to the query,
+	// which is always last.
+	ContextStrategyOptimized ContextStrategy = "optimized"
+)
@@ -0,0 +1,37 @@
+package entities
+
+import "context"
+
+// EditReview is a change a tool call would make, held in a chat that reviews
+// edits until the user applies or rejects it. ID is the tool call's ID.
+type EditReview struct {
+	ID        string `json:"id"`
+	ChatID    string `json:"chat_id"`
+	ToolName  string `json:"tool_name"`
+	Arguments string `json:"arguments"`
+	Result    string `json:"result"` // the preview's result
+	Diff      string `json:"diff"`
+}
+
+// EditDecision is the user's answer to an EditReview. Note is passed to the
+// model with a rejection.
+type EditDecision struct {
+	Apply bool
+	Note  string
+}
+
+type editReviewKey struct{}
+
+// WithEditReview returns a context whose turns hold edits for review when
+// their chat reviews edits. Only the Web UI can show a review, so it marks
+// the turns it sends.
+func WithEditReview(ctx context.Context) context.Context {
+	return context.WithValue(ctx, editReviewKey{}, true)
+}
+
+// EditReviewEnabled reports whether the context's turns can hold edits for
+// review.
+func EditReviewEnabled(ctx context.Context) bool {
+	enabled, _ := ctx.Value(editReviewKey{}).(bool)
+	return enabled
+}
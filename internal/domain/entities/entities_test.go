@@ -1,6 +1,8 @@
 package entities
 
 import (
+	"math"
+	"strings"
 	"testing"
 	"time"
 )
@@ -83,6 +85,25 @@ func TestAgent_FullSystemPrompt(t *testing.T) {
 	}
 }
 
+func TestAgent_SystemPromptPrefixSuffix(t *testing.T) {
+	agent := &Agent{
+		Name:               "TestAgent",
+		SystemPrompt:       "You are a helpful assistant.",
+		SystemPromptPrefix: "Follow the company policy.",
+		SystemPromptSuffix: "Keep answers short.",
+	}
+
+	expected := "Your name is TestAgent\nFollow the company policy.\n\nYou are a helpful assistant.\n\nKeep answers short."
+	if result := agent.StableSystemPrompt(); result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+
+	agent.SystemPromptPrefix = ""
+	if result := agent.StableSystemPrompt(); !strings.HasPrefix(result, "Your name is TestAgent\nYou are a helpful assistant.") {
+		t.Errorf("Expected no separator for an empty prefix, got %q", result)
+	}
+}
+
 func TestNewProvider(t *testing.T) {
 	id := "test-id"
 	name := "Test Provider"
@@ -237,6 +258,27 @@ func TestChat_Description(t *testing.T) {
 	}
 }
 
+func TestParseToolNames(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"", nil},
+		{"  ", nil},
+		{"none", []string{}},
+		{"Read, Write  Bash,Read", []string{"Read", "Write", "Bash"}},
+	}
+	for _, tt := range tests {
+		got := ParseToolNames(tt.input)
+		if (got == nil) != (tt.want == nil) || strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("ParseToolNames(%q) = %#v, want %#v", tt.input, got, tt.want)
+		}
+		if back := ParseToolNames(FormatToolNames(got)); (back == nil) != (got == nil) || strings.Join(back, ",") != strings.Join(got, ",") {
+			t.Errorf("Expected %#v to round trip, got %#v", got, back)
+		}
+	}
+}
+
 func TestNewTask(t *testing.T) {
 	name := "Test Task"
 	content := "This is a test task"
@@ -353,6 +395,33 @@ func TestMessage_AddUsage(t *testing.T) {
 	}
 }
 
+func TestMessage_ValidateOverrides(t *testing.T) {
+	temperature := func(v float64) *float64 { return &v }
+	maxTokens := func(v int) *int { return &v }
+
+	tests := []struct {
+		name    string
+		message Message
+		wantErr bool
+	}{
+		{"none", Message{}, false},
+		{"zero temperature", Message{Temperature: temperature(0)}, false},
+		{"high temperature", Message{Temperature: temperature(2)}, false},
+		{"negative temperature", Message{Temperature: temperature(-0.1)}, true},
+		{"temperature too high", Message{Temperature: temperature(2.5)}, true},
+		{"max tokens", Message{MaxTokens: maxTokens(500)}, false},
+		{"zero max tokens", Message{MaxTokens: maxTokens(0)}, true},
+		{"max tokens too high", Message{MaxTokens: maxTokens(MaxMessageMaxTokens + 1)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.message.ValidateOverrides(); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateOverrides() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestNewToolData(t *testing.T) {
 	toolType := "FileRead"
 	name := "File Read Tool"
@@ -422,3 +491,384 @@ func contains(s, substr string) bool {
 				return false
 			}()))
 }
+
+func TestApprovalPolicy_Matches(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   *ApprovalPolicy
+		chatID   string
+		toolName string
+		args     string
+		expected bool
+	}{
+		{"session approves all", NewApprovalPolicy(ApprovalScopeSession, "", "", ""), "chat-1", "Bash", `{"command":"rm -rf /"}`, true},
+		{"tool match", NewApprovalPolicy(ApprovalScopeGlobal, "", "Bash", ""), "chat-1", "Bash", `{}`, true},
+		{"tool mismatch", NewApprovalPolicy(ApprovalScopeGlobal, "", "Bash", ""), "chat-1", "Write", `{}`, false},
+		{"chat match", NewApprovalPolicy(ApprovalScopeChat, "chat-1", "Bash", ""), "chat-1", "Bash", `{}`, true},
+		{"chat mismatch", NewApprovalPolicy(ApprovalScopeChat, "chat-1", "Bash", ""), "chat-2", "Bash", `{}`, false},
+		{"command pattern", NewApprovalPolicy(ApprovalScopeGlobal, "", "Bash", `^go (test|build)\b`), "chat-1", "Bash", `{"command":"go test ./..."}`, true},
+		{"command pattern mismatch", NewApprovalPolicy(ApprovalScopeGlobal, "", "Bash", `^go (test|build)\b`), "chat-1", "Bash", `{"command":"rm -rf ."}`, false},
+		{"raw arguments pattern", NewApprovalPolicy(ApprovalScopeGlobal, "", "Write", `"filePath":"docs/`), "chat-1", "Write", `{"filePath":"docs/a.md"}`, true},
+		{"invalid pattern", NewApprovalPolicy(ApprovalScopeGlobal, "", "Bash", `(`), "chat-1", "Bash", `{"command":"ls"}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Matches(tt.chatID, tt.toolName, tt.args); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNewProgressEvent(t *testing.T) {
+	event := NewProgressEvent("chat-1", []ProgressItem{
+		{Content: "Read code", Status: "completed"},
+		{Content: "Write fix", Status: "in_progress"},
+		{Content: "Run tests", Status: "pending"},
+	})
+
+	if event.Total != 3 {
+		t.Errorf("Expected total 3, got %d", event.Total)
+	}
+	if event.Completed != 1 {
+		t.Errorf("Expected 1 completed, got %d", event.Completed)
+	}
+	if event.Current != "Write fix" {
+		t.Errorf("Expected current step 'Write fix', got '%s'", event.Current)
+	}
+
+	event = NewProgressEvent("chat-1", []ProgressItem{{Content: "Run tests", Status: "pending"}})
+	if event.Current != "Run tests" {
+		t.Errorf("Expected current step 'Run tests', got '%s'", event.Current)
+	}
+}
+
+func TestAgent_ReasoningRecommendation(t *testing.T) {
+	agent := NewAgent("Test Agent", "prompt", nil)
+
+	for i := 0; i < 5; i++ {
+		agent.RecordReasoningUsage("low", &Usage{CompletionTokens: 100, ReasoningTokens: 50}, 0.001)
+		agent.RecordReasoningUsage("medium", &Usage{CompletionTokens: 150, ReasoningTokens: 100}, 0.002)
+	}
+	agent.RecordReasoningUsage("none", &Usage{CompletionTokens: 100}, 0.001)
+
+	if _, ok := agent.ReasoningStats["none"]; ok {
+		t.Error("Expected responses without reasoning effort to be ignored")
+	}
+	if stats := agent.ReasoningStats["low"]; stats.Responses != 5 || stats.ReasoningTokens != 250 {
+		t.Errorf("Expected 5 low responses with 250 reasoning tokens, got %+v", stats)
+	}
+	if rec := agent.ReasoningRecommendation(); rec != "" {
+		t.Errorf("Expected no recommendation below the ratio, got '%s'", rec)
+	}
+
+	for i := 0; i < 5; i++ {
+		agent.RecordReasoningUsage("high", &Usage{CompletionTokens: 400, ReasoningTokens: 350}, 0.01)
+	}
+	expected := "high effort used 4.0x the tokens of low for similar tasks — consider lowering"
+	if rec := agent.ReasoningRecommendation(); rec != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, rec)
+	}
+}
+
+func TestModelPricing_CacheCost(t *testing.T) {
+	pricing := &ModelPricing{
+		Name:                    "claude",
+		InputPricePerMille:      3.00,
+		OutputPricePerMille:     15.00,
+		CacheReadPricePerMille:  0.30,
+		CacheWritePricePerMille: 3.75,
+	}
+
+	savings, writeCost := pricing.CacheCost(&Usage{PromptTokens: 2000000, CachedTokens: 1000000, CacheWriteTokens: 1000000})
+	if math.Abs(savings-2.70) > 1e-9 {
+		t.Errorf("Expected savings 2.70, got %f", savings)
+	}
+	if math.Abs(writeCost-0.75) > 1e-9 {
+		t.Errorf("Expected write cost 0.75, got %f", writeCost)
+	}
+
+	savings, writeCost = (&ModelPricing{InputPricePerMille: 3.00}).CacheCost(&Usage{CachedTokens: 1000000})
+	if savings != 0 || writeCost != 0 {
+		t.Errorf("Expected no cache adjustment without cache pricing, got %f and %f", savings, writeCost)
+	}
+}
+
+func TestChatBudget(t *testing.T) {
+	usage := &ChatUsage{TotalTokens: 900, TotalCost: 0.90}
+
+	var noBudget *ChatBudget
+	if reason := noBudget.Check(usage, 1000000, 100); reason != "" {
+		t.Errorf("Expected no limit without a budget, got '%s'", reason)
+	}
+
+	budget := &ChatBudget{MaxCost: 1.00, MaxTokens: 1000}
+	if reason := budget.Check(usage, 100, 0.10); reason != "" {
+		t.Errorf("Expected call within budget, got '%s'", reason)
+	}
+	if reason := budget.Check(usage, 101, 0); reason == "" {
+		t.Error("Expected token budget to be exceeded")
+	}
+	if reason := budget.Check(usage, 0, 0.11); reason == "" {
+		t.Error("Expected cost budget to be exceeded")
+	}
+
+	tokens, cost := budget.Remaining(usage)
+	if tokens != 100 || math.Abs(cost-0.10) > 1e-9 {
+		t.Errorf("Expected 100 tokens and $0.10 remaining, got %d and %f", tokens, cost)
+	}
+
+	tokens, cost = (&ChatBudget{MaxCost: 0.50}).Remaining(usage)
+	if tokens != -1 || cost != 0 {
+		t.Errorf("Expected unlimited tokens and no cost remaining, got %d and %f", tokens, cost)
+	}
+}
+
+func TestProvider_RequestTimeout(t *testing.T) {
+	provider := &Provider{}
+	if got := provider.RequestTimeout(); got != DefaultRequestTimeout {
+		t.Errorf("Expected the default timeout, got %v", got)
+	}
+
+	seconds := 90
+	provider.RequestTimeoutSeconds = &seconds
+	if got := provider.RequestTimeout(); got != 90*time.Second {
+		t.Errorf("Expected 90s, got %v", got)
+	}
+
+	disabled := 0
+	provider.RequestTimeoutSeconds = &disabled
+	if got := provider.RequestTimeout(); got != 0 {
+		t.Errorf("Expected 0 to disable the timeout, got %v", got)
+	}
+}
+
+func TestProject_Contains(t *testing.T) {
+	project := NewProject("aiagent", "/home/user/src/aiagent/")
+
+	tests := []struct {
+		dir      string
+		expected bool
+	}{
+		{"/home/user/src/aiagent", true},
+		{"/home/user/src/aiagent/internal/tui", true},
+		{"/home/user/src/aiagent-fork", false},
+		{"/home/user/src", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := project.Contains(tt.dir); got != tt.expected {
+			t.Errorf("Contains(%q) = %v, expected %v", tt.dir, got, tt.expected)
+		}
+	}
+
+	if (&Project{Name: "no workspace"}).Contains("/home/user") {
+		t.Error("Expected a project without a workspace to contain nothing")
+	}
+}
+
+func TestChatSearchFilter_Matches(t *testing.T) {
+	now := time.Now()
+	chat := &Chat{
+		AgentID:   "agent-1",
+		Name:      "Refactor the Parser",
+		Messages:  []Message{{Role: "user", Content: "Why does the LEXER drop comments?"}},
+		UpdatedAt: now,
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		filter   ChatSearchFilter
+		expected bool
+	}{
+		{"empty query", "", ChatSearchFilter{}, true},
+		{"name ignores case", "parser", ChatSearchFilter{}, true},
+		{"message content", "lexer drop", ChatSearchFilter{}, true},
+		{"no match", "tokenizer", ChatSearchFilter{}, false},
+		{"matching agent", "parser", ChatSearchFilter{AgentID: "agent-1"}, true},
+		{"other agent", "parser", ChatSearchFilter{AgentID: "agent-2"}, false},
+		{"within range", "", ChatSearchFilter{From: now.Add(-time.Hour), To: now.Add(time.Hour)}, true},
+		{"updated before range", "", ChatSearchFilter{From: now.Add(time.Hour)}, false},
+		{"updated after range", "", ChatSearchFilter{To: now.Add(-time.Hour)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(chat, tt.query); got != tt.expected {
+				t.Errorf("Matches(%q) = %v, expected %v", tt.query, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestResponseFormat_Validate(t *testing.T) {
+	negative := -1
+	tests := []struct {
+		name   string
+		format ResponseFormat
+		valid  bool
+	}{
+		{"json mode", ResponseFormat{Type: ResponseFormatJSON}, true},
+		{"schema", ResponseFormat{Type: ResponseFormatJSONSchema, Schema: map[string]any{"type": "object"}}, true},
+		{"schema missing", ResponseFormat{Type: ResponseFormatJSONSchema}, false},
+		{"unknown type", ResponseFormat{Type: "xml"}, false},
+		{"negative repairs", ResponseFormat{Type: ResponseFormatJSON, MaxRepairAttempts: &negative}, false},
+	}
+
+	for _, tt := range tests {
+		if err := tt.format.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: expected valid=%v, got %v", tt.name, tt.valid, err)
+		}
+	}
+
+	if got := (&ResponseFormat{}).RepairAttempts(); got != DefaultRepairAttempts {
+		t.Errorf("Expected the default repair attempts, got %d", got)
+	}
+	if (*ResponseFormat)(nil).Structured() {
+		t.Error("Expected a nil format to be plain text")
+	}
+}
+
+func TestOutputLimit_Truncate(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   *OutputLimit
+		content string
+		want    string
+	}{
+		{"within limits", &OutputLimit{MaxLines: 3, MaxWords: 10}, "one two\nthree", "one two\nthree"},
+		{"lines", &OutputLimit{MaxLines: 2}, "a\nb\nc\nd", "a\nb\n\n" + TrimmedNote},
+		{"words", &OutputLimit{MaxWords: 3}, "one  two\nthree four five", "one  two\nthree\n\n" + TrimmedNote},
+		{"open code block", &OutputLimit{MaxLines: 2}, "```go\nx := 1\ny := 2\n```", "```go\nx := 1\n```\n\n" + TrimmedNote},
+		{"disabled", nil, "anything at all", "anything at all"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.limit.Truncate(tt.content); got != tt.want {
+				t.Errorf("Truncate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutputLimit_Validate(t *testing.T) {
+	if err := (&OutputLimit{MaxLines: 5, Mode: OutputLimitReprompt}).Validate(); err != nil {
+		t.Errorf("expected valid limit, got %v", err)
+	}
+	if err := (&OutputLimit{MaxLines: 5, Mode: "shout"}).Validate(); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+	if err := (&OutputLimit{MaxWords: -1}).Validate(); err == nil {
+		t.Error("expected error for negative limit")
+	}
+}
+
+func TestProvider_EffectiveType(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider Provider
+		want     ProviderType
+	}{
+		{"known host", Provider{Type: ProviderGeneric, BaseURL: "https://api.anthropic.com/v1"}, ProviderAnthropic},
+		{"unknown host", Provider{Type: ProviderGeneric, BaseURL: "http://localhost:8080"}, ProviderGeneric},
+		{"ollama port", Provider{Type: ProviderGeneric, BaseURL: "http://localhost:11434/v1"}, ProviderOllama},
+		{"probed", Provider{Type: ProviderGeneric, BaseURL: "https://gateway.example.com", DetectedType: ProviderGoogle}, ProviderGoogle},
+		{"disabled", Provider{Type: ProviderGeneric, BaseURL: "https://api.openai.com", DisableTypeDetection: true}, ProviderGeneric},
+		{"native type kept", Provider{Type: ProviderOpenAI, BaseURL: "https://api.groq.com"}, ProviderOpenAI},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.provider.EffectiveType(); got != tt.want {
+				t.Errorf("EffectiveType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProvider_UsesStrictToolSchemas(t *testing.T) {
+	on, off := true, false
+	tests := []struct {
+		name     string
+		provider Provider
+		want     bool
+	}{
+		{"openai by default", Provider{Type: ProviderOpenAI}, true},
+		{"openai host by default", Provider{Type: ProviderGeneric, BaseURL: "https://api.openai.com/v1"}, true},
+		{"others off by default", Provider{Type: ProviderGeneric, BaseURL: "http://localhost:8080"}, false},
+		{"openai turned off", Provider{Type: ProviderOpenAI, StrictToolSchemas: &off}, false},
+		{"others turned on", Provider{Type: ProviderGroq, StrictToolSchemas: &on}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.provider.UsesStrictToolSchemas(); got != tt.want {
+				t.Errorf("UsesStrictToolSchemas() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModel_SupportsReasoningEffort(t *testing.T) {
+	tests := []struct {
+		name     string
+		model    Model
+		provider ProviderType
+		want     bool
+	}{
+		{"reasoning model", Model{Family: "o4", Reasoning: true}, ProviderOpenAI, true},
+		{"chat model", Model{Family: "gpt-4o"}, ProviderOpenAI, false},
+		{"added by hand", Model{}, ProviderGeneric, true},
+		{"anthropic", Model{Family: "claude", Reasoning: true}, ProviderAnthropic, false},
+		{"deepseek", Model{Family: "deepseek", Reasoning: true}, ProviderDeepseek, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.model.SupportsReasoningEffort(tt.provider); got != tt.want {
+				t.Errorf("SupportsReasoningEffort() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewImageAttachment(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	attachment, err := NewImageAttachment("shots/chart.png", png)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if attachment.Type != AttachmentImage || attachment.MimeType != "image/png" || attachment.Name != "chart.png" {
+		t.Errorf("Unexpected attachment: %+v", attachment)
+	}
+	if !strings.HasPrefix(attachment.DataURL(), "data:image/png;base64,iVBORw0KGgo") {
+		t.Errorf("Unexpected data URL: %s", attachment.DataURL())
+	}
+
+	if _, err := NewImageAttachment("notes.png", []byte("just text")); err == nil {
+		t.Error("Expected text to be refused as an image")
+	}
+	if _, err := NewImageAttachment("big.png", make([]byte, MaxAttachmentBytes+1)); err == nil {
+		t.Error("Expected an oversized file to be refused")
+	}
+	if url := (Attachment{URL: "https://example.com/a.png"}).DataURL(); url != "https://example.com/a.png" {
+		t.Errorf("Expected a remote attachment to keep its URL, got %s", url)
+	}
+}
+
+func TestAgent_ValidateEnv(t *testing.T) {
+	agent := &Agent{Env: map[string]string{"API_KEY": "secret", "_x1": ""}}
+	if err := agent.ValidateEnv(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	for _, name := range []string{"", "1KEY", "API-KEY", "A=B"} {
+		agent := &Agent{Env: map[string]string{name: "value"}}
+		if err := agent.ValidateEnv(); err == nil {
+			t.Errorf("Expected %q to be refused", name)
+		}
+	}
+}
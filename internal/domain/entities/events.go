@@ -115,3 +115,54 @@ func NewChatUpdateEvent(chatID, updateType string, data map[string]interface{})
 		Timestamp:  time.Now(),
 	}
 }
+
+// ProgressItem is a single step of an agent's declared plan.
+type ProgressItem struct {
+	Content string `json:"content"`
+	Status  string `json:"status"` // "pending", "in_progress", "completed", "cancelled"
+}
+
+// ProgressEvent reports how far an agent has worked through its declared plan.
+type ProgressEvent struct {
+	ID        string         `json:"id"`
+	ChatID    string         `json:"chat_id"`
+	Completed int            `json:"completed"`
+	Total     int            `json:"total"`
+	Current   string         `json:"current,omitempty"`
+	Items     []ProgressItem `json:"items"`
+	Timestamp time.Time      `json:"timestamp"`
+}
+
+// NewProgressEvent creates a ProgressEvent from the plan items. Cancelled items
+// count as done; the current step is the first in-progress item, falling back
+// to the first pending one.
+func NewProgressEvent(chatID string, items []ProgressItem) *ProgressEvent {
+	event := &ProgressEvent{
+		ID:        uuid.New().String(),
+		ChatID:    chatID,
+		Total:     len(items),
+		Items:     items,
+		Timestamp: time.Now(),
+	}
+
+	pending := ""
+	for _, item := range items {
+		switch item.Status {
+		case "completed", "cancelled":
+			event.Completed++
+		case "in_progress":
+			if event.Current == "" {
+				event.Current = item.Content
+			}
+		default:
+			if pending == "" {
+				pending = item.Content
+			}
+		}
+	}
+	if event.Current == "" {
+		event.Current = pending
+	}
+
+	return event
+}
@@ -1,6 +1,7 @@
 package entities
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,10 +18,14 @@ type ToolCall struct {
 }
 
 type Usage struct {
-	PromptTokens     int     `json:"prompt_tokens" bson:"prompt_tokens"`         // Input tokens
-	CompletionTokens int     `json:"completion_tokens" bson:"completion_tokens"` // Output tokens
-	TotalTokens      int     `json:"total_tokens" bson:"total_tokens"`           // Total tokens processed
-	Cost             float64 `json:"cost" bson:"cost"`                           // Cost in USD
+	PromptTokens     int     `json:"prompt_tokens" bson:"prompt_tokens"`                               // Input tokens
+	CompletionTokens int     `json:"completion_tokens" bson:"completion_tokens"`                       // Output tokens
+	TotalTokens      int     `json:"total_tokens" bson:"total_tokens"`                                 // Total tokens processed
+	ReasoningTokens  int     `json:"reasoning_tokens,omitempty" bson:"reasoning_tokens,omitempty"`     // Output tokens spent on reasoning
+	CachedTokens     int     `json:"cached_tokens,omitempty" bson:"cached_tokens,omitempty"`           // Input tokens read from the prompt cache
+	CacheWriteTokens int     `json:"cache_write_tokens,omitempty" bson:"cache_write_tokens,omitempty"` // Input tokens written to the prompt cache
+	CacheSavings     float64 `json:"cache_savings,omitempty" bson:"cache_savings,omitempty"`           // USD saved by prompt cache hits
+	Cost             float64 `json:"cost" bson:"cost"`                                                 // Cost in USD
 }
 
 type Message struct {
@@ -32,6 +37,39 @@ type Message struct {
 	ToolCallEvents []ToolCallEvent `json:"tool_call_events,omitempty" bson:"tool_call_events,omitempty"`
 	Usage          *Usage          `json:"usage,omitempty" bson:"usage,omitempty"`
 	Timestamp      time.Time       `json:"timestamp" bson:"timestamp"`
+	// Model names the fallback model that answered when the chat's model failed
+	Model string `json:"model,omitempty" bson:"model,omitempty"`
+	// Truncated marks a partial answer saved when the user cancelled the turn
+	Truncated bool `json:"truncated,omitempty" bson:"truncated,omitempty"`
+	// Reasoning holds the model's thinking, kept out of Content and never
+	// sent back to the provider
+	Reasoning string `json:"reasoning,omitempty" bson:"reasoning,omitempty"`
+	// Attachments are images sent with a user message
+	Attachments []Attachment `json:"attachments,omitempty" bson:"attachments,omitempty"`
+	// Temperature and MaxTokens override the model's settings for the
+	// response to this user message only
+	Temperature *float64 `json:"temperature,omitempty" bson:"temperature,omitempty"`
+	MaxTokens   *int     `json:"max_tokens,omitempty" bson:"max_tokens,omitempty"`
+}
+
+// Limits of the per-message overrides. Providers accept temperatures up to 2;
+// the max tokens cap only catches typos, the provider rejects what the model
+// cannot produce.
+const (
+	MaxMessageTemperature = 2.0
+	MaxMessageMaxTokens   = 1000000
+)
+
+// ValidateOverrides checks the temperature and max tokens the message sets
+// for its response.
+func (m *Message) ValidateOverrides() error {
+	if m.Temperature != nil && (*m.Temperature < 0 || *m.Temperature > MaxMessageTemperature) {
+		return fmt.Errorf("temperature must be between 0 and %g", MaxMessageTemperature)
+	}
+	if m.MaxTokens != nil && (*m.MaxTokens < 1 || *m.MaxTokens > MaxMessageMaxTokens) {
+		return fmt.Errorf("max tokens must be between 1 and %d", MaxMessageMaxTokens)
+	}
+	return nil
 }
 
 func NewMessage(role, content string) *Message {
@@ -81,3 +81,34 @@ func (m *Model) Description() string {
 	}
 	return fmt.Sprintf("%s | %s", providerInfo, fmt.Sprintf("%v", params))
 }
+
+// SupportsReasoningEffort reports whether requests to the model may carry its
+// reasoning effort. Anthropic needs the signed thinking blocks of earlier
+// turns sent back with tool results, and DeepSeek, Mistral and Ollama have no
+// effort setting. A model whose capabilities came from models.dev must be a
+// reasoning model; one added by hand is trusted to be.
+func (m *Model) SupportsReasoningEffort(providerType ProviderType) bool {
+	switch providerType {
+	case ProviderAnthropic, ProviderDeepseek, ProviderMistral, ProviderOllama:
+		return false
+	}
+	return m.Reasoning || !m.HasCapabilityMetadata()
+}
+
+// HasCapabilityMetadata reports whether the capability flags were filled in
+// from models.dev. Models added by hand leave them unset.
+func (m *Model) HasCapabilityMetadata() bool {
+	return m.Family != ""
+}
+
+// SupportsTools reports whether the model can be sent tool definitions.
+// Models without capability metadata are assumed to support them.
+func (m *Model) SupportsTools() bool {
+	return !m.HasCapabilityMetadata() || m.ToolCall
+}
+
+// SupportsImages reports whether the model accepts image input. Models
+// without capability metadata are assumed to accept it.
+func (m *Model) SupportsImages() bool {
+	return !m.HasCapabilityMetadata() || m.Attachment
+}
@@ -0,0 +1,114 @@
+package entities
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type OutputLimitMode string
+
+const (
+	// OutputLimitTruncate cuts a long answer down to the limit.
+	OutputLimitTruncate OutputLimitMode = "truncate"
+	// OutputLimitReprompt asks the model once for a shorter answer and only
+	// truncates if that answer is still too long.
+	OutputLimitReprompt OutputLimitMode = "reprompt"
+)
+
+// TrimmedNote marks an answer that was cut down to the agent's output limit.
+const TrimmedNote = "[trimmed]"
+
+// OutputLimit enforces the brevity an agent's prompt asks for on its final
+// answers. A zero MaxLines or MaxWords leaves that dimension unlimited.
+type OutputLimit struct {
+	MaxLines int             `json:"max_lines,omitempty" bson:"max_lines,omitempty"`
+	MaxWords int             `json:"max_words,omitempty" bson:"max_words,omitempty"`
+	Mode     OutputLimitMode `json:"mode,omitempty" bson:"mode,omitempty"`
+}
+
+func (l *OutputLimit) Validate() error {
+	switch l.Mode {
+	case "", OutputLimitTruncate, OutputLimitReprompt:
+	default:
+		return fmt.Errorf("unknown output limit mode: %s", l.Mode)
+	}
+	if l.MaxLines < 0 || l.MaxWords < 0 {
+		return fmt.Errorf("output limits must not be negative")
+	}
+	return nil
+}
+
+// Enabled reports whether the limit restricts anything.
+func (l *OutputLimit) Enabled() bool {
+	return l != nil && (l.MaxLines > 0 || l.MaxWords > 0)
+}
+
+// Exceeds reports whether content is longer than the limit.
+func (l *OutputLimit) Exceeds(content string) bool {
+	if !l.Enabled() {
+		return false
+	}
+	content = strings.TrimSpace(content)
+	return (l.MaxLines > 0 && len(strings.Split(content, "\n")) > l.MaxLines) ||
+		(l.MaxWords > 0 && len(strings.Fields(content)) > l.MaxWords)
+}
+
+// Truncate cuts content to the limit, keeping its formatting, closing a code
+// block left open by the cut and appending TrimmedNote.
+func (l *OutputLimit) Truncate(content string) string {
+	if !l.Exceeds(content) {
+		return content
+	}
+	content = strings.TrimSpace(content)
+
+	if l.MaxLines > 0 {
+		if lines := strings.Split(content, "\n"); len(lines) > l.MaxLines {
+			content = strings.Join(lines[:l.MaxLines], "\n")
+		}
+	}
+	if l.MaxWords > 0 {
+		content = truncateWords(content, l.MaxWords)
+	}
+
+	content = strings.TrimRightFunc(content, unicode.IsSpace)
+	if strings.Count(content, "```")%2 == 1 {
+		content += "\n```"
+	}
+	return content + "\n\n" + TrimmedNote
+}
+
+// Instructions is added to the system prompt so the model knows the limit.
+func (l *OutputLimit) Instructions() string {
+	if !l.Enabled() {
+		return ""
+	}
+	var limits []string
+	if l.MaxLines > 0 {
+		limits = append(limits, fmt.Sprintf("%d lines", l.MaxLines))
+	}
+	if l.MaxWords > 0 {
+		limits = append(limits, fmt.Sprintf("%d words", l.MaxWords))
+	}
+	return "Your final answer must not exceed " + strings.Join(limits, " or ") + "; longer answers are trimmed."
+}
+
+// truncateWords keeps the first n words of content and the text between them.
+func truncateWords(content string, n int) string {
+	words := 0
+	inWord := false
+	for i, r := range content {
+		if unicode.IsSpace(r) {
+			inWord = false
+			continue
+		}
+		if !inWord {
+			if words == n {
+				return content[:i]
+			}
+			words++
+			inWord = true
+		}
+	}
+	return content
+}
@@ -0,0 +1,23 @@
+package entities
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PlannedToolCall is a mutating tool call that plan mode held back for the
+// user to review.
+type PlannedToolCall struct {
+	ToolName  string `json:"tool_name" bson:"tool_name"`
+	Arguments string `json:"arguments" bson:"arguments"`
+}
+
+// FormatPlan lists the held back tool calls as numbered steps.
+func FormatPlan(steps []PlannedToolCall) string {
+	var b strings.Builder
+	b.WriteString("Plan (not executed yet):\n")
+	for i, step := range steps {
+		fmt.Fprintf(&b, "%d. %s %s\n", i+1, step.ToolName, step.Arguments)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,41 @@
+package entities
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Project groups chats that belong to the same codebase. A project is tied to
+// a workspace directory so new chats started there are filed under it.
+type Project struct {
+	ID        string    `json:"id" bson:"_id"`
+	Name      string    `json:"name" bson:"name"`
+	Workspace string    `json:"workspace" bson:"workspace"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+func NewProject(name, workspace string) *Project {
+	return &Project{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Workspace: filepath.Clean(workspace),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+// Contains reports whether dir is the project workspace or inside it.
+func (p *Project) Contains(dir string) bool {
+	if p.Workspace == "" || dir == "" {
+		return false
+	}
+	rel, err := filepath.Rel(p.Workspace, filepath.Clean(dir))
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -1,6 +1,7 @@
 package entities
 
 import (
+	"net/url"
 	"strings"
 	"time"
 )
@@ -16,16 +17,61 @@ const (
 	ProviderTogether  ProviderType = "together"
 	ProviderGroq      ProviderType = "groq"
 	ProviderMistral   ProviderType = "mistral"
+	ProviderOllama    ProviderType = "ollama"
 	ProviderGeneric   ProviderType = "generic"
 )
 
+// ToolCallIDFormat is the shape of tool call IDs a provider accepts. IDs
+// from other providers in a chat's history are mapped to it before sending.
+type ToolCallIDFormat string
+
+const (
+	// ToolCallIDPassthrough sends IDs unchanged and only fills in missing ones.
+	ToolCallIDPassthrough ToolCallIDFormat = "passthrough"
+	// ToolCallIDAnthropic allows letters, digits, underscores and dashes.
+	ToolCallIDAnthropic ToolCallIDFormat = "anthropic"
+	// ToolCallIDAlphanumeric9 requires exactly nine letters or digits, as
+	// Mistral does.
+	ToolCallIDAlphanumeric9 ToolCallIDFormat = "alphanumeric9"
+)
+
+// ModelSyncStatus flags how a model changed in the provider's last model
+// list sync.
+type ModelSyncStatus string
+
+const (
+	// ModelSyncNew marks a model the provider lists that wasn't known yet.
+	ModelSyncNew ModelSyncStatus = "new"
+	// ModelSyncRemoved marks a known model the provider no longer lists.
+	ModelSyncRemoved ModelSyncStatus = "removed"
+)
+
 // ModelPricing represents the cost structure for a specific model
 type ModelPricing struct {
-	Name                string  `json:"name" bson:"name"`                                     // Model name (e.g., "gpt-4o", "claude-3-opus")
-	InputPricePerMille  float64 `json:"input_price_per_mille" bson:"input_price_per_mille"`   // Cost per million input tokens
-	OutputPricePerMille float64 `json:"output_price_per_mille" bson:"output_price_per_mille"` // Cost per million output tokens
-	ContextWindow       int     `json:"context_window" bson:"context_window"`                 // Maximum context length in tokens
-	MaxOutputTokens     int     `json:"max_output_tokens" bson:"max_output_tokens"`           // Maximum output tokens allowed
+	Name                    string          `json:"name" bson:"name"`                                                                   // Model name (e.g., "gpt-4o", "claude-3-opus")
+	InputPricePerMille      float64         `json:"input_price_per_mille" bson:"input_price_per_mille"`                                 // Cost per million input tokens
+	OutputPricePerMille     float64         `json:"output_price_per_mille" bson:"output_price_per_mille"`                               // Cost per million output tokens
+	ContextWindow           int             `json:"context_window" bson:"context_window"`                                               // Maximum context length in tokens
+	MaxOutputTokens         int             `json:"max_output_tokens" bson:"max_output_tokens"`                                         // Maximum output tokens allowed
+	CacheReadPricePerMille  float64         `json:"cache_read_price_per_mille,omitempty" bson:"cache_read_price_per_mille,omitempty"`   // Cost per million cached input tokens
+	CacheWritePricePerMille float64         `json:"cache_write_price_per_mille,omitempty" bson:"cache_write_price_per_mille,omitempty"` // Cost per million input tokens written to the cache
+	SyncStatus              ModelSyncStatus `json:"sync_status,omitempty" bson:"sync_status,omitempty"`                                 // Set by the last model list sync
+}
+
+// CacheCost returns how much the prompt cache saved on cached input tokens and
+// the extra cost of writing tokens to the cache, both relative to the regular
+// input price. Without cache pricing the cache is assumed to have no effect.
+func (p *ModelPricing) CacheCost(usage *Usage) (savings, writeCost float64) {
+	if p == nil || usage == nil {
+		return 0, 0
+	}
+	if p.CacheReadPricePerMille > 0 {
+		savings = float64(usage.CachedTokens) * (p.InputPricePerMille - p.CacheReadPricePerMille) / 1000000.0
+	}
+	if p.CacheWritePricePerMille > 0 {
+		writeCost = float64(usage.CacheWriteTokens) * (p.CacheWritePricePerMille - p.InputPricePerMille) / 1000000.0
+	}
+	return savings, writeCost
 }
 
 // Provider represents an AI model provider
@@ -36,8 +82,95 @@ type Provider struct {
 	BaseURL    string         `json:"base_url" bson:"base_url"`
 	APIKeyName string         `json:"api_key_name" bson:"api_key_name"` // Name to display for the API key field
 	Models     []ModelPricing `json:"models" bson:"models"`
-	CreatedAt  time.Time      `json:"created_at" bson:"created_at"`
-	UpdatedAt  time.Time      `json:"updated_at" bson:"updated_at"`
+	// RequestsPerMinute limits requests across every chat using the provider; 0 is unlimited
+	RequestsPerMinute int `json:"requests_per_minute,omitempty" bson:"requests_per_minute,omitempty"`
+	// RequestTimeoutSeconds bounds a single model request. Nil uses the
+	// default and 0 disables the deadline for slow local models.
+	RequestTimeoutSeconds *int `json:"request_timeout_seconds,omitempty" bson:"request_timeout_seconds,omitempty"`
+	// DetectedType is the API a generic provider was found to speak by
+	// probing its models endpoint.
+	DetectedType ProviderType `json:"detected_type,omitempty" bson:"detected_type,omitempty"`
+	// DisableTypeDetection keeps a generic provider on the OpenAI-compatible
+	// integration even when its base URL belongs to a known provider.
+	DisableTypeDetection bool `json:"disable_type_detection,omitempty" bson:"disable_type_detection,omitempty"`
+	// ToolCallIDFormat overrides the tool call ID format of the provider's
+	// integration, e.g. for a generic provider proxying Mistral.
+	ToolCallIDFormat ToolCallIDFormat `json:"tool_call_id_format,omitempty" bson:"tool_call_id_format,omitempty"`
+	// StrictToolSchemas sends function definitions in OpenAI's strict mode
+	// when true, and never when false. Unset, see UsesStrictToolSchemas.
+	StrictToolSchemas *bool     `json:"strict_tool_schemas,omitempty" bson:"strict_tool_schemas,omitempty"`
+	CreatedAt         time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// DefaultRequestTimeout bounds a model request when the provider does not
+// configure a timeout.
+const DefaultRequestTimeout = 10 * time.Minute
+
+// RequestTimeout returns how long a single model request may take. Zero means
+// no deadline.
+func (p *Provider) RequestTimeout() time.Duration {
+	if p.RequestTimeoutSeconds == nil {
+		return DefaultRequestTimeout
+	}
+	if *p.RequestTimeoutSeconds <= 0 {
+		return 0
+	}
+	return time.Duration(*p.RequestTimeoutSeconds) * time.Second
+}
+
+// UsesStrictToolSchemas reports whether function definitions are sent in
+// strict mode: as StrictToolSchemas says when it is set, otherwise only to
+// OpenAI, since many OpenAI-compatible servers reject it.
+func (p *Provider) UsesStrictToolSchemas() bool {
+	if p.StrictToolSchemas != nil {
+		return *p.StrictToolSchemas
+	}
+	return p.EffectiveType() == ProviderOpenAI
+}
+
+// knownProviderHosts maps the API hosts of the native integrations to their type.
+var knownProviderHosts = map[string]ProviderType{
+	"api.openai.com":                    ProviderOpenAI,
+	"api.anthropic.com":                 ProviderAnthropic,
+	"api.x.ai":                          ProviderXAI,
+	"generativelanguage.googleapis.com": ProviderGoogle,
+	"api.deepseek.com":                  ProviderDeepseek,
+	"api.together.xyz":                  ProviderTogether,
+	"api.groq.com":                      ProviderGroq,
+	"api.mistral.ai":                    ProviderMistral,
+}
+
+// ollamaPort is the port Ollama listens on by default.
+const ollamaPort = "11434"
+
+// DetectProviderType infers the provider type from a well-known base URL.
+func DetectProviderType(baseURL string) (ProviderType, bool) {
+	u, err := url.Parse(strings.TrimSpace(baseURL))
+	if err != nil || u.Hostname() == "" {
+		return "", false
+	}
+	if u.Port() == ollamaPort {
+		return ProviderOllama, true
+	}
+	providerType, ok := knownProviderHosts[strings.ToLower(u.Hostname())]
+	return providerType, ok
+}
+
+// EffectiveType is the type whose integration serves the provider. A generic
+// provider pointing at a known provider's API, or found to speak its API,
+// uses that provider's native integration unless detection is disabled.
+func (p *Provider) EffectiveType() ProviderType {
+	if (p.Type != ProviderGeneric && p.Type != "") || p.DisableTypeDetection {
+		return p.Type
+	}
+	if detected, ok := DetectProviderType(p.BaseURL); ok {
+		return detected
+	}
+	if p.DetectedType != "" {
+		return p.DetectedType
+	}
+	return p.Type
 }
 
 // NewProvider creates a new provider with the specified attributes
@@ -0,0 +1,79 @@
+package entities
+
+import "fmt"
+
+// reasoningEffortLevels orders the reasoning effort settings from cheapest to
+// most expensive.
+var reasoningEffortLevels = []string{"minimal", "low", "medium", "high"}
+
+const (
+	// reasoningStatsMinResponses is how many responses an effort level needs
+	// before it is compared with another level.
+	reasoningStatsMinResponses = 5
+	// reasoningStatsCostRatio is how many times more tokens a higher effort must
+	// use before lowering it is recommended.
+	reasoningStatsCostRatio = 2.0
+)
+
+// ReasoningEffortStats accumulates the token usage of responses generated at a
+// single reasoning effort level.
+type ReasoningEffortStats struct {
+	Responses        int     `json:"responses" bson:"responses"`
+	CompletionTokens int     `json:"completion_tokens" bson:"completion_tokens"`
+	ReasoningTokens  int     `json:"reasoning_tokens" bson:"reasoning_tokens"`
+	Cost             float64 `json:"cost" bson:"cost"`
+}
+
+// AverageTokens returns the average completion tokens, which include the
+// reasoning tokens, per response.
+func (s *ReasoningEffortStats) AverageTokens() float64 {
+	if s == nil || s.Responses == 0 {
+		return 0
+	}
+	return float64(s.CompletionTokens) / float64(s.Responses)
+}
+
+// RecordReasoningUsage adds a response's usage to the stats for the effort.
+func (a *Agent) RecordReasoningUsage(effort string, usage *Usage, cost float64) {
+	if effort == "" || effort == "none" || usage == nil {
+		return
+	}
+	if a.ReasoningStats == nil {
+		a.ReasoningStats = make(map[string]*ReasoningEffortStats)
+	}
+	stats, ok := a.ReasoningStats[effort]
+	if !ok {
+		stats = &ReasoningEffortStats{}
+		a.ReasoningStats[effort] = stats
+	}
+	stats.Responses++
+	stats.CompletionTokens += usage.CompletionTokens
+	stats.ReasoningTokens += usage.ReasoningTokens
+	stats.Cost += cost
+}
+
+// ReasoningRecommendation compares the recorded effort levels and suggests
+// lowering the effort when a higher level uses far more tokens than a lower
+// one. It returns an empty string when there is not enough data.
+func (a *Agent) ReasoningRecommendation() string {
+	var best string
+	var bestRatio float64
+	for i, lower := range reasoningEffortLevels {
+		lowerStats := a.ReasoningStats[lower]
+		if lowerStats == nil || lowerStats.Responses < reasoningStatsMinResponses || lowerStats.AverageTokens() == 0 {
+			continue
+		}
+		for _, higher := range reasoningEffortLevels[i+1:] {
+			higherStats := a.ReasoningStats[higher]
+			if higherStats == nil || higherStats.Responses < reasoningStatsMinResponses {
+				continue
+			}
+			ratio := higherStats.AverageTokens() / lowerStats.AverageTokens()
+			if ratio >= reasoningStatsCostRatio && ratio > bestRatio {
+				bestRatio = ratio
+				best = fmt.Sprintf("%s effort used %.1fx the tokens of %s for similar tasks — consider lowering", higher, ratio, lower)
+			}
+		}
+	}
+	return best
+}
@@ -0,0 +1,87 @@
+package entities
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type ResponseFormatType string
+
+const (
+	ResponseFormatText       ResponseFormatType = "text"
+	ResponseFormatJSON       ResponseFormatType = "json_object"
+	ResponseFormatJSONSchema ResponseFormatType = "json_schema"
+)
+
+// DefaultRepairAttempts is how often an invalid structured response is sent
+// back to the model with the validation errors before giving up.
+const DefaultRepairAttempts = 2
+
+// ResponseFormat constrains an agent's final answer to JSON. With a schema,
+// providers supporting structured outputs receive it as their native
+// response_format and every response is also validated locally.
+type ResponseFormat struct {
+	Type   ResponseFormatType `json:"type" bson:"type"`
+	Name   string             `json:"name,omitempty" bson:"name,omitempty"`
+	Schema map[string]any     `json:"schema,omitempty" bson:"schema,omitempty"`
+	// Strict asks providers to enforce the schema exactly, which requires
+	// additionalProperties to be false and every property to be required.
+	Strict bool `json:"strict,omitempty" bson:"strict,omitempty"`
+	// MaxRepairAttempts overrides DefaultRepairAttempts; zero disables repair.
+	MaxRepairAttempts *int `json:"max_repair_attempts,omitempty" bson:"max_repair_attempts,omitempty"`
+}
+
+func (f *ResponseFormat) Validate() error {
+	switch f.Type {
+	case "", ResponseFormatText, ResponseFormatJSON:
+	case ResponseFormatJSONSchema:
+		if len(f.Schema) == 0 {
+			return fmt.Errorf("a JSON schema is required for the json_schema response format")
+		}
+	default:
+		return fmt.Errorf("unknown response format type: %s", f.Type)
+	}
+	if f.MaxRepairAttempts != nil && *f.MaxRepairAttempts < 0 {
+		return fmt.Errorf("max repair attempts must not be negative")
+	}
+	return nil
+}
+
+// Structured reports whether the format asks for JSON output at all.
+func (f *ResponseFormat) Structured() bool {
+	return f != nil && (f.Type == ResponseFormatJSON || f.Type == ResponseFormatJSONSchema)
+}
+
+// SchemaName is the name providers require for a JSON schema.
+func (f *ResponseFormat) SchemaName() string {
+	if f.Name != "" {
+		return f.Name
+	}
+	return "response"
+}
+
+func (f *ResponseFormat) RepairAttempts() int {
+	if f.MaxRepairAttempts == nil {
+		return DefaultRepairAttempts
+	}
+	return max(*f.MaxRepairAttempts, 0)
+}
+
+// Instructions is added to the system prompt so that providers without
+// native structured output know what to produce.
+func (f *ResponseFormat) Instructions() string {
+	if !f.Structured() {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("Your final answer must be a single valid JSON value with no surrounding text or markdown.")
+	if f.Type == ResponseFormatJSONSchema && len(f.Schema) > 0 {
+		if schema, err := json.Marshal(f.Schema); err == nil {
+			b.WriteString(" It must conform to this JSON schema:\n")
+			b.Write(schema)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,68 @@
+package entities
+
+import "strings"
+
+type SystemLayerKind string
+
+// The instruction layers in precedence order: later layers are more specific
+// and take precedence when they disagree with earlier ones.
+const (
+	// SystemLayerGlobal is the policy configured for every chat.
+	SystemLayerGlobal SystemLayerKind = "global"
+	// SystemLayerAgent is the agent's persona and system prompt.
+	SystemLayerAgent SystemLayerKind = "agent"
+	// SystemLayerProject holds the project's guidance from AGENTS.md,
+	// AIAGENT.md or the configured instruction files.
+	SystemLayerProject SystemLayerKind = "project"
+	// SystemLayerFile holds a file pinned to the chat with "@path", read
+	// again for every turn.
+	SystemLayerFile SystemLayerKind = "file"
+	// SystemLayerSession holds the instructions given for a single chat.
+	SystemLayerSession SystemLayerKind = "session"
+)
+
+// SystemLayer is one source of the instructions sent as the system prompt.
+type SystemLayer struct {
+	Kind SystemLayerKind
+	// Source names where the instructions came from, such as a file path.
+	Source  string
+	Content string
+}
+
+// Heading introduces the layer when layers are combined, so the model and
+// anyone reading the prompt can tell where each instruction came from. The
+// agent layer has none since it is the prompt's main body.
+func (l SystemLayer) Heading() string {
+	switch l.Kind {
+	case SystemLayerGlobal:
+		return "# Global policy"
+	case SystemLayerProject:
+		return "# Project instructions (" + l.Source + ")"
+	case SystemLayerFile:
+		return "# Pinned file (" + l.Source + ")"
+	case SystemLayerSession:
+		return "# Session instructions"
+	}
+	return ""
+}
+
+// Text is the layer's content under its heading.
+func (l SystemLayer) Text() string {
+	content := strings.TrimSpace(l.Content)
+	if heading := l.Heading(); heading != "" {
+		return heading + "\n" + content
+	}
+	return content
+}
+
+// ComposeSystemPrompt combines the layers, in the order given, into one
+// system prompt. Empty layers are left out.
+func ComposeSystemPrompt(layers []SystemLayer) string {
+	var parts []string
+	for _, layer := range layers {
+		if strings.TrimSpace(layer.Content) != "" {
+			parts = append(parts, layer.Text())
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
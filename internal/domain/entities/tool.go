@@ -42,18 +42,74 @@ type Parameter struct {
 	Required    bool
 }
 
+// Tool is a function the model can call. Built-in tools and those added with
+// tools.RegisterToolType implement it the same way.
 type Tool interface {
+	// Name is the name the model calls the tool by
 	Name() string
+	// Description is sent to the model with the tool's schema
 	Description() string
+	// FullDescription is the description with the parameters spelled out
 	FullDescription() string
+	// Configuration is the tool's settings, stored with its ToolData
 	Configuration() map[string]string
 	UpdateConfiguration(config map[string]string)
+	// Schema is the JSON schema of the arguments Execute accepts
 	Schema() map[string]any
+	// Execute runs the call with its arguments as JSON and returns the
+	// result for the model. It should stop when ctx is done.
 	Execute(ctx context.Context, arguments string) (string, error)
+	// FormatResult renders a result for the "tui" or "webui" ui
 	FormatResult(ui string, result, diff, arguments string) string
+	// DisplayName is the title and subtitle of a call in the ui
 	DisplayName(ui string, arguments string) (string, string)
 }
 
+// ConfigurableTool is implemented by tools that can hand out a copy of
+// themselves with another configuration. Tools are shared between chats, so
+// each run configures its own copy with its workspace and env rather than
+// changing the shared instance under another chat's feet. Tools without it
+// are updated in place.
+type ConfigurableTool interface {
+	WithConfiguration(config map[string]string) Tool
+}
+
+// MutatingTool is implemented by tools whose calls can change files, run
+// commands or otherwise have side effects. Plan mode holds such calls back
+// instead of running them.
+type MutatingTool interface {
+	Mutates(arguments string) bool
+}
+
+// PreviewTool is implemented by tools that can show what a call would change
+// without changing anything, such as the diff of a file edit. Chats that
+// review edits hold the call until the user applies or rejects the preview.
+type PreviewTool interface {
+	Preview(ctx context.Context, arguments string) (string, error)
+}
+
+// CacheableTool is implemented by read-only tools whose result can be reused
+// when the model makes the same call again in a response, such as reading a
+// file or running a search. A tool that reads files should implement
+// PathTool too, so changes to them are noticed.
+type CacheableTool interface {
+	Cacheable(arguments string) bool
+}
+
+// PathTool is implemented by tools that can tell which files or directories
+// a call reads or changes, as absolute paths. A call that changes a path
+// drops the cached results of the calls that read it, or anything under it.
+type PathTool interface {
+	Paths(arguments string) []string
+}
+
+// PlanTool is implemented by tools that keep a plan for each chat, so a ui can
+// show the plan when the chat is opened. Plan returns nil when the chat has
+// none.
+type PlanTool interface {
+	Plan(chatID string) (*ProgressEvent, error)
+}
+
 // ToolItem wraps a Tool to implement bubbles/list.Item
 type ToolItem struct {
 	Tool ToolData
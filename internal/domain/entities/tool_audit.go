@@ -0,0 +1,17 @@
+package entities
+
+import "time"
+
+// ToolAuditRecord is a tool call as written to the tool audit log.
+type ToolAuditRecord struct {
+	Time       time.Time `json:"time"`
+	ChatID     string    `json:"chat_id"`
+	Iteration  int       `json:"iteration"` // model round trip within the turn, from 1
+	ToolCallID string    `json:"tool_call_id"`
+	Tool       string    `json:"tool"`
+	Arguments  string    `json:"arguments"`
+	Result     string    `json:"result"`
+	Truncated  bool      `json:"truncated,omitempty"` // the result was cut to fit the log
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+}
@@ -0,0 +1,20 @@
+package entities
+
+import "context"
+
+type toolEnvKey struct{}
+
+// WithToolEnv returns a context carrying environment variables for the tools
+// run with it, such as an agent's Env.
+func WithToolEnv(ctx context.Context, env map[string]string) context.Context {
+	if len(env) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, toolEnvKey{}, env)
+}
+
+// ToolEnv returns the environment variables the context carries for tools.
+func ToolEnv(ctx context.Context) map[string]string {
+	env, _ := ctx.Value(toolEnvKey{}).(map[string]string)
+	return env
+}
@@ -0,0 +1,59 @@
+package entities
+
+import (
+	"sort"
+	"time"
+)
+
+// UsageTotals adds up the usage of the assistant messages in a group.
+type UsageTotals struct {
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Cost             float64 `json:"cost"` // USD
+	Messages         int     `json:"messages"`
+}
+
+// Add counts the usage of one message.
+func (t *UsageTotals) Add(usage *Usage, cost float64) {
+	t.PromptTokens += usage.PromptTokens
+	t.CompletionTokens += usage.CompletionTokens
+	t.TotalTokens += usage.PromptTokens + usage.CompletionTokens
+	t.Cost += cost
+	t.Messages++
+}
+
+// UsageGroup is the usage of one agent, provider, model or day.
+type UsageGroup struct {
+	Name string `json:"name"`
+	UsageTotals
+}
+
+// UsageReport breaks the usage of all chats since a time down by agent,
+// provider, model and day.
+type UsageReport struct {
+	Since      time.Time    `json:"since,omitempty"`
+	Chats      int          `json:"chats"`
+	Total      UsageTotals  `json:"total"`
+	ByAgent    []UsageGroup `json:"by_agent"`
+	ByProvider []UsageGroup `json:"by_provider"`
+	ByModel    []UsageGroup `json:"by_model"`
+	// ByDay lists the days with usage, oldest first, named YYYY-MM-DD
+	ByDay []UsageGroup `json:"by_day"`
+}
+
+// UsageGroups turns the totals keyed by name into groups, most expensive
+// first, then by name.
+func UsageGroups(totals map[string]*UsageTotals) []UsageGroup {
+	groups := make([]UsageGroup, 0, len(totals))
+	for name, total := range totals {
+		groups = append(groups, UsageGroup{Name: name, UsageTotals: *total})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Cost != groups[j].Cost {
+			return groups[i].Cost > groups[j].Cost
+		}
+		return groups[i].Name < groups[j].Name
+	})
+	return groups
+}
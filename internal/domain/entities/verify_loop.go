@@ -0,0 +1,43 @@
+package entities
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultVerifyAttempts is how often a failing build is handed back to the
+// model when the verify loop sets no limit.
+const DefaultVerifyAttempts = 3
+
+// VerifyLoop makes the chat service run an agent's build and test commands
+// after every turn that changed files and feed failures back to the model
+// until they pass, instead of relying on the model to run them.
+type VerifyLoop struct {
+	Commands    []string `json:"commands" bson:"commands"`
+	MaxAttempts int      `json:"max_attempts,omitempty" bson:"max_attempts,omitempty"`
+}
+
+func (v *VerifyLoop) Validate() error {
+	for _, command := range v.Commands {
+		if strings.TrimSpace(command) == "" {
+			return fmt.Errorf("verify commands must not be empty")
+		}
+	}
+	if v.MaxAttempts < 0 {
+		return fmt.Errorf("verify attempts must not be negative")
+	}
+	return nil
+}
+
+// Enabled reports whether there is anything to run.
+func (v *VerifyLoop) Enabled() bool {
+	return v != nil && len(v.Commands) > 0
+}
+
+// Attempts is the number of times failures are handed back to the model.
+func (v *VerifyLoop) Attempts() int {
+	if v == nil || v.MaxAttempts == 0 {
+		return DefaultVerifyAttempts
+	}
+	return v.MaxAttempts
+}
@@ -0,0 +1,19 @@
+package errors
+
+import "fmt"
+
+type BudgetExceededError struct {
+	message string
+}
+
+func (v *BudgetExceededError) Error() string {
+	return v.message
+}
+
+func BudgetExceededErrorf(format string, args ...any) *BudgetExceededError {
+	return &BudgetExceededError{
+		message: fmt.Sprintf(format, args...),
+	}
+}
+
+var _ error = &BudgetExceededError{}
@@ -0,0 +1,19 @@
+package errors
+
+import "fmt"
+
+type RateLimitError struct {
+	message string
+}
+
+func (v *RateLimitError) Error() string {
+	return v.message
+}
+
+func RateLimitErrorf(format string, args ...any) *RateLimitError {
+	return &RateLimitError{
+		message: fmt.Sprintf(format, args...),
+	}
+}
+
+var _ error = &RateLimitError{}
@@ -12,6 +12,7 @@ const (
 	ProcessFailedEventType   uint32 = 3
 	ChatUpdateEventType      uint32 = 4
 	SubAgentEventType        uint32 = 5
+	ProgressEventType        uint32 = 6
 )
 
 // ToolCallEventData wraps the ToolCallEvent for publishing
@@ -113,3 +114,23 @@ func PublishSubAgentEvent(e *entities.SubAgentEvent) {
 func SubscribeToSubAgentEvents(handler func(data SubAgentEventData)) func() {
 	return event.On(handler)
 }
+
+// ProgressEventData wraps the ProgressEvent for publishing
+type ProgressEventData struct {
+	Event *entities.ProgressEvent
+}
+
+// Type implements the Event interface
+func (p ProgressEventData) Type() uint32 {
+	return ProgressEventType
+}
+
+// PublishProgressEvent publishes a plan progress event
+func PublishProgressEvent(e *entities.ProgressEvent) {
+	event.Emit(ProgressEventData{Event: e})
+}
+
+// SubscribeToProgressEvents subscribes to plan progress events
+func SubscribeToProgressEvents(handler func(data ProgressEventData)) func() {
+	return event.On(handler)
+}
@@ -14,6 +14,10 @@ type AIModelIntegration interface {
 	// GenerateResponse generates response(s) from the AI model with incremental saving
 	GenerateResponse(ctx context.Context, messages []*entities.Message, toolList []entities.Tool, options map[string]any, callback MessageCallback) ([]*entities.Message, error)
 
+	// CountTokens returns the prompt tokens messages would use with the
+	// model's tokenizer
+	CountTokens(messages []*entities.Message) (int, error)
+
 	// GetUsage returns token usage information for billing/reporting
 	GetUsage() (*entities.Usage, error)
 
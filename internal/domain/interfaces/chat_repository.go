@@ -12,4 +12,7 @@ type ChatRepository interface {
 	DeleteChat(ctx context.Context, id string) error
 	GetChat(ctx context.Context, id string) (*entities.Chat, error)
 	ListChats(ctx context.Context) ([]*entities.Chat, error)
+	SearchChats(ctx context.Context, query string, filter entities.ChatSearchFilter) ([]*entities.Chat, error)
+	// SetActiveChat atomically makes chatID the only active chat.
+	SetActiveChat(ctx context.Context, chatID string) error
 }
@@ -0,0 +1,14 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+)
+
+// EditReviewer holds the change a tool call would make for the user to apply
+// or reject. Review blocks until the user decides; a done ctx rejects the
+// change. Integrations look for it in the "edit_reviewer" option.
+type EditReviewer interface {
+	Review(ctx context.Context, review *entities.EditReview) entities.EditDecision
+}
@@ -0,0 +1,15 @@
+package interfaces
+
+import (
+	"context"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+)
+
+type ProjectRepository interface {
+	CreateProject(ctx context.Context, project *entities.Project) error
+	UpdateProject(ctx context.Context, project *entities.Project) error
+	DeleteProject(ctx context.Context, id string) error
+	GetProject(ctx context.Context, id string) (*entities.Project, error)
+	ListProjects(ctx context.Context) ([]*entities.Project, error)
+}
@@ -0,0 +1,10 @@
+package interfaces
+
+// SteeringQueue holds the notes a user sends to redirect a response while it
+// is being generated. Integrations look for it in the "steering_queue" option
+// and add the notes as user messages before their next request. Pending
+// reports whether notes are waiting and Take removes and returns them.
+type SteeringQueue interface {
+	Pending() bool
+	Take() []string
+}
@@ -0,0 +1,9 @@
+package interfaces
+
+import "context"
+
+// ToolApprover decides whether a tool call may run without asking the user.
+// Integrations look for it in the "tool_approver" option.
+type ToolApprover interface {
+	IsApproved(ctx context.Context, chatID, toolName, arguments string) bool
+}
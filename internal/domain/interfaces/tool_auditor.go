@@ -0,0 +1,9 @@
+package interfaces
+
+import "github.com/drujensen/aiagent/internal/domain/entities"
+
+// ToolAuditor keeps a record of the tool calls agents make. Integrations look
+// for it in the "tool_auditor" option.
+type ToolAuditor interface {
+	RecordToolCall(record entities.ToolAuditRecord)
+}
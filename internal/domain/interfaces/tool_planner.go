@@ -0,0 +1,8 @@
+package interfaces
+
+// ToolPlanner collects mutating tool calls instead of running them when an
+// agent is in plan mode. Plan returns the call's step number in the plan.
+// Integrations look for it in the "tool_planner" option.
+type ToolPlanner interface {
+	Plan(toolName, arguments string) int
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/domain/interfaces"
+	"github.com/drujensen/aiagent/internal/impl/integrations"
+	"github.com/drujensen/aiagent/internal/impl/redact"
+)
+
+// minRedactedLength keeps short values, which are unlikely to be secrets and
+// would garble the record if replaced everywhere, out of the redaction.
+const minRedactedLength = 4
+
+// envRedactingAuditor blanks the values of an agent's env in the tool calls it
+// records, so secrets given to the agent's tools stay out of the audit log.
+type envRedactingAuditor struct {
+	interfaces.ToolAuditor
+	values []string
+}
+
+// redactEnvAuditor wraps the auditor to redact the env's values, or returns it
+// as is when there is nothing to redact.
+func redactEnvAuditor(auditor interfaces.ToolAuditor, env map[string]string) interfaces.ToolAuditor {
+	values := envSecrets(env)
+	if len(values) == 0 {
+		return auditor
+	}
+	return &envRedactingAuditor{ToolAuditor: auditor, values: values}
+}
+
+func (a *envRedactingAuditor) RecordToolCall(record entities.ToolAuditRecord) {
+	record.Arguments = redact.Values(record.Arguments, a.values...)
+	record.Result = redact.Values(record.Result, a.values...)
+	record.Error = redact.Values(record.Error, a.values...)
+	a.ToolAuditor.RecordToolCall(record)
+}
+
+// envSecrets returns the env's values long enough to redact.
+func envSecrets(env map[string]string) []string {
+	var values []string
+	for _, value := range env {
+		if len(value) >= minRedactedLength {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+type runSecretsKey struct{}
+
+// withRunSecrets returns a context carrying the secret values of a run, such
+// as its agent's env, on top of those ctx already carries, for the model
+// integrations created during it to redact.
+func withRunSecrets(ctx context.Context, secrets []string) context.Context {
+	if len(secrets) == 0 {
+		return ctx
+	}
+	carried, _ := ctx.Value(runSecretsKey{}).([]string)
+	return context.WithValue(ctx, runSecretsKey{}, append(carried[:len(carried):len(carried)], secrets...))
+}
+
+// newModelIntegration creates the model's integration, set to redact the
+// run's secrets that ctx carries from the bodies it logs.
+func (s *chatService) newModelIntegration(ctx context.Context, model *entities.Model, provider *entities.Provider, apiKey string) (interfaces.AIModelIntegration, error) {
+	aiModel, err := integrations.NewAIModelFactory(s.toolRepo, s.logger).CreateModelIntegration(model, provider, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	secrets, _ := ctx.Value(runSecretsKey{}).([]string)
+	integrations.RedactSecrets(aiModel, secrets)
+	return aiModel, nil
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/impl/config"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type recordingAuditor struct {
+	records []entities.ToolAuditRecord
+}
+
+func (a *recordingAuditor) RecordToolCall(record entities.ToolAuditRecord) {
+	a.records = append(a.records, record)
+}
+
+func TestConfigureTool_AgentEnv(t *testing.T) {
+	t.Setenv("FETCH_TOKEN", "process-token")
+	cfg, err := config.InitConfig()
+	if err != nil {
+		t.Fatalf("InitConfig failed: %v", err)
+	}
+	cs := &chatService{
+		toolRepo: &storedToolRepo{data: []*entities.ToolData{
+			{Name: "Fetch", Configuration: map[string]string{"token": "#{FETCH_TOKEN}#"}},
+		}},
+		config: cfg,
+		logger: zap.NewNop(),
+	}
+	tool := &configuredTool{}
+	configs := cs.toolConfigurations(context.Background())
+
+	_, secrets, err := cs.configureTool(tool, "Fetch", configs, "", map[string]string{"FETCH_TOKEN": "agent-token"})
+	if err != nil {
+		t.Fatalf("configureTool failed: %v", err)
+	}
+	if tool.config["token"] != "agent-token" {
+		t.Errorf("Expected the agent's env over the process env, got %s", tool.config["token"])
+	}
+	if len(secrets) != 1 || secrets[0] != "agent-token" {
+		t.Errorf("Expected the resolved reference as a secret, got %v", secrets)
+	}
+
+	if _, _, err := cs.configureTool(tool, "Fetch", configs, "", nil); err != nil {
+		t.Fatalf("configureTool failed: %v", err)
+	}
+	if tool.config["token"] != "process-token" {
+		t.Errorf("Expected the process env without an agent env, got %s", tool.config["token"])
+	}
+}
+
+func TestRedactEnvAuditor(t *testing.T) {
+	recorder := &recordingAuditor{}
+	if auditor := redactEnvAuditor(recorder, map[string]string{"DEBUG": "1"}); auditor != recorder {
+		t.Error("Expected short values to leave the auditor as is")
+	}
+
+	auditor := redactEnvAuditor(recorder, map[string]string{"API_KEY": "sk-12345", "DEBUG": "1"})
+	auditor.RecordToolCall(entities.ToolAuditRecord{
+		Arguments: `{"command": "echo $API_KEY"}`,
+		Result:    "sk-12345\n1",
+		Error:     "bad key sk-12345",
+	})
+	record := recorder.records[0]
+	if record.Result != "[REDACTED]\n1" || record.Error != "bad key [REDACTED]" {
+		t.Errorf("Expected the value to be redacted, got %+v", record)
+	}
+}
+
+func TestNewModelIntegration_RedactsRunSecrets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": {"message": "unexpected value sk-12345"}}`))
+	}))
+	defer server.Close()
+
+	core, logs := observer.New(zap.DebugLevel)
+	cs := &chatService{logger: zap.New(core)}
+	ctx := withRunSecrets(context.Background(), envSecrets(map[string]string{"API_KEY": "sk-12345", "DEBUG": "1"}))
+	ctx = withRunSecrets(ctx, []string{"tool-token"})
+	aiModel, err := cs.newModelIntegration(ctx, &entities.Model{ModelName: "test-model"},
+		&entities.Provider{Type: entities.ProviderGeneric, BaseURL: server.URL}, "test-key")
+	if err != nil {
+		t.Fatalf("newModelIntegration failed: %v", err)
+	}
+
+	messages := []*entities.Message{entities.NewMessage("user", "The keys are sk-12345 and tool-token")}
+	if _, err := aiModel.GenerateResponse(ctx, messages, nil, map[string]any{}, nil); err == nil {
+		t.Fatal("Expected an error for a 400")
+	}
+	for _, entry := range logs.All() {
+		for key, value := range entry.ContextMap() {
+			if s, ok := value.(string); ok && (strings.Contains(s, "sk-12345") || strings.Contains(s, "tool-token")) {
+				t.Errorf("Expected the run's secrets to be redacted from the %q log field, got %s", key, s)
+			}
+		}
+	}
+}
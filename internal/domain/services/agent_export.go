@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/domain/errs"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// agentExportVersion is the version of the agent export format.
+const agentExportVersion = 1
+
+// agentExport is an agent definition that can be imported into another
+// installation. Models are referenced by provider and model name because
+// IDs differ between installations.
+type agentExport struct {
+	Version                int                         `json:"version"`
+	Name                   string                      `json:"name"`
+	SystemPrompt           string                      `json:"system_prompt"`
+	Tools                  []string                    `json:"tools,omitempty"`
+	Fallbacks              []modelReference            `json:"fallbacks,omitempty"`
+	ResponseFormat         *entities.ResponseFormat    `json:"response_format,omitempty"`
+	OutputLimit            *entities.OutputLimit       `json:"output_limit,omitempty"`
+	PlanMode               bool                        `json:"plan_mode,omitempty"`
+	VerifyLoop             *entities.VerifyLoop        `json:"verify_loop,omitempty"`
+	Compression            *entities.CompressionConfig `json:"compression,omitempty"`
+	SystemPromptPrefix     string                      `json:"system_prompt_prefix,omitempty"`
+	SystemPromptSuffix     string                      `json:"system_prompt_suffix,omitempty"`
+	MaxConsecutiveFailures int                         `json:"max_consecutive_failures,omitempty"`
+}
+
+// modelReference identifies a model by its provider and name, with the
+// settings used to create it when it doesn't exist.
+type modelReference struct {
+	ProviderType    entities.ProviderType `json:"provider_type"`
+	ProviderName    string                `json:"provider_name"`
+	BaseURL         string                `json:"base_url,omitempty"`
+	APIKeyName      string                `json:"api_key_name,omitempty"`
+	ModelName       string                `json:"model_name"`
+	Name            string                `json:"name,omitempty"`
+	Temperature     *float64              `json:"temperature,omitempty"`
+	MaxTokens       *int                  `json:"max_tokens,omitempty"`
+	ContextWindow   *int                  `json:"context_window,omitempty"`
+	ReasoningEffort string                `json:"reasoning_effort,omitempty"`
+}
+
+// ExportAgent returns the agent as portable JSON.
+func (s *agentService) ExportAgent(ctx context.Context, id string) ([]byte, error) {
+	if id == "" {
+		return nil, errors.ValidationErrorf("agent ID is required")
+	}
+
+	// Read from the repository; GetAgent appends the skills to the prompt
+	agent, err := s.agentRepo.GetAgent(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	export := agentExport{
+		Version:                agentExportVersion,
+		Name:                   agent.Name,
+		SystemPrompt:           agent.SystemPrompt,
+		Tools:                  agent.Tools,
+		ResponseFormat:         agent.ResponseFormat,
+		OutputLimit:            agent.OutputLimit,
+		PlanMode:               agent.PlanMode,
+		VerifyLoop:             agent.VerifyLoop,
+		Compression:            agent.Compression,
+		SystemPromptPrefix:     agent.SystemPromptPrefix,
+		SystemPromptSuffix:     agent.SystemPromptSuffix,
+		MaxConsecutiveFailures: agent.MaxConsecutiveFailures,
+	}
+	for _, modelID := range agent.Fallbacks {
+		ref, err := s.modelReference(ctx, modelID)
+		if err != nil {
+			s.logger.Warn("Skipping fallback model that can't be exported", zap.String("model_id", modelID), zap.Error(err))
+			continue
+		}
+		export.Fallbacks = append(export.Fallbacks, *ref)
+	}
+
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return nil, errors.InternalErrorf("failed to marshal agent: %v", err)
+	}
+	return data, nil
+}
+
+func (s *agentService) modelReference(ctx context.Context, modelID string) (*modelReference, error) {
+	model, err := s.modelRepo.GetModel(ctx, modelID)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := s.providerRepo.GetProvider(ctx, model.ProviderID)
+	if err != nil {
+		return nil, err
+	}
+	return &modelReference{
+		ProviderType:    provider.Type,
+		ProviderName:    provider.Name,
+		BaseURL:         provider.BaseURL,
+		APIKeyName:      provider.APIKeyName,
+		ModelName:       model.ModelName,
+		Name:            model.Name,
+		Temperature:     model.Temperature,
+		MaxTokens:       model.MaxTokens,
+		ContextWindow:   model.ContextWindow,
+		ReasoningEffort: model.ReasoningEffort,
+	}, nil
+}
+
+// ImportAgent creates an agent from JSON written by ExportAgent. The agent
+// gets a new ID, fallback models are resolved by provider and model name and
+// created with their provider when missing, and tools that aren't installed
+// are dropped.
+func (s *agentService) ImportAgent(ctx context.Context, data []byte) (*entities.Agent, error) {
+	var export agentExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, errors.ValidationErrorf("invalid agent export: %v", err)
+	}
+	if export.Version > agentExportVersion {
+		return nil, errors.ValidationErrorf("unsupported agent export version %d", export.Version)
+	}
+
+	agent := entities.NewAgent(export.Name, export.SystemPrompt, nil)
+	agent.ResponseFormat = export.ResponseFormat
+	agent.OutputLimit = export.OutputLimit
+	agent.PlanMode = export.PlanMode
+	agent.VerifyLoop = export.VerifyLoop
+	agent.Compression = export.Compression
+	agent.SystemPromptPrefix = export.SystemPromptPrefix
+	agent.SystemPromptSuffix = export.SystemPromptSuffix
+	agent.MaxConsecutiveFailures = export.MaxConsecutiveFailures
+
+	for _, name := range export.Tools {
+		if tool, err := s.toolRepo.GetToolByName(name); err != nil || tool == nil {
+			s.logger.Warn("Dropping tool that isn't installed from imported agent", zap.String("tool", name))
+			continue
+		}
+		agent.Tools = append(agent.Tools, name)
+	}
+
+	for _, ref := range export.Fallbacks {
+		model, err := s.resolveModelReference(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		agent.Fallbacks = append(agent.Fallbacks, model.ID)
+	}
+
+	if err := s.CreateAgent(ctx, agent); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Imported agent", zap.String("agent_id", agent.ID), zap.String("name", agent.Name))
+	return agent, nil
+}
+
+// resolveModelReference finds the model a reference points to, creating it
+// and its provider when they don't exist.
+func (s *agentService) resolveModelReference(ctx context.Context, ref modelReference) (*entities.Model, error) {
+	if ref.ProviderType == "" || ref.ModelName == "" {
+		return nil, errors.ValidationErrorf("fallback model needs a provider type and model name")
+	}
+
+	providers, err := s.providerRepo.ListProviders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var provider *entities.Provider
+	for _, p := range providers {
+		if p.Type != ref.ProviderType {
+			continue
+		}
+		// Generic providers are told apart by their base URL
+		if ref.ProviderType == entities.ProviderGeneric && !strings.EqualFold(strings.TrimRight(p.BaseURL, "/"), strings.TrimRight(ref.BaseURL, "/")) {
+			continue
+		}
+		if provider == nil || strings.EqualFold(p.Name, ref.ProviderName) {
+			provider = p
+		}
+	}
+	if provider == nil {
+		name := ref.ProviderName
+		if name == "" {
+			name = string(ref.ProviderType)
+		}
+		provider = entities.NewProvider(uuid.New().String(), name, ref.ProviderType, ref.BaseURL, ref.APIKeyName, nil)
+		if err := s.providerRepo.CreateProvider(ctx, provider); err != nil {
+			return nil, errors.InternalErrorf("failed to create provider %s: %v", name, err)
+		}
+		s.logger.Info("Created provider for imported agent", zap.String("provider", name))
+	}
+
+	models, err := s.modelRepo.GetModelsByProvider(ctx, provider.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, model := range models {
+		if model.ModelName == ref.ModelName {
+			return model, nil
+		}
+	}
+
+	name := ref.Name
+	if name == "" {
+		name = provider.Name + " " + ref.ModelName
+	}
+	model := entities.NewModel(name, provider.ID, provider.Type, ref.ModelName, "", ref.Temperature, ref.MaxTokens, ref.ContextWindow, ref.ReasoningEffort, "", false, false, false, false, false)
+	if err := s.modelRepo.CreateModel(ctx, model); err != nil {
+		return nil, errors.InternalErrorf("failed to create model %s: %v", ref.ModelName, err)
+	}
+	s.logger.Info("Created model for imported agent", zap.String("model", ref.ModelName))
+	return model, nil
+}
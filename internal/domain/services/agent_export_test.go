@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/domain/interfaces"
+
+	"go.uber.org/zap"
+)
+
+type memoryAgentRepo struct {
+	interfaces.AgentRepository
+	agents map[string]*entities.Agent
+}
+
+func (r *memoryAgentRepo) GetAgent(ctx context.Context, id string) (*entities.Agent, error) {
+	if agent, ok := r.agents[id]; ok {
+		return agent, nil
+	}
+	return nil, fmt.Errorf("agent %s not found", id)
+}
+
+func (r *memoryAgentRepo) CreateAgent(ctx context.Context, agent *entities.Agent) error {
+	r.agents[agent.ID] = agent
+	return nil
+}
+
+type memoryProviderRepo struct {
+	interfaces.ProviderRepository
+	providers []*entities.Provider
+}
+
+func (r *memoryProviderRepo) ListProviders(ctx context.Context) ([]*entities.Provider, error) {
+	return r.providers, nil
+}
+
+func (r *memoryProviderRepo) GetProvider(ctx context.Context, id string) (*entities.Provider, error) {
+	for _, p := range r.providers {
+		if p.ID == id {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("provider %s not found", id)
+}
+
+func (r *memoryProviderRepo) CreateProvider(ctx context.Context, provider *entities.Provider) error {
+	r.providers = append(r.providers, provider)
+	return nil
+}
+
+type memoryModelRepo struct {
+	interfaces.ModelRepository
+	models []*entities.Model
+}
+
+func (r *memoryModelRepo) GetModel(ctx context.Context, id string) (*entities.Model, error) {
+	for _, m := range r.models {
+		if m.ID == id {
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("model %s not found", id)
+}
+
+func (r *memoryModelRepo) GetModelsByProvider(ctx context.Context, providerID string) ([]*entities.Model, error) {
+	var models []*entities.Model
+	for _, m := range r.models {
+		if m.ProviderID == providerID {
+			models = append(models, m)
+		}
+	}
+	return models, nil
+}
+
+func (r *memoryModelRepo) CreateModel(ctx context.Context, model *entities.Model) error {
+	r.models = append(r.models, model)
+	return nil
+}
+
+// namedTool is an installed tool; only its presence matters.
+type namedTool struct {
+	entities.Tool
+}
+
+type memoryToolRepo struct {
+	interfaces.ToolRepository
+	names []string
+}
+
+func (r *memoryToolRepo) GetToolByName(name string) (entities.Tool, error) {
+	for _, n := range r.names {
+		if n == name {
+			return &namedTool{}, nil
+		}
+	}
+	return nil, fmt.Errorf("tool %s not found", name)
+}
+
+func TestExportImportAgent(t *testing.T) {
+	provider := entities.NewProvider("anthropic-id", "Anthropic", entities.ProviderAnthropic, "https://api.anthropic.com", "ANTHROPIC_API_KEY", nil)
+	temperature := 0.2
+	model := entities.NewModel("Claude", provider.ID, provider.Type, "claude-sonnet", "", &temperature, nil, nil, "", "", false, false, false, false, false)
+	agent := entities.NewAgent("Reviewer", "Review code.", []string{"Read", "Grep"})
+	agent.Fallbacks = []string{model.ID}
+	agent.PlanMode = true
+
+	source := NewAgentService(
+		&memoryAgentRepo{agents: map[string]*entities.Agent{agent.ID: agent}},
+		&memoryModelRepo{models: []*entities.Model{model}},
+		&memoryProviderRepo{providers: []*entities.Provider{provider}},
+		&memoryToolRepo{}, nil, zap.NewNop())
+	data, err := source.ExportAgent(context.Background(), agent.ID)
+	if err != nil {
+		t.Fatalf("ExportAgent: %v", err)
+	}
+
+	var exported map[string]any
+	if err := json.Unmarshal(data, &exported); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if _, ok := exported["id"]; ok {
+		t.Errorf("expected the export to leave out the agent ID")
+	}
+
+	// Import into an installation without the provider or one of the tools
+	agentRepo := &memoryAgentRepo{agents: map[string]*entities.Agent{}}
+	modelRepo := &memoryModelRepo{}
+	providerRepo := &memoryProviderRepo{}
+	target := NewAgentService(agentRepo, modelRepo, providerRepo, &memoryToolRepo{names: []string{"Read"}}, nil, zap.NewNop())
+	imported, err := target.ImportAgent(context.Background(), data)
+	if err != nil {
+		t.Fatalf("ImportAgent: %v", err)
+	}
+
+	if imported.ID == agent.ID || agentRepo.agents[imported.ID] == nil {
+		t.Errorf("expected the agent to be saved with a new ID")
+	}
+	if imported.Name != "Reviewer" || !imported.PlanMode || len(imported.Tools) != 1 || imported.Tools[0] != "Read" {
+		t.Errorf("unexpected imported agent: %+v", imported)
+	}
+	if len(providerRepo.providers) != 1 || providerRepo.providers[0].Type != entities.ProviderAnthropic || providerRepo.providers[0].APIKeyName != "ANTHROPIC_API_KEY" {
+		t.Fatalf("expected the provider to be created, got %+v", providerRepo.providers)
+	}
+	if len(modelRepo.models) != 1 || imported.Fallbacks[0] != modelRepo.models[0].ID || *modelRepo.models[0].Temperature != 0.2 {
+		t.Fatalf("expected the fallback model to be created, got %+v", modelRepo.models)
+	}
+
+	// A second import reuses the provider and model
+	if _, err := target.ImportAgent(context.Background(), data); err != nil {
+		t.Fatalf("second ImportAgent: %v", err)
+	}
+	if len(providerRepo.providers) != 1 || len(modelRepo.models) != 1 {
+		t.Errorf("expected the provider and model to be reused, got %d and %d", len(providerRepo.providers), len(modelRepo.models))
+	}
+
+	if _, err := target.ImportAgent(context.Background(), []byte(`{"version": 99, "name": "x", "system_prompt": "y"}`)); err == nil {
+		t.Errorf("expected a newer export version to be rejected")
+	}
+}
@@ -18,17 +18,25 @@ type AgentService interface {
 	CreateAgent(ctx context.Context, agent *entities.Agent) error
 	UpdateAgent(ctx context.Context, agent *entities.Agent) error
 	DeleteAgent(ctx context.Context, id string) error
+	ExportAgent(ctx context.Context, id string) ([]byte, error)
+	ImportAgent(ctx context.Context, data []byte) (*entities.Agent, error)
 }
 
 type agentService struct {
 	agentRepo    interfaces.AgentRepository
+	modelRepo    interfaces.ModelRepository
+	providerRepo interfaces.ProviderRepository
+	toolRepo     interfaces.ToolRepository
 	skillService SkillService
 	logger       *zap.Logger
 }
 
-func NewAgentService(agentRepo interfaces.AgentRepository, skillService SkillService, logger *zap.Logger) *agentService {
+func NewAgentService(agentRepo interfaces.AgentRepository, modelRepo interfaces.ModelRepository, providerRepo interfaces.ProviderRepository, toolRepo interfaces.ToolRepository, skillService SkillService, logger *zap.Logger) *agentService {
 	return &agentService{
 		agentRepo:    agentRepo,
+		modelRepo:    modelRepo,
+		providerRepo: providerRepo,
+		toolRepo:     toolRepo,
 		skillService: skillService,
 		logger:       logger,
 	}
@@ -79,6 +87,32 @@ func (s *agentService) CreateAgent(ctx context.Context, agent *entities.Agent) e
 	if agent.SystemPrompt == "" {
 		return errors.ValidationErrorf("agent prompt is required")
 	}
+	if agent.ResponseFormat != nil {
+		if err := agent.ResponseFormat.Validate(); err != nil {
+			return errors.ValidationErrorf("invalid response format: %v", err)
+		}
+	}
+	if agent.OutputLimit != nil {
+		if err := agent.OutputLimit.Validate(); err != nil {
+			return errors.ValidationErrorf("invalid output limit: %v", err)
+		}
+	}
+	if agent.VerifyLoop != nil {
+		if err := agent.VerifyLoop.Validate(); err != nil {
+			return errors.ValidationErrorf("invalid verify loop: %v", err)
+		}
+	}
+	if agent.Compression != nil {
+		if err := agent.Compression.Validate(); err != nil {
+			return errors.ValidationErrorf("invalid compression: %v", err)
+		}
+	}
+	if agent.MaxConsecutiveFailures < 0 {
+		return errors.ValidationErrorf("max consecutive failures cannot be negative")
+	}
+	if err := agent.ValidateEnv(); err != nil {
+		return errors.ValidationErrorf("%v", err)
+	}
 
 	agent.CreatedAt = time.Now()
 	agent.UpdatedAt = time.Now()
@@ -106,9 +140,38 @@ func (s *agentService) UpdateAgent(ctx context.Context, agent *entities.Agent) e
 	if agent.SystemPrompt == "" {
 		return errors.ValidationErrorf("agent prompt is required")
 	}
+	if agent.ResponseFormat != nil {
+		if err := agent.ResponseFormat.Validate(); err != nil {
+			return errors.ValidationErrorf("invalid response format: %v", err)
+		}
+	}
+	if agent.OutputLimit != nil {
+		if err := agent.OutputLimit.Validate(); err != nil {
+			return errors.ValidationErrorf("invalid output limit: %v", err)
+		}
+	}
+	if agent.VerifyLoop != nil {
+		if err := agent.VerifyLoop.Validate(); err != nil {
+			return errors.ValidationErrorf("invalid verify loop: %v", err)
+		}
+	}
+	if agent.Compression != nil {
+		if err := agent.Compression.Validate(); err != nil {
+			return errors.ValidationErrorf("invalid compression: %v", err)
+		}
+	}
+	if agent.MaxConsecutiveFailures < 0 {
+		return errors.ValidationErrorf("max consecutive failures cannot be negative")
+	}
+	if err := agent.ValidateEnv(); err != nil {
+		return errors.ValidationErrorf("%v", err)
+	}
 
 	agent.CreatedAt = existing.CreatedAt
 	agent.UpdatedAt = time.Now()
+	if agent.ReasoningStats == nil {
+		agent.ReasoningStats = existing.ReasoningStats
+	}
 
 	if err := s.agentRepo.UpdateAgent(ctx, agent); err != nil {
 		return err
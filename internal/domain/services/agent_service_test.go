@@ -66,7 +66,7 @@ func TestAgentService_ListAgents(t *testing.T) {
 	mockRepo := new(mockAgentRepository)
 	mockSkill := new(mockSkillService)
 	logger := zap.NewNop()
-	service := NewAgentService(mockRepo, mockSkill, logger)
+	service := NewAgentService(mockRepo, nil, nil, nil, mockSkill, logger)
 	mockSkill.On("ListSkills", mock.Anything).Return([]*entities.Skill{}, nil)
 
 	ctx := context.Background()
@@ -87,7 +87,7 @@ func TestAgentService_GetAgent(t *testing.T) {
 	mockRepo := new(mockAgentRepository)
 	mockSkill := new(mockSkillService)
 	logger := zap.NewNop()
-	service := NewAgentService(mockRepo, mockSkill, logger)
+	service := NewAgentService(mockRepo, nil, nil, nil, mockSkill, logger)
 	mockSkill.On("ListSkills", mock.Anything).Return([]*entities.Skill{}, nil)
 
 	ctx := context.Background()
@@ -115,7 +115,7 @@ func TestAgentService_CreateAgent(t *testing.T) {
 	mockRepo := new(mockAgentRepository)
 	mockSkill := new(mockSkillService)
 	logger := zap.NewNop()
-	service := NewAgentService(mockRepo, mockSkill, logger)
+	service := NewAgentService(mockRepo, nil, nil, nil, mockSkill, logger)
 	mockSkill.On("ListSkills", mock.Anything).Return([]*entities.Skill{}, nil)
 
 	ctx := context.Background()
@@ -157,7 +157,7 @@ func TestAgentService_UpdateAgent(t *testing.T) {
 	mockRepo := new(mockAgentRepository)
 	mockSkill := new(mockSkillService)
 	logger := zap.NewNop()
-	service := NewAgentService(mockRepo, mockSkill, logger)
+	service := NewAgentService(mockRepo, nil, nil, nil, mockSkill, logger)
 	mockSkill.On("ListSkills", mock.Anything).Return([]*entities.Skill{}, nil)
 
 	ctx := context.Background()
@@ -195,7 +195,7 @@ func TestAgentService_DeleteAgent(t *testing.T) {
 	mockRepo := new(mockAgentRepository)
 	mockSkill := new(mockSkillService)
 	logger := zap.NewNop()
-	service := NewAgentService(mockRepo, mockSkill, logger)
+	service := NewAgentService(mockRepo, nil, nil, nil, mockSkill, logger)
 	mockSkill.On("ListSkills", mock.Anything).Return([]*entities.Skill{}, nil)
 
 	ctx := context.Background()
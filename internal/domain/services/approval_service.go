@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"sync"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	errors "github.com/drujensen/aiagent/internal/domain/errs"
+	"github.com/drujensen/aiagent/internal/domain/interfaces"
+	"github.com/drujensen/aiagent/internal/impl/config"
+
+	"go.uber.org/zap"
+)
+
+type ApprovalService interface {
+	ListPolicies(ctx context.Context, chatID string) ([]*entities.ApprovalPolicy, error)
+	AddPolicy(ctx context.Context, policy *entities.ApprovalPolicy) error
+	RemovePolicy(ctx context.Context, chatID, policyID string) error
+	RequiresApproval(toolName string) bool
+	RequiredTools() []string
+	IsApproved(ctx context.Context, chatID, toolName, arguments string) bool
+}
+
+// approvalService stores approval policies by scope: session policies live in
+// memory, chat policies on the chat and global policies in the global config.
+type approvalService struct {
+	chatRepo        interfaces.ChatRepository
+	globalConfig    *config.GlobalConfig
+	sessionPolicies []*entities.ApprovalPolicy
+	mu              sync.RWMutex
+	logger          *zap.Logger
+}
+
+func NewApprovalService(chatRepo interfaces.ChatRepository, globalConfig *config.GlobalConfig, logger *zap.Logger) *approvalService {
+	return &approvalService{
+		chatRepo:     chatRepo,
+		globalConfig: globalConfig,
+		logger:       logger,
+	}
+}
+
+// ListPolicies returns the session and global policies, and the chat's when
+// chatID is set. They are copies taken under the lock, so policies removed
+// meanwhile do not change them.
+func (s *approvalService) ListPolicies(ctx context.Context, chatID string) ([]*entities.ApprovalPolicy, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	policies := make([]*entities.ApprovalPolicy, 0)
+	for _, policy := range s.sessionPolicies {
+		p := *policy
+		policies = append(policies, &p)
+	}
+
+	if chatID != "" {
+		chat, err := s.chatRepo.GetChat(ctx, chatID)
+		if err != nil {
+			return nil, err
+		}
+		for _, policy := range chat.ApprovalPolicies {
+			policies = append(policies, &policy)
+		}
+	}
+
+	if s.globalConfig != nil {
+		for _, policy := range s.globalConfig.ApprovalPolicies {
+			policies = append(policies, &policy)
+		}
+	}
+
+	return policies, nil
+}
+
+func (s *approvalService) AddPolicy(ctx context.Context, policy *entities.ApprovalPolicy) error {
+	if policy.ID == "" {
+		return errors.ValidationErrorf("policy ID is required")
+	}
+	if policy.ToolName == "" && policy.Pattern == "" && policy.Scope != entities.ApprovalScopeSession {
+		return errors.ValidationErrorf("approving every tool is only allowed for the session scope")
+	}
+	if policy.Pattern != "" {
+		if _, err := regexp.Compile(policy.Pattern); err != nil {
+			return errors.ValidationErrorf("invalid pattern: %v", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch policy.Scope {
+	case entities.ApprovalScopeSession:
+		s.sessionPolicies = append(s.sessionPolicies, policy)
+	case entities.ApprovalScopeChat:
+		if policy.ChatID == "" {
+			return errors.ValidationErrorf("chat ID is required for chat scoped policies")
+		}
+		chat, err := s.chatRepo.GetChat(ctx, policy.ChatID)
+		if err != nil {
+			return err
+		}
+		chat.ApprovalPolicies = append(chat.ApprovalPolicies, *policy)
+		if err := s.chatRepo.UpdateChat(ctx, chat); err != nil {
+			return err
+		}
+	case entities.ApprovalScopeGlobal:
+		if s.globalConfig == nil {
+			return errors.InternalErrorf("global config is not available")
+		}
+		s.globalConfig.ApprovalPolicies = append(s.globalConfig.ApprovalPolicies, *policy)
+		if err := config.SaveGlobalConfig(s.globalConfig, s.logger); err != nil {
+			return errors.InternalErrorf("failed to save approval policy: %v", err)
+		}
+	default:
+		return errors.ValidationErrorf("invalid approval scope: %s", policy.Scope)
+	}
+
+	s.logger.Info("Approval policy added",
+		zap.String("scope", string(policy.Scope)),
+		zap.String("tool", policy.ToolName),
+		zap.String("pattern", policy.Pattern))
+	return nil
+}
+
+func (s *approvalService) RemovePolicy(ctx context.Context, chatID, policyID string) error {
+	if policyID == "" {
+		return errors.ValidationErrorf("policy ID is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, p := range s.sessionPolicies {
+		if p.ID == policyID {
+			s.sessionPolicies = slices.Delete(s.sessionPolicies, i, i+1)
+			return nil
+		}
+	}
+
+	if chatID != "" {
+		chat, err := s.chatRepo.GetChat(ctx, chatID)
+		if err != nil {
+			return err
+		}
+		for i, p := range chat.ApprovalPolicies {
+			if p.ID == policyID {
+				chat.ApprovalPolicies = slices.Delete(chat.ApprovalPolicies, i, i+1)
+				return s.chatRepo.UpdateChat(ctx, chat)
+			}
+		}
+	}
+
+	if s.globalConfig != nil {
+		for i, p := range s.globalConfig.ApprovalPolicies {
+			if p.ID == policyID {
+				s.globalConfig.ApprovalPolicies = slices.Delete(s.globalConfig.ApprovalPolicies, i, i+1)
+				return config.SaveGlobalConfig(s.globalConfig, s.logger)
+			}
+		}
+	}
+
+	return errors.NotFoundErrorf("approval policy not found: %s", policyID)
+}
+
+// RequiresApproval reports whether the tool is gated by the approval policies.
+func (s *approvalService) RequiresApproval(toolName string) bool {
+	return slices.Contains(s.RequiredTools(), toolName)
+}
+
+// RequiredTools returns a copy of the tools gated by the approval policies.
+func (s *approvalService) RequiredTools() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.globalConfig == nil {
+		return nil
+	}
+	return slices.Clone(s.globalConfig.ApprovalRequiredTools)
+}
+
+// IsApproved reports whether a tool call may run. Tools that do not require
+// approval are always allowed.
+func (s *approvalService) IsApproved(ctx context.Context, chatID, toolName, arguments string) bool {
+	if !s.RequiresApproval(toolName) {
+		return true
+	}
+
+	policies, err := s.ListPolicies(ctx, chatID)
+	if err != nil {
+		s.logger.Warn("Failed to list approval policies", zap.String("chat_id", chatID), zap.Error(err))
+		return false
+	}
+
+	for _, policy := range policies {
+		if policy.Matches(chatID, toolName, arguments) {
+			return true
+		}
+	}
+
+	s.logger.Info("Tool call not approved", zap.String("chat_id", chatID), zap.String("tool", toolName))
+	return false
+}
+
+var _ interfaces.ToolApprover = (*approvalService)(nil)
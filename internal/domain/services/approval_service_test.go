@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/impl/config"
+
+	"go.uber.org/zap"
+)
+
+func TestApprovalService_ScopedPolicies(t *testing.T) {
+	ctx := context.Background()
+	chat := &entities.Chat{ID: "chat"}
+	s := NewApprovalService(&memoryChatRepo{chat: chat}, &config.GlobalConfig{ApprovalRequiredTools: []string{"Bash"}}, zap.NewNop())
+
+	if s.IsApproved(ctx, "chat", "Bash", `{"command": "go test ./..."}`) {
+		t.Fatal("Expected the call to need approval without a policy")
+	}
+	if !s.IsApproved(ctx, "chat", "Read", `{}`) {
+		t.Error("Expected tools that do not require approval to run")
+	}
+
+	chatPolicy := entities.NewApprovalPolicy(entities.ApprovalScopeChat, "chat", "Bash", "^go test")
+	if err := s.AddPolicy(ctx, chatPolicy); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	if !s.IsApproved(ctx, "chat", "Bash", `{"command": "go test ./..."}`) {
+		t.Error("Expected the chat policy to approve the call")
+	}
+	if s.IsApproved(ctx, "chat", "Bash", `{"command": "rm -rf /"}`) || s.IsApproved(ctx, "other", "Bash", `{"command": "go test ./..."}`) {
+		t.Error("Expected the chat policy to approve only matching calls in its chat")
+	}
+	if err := s.AddPolicy(ctx, entities.NewApprovalPolicy(entities.ApprovalScopeChat, "chat", "", "")); err == nil {
+		t.Error("Expected approving every tool to be refused outside the session scope")
+	}
+
+	sessionPolicy := entities.NewApprovalPolicy(entities.ApprovalScopeSession, "", "", "")
+	if err := s.AddPolicy(ctx, sessionPolicy); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	policies, err := s.ListPolicies(ctx, "chat")
+	if err != nil || len(policies) != 2 {
+		t.Fatalf("Expected the session and chat policies, got %v, %v", policies, err)
+	}
+
+	// Listed policies are copies that removals do not change
+	if err := s.RemovePolicy(ctx, "chat", sessionPolicy.ID); err != nil {
+		t.Fatalf("RemovePolicy failed: %v", err)
+	}
+	if err := s.RemovePolicy(ctx, "chat", chatPolicy.ID); err != nil {
+		t.Fatalf("RemovePolicy failed: %v", err)
+	}
+	if policies[0].ID != sessionPolicy.ID || policies[1].ID != chatPolicy.ID {
+		t.Errorf("Expected the listed policies to be left as they were, got %+v, %+v", policies[0], policies[1])
+	}
+	if len(chat.ApprovalPolicies) != 0 || s.IsApproved(ctx, "chat", "Bash", `{"command": "go test ./..."}`) {
+		t.Error("Expected the policies to be removed")
+	}
+	if err := s.RemovePolicy(ctx, "chat", chatPolicy.ID); err == nil {
+		t.Error("Expected removing a missing policy to fail")
+	}
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	errors "github.com/drujensen/aiagent/internal/domain/errs"
+
+	"go.uber.org/zap"
+)
+
+func (s *chatService) ListProjects(ctx context.Context) ([]*entities.Project, error) {
+	if s.projectRepo == nil {
+		return []*entities.Project{}, nil
+	}
+
+	projects, err := s.projectRepo.ListProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(projects, func(i, j int) bool {
+		return strings.ToLower(projects[i].Name) < strings.ToLower(projects[j].Name)
+	})
+	return projects, nil
+}
+
+func (s *chatService) GetProject(ctx context.Context, id string) (*entities.Project, error) {
+	if id == "" {
+		return nil, errors.ValidationErrorf("project ID is required")
+	}
+	if s.projectRepo == nil {
+		return nil, errors.NotFoundErrorf("project not found: %s", id)
+	}
+
+	return s.projectRepo.GetProject(ctx, id)
+}
+
+func (s *chatService) CreateProject(ctx context.Context, name, workspace string) (*entities.Project, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.ValidationErrorf("project name is required")
+	}
+	if s.projectRepo == nil {
+		return nil, errors.InternalErrorf("project storage is not configured")
+	}
+
+	if workspace != "" {
+		abs, err := filepath.Abs(workspace)
+		if err != nil {
+			return nil, errors.ValidationErrorf("invalid workspace: %v", err)
+		}
+		workspace = abs
+	}
+
+	project := entities.NewProject(name, workspace)
+	if err := s.projectRepo.CreateProject(ctx, project); err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+func (s *chatService) UpdateProject(ctx context.Context, id, name, workspace string) (*entities.Project, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.ValidationErrorf("project name is required")
+	}
+
+	project, err := s.GetProject(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	project.Name = name
+	if workspace != "" {
+		abs, err := filepath.Abs(workspace)
+		if err != nil {
+			return nil, errors.ValidationErrorf("invalid workspace: %v", err)
+		}
+		project.Workspace = abs
+	}
+
+	if err := s.projectRepo.UpdateProject(ctx, project); err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+// DeleteProject removes the project and moves its chats back to the
+// ungrouped list. The chats themselves are kept.
+func (s *chatService) DeleteProject(ctx context.Context, id string) error {
+	if _, err := s.GetProject(ctx, id); err != nil {
+		return err
+	}
+
+	chats, err := s.chatRepo.ListChats(ctx)
+	if err != nil {
+		return err
+	}
+	for _, chat := range chats {
+		if chat.ProjectID != id {
+			continue
+		}
+		chat.ProjectID = ""
+		if err := s.chatRepo.UpdateChat(ctx, chat); err != nil {
+			return err
+		}
+	}
+
+	return s.projectRepo.DeleteProject(ctx, id)
+}
+
+// SetChatProject files a chat under a project. An empty projectID removes
+// the chat from its project.
+func (s *chatService) SetChatProject(ctx context.Context, chatID, projectID string) error {
+	if chatID == "" {
+		return errors.ValidationErrorf("chat ID is required")
+	}
+	if projectID != "" {
+		if _, err := s.GetProject(ctx, projectID); err != nil {
+			return err
+		}
+	}
+
+	chat, err := s.chatRepo.GetChat(ctx, chatID)
+	if err != nil {
+		return err
+	}
+
+	chat.ProjectID = projectID
+	chat.UpdatedAt = time.Now()
+	return s.chatRepo.UpdateChat(ctx, chat)
+}
+
+// ListChatsByProject returns the chats filed under a project. An empty
+// projectID returns the chats that are not in any project.
+func (s *chatService) ListChatsByProject(ctx context.Context, projectID string) ([]*entities.Chat, error) {
+	chats, err := s.chatRepo.ListChats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]*entities.Chat, 0, len(chats))
+	for _, chat := range chats {
+		if chat.ProjectID == projectID {
+			filtered = append(filtered, chat)
+		}
+	}
+	return filtered, nil
+}
+
+// workspaceProjectID finds the project for the current working directory,
+// preferring the most specific workspace when projects are nested. When none
+// matches and AutoCreateProjects is enabled, a project named after the
+// directory is created. Failures only leave the chat ungrouped.
+func (s *chatService) workspaceProjectID(ctx context.Context) string {
+	if s.projectRepo == nil {
+		return ""
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+
+	projects, err := s.projectRepo.ListProjects(ctx)
+	if err != nil {
+		s.logger.Warn("Failed to list projects", zap.Error(err))
+		return ""
+	}
+
+	project := projectForWorkspace(projects, cwd)
+	if project != nil {
+		return project.ID
+	}
+
+	if s.globalConfig == nil || !s.globalConfig.AutoCreateProjects {
+		return ""
+	}
+
+	project = entities.NewProject(filepath.Base(cwd), cwd)
+	if err := s.projectRepo.CreateProject(ctx, project); err != nil {
+		s.logger.Warn("Failed to create project for workspace", zap.String("workspace", cwd), zap.Error(err))
+		return ""
+	}
+	return project.ID
+}
+
+func projectForWorkspace(projects []*entities.Project, dir string) *entities.Project {
+	var best *entities.Project
+	for _, project := range projects {
+		if !project.Contains(dir) {
+			continue
+		}
+		if best == nil || len(project.Workspace) > len(best.Workspace) {
+			best = project
+		}
+	}
+	return best
+}
@@ -1,10 +1,13 @@
 package services
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/drujensen/aiagent/internal/domain/entities"
@@ -21,6 +24,7 @@ import (
 
 type ChatService interface {
 	ListChats(ctx context.Context) ([]*entities.Chat, error)
+	SearchChats(ctx context.Context, query string, filters ...entities.ChatSearchFilter) ([]*entities.Chat, error)
 	GetChat(ctx context.Context, id string) (*entities.Chat, error)
 	GetActiveChat(ctx context.Context) (*entities.Chat, error)
 	SetActiveChat(ctx context.Context, chatID string) error
@@ -29,45 +33,106 @@ type ChatService interface {
 	UpdateChat(ctx context.Context, id, agentID, modelID, name string) (*entities.Chat, error)
 	DeleteChat(ctx context.Context, id string) error
 	SendMessage(ctx context.Context, id string, message *entities.Message) (*entities.Message, error)
+	EditMessage(ctx context.Context, chatID, messageID, content string) (*entities.Message, error)
+	DeleteMessage(ctx context.Context, chatID, messageID string) error
+	RegenerateLast(ctx context.Context, chatID string) (*entities.Message, error)
 	SaveMessagesIncrementally(ctx context.Context, chatID string, messages []*entities.Message) error
 	CalculateTotalChatCost(ctx context.Context, chatID string) (float64, error)
+	ContextUsage(ctx context.Context, chatID string) (*entities.ContextUsage, error)
+	UsageReport(ctx context.Context, since time.Time) (*entities.UsageReport, error)
+	ExportChat(ctx context.Context, chatID string, format string) ([]byte, error)
+	SetBudget(ctx context.Context, chatID string, budget *entities.ChatBudget) error
+	SetInstructions(ctx context.Context, chatID string, instructions string) error
+	SetToolsOverride(ctx context.Context, chatID string, tools []string) error
+	SetWorkspace(ctx context.Context, chatID, workspace string) (string, error)
+	PinFiles(ctx context.Context, chatID string, paths []string) ([]string, error)
+	UnpinFiles(ctx context.Context, chatID string, paths []string) ([]string, error)
+	Steer(ctx context.Context, chatID, note string) error
+	ClearContext(ctx context.Context, chatID string) (*entities.Chat, error)
+	CompactChat(ctx context.Context, chatID string) error
+	PreviewCompaction(ctx context.Context, chatID string) (*entities.CompactionPreview, error)
+	ApprovePlan(ctx context.Context, chatID string) (*entities.Message, error)
+	DiscardPlan(ctx context.Context, chatID string) error
+	SetReviewEdits(ctx context.Context, chatID string, enabled bool) error
+	PendingEdits(ctx context.Context, chatID string) ([]*entities.EditReview, error)
+	ResolveEdit(ctx context.Context, chatID, reviewID string, decision entities.EditDecision) error
+	WaitForNotifications()
 	GenerateAndUpdateTitle(ctx context.Context, chatID string) (*entities.Chat, error)
 	ExecuteSkill(ctx context.Context, skillName string) error
+	ListProjects(ctx context.Context) ([]*entities.Project, error)
+	GetProject(ctx context.Context, id string) (*entities.Project, error)
+	CreateProject(ctx context.Context, name, workspace string) (*entities.Project, error)
+	UpdateProject(ctx context.Context, id, name, workspace string) (*entities.Project, error)
+	DeleteProject(ctx context.Context, id string) error
+	SetChatProject(ctx context.Context, chatID, projectID string) error
+	ListChatsByProject(ctx context.Context, projectID string) ([]*entities.Chat, error)
 }
 
 type chatService struct {
-	chatRepo     interfaces.ChatRepository
-	agentRepo    interfaces.AgentRepository
-	agentService AgentService
-	modelRepo    interfaces.ModelRepository
-	providerRepo interfaces.ProviderRepository
-	toolRepo     interfaces.ToolRepository
-	skillService SkillService
-	config       *config.Config
-	logger       *zap.Logger
+	chatRepo        interfaces.ChatRepository
+	projectRepo     interfaces.ProjectRepository
+	agentRepo       interfaces.AgentRepository
+	agentService    AgentService
+	modelRepo       interfaces.ModelRepository
+	providerRepo    interfaces.ProviderRepository
+	toolRepo        interfaces.ToolRepository
+	skillService    SkillService
+	approvalService ApprovalService
+	toolAuditor     interfaces.ToolAuditor
+	config          *config.Config
+	globalConfig    *config.GlobalConfig
+	logger          *zap.Logger
+
+	steeringMu sync.Mutex
+	steering   map[string]*steeringQueue // keyed by chat ID
+
+	edits editReviews
+
+	webhooks sync.WaitGroup // completion webhooks being delivered
+
+	chatLocks sync.Map // *sync.Mutex keyed by chat ID, see lockChat
+}
+
+// lockChat holds the chat's update lock until the returned func is called, so
+// updates that load, change and save a chat, such as the messages saved during
+// a response and its generated title, don't overwrite each other.
+func (s *chatService) lockChat(chatID string) func() {
+	value, _ := s.chatLocks.LoadOrStore(chatID, &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
 }
 
 func NewChatService(
 	chatRepo interfaces.ChatRepository,
+	projectRepo interfaces.ProjectRepository,
 	agentRepo interfaces.AgentRepository,
 	agentService AgentService,
 	modelRepo interfaces.ModelRepository,
 	providerRepo interfaces.ProviderRepository,
 	toolRepo interfaces.ToolRepository,
 	skillService SkillService,
+	approvalService ApprovalService,
+	toolAuditor interfaces.ToolAuditor,
 	cfg *config.Config,
+	globalConfig *config.GlobalConfig,
 	logger *zap.Logger,
 ) *chatService {
 	return &chatService{
-		chatRepo:     chatRepo,
-		agentRepo:    agentRepo,
-		agentService: agentService,
-		modelRepo:    modelRepo,
-		providerRepo: providerRepo,
-		toolRepo:     toolRepo,
-		skillService: skillService,
-		config:       cfg,
-		logger:       logger,
+		chatRepo:        chatRepo,
+		projectRepo:     projectRepo,
+		agentRepo:       agentRepo,
+		agentService:    agentService,
+		modelRepo:       modelRepo,
+		providerRepo:    providerRepo,
+		toolRepo:        toolRepo,
+		skillService:    skillService,
+		approvalService: approvalService,
+		toolAuditor:     toolAuditor,
+		config:          cfg,
+		globalConfig:    globalConfig,
+		logger:          logger,
+		steering:        make(map[string]*steeringQueue),
 	}
 }
 
@@ -80,6 +145,21 @@ func (s *chatService) ListChats(ctx context.Context) ([]*entities.Chat, error) {
 	return chats, nil
 }
 
+// SearchChats finds chats whose name or message content contains query,
+// ignoring case, most recently updated first. An optional filter restricts the
+// results to one agent and a range of update times.
+func (s *chatService) SearchChats(ctx context.Context, query string, filters ...entities.ChatSearchFilter) ([]*entities.Chat, error) {
+	var filter entities.ChatSearchFilter
+	if len(filters) > 0 {
+		filter = filters[0]
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+		return nil, errors.ValidationErrorf("search range ends before it starts")
+	}
+
+	return s.chatRepo.SearchChats(ctx, strings.TrimSpace(query), filter)
+}
+
 func (s *chatService) GetChat(ctx context.Context, id string) (*entities.Chat, error) {
 	if id == "" {
 		return nil, errors.ValidationErrorf("chat ID is required")
@@ -102,11 +182,14 @@ func (s *chatService) CreateChat(ctx context.Context, agentID, modelID, name str
 	}
 
 	chat := entities.NewChat(agentID, modelID, name)
+	chat.ProjectID = s.workspaceProjectID(ctx)
 	if err := s.chatRepo.CreateChat(ctx, chat); err != nil {
 		return nil, err
 	}
 
-	setOtherChatsInactive(ctx, s.chatRepo, chat.ID)
+	if err := s.chatRepo.SetActiveChat(ctx, chat.ID); err != nil {
+		s.logger.Error("Failed to activate new chat", zap.String("chat_id", chat.ID), zap.Error(err))
+	}
 
 	return chat, nil
 }
@@ -133,22 +216,6 @@ func (s *chatService) CreateSubChat(ctx context.Context, agentID, modelID, name,
 	return chat, nil
 }
 
-func setOtherChatsInactive(ctx context.Context, chatRepo interfaces.ChatRepository, activeChatID string) error {
-	chats, err := chatRepo.ListChats(ctx)
-	if err != nil {
-		return err
-	}
-	for _, c := range chats {
-		if c.ID != activeChatID {
-			c.Active = false
-			if err := chatRepo.UpdateChat(ctx, c); err != nil {
-				return err
-			}
-		}
-	}
-	return nil
-}
-
 func (s *chatService) GetActiveChat(ctx context.Context) (*entities.Chat, error) {
 	chats, err := s.chatRepo.ListChats(ctx)
 	if err != nil {
@@ -169,18 +236,7 @@ func (s *chatService) SetActiveChat(ctx context.Context, chatID string) error {
 		return errors.ValidationErrorf("chat ID is required")
 	}
 
-	chat, err := s.chatRepo.GetChat(ctx, chatID)
-	if err != nil {
-		return err
-	}
-	if err := setOtherChatsInactive(ctx, s.chatRepo, chat.ID); err != nil {
-		return err
-	}
-	chat.Active = true
-	if err := s.chatRepo.UpdateChat(ctx, chat); err != nil {
-		s.logger.Error("Failed to update chat status", zap.String("chat_id", chat.ID), zap.Error(err))
-	}
-	return nil
+	return s.chatRepo.SetActiveChat(ctx, chatID)
 }
 
 func (s *chatService) UpdateChat(ctx context.Context, id, agentID, modelID, name string) (*entities.Chat, error) {
@@ -200,6 +256,7 @@ func (s *chatService) UpdateChat(ctx context.Context, id, agentID, modelID, name
 		return nil, errors.ValidationErrorf("chat name is required")
 	}
 
+	defer s.lockChat(id)()
 	existingChat, err := s.chatRepo.GetChat(ctx, id)
 	if err != nil {
 		return nil, err
@@ -237,17 +294,22 @@ func (s *chatService) SaveMessagesIncrementally(ctx context.Context, chatID stri
 		return nil
 	}
 
+	defer s.lockChat(chatID)()
 	chat, err := s.chatRepo.GetChat(ctx, chatID)
 	if err != nil {
 		return err
 	}
 
-	// Append new messages to chat
+	// Append new messages to chat, replacing ones saved earlier in the turn
 	for _, msg := range messages {
 		if msg.Role == "assistant" && msg.Content == "" && len(msg.ToolCalls) == 0 {
 			continue
 		}
 
+		if i := slices.IndexFunc(chat.Messages, func(m entities.Message) bool { return msg.ID != "" && m.ID == msg.ID }); i >= 0 {
+			chat.Messages[i] = *msg
+			continue
+		}
 		chat.Messages = append(chat.Messages, *msg)
 	}
 
@@ -268,18 +330,41 @@ func (s *chatService) SaveMessagesIncrementally(ctx context.Context, chatID stri
 	return nil
 }
 
+// SendMessage adds the message to the chat and generates the response,
+// announcing its outcome to the completion webhook when one is configured.
 func (s *chatService) SendMessage(ctx context.Context, id string, message *entities.Message) (*entities.Message, error) {
+	started := time.Now()
+	var target completionTarget
+	response, err := s.sendMessage(ctx, id, message, &target)
+	s.notifyCompletion(target, started, response, err)
+	return response, err
+}
+
+// sendMessage runs the response, recording the chat and agent in target once
+// they are loaded.
+func (s *chatService) sendMessage(ctx context.Context, id string, message *entities.Message, target *completionTarget) (*entities.Message, error) {
 	if id == "" {
 		return nil, errors.ValidationErrorf("chat ID is required")
 	}
-	if message.Role == "" || message.Content == "" {
+	if message.Role == "" || (message.Content == "" && len(message.Attachments) == 0) {
 		return nil, errors.ValidationErrorf("message role and content are required")
 	}
+	if err := message.ValidateOverrides(); err != nil {
+		return nil, errors.ValidationErrorf("%v", err)
+	}
 
 	chat, err := s.chatRepo.GetChat(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+	target.chat = chat
+
+	// Refuse images the chat's model can't read before saving the message
+	if model, err := s.modelRepo.GetModel(ctx, chat.ModelID); err == nil {
+		if err := checkImageSupport(model, message); err != nil {
+			return nil, err
+		}
+	}
 
 	if message.ID == "" {
 		message.ID = uuid.New().String()
@@ -287,6 +372,18 @@ func (s *chatService) SendMessage(ctx context.Context, id string, message *entit
 	if message.Timestamp.IsZero() {
 		message.Timestamp = time.Now()
 	}
+
+	// Repair the stored history before adding to it, so a run cut off in the
+	// middle of a tool call does not break the chat for good. The repair is
+	// saved with the new message.
+	if s.globalConfig == nil || !s.globalConfig.StrictToolCallValidation {
+		if issues := repairToolCallHistory(chat); len(issues) > 0 {
+			s.logger.Warn("Repaired unbalanced tool calls in the stored chat",
+				zap.String("chat_id", chat.ID),
+				zap.Strings("issues", issues))
+		}
+	}
+
 	chat.Messages = append(chat.Messages, *message)
 	chat.UpdatedAt = time.Now()
 
@@ -294,23 +391,14 @@ func (s *chatService) SendMessage(ctx context.Context, id string, message *entit
 		return nil, err
 	}
 
-	// Generate title if chat still has default title (allows retry if previous attempts failed)
-	if strings.HasPrefix(chat.Name, "New Chat") {
-		s.logger.Info("Generating title for chat with default title", zap.String("chat_id", chat.ID))
-		if updatedChat, err := s.GenerateAndUpdateTitle(ctx, chat.ID); err != nil {
-			s.logger.Warn("Failed to generate title", zap.Error(err))
-		} else {
-			s.logger.Info("Successfully updated chat title", zap.String("chat_id", chat.ID), zap.String("new_title", updatedChat.Name))
-			// Update the in-memory chat with the new title
-			*chat = *updatedChat
-		}
-	}
-
 	// Check for cancellation
 	if ctx.Err() == context.Canceled {
 		return nil, errors.CanceledErrorf("message processing was canceled")
 	}
 
+	// Switch back to the preferred model if a downgrade has cooled down
+	s.restoreDowngradedModel(ctx, chat)
+
 	// Get Model instead of Agent for inference settings
 	model, err := s.modelRepo.GetModel(ctx, chat.ModelID)
 	if err != nil {
@@ -322,6 +410,10 @@ func (s *chatService) SendMessage(ctx context.Context, id string, message *entit
 	if err != nil {
 		return nil, err
 	}
+	target.agent = agent
+
+	// Keep the agent's env out of what the model integrations log
+	ctx = withRunSecrets(ctx, envSecrets(agent.Env))
 
 	// Get provider using model's ProviderID
 	provider, err := s.providerRepo.GetProvider(ctx, model.ProviderID)
@@ -334,31 +426,32 @@ func (s *chatService) SendMessage(ctx context.Context, id string, message *entit
 	}
 
 	// Resolve API key from provider
-	apiKeyReference := "#{" + provider.APIKeyName + "}#"
-	resolvedAPIKey, err := s.config.ResolveEnvironmentVariable(apiKeyReference)
+	resolvedAPIKey, err := s.providerAPIKey(provider)
 	if err != nil {
 		s.logger.Error("Failed to resolve API key", zap.String("provider_id", provider.ID), zap.Error(err))
 		return nil, errors.InternalErrorf("failed to resolve API key for provider %s: %v", provider.ID, err)
 	}
 
-	contextLength := 128000 // default
-	if model.ContextWindow != nil {
-		contextLength = *model.ContextWindow
-	}
+	tokenLimit := contextWindow(model)
 
-	tokenLimit := contextLength
+	// Layer the global policy, agent, project and session instructions
+	layers := s.systemLayers(ctx, chat, agent.FullSystemPrompt())
+	systemMessages := s.systemMessages(layers)
+	stableSystemMessages := s.systemMessages(withLayerContent(layers, entities.SystemLayerAgent, agent.StableSystemPrompt()))
 
-	systemMessage := &entities.Message{
-		Role:    "system",
-		Content: agent.FullSystemPrompt(),
+	// Create AI model integration based on provider type
+	aiModel, err := s.newModelIntegration(ctx, model, provider, resolvedAPIKey)
+	if err != nil {
+		s.logger.Error("Failed to create AI model integration", zap.String("model_id", model.ID), zap.Error(err))
+		return nil, errors.InternalErrorf("failed to initialize AI model: %v", err)
 	}
 
-	// Use provider-specific token estimation for system message
-	systemEstimateFunc := estimateTokens
-	if provider.Type == entities.ProviderAnthropic {
-		systemEstimateFunc = estimateAnthropicTokens
+	// Use provider-specific token estimation as the fallback
+	tokenEstimator := estimateTokens
+	if provider.EffectiveType() == entities.ProviderAnthropic {
+		tokenEstimator = estimateAnthropicTokens
 	}
-	systemTokens := systemEstimateFunc(systemMessage)
+	systemTokens := s.countTokens(aiModel, systemMessages, tokenEstimator)
 	if systemTokens > tokenLimit {
 		return nil, errors.InternalErrorf("system prompt too large for the context window")
 	}
@@ -368,30 +461,24 @@ func (s *chatService) SendMessage(ctx context.Context, id string, message *entit
 		return nil, errors.CanceledErrorf("message processing was canceled")
 	}
 
-	// Check if we need message compression (at 70% of token limit)
-	compressionThreshold := float64(tokenLimit) * 0.7
+	// Check if we need message compression (at the agent's trigger, 70% of the token limit by default)
+	compressionThreshold := float64(tokenLimit) * agent.Compression.TriggerRatio()
 	var messagesToSend []*entities.Message
 
-	// Always start with the system message
-	messagesToSend = append(messagesToSend, systemMessage)
+	// Always start with the system messages
+	messagesToSend = append(messagesToSend, systemMessages...)
 
 	// Check if we need to compress messages
-	totalMessageTokens := systemTokens
-	// Use provider-specific token estimation
-	tokenEstimator := estimateTokens
-	if provider.Type == entities.ProviderAnthropic {
-		tokenEstimator = estimateAnthropicTokens
-		s.logger.Debug("Using Anthropic-specific token estimation")
-	}
-
+	history := make([]*entities.Message, len(chat.Messages))
 	for i := range chat.Messages {
-		totalMessageTokens += tokenEstimator(&chat.Messages[i])
+		history[i] = &chat.Messages[i]
 	}
+	totalMessageTokens := systemTokens + s.countTokens(aiModel, history, tokenEstimator)
 
 	s.logger.Debug("Total message tokens: ", zap.Float64("total_message_tokens", float64(totalMessageTokens)), zap.Float64("compression_threshold", compressionThreshold))
 	if float64(totalMessageTokens) > compressionThreshold && len(chat.Messages) > 0 {
 		// Compress messages
-		compressedMessages, originalMessagesReplaced, err := s.compressMessages(ctx, chat, model, provider, resolvedAPIKey, tokenLimit)
+		compressedMessages, originalMessagesReplaced, err := s.compressMessages(ctx, chat, model, provider, resolvedAPIKey, tokenLimit, agent.Compression)
 		if err != nil {
 			s.logger.Warn("Failed to compress messages", zap.Error(err))
 			var tempMessages []*entities.Message
@@ -424,22 +511,61 @@ func (s *chatService) SendMessage(ctx context.Context, id string, message *entit
 
 	options := map[string]any{
 		"temperature": 0.0,
-		"max_tokens":  16384, // Increased for complex multi-tool tasks
 		"session_id":  chat.ID,
 	}
+	steering := s.startSteering(chat.ID)
+	defer s.stopSteering(ctx, chat.ID, steering)
+	options["steering_queue"] = steering
 	if model.Temperature != nil {
 		options["temperature"] = *model.Temperature
 	}
 	if model.MaxTokens != nil {
 		options["max_tokens"] = *model.MaxTokens
 	}
+	applyMessageOverrides(options, message)
 	if model.ReasoningEffort != "none" && model.ReasoningEffort != "" {
-		options["reasoning_effort"] = model.ReasoningEffort
+		if model.SupportsReasoningEffort(provider.EffectiveType()) {
+			options["reasoning_effort"] = model.ReasoningEffort
+		} else {
+			s.logger.Debug("Dropping reasoning effort the model does not support",
+				zap.String("model", model.ModelName),
+				zap.String("provider_type", string(provider.EffectiveType())),
+				zap.String("reasoning_effort", model.ReasoningEffort))
+		}
+	}
+	if s.approvalService != nil {
+		options["tool_approver"] = s.approvalService
+	}
+	if s.toolAuditor != nil {
+		options["tool_auditor"] = redactEnvAuditor(s.toolAuditor, agent.Env)
+	}
+	options["prompt_caching"] = s.globalConfig == nil || !s.globalConfig.DisablePromptCaching
+	if s.globalConfig != nil && s.globalConfig.MaxToolResultTokens > 0 {
+		options["max_tool_result_tokens"] = s.globalConfig.MaxToolResultTokens
+	}
+	if agent.MaxConsecutiveFailures > 0 {
+		options["max_consecutive_failures"] = agent.MaxConsecutiveFailures
+	}
+	if s.globalConfig != nil && s.globalConfig.MaxToolConcurrency > 0 {
+		options["max_tool_concurrency"] = s.globalConfig.MaxToolConcurrency
+	}
+	if chat.ReviewEdits && entities.EditReviewEnabled(ctx) {
+		options["edit_reviewer"] = &s.edits
+	}
+	var plan *toolPlan
+	if agent.PlanMode {
+		plan = &toolPlan{}
+		options["tool_planner"] = plan
+	}
+	if agent.ResponseFormat.Structured() {
+		options["response_format"] = agent.ResponseFormat
 	}
 
 	// Resolve tool configurations
 	tools := []entities.Tool{}
-	for _, toolName := range agent.Tools {
+	var toolSecrets []string
+	toolConfigs := s.toolConfigurations(ctx)
+	for _, toolName := range chat.ToolNames(agent) {
 		tool, err := s.toolRepo.GetToolByName(toolName)
 		if err != nil {
 			return nil, errors.InternalErrorf("failed to get tool %s: %v", toolName, err)
@@ -447,24 +573,26 @@ func (s *chatService) SendMessage(ctx context.Context, id string, message *entit
 		if tool == nil {
 			return nil, errors.InternalErrorf("tool repository returned nil for tool %s", toolName)
 		}
-		config := tool.Configuration()
-		if config == nil {
-			config = make(map[string]string)
-		}
-		resolvedConfig, err := s.config.ResolveConfiguration(config)
+		var secrets []string
+		tool, secrets, err = s.configureTool(tool, toolName, toolConfigs, chat.Workspace, agent.Env)
 		if err != nil {
 			return nil, errors.InternalErrorf("failed to resolve configuration for tool %s: %v", toolName, err)
 		}
-		tool.UpdateConfiguration(resolvedConfig)
 		tools = append(tools, tool)
+		for _, secret := range secrets {
+			if len(secret) >= minRedactedLength {
+				toolSecrets = append(toolSecrets, secret)
+			}
+		}
 	}
 
-	// Create AI model integration based on provider type
-	aiModelFactory := integrations.NewAIModelFactory(s.toolRepo, s.logger)
-	aiModel, err := aiModelFactory.CreateModelIntegration(model, provider, resolvedAPIKey)
-	if err != nil {
-		s.logger.Error("Failed to create AI model integration", zap.String("model_id", model.ID), zap.Error(err))
-		return nil, errors.InternalErrorf("failed to initialize AI model: %v", err)
+	// The tools' resolved secrets stay out of the logs like the agent's env
+	integrations.RedactSecrets(aiModel, toolSecrets)
+	ctx = withRunSecrets(ctx, toolSecrets)
+	if len(tools) > 0 && !model.SupportsTools() {
+		s.logger.Warn("Model does not support tool calls, sending the request without tools",
+			zap.String("model", model.Name), zap.Int("tools", len(tools)))
+		tools = []entities.Tool{}
 	}
 
 	// Pre-flight context check to ensure we're under safe limits
@@ -472,20 +600,7 @@ func (s *chatService) SendMessage(ctx context.Context, id string, message *entit
 		return nil, errors.InternalErrorf("no messages to send")
 	}
 
-	// Use provider-specific token estimation for pre-flight check
-	var estimateFunc func(*entities.Message) int = estimateTokens
-	if provider.Type == entities.ProviderAnthropic {
-		estimateFunc = estimateAnthropicTokens
-	}
-
-	totalTokens := 0
-	for _, msg := range messagesToSend {
-		if msg == nil {
-			s.logger.Error("Nil message found in messagesToSend")
-			continue
-		}
-		totalTokens += estimateFunc(msg)
-	}
+	totalTokens := s.countTokens(aiModel, messagesToSend, tokenEstimator)
 
 	// More aggressive pre-flight compression at 75% to prevent API errors
 	preFlightLimit := int(float64(tokenLimit) * 0.75)
@@ -496,10 +611,10 @@ func (s *chatService) SendMessage(ctx context.Context, id string, message *entit
 			zap.Int("token_limit", tokenLimit))
 
 		// Try compression with the pre-flight limit as target
-		compressedMessages, originalMessagesReplaced, err := s.compressMessages(ctx, chat, model, provider, resolvedAPIKey, preFlightLimit)
+		compressedMessages, originalMessagesReplaced, err := s.compressMessages(ctx, chat, model, provider, resolvedAPIKey, preFlightLimit, agent.Compression)
 		if err != nil {
 			s.logger.Warn("Pre-flight compression failed, falling back to trimming", zap.Error(err))
-			messagesToSend = s.trimMessagesToLimit(messagesToSend, preFlightLimit, provider.Type)
+			messagesToSend = s.trimMessagesToLimit(messagesToSend, preFlightLimit, provider.EffectiveType())
 			s.logger.Info("Pre-flight trimming applied", zap.Int("original_count", len(messagesToSend)), zap.Int("trimmed_count", len(messagesToSend)))
 		} else {
 			if originalMessagesReplaced {
@@ -508,7 +623,7 @@ func (s *chatService) SendMessage(ctx context.Context, id string, message *entit
 				}
 			}
 			// Replace messagesToSend with compressed version
-			messagesToSend = append([]*entities.Message{systemMessage}, compressedMessages...)
+			messagesToSend = append(slices.Clone(systemMessages), compressedMessages...)
 			s.logger.Info("Pre-flight compression successful",
 				zap.Int("original_count", len(chat.Messages)),
 				zap.Int("compressed_count", len(compressedMessages)),
@@ -516,9 +631,89 @@ func (s *chatService) SendMessage(ctx context.Context, id string, message *entit
 		}
 	}
 
+	messagesToSend = s.assembleContext(messagesToSend, stableSystemMessages, time.Now())
+	messagesToSend = withResponseInstructions(messagesToSend, agent.ResponseFormat)
+	messagesToSend = withSystemInstructions(messagesToSend, agent.OutputLimit.Instructions())
+	if agent.PlanMode {
+		messagesToSend = withSystemInstructions(messagesToSend, planModeInstructions)
+	}
+	// Earlier images can't be sent to a model that has no image support
+	if !model.SupportsImages() {
+		if removed := stripImages(messagesToSend); removed > 0 {
+			s.logger.Warn("Removed images the model cannot read from the request",
+				zap.String("model", model.Name), zap.Int("images", removed))
+		}
+	}
+
+	// Refuse to send when the prompt alone would exceed the chat's budget
+	budgetPricing := provider.GetModelPricing(model.ModelName)
+	if chat.Budget != nil {
+		var estimatedCost float64
+		if budgetPricing != nil {
+			estimatedCost = float64(totalTokens) * budgetPricing.InputPricePerMille / 1000000.0
+		}
+		if reason := chat.Budget.Check(chat.Usage, totalTokens, estimatedCost); reason != "" {
+			events.PublishProcessFailedEvent(entities.NewProcessFailedEvent(chat.ID, reason))
+			return nil, errors.BudgetExceededErrorf("chat budget exceeded: %s", reason)
+		}
+	}
+
+	// Stop a runaway agentic loop mid-run once the budget is spent. The
+	// agent's env goes with the run to the tools that run commands.
+	runCtx, cancelRun := context.WithCancelCause(entities.WithToolEnv(ctx, agent.Env))
+	defer cancelRun(nil)
+
+	// Bound the turn's wall-clock time independently of the caller's deadline
+	if limit := s.maxTurnDuration(); limit > 0 {
+		timer := time.AfterFunc(limit, func() { cancelRun(&turnTimeLimitError{limit: limit}) })
+		defer timer.Stop()
+	}
+	var runTokens int
+	var runCost float64
+	var lastSeenUsage entities.Usage
+
+	// Models still to try when the current one fails, and the name of the
+	// fallback that is answering
+	fallbackChain := slices.Clone(agent.Fallbacks)
+	var answeredBy string
+
 	// Create a callback function for incremental message saving
 	messageCallback := func(messages []*entities.Message) error {
-		return s.SaveMessagesIncrementally(ctx, chat.ID, messages)
+		if answeredBy != "" {
+			for _, msg := range messages {
+				if msg.Role == "assistant" {
+					msg.Model = answeredBy
+				}
+			}
+		}
+		if err := s.SaveMessagesIncrementally(ctx, chat.ID, messages); err != nil {
+			return err
+		}
+
+		// Each provider request reports its own usage, count it once
+		usage, err := aiModel.GetLastUsage()
+		if err != nil || usage == nil || *usage == lastSeenUsage {
+			return nil
+		}
+		lastSeenUsage = *usage
+		runTokens += usage.TotalTokens
+		if budgetPricing != nil {
+			runCost += (float64(usage.PromptTokens)*budgetPricing.InputPricePerMille + float64(usage.CompletionTokens)*budgetPricing.OutputPricePerMille) / 1000000.0
+		}
+		// The run's usage so far, estimated until the response is priced
+		events.PublishChatUpdateEvent(entities.NewChatUpdateEvent(chat.ID, "run_usage", map[string]interface{}{
+			"run_tokens": runTokens,
+			"run_cost":   runCost,
+		}))
+
+		if chat.Budget == nil {
+			return nil
+		}
+		if reason := chat.Budget.Check(chat.Usage, runTokens, runCost); reason != "" {
+			s.logger.Warn("Chat budget exceeded, stopping run", zap.String("chat_id", chat.ID), zap.String("reason", reason))
+			cancelRun(errors.BudgetExceededErrorf("chat budget exceeded: %s", reason))
+		}
+		return nil
 	}
 
 	// Use the callback method for incremental saving with retry logic for context errors
@@ -539,12 +734,58 @@ func (s *chatService) SendMessage(ctx context.Context, id string, message *entit
 			}
 		}
 
-		newMessages, err = aiModel.GenerateResponse(ctx, messagesToSend, tools, options, messageCallback)
+		// Catch tool calls without responses (and vice versa) before the provider rejects them
+		balancedMessages, issues := balanceToolCalls(messagesToSend)
+		if len(issues) > 0 {
+			if s.globalConfig != nil && s.globalConfig.StrictToolCallValidation {
+				return nil, errors.ValidationErrorf("chat history has unbalanced tool calls: %s", strings.Join(issues, "; "))
+			}
+			s.logger.Warn("Repaired unbalanced tool calls before sending",
+				zap.String("chat_id", chat.ID),
+				zap.Strings("issues", issues))
+			messagesToSend = balancedMessages
+		}
+
+		newMessages, err = aiModel.GenerateResponse(runCtx, messagesToSend, tools, options, messageCallback)
 		if err == nil {
+			lastErr = nil
 			break // Success
 		}
 
 		lastErr = err
+		if runCtx.Err() != nil {
+			break // Stopped by the budget or the turn time limit
+		}
+		if len(fallbackChain) > 0 && isFallbackError(ctx, err) {
+			fallback, rest, fbErr := s.nextFallback(ctx, fallbackChain)
+			fallbackChain = rest
+			if fbErr != nil {
+				s.logger.Warn("No usable fallback model", zap.String("chat_id", chat.ID), zap.Error(fbErr))
+			} else {
+				s.logger.Warn("Model request failed, switching to fallback model",
+					zap.String("chat_id", chat.ID),
+					zap.String("from", model.Name),
+					zap.String("to", fallback.model.Name),
+					zap.Error(err))
+				events.PublishChatUpdateEvent(entities.NewChatUpdateEvent(chat.ID, "model", map[string]interface{}{
+					"model_id": fallback.model.ID,
+					"message":  fmt.Sprintf("%s failed, answering with fallback %s", model.Name, fallback.model.Name),
+				}))
+				model, provider, resolvedAPIKey, aiModel = fallback.model, fallback.provider, fallback.apiKey, fallback.aiModel
+				answeredBy = model.Name
+				attempt-- // a fallback does not use up a retry
+				continue
+			}
+		}
+		if _, ok := err.(*errors.RateLimitError); ok && attempt < maxRetries {
+			downgradedModel, downgradedAI, dgErr := s.downgradeModel(ctx, chat, model, provider, resolvedAPIKey, "Rate limited")
+			if dgErr != nil {
+				s.logger.Warn("Rate limited and no model downgrade available", zap.Error(dgErr))
+				break
+			}
+			model, aiModel = downgradedModel, downgradedAI
+			continue
+		}
 		if !isContextError(err) || attempt == maxRetries {
 			break // Not a context error or max retries reached
 		}
@@ -564,10 +805,10 @@ func (s *chatService) SendMessage(ctx context.Context, id string, message *entit
 		}
 
 		// Try compression with progressive targets
-		compressedMessages, originalMessagesReplaced, err := s.compressMessages(ctx, chat, model, provider, resolvedAPIKey, compressionTarget)
+		compressedMessages, originalMessagesReplaced, err := s.compressMessages(ctx, chat, model, provider, resolvedAPIKey, compressionTarget, agent.Compression)
 		if err != nil {
 			s.logger.Warn("Failed progressive compression, using fallback trimming", zap.Error(err), zap.Int("target_tokens", compressionTarget))
-			compressedMessages = s.trimMessagesToLimit(messagesToSend, compressionTarget, provider.Type)
+			compressedMessages = s.trimMessagesToLimit(messagesToSend, compressionTarget, provider.EffectiveType())
 			originalMessagesReplaced = false
 		} else if originalMessagesReplaced {
 			if err := s.chatRepo.UpdateChat(ctx, chat); err != nil {
@@ -605,6 +846,13 @@ func (s *chatService) SendMessage(ctx context.Context, id string, message *entit
 	}
 
 	if lastErr != nil {
+		if budgetErr, ok := context.Cause(runCtx).(*errors.BudgetExceededError); ok {
+			events.PublishProcessFailedEvent(entities.NewProcessFailedEvent(chat.ID, budgetErr.Error()))
+			return nil, budgetErr
+		}
+		if limitErr, ok := context.Cause(runCtx).(*turnTimeLimitError); ok && ctx.Err() == nil {
+			return s.finishTimedOutTurn(ctx, chat.ID, limitErr)
+		}
 		if strings.Contains(lastErr.Error(), "canceled") {
 			// Publish process failed event
 			failedEvent := entities.NewProcessFailedEvent(chat.ID, lastErr.Error())
@@ -619,6 +867,30 @@ func (s *chatService) SendMessage(ctx context.Context, id string, message *entit
 		return nil, errors.InternalErrorf("failed to generate AI response after retries: %v", lastErr)
 	}
 
+	// Move the next turns to the cheaper model while the provider's rate
+	// limits are close to running out
+	if integrations.NearQuota(aiModel) {
+		s.downgradeNearQuota(ctx, chat, model, provider, resolvedAPIKey)
+	}
+
+	// Run the build and tests after file changes until they pass. Plan mode
+	// changes nothing, so there is nothing to verify.
+	if agent.VerifyLoop.Enabled() && !agent.PlanMode {
+		newMessages = s.enforceVerifyLoop(runCtx, chat.ID, chat.Workspace, agent.VerifyLoop, aiModel, messagesToSend, newMessages, tools, options, messageCallback)
+	}
+
+	// Hold structured answers to the agent's response format
+	var formatProblems []string
+	if agent.ResponseFormat.Structured() {
+		newMessages, formatProblems, err = s.repairStructuredResponse(runCtx, chat.ID, agent.ResponseFormat, aiModel, messagesToSend, newMessages, tools, options, messageCallback)
+		if err != nil {
+			s.logger.Warn("Failed to repair structured response", zap.String("chat_id", chat.ID), zap.Error(err))
+		}
+	} else if agent.OutputLimit.Enabled() {
+		// Truncating JSON would break it, so limits only apply to text answers
+		newMessages = s.enforceOutputLimit(runCtx, chat.ID, agent.OutputLimit, aiModel, messagesToSend, newMessages, tools, options, messageCallback)
+	}
+
 	// Get usage information for billing
 	totalUsage, err := aiModel.GetUsage()
 	if err != nil {
@@ -639,10 +911,13 @@ func (s *chatService) SendMessage(ctx context.Context, id string, message *entit
 		s.logger.Warn("No pricing found for model", zap.String("model", model.ModelName), zap.String("provider", provider.Name))
 	}
 
-	// Calculate total cost
-	var totalCost float64
+	// Calculate total cost, crediting prompt cache hits
+	var totalCost, cacheSavings float64
 	if totalUsage != nil {
 		totalCost = (float64(totalUsage.PromptTokens)*inputPricePerMille + float64(totalUsage.CompletionTokens)*outputPricePerMille) / 1000000.0
+		savings, writeCost := modelPricing.CacheCost(totalUsage)
+		cacheSavings = savings
+		totalCost += writeCost - savings
 	}
 
 	// Add usage information to the last message
@@ -650,6 +925,10 @@ func (s *chatService) SendMessage(ctx context.Context, id string, message *entit
 		lastMsg := newMessages[len(newMessages)-1]
 		lastMsg.AddUsage(lastUsage.PromptTokens, lastUsage.CompletionTokens, inputPricePerMille, outputPricePerMille)
 		lastMsg.Usage.Cost = totalCost
+		lastMsg.Usage.ReasoningTokens = lastUsage.ReasoningTokens
+		lastMsg.Usage.CachedTokens = lastUsage.CachedTokens
+		lastMsg.Usage.CacheWriteTokens = lastUsage.CacheWriteTokens
+		lastMsg.Usage.CacheSavings = cacheSavings
 
 		// Update the last message in the chat with usage information
 		currentChat, err := s.chatRepo.GetChat(ctx, chat.ID)
@@ -669,6 +948,8 @@ func (s *chatService) SendMessage(ctx context.Context, id string, message *entit
 				}
 			}
 		}
+
+		s.recordReasoningUsage(ctx, chat.AgentID, model.ReasoningEffort, lastUsage, totalCost)
 	}
 
 	// Check if this is a partial response due to cancellation
@@ -692,12 +973,33 @@ func (s *chatService) SendMessage(ctx context.Context, id string, message *entit
 		s.logger.Warn("Failed to process compression instructions", zap.Error(err))
 	}
 
+	if len(formatProblems) > 0 {
+		reason := "response does not match the required format: " + strings.Join(formatProblems, "; ")
+		events.PublishProcessFailedEvent(entities.NewProcessFailedEvent(chat.ID, reason))
+		return nil, errors.ValidationErrorf("%s", reason)
+	}
+
+	// Hand the held back tool calls to the user as a plan to approve
+	if plan != nil && !isPartialResponse {
+		if steps := plan.Steps(); len(steps) > 0 {
+			planMsg, err := s.savePlan(ctx, chat.ID, steps)
+			if err != nil {
+				s.logger.Warn("Failed to save plan", zap.String("chat_id", chat.ID), zap.Error(err))
+			} else {
+				newMessages = append(newMessages, planMsg)
+			}
+		}
+	}
+
 	// Publish process finished event
 	finishedEvent := entities.NewProcessFinishedEvent(chat.ID)
 	events.PublishProcessFinishedEvent(finishedEvent)
 
 	// Return the last message (final assistant response)
 	if len(newMessages) > 0 {
+		if isDefaultChatName(chat.Name) {
+			s.generateTitleInBackground(chat.ID)
+		}
 		return newMessages[len(newMessages)-1], nil
 	}
 	// Publish process failed event
@@ -707,6 +1009,22 @@ func (s *chatService) SendMessage(ctx context.Context, id string, message *entit
 	return nil, errors.InternalErrorf("no AI response generated")
 }
 
+// countTokens measures messages with the integration's tokenizer, falling
+// back to estimate when it can't count them.
+func (s *chatService) countTokens(aiModel interfaces.AIModelIntegration, messages []*entities.Message, estimate func(*entities.Message) int) int {
+	count, err := aiModel.CountTokens(messages)
+	if err == nil {
+		return count
+	}
+	s.logger.Debug("Falling back to estimated token count", zap.Error(err))
+
+	total := 0
+	for _, msg := range messages {
+		total += estimate(msg)
+	}
+	return total
+}
+
 func estimateTokens(msg *entities.Message) int {
 	if msg == nil {
 		return 0
@@ -744,6 +1062,128 @@ func estimateAnthropicTokens(msg *entities.Message) int {
 	return tokenEstimate
 }
 
+// downgradeModel switches the chat to the cheaper model configured for model
+// after the provider reports rate or quota pressure, for reason. Only models
+// from the same provider are considered so the resolved API key stays valid.
+// The downgrade is saved on the chat, and the preferred model is restored by
+// restoreDowngradedModel once the cooldown has passed.
+func (s *chatService) downgradeModel(ctx context.Context, chat *entities.Chat, model *entities.Model, provider *entities.Provider, apiKey, reason string) (*entities.Model, interfaces.AIModelIntegration, error) {
+	if s.globalConfig == nil || len(s.globalConfig.ModelDowngrades) == 0 {
+		return nil, nil, errors.ValidationErrorf("model downgrades are not configured")
+	}
+	fallbackName, ok := s.globalConfig.ModelDowngrades[model.ModelName]
+	if !ok || fallbackName == "" {
+		return nil, nil, errors.NotFoundErrorf("no downgrade configured for model %s", model.ModelName)
+	}
+
+	models, err := s.modelRepo.GetModelsByProvider(ctx, provider.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var fallback *entities.Model
+	for _, m := range models {
+		if m.ModelName == fallbackName {
+			fallback = m
+			break
+		}
+	}
+	if fallback == nil {
+		return nil, nil, errors.NotFoundErrorf("downgrade model %s not found for provider %s", fallbackName, provider.Name)
+	}
+
+	aiModel, err := s.newModelIntegration(ctx, fallback, provider, apiKey)
+	if err != nil {
+		return nil, nil, errors.InternalErrorf("failed to initialize downgrade model: %v", err)
+	}
+
+	// Downgrading again from the downgrade model keeps the model to go back to
+	preferredModelID := chat.ModelID
+	if chat.Downgrade != nil && chat.Downgrade.ModelID == chat.ModelID {
+		preferredModelID = chat.Downgrade.PreferredModelID
+	}
+	chat.Downgrade = &entities.ModelDowngrade{
+		PreferredModelID: preferredModelID,
+		ModelID:          fallback.ID,
+		Until:            time.Now().Add(s.downgradeCooldown()),
+	}
+	chat.ModelID = fallback.ID
+	if err := s.chatRepo.UpdateChat(ctx, chat); err != nil {
+		s.logger.Warn("Failed to save downgraded model", zap.Error(err))
+	}
+
+	s.logger.Warn(reason+", downgrading model",
+		zap.String("chat_id", chat.ID),
+		zap.String("from", model.ModelName),
+		zap.String("to", fallback.ModelName))
+	events.PublishChatUpdateEvent(entities.NewChatUpdateEvent(chat.ID, "model", map[string]interface{}{
+		"model_id": fallback.ID,
+		"message":  fmt.Sprintf("%s on %s, switched to %s", reason, model.Name, fallback.Name),
+	}))
+
+	return fallback, aiModel, nil
+}
+
+// downgradeNearQuota switches the chat to the cheaper model for its next
+// turns when the provider's rate limit headers show little remaining. On the
+// downgrade model already, it puts off going back instead.
+func (s *chatService) downgradeNearQuota(ctx context.Context, chat *entities.Chat, model *entities.Model, provider *entities.Provider, apiKey string) {
+	if chat.Downgrade != nil && chat.Downgrade.ModelID == chat.ModelID {
+		chat.Downgrade.Until = time.Now().Add(s.downgradeCooldown())
+		if err := s.chatRepo.UpdateChat(ctx, chat); err != nil {
+			s.logger.Warn("Failed to save model downgrade", zap.Error(err))
+		}
+		return
+	}
+	if _, _, err := s.downgradeModel(ctx, chat, model, provider, apiKey, "Near the rate limit"); err != nil {
+		s.logger.Debug("Near the rate limit and no model downgrade available", zap.String("chat_id", chat.ID), zap.Error(err))
+	}
+}
+
+// downgradeCooldown is how long a chat stays on the downgrade model.
+func (s *chatService) downgradeCooldown() time.Duration {
+	if s.globalConfig != nil && s.globalConfig.DowngradeCooldownMinutes > 0 {
+		return time.Duration(s.globalConfig.DowngradeCooldownMinutes) * time.Minute
+	}
+	return 15 * time.Minute
+}
+
+// restoreDowngradedModel switches a chat back to its preferred model once the
+// downgrade cooldown has passed. A model the user picked since is kept.
+func (s *chatService) restoreDowngradedModel(ctx context.Context, chat *entities.Chat) {
+	downgrade := chat.Downgrade
+	if downgrade == nil || time.Now().Before(downgrade.Until) {
+		return
+	}
+	chat.Downgrade = nil
+	if chat.ModelID != downgrade.ModelID {
+		if err := s.chatRepo.UpdateChat(ctx, chat); err != nil {
+			s.logger.Warn("Failed to clear model downgrade", zap.Error(err))
+		}
+		return
+	}
+
+	preferred, err := s.modelRepo.GetModel(ctx, downgrade.PreferredModelID)
+	if err != nil {
+		s.logger.Warn("Failed to restore preferred model", zap.String("model_id", downgrade.PreferredModelID), zap.Error(err))
+		if err := s.chatRepo.UpdateChat(ctx, chat); err != nil {
+			s.logger.Warn("Failed to clear model downgrade", zap.Error(err))
+		}
+		return
+	}
+
+	chat.ModelID = preferred.ID
+	if err := s.chatRepo.UpdateChat(ctx, chat); err != nil {
+		s.logger.Warn("Failed to save restored model", zap.Error(err))
+		return
+	}
+
+	s.logger.Info("Restored preferred model", zap.String("chat_id", chat.ID), zap.String("model", preferred.ModelName))
+	events.PublishChatUpdateEvent(entities.NewChatUpdateEvent(chat.ID, "model", map[string]interface{}{
+		"model_id": preferred.ID,
+		"message":  fmt.Sprintf("Rate limits cleared, switched back to %s", preferred.Name),
+	}))
+}
+
 // isContextError checks if an error is related to context window limits
 func isContextError(err error) bool {
 	if err == nil {
@@ -1018,14 +1458,12 @@ func (s *chatService) createSummaryFromMessages(ctx context.Context, messages []
 		return nil, fmt.Errorf("failed to get provider: %v", err)
 	}
 
-	apiKeyReference := "#{" + provider.APIKeyName + "}#"
-	apiKey, err := s.config.ResolveEnvironmentVariable(apiKeyReference)
+	apiKey, err := s.providerAPIKey(provider)
 	if err != nil {
 		return nil, fmt.Errorf("failed to resolve API key: %v", err)
 	}
 
-	aiModelFactory := integrations.NewAIModelFactory(s.toolRepo, s.logger)
-	aiModel, err := aiModelFactory.CreateModelIntegration(model, provider, apiKey)
+	aiModel, err := s.newModelIntegration(ctx, model, provider, apiKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create AI model: %v", err)
 	}
@@ -1195,7 +1633,8 @@ func (s *chatService) buildFullResetPrompt(analysis map[string]int) string {
 	return prompt
 }
 
-// compressMessages summarizes older messages to reduce token count while preserving context
+// compressMessages shortens the chat history with the agent's compression
+// strategy, by default summarizing older messages while preserving context.
 // Returns the compressed messages, a flag indicating if the chat messages were replaced, and any error
 func (s *chatService) compressMessages(
 	ctx context.Context,
@@ -1204,10 +1643,11 @@ func (s *chatService) compressMessages(
 	provider *entities.Provider,
 	apiKey string,
 	tokenLimit int,
+	compression *entities.CompressionConfig,
 ) ([]*entities.Message, bool, error) {
 	// Use provider-specific token estimation
 	var estimateFunc func(*entities.Message) int = estimateTokens
-	if provider.Type == entities.ProviderAnthropic {
+	if provider.EffectiveType() == entities.ProviderAnthropic {
 		estimateFunc = estimateAnthropicTokens
 	}
 	// Calculate current total tokens to determine compression aggressiveness
@@ -1217,40 +1657,29 @@ func (s *chatService) compressMessages(
 	}
 
 	// If we're way over the limit, be more aggressive with compression
-	compressionRatio := 0.5 // Default: keep 50% of messages
-	if currentTokens > tokenLimit*2 {
-		compressionRatio = 0.3 // If 2x over limit, keep only 30%
-	} else if currentTokens > int(float64(tokenLimit)*1.5) {
-		compressionRatio = 0.4 // If 1.5x over limit, keep 40%
+	compressionRatio := compression.KeepRatioFor(currentTokens, tokenLimit)
+	strategy := compression.EffectiveStrategy()
+	if strategy == entities.CompressionTruncateOldest {
+		kept := truncateOldest(chat.Messages, int(float64(tokenLimit)*compressionRatio), estimateFunc)
+		if kept == nil {
+			s.logger.Warn("No safe split point found; skipping compression to avoid unbalanced messages")
+			return nil, false, nil
+		}
+		s.logger.Debug("Truncated oldest messages",
+			zap.Int("messages_total", len(chat.Messages)),
+			zap.Int("messages_kept", len(kept)))
+		return kept, false, nil
 	}
 
-	numMessagesToKeep := int(float64(len(chat.Messages)) * compressionRatio)
-	if numMessagesToKeep < 1 {
-		numMessagesToKeep = 1 // Always keep at least the most recent message
-	}
+	summarizeEndIdx, safeFound := summarizeSplit(chat.Messages, compressionRatio)
 
 	s.logger.Debug("Compression calculation",
 		zap.Int("current_tokens", currentTokens),
 		zap.Int("token_limit", tokenLimit),
 		zap.Float64("compression_ratio", compressionRatio),
 		zap.Int("messages_total", len(chat.Messages)),
-		zap.Int("messages_to_keep", numMessagesToKeep))
-
-	// Tentative split point
-	summarizeEndIdx := len(chat.Messages) - numMessagesToKeep
-	if summarizeEndIdx < 1 {
-		summarizeEndIdx = 1 // Ensure we have at least one message to summarize
-	}
+		zap.Int("messages_to_summarize", summarizeEndIdx))
 
-	// Find the largest safe split point <= summarizeEndIdx
-	safeFound := false
-	for j := summarizeEndIdx; j >= 1; j-- { // Require at least 1 message to summarize
-		if isSafeSplit(chat.Messages, j) {
-			summarizeEndIdx = j
-			safeFound = true
-			break
-		}
-	}
 	if !safeFound {
 		s.logger.Warn("No safe split point found; skipping compression to avoid unbalanced messages")
 		return nil, false, nil // Return nil to indicate skipping, no error
@@ -1259,9 +1688,12 @@ func (s *chatService) compressMessages(
 	messagesToSummarize := chat.Messages[:summarizeEndIdx]
 	recentMessagesToKeep := chat.Messages[summarizeEndIdx:]
 
+	if strategy == entities.CompressionSlidingWindow {
+		return messagePointers(recentMessagesToKeep), false, nil
+	}
+
 	// Create AI model for summarization, using model for context window
-	aiModelFactory := integrations.NewAIModelFactory(s.toolRepo, s.logger)
-	aiModel, err := aiModelFactory.CreateModelIntegration(model, provider, apiKey)
+	aiModel, err := s.newModelIntegration(ctx, model, provider, apiKey)
 	if err != nil {
 		return nil, false, errors.InternalErrorf("failed to initialize AI model for summarization: %v", err)
 	}
@@ -1309,6 +1741,9 @@ func (s *chatService) compressMessages(
 	var totalPrompt, totalCompletion, totalTokens int
 	var totalCost float64
 	for _, msg := range messagesToSummarize {
+		if msg.Usage == nil {
+			continue
+		}
 		totalPrompt += msg.Usage.PromptTokens
 		totalCompletion += msg.Usage.CompletionTokens
 		totalTokens += msg.Usage.TotalTokens
@@ -1319,7 +1754,7 @@ func (s *chatService) compressMessages(
 	summaryMsg := &entities.Message{
 		ID:        uuid.New().String(),
 		Role:      "assistant",
-		Content:   "Summary of previous conversation: " + summaryResponse[0].Content,
+		Content:   summaryPrefix + summaryResponse[0].Content,
 		Timestamp: time.Now(),
 		Usage: &entities.Usage{
 			PromptTokens:     totalPrompt,
@@ -1350,6 +1785,66 @@ func (s *chatService) compressMessages(
 	return finalMessages, true, nil
 }
 
+// summarizeSplit returns the index of the first message kept when the
+// history is compressed keeping keepRatio of its messages; the ones before it
+// are summarized. The split is moved back to where no tool call is separated
+// from its result, and is false when no such point leaves a message to
+// summarize.
+func summarizeSplit(messages []entities.Message, keepRatio float64) (int, bool) {
+	numMessagesToKeep := int(float64(len(messages)) * keepRatio)
+	if keepRatio == entities.KeepLatest || numMessagesToKeep < 1 {
+		numMessagesToKeep = 1 // Always keep at least the most recent message
+	}
+
+	// Tentative split point
+	summarizeEndIdx := len(messages) - numMessagesToKeep
+	if summarizeEndIdx < 1 {
+		summarizeEndIdx = 1 // Ensure we have at least one message to summarize
+	}
+
+	// Find the largest safe split point <= summarizeEndIdx
+	for j := summarizeEndIdx; j >= 1; j-- { // Require at least 1 message to summarize
+		if isSafeSplit(messages, j) {
+			return j, true
+		}
+	}
+	return 0, false
+}
+
+// truncateOldest drops the oldest messages, never separating a tool call
+// from its result, until the rest fit within budget tokens. The newest
+// message is always kept. It returns nil when no split is safe.
+func truncateOldest(messages []entities.Message, budget int, estimate func(*entities.Message) int) []*entities.Message {
+	remaining := 0
+	for i := range messages {
+		remaining += estimate(&messages[i])
+	}
+
+	split := -1
+	for i := range messages {
+		if isSafeSplit(messages, i) {
+			split = i
+			if remaining <= budget {
+				break
+			}
+		}
+		remaining -= estimate(&messages[i])
+	}
+	if split == -1 {
+		return nil
+	}
+	return messagePointers(messages[split:])
+}
+
+// messagePointers points into messages without copying them.
+func messagePointers(messages []entities.Message) []*entities.Message {
+	pointers := make([]*entities.Message, len(messages))
+	for i := range messages {
+		pointers[i] = &messages[i]
+	}
+	return pointers
+}
+
 // trimMessagesToLimit removes oldest messages until under token limit
 func (s *chatService) trimMessagesToLimit(messages []*entities.Message, maxTokens int, providerType entities.ProviderType) []*entities.Message {
 	if messages == nil || len(messages) <= 1 {
@@ -1429,6 +1924,80 @@ func (s *chatService) ensureToolCallResponses(messages []*entities.Message) []*e
 	return messages
 }
 
+// balanceToolCalls checks that every assistant tool call is directly followed
+// by its tool response and every tool response belongs to a call. Misplaced
+// responses are moved after their call, missing responses are filled with an
+// error result and orphaned responses are dropped. It returns the repaired
+// messages and a description of each problem found.
+func balanceToolCalls(messages []*entities.Message) ([]*entities.Message, []string) {
+	responses := make(map[string]*entities.Message)
+	for _, msg := range messages {
+		if msg.Role == "tool" && msg.ToolCallID != "" {
+			if _, exists := responses[msg.ToolCallID]; !exists {
+				responses[msg.ToolCallID] = msg
+			}
+		}
+	}
+
+	var issues []string
+	used := make(map[*entities.Message]bool)
+	result := make([]*entities.Message, 0, len(messages))
+
+	for i, msg := range messages {
+		if msg.Role == "tool" {
+			if !used[msg] {
+				issues = append(issues, fmt.Sprintf("tool response %q at position %d has no matching tool call", msg.ToolCallID, i))
+			}
+			continue
+		}
+
+		result = append(result, msg)
+		if msg.Role != "assistant" || len(msg.ToolCalls) == 0 {
+			continue
+		}
+
+		// The responses for a call must directly follow the assistant message
+		following := make(map[*entities.Message]bool)
+		for j := i + 1; j < len(messages) && messages[j].Role == "tool"; j++ {
+			following[messages[j]] = true
+		}
+		for _, toolCall := range msg.ToolCalls {
+			response, ok := responses[toolCall.ID]
+			if !ok || used[response] {
+				issues = append(issues, fmt.Sprintf("tool call %q (%s) has no response", toolCall.ID, toolCall.Function.Name))
+				response = &entities.Message{
+					ID:         uuid.New().String(),
+					Role:       "tool",
+					Content:    "Tool execution failed: No response generated",
+					ToolCallID: toolCall.ID,
+					Timestamp:  time.Now(),
+				}
+			} else if !following[response] {
+				issues = append(issues, fmt.Sprintf("tool response %q is not directly after its tool call", toolCall.ID))
+			}
+			used[response] = true
+			result = append(result, response)
+		}
+	}
+
+	return result, issues
+}
+
+// providerAPIKey resolves the provider's API key from the environment.
+func (s *chatService) providerAPIKey(provider *entities.Provider) (string, error) {
+	return providerAPIKey(s.config, provider)
+}
+
+// providerAPIKey resolves the provider's API key through cfg, which also
+// sees the keys loaded from the .env file. Providers without a key name,
+// such as a local Ollama, have no key.
+func providerAPIKey(cfg *config.Config, provider *entities.Provider) (string, error) {
+	if provider.APIKeyName == "" {
+		return "", nil
+	}
+	return cfg.ResolveEnvironmentVariable("#{" + provider.APIKeyName + "}#")
+}
+
 // CalculateTotalChatCost calculates the total cost of all messages in a chat
 func (s *chatService) CalculateTotalChatCost(ctx context.Context, chatID string) (float64, error) {
 	if chatID == "" {
@@ -1447,6 +2016,431 @@ func (s *chatService) CalculateTotalChatCost(ctx context.Context, chatID string)
 	return chat.Usage.TotalCost, nil
 }
 
+// ContextUsage estimates the tokens the chat's next request starts with,
+// counted the way SendMessage counts them to decide on compression.
+func (s *chatService) ContextUsage(ctx context.Context, chatID string) (*entities.ContextUsage, error) {
+	if chatID == "" {
+		return nil, errors.ValidationErrorf("chat ID is required")
+	}
+
+	chat, err := s.chatRepo.GetChat(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	model, err := s.modelRepo.GetModel(ctx, chat.ModelID)
+	if err != nil {
+		return nil, err
+	}
+	agent, err := s.agentService.GetAgent(ctx, chat.AgentID)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := s.providerRepo.GetProvider(ctx, model.ProviderID)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &entities.ContextUsage{Limit: contextWindow(model), TriggerRatio: agent.Compression.TriggerRatio()}
+
+	messages := s.systemMessages(s.systemLayers(ctx, chat, agent.FullSystemPrompt()))
+	for i := range chat.Messages {
+		messages = append(messages, &chat.Messages[i])
+	}
+
+	estimate := estimateTokens
+	if provider.EffectiveType() == entities.ProviderAnthropic {
+		estimate = estimateAnthropicTokens
+	}
+
+	// Tokenizers are local, so a missing API key only matters if the
+	// integration refuses to be created without one
+	apiKey, _ := s.providerAPIKey(provider)
+	aiModel, err := s.newModelIntegration(ctx, model, provider, apiKey)
+	if err != nil {
+		for _, msg := range messages {
+			usage.Tokens += estimate(msg)
+		}
+		return usage, nil
+	}
+	usage.Tokens = s.countTokens(aiModel, messages, estimate)
+	return usage, nil
+}
+
+// SetBudget sets or, when budget is nil, removes the chat's spending limits.
+func (s *chatService) SetBudget(ctx context.Context, chatID string, budget *entities.ChatBudget) error {
+	if chatID == "" {
+		return errors.ValidationErrorf("chat ID is required")
+	}
+	if budget != nil && (budget.MaxCost < 0 || budget.MaxTokens < 0) {
+		return errors.ValidationErrorf("budget limits must not be negative")
+	}
+	if budget != nil && budget.MaxCost == 0 && budget.MaxTokens == 0 {
+		budget = nil
+	}
+
+	chat, err := s.chatRepo.GetChat(ctx, chatID)
+	if err != nil {
+		return err
+	}
+
+	chat.Budget = budget
+	chat.UpdatedAt = time.Now()
+	return s.chatRepo.UpdateChat(ctx, chat)
+}
+
+// SetInstructions sets the chat's own instructions, the last layer of its
+// system prompt. Empty instructions remove them.
+func (s *chatService) SetInstructions(ctx context.Context, chatID string, instructions string) error {
+	if chatID == "" {
+		return errors.ValidationErrorf("chat ID is required")
+	}
+
+	chat, err := s.chatRepo.GetChat(ctx, chatID)
+	if err != nil {
+		return err
+	}
+
+	chat.Instructions = strings.TrimSpace(instructions)
+	chat.UpdatedAt = time.Now()
+	return s.chatRepo.UpdateChat(ctx, chat)
+}
+
+// SetToolsOverride sets the tools the chat uses in place of its agent's. A nil
+// list goes back to the agent's tools and an empty list turns tools off.
+func (s *chatService) SetToolsOverride(ctx context.Context, chatID string, tools []string) error {
+	if chatID == "" {
+		return errors.ValidationErrorf("chat ID is required")
+	}
+	for _, name := range tools {
+		if tool, err := s.toolRepo.GetToolByName(name); err != nil || tool == nil {
+			return errors.ValidationErrorf("unknown tool: %s", name)
+		}
+	}
+
+	chat, err := s.chatRepo.GetChat(ctx, chatID)
+	if err != nil {
+		return err
+	}
+
+	chat.ToolsOverride = slices.Clone(tools)
+	chat.UpdatedAt = time.Now()
+	return s.chatRepo.UpdateChat(ctx, chat)
+}
+
+// ClearContext starts the chat over from a summary of its history. The chat,
+// its usage and the latest message are kept; everything before it is
+// replaced by the summary, split where no tool call loses its result.
+func (s *chatService) ClearContext(ctx context.Context, chatID string) (*entities.Chat, error) {
+	if chatID == "" {
+		return nil, errors.ValidationErrorf("chat ID is required")
+	}
+	if s.responding(chatID) {
+		return nil, errors.ValidationErrorf("a response is being generated for this chat")
+	}
+
+	chat, err := s.chatRepo.GetChat(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if len(chat.Messages) < 2 {
+		return nil, errors.ValidationErrorf("the chat has no history to clear")
+	}
+
+	model, err := s.modelRepo.GetModel(ctx, chat.ModelID)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := s.providerRepo.GetProvider(ctx, model.ProviderID)
+	if err != nil {
+		return nil, errors.InternalErrorf("failed to get provider for model %s: %v", chat.ModelID, err)
+	}
+	apiKey, err := s.providerAPIKey(provider)
+	if err != nil {
+		return nil, errors.InternalErrorf("failed to resolve API key for provider %s: %v", provider.ID, err)
+	}
+
+	tokenLimit := contextWindow(model)
+
+	fresh := &entities.CompressionConfig{Strategy: entities.CompressionSummarize, KeepRatio: entities.KeepLatest}
+	_, replaced, err := s.compressMessages(ctx, chat, model, provider, apiKey, tokenLimit, fresh)
+	if err != nil {
+		return nil, err
+	}
+	if !replaced {
+		return nil, errors.ValidationErrorf("no point in the history can be summarized without splitting a tool call")
+	}
+
+	chat.UpdatedAt = time.Now()
+	if err := s.chatRepo.UpdateChat(ctx, chat); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Cleared chat context", zap.String("chat_id", chat.ID), zap.Int("messages_kept", len(chat.Messages)))
+	return chat, nil
+}
+
+// CompactChat compresses the chat's history now rather than when it reaches
+// the compression trigger. Older messages are summarized, keeping the share
+// the agent's compression settings keep. Strategies that only truncate what
+// is sent would leave the chat as it is, so compacting always summarizes.
+func (s *chatService) CompactChat(ctx context.Context, chatID string) error {
+	if s.responding(chatID) {
+		return errors.ValidationErrorf("a response is being generated for this chat")
+	}
+	chat, agent, model, provider, err := s.compactionTarget(ctx, chatID)
+	if err != nil {
+		return err
+	}
+	apiKey, err := s.providerAPIKey(provider)
+	if err != nil {
+		return errors.InternalErrorf("failed to resolve API key for provider %s: %v", provider.ID, err)
+	}
+
+	before := len(chat.Messages)
+	_, replaced, err := s.compressMessages(ctx, chat, model, provider, apiKey, contextWindow(model), compactionConfig(agent))
+	if err != nil {
+		return err
+	}
+	if !replaced {
+		return errors.ValidationErrorf("no point in the history can be summarized without splitting a tool call")
+	}
+
+	chat.UpdatedAt = time.Now()
+	if err := s.chatRepo.UpdateChat(ctx, chat); err != nil {
+		return err
+	}
+	s.logger.Info("Compacted chat",
+		zap.String("chat_id", chat.ID),
+		zap.Int("messages_before", before),
+		zap.Int("messages_kept", len(chat.Messages)))
+	return nil
+}
+
+// PreviewCompaction reports which messages CompactChat would summarize,
+// without changing the chat or calling the model.
+func (s *chatService) PreviewCompaction(ctx context.Context, chatID string) (*entities.CompactionPreview, error) {
+	chat, agent, model, provider, err := s.compactionTarget(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	estimate := estimateTokens
+	if provider.EffectiveType() == entities.ProviderAnthropic {
+		estimate = estimateAnthropicTokens
+	}
+	tokens := 0
+	for i := range chat.Messages {
+		tokens += estimate(&chat.Messages[i])
+	}
+
+	keepRatio := compactionConfig(agent).KeepRatioFor(tokens, contextWindow(model))
+	end, ok := summarizeSplit(chat.Messages, keepRatio)
+	if !ok {
+		return nil, errors.ValidationErrorf("no point in the history can be summarized without splitting a tool call")
+	}
+
+	preview := &entities.CompactionPreview{To: end, Total: len(chat.Messages)}
+	for i := range chat.Messages[:end] {
+		preview.SummarizedTokens += estimate(&chat.Messages[i])
+	}
+	return preview, nil
+}
+
+// compactionTarget loads the chat to compact with its agent, model and
+// provider.
+func (s *chatService) compactionTarget(ctx context.Context, chatID string) (*entities.Chat, *entities.Agent, *entities.Model, *entities.Provider, error) {
+	if chatID == "" {
+		return nil, nil, nil, nil, errors.ValidationErrorf("chat ID is required")
+	}
+
+	chat, err := s.chatRepo.GetChat(ctx, chatID)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if len(chat.Messages) < 2 {
+		return nil, nil, nil, nil, errors.ValidationErrorf("the chat has no history to compact")
+	}
+	agent, err := s.agentRepo.GetAgent(ctx, chat.AgentID)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	model, err := s.modelRepo.GetModel(ctx, chat.ModelID)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	provider, err := s.providerRepo.GetProvider(ctx, model.ProviderID)
+	if err != nil {
+		return nil, nil, nil, nil, errors.InternalErrorf("failed to get provider for model %s: %v", chat.ModelID, err)
+	}
+	return chat, agent, model, provider, nil
+}
+
+// compactionConfig summarizes with the agent's keep ratio.
+func compactionConfig(agent *entities.Agent) *entities.CompressionConfig {
+	compression := &entities.CompressionConfig{Strategy: entities.CompressionSummarize}
+	if agent.Compression != nil {
+		compression.KeepRatio = agent.Compression.KeepRatio
+	}
+	return compression
+}
+
+// defaultContextWindow is assumed for models whose context window isn't
+// known.
+const defaultContextWindow = 128000
+
+// contextWindow is the model's context window, or defaultContextWindow when
+// it isn't known.
+func contextWindow(model *entities.Model) int {
+	if model.ContextWindow != nil {
+		return *model.ContextWindow
+	}
+	return defaultContextWindow
+}
+
+// ExportChat renders a chat as "markdown" or "json" for sharing or documentation.
+func (s *chatService) ExportChat(ctx context.Context, chatID string, format string) ([]byte, error) {
+	if chatID == "" {
+		return nil, errors.ValidationErrorf("chat ID is required")
+	}
+
+	chat, err := s.chatRepo.GetChat(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	chat.UpdateUsage()
+
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(chat, "", "  ")
+		if err != nil {
+			return nil, errors.InternalErrorf("failed to marshal chat: %v", err)
+		}
+		return data, nil
+	case "markdown", "md", "":
+		return []byte(renderChatMarkdown(chat)), nil
+	default:
+		return nil, errors.ValidationErrorf("unsupported export format: %s", format)
+	}
+}
+
+// recordReasoningUsage adds the response's usage to the agent's reasoning
+// effort stats so the effort setting can be tuned from real cost data.
+func (s *chatService) recordReasoningUsage(ctx context.Context, agentID, effort string, usage *entities.Usage, cost float64) {
+	if effort == "" || effort == "none" || usage == nil {
+		return
+	}
+
+	agent, err := s.agentRepo.GetAgent(ctx, agentID)
+	if err != nil {
+		s.logger.Warn("Failed to get agent for reasoning stats", zap.String("agent_id", agentID), zap.Error(err))
+		return
+	}
+
+	agent.RecordReasoningUsage(effort, usage, cost)
+	if err := s.agentRepo.UpdateAgent(ctx, agent); err != nil {
+		s.logger.Warn("Failed to save reasoning stats", zap.String("agent_id", agentID), zap.Error(err))
+		return
+	}
+
+	if recommendation := agent.ReasoningRecommendation(); recommendation != "" {
+		s.logger.Info("Reasoning effort recommendation", zap.String("agent", agent.Name), zap.String("recommendation", recommendation))
+	}
+}
+
+// renderChatMarkdown renders the chat messages in order with role headings,
+// tool calls as fenced blocks and a usage summary at the bottom.
+func renderChatMarkdown(chat *entities.Chat) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("# %s\n\n", chat.Name))
+	b.WriteString(fmt.Sprintf("_Created %s_\n\n", chat.CreatedAt.Format("2006-01-02 15:04:05")))
+
+	for _, msg := range chat.Messages {
+		timestamp := msg.Timestamp.Format("2006-01-02 15:04:05")
+		switch {
+		case msg.Role == "assistant" && strings.HasPrefix(msg.Content, "Summary of previous conversation: "):
+			b.WriteString(fmt.Sprintf("## Summary (%s)\n\n", timestamp))
+			b.WriteString("> Earlier messages were compressed into this summary.\n\n")
+			b.WriteString(strings.TrimPrefix(msg.Content, "Summary of previous conversation: "))
+			b.WriteString("\n\n")
+			continue
+		case msg.Role == "tool":
+			b.WriteString(fmt.Sprintf("### Tool result (%s)\n\n", timestamp))
+		case msg.Role == "":
+			b.WriteString(fmt.Sprintf("## Message (%s)\n\n", timestamp))
+		default:
+			b.WriteString(fmt.Sprintf("## %s (%s)\n\n", strings.ToUpper(msg.Role[:1])+msg.Role[1:], timestamp))
+		}
+
+		if msg.Role == "tool" {
+			diff := ""
+			for _, event := range msg.ToolCallEvents {
+				if event.Diff != "" {
+					diff = event.Diff
+				}
+			}
+			if diff != "" {
+				b.WriteString("```diff\n" + strings.TrimRight(diff, "\n") + "\n```\n\n")
+			} else if msg.Content != "" {
+				b.WriteString("```\n" + strings.TrimRight(msg.Content, "\n") + "\n```\n\n")
+			}
+			continue
+		}
+
+		if msg.Content != "" {
+			b.WriteString(msg.Content)
+			b.WriteString("\n\n")
+		}
+		for _, toolCall := range msg.ToolCalls {
+			b.WriteString(fmt.Sprintf("**Tool call:** `%s`\n\n", toolCall.Function.Name))
+			arguments := toolCall.Function.Arguments
+			var pretty bytes.Buffer
+			if json.Indent(&pretty, []byte(arguments), "", "  ") == nil {
+				arguments = pretty.String()
+			}
+			b.WriteString("```json\n" + arguments + "\n```\n\n")
+		}
+	}
+
+	if chat.Usage != nil {
+		b.WriteString("---\n\n")
+		b.WriteString("## Usage\n\n")
+		b.WriteString(fmt.Sprintf("- Prompt tokens: %d\n", chat.Usage.TotalPromptTokens))
+		b.WriteString(fmt.Sprintf("- Completion tokens: %d\n", chat.Usage.TotalCompletionTokens))
+		b.WriteString(fmt.Sprintf("- Total tokens: %d\n", chat.Usage.TotalTokens))
+		b.WriteString(fmt.Sprintf("- Cost: $%.4f\n", chat.Usage.TotalCost))
+	}
+
+	return b.String()
+}
+
+// isDefaultChatName reports whether a chat still has the name it was
+// created with, so it can be given a generated title.
+func isDefaultChatName(name string) bool {
+	return strings.HasPrefix(name, "New Chat")
+}
+
+// autoTitlesEnabled reports whether chats are titled automatically.
+func (s *chatService) autoTitlesEnabled() bool {
+	return s.globalConfig == nil || !s.globalConfig.DisableAutoTitles
+}
+
+// generateTitleInBackground titles a chat without holding up the response.
+func (s *chatService) generateTitleInBackground(chatID string) {
+	if !s.autoTitlesEnabled() {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if _, err := s.GenerateAndUpdateTitle(ctx, chatID); err != nil {
+			s.logger.Warn("Failed to generate title", zap.String("chat_id", chatID), zap.Error(err))
+		}
+	}()
+}
+
+// GenerateAndUpdateTitle asks the model for a short title for a chat that
+// still has its default name and publishes a "title" chat update. Chats that
+// were renamed, or every chat when auto titles are disabled, are returned
+// unchanged.
 func (s *chatService) GenerateAndUpdateTitle(ctx context.Context, chatID string) (*entities.Chat, error) {
 	s.logger.Info("Starting title generation", zap.String("chat_id", chatID))
 	chat, err := s.chatRepo.GetChat(ctx, chatID)
@@ -1454,37 +2448,41 @@ func (s *chatService) GenerateAndUpdateTitle(ctx context.Context, chatID string)
 		s.logger.Error("Failed to get chat for title generation", zap.Error(err))
 		return nil, err
 	}
+	if !s.autoTitlesEnabled() || !isDefaultChatName(chat.Name) {
+		return chat, nil
+	}
 	if len(chat.Messages) < 1 {
 		s.logger.Warn("Not enough messages for title generation", zap.Int("message_count", len(chat.Messages)))
 		return nil, fmt.Errorf("not enough messages")
 	}
 
-	// Get first user message only (not assistant response)
-	var firstUser string
+	// The first user message and the answer to it
+	var firstUser, firstAssistant string
 	for _, msg := range chat.Messages {
-		if msg.Role == "user" && firstUser == "" {
+		switch {
+		case msg.Role == "user" && firstUser == "":
 			firstUser = msg.Content
+		case msg.Role == "assistant" && firstUser != "" && msg.Content != "":
+			firstAssistant = msg.Content
+		}
+		if firstAssistant != "" {
 			break
 		}
 	}
-
-	userMsgPreview := firstUser
-	if len(userMsgPreview) > 50 {
-		userMsgPreview = userMsgPreview[:50] + "..."
-	}
+	firstAssistant = truncateRunes(firstAssistant, 500)
+	userMsgPreview := truncateRunes(firstUser, 50)
 	s.logger.Info("Extracting conversation preview for title", zap.String("user_msg", userMsgPreview))
 
-	// Generate title using AI based on user request only
-	prompt := fmt.Sprintf(`You are naming a chat conversation. Create a brief, descriptive title (max 60 chars) for this conversation based on the user's first message.
+	prompt := fmt.Sprintf(`You are naming a chat conversation. Reply with only a title of 3 to 6 words summarizing what the conversation is about.
 
 Guidelines:
-- Focus on the topic or intent of the message
-- Use conversational, friendly language
+- Focus on the topic or intent of the conversation
 - For simple greetings like "hello" or "hi", use titles like "Greeting" or "Hello"
-- Keep it under 60 characters
+- No quotes and no trailing punctuation
 - Make it suitable for a chat sidebar
 
-User's first message: %s`, firstUser)
+User: %s
+Assistant: %s`, firstUser, firstAssistant)
 
 	title, err := s.generateTitleWithAI(ctx, chat.ModelID, prompt)
 	if err != nil {
@@ -1494,17 +2492,42 @@ User's first message: %s`, firstUser)
 
 	s.logger.Info("Generated title", zap.String("title", title))
 
-	// Update chat title
-	updatedChat, err := s.UpdateChat(ctx, chatID, chat.AgentID, chat.ModelID, title)
+	// Reload the chat under its lock, so messages saved and renames made
+	// while the title was generated are kept
+	unlock := s.lockChat(chatID)
+	updatedChat, err := s.chatRepo.GetChat(ctx, chatID)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	if !isDefaultChatName(updatedChat.Name) {
+		unlock()
+		return updatedChat, nil
+	}
+	updatedChat.Name = title
+	updatedChat.UpdatedAt = time.Now()
+	err = s.chatRepo.UpdateChat(ctx, updatedChat)
+	unlock()
 	if err != nil {
 		s.logger.Error("Failed to update chat title", zap.Error(err))
 		return nil, err
 	}
 
+	events.PublishChatUpdateEvent(entities.NewChatUpdateEvent(chatID, "title", map[string]interface{}{
+		"name": title,
+	}))
 	s.logger.Info("Chat title updated successfully", zap.String("chat_id", chatID), zap.String("title", title))
 	return updatedChat, nil
 }
 
+// truncateRunes cuts s to at most n characters, marking the cut with "...".
+func truncateRunes(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n]) + "..."
+	}
+	return s
+}
+
 func (s *chatService) generateTitleWithAI(ctx context.Context, modelID, prompt string) (string, error) {
 	s.logger.Info("Generating title with AI", zap.String("model", modelID))
 
@@ -1520,15 +2543,13 @@ func (s *chatService) generateTitleWithAI(ctx context.Context, modelID, prompt s
 		return s.generateFallbackTitle(prompt), nil
 	}
 
-	apiKeyReference := "#{" + provider.APIKeyName + "}#"
-	apiKey, err := s.config.ResolveEnvironmentVariable(apiKeyReference)
+	apiKey, err := s.providerAPIKey(provider)
 	if err != nil {
 		s.logger.Error("Failed to resolve API key for title generation", zap.Error(err))
 		return s.generateFallbackTitle(prompt), nil
 	}
 
-	aiModelFactory := integrations.NewAIModelFactory(s.toolRepo, s.logger)
-	aiModel, err := aiModelFactory.CreateModelIntegration(model, provider, apiKey)
+	aiModel, err := s.newModelIntegration(ctx, model, provider, apiKey)
 	if err != nil {
 		s.logger.Error("Failed to create AI model for title generation", zap.Error(err))
 		return s.generateFallbackTitle(prompt), nil
@@ -1543,7 +2564,7 @@ func (s *chatService) generateTitleWithAI(ctx context.Context, modelID, prompt s
 	// Generate title with moderate temperature for better creativity while maintaining consistency
 	options := map[string]any{
 		"temperature": 0.3,
-		"max_tokens":  30, // Shorter for titles
+		"max_tokens":  20, // A few words
 	}
 
 	s.logger.Debug("Calling AI for title generation")
@@ -1560,7 +2581,13 @@ func (s *chatService) generateTitleWithAI(ctx context.Context, modelID, prompt s
 
 	// Clean up the title
 	title := strings.TrimSpace(response[0].Content)
-	title = strings.Trim(title, `"'`)
+	if i := strings.IndexByte(title, '\n'); i >= 0 {
+		title = strings.TrimSpace(title[:i])
+	}
+	title = strings.TrimRight(strings.Trim(title, `"'*`), ".")
+	if title == "" {
+		return s.generateFallbackTitle(prompt), nil
+	}
 	if len(title) > 60 {
 		title = title[:60]
 	}
@@ -1577,9 +2604,7 @@ func (s *chatService) generateFallbackTitle(prompt string) string {
 			userMsg := strings.TrimPrefix(line, "User: ")
 			userMsg = strings.ReplaceAll(userMsg, "\n", " ")
 			userMsg = strings.TrimSpace(userMsg)
-			if len(userMsg) > 50 {
-				userMsg = userMsg[:50] + "..."
-			}
+			userMsg = truncateRunes(userMsg, 50)
 			if userMsg != "" {
 				return userMsg
 			}
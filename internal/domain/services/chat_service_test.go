@@ -1,10 +1,17 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"github.com/drujensen/aiagent/internal/domain/entities"
 	errors "github.com/drujensen/aiagent/internal/domain/errs"
+	"github.com/drujensen/aiagent/internal/impl/config"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
@@ -130,3 +137,412 @@ func TestIsContextError(t *testing.T) {
 		})
 	}
 }
+
+func TestRenderChatMarkdown(t *testing.T) {
+	chat := entities.NewChat("agent-1", "model-1", "Export Test")
+	toolCall := entities.ToolCall{ID: "call_1", Type: "function"}
+	toolCall.Function.Name = "Edit"
+	toolCall.Function.Arguments = `{"filePath":"main.go"}`
+
+	chat.Messages = []entities.Message{
+		{Role: "assistant", Content: "Summary of previous conversation: we fixed the build"},
+		{Role: "user", Content: "Rename the function"},
+		{Role: "assistant", Content: "Renaming now", ToolCalls: []entities.ToolCall{toolCall}, Usage: &entities.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15, Cost: 0.01}},
+		{Role: "tool", ToolCallID: "call_1", Content: "{}", ToolCallEvents: []entities.ToolCallEvent{{Diff: "-old\n+new"}}},
+	}
+	chat.UpdateUsage()
+
+	result := renderChatMarkdown(chat)
+
+	expected := []string{
+		"# Export Test",
+		"## Summary",
+		"we fixed the build",
+		"## User",
+		"## Assistant",
+		"**Tool call:** `Edit`",
+		"\"filePath\": \"main.go\"",
+		"```diff\n-old\n+new\n```",
+		"- Total tokens: 15",
+		"- Cost: $0.0100",
+	}
+	for _, want := range expected {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected markdown to contain %q, got:\n%s", want, result)
+		}
+	}
+	if strings.Index(result, "Rename the function") > strings.Index(result, "Renaming now") {
+		t.Error("Expected messages to keep their order")
+	}
+}
+
+func TestBalanceToolCalls(t *testing.T) {
+	toolCall := func(id string) entities.ToolCall {
+		tc := entities.ToolCall{ID: id, Type: "function"}
+		tc.Function.Name = "Bash"
+		return tc
+	}
+	user := &entities.Message{Role: "user", Content: "run it"}
+	assistant := &entities.Message{Role: "assistant", ToolCalls: []entities.ToolCall{toolCall("call_1"), toolCall("call_2")}}
+	response1 := &entities.Message{Role: "tool", ToolCallID: "call_1", Content: "ok"}
+	response2 := &entities.Message{Role: "tool", ToolCallID: "call_2", Content: "ok"}
+	final := &entities.Message{Role: "assistant", Content: "done"}
+
+	t.Run("balanced history is unchanged", func(t *testing.T) {
+		messages := []*entities.Message{user, assistant, response2, response1, final}
+		result, issues := balanceToolCalls(messages)
+		if len(issues) != 0 {
+			t.Errorf("Expected no issues, got %v", issues)
+		}
+		if len(result) != len(messages) {
+			t.Errorf("Expected %d messages, got %d", len(messages), len(result))
+		}
+	})
+
+	t.Run("missing response is filled", func(t *testing.T) {
+		result, issues := balanceToolCalls([]*entities.Message{user, assistant, response1, final})
+		if len(issues) != 1 {
+			t.Fatalf("Expected 1 issue, got %v", issues)
+		}
+		if len(result) != 5 || result[3].Role != "tool" || result[3].ToolCallID != "call_2" {
+			t.Errorf("Expected a generated response for call_2 after the tool call, got %+v", result[3])
+		}
+	})
+
+	t.Run("misplaced response is moved", func(t *testing.T) {
+		result, issues := balanceToolCalls([]*entities.Message{user, assistant, response1, final, response2})
+		if len(issues) != 1 {
+			t.Fatalf("Expected 1 issue, got %v", issues)
+		}
+		if len(result) != 5 || result[3] != response2 || result[4] != final {
+			t.Errorf("Expected call_2 response to be moved after its tool call")
+		}
+	})
+
+	t.Run("orphaned response is dropped", func(t *testing.T) {
+		orphan := &entities.Message{Role: "tool", ToolCallID: "call_9", Content: "stale"}
+		result, issues := balanceToolCalls([]*entities.Message{user, orphan, final})
+		if len(issues) != 1 {
+			t.Fatalf("Expected 1 issue, got %v", issues)
+		}
+		if len(result) != 2 {
+			t.Errorf("Expected orphaned response to be dropped, got %d messages", len(result))
+		}
+	})
+}
+
+func TestSendMessageRepairsStoredToolCalls(t *testing.T) {
+	toolCall := entities.ToolCall{ID: "call_1", Type: "function"}
+	toolCall.Function.Name = "Bash"
+	chat := &entities.Chat{ID: "chat-1", ModelID: "missing", Messages: []entities.Message{
+		{Role: "user", Content: "run it"},
+		{Role: "assistant", ToolCalls: []entities.ToolCall{toolCall}},
+		{Role: "tool", ToolCallID: "call_9", Content: "stale"},
+	}}
+	repo := &memoryChatRepo{chat: chat}
+	cs := &chatService{chatRepo: repo, modelRepo: &memoryModelRepo{}, logger: zap.NewNop()}
+
+	// The model lookup fails after the message is saved, which is enough here
+	cs.SendMessage(context.Background(), chat.ID, entities.NewMessage("user", "try again"))
+
+	messages := repo.chat.Messages
+	if len(messages) != 4 {
+		t.Fatalf("Expected 4 messages, got %d: %+v", len(messages), messages)
+	}
+	if messages[2].Role != "tool" || messages[2].ToolCallID != "call_1" {
+		t.Errorf("Expected a generated response for call_1, got %+v", messages[2])
+	}
+	if messages[3].Content != "try again" {
+		t.Errorf("Expected the new message last, got %+v", messages[3])
+	}
+}
+
+func TestAssembleOptimizedContext(t *testing.T) {
+	agent := &entities.Agent{Name: "Coder", SystemPrompt: "Write code."}
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	system := &entities.Message{Role: "system", Content: agent.FullSystemPrompt()}
+	stable := []*entities.Message{{Role: "system", Content: agent.StableSystemPrompt()}}
+	older := &entities.Message{Role: "user", Content: "first question"}
+	summary := &entities.Message{Role: "assistant", Content: summaryPrefix + "we fixed the build"}
+	recent := &entities.Message{Role: "assistant", Content: "recent answer"}
+	query := &entities.Message{Role: "user", Content: "next task"}
+
+	result := assembleOptimizedContext([]*entities.Message{system, older, summary, recent, query}, stable, now)
+
+	if len(result) != 5 {
+		t.Fatalf("Expected 5 messages, got %d", len(result))
+	}
+	if result[0].Content != agent.StableSystemPrompt() {
+		t.Errorf("Expected the stable system prompt first, got %q", result[0].Content)
+	}
+	if result[1] != summary || result[2] != older || result[3] != recent {
+		t.Errorf("Expected summaries ahead of the remaining history")
+	}
+	if result[4].Content != "Current date and time is 2026-01-02 03:04:05\n\nnext task" {
+		t.Errorf("Expected the query last with the current time, got %q", result[4].Content)
+	}
+	if query.Content != "next task" || system.Content != agent.FullSystemPrompt() {
+		t.Error("Expected the original messages to be left unchanged")
+	}
+
+	// Without a trailing query the time stays in the system prompt
+	result = assembleOptimizedContext([]*entities.Message{system, recent}, stable, now)
+	if !strings.Contains(result[0].Content, "Current date and time") {
+		t.Errorf("Expected the time in the system prompt, got %q", result[0].Content)
+	}
+}
+
+func TestProjectForWorkspace(t *testing.T) {
+	root := entities.NewProject("src", "/home/user/src")
+	nested := entities.NewProject("aiagent", "/home/user/src/aiagent")
+	other := entities.NewProject("other", "/home/user/other")
+	projects := []*entities.Project{root, nested, other}
+
+	if got := projectForWorkspace(projects, "/home/user/src/aiagent/internal"); got != nested {
+		t.Errorf("Expected the most specific project, got %v", got)
+	}
+	if got := projectForWorkspace(projects, "/home/user/src/website"); got != root {
+		t.Errorf("Expected the enclosing project, got %v", got)
+	}
+	if got := projectForWorkspace(projects, "/tmp"); got != nil {
+		t.Errorf("Expected no project, got %v", got)
+	}
+}
+
+func TestValidateStructuredResponse(t *testing.T) {
+	var schema map[string]any
+	err := json.Unmarshal([]byte(`{
+		"type": "object",
+		"properties": {
+			"name": {"type": "string", "minLength": 1},
+			"count": {"type": "integer", "minimum": 0},
+			"status": {"enum": ["open", "closed"]},
+			"tags": {"type": "array", "items": {"type": "string"}}
+		},
+		"required": ["name", "count"],
+		"additionalProperties": false
+	}`), &schema)
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+	format := &entities.ResponseFormat{Type: entities.ResponseFormatJSONSchema, Schema: schema}
+
+	tests := []struct {
+		name     string
+		content  string
+		problems []string
+	}{
+		{"valid", `{"name":"a","count":2,"status":"open","tags":["x"]}`, nil},
+		{"code fence", "```json\n{\"name\":\"a\",\"count\":0}\n```", nil},
+		{"not json", `name: a`, []string{"response is not valid JSON"}},
+		{"missing required", `{"name":"a"}`, []string{`$ is missing required property "count"`}},
+		{"wrong types", `{"name":"a","count":1.5,"tags":[1]}`, []string{
+			"$.count should be of type integer, got number",
+			"$.tags[0] should be of type string, got integer",
+		}},
+		{"bounds and enum", `{"name":"","count":-1,"status":"done"}`, []string{
+			"$.count should be at least 0",
+			"$.name should be at least 1 characters",
+			"$.status must be one of [open closed]",
+		}},
+		{"additional property", `{"name":"a","count":1,"extra":true}`, []string{"$.extra is not allowed"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := validateStructuredResponse(format, tt.content)
+			if len(problems) != len(tt.problems) {
+				t.Fatalf("Expected %d problems, got %v", len(tt.problems), problems)
+			}
+			for i, want := range tt.problems {
+				if !strings.HasPrefix(problems[i], want) {
+					t.Errorf("Expected problem %q, got %q", want, problems[i])
+				}
+			}
+		})
+	}
+
+	jsonOnly := &entities.ResponseFormat{Type: entities.ResponseFormatJSON}
+	if problems := validateStructuredResponse(jsonOnly, `[1, 2]`); problems != nil {
+		t.Errorf("Expected any JSON to pass JSON mode, got %v", problems)
+	}
+}
+
+func TestWithResponseInstructions(t *testing.T) {
+	system := &entities.Message{Role: "system", Content: "You are helpful"}
+	messages := []*entities.Message{system, {Role: "user", Content: "Hi"}}
+
+	if got := withResponseInstructions(messages, nil); got[0] != system {
+		t.Error("Expected messages to be unchanged without a response format")
+	}
+
+	format := &entities.ResponseFormat{Type: entities.ResponseFormatJSONSchema, Schema: map[string]any{"type": "object"}}
+	got := withResponseInstructions(messages, format)
+	if !strings.Contains(got[0].Content, `{"type":"object"}`) {
+		t.Errorf("Expected the schema in the system prompt, got %q", got[0].Content)
+	}
+	if system.Content != "You are helpful" {
+		t.Error("Expected the original system message to be left unchanged")
+	}
+}
+
+func TestIsFallbackError(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{"rate limit", context.Background(), errors.RateLimitErrorf("rate limit exceeded"), true},
+		{"server error", context.Background(), fmt.Errorf("unexpected status 503: overloaded"), true},
+		{"network error", context.Background(), fmt.Errorf("error making request: connection refused"), true},
+		{"timeout", context.Background(), fmt.Errorf("model request timed out: context deadline exceeded"), true},
+		{"client error", context.Background(), fmt.Errorf("unexpected status 400: bad request"), false},
+		{"cancelled by user", cancelled, fmt.Errorf("error making request: context canceled"), false},
+		{"nil", context.Background(), nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isFallbackError(tt.ctx, tt.err); got != tt.want {
+				t.Errorf("isFallbackError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveMessagesIncrementallyReplacesSavedMessages(t *testing.T) {
+	repo := &memoryChatRepo{chat: &entities.Chat{ID: "chat", Messages: []entities.Message{{ID: "m1", Role: "user", Content: "hi"}}}}
+	cs := &chatService{chatRepo: repo, logger: zap.NewNop()}
+
+	answer := &entities.Message{ID: "m2", Role: "assistant", Content: "Let me check"}
+	if err := cs.SaveMessagesIncrementally(context.Background(), "chat", []*entities.Message{answer}); err != nil {
+		t.Fatalf("SaveMessagesIncrementally failed: %v", err)
+	}
+	answer.Truncated = true
+	if err := cs.SaveMessagesIncrementally(context.Background(), "chat", []*entities.Message{answer}); err != nil {
+		t.Fatalf("SaveMessagesIncrementally failed: %v", err)
+	}
+
+	messages := repo.chat.Messages
+	if len(messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %d: %+v", len(messages), messages)
+	}
+	if !messages[1].Truncated {
+		t.Errorf("Expected the saved answer to be marked truncated, got %+v", messages[1])
+	}
+}
+
+func TestGenerateAndUpdateTitleSkipsNamedChats(t *testing.T) {
+	messages := []entities.Message{
+		{Role: "user", Content: "How do I reverse a slice in Go?"},
+		{Role: "assistant", Content: "Use slices.Reverse."},
+	}
+
+	// A renamed chat is left alone without a model call
+	repo := &memoryChatRepo{chat: &entities.Chat{ID: "chat", Name: "Slice tricks", Messages: messages}}
+	cs := &chatService{chatRepo: repo, logger: zap.NewNop()}
+	chat, err := cs.GenerateAndUpdateTitle(context.Background(), "chat")
+	if err != nil || chat.Name != "Slice tricks" {
+		t.Errorf("expected the renamed chat to be kept, got %v, %v", chat, err)
+	}
+
+	// So is a default name when auto titles are disabled
+	repo.chat = &entities.Chat{ID: "chat", Name: "New Chat - 2026-01-02 10:00", Messages: messages}
+	cs.globalConfig = &config.GlobalConfig{DisableAutoTitles: true}
+	chat, err = cs.GenerateAndUpdateTitle(context.Background(), "chat")
+	if err != nil || chat.Name != "New Chat - 2026-01-02 10:00" {
+		t.Errorf("expected the default name to be kept, got %v, %v", chat, err)
+	}
+}
+
+func TestGenerateAndUpdateTitleTruncatesByRunes(t *testing.T) {
+	question := strings.Repeat("é", 60)
+	repo := &memoryChatRepo{chat: &entities.Chat{ID: "chat", Name: "New Chat - 2026-01-02 10:00", Messages: []entities.Message{
+		{Role: "user", Content: question},
+		{Role: "assistant", Content: strings.Repeat("ü", 600)},
+	}}}
+	cs := &chatService{chatRepo: repo, modelRepo: &memoryModelRepo{}, logger: zap.NewNop()}
+
+	// Without the model the title falls back to the start of the question
+	chat, err := cs.GenerateAndUpdateTitle(context.Background(), "chat")
+	if err != nil {
+		t.Fatalf("GenerateAndUpdateTitle failed: %v", err)
+	}
+	if want := strings.Repeat("é", 50) + "..."; chat.Name != want || !utf8.ValidString(chat.Name) {
+		t.Errorf("Expected title %q, got %q", want, chat.Name)
+	}
+	if len(repo.chat.Messages) != 2 {
+		t.Errorf("Expected the messages to be kept, got %d", len(repo.chat.Messages))
+	}
+}
+
+func TestClearContextNeedsHistory(t *testing.T) {
+	repo := &memoryChatRepo{chat: &entities.Chat{ID: "chat", Messages: []entities.Message{{Role: "user", Content: "hi"}}}}
+	cs := &chatService{chatRepo: repo, logger: zap.NewNop()}
+	if _, err := cs.ClearContext(context.Background(), "chat"); err == nil {
+		t.Errorf("expected a chat with a single message to have nothing to clear")
+	}
+	if len(repo.chat.Messages) != 1 {
+		t.Errorf("expected the message to be kept, got %d", len(repo.chat.Messages))
+	}
+
+	// Clearing would race the incremental saves of a running response
+	repo.chat.Messages = append(repo.chat.Messages, entities.Message{Role: "assistant", Content: "hello"})
+	cs.startSteering("chat")
+	if _, err := cs.ClearContext(context.Background(), "chat"); err == nil || !strings.Contains(err.Error(), "being generated") {
+		t.Errorf("expected clearing during a response to be refused, got %v", err)
+	}
+}
+
+func TestSummarizeSplitKeepLatest(t *testing.T) {
+	messages := []entities.Message{
+		{Role: "user", Content: "one"},
+		{Role: "assistant", Content: "two"},
+		{Role: "user", Content: "three"},
+		{Role: "assistant", Content: "four"},
+	}
+	if split, ok := summarizeSplit(messages, entities.KeepLatest); !ok || split != 3 {
+		t.Errorf("Expected all but the latest message summarized, got %d, %v", split, ok)
+	}
+}
+
+func TestSetToolsOverride(t *testing.T) {
+	agent := entities.NewAgent("Coder", "Write code.", []string{"Read", "Write", "Bash"})
+	repo := &memoryChatRepo{chat: &entities.Chat{ID: "chat"}}
+	cs := &chatService{chatRepo: repo, toolRepo: &memoryToolRepo{names: []string{"Read", "Write", "Bash"}}, logger: zap.NewNop()}
+
+	if err := cs.SetToolsOverride(context.Background(), "chat", []string{"Read", "Deploy"}); err == nil {
+		t.Errorf("expected an unknown tool to be rejected")
+	} else if _, ok := err.(*errors.ValidationError); !ok {
+		t.Errorf("expected a validation error, got %T", err)
+	}
+	if got := repo.chat.ToolNames(agent); len(got) != 3 {
+		t.Errorf("expected the agent's tools without an override, got %v", got)
+	}
+
+	if err := cs.SetToolsOverride(context.Background(), "chat", []string{"Read"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := repo.chat.ToolNames(agent); len(got) != 1 || got[0] != "Read" {
+		t.Errorf("expected only Read, got %v", got)
+	}
+
+	if err := cs.SetToolsOverride(context.Background(), "chat", []string{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := repo.chat.ToolNames(agent); got == nil || len(got) != 0 {
+		t.Errorf("expected no tools, got %v", got)
+	}
+
+	if err := cs.SetToolsOverride(context.Background(), "chat", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := repo.chat.ToolNames(agent); len(got) != 3 {
+		t.Errorf("expected the agent's tools after a reset, got %v", got)
+	}
+}
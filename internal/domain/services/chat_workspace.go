@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	errors "github.com/drujensen/aiagent/internal/domain/errs"
+	"github.com/drujensen/aiagent/internal/impl/config"
+	"github.com/drujensen/aiagent/internal/impl/workspacepath"
+
+	"go.uber.org/zap"
+)
+
+// SetWorkspace points the chat's tools at another directory, so chats can
+// work on different repositories without restarting. An empty workspace goes
+// back to the one the tools are configured with. It returns the directory
+// the path resolved to.
+func (s *chatService) SetWorkspace(ctx context.Context, chatID, workspace string) (string, error) {
+	if chatID == "" {
+		return "", errors.ValidationErrorf("chat ID is required")
+	}
+
+	resolved := ""
+	if strings.TrimSpace(workspace) != "" {
+		var err error
+		resolved, err = resolveWorkspace(workspace)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	chat, err := s.chatRepo.GetChat(ctx, chatID)
+	if err != nil {
+		return "", err
+	}
+
+	chat.Workspace = resolved
+	chat.UpdatedAt = time.Now()
+	if err := s.chatRepo.UpdateChat(ctx, chat); err != nil {
+		return "", err
+	}
+	return resolved, nil
+}
+
+// resolveWorkspace turns a workspace path into an absolute directory with
+// symlinks resolved, so the tools' checks that paths stay inside the
+// workspace compare real locations. The filesystem root, the home directory
+// itself and system directories are refused, since they would put every
+// file, or the system's own, in reach.
+func resolveWorkspace(workspace string) (string, error) {
+	workspace = strings.TrimSpace(workspace)
+	home, _ := os.UserHomeDir()
+	if workspace == "~" || strings.HasPrefix(workspace, "~/") {
+		if home == "" {
+			return "", errors.ValidationErrorf("cannot expand ~ without a home directory")
+		}
+		workspace = filepath.Join(home, strings.TrimPrefix(workspace, "~"))
+	}
+
+	abs, err := filepath.Abs(workspace)
+	if err != nil {
+		return "", errors.ValidationErrorf("invalid workspace: %v", err)
+	}
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return "", errors.ValidationErrorf("workspace does not exist: %s", abs)
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return "", errors.ValidationErrorf("workspace does not exist: %s", abs)
+	}
+	if !info.IsDir() {
+		return "", errors.ValidationErrorf("workspace is not a directory: %s", abs)
+	}
+
+	if resolved == filepath.Dir(resolved) {
+		return "", errors.ValidationErrorf("the filesystem root cannot be a workspace")
+	}
+	if home != "" {
+		if realHome, err := filepath.EvalSymlinks(home); err == nil && resolved == realHome {
+			return "", errors.ValidationErrorf("the home directory cannot be a workspace, choose a directory inside it")
+		}
+	}
+	if isSystemDirectory(resolved) {
+		return "", errors.ValidationErrorf("the system directory %s cannot be a workspace", resolved)
+	}
+	return resolved, nil
+}
+
+// systemTrees are the directories of the operating system, refused as a
+// workspace along with everything inside them.
+var systemTrees = []string{
+	"/bin", "/boot", "/dev", "/etc", "/lib", "/lib32", "/lib64", "/libx32", "/proc", "/run", "/sbin", "/sys", "/usr",
+	"/Applications", "/Library", "/System", "/private/etc", "/private/var/db",
+}
+
+// systemParents are the directories holding everyone's files, such as the
+// home directories, refused as a workspace themselves but not the
+// directories inside them.
+var systemParents = []string{
+	"/home", "/mnt", "/media", "/opt", "/root", "/srv", "/tmp", "/var",
+	"/Users", "/Volumes", "/private", "/private/tmp", "/private/var",
+}
+
+// isSystemDirectory reports whether dir, an absolute path with symlinks
+// resolved, is a system directory or inside one. On Windows, the Windows and
+// Program Files directories count.
+func isSystemDirectory(dir string) bool {
+	trees := systemTrees
+	for _, name := range []string{"SystemRoot", "ProgramFiles", "ProgramFiles(x86)", "ProgramData"} {
+		if value := os.Getenv(name); value != "" && filepath.IsAbs(value) {
+			trees = append(trees, value)
+		}
+	}
+	for _, tree := range trees {
+		if workspacepath.Within(tree, dir) {
+			return true
+		}
+	}
+	return slices.Contains(systemParents, dir)
+}
+
+// toolConfigurations returns each tool's stored configuration by name. The
+// tool instances are shared between chats, so a chat's workspace is applied
+// on top of the stored configuration rather than whatever the last chat set.
+func (s *chatService) toolConfigurations(ctx context.Context) map[string]map[string]string {
+	toolData, err := s.toolRepo.ListToolData(ctx)
+	if err != nil {
+		s.logger.Warn("Failed to list tool configurations", zap.Error(err))
+		return nil
+	}
+	configs := make(map[string]map[string]string, len(toolData))
+	for _, data := range toolData {
+		configs[data.Name] = data.Configuration
+	}
+	return configs
+}
+
+// configureTool resolves the tool's configuration, with the agent's env ahead
+// of the process environment, and, when the chat has a workspace, points the
+// tool at it. Tools are shared between chats, so it returns a copy configured
+// for the run when the tool can make one, along with the secret values the
+// configuration's references resolved to.
+func (s *chatService) configureTool(tool entities.Tool, toolName string, configs map[string]map[string]string, workspace string, env map[string]string) (entities.Tool, []string, error) {
+	stored, ok := configs[toolName]
+	if !ok {
+		stored = tool.Configuration()
+	}
+	if stored == nil {
+		stored = make(map[string]string)
+	}
+	resolvedConfig, err := s.config.ResolveConfiguration(stored, env)
+	if err != nil {
+		return nil, nil, err
+	}
+	secrets := config.ResolvedSecrets(stored, resolvedConfig)
+	if workspace != "" {
+		resolvedConfig["workspace"] = workspace
+	}
+	if configurable, ok := tool.(entities.ConfigurableTool); ok {
+		return configurable.WithConfiguration(resolvedConfig), secrets, nil
+	}
+	tool.UpdateConfiguration(resolvedConfig)
+	return tool, secrets, nil
+}
@@ -0,0 +1,278 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/domain/interfaces"
+	"github.com/drujensen/aiagent/internal/impl/config"
+
+	"go.uber.org/zap"
+)
+
+// configuredTool keeps the last configuration it was given.
+type configuredTool struct {
+	entities.Tool
+	config map[string]string
+}
+
+func (t *configuredTool) Configuration() map[string]string             { return t.config }
+func (t *configuredTool) UpdateConfiguration(config map[string]string) { t.config = config }
+
+// workspaceTool reports the workspace it is configured with, once every
+// call it waits for has started.
+type workspaceTool struct {
+	entities.Tool
+	config  map[string]string
+	started *sync.WaitGroup
+}
+
+func (t *workspaceTool) Configuration() map[string]string             { return t.config }
+func (t *workspaceTool) UpdateConfiguration(config map[string]string) { t.config = config }
+func (t *workspaceTool) WithConfiguration(config map[string]string) entities.Tool {
+	c := *t
+	c.config = config
+	return &c
+}
+func (t *workspaceTool) Execute(ctx context.Context, arguments string) (string, error) {
+	t.started.Done()
+	t.started.Wait()
+	return t.config["workspace"], nil
+}
+
+type storedToolRepo struct {
+	interfaces.ToolRepository
+	data []*entities.ToolData
+	tool entities.Tool
+}
+
+func (r *storedToolRepo) GetToolByName(name string) (entities.Tool, error) {
+	return r.tool, nil
+}
+
+func (r *storedToolRepo) ListToolData(ctx context.Context) ([]*entities.ToolData, error) {
+	return r.data, nil
+}
+
+func TestSetWorkspace(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file.txt")
+	os.WriteFile(file, []byte("x"), 0644)
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(dir, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	realDir, _ := filepath.EvalSymlinks(dir)
+
+	repo := &memoryChatRepo{chat: &entities.Chat{ID: "chat"}}
+	cs := &chatService{chatRepo: repo, logger: zap.NewNop()}
+	ctx := context.Background()
+
+	for _, bad := range []string{filepath.Join(dir, "missing"), file, "/", "/etc"} {
+		if _, err := cs.SetWorkspace(ctx, "chat", bad); err == nil {
+			t.Errorf("Expected %s to be refused", bad)
+		}
+	}
+
+	workspace, err := cs.SetWorkspace(ctx, "chat", link)
+	if err != nil {
+		t.Fatalf("SetWorkspace failed: %v", err)
+	}
+	if workspace != realDir || repo.chat.Workspace != realDir {
+		t.Errorf("Expected the workspace to resolve to %s, got %s", realDir, repo.chat.Workspace)
+	}
+
+	if _, err := cs.SetWorkspace(ctx, "chat", ""); err != nil {
+		t.Fatalf("SetWorkspace failed: %v", err)
+	}
+	if repo.chat.Workspace != "" {
+		t.Errorf("Expected the workspace to be reset, got %s", repo.chat.Workspace)
+	}
+}
+
+func TestIsSystemDirectory(t *testing.T) {
+	tests := []struct {
+		dir  string
+		want bool
+	}{
+		{"/etc", true},
+		{"/usr/local/lib", true},
+		{"/System/Library", true},
+		{"/var", true},
+		{"/home", true},
+		{"/home/alice/project", false},
+		{"/var/www/site", false},
+		{"/tmp/scratch", false},
+		{"/etcetera", false},
+	}
+	for _, tt := range tests {
+		if got := isSystemDirectory(tt.dir); got != tt.want {
+			t.Errorf("isSystemDirectory(%q) = %v, want %v", tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestConfigureTool_Workspace(t *testing.T) {
+	cfg, err := config.InitConfig()
+	if err != nil {
+		t.Fatalf("InitConfig failed: %v", err)
+	}
+	cs := &chatService{
+		toolRepo: &storedToolRepo{data: []*entities.ToolData{
+			{Name: "Bash", Configuration: map[string]string{"workspace": "/configured", "sandbox": "true"}},
+		}},
+		config: cfg,
+		logger: zap.NewNop(),
+	}
+	tool := &configuredTool{}
+	configs := cs.toolConfigurations(context.Background())
+
+	if _, _, err := cs.configureTool(tool, "Bash", configs, "/chat", nil); err != nil {
+		t.Fatalf("configureTool failed: %v", err)
+	}
+	if tool.config["workspace"] != "/chat" || tool.config["sandbox"] != "true" {
+		t.Errorf("Expected the chat's workspace over the stored configuration, got %v", tool.config)
+	}
+
+	// The next chat without a workspace gets the configured one back
+	if _, _, err := cs.configureTool(tool, "Bash", configs, "", nil); err != nil {
+		t.Fatalf("configureTool failed: %v", err)
+	}
+	if tool.config["workspace"] != "/configured" {
+		t.Errorf("Expected the configured workspace, got %s", tool.config["workspace"])
+	}
+}
+
+func TestRunTool_ConcurrentWorkspaces(t *testing.T) {
+	cfg, err := config.InitConfig()
+	if err != nil {
+		t.Fatalf("InitConfig failed: %v", err)
+	}
+	started := &sync.WaitGroup{}
+	started.Add(2)
+	cs := &chatService{
+		toolRepo: &storedToolRepo{
+			data: []*entities.ToolData{{Name: "Bash", Configuration: map[string]string{"workspace": "/configured"}}},
+			tool: &workspaceTool{started: started},
+		},
+		config: cfg,
+		logger: zap.NewNop(),
+	}
+
+	// Two chats run the shared tool at once, each in its own workspace
+	workspaces := []string{"/chat-a", "/chat-b"}
+	results := make([]string, len(workspaces))
+	var wg sync.WaitGroup
+	for i, workspace := range workspaces {
+		wg.Add(1)
+		go func(i int, workspace string) {
+			defer wg.Done()
+			result, err := cs.runTool(context.Background(), workspace, "Bash", "{}")
+			if err != nil {
+				t.Errorf("runTool failed: %v", err)
+			}
+			results[i] = result
+		}(i, workspace)
+	}
+	wg.Wait()
+
+	for i, workspace := range workspaces {
+		if results[i] != workspace {
+			t.Errorf("Expected the chat's tool to run in %s, got %s", workspace, results[i])
+		}
+	}
+}
+
+// lookupTool reports the configuration of the copy that runs it.
+type lookupTool struct {
+	entities.Tool
+	config map[string]string
+	ran    chan map[string]string
+}
+
+func (t *lookupTool) Name() string                                 { return "Lookup" }
+func (t *lookupTool) Description() string                          { return "looks things up" }
+func (t *lookupTool) Schema() map[string]any                       { return map[string]any{"type": "object"} }
+func (t *lookupTool) Configuration() map[string]string             { return t.config }
+func (t *lookupTool) UpdateConfiguration(config map[string]string) { t.config = config }
+func (t *lookupTool) WithConfiguration(config map[string]string) entities.Tool {
+	c := *t
+	c.config = config
+	return &c
+}
+func (t *lookupTool) Execute(ctx context.Context, arguments string) (string, error) {
+	t.ran <- t.config
+	return "found it", nil
+}
+
+// stubAgentService returns a single agent.
+type stubAgentService struct {
+	AgentService
+	agent *entities.Agent
+}
+
+func (s *stubAgentService) GetAgent(ctx context.Context, id string) (*entities.Agent, error) {
+	return s.agent, nil
+}
+
+func TestSendMessage_RunsConfiguredTools(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if requests.Add(1) == 1 {
+			w.Write([]byte(`{"choices": [{"finish_reason": "tool_calls", "message": {"content": "",
+				"tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "Lookup", "arguments": "{}"}}]}}],
+				"usage": {"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15}}`))
+			return
+		}
+		w.Write([]byte(`{"choices": [{"finish_reason": "stop", "message": {"content": "Done."}}],
+			"usage": {"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15}}`))
+	}))
+	defer server.Close()
+
+	cfg, err := config.InitConfig()
+	if err != nil {
+		t.Fatalf("InitConfig failed: %v", err)
+	}
+	workspace := t.TempDir()
+	t.Setenv("LOOKUP_API_KEY", "test-key")
+	provider := &entities.Provider{ID: "provider", Type: entities.ProviderGeneric, BaseURL: server.URL, APIKeyName: "LOOKUP_API_KEY"}
+	model := &entities.Model{ID: "model", ProviderID: provider.ID, ModelName: "test-model"}
+	agent := &entities.Agent{ID: "agent", Tools: []string{"Lookup"}, Env: map[string]string{"LOOKUP_TOKEN": "agent-token"}}
+	shared := &lookupTool{ran: make(chan map[string]string, 1)}
+	chat := &entities.Chat{ID: "chat", AgentID: agent.ID, ModelID: model.ID, Workspace: workspace}
+	cs := &chatService{
+		chatRepo:     &memoryChatRepo{chat: chat},
+		modelRepo:    &memoryModelRepo{models: []*entities.Model{model}},
+		providerRepo: &memoryProviderRepo{providers: []*entities.Provider{provider}},
+		agentService: &stubAgentService{agent: agent},
+		toolRepo: &storedToolRepo{
+			data: []*entities.ToolData{{Name: "Lookup", Configuration: map[string]string{"token": "#{LOOKUP_TOKEN}#"}}},
+			tool: shared,
+		},
+		config: cfg,
+		logger: zap.NewNop(),
+	}
+
+	if _, err := cs.SendMessage(context.Background(), chat.ID, entities.NewMessage("user", "look it up")); err != nil {
+		t.Fatalf("SendMessage failed: %v", err)
+	}
+	select {
+	case ran := <-shared.ran:
+		if ran["workspace"] != workspace || ran["token"] != "agent-token" {
+			t.Errorf("Expected the call to run the tool configured for the chat, got %v", ran)
+		}
+	default:
+		t.Fatal("Expected the tool to run")
+	}
+	if shared.config != nil {
+		t.Errorf("Expected the shared tool to be left as it was, got %v", shared.config)
+	}
+}
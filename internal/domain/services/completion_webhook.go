@@ -0,0 +1,142 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	errors "github.com/drujensen/aiagent/internal/domain/errs"
+
+	"go.uber.org/zap"
+)
+
+// webhookTimeout bounds a completion webhook call, lookups included.
+const webhookTimeout = 10 * time.Second
+
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+// completionNotification is the body POSTed to a completion webhook.
+type completionNotification struct {
+	ChatID     string              `json:"chat_id"`
+	ChatName   string              `json:"chat_name"`
+	Agent      string              `json:"agent,omitempty"`
+	Status     string              `json:"status"` // completed, failed or canceled
+	Error      string              `json:"error,omitempty"`
+	DurationMs int64               `json:"duration_ms"`
+	Usage      *entities.Usage     `json:"usage,omitempty"` // of the response
+	ChatUsage  *entities.ChatUsage `json:"chat_usage,omitempty"`
+}
+
+// completionTarget is the chat and agent of a response, as sendMessage loaded
+// them, so announcing it doesn't look them up again.
+type completionTarget struct {
+	chat  *entities.Chat
+	agent *entities.Agent
+}
+
+// webhook returns the agent's webhook, or the global one.
+func (s *chatService) webhook(agent *entities.Agent) string {
+	if agent != nil && agent.CompletionWebhook != "" {
+		return agent.CompletionWebhook
+	}
+	if s.globalConfig != nil {
+		return s.globalConfig.CompletionWebhook
+	}
+	return ""
+}
+
+// notifyCompletion POSTs the outcome of a response to the agent's webhook, or
+// the global one, in the background. It is best effort: failures are logged
+// and the response never waits for it. Requests refused before anything ran
+// and the responses of sub-agents, which their parent's response covers,
+// are not announced.
+func (s *chatService) notifyCompletion(target completionTarget, started time.Time, response *entities.Message, err error) {
+	if _, ok := err.(*errors.ValidationError); ok {
+		return
+	}
+	chat := target.chat
+	if chat == nil || chat.ParentChatID != "" {
+		return
+	}
+	url := s.webhook(target.agent)
+	if url == "" {
+		return
+	}
+
+	notification := completionNotification{
+		ChatID:     chat.ID,
+		ChatName:   chat.Name,
+		Status:     "completed",
+		DurationMs: time.Since(started).Milliseconds(),
+		ChatUsage:  chat.Usage,
+	}
+	if target.agent != nil {
+		notification.Agent = target.agent.Name
+	}
+	if response != nil {
+		notification.Usage = response.Usage
+	}
+	if err != nil {
+		notification.Status = "failed"
+		if _, ok := err.(*errors.CanceledError); ok {
+			notification.Status = "canceled"
+		}
+		notification.Error = err.Error()
+	}
+
+	s.webhooks.Add(1)
+	go func() {
+		defer s.webhooks.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+		defer cancel()
+
+		// The usage saved with the response is newer than the chat loaded
+		// before it
+		if saved, getErr := s.chatRepo.GetChat(ctx, chat.ID); getErr == nil {
+			notification.ChatUsage = saved.Usage
+		}
+		if postErr := postWebhook(ctx, url, notification); postErr != nil {
+			s.logger.Warn("Failed to call the completion webhook", zap.String("chat_id", chat.ID), zap.Error(postErr))
+		}
+	}()
+}
+
+// WaitForNotifications waits for the completion webhooks being delivered,
+// for at most webhookTimeout, so a process can exit without dropping them.
+func (s *chatService) WaitForNotifications() {
+	done := make(chan struct{})
+	go func() {
+		s.webhooks.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(webhookTimeout):
+	}
+}
+
+func postWebhook(ctx context.Context, url string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	errors "github.com/drujensen/aiagent/internal/domain/errs"
+	"github.com/drujensen/aiagent/internal/impl/config"
+
+	"go.uber.org/zap"
+)
+
+func TestNotifyCompletion(t *testing.T) {
+	received := make(chan completionNotification, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var notification completionNotification
+		if err := json.NewDecoder(r.Body).Decode(&notification); err != nil {
+			t.Errorf("Failed to decode the notification: %v", err)
+		}
+		received <- notification
+	}))
+	defer server.Close()
+	receive := func() (completionNotification, bool) {
+		select {
+		case notification := <-received:
+			return notification, true
+		case <-time.After(2 * time.Second):
+			return completionNotification{}, false
+		}
+	}
+
+	chat := &entities.Chat{ID: "chat", Name: "Fix tests", AgentID: "coder", Usage: &entities.ChatUsage{TotalTokens: 1200}}
+	target := completionTarget{chat: chat, agent: &entities.Agent{ID: "coder", Name: "Code"}}
+	cs := &chatService{
+		chatRepo:     &memoryChatRepo{chat: chat},
+		globalConfig: &config.GlobalConfig{CompletionWebhook: server.URL},
+		logger:       zap.NewNop(),
+	}
+
+	response := &entities.Message{Role: "assistant", Usage: &entities.Usage{TotalTokens: 300, Cost: 0.01}}
+	cs.notifyCompletion(target, time.Now(), response, nil)
+	notification, ok := receive()
+	if !ok {
+		t.Fatal("Expected the webhook to be called")
+	}
+	if notification.Status != "completed" || notification.Agent != "Code" || notification.ChatName != "Fix tests" {
+		t.Errorf("Unexpected notification %+v", notification)
+	}
+	if notification.Usage == nil || notification.Usage.TotalTokens != 300 || notification.ChatUsage == nil || notification.ChatUsage.TotalTokens != 1200 {
+		t.Errorf("Expected the response and chat usage, got %+v", notification)
+	}
+
+	cs.notifyCompletion(target, time.Now(), nil, errors.CanceledErrorf("message processing was canceled"))
+	if notification, ok := receive(); !ok || notification.Status != "canceled" || notification.Error == "" {
+		t.Errorf("Expected a canceled notification, got %+v", notification)
+	}
+
+	cs.notifyCompletion(target, time.Now(), nil, errors.ValidationErrorf("message role and content are required"))
+	cs.notifyCompletion(completionTarget{}, time.Now(), nil, errors.NotFoundErrorf("chat not found"))
+	cs.globalConfig.CompletionWebhook = ""
+	cs.notifyCompletion(completionTarget{chat: chat}, time.Now(), response, nil)
+	cs.globalConfig.CompletionWebhook = server.URL
+	chat.ParentChatID = "parent"
+	cs.notifyCompletion(target, time.Now(), response, nil)
+	if notification, ok := receive(); ok {
+		t.Errorf("Expected refused requests, chats without a webhook and sub-agents not to be announced, got %+v", notification)
+	}
+}
+
+func TestWaitForNotifications(t *testing.T) {
+	delivered := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		delivered <- struct{}{}
+	}))
+	defer server.Close()
+
+	chat := &entities.Chat{ID: "chat", Name: "Batch run"}
+	cs := &chatService{
+		chatRepo:     &memoryChatRepo{chat: chat},
+		globalConfig: &config.GlobalConfig{CompletionWebhook: server.URL},
+		logger:       zap.NewNop(),
+	}
+
+	// A headless run exits right after its response, so the delivery has to
+	// be waited for
+	cs.notifyCompletion(completionTarget{chat: chat}, time.Now(), nil, nil)
+	cs.WaitForNotifications()
+	select {
+	case <-delivered:
+	default:
+		t.Error("Expected the webhook to be delivered before WaitForNotifications returned")
+	}
+}
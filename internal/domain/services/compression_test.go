@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+
+	"go.uber.org/zap"
+)
+
+// toolExchange is a user question answered with a tool call, its result and
+// a final answer.
+func toolExchange(id string) []entities.Message {
+	call := entities.ToolCall{ID: id, Type: "function"}
+	call.Function.Name = "Read"
+	return []entities.Message{
+		{Role: "user", Content: "question " + id},
+		{Role: "assistant", ToolCalls: []entities.ToolCall{call}},
+		{Role: "tool", ToolCallID: id, Content: "result " + id},
+		{Role: "assistant", Content: "answer " + id},
+	}
+}
+
+func countWords(msg *entities.Message) int {
+	return len(strings.Fields(msg.Content)) + 1
+}
+
+func TestTruncateOldest(t *testing.T) {
+	var messages []entities.Message
+	for _, id := range []string{"a", "b", "c"} {
+		messages = append(messages, toolExchange(id)...)
+	}
+
+	// Each exchange is 3+1+3+3 = 10 words; 12 only fits the newest one
+	kept := truncateOldest(messages, 12, countWords)
+	if len(kept) != 4 || kept[0].Content != "question c" {
+		t.Fatalf("expected the newest exchange, got %d messages starting with %q", len(kept), kept[0].Content)
+	}
+
+	// Every budget keeps tool results together with their calls
+	for budget := 0; budget <= 30; budget++ {
+		calls := map[string]bool{}
+		for _, msg := range truncateOldest(messages, budget, countWords) {
+			for _, tc := range msg.ToolCalls {
+				calls[tc.ID] = true
+			}
+			if msg.Role == "tool" && !calls[msg.ToolCallID] {
+				t.Fatalf("budget %d kept the result of a dropped tool call", budget)
+			}
+		}
+	}
+
+	kept = truncateOldest(messages, 1000, countWords)
+	if len(kept) != len(messages) {
+		t.Errorf("expected every message within budget, got %d", len(kept))
+	}
+	if &messages[0] != kept[0] {
+		t.Errorf("expected pointers into the chat's messages")
+	}
+}
+
+func TestCompressMessagesSlidingWindow(t *testing.T) {
+	var messages []entities.Message
+	for _, id := range []string{"a", "b", "c", "d"} {
+		messages = append(messages, toolExchange(id)...)
+	}
+	chat := &entities.Chat{Messages: messages}
+	provider := &entities.Provider{Type: entities.ProviderOpenAI}
+	s := &chatService{logger: zap.NewNop()}
+
+	compression := &entities.CompressionConfig{Strategy: entities.CompressionSlidingWindow, KeepRatio: 0.5}
+	kept, replaced, err := s.compressMessages(context.Background(), chat, &entities.Model{}, provider, "", 1000, compression)
+	if err != nil {
+		t.Fatalf("compressMessages: %v", err)
+	}
+	if replaced {
+		t.Errorf("sliding window must not rewrite the chat")
+	}
+	if len(kept) != 8 || kept[0].Content != "question c" {
+		t.Errorf("expected the last two exchanges, got %d messages starting with %q", len(kept), kept[0].Content)
+	}
+	if len(chat.Messages) != 16 {
+		t.Errorf("expected the chat to keep all messages, got %d", len(chat.Messages))
+	}
+}
+
+func TestCompressionConfig(t *testing.T) {
+	var unset *entities.CompressionConfig
+	if unset.EffectiveStrategy() != entities.CompressionSummarize || unset.TriggerRatio() != entities.DefaultCompressionTrigger {
+		t.Errorf("expected summarize at the default trigger without a config")
+	}
+	if got := unset.KeepRatioFor(250, 100); got != 0.3 {
+		t.Errorf("KeepRatioFor() = %v, want 0.3 far over the limit", got)
+	}
+	if got := (&entities.CompressionConfig{KeepRatio: 0.6}).KeepRatioFor(250, 100); got != 0.6 {
+		t.Errorf("KeepRatioFor() = %v, want the configured 0.6", got)
+	}
+
+	invalid := []entities.CompressionConfig{
+		{Strategy: "forget-everything"},
+		{KeepRatio: 1},
+		{Trigger: 1.5},
+	}
+	for _, c := range invalid {
+		if err := c.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", c)
+		}
+	}
+}
+
+func TestPreviewCompaction(t *testing.T) {
+	var messages []entities.Message
+	for _, id := range []string{"a", "b", "c", "d"} {
+		messages = append(messages, toolExchange(id)...)
+	}
+	agent := &entities.Agent{ID: "agent", Compression: &entities.CompressionConfig{Strategy: entities.CompressionSlidingWindow, KeepRatio: 0.4}}
+	repo := &memoryChatRepo{chat: &entities.Chat{ID: "chat", AgentID: "agent", ModelID: "model", Messages: messages}}
+	cs := &chatService{
+		chatRepo:     repo,
+		agentRepo:    &memoryAgentRepo{agents: map[string]*entities.Agent{"agent": agent}},
+		modelRepo:    &memoryModelRepo{models: []*entities.Model{{ID: "model", ProviderID: "provider"}}},
+		providerRepo: &memoryProviderRepo{providers: []*entities.Provider{{ID: "provider", Type: entities.ProviderOpenAI}}},
+		logger:       zap.NewNop(),
+	}
+
+	// Keeping 40% of 16 messages splits at 10, between a tool call and its
+	// result, so the split moves back to the call
+	preview, err := cs.PreviewCompaction(context.Background(), "chat")
+	if err != nil {
+		t.Fatalf("PreviewCompaction: %v", err)
+	}
+	if preview.To != 9 || preview.Total != 16 {
+		t.Errorf("expected 9 of 16 messages to be summarized, got %d of %d", preview.To, preview.Total)
+	}
+	if len(repo.chat.Messages) != 16 {
+		t.Errorf("expected a preview to leave the chat as it is, got %d messages", len(repo.chat.Messages))
+	}
+
+	repo.chat.Messages = messages[:1]
+	if _, err := cs.PreviewCompaction(context.Background(), "chat"); err == nil {
+		t.Errorf("expected a chat with a single message to have nothing to compact")
+	}
+	if err := cs.CompactChat(context.Background(), "chat"); err == nil {
+		t.Errorf("expected a chat with a single message to have nothing to compact")
+	}
+
+	// Compacting would race the incremental saves of a running response
+	repo.chat.Messages = messages
+	cs.startSteering("chat")
+	if err := cs.CompactChat(context.Background(), "chat"); err == nil || !strings.Contains(err.Error(), "being generated") {
+		t.Errorf("expected compacting during a response to be refused, got %v", err)
+	}
+}
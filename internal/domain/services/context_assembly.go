@@ -0,0 +1,96 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+
+	"go.uber.org/zap"
+)
+
+// summaryPrefix marks the assistant message that replaces compressed history.
+const summaryPrefix = "Summary of previous conversation: "
+
+// contextParts is the context split into the sections the assembly strategy
+// orders.
+type contextParts struct {
+	system    []*entities.Message
+	summaries []*entities.Message
+	history   []*entities.Message
+	query     *entities.Message
+}
+
+// splitContext splits messages, which start with the system messages, into
+// their sections. The query is the trailing user message, if any.
+func splitContext(messages []*entities.Message) contextParts {
+	var parts contextParts
+	if len(messages) == 0 {
+		return parts
+	}
+
+	rest := messages
+	for len(rest) > 0 && rest[0] != nil && rest[0].Role == "system" {
+		parts.system = append(parts.system, rest[0])
+		rest = rest[1:]
+	}
+	if n := len(rest); n > 0 && rest[n-1] != nil && rest[n-1].Role == "user" {
+		parts.query = rest[n-1]
+		rest = rest[:n-1]
+	}
+
+	for _, msg := range rest {
+		if msg != nil && msg.Role == "assistant" && len(msg.ToolCalls) == 0 && strings.HasPrefix(msg.Content, summaryPrefix) {
+			parts.summaries = append(parts.summaries, msg)
+		} else {
+			parts.history = append(parts.history, msg)
+		}
+	}
+	return parts
+}
+
+// assembleContext orders the messages according to the configured strategy.
+// Messages that are changed are copied so the chat history is not modified.
+// stableSystem is the system prompt without the current time.
+func (s *chatService) assembleContext(messages []*entities.Message, stableSystem []*entities.Message, now time.Time) []*entities.Message {
+	strategy := entities.ContextStrategyChronological
+	if s.globalConfig != nil && s.globalConfig.ContextStrategy != "" {
+		strategy = s.globalConfig.ContextStrategy
+	}
+
+	switch strategy {
+	case entities.ContextStrategyChronological:
+		return messages
+	case entities.ContextStrategyOptimized:
+		return assembleOptimizedContext(messages, stableSystem, now)
+	default:
+		s.logger.Warn("Unknown context strategy, using chronological order", zap.String("strategy", string(strategy)))
+		return messages
+	}
+}
+
+// assembleOptimizedContext puts the stable system prompt first, then the
+// summaries of older history, the recent messages and finally the query with
+// the current time attached, so the prefix stays cacheable between requests
+// and the current task is what the model reads last.
+func assembleOptimizedContext(messages []*entities.Message, stableSystem []*entities.Message, now time.Time) []*entities.Message {
+	parts := splitContext(messages)
+	timeNote := "Current date and time is " + now.Format("2006-01-02 15:04:05")
+
+	result := make([]*entities.Message, 0, len(messages))
+	if parts.query != nil && len(parts.system) > 0 {
+		result = append(result, stableSystem...)
+	} else {
+		// Without a query to carry it, keep the time in the system prompt
+		result = append(result, parts.system...)
+	}
+	result = append(result, parts.summaries...)
+	result = append(result, parts.history...)
+
+	if parts.query != nil {
+		query := *parts.query
+		query.Content = timeNote + "\n\n" + query.Content
+		result = append(result, &query)
+	}
+	return result
+}
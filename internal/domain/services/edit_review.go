@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	errors "github.com/drujensen/aiagent/internal/domain/errs"
+	"github.com/drujensen/aiagent/internal/domain/events"
+)
+
+// editReviews holds the edits of the responses being generated that wait for
+// the user to apply or reject them. Each is announced with a chat_update
+// event, so the Web UI can show its diff.
+type editReviews struct {
+	mu      sync.Mutex
+	pending map[string][]*pendingEdit // keyed by chat ID
+}
+
+type pendingEdit struct {
+	review   *entities.EditReview
+	decision chan entities.EditDecision
+}
+
+// Review holds the edit until the user decides or ctx is done, which rejects
+// it.
+func (r *editReviews) Review(ctx context.Context, review *entities.EditReview) entities.EditDecision {
+	edit := &pendingEdit{review: review, decision: make(chan entities.EditDecision, 1)}
+	r.mu.Lock()
+	if r.pending == nil {
+		r.pending = make(map[string][]*pendingEdit)
+	}
+	r.pending[review.ChatID] = append(r.pending[review.ChatID], edit)
+	r.mu.Unlock()
+	publishEditUpdate(review, "edit_review")
+
+	select {
+	case decision := <-edit.decision:
+		return decision
+	case <-ctx.Done():
+		r.take(review.ChatID, review.ID)
+		publishEditUpdate(review, "edit_resolved")
+		return entities.EditDecision{}
+	}
+}
+
+// take removes and returns the chat's pending edit.
+func (r *editReviews) take(chatID, reviewID string) *pendingEdit {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	edits := r.pending[chatID]
+	i := slices.IndexFunc(edits, func(edit *pendingEdit) bool { return edit.review.ID == reviewID })
+	if i < 0 {
+		return nil
+	}
+	edit := edits[i]
+	if edits = slices.Delete(edits, i, i+1); len(edits) == 0 {
+		delete(r.pending, chatID)
+	} else {
+		r.pending[chatID] = edits
+	}
+	return edit
+}
+
+// list returns the chat's pending edits, oldest first.
+func (r *editReviews) list(chatID string) []*entities.EditReview {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var reviews []*entities.EditReview
+	for _, edit := range r.pending[chatID] {
+		reviews = append(reviews, edit.review)
+	}
+	return reviews
+}
+
+func publishEditUpdate(review *entities.EditReview, updateType string) {
+	events.PublishChatUpdateEvent(entities.NewChatUpdateEvent(review.ChatID, updateType, map[string]interface{}{
+		"id":        review.ID,
+		"tool_name": review.ToolName,
+	}))
+}
+
+// SetReviewEdits turns the review of the chat's file edits on or off. While
+// it is on, the edits of turns sent from the Web UI wait for the user to
+// apply or reject their diff.
+func (s *chatService) SetReviewEdits(ctx context.Context, chatID string, enabled bool) error {
+	if chatID == "" {
+		return errors.ValidationErrorf("chat ID is required")
+	}
+
+	chat, err := s.chatRepo.GetChat(ctx, chatID)
+	if err != nil {
+		return err
+	}
+
+	chat.ReviewEdits = enabled
+	chat.UpdatedAt = time.Now()
+	return s.chatRepo.UpdateChat(ctx, chat)
+}
+
+// PendingEdits returns the edits of the chat's response that wait for review.
+func (s *chatService) PendingEdits(ctx context.Context, chatID string) ([]*entities.EditReview, error) {
+	if chatID == "" {
+		return nil, errors.ValidationErrorf("chat ID is required")
+	}
+	return s.edits.list(chatID), nil
+}
+
+// ResolveEdit applies or rejects a pending edit. An applied edit runs as the
+// model asked; a rejected one is answered with the rejection and the note.
+func (s *chatService) ResolveEdit(ctx context.Context, chatID, reviewID string, decision entities.EditDecision) error {
+	if chatID == "" {
+		return errors.ValidationErrorf("chat ID is required")
+	}
+
+	edit := s.edits.take(chatID, reviewID)
+	if edit == nil {
+		return errors.NotFoundErrorf("no edit %s is waiting for review", reviewID)
+	}
+	edit.decision <- decision
+	publishEditUpdate(edit.review, "edit_resolved")
+	return nil
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+
+	"go.uber.org/zap"
+)
+
+func TestResolveEdit(t *testing.T) {
+	cs := &chatService{chatRepo: &memoryChatRepo{chat: &entities.Chat{ID: "chat"}}, logger: zap.NewNop()}
+	ctx := context.Background()
+
+	decided := make(chan entities.EditDecision)
+	go func() {
+		decided <- cs.edits.Review(ctx, &entities.EditReview{ID: "call-1", ChatID: "chat", ToolName: "Edit"})
+	}()
+	var pending []*entities.EditReview
+	for start := time.Now(); len(pending) == 0 && time.Since(start) < time.Second; time.Sleep(time.Millisecond) {
+		pending, _ = cs.PendingEdits(ctx, "chat")
+	}
+	if len(pending) != 1 || pending[0].ID != "call-1" {
+		t.Fatalf("Expected the edit to wait for review, got %v", pending)
+	}
+
+	if err := cs.ResolveEdit(ctx, "chat", "other", entities.EditDecision{Apply: true}); err == nil {
+		t.Error("Expected an error for an edit that isn't pending")
+	}
+	if err := cs.ResolveEdit(ctx, "chat", "call-1", entities.EditDecision{Note: "keep the name"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if decision := <-decided; decision.Apply || decision.Note != "keep the name" {
+		t.Errorf("Expected the rejection to be returned, got %+v", decision)
+	}
+	if pending, _ := cs.PendingEdits(ctx, "chat"); len(pending) != 0 {
+		t.Errorf("Expected no edits left, got %v", pending)
+	}
+}
+
+func TestReviewEdit_Canceled(t *testing.T) {
+	cs := &chatService{logger: zap.NewNop()}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if decision := cs.edits.Review(ctx, &entities.EditReview{ID: "call-1", ChatID: "chat"}); decision.Apply {
+		t.Error("Expected a canceled review to reject the edit")
+	}
+	if pending, _ := cs.PendingEdits(ctx, "chat"); len(pending) != 0 {
+		t.Errorf("Expected the canceled edit to be dropped, got %v", pending)
+	}
+}
+
+func TestSetReviewEdits(t *testing.T) {
+	repo := &memoryChatRepo{chat: &entities.Chat{ID: "chat"}}
+	cs := &chatService{chatRepo: repo, logger: zap.NewNop()}
+
+	if err := cs.SetReviewEdits(context.Background(), "chat", true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !repo.chat.ReviewEdits {
+		t.Error("Expected the chat to review edits")
+	}
+}
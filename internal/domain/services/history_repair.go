@@ -0,0 +1,30 @@
+package services
+
+import (
+	"github.com/drujensen/aiagent/internal/domain/entities"
+)
+
+// repairToolCallHistory balances the tool calls stored in the chat. A run
+// that stopped between a tool call and its response, say because the
+// process was killed, leaves a call the providers refuse to see unanswered,
+// so every later message would fail. Missing responses are filled with an
+// error result and responses without a call are dropped. It returns the
+// problems found; the chat is only changed when there are any.
+func repairToolCallHistory(chat *entities.Chat) []string {
+	messages := make([]*entities.Message, len(chat.Messages))
+	for i := range chat.Messages {
+		messages[i] = &chat.Messages[i]
+	}
+
+	balanced, issues := balanceToolCalls(messages)
+	if len(issues) == 0 {
+		return nil
+	}
+
+	repaired := make([]entities.Message, len(balanced))
+	for i, msg := range balanced {
+		repaired[i] = *msg
+	}
+	chat.Messages = repaired
+	return issues
+}
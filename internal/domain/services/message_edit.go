@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	errors "github.com/drujensen/aiagent/internal/domain/errs"
+
+	"go.uber.org/zap"
+)
+
+// EditMessage replaces the content of one of the chat's user messages and
+// answers it again. The messages after it are removed first, as the answer
+// to the old content no longer applies. It returns the new response.
+func (s *chatService) EditMessage(ctx context.Context, chatID, messageID, content string) (*entities.Message, error) {
+	if messageID == "" {
+		return nil, errors.ValidationErrorf("message ID is required")
+	}
+	if strings.TrimSpace(content) == "" {
+		return nil, errors.ValidationErrorf("message content is required")
+	}
+
+	message, restore, err := s.rewind(ctx, chatID, func(chat *entities.Chat) (int, error) {
+		i := messageIndex(chat, messageID)
+		if i < 0 {
+			return 0, errors.NotFoundErrorf("message %s not found", messageID)
+		}
+		if chat.Messages[i].Role != "user" {
+			return 0, errors.ValidationErrorf("only user messages can be edited")
+		}
+		return i, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	message.Content = content
+	response, err := s.SendMessage(ctx, chatID, message)
+	if err != nil {
+		restore()
+	}
+	return response, err
+}
+
+// RegenerateLast removes the response to the chat's last user message, with
+// its tool calls and results, and sends the message again.
+func (s *chatService) RegenerateLast(ctx context.Context, chatID string) (*entities.Message, error) {
+	message, restore, err := s.rewind(ctx, chatID, func(chat *entities.Chat) (int, error) {
+		for i := len(chat.Messages) - 1; i >= 0; i-- {
+			if chat.Messages[i].Role == "user" {
+				return i, nil
+			}
+		}
+		return 0, errors.ValidationErrorf("the chat has no message to regenerate")
+	})
+	if err != nil {
+		return nil, err
+	}
+	response, err := s.SendMessage(ctx, chatID, message)
+	if err != nil {
+		restore()
+	}
+	return response, err
+}
+
+// rewind removes the user message at the index pick returns and everything
+// after it, and returns the message so it can be sent again. A pending plan
+// always belongs to the last turn, so it goes too. The returned restore puts
+// the chat's messages back as they were, for when sending the message again
+// fails, so a failed retry doesn't lose the history it replaced.
+func (s *chatService) rewind(ctx context.Context, chatID string, pick func(*entities.Chat) (int, error)) (*entities.Message, func(), error) {
+	if chatID == "" {
+		return nil, nil, errors.ValidationErrorf("chat ID is required")
+	}
+	if s.responding(chatID) {
+		return nil, nil, errors.ValidationErrorf("a response is being generated for this chat")
+	}
+
+	chat, err := s.chatRepo.GetChat(ctx, chatID)
+	if err != nil {
+		return nil, nil, err
+	}
+	i, err := pick(chat)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	messages, plan := chat.Messages, chat.PendingPlan
+	message := chat.Messages[i]
+	message.Timestamp = time.Now()
+	removed := len(chat.Messages) - i
+	chat.Messages = slices.Clone(chat.Messages[:i])
+	chat.PendingPlan = nil
+	chat.UpdatedAt = time.Now()
+	if err := s.chatRepo.UpdateChat(ctx, chat); err != nil {
+		return nil, nil, err
+	}
+	s.logger.Info("Rewound chat to resend a message",
+		zap.String("chat_id", chatID),
+		zap.String("message_id", message.ID),
+		zap.Int("messages_removed", removed))
+
+	restore := func() {
+		// The send may have failed because ctx was canceled
+		ctx := context.WithoutCancel(ctx)
+		chat, err := s.chatRepo.GetChat(ctx, chatID)
+		if err == nil {
+			chat.Messages = messages
+			chat.PendingPlan = plan
+			chat.UpdatedAt = time.Now()
+			err = s.chatRepo.UpdateChat(ctx, chat)
+		}
+		if err != nil {
+			s.logger.Error("Failed to restore the chat after a failed resend", zap.String("chat_id", chatID), zap.Error(err))
+			return
+		}
+		s.logger.Info("Restored the chat after a failed resend", zap.String("chat_id", chatID))
+	}
+	return &message, restore, nil
+}
+
+// DeleteMessage removes a message from the chat without the tool calls and
+// results losing their pairing. Deleting a user message removes its whole
+// exchange, up to the next user message; deleting an assistant message also
+// removes the results of the tools it called. A tool result can't be deleted
+// on its own.
+func (s *chatService) DeleteMessage(ctx context.Context, chatID, messageID string) error {
+	if chatID == "" {
+		return errors.ValidationErrorf("chat ID is required")
+	}
+	if messageID == "" {
+		return errors.ValidationErrorf("message ID is required")
+	}
+	if s.responding(chatID) {
+		return errors.ValidationErrorf("a response is being generated for this chat")
+	}
+
+	chat, err := s.chatRepo.GetChat(ctx, chatID)
+	if err != nil {
+		return err
+	}
+	i := messageIndex(chat, messageID)
+	if i < 0 {
+		return errors.NotFoundErrorf("message %s not found", messageID)
+	}
+
+	remove := map[int]bool{i: true}
+	switch chat.Messages[i].Role {
+	case "tool":
+		return errors.ValidationErrorf("a tool result can't be deleted without its call; delete the assistant message that made the call")
+	case "user":
+		for j := i + 1; j < len(chat.Messages) && chat.Messages[j].Role != "user"; j++ {
+			remove[j] = true
+		}
+	}
+	// Take the results of every call being removed with it
+	calls := make(map[string]bool)
+	for j := range remove {
+		for _, call := range chat.Messages[j].ToolCalls {
+			calls[call.ID] = true
+		}
+	}
+	for j, msg := range chat.Messages {
+		if msg.Role == "tool" && calls[msg.ToolCallID] {
+			remove[j] = true
+		}
+	}
+
+	kept := make([]entities.Message, 0, len(chat.Messages)-len(remove))
+	for j, msg := range chat.Messages {
+		if !remove[j] {
+			kept = append(kept, msg)
+		}
+	}
+	// The pending plan is announced by the last message
+	if remove[len(chat.Messages)-1] {
+		chat.PendingPlan = nil
+	}
+	chat.Messages = kept
+	chat.UpdatedAt = time.Now()
+	if err := s.chatRepo.UpdateChat(ctx, chat); err != nil {
+		return err
+	}
+	s.logger.Info("Deleted chat messages",
+		zap.String("chat_id", chatID),
+		zap.String("message_id", messageID),
+		zap.Int("messages_removed", len(remove)))
+	return nil
+}
+
+// responding reports whether a response is being generated for the chat.
+func (s *chatService) responding(chatID string) bool {
+	s.steeringMu.Lock()
+	defer s.steeringMu.Unlock()
+	return s.steering[chatID] != nil
+}
+
+// messageIndex returns the position of the message in the chat, or -1.
+func messageIndex(chat *entities.Chat, messageID string) int {
+	return slices.IndexFunc(chat.Messages, func(msg entities.Message) bool {
+		return msg.ID == messageID
+	})
+}
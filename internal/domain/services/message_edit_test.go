@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+
+	"go.uber.org/zap"
+)
+
+// editableChat has two exchanges, the second with a tool call.
+func editableChat() *entities.Chat {
+	call := entities.ToolCall{ID: "call", Type: "function"}
+	call.Function.Name = "Bash"
+	return &entities.Chat{
+		ID: "chat",
+		Messages: []entities.Message{
+			{ID: "u1", Role: "user", Content: "hi"},
+			{ID: "a1", Role: "assistant", Content: "hello"},
+			{ID: "u2", Role: "user", Content: "list files"},
+			{ID: "a2", Role: "assistant", ToolCalls: []entities.ToolCall{call}},
+			{ID: "t2", Role: "tool", ToolCallID: "call", Content: "main.go"},
+			{ID: "a3", Role: "assistant", Content: "There is main.go"},
+		},
+		PendingPlan: []entities.PlannedToolCall{{ToolName: "Bash"}},
+	}
+}
+
+func messageIDs(chat *entities.Chat) []string {
+	var ids []string
+	for _, msg := range chat.Messages {
+		ids = append(ids, msg.ID)
+	}
+	return ids
+}
+
+func TestDeleteMessage(t *testing.T) {
+	ctx := context.Background()
+
+	repo := &memoryChatRepo{chat: editableChat()}
+	cs := &chatService{chatRepo: repo, logger: zap.NewNop()}
+	if err := cs.DeleteMessage(ctx, "chat", "a2"); err != nil {
+		t.Fatalf("DeleteMessage failed: %v", err)
+	}
+	if got := messageIDs(repo.chat); len(got) != 4 || got[3] != "a3" {
+		t.Errorf("Expected the tool call and its result to go together, got %v", got)
+	}
+	if repo.chat.PendingPlan == nil {
+		t.Error("Expected the plan to stay while its message is kept")
+	}
+
+	repo.chat = editableChat()
+	if err := cs.DeleteMessage(ctx, "chat", "u2"); err != nil {
+		t.Fatalf("DeleteMessage failed: %v", err)
+	}
+	if got := messageIDs(repo.chat); len(got) != 2 || got[1] != "a1" {
+		t.Errorf("Expected the whole exchange to be deleted, got %v", got)
+	}
+	if repo.chat.PendingPlan != nil {
+		t.Error("Expected the last exchange's plan to be dropped")
+	}
+
+	repo.chat = editableChat()
+	if err := cs.DeleteMessage(ctx, "chat", "t2"); err == nil {
+		t.Error("Expected a tool result alone to be refused")
+	}
+	if err := cs.DeleteMessage(ctx, "chat", "missing"); err == nil {
+		t.Error("Expected an unknown message to be refused")
+	}
+}
+
+func TestRewind(t *testing.T) {
+	ctx := context.Background()
+	repo := &memoryChatRepo{chat: editableChat()}
+	cs := &chatService{chatRepo: repo, logger: zap.NewNop()}
+
+	message, _, err := cs.rewind(ctx, "chat", func(chat *entities.Chat) (int, error) {
+		return messageIndex(chat, "u2"), nil
+	})
+	if err != nil {
+		t.Fatalf("rewind failed: %v", err)
+	}
+	if message.ID != "u2" || message.Content != "list files" {
+		t.Errorf("Expected the picked message back, got %+v", message)
+	}
+	if got := messageIDs(repo.chat); len(got) != 2 || got[1] != "a1" {
+		t.Errorf("Expected the chat to end before the message, got %v", got)
+	}
+	if repo.chat.PendingPlan != nil {
+		t.Error("Expected the pending plan to be dropped")
+	}
+
+	if _, err := cs.EditMessage(ctx, "chat", "a1", "changed"); err == nil {
+		t.Error("Expected an assistant message edit to be refused")
+	}
+
+	cs.startSteering("chat")
+	if _, err := cs.RegenerateLast(ctx, "chat"); err == nil {
+		t.Error("Expected a regenerate during a response to be refused")
+	}
+}
+
+func TestEditMessageRestoresHistoryOnFailure(t *testing.T) {
+	ctx := context.Background()
+	chat := editableChat()
+	chat.ModelID = "missing"
+	repo := &memoryChatRepo{chat: chat}
+	cs := &chatService{chatRepo: repo, modelRepo: &memoryModelRepo{}, logger: zap.NewNop()}
+
+	// The model lookup fails after the edited message is saved
+	if _, err := cs.EditMessage(ctx, "chat", "u2", "list all files"); err == nil {
+		t.Fatal("Expected the send to fail without a model")
+	}
+	if got := messageIDs(repo.chat); len(got) != 6 || got[5] != "a3" {
+		t.Errorf("Expected the original history back, got %v", got)
+	}
+	if repo.chat.Messages[2].Content != "list files" {
+		t.Errorf("Expected the original message back, got %q", repo.chat.Messages[2].Content)
+	}
+	if repo.chat.PendingPlan == nil {
+		t.Error("Expected the pending plan back")
+	}
+}
@@ -0,0 +1,16 @@
+package services
+
+import (
+	"github.com/drujensen/aiagent/internal/domain/entities"
+)
+
+// applyMessageOverrides puts the temperature and max tokens the message sets
+// over the model's settings for this request.
+func applyMessageOverrides(options map[string]any, message *entities.Message) {
+	if message.Temperature != nil {
+		options["temperature"] = *message.Temperature
+	}
+	if message.MaxTokens != nil {
+		options["max_tokens"] = *message.MaxTokens
+	}
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	errors "github.com/drujensen/aiagent/internal/domain/errs"
+)
+
+// inlineImagePattern matches images embedded in message content as data URLs.
+var inlineImagePattern = regexp.MustCompile(`data:image/[a-zA-Z0-9.+-]+;base64,[A-Za-z0-9+/=]+`)
+
+// checkImageSupport rejects a new message that carries images when the chat's
+// model cannot read them, instead of letting the provider fail the request.
+func checkImageSupport(model *entities.Model, message *entities.Message) error {
+	if model.SupportsImages() || (len(message.Attachments) == 0 && !inlineImagePattern.MatchString(message.Content)) {
+		return nil
+	}
+	return errors.ValidationErrorf("model %s does not accept images; switch to a model with image support or remove the image", model.Name)
+}
+
+// stripImages replaces the images in messages bound for a model that cannot
+// read them with a placeholder. It returns the number of images removed;
+// changed messages are copies, so the stored chat keeps its images.
+func stripImages(messages []*entities.Message) int {
+	removed := 0
+	for i, msg := range messages {
+		matches := inlineImagePattern.FindAllStringIndex(msg.Content, -1)
+		if len(matches) == 0 && len(msg.Attachments) == 0 {
+			continue
+		}
+		stripped := *msg
+		stripped.Content = inlineImagePattern.ReplaceAllString(msg.Content, "[image removed]")
+		for _, attachment := range msg.Attachments {
+			stripped.Content += fmt.Sprintf("\n[image removed: %s]", attachment.Name)
+		}
+		stripped.Attachments = nil
+		messages[i] = &stripped
+		removed += len(matches) + len(msg.Attachments)
+	}
+	return removed
+}
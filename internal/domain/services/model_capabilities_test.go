@@ -0,0 +1,93 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	errors "github.com/drujensen/aiagent/internal/domain/errs"
+)
+
+const testImage = "data:image/png;base64,iVBORw0KGgo="
+
+func TestCheckImageSupport(t *testing.T) {
+	textOnly := &entities.Model{Name: "Text Model", Family: "text", Attachment: false}
+	vision := &entities.Model{Name: "Vision Model", Family: "vision", Attachment: true}
+	unknown := &entities.Model{Name: "Custom Model"}
+
+	tests := []struct {
+		name    string
+		model   *entities.Model
+		content string
+		wantErr bool
+	}{
+		{"text to text-only model", textOnly, "hello", false},
+		{"image to text-only model", textOnly, "what is this? " + testImage, true},
+		{"image to vision model", vision, "what is this? " + testImage, false},
+		{"image to model without metadata", unknown, "what is this? " + testImage, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkImageSupport(tt.model, entities.NewMessage("user", tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkImageSupport() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if _, ok := err.(*errors.ValidationError); !ok {
+					t.Errorf("expected a validation error, got %T", err)
+				}
+			}
+		})
+	}
+}
+
+func TestStripImages(t *testing.T) {
+	original := entities.NewMessage("user", "compare "+testImage+" and "+testImage)
+	plain := entities.NewMessage("assistant", "they match")
+	messages := []*entities.Message{original, plain}
+
+	if removed := stripImages(messages); removed != 2 {
+		t.Errorf("expected 2 images removed, got %d", removed)
+	}
+	if got, want := messages[0].Content, "compare [image removed] and [image removed]"; got != want {
+		t.Errorf("content = %q, want %q", got, want)
+	}
+	if messages[1] != plain {
+		t.Errorf("expected messages without images to be left alone")
+	}
+	if original.Content == messages[0].Content {
+		t.Errorf("expected the original message to keep its images")
+	}
+}
+
+func TestAttachmentSupport(t *testing.T) {
+	textOnly := &entities.Model{Name: "Text Model", Family: "text"}
+	message := entities.NewMessage("user", "what is this?")
+	message.Attachments = []entities.Attachment{{Type: entities.AttachmentImage, MimeType: "image/png", Name: "chart.png", Data: "iVBORw0KGgo="}}
+
+	if err := checkImageSupport(textOnly, message); err == nil {
+		t.Errorf("expected an attachment to a text-only model to be refused")
+	}
+
+	messages := []*entities.Message{message}
+	if removed := stripImages(messages); removed != 1 {
+		t.Errorf("expected 1 image removed, got %d", removed)
+	}
+	if got, want := messages[0].Content, "what is this?\n[image removed: chart.png]"; got != want {
+		t.Errorf("content = %q, want %q", got, want)
+	}
+	if len(messages[0].Attachments) != 0 || len(message.Attachments) != 1 {
+		t.Errorf("expected only the copy sent to the model to lose its attachment")
+	}
+}
+
+func TestModelSupportsTools(t *testing.T) {
+	if !(&entities.Model{}).SupportsTools() {
+		t.Errorf("expected a model without metadata to support tools")
+	}
+	if (&entities.Model{Family: "embedding"}).SupportsTools() {
+		t.Errorf("expected a model without tool_call to not support tools")
+	}
+	if !(&entities.Model{Family: "gpt", ToolCall: true}).SupportsTools() {
+		t.Errorf("expected a model with tool_call to support tools")
+	}
+}
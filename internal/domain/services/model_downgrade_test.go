@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/impl/config"
+
+	"go.uber.org/zap"
+)
+
+func TestModelDowngrade(t *testing.T) {
+	ctx := context.Background()
+	provider := &entities.Provider{ID: "provider", Name: "Provider", Type: entities.ProviderGeneric, BaseURL: "http://localhost"}
+	preferred := &entities.Model{ID: "preferred", Name: "Big", ProviderID: provider.ID, ModelName: "big"}
+	cheap := &entities.Model{ID: "cheap", Name: "Small", ProviderID: provider.ID, ModelName: "small"}
+	other := &entities.Model{ID: "other", Name: "Other", ProviderID: provider.ID, ModelName: "other"}
+	repo := &memoryChatRepo{chat: &entities.Chat{ID: "chat", ModelID: preferred.ID}}
+	newService := func() *chatService {
+		return &chatService{
+			chatRepo:     repo,
+			modelRepo:    &memoryModelRepo{models: []*entities.Model{preferred, cheap, other}},
+			globalConfig: &config.GlobalConfig{ModelDowngrades: map[string]string{"big": "small"}, DowngradeCooldownMinutes: 10},
+			logger:       zap.NewNop(),
+		}
+	}
+
+	cs := newService()
+	chat := repo.chat
+	if _, _, err := cs.downgradeModel(ctx, chat, preferred, provider, "key", "Rate limited"); err != nil {
+		t.Fatalf("downgradeModel failed: %v", err)
+	}
+	if chat.ModelID != cheap.ID || chat.Downgrade == nil || chat.Downgrade.PreferredModelID != preferred.ID || chat.Downgrade.ModelID != cheap.ID {
+		t.Fatalf("Expected the downgrade to be saved on the chat, got model %s and %+v", chat.ModelID, chat.Downgrade)
+	}
+
+	// Still near the limit on the cheap model puts off going back
+	chat.Downgrade.Until = time.Now().Add(time.Minute)
+	cs.downgradeNearQuota(ctx, chat, cheap, provider, "key")
+	if chat.ModelID != cheap.ID || chat.Downgrade.PreferredModelID != preferred.ID || time.Until(chat.Downgrade.Until) < 9*time.Minute {
+		t.Errorf("Expected the cooldown to start again, got %+v", chat.Downgrade)
+	}
+	cs.restoreDowngradedModel(ctx, chat)
+	if chat.ModelID != cheap.ID {
+		t.Error("Expected the chat to stay on the cheap model during the cooldown")
+	}
+
+	// A restarted service restores the preferred model once the cooldown is over
+	chat.Downgrade.Until = time.Now().Add(-time.Minute)
+	newService().restoreDowngradedModel(ctx, chat)
+	if chat.ModelID != preferred.ID || chat.Downgrade != nil {
+		t.Errorf("Expected the preferred model back, got model %s and %+v", chat.ModelID, chat.Downgrade)
+	}
+
+	// A model the user picked during the cooldown is kept
+	if _, _, err := cs.downgradeModel(ctx, chat, preferred, provider, "key", "Rate limited"); err != nil {
+		t.Fatalf("downgradeModel failed: %v", err)
+	}
+	chat.ModelID = other.ID
+	chat.Downgrade.Until = time.Now().Add(-time.Minute)
+	cs.restoreDowngradedModel(ctx, chat)
+	if chat.ModelID != other.ID || chat.Downgrade != nil {
+		t.Errorf("Expected the user's model to be kept, got model %s and %+v", chat.ModelID, chat.Downgrade)
+	}
+
+	if _, _, err := cs.downgradeModel(ctx, chat, other, provider, "key", "Rate limited"); err == nil {
+		t.Error("Expected no downgrade for a model without one configured")
+	}
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	errors "github.com/drujensen/aiagent/internal/domain/errs"
+	"github.com/drujensen/aiagent/internal/domain/interfaces"
+)
+
+var serverErrorStatus = regexp.MustCompile(`status 5\d\d`)
+
+// isFallbackError reports whether a failed request should be retried on the
+// next model of the agent's fallback chain: rate limits, 5xx responses,
+// network failures and request timeouts. Cancellation by the user is not.
+func isFallbackError(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	if _, ok := err.(*errors.RateLimitError); ok {
+		return true
+	}
+
+	errStr := strings.ToLower(err.Error())
+	return serverErrorStatus.MatchString(errStr) ||
+		strings.Contains(errStr, "error making request") ||
+		strings.Contains(errStr, "deadline exceeded") ||
+		strings.Contains(errStr, "timeout")
+}
+
+// modelFallback is a resolved entry of an agent's fallback chain.
+type modelFallback struct {
+	model    *entities.Model
+	provider *entities.Provider
+	apiKey   string
+	aiModel  interfaces.AIModelIntegration
+}
+
+// nextFallback resolves the first usable model of the chain and returns the
+// remaining chain. Entries that cannot be resolved are skipped.
+func (s *chatService) nextFallback(ctx context.Context, chain []string) (*modelFallback, []string, error) {
+	var lastErr error
+	for len(chain) > 0 {
+		modelID := chain[0]
+		chain = chain[1:]
+
+		fallback, err := s.resolveFallback(ctx, modelID)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return fallback, chain, nil
+	}
+	if lastErr == nil {
+		lastErr = errors.NotFoundErrorf("no fallback models left")
+	}
+	return nil, nil, lastErr
+}
+
+func (s *chatService) resolveFallback(ctx context.Context, modelID string) (*modelFallback, error) {
+	model, err := s.modelRepo.GetModel(ctx, modelID)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := s.providerRepo.GetProvider(ctx, model.ProviderID)
+	if err != nil {
+		return nil, err
+	}
+	apiKey, err := s.providerAPIKey(provider)
+	if err != nil {
+		return nil, errors.InternalErrorf("failed to resolve API key for provider %s: %v", provider.ID, err)
+	}
+	aiModel, err := s.newModelIntegration(ctx, model, provider, apiKey)
+	if err != nil {
+		return nil, errors.InternalErrorf("failed to initialize fallback model: %v", err)
+	}
+	return &modelFallback{model: model, provider: provider, apiKey: apiKey, aiModel: aiModel}, nil
+}
@@ -206,12 +206,14 @@ func (s *modelRefreshService) refreshProvider(ctx context.Context, provider *ent
 
 		// Update provider with config data
 		providerToUpdate := &entities.Provider{
-			ID:         provider.ID,
-			Name:       customConfig.Name,
-			Type:       entities.ProviderType(customConfig.Type),
-			BaseURL:    customConfig.BaseURL,
-			APIKeyName: customConfig.APIKeyName,
-			Models:     make([]entities.ModelPricing, 0),
+			ID:                    provider.ID,
+			Name:                  customConfig.Name,
+			Type:                  entities.ProviderType(customConfig.Type),
+			BaseURL:               customConfig.BaseURL,
+			APIKeyName:            customConfig.APIKeyName,
+			Models:                make([]entities.ModelPricing, 0),
+			RequestsPerMinute:     provider.RequestsPerMinute,
+			RequestTimeoutSeconds: provider.RequestTimeoutSeconds,
 		}
 
 		// Add models from config
@@ -264,12 +266,14 @@ func (s *modelRefreshService) refreshProvider(ctx context.Context, provider *ent
 	}
 
 	providerToUpdate := &entities.Provider{
-		ID:         provider.ID,
-		Name:       provider.Name,
-		Type:       provider.Type,
-		BaseURL:    provider.BaseURL,
-		APIKeyName: provider.APIKeyName,
-		Models:     make([]entities.ModelPricing, 0),
+		ID:                    provider.ID,
+		Name:                  provider.Name,
+		Type:                  provider.Type,
+		BaseURL:               provider.BaseURL,
+		APIKeyName:            provider.APIKeyName,
+		Models:                make([]entities.ModelPricing, 0),
+		RequestsPerMinute:     provider.RequestsPerMinute,
+		RequestTimeoutSeconds: provider.RequestTimeoutSeconds,
 	}
 
 	// Handle provider key mapping for models.dev
@@ -286,6 +290,12 @@ func (s *modelRefreshService) refreshProvider(ctx context.Context, provider *ent
 			ContextWindow:       modelData.Limit.Context,
 			MaxOutputTokens:     modelData.Limit.Output,
 		}
+		if modelData.Cost.CacheRead != nil {
+			pricing.CacheReadPricePerMille = *modelData.Cost.CacheRead
+		}
+		if modelData.Cost.CacheWrite != nil {
+			pricing.CacheWritePricePerMille = *modelData.Cost.CacheWrite
+		}
 
 		providerToUpdate.Models = append(providerToUpdate.Models, pricing)
 	}
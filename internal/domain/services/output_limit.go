@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/domain/interfaces"
+
+	"go.uber.org/zap"
+)
+
+func shorterPrompt(limit *entities.OutputLimit) string {
+	return fmt.Sprintf("Your previous answer was too long. %s Reply again with a shorter answer.", limit.Instructions())
+}
+
+// enforceOutputLimit holds the final answer to the agent's output limit. In
+// reprompt mode the model is asked once for a shorter answer; an answer that
+// is still too long is truncated and the stored message updated to match.
+func (s *chatService) enforceOutputLimit(
+	ctx context.Context,
+	chatID string,
+	limit *entities.OutputLimit,
+	aiModel interfaces.AIModelIntegration,
+	history []*entities.Message,
+	newMessages []*entities.Message,
+	tools []entities.Tool,
+	options map[string]any,
+	callback interfaces.MessageCallback,
+) []*entities.Message {
+	if len(newMessages) == 0 || newMessages[len(newMessages)-1].Role != "assistant" {
+		return newMessages
+	}
+	if !limit.Exceeds(newMessages[len(newMessages)-1].Content) {
+		return newMessages
+	}
+
+	if limit.Mode == entities.OutputLimitReprompt && ctx.Err() == nil {
+		s.logger.Info("Answer exceeds the output limit, asking the model for a shorter one", zap.String("chat_id", chatID))
+
+		prompt := entities.NewMessage("user", shorterPrompt(limit))
+		if err := s.SaveMessagesIncrementally(ctx, chatID, []*entities.Message{prompt}); err != nil {
+			s.logger.Warn("Failed to save output limit prompt", zap.String("chat_id", chatID), zap.Error(err))
+		} else {
+			messages := make([]*entities.Message, 0, len(history)+len(newMessages)+1)
+			messages = append(messages, history...)
+			messages = append(messages, newMessages...)
+			messages = append(messages, prompt)
+
+			shorter, err := aiModel.GenerateResponse(ctx, messages, tools, options, callback)
+			newMessages = append(newMessages, prompt)
+			newMessages = append(newMessages, shorter...)
+			if err != nil {
+				s.logger.Warn("Failed to get a shorter answer", zap.String("chat_id", chatID), zap.Error(err))
+			}
+		}
+	}
+
+	last := newMessages[len(newMessages)-1]
+	if last.Role != "assistant" || !limit.Exceeds(last.Content) {
+		return newMessages
+	}
+
+	last.Content = limit.Truncate(last.Content)
+	if err := s.updateStoredContent(ctx, chatID, last); err != nil {
+		s.logger.Warn("Failed to store trimmed answer", zap.String("chat_id", chatID), zap.Error(err))
+	}
+	return newMessages
+}
+
+// updateStoredContent replaces the content of a message already saved to the chat.
+func (s *chatService) updateStoredContent(ctx context.Context, chatID string, message *entities.Message) error {
+	chat, err := s.chatRepo.GetChat(ctx, chatID)
+	if err != nil {
+		return err
+	}
+	for i := range chat.Messages {
+		if chat.Messages[i].ID == message.ID {
+			chat.Messages[i].Content = message.Content
+			return s.chatRepo.UpdateChat(ctx, chat)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	errors "github.com/drujensen/aiagent/internal/domain/errs"
+	"github.com/drujensen/aiagent/internal/impl/workspacepath"
+
+	"go.uber.org/zap"
+)
+
+// Limits of the pinned files sent with each turn. A file over the per-file
+// cap is cut; once the total is reached the remaining files are left out.
+const (
+	maxPinnedFileBytes = 64 * 1024
+	maxPinnedBytes     = 256 * 1024
+	// maxPinnedDirFiles caps the files a pinned directory contributes
+	maxPinnedDirFiles = 50
+)
+
+// PinFiles pins files or directories to the chat so their contents are sent
+// with every turn. Paths are relative to the chat's workspace and must stay
+// inside it; a directory contributes the text files directly in it. It
+// returns the chat's pinned paths.
+func (s *chatService) PinFiles(ctx context.Context, chatID string, paths []string) ([]string, error) {
+	if chatID == "" {
+		return nil, errors.ValidationErrorf("chat ID is required")
+	}
+	chat, err := s.chatRepo.GetChat(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	workspace := s.contextWorkspace(ctx, chat)
+	for _, path := range paths {
+		rel, err := pinnedPath(workspace, path)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(chat.PinnedFiles, rel) {
+			chat.PinnedFiles = append(chat.PinnedFiles, rel)
+		}
+	}
+
+	chat.UpdatedAt = time.Now()
+	if err := s.chatRepo.UpdateChat(ctx, chat); err != nil {
+		return nil, err
+	}
+	return chat.PinnedFiles, nil
+}
+
+// UnpinFiles stops sending the given pinned paths, or every pinned path when
+// none are given. It returns the paths still pinned.
+func (s *chatService) UnpinFiles(ctx context.Context, chatID string, paths []string) ([]string, error) {
+	if chatID == "" {
+		return nil, errors.ValidationErrorf("chat ID is required")
+	}
+	chat, err := s.chatRepo.GetChat(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(paths) == 0 {
+		chat.PinnedFiles = nil
+	} else {
+		for _, path := range paths {
+			path = filepath.Clean(path)
+			i := slices.Index(chat.PinnedFiles, path)
+			if i < 0 {
+				return nil, errors.NotFoundErrorf("%s is not pinned", path)
+			}
+			chat.PinnedFiles = slices.Delete(chat.PinnedFiles, i, i+1)
+		}
+	}
+
+	chat.UpdatedAt = time.Now()
+	if err := s.chatRepo.UpdateChat(ctx, chat); err != nil {
+		return nil, err
+	}
+	return chat.PinnedFiles, nil
+}
+
+// pinnedPath checks that path names an existing file or directory inside the
+// workspace, following symlinks, and returns it relative to the workspace.
+func pinnedPath(workspace, path string) (string, error) {
+	if workspace == "" {
+		return "", errors.ValidationErrorf("the chat has no workspace to pin files from")
+	}
+	fullPath, err := workspacepath.Resolve(workspace, path)
+	if err != nil {
+		return "", errors.ValidationErrorf("%s: %v", path, err)
+	}
+	if _, err := os.Stat(fullPath); err != nil {
+		return "", errors.ValidationErrorf("%s does not exist", path)
+	}
+	rel, err := filepath.Rel(workspacepath.Real(workspace), workspacepath.Real(fullPath))
+	if err != nil {
+		return "", errors.ValidationErrorf("%s is outside the workspace %s", path, workspace)
+	}
+	return rel, nil
+}
+
+// pinnedFileLayers reads the chat's pinned files afresh, so the model sees
+// their current contents. Files that went missing or left the workspace are
+// skipped with a warning rather than failing the turn.
+func (s *chatService) pinnedFileLayers(ctx context.Context, chat *entities.Chat) []entities.SystemLayer {
+	if len(chat.PinnedFiles) == 0 {
+		return nil
+	}
+	workspace := s.contextWorkspace(ctx, chat)
+
+	var layers []entities.SystemLayer
+	remaining := maxPinnedBytes
+	for _, pinned := range chat.PinnedFiles {
+		rel, err := pinnedPath(workspace, pinned)
+		if err != nil {
+			s.logger.Warn("Skipping pinned file", zap.String("path", pinned), zap.Error(err))
+			continue
+		}
+		for _, file := range pinnedFiles(filepath.Join(workspace, rel), rel) {
+			// A directory's entries may be symlinks out of the workspace
+			if _, err := pinnedPath(workspace, file); err != nil {
+				s.logger.Warn("Skipping pinned file", zap.String("path", file), zap.Error(err))
+				continue
+			}
+			if remaining <= 0 {
+				s.logger.Warn("Pinned files cap reached, skipping file", zap.String("path", file))
+				return layers
+			}
+			content, err := readPinnedFile(filepath.Join(workspace, file), min(maxPinnedFileBytes, remaining))
+			if err != nil {
+				s.logger.Warn("Skipping pinned file", zap.String("path", file), zap.Error(err))
+				continue
+			}
+			remaining -= len(content)
+			layers = append(layers, entities.SystemLayer{Kind: entities.SystemLayerFile, Source: file, Content: content})
+		}
+	}
+	return layers
+}
+
+// pinnedFiles lists the files a pinned path stands for, relative to the
+// workspace: the file itself, or the files directly in a directory in name
+// order. Hidden files are left out of directories.
+func pinnedFiles(path, rel string) []string {
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		return []string{rel}
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if len(files) == maxPinnedDirFiles {
+			break
+		}
+		files = append(files, filepath.Join(rel, entry.Name()))
+	}
+	return files
+}
+
+// readPinnedFile returns up to limit bytes of a text file, with a note when
+// the rest was cut. Binary files are refused.
+func readPinnedFile(path string, limit int) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data) {
+		return "", fmt.Errorf("not a text file")
+	}
+	if len(data) <= limit {
+		return string(data), nil
+	}
+	content := strings.ToValidUTF8(string(data[:limit]), "")
+	return content + fmt.Sprintf("\n[truncated: %d of %d bytes shown]", len(content), len(data)), nil
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+
+	"go.uber.org/zap"
+)
+
+func TestPinFiles(t *testing.T) {
+	workspace := t.TempDir()
+	os.WriteFile(filepath.Join(workspace, "main.go"), []byte("package main"), 0644)
+	os.Mkdir(filepath.Join(workspace, "docs"), 0755)
+	os.WriteFile(filepath.Join(workspace, "docs", "a.md"), []byte("# A"), 0644)
+	os.WriteFile(filepath.Join(workspace, "docs", "b.md"), []byte("# B"), 0644)
+	os.WriteFile(filepath.Join(workspace, "docs", "logo.png"), []byte{0x89, 'P', 'N', 'G', 0}, 0644)
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	os.WriteFile(outside, []byte("secret"), 0644)
+
+	repo := &memoryChatRepo{chat: &entities.Chat{ID: "chat", Workspace: workspace}}
+	cs := &chatService{chatRepo: repo, logger: zap.NewNop()}
+	ctx := context.Background()
+
+	pinned, err := cs.PinFiles(ctx, "chat", []string{"main.go", filepath.Join(workspace, "docs"), "main.go"})
+	if err != nil {
+		t.Fatalf("PinFiles failed: %v", err)
+	}
+	if len(pinned) != 2 || pinned[0] != "main.go" || pinned[1] != "docs" {
+		t.Errorf("Expected main.go and docs pinned once each, got %v", pinned)
+	}
+	if _, err := cs.PinFiles(ctx, "chat", []string{outside}); err == nil {
+		t.Error("Expected a file outside the workspace to be refused")
+	}
+	if _, err := cs.PinFiles(ctx, "chat", []string{"../secret.txt"}); err == nil {
+		t.Error("Expected a relative path out of the workspace to be refused")
+	}
+	if _, err := cs.PinFiles(ctx, "chat", []string{"missing.go"}); err == nil {
+		t.Error("Expected a missing file to be refused")
+	}
+
+	layers := cs.pinnedFileLayers(ctx, repo.chat)
+	var sources []string
+	for _, layer := range layers {
+		sources = append(sources, layer.Source)
+	}
+	if strings.Join(sources, ",") != "main.go,docs/a.md,docs/b.md" {
+		t.Errorf("Expected the file and the directory's text files, got %v", sources)
+	}
+	if layers[0].Content != "package main" || layers[0].Kind != entities.SystemLayerFile {
+		t.Errorf("Unexpected layer: %+v", layers[0])
+	}
+
+	// The contents are read again for each turn
+	os.WriteFile(filepath.Join(workspace, "main.go"), []byte("package main\n\nfunc main() {}"), 0644)
+	if layers := cs.pinnedFileLayers(ctx, repo.chat); !strings.Contains(layers[0].Content, "func main") {
+		t.Errorf("Expected the current contents, got %q", layers[0].Content)
+	}
+
+	pinned, err = cs.UnpinFiles(ctx, "chat", []string{"docs"})
+	if err != nil || len(pinned) != 1 || pinned[0] != "main.go" {
+		t.Errorf("Expected only main.go left, got %v, %v", pinned, err)
+	}
+	if _, err := cs.UnpinFiles(ctx, "chat", []string{"docs"}); err == nil {
+		t.Error("Expected unpinning a path that isn't pinned to fail")
+	}
+	if pinned, _ := cs.UnpinFiles(ctx, "chat", nil); len(pinned) != 0 {
+		t.Errorf("Expected every file unpinned, got %v", pinned)
+	}
+}
+
+func TestReadPinnedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "big.txt")
+	os.WriteFile(path, []byte(strings.Repeat("x", 100)), 0644)
+
+	content, err := readPinnedFile(path, 10)
+	if err != nil {
+		t.Fatalf("readPinnedFile failed: %v", err)
+	}
+	if !strings.HasPrefix(content, strings.Repeat("x", 10)+"\n[truncated: 10 of 100 bytes shown]") {
+		t.Errorf("Expected the file cut with a note, got %q", content)
+	}
+}
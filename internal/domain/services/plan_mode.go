@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	errors "github.com/drujensen/aiagent/internal/domain/errs"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const planModeInstructions = "You are in plan mode. Read-only tools run normally, but tools that change files, run commands or have other side effects are not executed; each such call is added to a plan for the user to review. Investigate first, then make the calls needed to complete the task and finish with a short summary of the plan."
+
+// toolPlan collects the mutating tool calls held back during a plan mode
+// turn. Tools may run in parallel, so steps are added under a lock.
+type toolPlan struct {
+	mu    sync.Mutex
+	steps []entities.PlannedToolCall
+}
+
+// Plan adds a tool call to the plan and returns its step number.
+func (p *toolPlan) Plan(toolName, arguments string) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.steps = append(p.steps, entities.PlannedToolCall{ToolName: toolName, Arguments: arguments})
+	return len(p.steps)
+}
+
+// Steps returns the tool calls planned so far.
+func (p *toolPlan) Steps() []entities.PlannedToolCall {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]entities.PlannedToolCall(nil), p.steps...)
+}
+
+// savePlan stores the plan on the chat until the user approves or discards
+// it and adds it to the history as a system message.
+func (s *chatService) savePlan(ctx context.Context, chatID string, steps []entities.PlannedToolCall) (*entities.Message, error) {
+	chat, err := s.chatRepo.GetChat(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	planMsg := &entities.Message{
+		ID:        uuid.New().String(),
+		Role:      "system",
+		Content:   entities.FormatPlan(steps) + "\n\nApprove the plan to run these steps or discard it.",
+		Timestamp: time.Now(),
+	}
+	chat.PendingPlan = steps
+	chat.Messages = append(chat.Messages, *planMsg)
+	chat.UpdatedAt = time.Now()
+	if err := s.chatRepo.UpdateChat(ctx, chat); err != nil {
+		return nil, err
+	}
+	return planMsg, nil
+}
+
+// ApprovePlan runs the chat's pending plan in order. It stops at the first
+// step that fails; the steps that ran and the failure are reported in a
+// system message added to the chat.
+func (s *chatService) ApprovePlan(ctx context.Context, chatID string) (*entities.Message, error) {
+	if chatID == "" {
+		return nil, errors.ValidationErrorf("chat ID is required")
+	}
+
+	chat, err := s.chatRepo.GetChat(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if len(chat.PendingPlan) == 0 {
+		return nil, errors.ValidationErrorf("chat has no pending plan")
+	}
+	if agent, err := s.agentRepo.GetAgent(ctx, chat.AgentID); err == nil {
+		ctx = entities.WithToolEnv(ctx, agent.Env)
+	}
+
+	var report strings.Builder
+	report.WriteString("Plan approved:\n")
+	for i, step := range chat.PendingPlan {
+		result, err := s.runTool(ctx, chat.Workspace, step.ToolName, step.Arguments)
+		if err != nil {
+			s.logger.Warn("Planned tool call failed", zap.String("chat_id", chatID), zap.String("tool", step.ToolName), zap.Error(err))
+			fmt.Fprintf(&report, "%d. %s failed: %v\nThe remaining steps were not run.", i+1, step.ToolName, err)
+			break
+		}
+		fmt.Fprintf(&report, "%d. %s: %s\n", i+1, step.ToolName, result)
+	}
+
+	resultMsg := &entities.Message{
+		ID:        uuid.New().String(),
+		Role:      "system",
+		Content:   strings.TrimRight(report.String(), "\n"),
+		Timestamp: time.Now(),
+	}
+	chat.PendingPlan = nil
+	chat.Messages = append(chat.Messages, *resultMsg)
+	chat.UpdatedAt = time.Now()
+	if err := s.chatRepo.UpdateChat(ctx, chat); err != nil {
+		return nil, err
+	}
+	return resultMsg, nil
+}
+
+// DiscardPlan drops the chat's pending plan without running it.
+func (s *chatService) DiscardPlan(ctx context.Context, chatID string) error {
+	if chatID == "" {
+		return errors.ValidationErrorf("chat ID is required")
+	}
+
+	chat, err := s.chatRepo.GetChat(ctx, chatID)
+	if err != nil {
+		return err
+	}
+	if len(chat.PendingPlan) == 0 {
+		return errors.ValidationErrorf("chat has no pending plan")
+	}
+
+	chat.PendingPlan = nil
+	chat.Messages = append(chat.Messages, entities.Message{
+		ID:        uuid.New().String(),
+		Role:      "system",
+		Content:   "Plan discarded.",
+		Timestamp: time.Now(),
+	})
+	chat.UpdatedAt = time.Now()
+	return s.chatRepo.UpdateChat(ctx, chat)
+}
+
+// runTool runs a tool outside of a model turn with its configuration
+// resolved, in the chat's workspace when it has one, and the environment ctx
+// carries for tools.
+func (s *chatService) runTool(ctx context.Context, workspace, toolName, arguments string) (string, error) {
+	tool, err := s.toolRepo.GetToolByName(toolName)
+	if err != nil {
+		return "", err
+	}
+	if tool == nil {
+		return "", fmt.Errorf("tool %s not found", toolName)
+	}
+	tool, _, err = s.configureTool(tool, toolName, s.toolConfigurations(ctx), workspace, entities.ToolEnv(ctx))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve configuration for tool %s: %v", toolName, err)
+	}
+	return tool.Execute(ctx, arguments)
+}
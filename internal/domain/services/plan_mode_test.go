@@ -0,0 +1,42 @@
+package services
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+)
+
+func TestToolPlan(t *testing.T) {
+	plan := &toolPlan{}
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			plan.Plan("Write", `{"filePath":"a.go"}`)
+		}()
+	}
+	wg.Wait()
+
+	if steps := plan.Steps(); len(steps) != 5 {
+		t.Fatalf("Expected 5 steps, got %d", len(steps))
+	}
+	if step := plan.Plan("Process", `{"command":"go test"}`); step != 6 {
+		t.Errorf("Expected step 6, got %d", step)
+	}
+}
+
+func TestToolPlan_Format(t *testing.T) {
+	plan := &toolPlan{}
+	plan.Plan("Write", `{"filePath":"a.go"}`)
+	plan.Plan("Process", `{"command":"go test"}`)
+
+	text := entities.FormatPlan(plan.Steps())
+	for _, want := range []string{"1. Write", "2. Process", "go test"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected plan to contain %q, got %q", want, text)
+		}
+	}
+}
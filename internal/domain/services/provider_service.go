@@ -3,11 +3,14 @@ package services
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/drujensen/aiagent/internal/domain/entities"
 	"github.com/drujensen/aiagent/internal/domain/errs"
 	"github.com/drujensen/aiagent/internal/domain/interfaces"
 	"github.com/drujensen/aiagent/internal/impl/config"
+	"github.com/drujensen/aiagent/internal/impl/integrations"
 
 	"go.uber.org/zap"
 )
@@ -16,16 +19,22 @@ type ProviderService interface {
 	ListProviders(ctx context.Context) ([]*entities.Provider, error)
 	GetProvider(ctx context.Context, id string) (*entities.Provider, error)
 	EnsureCustomProviders(ctx context.Context, globalConfig *config.GlobalConfig) error
+	SetRequestsPerMinute(ctx context.Context, id string, requestsPerMinute int) error
+	SetRequestTimeout(ctx context.Context, id string, timeoutSeconds *int) error
+	CheckProvider(ctx context.Context, providerID string) error
+	SyncModels(ctx context.Context, providerID string) error
 }
 
 type providerService struct {
 	providerRepo interfaces.ProviderRepository
+	config       *config.Config
 	logger       *zap.Logger
 }
 
-func NewProviderService(providerRepo interfaces.ProviderRepository, logger *zap.Logger) ProviderService {
+func NewProviderService(providerRepo interfaces.ProviderRepository, cfg *config.Config, logger *zap.Logger) ProviderService {
 	return &providerService{
 		providerRepo: providerRepo,
+		config:       cfg,
 		logger:       logger,
 	}
 }
@@ -80,7 +89,12 @@ func (s *providerService) EnsureCustomProviders(ctx context.Context, globalConfi
 			BaseURL:    customConfig.BaseURL,
 			APIKeyName: customConfig.APIKeyName,
 			Models:     []entities.ModelPricing{}, // Will be populated during refresh
+
+			DisableTypeDetection: customConfig.DisableTypeDetection,
+			ToolCallIDFormat:     entities.ToolCallIDFormat(customConfig.ToolCallIDFormat),
+			StrictToolSchemas:    customConfig.StrictToolSchemas,
 		}
+		s.detectProviderType(ctx, provider)
 
 		if err := s.providerRepo.CreateProvider(ctx, provider); err != nil {
 			return fmt.Errorf("failed to create custom provider %s: %w", providerKey, err)
@@ -94,3 +108,137 @@ func (s *providerService) EnsureCustomProviders(ctx context.Context, globalConfi
 
 	return nil
 }
+
+// detectProviderType probes a generic provider whose base URL is not a
+// well-known one to find out whether it speaks a native API. A failed probe
+// leaves the provider on the OpenAI-compatible integration.
+func (s *providerService) detectProviderType(ctx context.Context, provider *entities.Provider) {
+	if provider.EffectiveType() != entities.ProviderGeneric || provider.DisableTypeDetection || provider.BaseURL == "" {
+		return
+	}
+
+	// The probe still works without a key against servers that need none
+	apiKey, _ := providerAPIKey(s.config, provider)
+	detected, err := integrations.ProbeProviderType(ctx, provider.BaseURL, apiKey)
+	if err != nil {
+		s.logger.Debug("Could not probe provider type",
+			zap.String("name", provider.Name),
+			zap.Error(err))
+		return
+	}
+	if detected != entities.ProviderGeneric {
+		s.logger.Info("Detected provider type",
+			zap.String("name", provider.Name),
+			zap.String("type", string(detected)))
+		provider.DetectedType = detected
+	}
+}
+
+// SetRequestsPerMinute sets the provider's shared request rate limit. Zero
+// removes the limit.
+func (s *providerService) SetRequestsPerMinute(ctx context.Context, id string, requestsPerMinute int) error {
+	if requestsPerMinute < 0 {
+		return errors.ValidationErrorf("requests per minute must not be negative")
+	}
+
+	provider, err := s.providerRepo.GetProvider(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	provider.RequestsPerMinute = requestsPerMinute
+	provider.UpdatedAt = time.Now()
+	return s.providerRepo.UpdateProvider(ctx, provider)
+}
+
+// SetRequestTimeout sets how long a single model request may take. Nil
+// restores the default and zero disables the deadline.
+func (s *providerService) SetRequestTimeout(ctx context.Context, id string, timeoutSeconds *int) error {
+	if timeoutSeconds != nil && *timeoutSeconds < 0 {
+		return errors.ValidationErrorf("request timeout must not be negative")
+	}
+
+	provider, err := s.providerRepo.GetProvider(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	provider.RequestTimeoutSeconds = timeoutSeconds
+	provider.UpdatedAt = time.Now()
+	return s.providerRepo.UpdateProvider(ctx, provider)
+}
+
+// CheckProvider makes an authenticated request to the provider's models
+// endpoint to confirm its base URL and API key work.
+func (s *providerService) CheckProvider(ctx context.Context, providerID string) error {
+	_, err := s.listModels(ctx, providerID)
+	return err
+}
+
+// SyncModels reconciles the provider's models with the ones its models
+// endpoint lists. Models it started listing since the last sync are added
+// without pricing and flagged new, ones it stopped listing are kept and
+// flagged removed, and the pricing of known models is left as is.
+func (s *providerService) SyncModels(ctx context.Context, providerID string) error {
+	provider, err := s.providerRepo.GetProvider(ctx, providerID)
+	if err != nil {
+		return err
+	}
+	listed, err := s.listModels(ctx, providerID)
+	if err != nil {
+		return err
+	}
+
+	provider.Models = reconcileModels(provider.Models, listed)
+	provider.UpdatedAt = time.Now()
+	if err := s.providerRepo.UpdateProvider(ctx, provider); err != nil {
+		return err
+	}
+	s.logger.Info("Synced provider models", zap.String("provider", provider.Name), zap.Int("listed", len(listed)))
+	return nil
+}
+
+func (s *providerService) listModels(ctx context.Context, providerID string) ([]string, error) {
+	provider, err := s.providerRepo.GetProvider(ctx, providerID)
+	if err != nil {
+		return nil, err
+	}
+	apiKey, err := providerAPIKey(s.config, provider)
+	if err != nil {
+		return nil, errors.ValidationErrorf("%s is not set", provider.APIKeyName)
+	}
+	models, err := integrations.ListProviderModels(ctx, provider, apiKey)
+	if err != nil {
+		return nil, errors.ValidationErrorf("%s: %v", provider.Name, err)
+	}
+	return models, nil
+}
+
+// reconcileModels merges the model IDs a provider lists into its known
+// models, flagging the ones that were added or are no longer listed.
+func reconcileModels(known []entities.ModelPricing, listed []string) []entities.ModelPricing {
+	isListed := make(map[string]bool, len(listed))
+	for _, id := range listed {
+		isListed[strings.ToLower(id)] = true
+	}
+
+	seen := make(map[string]bool, len(known))
+	models := make([]entities.ModelPricing, 0, len(known)+len(listed))
+	for _, model := range known {
+		name := strings.ToLower(model.Name)
+		seen[name] = true
+		model.SyncStatus = ""
+		if !isListed[name] {
+			model.SyncStatus = entities.ModelSyncRemoved
+		}
+		models = append(models, model)
+	}
+	for _, id := range listed {
+		if seen[strings.ToLower(id)] {
+			continue
+		}
+		seen[strings.ToLower(id)] = true
+		models = append(models, entities.ModelPricing{Name: id, SyncStatus: entities.ModelSyncNew})
+	}
+	return models
+}
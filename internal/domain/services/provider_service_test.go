@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/impl/config"
+
+	"go.uber.org/zap"
+)
+
+func (r *memoryProviderRepo) UpdateProvider(ctx context.Context, provider *entities.Provider) error {
+	for i, p := range r.providers {
+		if p.ID == provider.ID {
+			r.providers[i] = provider
+		}
+	}
+	return nil
+}
+
+func TestSyncModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"object":"list","data":[{"id":"gpt-4o"},{"id":"gpt-5"}]}`))
+	}))
+	defer server.Close()
+	t.Setenv("TEST_PROVIDER_KEY", "key")
+
+	provider := entities.NewProvider("openai-id", "OpenAI", entities.ProviderOpenAI, server.URL, "TEST_PROVIDER_KEY", []entities.ModelPricing{
+		{Name: "gpt-4o", InputPricePerMille: 2.5, OutputPricePerMille: 10},
+		{Name: "gpt-3.5-turbo", InputPricePerMille: 0.5},
+	})
+	repo := &memoryProviderRepo{providers: []*entities.Provider{provider}}
+	cfg, err := config.InitConfig()
+	if err != nil {
+		t.Fatalf("InitConfig: %v", err)
+	}
+	service := NewProviderService(repo, cfg, zap.NewNop())
+
+	if err := service.CheckProvider(context.Background(), provider.ID); err != nil {
+		t.Fatalf("CheckProvider: %v", err)
+	}
+	if err := service.SyncModels(context.Background(), provider.ID); err != nil {
+		t.Fatalf("SyncModels: %v", err)
+	}
+
+	models := repo.providers[0].Models
+	if len(models) != 3 {
+		t.Fatalf("expected 3 models, got %+v", models)
+	}
+	if models[0].Name != "gpt-4o" || models[0].SyncStatus != "" || models[0].InputPricePerMille != 2.5 {
+		t.Errorf("expected the listed model to keep its pricing, got %+v", models[0])
+	}
+	if models[1].Name != "gpt-3.5-turbo" || models[1].SyncStatus != entities.ModelSyncRemoved {
+		t.Errorf("expected the unlisted model to be flagged removed, got %+v", models[1])
+	}
+	if models[2].Name != "gpt-5" || models[2].SyncStatus != entities.ModelSyncNew {
+		t.Errorf("expected the new model to be flagged new, got %+v", models[2])
+	}
+
+	// A later sync no longer flags the model as new
+	if err := service.SyncModels(context.Background(), provider.ID); err != nil {
+		t.Fatalf("SyncModels: %v", err)
+	}
+	if models := repo.providers[0].Models; len(models) != 3 || models[2].SyncStatus != "" {
+		t.Errorf("unexpected models after the second sync: %+v", models)
+	}
+
+	t.Setenv("TEST_PROVIDER_KEY", "")
+	if err := service.CheckProvider(context.Background(), provider.ID); err == nil {
+		t.Errorf("expected a missing API key to fail the check")
+	}
+}
@@ -0,0 +1,295 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/domain/interfaces"
+
+	"go.uber.org/zap"
+)
+
+// maxReportedProblems caps the validation errors sent back to the model.
+const maxReportedProblems = 20
+
+// validateStructuredResponse checks a response against the format and returns
+// the problems found. A response wrapped in a markdown code fence is accepted.
+func validateStructuredResponse(format *entities.ResponseFormat, content string) []string {
+	var value any
+	if err := json.Unmarshal([]byte(stripCodeFence(content)), &value); err != nil {
+		return []string{fmt.Sprintf("response is not valid JSON: %v", err)}
+	}
+	if format.Type != entities.ResponseFormatJSONSchema {
+		return nil
+	}
+
+	problems := validateSchema(format.Schema, value, "$")
+	if len(problems) > maxReportedProblems {
+		problems = append(problems[:maxReportedProblems], fmt.Sprintf("and %d more problems", len(problems)-maxReportedProblems))
+	}
+	return problems
+}
+
+func stripCodeFence(content string) string {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, "```") {
+		return content
+	}
+	content = strings.TrimPrefix(content, "```")
+	if newline := strings.IndexByte(content, '\n'); newline >= 0 {
+		content = content[newline+1:] // drop the language tag
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(content), "```"))
+}
+
+// validateSchema supports the JSON schema keywords used for structured
+// outputs: type, enum, const, properties, required, additionalProperties,
+// items, the length and item count bounds, the numeric bounds and anyOf.
+func validateSchema(schema map[string]any, value any, path string) []string {
+	if len(schema) == 0 {
+		return nil
+	}
+
+	if anyOf, ok := schema["anyOf"].([]any); ok {
+		for _, option := range anyOf {
+			if sub, ok := option.(map[string]any); ok && len(validateSchema(sub, value, path)) == 0 {
+				return nil
+			}
+		}
+		return []string{fmt.Sprintf("%s does not match any of the allowed schemas", path)}
+	}
+
+	if types := schemaTypes(schema["type"]); len(types) > 0 && !matchesAnyType(types, value) {
+		return []string{fmt.Sprintf("%s should be of type %s, got %s", path, strings.Join(types, " or "), jsonType(value))}
+	}
+
+	var problems []string
+	if enum, ok := schema["enum"].([]any); ok && !containsValue(enum, value) {
+		problems = append(problems, fmt.Sprintf("%s must be one of %v", path, enum))
+	}
+	if constant, ok := schema["const"]; ok && !reflect.DeepEqual(constant, value) {
+		problems = append(problems, fmt.Sprintf("%s must be %v", path, constant))
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		problems = append(problems, validateObject(schema, v, path)...)
+	case []any:
+		if n, ok := schemaNumber(schema, "minItems"); ok && float64(len(v)) < n {
+			problems = append(problems, fmt.Sprintf("%s should have at least %v items", path, n))
+		}
+		if n, ok := schemaNumber(schema, "maxItems"); ok && float64(len(v)) > n {
+			problems = append(problems, fmt.Sprintf("%s should have at most %v items", path, n))
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				problems = append(problems, validateSchema(items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	case string:
+		length := float64(len([]rune(v)))
+		if n, ok := schemaNumber(schema, "minLength"); ok && length < n {
+			problems = append(problems, fmt.Sprintf("%s should be at least %v characters", path, n))
+		}
+		if n, ok := schemaNumber(schema, "maxLength"); ok && length > n {
+			problems = append(problems, fmt.Sprintf("%s should be at most %v characters", path, n))
+		}
+	case float64:
+		if n, ok := schemaNumber(schema, "minimum"); ok && v < n {
+			problems = append(problems, fmt.Sprintf("%s should be at least %v", path, n))
+		}
+		if n, ok := schemaNumber(schema, "maximum"); ok && v > n {
+			problems = append(problems, fmt.Sprintf("%s should be at most %v", path, n))
+		}
+	}
+	return problems
+}
+
+func validateObject(schema map[string]any, object map[string]any, path string) []string {
+	var problems []string
+	properties, _ := schema["properties"].(map[string]any)
+
+	if required, ok := schema["required"].([]any); ok {
+		for _, r := range required {
+			if name, ok := r.(string); ok {
+				if _, present := object[name]; !present {
+					problems = append(problems, fmt.Sprintf("%s is missing required property %q", path, name))
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		propertyPath := path + "." + name
+		if sub, ok := properties[name].(map[string]any); ok {
+			problems = append(problems, validateSchema(sub, object[name], propertyPath)...)
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				problems = append(problems, fmt.Sprintf("%s is not allowed", propertyPath))
+			}
+		case map[string]any:
+			problems = append(problems, validateSchema(additional, object[name], propertyPath)...)
+		}
+	}
+	return problems
+}
+
+func schemaTypes(t any) []string {
+	switch v := t.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		types := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func matchesAnyType(types []string, value any) bool {
+	actual := jsonType(value)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func jsonType(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func schemaNumber(schema map[string]any, key string) (float64, bool) {
+	n, ok := schema[key].(float64)
+	return n, ok
+}
+
+func containsValue(values []any, value any) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// withResponseInstructions adds the format instructions to the system message.
+func withResponseInstructions(messages []*entities.Message, format *entities.ResponseFormat) []*entities.Message {
+	return withSystemInstructions(messages, format.Instructions())
+}
+
+// withSystemInstructions appends instructions to the last of the leading
+// system messages. The messages are copied so the chat history is unchanged.
+func withSystemInstructions(messages []*entities.Message, instructions string) []*entities.Message {
+	last := -1
+	for i := 0; i < len(messages) && messages[i].Role == "system"; i++ {
+		last = i
+	}
+	if instructions == "" || last < 0 {
+		return messages
+	}
+
+	system := *messages[last]
+	system.Content += "\n\n" + instructions
+	result := make([]*entities.Message, len(messages))
+	copy(result, messages)
+	result[last] = &system
+	return result
+}
+
+func repairPrompt(problems []string) string {
+	return "Your previous response did not match the required format:\n- " +
+		strings.Join(problems, "\n- ") +
+		"\nReply again with only the corrected JSON."
+}
+
+// repairStructuredResponse validates the final answer against the agent's
+// response format and, while it does not match, sends the problems back to
+// the model for another attempt. The repair prompts are saved to the chat
+// along with the new answers. It returns all messages generated and the
+// problems that remain after the last attempt.
+func (s *chatService) repairStructuredResponse(
+	ctx context.Context,
+	chatID string,
+	format *entities.ResponseFormat,
+	aiModel interfaces.AIModelIntegration,
+	history []*entities.Message,
+	newMessages []*entities.Message,
+	tools []entities.Tool,
+	options map[string]any,
+	callback interfaces.MessageCallback,
+) ([]*entities.Message, []string, error) {
+	for attempt := 0; ; attempt++ {
+		if len(newMessages) == 0 {
+			return newMessages, nil, nil
+		}
+		last := newMessages[len(newMessages)-1]
+		if last.Role != "assistant" {
+			return newMessages, nil, nil
+		}
+
+		problems := validateStructuredResponse(format, last.Content)
+		if len(problems) == 0 || attempt >= format.RepairAttempts() || ctx.Err() != nil {
+			return newMessages, problems, nil
+		}
+
+		s.logger.Info("Structured response did not validate, asking the model to repair it",
+			zap.String("chat_id", chatID),
+			zap.Int("attempt", attempt+1),
+			zap.Strings("problems", problems))
+
+		repair := entities.NewMessage("user", repairPrompt(problems))
+		if err := s.SaveMessagesIncrementally(ctx, chatID, []*entities.Message{repair}); err != nil {
+			return newMessages, problems, err
+		}
+
+		messages := make([]*entities.Message, 0, len(history)+len(newMessages)+1)
+		messages = append(messages, history...)
+		messages = append(messages, newMessages...)
+		messages = append(messages, repair)
+
+		repaired, err := aiModel.GenerateResponse(ctx, messages, tools, options, callback)
+		newMessages = append(newMessages, repair)
+		newMessages = append(newMessages, repaired...)
+		if err != nil {
+			return newMessages, problems, err
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	errors "github.com/drujensen/aiagent/internal/domain/errs"
+
+	"go.uber.org/zap"
+)
+
+// steeringQueue holds the notes the user sends to steer a chat's response
+// while it is being generated, until the integration's next request takes
+// them.
+type steeringQueue struct {
+	mu    sync.Mutex
+	notes []string
+}
+
+func (q *steeringQueue) push(note string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.notes = append(q.notes, note)
+}
+
+// Pending reports whether notes are waiting to be sent.
+func (q *steeringQueue) Pending() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.notes) > 0
+}
+
+// Take removes and returns the waiting notes.
+func (q *steeringQueue) Take() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	notes := q.notes
+	q.notes = nil
+	return notes
+}
+
+// Steer queues a note for the response being generated in the chat. It is
+// sent as a user message before the model's next request, so the agent can
+// change course without the run being canceled.
+func (s *chatService) Steer(ctx context.Context, chatID, note string) error {
+	if chatID == "" {
+		return errors.ValidationErrorf("chat ID is required")
+	}
+	note = strings.TrimSpace(note)
+	if note == "" {
+		return errors.ValidationErrorf("a steering message is required")
+	}
+
+	s.steeringMu.Lock()
+	queue := s.steering[chatID]
+	s.steeringMu.Unlock()
+	if queue == nil {
+		return errors.ValidationErrorf("no response is being generated for this chat")
+	}
+	queue.push(note)
+	return nil
+}
+
+// startSteering opens the queue Steer adds to while the chat's response is
+// generated.
+func (s *chatService) startSteering(chatID string) *steeringQueue {
+	queue := &steeringQueue{}
+	s.steeringMu.Lock()
+	defer s.steeringMu.Unlock()
+	if s.steering == nil {
+		s.steering = make(map[string]*steeringQueue)
+	}
+	s.steering[chatID] = queue
+	return queue
+}
+
+// stopSteering closes the chat's queue once its response is done. Notes that
+// came in after the last request are kept in the chat, so the model sees them
+// with the next message.
+func (s *chatService) stopSteering(ctx context.Context, chatID string, queue *steeringQueue) {
+	s.steeringMu.Lock()
+	if s.steering[chatID] == queue {
+		delete(s.steering, chatID)
+	}
+	s.steeringMu.Unlock()
+
+	var left []*entities.Message
+	for _, note := range queue.Take() {
+		left = append(left, entities.NewMessage("user", note))
+	}
+	if err := s.SaveMessagesIncrementally(context.WithoutCancel(ctx), chatID, left); err != nil {
+		s.logger.Warn("Failed to save steering messages", zap.String("chat_id", chatID), zap.Error(err))
+	}
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+
+	"go.uber.org/zap"
+)
+
+func TestSteer(t *testing.T) {
+	repo := &memoryChatRepo{chat: &entities.Chat{ID: "chat"}}
+	cs := &chatService{chatRepo: repo, logger: zap.NewNop()}
+
+	if err := cs.Steer(context.Background(), "chat", "use Go"); err == nil {
+		t.Error("Expected an error without a response being generated")
+	}
+
+	queue := cs.startSteering("chat")
+	if err := cs.Steer(context.Background(), "chat", "  "); err == nil {
+		t.Error("Expected an empty note to be rejected")
+	}
+	if err := cs.Steer(context.Background(), "chat", " use Go "); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if notes := queue.Take(); len(notes) != 1 || notes[0] != "use Go" {
+		t.Errorf("Expected the note to be queued, got %v", notes)
+	}
+
+	// A note the run never took is kept in the chat
+	if err := cs.Steer(context.Background(), "chat", "add tests"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cs.stopSteering(context.Background(), "chat", queue)
+	if messages := repo.chat.Messages; len(messages) != 1 || messages[0].Role != "user" || messages[0].Content != "add tests" {
+		t.Errorf("Expected the late note to be saved, got %+v", messages)
+	}
+	if err := cs.Steer(context.Background(), "chat", "more"); err == nil {
+		t.Error("Expected an error once the response is done")
+	}
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+
+	"go.uber.org/zap"
+)
+
+// projectInstructionFiles hold a project's guidance for agents, at the root
+// of the project's workspace. AIAGENT.md is the memory file the default
+// prompts tell agents to keep.
+var projectInstructionFiles = []string{"AGENTS.md", "AIAGENT.md"}
+
+// maxProjectInstructions caps the project layers so large files cannot crowd
+// out the conversation.
+const maxProjectInstructions = 32 * 1024
+
+// systemLayers collects the instruction layers of a chat in precedence order:
+// global policy, the agent's persona, the project's instruction files, the
+// files pinned to the chat and the chat's own instructions. persona is the
+// agent's system prompt.
+func (s *chatService) systemLayers(ctx context.Context, chat *entities.Chat, persona string) []entities.SystemLayer {
+	var layers []entities.SystemLayer
+	if s.globalConfig != nil && strings.TrimSpace(s.globalConfig.SystemPolicy) != "" {
+		layers = append(layers, entities.SystemLayer{Kind: entities.SystemLayerGlobal, Source: "config", Content: s.globalConfig.SystemPolicy})
+	}
+	layers = append(layers, entities.SystemLayer{Kind: entities.SystemLayerAgent, Source: "agent", Content: persona})
+	layers = append(layers, s.projectLayers(ctx, chat)...)
+	layers = append(layers, s.pinnedFileLayers(ctx, chat)...)
+	if strings.TrimSpace(chat.Instructions) != "" {
+		layers = append(layers, entities.SystemLayer{Kind: entities.SystemLayerSession, Source: "chat", Content: chat.Instructions})
+	}
+	return layers
+}
+
+// projectLayers reads the instruction files from the chat's workspace, the
+// workspace of its project, or the current directory, in that order. The
+// files share one size cap, taken in the order they are configured.
+func (s *chatService) projectLayers(ctx context.Context, chat *entities.Chat) []entities.SystemLayer {
+	workspace := s.contextWorkspace(ctx, chat)
+	if workspace == "" {
+		return nil
+	}
+
+	files, remaining := projectInstructionFiles, maxProjectInstructions
+	if s.globalConfig != nil {
+		if len(s.globalConfig.InstructionFiles) > 0 {
+			files = s.globalConfig.InstructionFiles
+		}
+		if s.globalConfig.MaxInstructionsKB > 0 {
+			remaining = s.globalConfig.MaxInstructionsKB * 1024
+		}
+	}
+
+	var layers []entities.SystemLayer
+	for _, file := range files {
+		if remaining <= 0 {
+			s.logger.Warn("Project instructions cap reached, skipping file", zap.String("file", file))
+			break
+		}
+		path := filepath.Join(workspace, file)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				s.logger.Warn("Failed to read project instructions", zap.String("path", path), zap.Error(err))
+			}
+			continue
+		}
+		content := string(data)
+		if len(content) > remaining {
+			s.logger.Warn("Project instructions truncated", zap.String("path", path), zap.Int("size", len(content)))
+			content = strings.ToValidUTF8(content[:remaining], "")
+		}
+		if strings.TrimSpace(content) == "" {
+			continue
+		}
+		remaining -= len(content)
+		layers = append(layers, entities.SystemLayer{Kind: entities.SystemLayerProject, Source: path, Content: content})
+	}
+	return layers
+}
+
+// contextWorkspace is the directory the chat's context files are read from:
+// the chat's workspace, the workspace of its project, or the current
+// directory, in that order.
+func (s *chatService) contextWorkspace(ctx context.Context, chat *entities.Chat) string {
+	if chat.Workspace != "" {
+		return chat.Workspace
+	}
+	if chat.ProjectID != "" && s.projectRepo != nil {
+		if project, err := s.projectRepo.GetProject(ctx, chat.ProjectID); err == nil && project.Workspace != "" {
+			return project.Workspace
+		}
+	}
+	workspace, _ := os.Getwd()
+	return workspace
+}
+
+// withLayerContent returns a copy of layers with the content of the layer of
+// the given kind replaced.
+func withLayerContent(layers []entities.SystemLayer, kind entities.SystemLayerKind, content string) []entities.SystemLayer {
+	result := make([]entities.SystemLayer, len(layers))
+	copy(result, layers)
+	for i := range result {
+		if result[i].Kind == kind {
+			result[i].Content = content
+		}
+	}
+	return result
+}
+
+// systemMessages turns the layers into the system messages sent to the model:
+// one combined message, or one message per layer when separate system
+// messages are configured.
+func (s *chatService) systemMessages(layers []entities.SystemLayer) []*entities.Message {
+	if s.globalConfig == nil || !s.globalConfig.SeparateSystemMessages {
+		return []*entities.Message{{Role: "system", Content: entities.ComposeSystemPrompt(layers)}}
+	}
+
+	var messages []*entities.Message
+	for _, layer := range layers {
+		if strings.TrimSpace(layer.Content) != "" {
+			messages = append(messages, &entities.Message{Role: "system", Content: layer.Text()})
+		}
+	}
+	return messages
+}
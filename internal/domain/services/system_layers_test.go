@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/domain/interfaces"
+	"github.com/drujensen/aiagent/internal/impl/config"
+
+	"go.uber.org/zap"
+)
+
+// singleProjectRepo returns the same project for any ID.
+type singleProjectRepo struct {
+	interfaces.ProjectRepository
+	project *entities.Project
+}
+
+func (r *singleProjectRepo) GetProject(ctx context.Context, id string) (*entities.Project, error) {
+	return r.project, nil
+}
+
+func TestSystemLayers(t *testing.T) {
+	workspace := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspace, "AGENTS.md"), []byte("Run make test before committing."), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cs := &chatService{
+		projectRepo:  &singleProjectRepo{project: entities.NewProject("app", workspace)},
+		globalConfig: &config.GlobalConfig{SystemPolicy: "Never push to main."},
+		logger:       zap.NewNop(),
+	}
+	chat := &entities.Chat{ProjectID: "app", Instructions: "Answer in French."}
+
+	layers := cs.systemLayers(context.Background(), chat, "You are a coder.")
+	kinds := make([]entities.SystemLayerKind, len(layers))
+	for i, layer := range layers {
+		kinds[i] = layer.Kind
+	}
+	want := []entities.SystemLayerKind{entities.SystemLayerGlobal, entities.SystemLayerAgent, entities.SystemLayerProject, entities.SystemLayerSession}
+	if !slices.Equal(kinds, want) {
+		t.Fatalf("Expected layers %v, got %v", want, kinds)
+	}
+
+	combined := cs.systemMessages(layers)
+	if len(combined) != 1 {
+		t.Fatalf("Expected one combined system message, got %d", len(combined))
+	}
+	content := combined[0].Content
+	if !strings.HasPrefix(content, "# Global policy\nNever push to main.") ||
+		!strings.Contains(content, "Run make test") ||
+		!strings.HasSuffix(content, "# Session instructions\nAnswer in French.") {
+		t.Errorf("Unexpected combined prompt: %q", content)
+	}
+
+	cs.globalConfig.SeparateSystemMessages = true
+	separate := cs.systemMessages(layers)
+	if len(separate) != 4 || separate[1].Content != "You are a coder." {
+		t.Errorf("Expected one system message per layer, got %d", len(separate))
+	}
+}
+
+func TestProjectLayers_InstructionFiles(t *testing.T) {
+	workspace := t.TempDir()
+	files := map[string]string{
+		"AGENTS.md":  "Run make test before committing.",
+		"AIAGENT.md": strings.Repeat("m", 2048),
+		"NOTES.md":   "Deploy on Fridays.",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(workspace, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cs := &chatService{
+		projectRepo:  &singleProjectRepo{project: entities.NewProject("app", workspace)},
+		globalConfig: &config.GlobalConfig{},
+		logger:       zap.NewNop(),
+	}
+	chat := &entities.Chat{ProjectID: "app"}
+
+	layers := cs.projectLayers(context.Background(), chat)
+	if len(layers) != 2 || !strings.HasSuffix(layers[0].Source, "AGENTS.md") || !strings.HasSuffix(layers[1].Source, "AIAGENT.md") {
+		t.Fatalf("Expected AGENTS.md and AIAGENT.md layers, got %+v", layers)
+	}
+
+	cs.globalConfig.InstructionFiles = []string{"AIAGENT.md", "NOTES.md"}
+	cs.globalConfig.MaxInstructionsKB = 1
+	layers = cs.projectLayers(context.Background(), chat)
+	if len(layers) != 1 || len(layers[0].Content) != 1024 {
+		t.Fatalf("Expected only the configured file, cut to the cap, got %d layers", len(layers))
+	}
+}
+
+func TestWithSystemInstructions_Layered(t *testing.T) {
+	messages := []*entities.Message{
+		{Role: "system", Content: "agent"},
+		{Role: "system", Content: "session"},
+		{Role: "user", Content: "hi"},
+	}
+	result := withSystemInstructions(messages, "Reply in JSON.")
+	if result[1].Content != "session\n\nReply in JSON." || result[0].Content != "agent" {
+		t.Errorf("Expected the instructions on the last system message, got %q / %q", result[0].Content, result[1].Content)
+	}
+	if messages[1].Content != "session" {
+		t.Error("Expected the original messages to be left unchanged")
+	}
+}
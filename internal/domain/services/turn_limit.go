@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/domain/events"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// turnTimeLimitError is the cause of a run stopped by the turn time limit.
+type turnTimeLimitError struct {
+	limit time.Duration
+}
+
+func (e *turnTimeLimitError) Error() string {
+	return fmt.Sprintf("turn time limit of %s reached", e.limit)
+}
+
+// maxTurnDuration is the configured wall-clock limit of a turn, zero when
+// turns are unlimited.
+func (s *chatService) maxTurnDuration() time.Duration {
+	if s.globalConfig == nil || s.globalConfig.MaxTurnSeconds <= 0 {
+		return 0
+	}
+	return time.Duration(s.globalConfig.MaxTurnSeconds) * time.Second
+}
+
+// finishTimedOutTurn wraps up a turn stopped by the time limit. The messages
+// saved so far are kept, tool calls left without a response get one so the
+// next turn can continue, and a notice is returned as the turn's answer.
+func (s *chatService) finishTimedOutTurn(ctx context.Context, chatID string, limitErr *turnTimeLimitError) (*entities.Message, error) {
+	s.logger.Warn("Turn time limit reached, stopping run",
+		zap.String("chat_id", chatID),
+		zap.Duration("limit", limitErr.limit))
+
+	chat, err := s.chatRepo.GetChat(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]*entities.Message, len(chat.Messages))
+	for i := range chat.Messages {
+		history[i] = &chat.Messages[i]
+	}
+	balanced, issues := balanceToolCalls(history)
+	if len(issues) > 0 {
+		s.logger.Info("Repaired tool calls left open by the turn time limit",
+			zap.String("chat_id", chatID),
+			zap.Strings("issues", issues))
+	}
+
+	notice := &entities.Message{
+		ID:        uuid.New().String(),
+		Role:      "assistant",
+		Content:   fmt.Sprintf("Turn time limit reached after %s. The work done so far has been saved; send another message to continue.", limitErr.limit),
+		Timestamp: time.Now(),
+	}
+
+	chat.Messages = make([]entities.Message, 0, len(balanced)+1)
+	for _, msg := range balanced {
+		chat.Messages = append(chat.Messages, *msg)
+	}
+	chat.Messages = append(chat.Messages, *notice)
+	chat.UpdatedAt = time.Now()
+	if err := s.chatRepo.UpdateChat(ctx, chat); err != nil {
+		return nil, err
+	}
+
+	events.PublishProcessFinishedEvent(entities.NewProcessFinishedEvent(chatID))
+	return notice, nil
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/domain/interfaces"
+	"github.com/drujensen/aiagent/internal/impl/config"
+
+	"go.uber.org/zap"
+)
+
+// memoryChatRepo keeps a single chat in memory.
+type memoryChatRepo struct {
+	interfaces.ChatRepository
+	chat *entities.Chat
+}
+
+func (r *memoryChatRepo) GetChat(ctx context.Context, id string) (*entities.Chat, error) {
+	return r.chat, nil
+}
+
+func (r *memoryChatRepo) UpdateChat(ctx context.Context, chat *entities.Chat) error {
+	r.chat = chat
+	return nil
+}
+
+func TestMaxTurnDuration(t *testing.T) {
+	cs := &chatService{logger: zap.NewNop()}
+	if got := cs.maxTurnDuration(); got != 0 {
+		t.Errorf("Expected no limit without config, got %v", got)
+	}
+	cs.globalConfig = &config.GlobalConfig{MaxTurnSeconds: 90}
+	if got := cs.maxTurnDuration(); got != 90*time.Second {
+		t.Errorf("Expected 90s, got %v", got)
+	}
+}
+
+func TestFinishTimedOutTurn(t *testing.T) {
+	repo := &memoryChatRepo{chat: &entities.Chat{
+		ID: "chat",
+		Messages: []entities.Message{
+			{ID: "1", Role: "user", Content: "build it"},
+			{ID: "2", Role: "assistant", ToolCalls: []entities.ToolCall{{ID: "call_1", Type: "function"}}},
+		},
+	}}
+	cs := &chatService{chatRepo: repo, logger: zap.NewNop()}
+
+	notice, err := cs.finishTimedOutTurn(context.Background(), "chat", &turnTimeLimitError{limit: time.Minute})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(notice.Content, "Turn time limit reached") {
+		t.Errorf("unexpected notice: %q", notice.Content)
+	}
+
+	messages := repo.chat.Messages
+	if len(messages) != 4 {
+		t.Fatalf("Expected 4 messages, got %d", len(messages))
+	}
+	if messages[2].Role != "tool" || messages[2].ToolCallID != "call_1" {
+		t.Errorf("Expected a response for the open tool call, got %+v", messages[2])
+	}
+	if messages[3].ID != notice.ID {
+		t.Error("Expected the notice to be saved last")
+	}
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+
+	"go.uber.org/zap"
+)
+
+// unknownUsageName groups usage whose agent, model or provider was deleted.
+const unknownUsageName = "(deleted)"
+
+// UsageReport adds up the usage recorded on the messages of every chat since
+// the given time, or of all time when since is zero, by agent, provider,
+// model and day. Messages answered by a fallback model count toward that
+// model. Messages recorded without a cost are priced with the provider's
+// current pricing.
+func (s *chatService) UsageReport(ctx context.Context, since time.Time) (*entities.UsageReport, error) {
+	chats, err := s.chatRepo.ListChats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resolver := newUsageResolver(ctx, s)
+	report := &entities.UsageReport{Since: since}
+	byAgent := make(map[string]*entities.UsageTotals)
+	byProvider := make(map[string]*entities.UsageTotals)
+	byModel := make(map[string]*entities.UsageTotals)
+	byDay := make(map[string]*entities.UsageTotals)
+	add := func(totals map[string]*entities.UsageTotals, name string, usage *entities.Usage, cost float64) {
+		if totals[name] == nil {
+			totals[name] = &entities.UsageTotals{}
+		}
+		totals[name].Add(usage, cost)
+	}
+
+	for _, chat := range chats {
+		counted := false
+		for i := range chat.Messages {
+			msg := &chat.Messages[i]
+			if msg.Usage == nil || msg.Timestamp.Before(since) {
+				continue
+			}
+
+			model := resolver.chatModel(chat.ModelID)
+			if msg.Model != "" {
+				model = resolver.modelNamed(msg.Model)
+			}
+			provider := resolver.provider(model)
+			cost := msg.Usage.Cost
+			if cost == 0 && model != nil && provider != nil {
+				if pricing := provider.GetModelPricing(model.ModelName); pricing != nil {
+					cost = (float64(msg.Usage.PromptTokens)*pricing.InputPricePerMille + float64(msg.Usage.CompletionTokens)*pricing.OutputPricePerMille) / 1000000.0
+				}
+			}
+
+			report.Total.Add(msg.Usage, cost)
+			add(byAgent, resolver.agentName(chat.AgentID), msg.Usage, cost)
+			add(byModel, usageModelName(model, msg.Model), msg.Usage, cost)
+			add(byProvider, usageProviderName(provider), msg.Usage, cost)
+			add(byDay, msg.Timestamp.Local().Format("2006-01-02"), msg.Usage, cost)
+			counted = true
+		}
+		if counted {
+			report.Chats++
+		}
+	}
+
+	report.ByAgent = entities.UsageGroups(byAgent)
+	report.ByProvider = entities.UsageGroups(byProvider)
+	report.ByModel = entities.UsageGroups(byModel)
+	report.ByDay = entities.UsageGroups(byDay)
+	sort.Slice(report.ByDay, func(i, j int) bool { return report.ByDay[i].Name < report.ByDay[j].Name })
+	return report, nil
+}
+
+func usageModelName(model *entities.Model, fallbackName string) string {
+	if model != nil {
+		return model.Name
+	}
+	if fallbackName != "" {
+		return fallbackName
+	}
+	return unknownUsageName
+}
+
+func usageProviderName(provider *entities.Provider) string {
+	if provider == nil {
+		return unknownUsageName
+	}
+	return provider.Name
+}
+
+// usageResolver looks up the agents, models and providers of the report once
+// each.
+type usageResolver struct {
+	ctx       context.Context
+	s         *chatService
+	agents    map[string]string
+	models    map[string]*entities.Model
+	providers map[string]*entities.Provider
+	byName    map[string]*entities.Model
+}
+
+func newUsageResolver(ctx context.Context, s *chatService) *usageResolver {
+	return &usageResolver{
+		ctx:       ctx,
+		s:         s,
+		agents:    make(map[string]string),
+		models:    make(map[string]*entities.Model),
+		providers: make(map[string]*entities.Provider),
+	}
+}
+
+func (r *usageResolver) agentName(id string) string {
+	if name, ok := r.agents[id]; ok {
+		return name
+	}
+	name := unknownUsageName
+	if agent, err := r.s.agentRepo.GetAgent(r.ctx, id); err == nil {
+		name = agent.Name
+	}
+	r.agents[id] = name
+	return name
+}
+
+func (r *usageResolver) chatModel(id string) *entities.Model {
+	if model, ok := r.models[id]; ok {
+		return model
+	}
+	model, err := r.s.modelRepo.GetModel(r.ctx, id)
+	if err != nil {
+		model = nil
+	}
+	r.models[id] = model
+	return model
+}
+
+// modelNamed finds the fallback model a message was answered by, which is
+// recorded by name.
+func (r *usageResolver) modelNamed(name string) *entities.Model {
+	if r.byName == nil {
+		r.byName = make(map[string]*entities.Model)
+		models, err := r.s.modelRepo.ListModels(r.ctx)
+		if err != nil {
+			r.s.logger.Warn("Failed to list models for the usage report", zap.Error(err))
+		}
+		for _, model := range models {
+			r.byName[strings.ToLower(model.Name)] = model
+		}
+	}
+	return r.byName[strings.ToLower(name)]
+}
+
+func (r *usageResolver) provider(model *entities.Model) *entities.Provider {
+	if model == nil {
+		return nil
+	}
+	if provider, ok := r.providers[model.ProviderID]; ok {
+		return provider
+	}
+	provider, err := r.s.providerRepo.GetProvider(r.ctx, model.ProviderID)
+	if err != nil {
+		provider = nil
+	}
+	r.providers[model.ProviderID] = provider
+	return provider
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/domain/interfaces"
+
+	"go.uber.org/zap"
+)
+
+// chatListRepo lists a fixed set of chats.
+type chatListRepo struct {
+	interfaces.ChatRepository
+	chats []*entities.Chat
+}
+
+func (r *chatListRepo) ListChats(ctx context.Context) ([]*entities.Chat, error) {
+	return r.chats, nil
+}
+
+func (r *memoryModelRepo) ListModels(ctx context.Context) ([]*entities.Model, error) {
+	return r.models, nil
+}
+
+func TestUsageReport(t *testing.T) {
+	day := time.Date(2026, 3, 2, 12, 0, 0, 0, time.Local)
+	usage := func(prompt, completion int, cost float64) *entities.Usage {
+		return &entities.Usage{PromptTokens: prompt, CompletionTokens: completion, TotalTokens: prompt + completion, Cost: cost}
+	}
+
+	cs := &chatService{
+		chatRepo: &chatListRepo{chats: []*entities.Chat{
+			{ID: "c1", AgentID: "coder", ModelID: "m1", Messages: []entities.Message{
+				{Role: "user", Content: "hi", Timestamp: day},
+				{Role: "assistant", Usage: usage(100, 50, 0.5), Timestamp: day},
+				{Role: "assistant", Usage: usage(1000, 0, 0), Timestamp: day.AddDate(0, 0, 1), Model: "Cheap"},
+			}},
+			{ID: "c2", AgentID: "gone", ModelID: "m1", Messages: []entities.Message{
+				{Role: "assistant", Usage: usage(10, 10, 0.25), Timestamp: day.AddDate(0, 0, -10)},
+			}},
+		}},
+		agentRepo: &memoryAgentRepo{agents: map[string]*entities.Agent{"coder": {ID: "coder", Name: "Coder"}}},
+		modelRepo: &memoryModelRepo{models: []*entities.Model{
+			{ID: "m1", Name: "Smart", ProviderID: "p1", ModelName: "smart-1"},
+			{ID: "m2", Name: "Cheap", ProviderID: "p1", ModelName: "cheap-1"},
+		}},
+		providerRepo: &memoryProviderRepo{providers: []*entities.Provider{
+			{ID: "p1", Name: "Acme", Models: []entities.ModelPricing{{Name: "cheap-1", InputPricePerMille: 2}}},
+		}},
+		logger: zap.NewNop(),
+	}
+
+	report, err := cs.UsageReport(context.Background(), day.AddDate(0, 0, -1))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.Chats != 1 || report.Total.Messages != 2 || report.Total.TotalTokens != 1150 {
+		t.Errorf("Expected the old chat to be left out, got %+v", report)
+	}
+	// The fallback message is priced at $2 per million input tokens
+	if want := 0.502; report.Total.Cost < want-1e-9 || report.Total.Cost > want+1e-9 {
+		t.Errorf("Expected a total cost of %v, got %v", want, report.Total.Cost)
+	}
+	if len(report.ByModel) != 2 || report.ByModel[0].Name != "Smart" || report.ByModel[1].Name != "Cheap" {
+		t.Errorf("Expected usage by model, most expensive first, got %+v", report.ByModel)
+	}
+	if len(report.ByDay) != 2 || report.ByDay[0].Name != "2026-03-02" || report.ByDay[1].Name != "2026-03-03" {
+		t.Errorf("Expected usage by day, oldest first, got %+v", report.ByDay)
+	}
+
+	report, err = cs.UsageReport(context.Background(), time.Time{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.Chats != 2 || len(report.ByAgent) != 2 || report.ByAgent[0].Name != "Coder" || report.ByAgent[1].Name != unknownUsageName {
+		t.Errorf("Expected a deleted agent to be grouped on its own, got %+v", report.ByAgent)
+	}
+	if len(report.ByProvider) != 1 || report.ByProvider[0].Name != "Acme" || report.ByProvider[0].Messages != 3 {
+		t.Errorf("Expected usage by provider, got %+v", report.ByProvider)
+	}
+}
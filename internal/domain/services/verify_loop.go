@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/domain/interfaces"
+
+	"go.uber.org/zap"
+)
+
+// verifyOutputLimit caps the output of each verify command handed back to
+// the model; the end of a build log is where the errors are.
+const verifyOutputLimit = 4000
+
+// changedFiles reports whether any of the messages records a successful
+// Write or Edit.
+func changedFiles(messages []*entities.Message) bool {
+	for _, msg := range messages {
+		if msg.Role != "tool" {
+			continue
+		}
+		for _, event := range msg.ToolCallEvents {
+			if (event.ToolName == "Write" || event.ToolName == "Edit") && event.Error == "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tailOutput keeps the last verifyOutputLimit bytes of a command's output.
+func tailOutput(output string) string {
+	output = strings.TrimSpace(output)
+	if len(output) <= verifyOutputLimit {
+		return output
+	}
+	return "...\n" + output[len(output)-verifyOutputLimit:]
+}
+
+// runVerifyCommands runs the commands in order with the Bash tool and stops
+// at the first failure. It returns whether all passed and a report of the
+// failing command's output.
+func (s *chatService) runVerifyCommands(ctx context.Context, workspace string, commands []string) (bool, string) {
+	for _, command := range commands {
+		args, _ := json.Marshal(map[string]any{"command": command, "shell": true})
+		result, err := s.runTool(ctx, workspace, "Bash", string(args))
+		if err != nil {
+			return false, fmt.Sprintf("$ %s\n%v", command, err)
+		}
+
+		var resp struct {
+			Stdout string `json:"stdout"`
+			Stderr string `json:"stderr"`
+			Status string `json:"status"`
+		}
+		if err := json.Unmarshal([]byte(result), &resp); err != nil {
+			return false, fmt.Sprintf("$ %s\n%s", command, tailOutput(result))
+		}
+		if resp.Status != "completed" {
+			output := strings.TrimSpace(resp.Stdout + "\n" + resp.Stderr)
+			return false, fmt.Sprintf("$ %s (%s)\n%s", command, resp.Status, tailOutput(output))
+		}
+	}
+	return true, ""
+}
+
+func verifyFailedPrompt(report string, attempt, attempts int) string {
+	return fmt.Sprintf("The automatic build and test run failed (attempt %d of %d):\n\n%s\n\nFix the problems. If they cannot be fixed, explain why without changing more files.", attempt, attempts, report)
+}
+
+// enforceVerifyLoop runs the agent's build and test commands after a turn
+// that changed files. While they fail the output is handed back to the model
+// for another round, up to the loop's attempt limit. The loop also ends once
+// the model answers without changing files, which is how it gives up.
+func (s *chatService) enforceVerifyLoop(
+	ctx context.Context,
+	chatID string,
+	workspace string,
+	loop *entities.VerifyLoop,
+	aiModel interfaces.AIModelIntegration,
+	history []*entities.Message,
+	newMessages []*entities.Message,
+	tools []entities.Tool,
+	options map[string]any,
+	callback interfaces.MessageCallback,
+) []*entities.Message {
+	latest := newMessages
+	for attempt := 1; changedFiles(latest) && ctx.Err() == nil; attempt++ {
+		passed, report := s.runVerifyCommands(ctx, workspace, loop.Commands)
+		if passed {
+			s.logger.Info("Build and tests pass", zap.String("chat_id", chatID), zap.Int("attempt", attempt))
+			return newMessages
+		}
+		if attempt > loop.Attempts() {
+			s.logger.Warn("Build and tests still fail, giving up", zap.String("chat_id", chatID), zap.Int("attempts", loop.Attempts()))
+			notice := entities.NewMessage("system", fmt.Sprintf("Build and tests still fail after %d automatic attempts:\n\n%s", loop.Attempts(), report))
+			if err := s.SaveMessagesIncrementally(ctx, chatID, []*entities.Message{notice}); err != nil {
+				s.logger.Warn("Failed to save verify notice", zap.String("chat_id", chatID), zap.Error(err))
+			}
+			return append(newMessages, notice)
+		}
+
+		s.logger.Info("Build or tests failed, handing the output back to the model", zap.String("chat_id", chatID), zap.Int("attempt", attempt))
+		prompt := entities.NewMessage("user", verifyFailedPrompt(report, attempt, loop.Attempts()))
+		if err := s.SaveMessagesIncrementally(ctx, chatID, []*entities.Message{prompt}); err != nil {
+			s.logger.Warn("Failed to save verify prompt", zap.String("chat_id", chatID), zap.Error(err))
+			return newMessages
+		}
+
+		messages := make([]*entities.Message, 0, len(history)+len(newMessages)+1)
+		messages = append(messages, history...)
+		messages = append(messages, newMessages...)
+		messages = append(messages, prompt)
+
+		response, err := aiModel.GenerateResponse(ctx, messages, tools, options, callback)
+		newMessages = append(newMessages, prompt)
+		newMessages = append(newMessages, response...)
+		if err != nil {
+			s.logger.Warn("Failed to get a fix for the failing build", zap.String("chat_id", chatID), zap.Error(err))
+			return newMessages
+		}
+		latest = response
+	}
+	return newMessages
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/domain/interfaces"
+	"github.com/drujensen/aiagent/internal/impl/config"
+
+	"go.uber.org/zap"
+)
+
+// scriptedBash answers each command with the next canned result.
+type scriptedBash struct {
+	entities.Tool
+	results  []string
+	commands []string
+}
+
+func (t *scriptedBash) Configuration() map[string]string      { return nil }
+func (t *scriptedBash) UpdateConfiguration(map[string]string) {}
+func (t *scriptedBash) Execute(ctx context.Context, arguments string) (string, error) {
+	t.commands = append(t.commands, arguments)
+	result := t.results[0]
+	t.results = t.results[1:]
+	return result, nil
+}
+
+type bashToolRepo struct {
+	interfaces.ToolRepository
+	bash *scriptedBash
+}
+
+func (r *bashToolRepo) GetToolByName(name string) (entities.Tool, error) {
+	return r.bash, nil
+}
+
+func (r *bashToolRepo) ListToolData(ctx context.Context) ([]*entities.ToolData, error) {
+	return nil, nil
+}
+
+// fixingModel answers every prompt with an edit.
+type fixingModel struct {
+	interfaces.AIModelIntegration
+	prompts []string
+}
+
+func (m *fixingModel) GenerateResponse(ctx context.Context, messages []*entities.Message, tools []entities.Tool, options map[string]any, callback interfaces.MessageCallback) ([]*entities.Message, error) {
+	m.prompts = append(m.prompts, messages[len(messages)-1].Content)
+	return editMessages(), nil
+}
+
+func editMessages() []*entities.Message {
+	return []*entities.Message{
+		{Role: "tool", ToolCallEvents: []entities.ToolCallEvent{{ToolName: "Edit"}}},
+		{Role: "assistant", Content: "Fixed"},
+	}
+}
+
+const (
+	bashPassed = `{"command":"go test ./...","stdout":"ok","status":"completed"}`
+	bashFailed = `{"command":"go test ./...","stderr":"undefined: foo","status":"failed"}`
+)
+
+func newVerifyTestService(bash *scriptedBash) *chatService {
+	return &chatService{
+		chatRepo: &memoryChatRepo{chat: &entities.Chat{ID: "chat"}},
+		toolRepo: &bashToolRepo{bash: bash},
+		config:   &config.Config{},
+		logger:   zap.NewNop(),
+	}
+}
+
+func TestChangedFiles(t *testing.T) {
+	if !changedFiles(editMessages()) {
+		t.Error("Expected a successful edit to count as a change")
+	}
+	failed := []*entities.Message{{Role: "tool", ToolCallEvents: []entities.ToolCallEvent{{ToolName: "Write", Error: "denied"}}}}
+	if changedFiles(failed) {
+		t.Error("Expected a failed write not to count as a change")
+	}
+	read := []*entities.Message{{Role: "tool", ToolCallEvents: []entities.ToolCallEvent{{ToolName: "Read"}}}}
+	if changedFiles(read) {
+		t.Error("Expected a read not to count as a change")
+	}
+}
+
+func TestEnforceVerifyLoop_FixesUntilGreen(t *testing.T) {
+	bash := &scriptedBash{results: []string{bashFailed, bashPassed}}
+	cs := newVerifyTestService(bash)
+	model := &fixingModel{}
+	loop := &entities.VerifyLoop{Commands: []string{"go test ./..."}}
+
+	messages := cs.enforceVerifyLoop(context.Background(), "chat", "", loop, model, nil, editMessages(), nil, nil, nil)
+
+	if len(bash.commands) != 2 {
+		t.Errorf("Expected the tests to run twice, ran %d times", len(bash.commands))
+	}
+	if len(model.prompts) != 1 || !strings.Contains(model.prompts[0], "undefined: foo") {
+		t.Errorf("Expected the failure to be handed back once, got %q", model.prompts)
+	}
+	if last := messages[len(messages)-1]; last.Content != "Fixed" {
+		t.Errorf("Expected the fix to be the final answer, got %q", last.Content)
+	}
+}
+
+func TestEnforceVerifyLoop_GivesUp(t *testing.T) {
+	bash := &scriptedBash{results: []string{bashFailed, bashFailed, bashFailed}}
+	cs := newVerifyTestService(bash)
+	model := &fixingModel{}
+	loop := &entities.VerifyLoop{Commands: []string{"go test ./..."}, MaxAttempts: 2}
+
+	messages := cs.enforceVerifyLoop(context.Background(), "chat", "", loop, model, nil, editMessages(), nil, nil, nil)
+
+	if len(model.prompts) != 2 {
+		t.Errorf("Expected 2 fix attempts, got %d", len(model.prompts))
+	}
+	last := messages[len(messages)-1]
+	if last.Role != "system" || !strings.Contains(last.Content, "still fail after 2") {
+		t.Errorf("Expected a notice that the build still fails, got %+v", last)
+	}
+}
+
+func TestEnforceVerifyLoop_SkipsWithoutChanges(t *testing.T) {
+	bash := &scriptedBash{}
+	cs := newVerifyTestService(bash)
+	loop := &entities.VerifyLoop{Commands: []string{"go test ./..."}}
+	answer := []*entities.Message{{Role: "assistant", Content: "Nothing to change"}}
+
+	cs.enforceVerifyLoop(context.Background(), "chat", "", loop, &fixingModel{}, nil, answer, nil, nil, nil)
+
+	if len(bash.commands) != 0 {
+		t.Errorf("Expected no commands without file changes, ran %d", len(bash.commands))
+	}
+}
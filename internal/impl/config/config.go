@@ -63,13 +63,32 @@ func InitConfig() (*Config, error) {
 }
 
 func (c *Config) ResolveEnvironmentVariable(value string) (string, error) {
-	const prefix, suffix = "#{", "}#"
-	if strings.HasPrefix(value, prefix) && strings.HasSuffix(value, suffix) {
-		varName := strings.TrimSuffix(strings.TrimPrefix(value, prefix), suffix)
+	return c.resolveReference(value, nil)
+}
+
+const referencePrefix, referenceSuffix = "#{", "}#"
+
+// IsReference reports whether the value is a #{NAME}# reference to an
+// environment variable.
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, referencePrefix) && strings.HasSuffix(value, referenceSuffix)
+}
+
+// resolveReference resolves a #{NAME}# reference from env, falling back to
+// the process environment.
+func (c *Config) resolveReference(value string, env map[string]string) (string, error) {
+	if IsReference(value) {
+		varName := strings.TrimSuffix(strings.TrimPrefix(value, referencePrefix), referenceSuffix)
 		if varName == "" {
 			return "", fmt.Errorf("empty variable name in reference: %s", value)
 		}
 
+		if resolved := env[varName]; resolved != "" {
+			c.logger.Debug("Resolved agent environment variable",
+				zap.String("var_name", varName),
+				zap.String("resolved", maskKey(resolved)))
+			return resolved, nil
+		}
 		resolved := os.Getenv(varName)
 		if resolved == "" {
 			c.logger.Warn("Environment variable not found for reference",
@@ -88,10 +107,12 @@ func (c *Config) ResolveEnvironmentVariable(value string) (string, error) {
 	return value, nil
 }
 
-func (c *Config) ResolveConfiguration(config map[string]string) (map[string]string, error) {
+// ResolveConfiguration resolves the #{NAME}# references of a tool
+// configuration, looking names up in env before the process environment.
+func (c *Config) ResolveConfiguration(config map[string]string, env map[string]string) (map[string]string, error) {
 	resolvedConfig := make(map[string]string)
 	for key, value := range config {
-		resolvedValue, err := c.ResolveEnvironmentVariable(value)
+		resolvedValue, err := c.resolveReference(value, env)
 		if err != nil {
 			return nil, fmt.Errorf("failed to resolve configuration for key '%s': %w", key, err)
 		}
@@ -100,6 +121,19 @@ func (c *Config) ResolveConfiguration(config map[string]string) (map[string]stri
 	return resolvedConfig, nil
 }
 
+// ResolvedSecrets returns the values resolved for the #{NAME}# references of
+// config, the secrets such as API keys that it keeps out of the stored
+// configuration, for the logs to mask.
+func ResolvedSecrets(config, resolved map[string]string) []string {
+	var secrets []string
+	for key, value := range config {
+		if IsReference(value) && resolved[key] != "" {
+			secrets = append(secrets, resolved[key])
+		}
+	}
+	return secrets
+}
+
 func maskKey(key string) string {
 	if len(key) <= 4 {
 		return strings.Repeat("*", len(key))
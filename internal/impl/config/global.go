@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/drujensen/aiagent/internal/domain/entities"
+
 	"go.uber.org/zap"
 )
 
@@ -16,6 +18,70 @@ type GlobalConfig struct {
 	LastUsedAgent         string                          `json:"last_used_agent"` // Agent name (not ID)
 	LastUsedModel         string                          `json:"last_used_model"` // Model name (not ID)
 	Providers             map[string]CustomProviderConfig `json:"providers,omitempty"`
+	// ApprovalRequiredTools lists tools that only run when an approval policy matches.
+	ApprovalRequiredTools []string                  `json:"approval_required_tools,omitempty"`
+	ApprovalPolicies      []entities.ApprovalPolicy `json:"approval_policies,omitempty"` // global scope
+	// ModelDowngrades maps a model name to a cheaper model used while the
+	// provider is rate limiting or reporting quota pressure.
+	ModelDowngrades          map[string]string `json:"model_downgrades,omitempty"`
+	DowngradeCooldownMinutes int               `json:"downgrade_cooldown_minutes,omitempty"`
+	// DisablePromptCaching turns off provider-native prompt caching of the
+	// system prompt and tool definitions.
+	DisablePromptCaching bool `json:"disable_prompt_caching,omitempty"`
+	// StrictToolCallValidation refuses to send a history with unbalanced tool
+	// calls instead of repairing it.
+	StrictToolCallValidation bool `json:"strict_tool_call_validation,omitempty"`
+	// ContextStrategy orders the context sent to the model. Defaults to
+	// chronological.
+	ContextStrategy entities.ContextStrategy `json:"context_strategy,omitempty"`
+	// AutoCreateProjects files new chats under a project named after the
+	// workspace when no existing project covers it.
+	AutoCreateProjects bool `json:"auto_create_projects,omitempty"`
+	// LogFile also writes the logs as JSON lines to this file, which the
+	// logs command and the /admin/logs endpoint query.
+	LogFile string `json:"log_file,omitempty"`
+	// MaxTurnSeconds bounds the wall-clock time of a single turn. When it is
+	// reached the turn stops and keeps what it did so far. Zero is unlimited.
+	MaxTurnSeconds int `json:"max_turn_seconds,omitempty"`
+	// SystemPolicy is sent ahead of every agent's system prompt.
+	SystemPolicy string `json:"system_policy,omitempty"`
+	// SeparateSystemMessages sends each instruction layer (global policy,
+	// agent, project, session) as its own system message instead of one.
+	SeparateSystemMessages bool `json:"separate_system_messages,omitempty"`
+	// InstructionFiles are read from the root of the workspace and added to
+	// the system prompt as project instructions. Defaults to AGENTS.md and
+	// AIAGENT.md.
+	InstructionFiles []string `json:"instruction_files,omitempty"`
+	// MaxInstructionsKB caps the project instructions taken from those
+	// files. Defaults to 32.
+	MaxInstructionsKB int `json:"max_instructions_kb,omitempty"`
+	// DisableAutoTitles keeps new chats' default names instead of asking the
+	// model for a title after the first response.
+	DisableAutoTitles bool `json:"disable_auto_titles,omitempty"`
+	// AuditToolCalls writes every tool call, with its arguments, result and
+	// duration, as JSON lines to .aiagent/logs/tools-<chat>.jsonl.
+	AuditToolCalls bool `json:"audit_tool_calls,omitempty"`
+	// AuditLogMaxSizeMB is the size a chat's audit log is rotated at.
+	// Defaults to 10.
+	AuditLogMaxSizeMB int `json:"audit_log_max_size_mb,omitempty"`
+	// MaxToolResultTokens caps each tool result sent to the model; longer
+	// results are cut on a line boundary. Defaults to 20000.
+	MaxToolResultTokens int `json:"max_tool_result_tokens,omitempty"`
+	// MaxToolConcurrency caps how many tool calls of one response run at
+	// once. Calls that change files or run commands always run one at a
+	// time. Defaults to 4.
+	MaxToolConcurrency int `json:"max_tool_concurrency,omitempty"`
+	// Spinner is the TUI's animation while a response is generated: dot,
+	// line, minidot, jump, pulse, points, meter, hamburger, ellipsis or
+	// none. Defaults to dot.
+	Spinner string `json:"spinner,omitempty"`
+	// CompletionWebhook is POSTed the chat, status and usage of each
+	// response when it completes, fails or is canceled. An agent's own
+	// webhook takes its place.
+	CompletionWebhook string `json:"completion_webhook,omitempty"`
+	// DesktopNotify rings the terminal bell and sends a desktop notification
+	// when a TUI response that ran for a while ends.
+	DesktopNotify bool `json:"desktop_notify,omitempty"`
 }
 
 // CustomProviderConfig represents a custom provider configuration
@@ -25,6 +91,15 @@ type CustomProviderConfig struct {
 	BaseURL    string                       `json:"base_url"`
 	APIKeyName string                       `json:"api_key_name"`
 	Models     map[string]CustomModelConfig `json:"models"`
+	// DisableTypeDetection keeps a generic provider on the OpenAI-compatible
+	// integration instead of detecting its native API.
+	DisableTypeDetection bool `json:"disable_type_detection,omitempty"`
+	// ToolCallIDFormat is "passthrough", "anthropic" or "alphanumeric9"
+	// when the provider needs tool call IDs in a particular shape.
+	ToolCallIDFormat string `json:"tool_call_id_format,omitempty"`
+	// StrictToolSchemas turns the strict mode of function definitions on or
+	// off. Unset, it is on for OpenAI only.
+	StrictToolSchemas *bool `json:"strict_tool_schemas,omitempty"`
 }
 
 // CustomModelConfig represents a custom model configuration
@@ -60,7 +135,7 @@ func DefaultGlobalConfig() *GlobalConfig {
 			},
 			"ollama": {
 				Name:       "Ollama",
-				Type:       "generic",
+				Type:       "ollama",
 				BaseURL:    "http://localhost:11434",
 				APIKeyName: "",
 				Models: map[string]CustomModelConfig{
@@ -44,6 +44,11 @@ func (m *MongoDB) Collection(name string) *mongo.Collection {
 	return m.database.Collection(name)
 }
 
+// Ping checks that the server is still reachable.
+func (m *MongoDB) Ping(ctx context.Context) error {
+	return m.client.Ping(ctx, nil)
+}
+
 func (m *MongoDB) Disconnect(ctx context.Context) error {
 	return m.client.Disconnect(ctx)
 }
@@ -153,7 +153,7 @@ Stop researching when:
 - If information is incomplete, clearly state what you know and what you don't
 - Provide sources and evidence for claims
 - Ask for clarification only when essential` + systemPrompt,
-			Tools:     []string{"WebSearch", "Read", "Write", "Edit", "Grep", "Glob", "Bash", "TodoWrite", "Compression"},
+			Tools:     []string{"WebSearch", "Read", "Write", "Edit", "Grep", "CodeSearch", "Glob", "Bash", "TodoWrite", "Compression"},
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		},
@@ -202,7 +202,7 @@ Stop planning when:
 - Do not attempt to run build commands, tests, or modify any files
 - Only provide planning analysis and task breakdowns
 - Treat planning as a collaborative, iterative process` + systemPrompt,
-			Tools:     []string{"WebSearch", "Read", "Grep", "Glob", "TodoWrite", "Compression"},
+			Tools:     []string{"WebSearch", "Read", "Grep", "CodeSearch", "Glob", "TodoWrite", "Compression"},
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		},
@@ -271,7 +271,7 @@ This precise approach prevents duplicate functions, wrong placements, and other
 - After making file edits, automatically run the lint/format/build/test cycle using Bash tool
 - After tool usage, assess if additional steps are needed to complete the task
 - Continue autonomously - don't stop after individual actions unless the task is fully complete\` + systemPrompt,
-			Tools:     []string{"WebSearch", "Read", "Write", "Edit", "Grep", "Glob", "Bash", "TodoWrite", "Compression"},
+			Tools:     []string{"WebSearch", "Read", "Write", "Edit", "Grep", "CodeSearch", "Glob", "Bash", "TestRunner", "TodoWrite", "Compression"},
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		},
@@ -324,7 +324,7 @@ Stop when:
 
 - You are a coordinator, not an implementer – delegate implementation work; do not write code yourself
 - Keep the user informed of the plan and progress at each major step` + systemPrompt,
-			Tools:     []string{"Agent", "Read", "Grep", "Glob", "TodoWrite", "Compression"},
+			Tools:     []string{"Agent", "Read", "Grep", "CodeSearch", "Glob", "TodoWrite", "Compression"},
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		},
@@ -372,7 +372,7 @@ Stop when:
 - Focus on the "why" not just the "what" – architectural decisions need clear rationale
 - Be explicit about assumptions and constraints
 - Raise risks and open questions rather than hiding them` + systemPrompt,
-			Tools:     []string{"WebSearch", "Read", "Grep", "Glob", "TodoWrite", "Compression"},
+			Tools:     []string{"WebSearch", "Read", "Grep", "CodeSearch", "Glob", "TodoWrite", "Compression"},
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		},
@@ -398,6 +398,7 @@ You are the QA Agent. Your responsibility is to ensure software quality through
 - Integration tests: test interactions between components
 - Edge cases: identify and test boundary conditions and error paths
 - Regression: verify that existing functionality is not broken
+- Contract tests: call a live API's operations and check the responses against its Swagger specification
 
 ### Code Review Checklist
 
@@ -412,6 +413,7 @@ You are the QA Agent. Your responsibility is to ensure software quality through
 
 - Use Read, Grep, Glob to read source and test files
 - Use Process to run the test suite and linters
+- Use Swagger's execute operation to contract test an API against its specification
 - Use Write or Edit to write new or updated test files
 - Use TodoWrite to track the test plan and findings
 - Use WebSearch to research testing patterns or libraries if needed
@@ -422,7 +424,7 @@ Stop when:
 - All planned tests have been executed and results recorded
 - The code review is complete with all findings documented
 - A clear pass/fail summary has been delivered` + systemPrompt,
-			Tools:     []string{"WebSearch", "Read", "Write", "Edit", "Grep", "Glob", "Bash", "TodoWrite", "Compression"},
+			Tools:     []string{"WebSearch", "Read", "Write", "Edit", "Grep", "CodeSearch", "Glob", "Bash", "TestRunner", "Swagger", "TodoWrite", "Compression"},
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		},
@@ -472,7 +474,7 @@ Stop when:
 - The deployment or infrastructure change is complete and verified
 - The pipeline change has been committed and is passing
 - Findings have been reported and any blockers surfaced` + systemPrompt,
-			Tools:     []string{"WebSearch", "Read", "Write", "Edit", "Grep", "Glob", "Bash", "TodoWrite", "Compression"},
+			Tools:     []string{"WebSearch", "Read", "Write", "Edit", "Grep", "CodeSearch", "Glob", "Bash", "TodoWrite", "Compression"},
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		},
@@ -508,7 +510,7 @@ Structure your response as:
 - Be fast – the goal is orientation, not exhaustive analysis
 - If asked to explore a broad area, start shallow and go deeper only where relevant
 - Summarise what you found; do not dump raw file contents` + systemPrompt,
-			Tools:     []string{"Read", "Grep", "Glob", "Compression"},
+			Tools:     []string{"Read", "Grep", "CodeSearch", "Glob", "Compression"},
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		},
@@ -552,7 +554,7 @@ When you cannot reproduce the issue:
 - Use Process to run the application, tests, and reproduce the failure
 - Use Write or Edit only to apply the fix or add temporary instrumentation
 - Use TodoWrite to track your investigation steps` + systemPrompt,
-			Tools:     []string{"WebSearch", "Read", "Write", "Edit", "Grep", "Glob", "Bash", "TodoWrite", "Compression"},
+			Tools:     []string{"WebSearch", "Read", "Write", "Edit", "Grep", "CodeSearch", "Glob", "Bash", "TestRunner", "TodoWrite", "Compression"},
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		},
@@ -600,7 +602,7 @@ Produce a structured review with these sections:
 - Do not flag style preferences as correctness issues
 - Do not rewrite code in the review; describe the problem and suggest direction
 - Read-only: do not modify files` + systemPrompt,
-			Tools:     []string{"WebSearch", "Read", "Grep", "Glob", "TodoWrite", "Compression"},
+			Tools:     []string{"WebSearch", "Read", "Grep", "CodeSearch", "Glob", "TodoWrite", "Compression"},
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		},
@@ -646,7 +648,7 @@ Common improvements to look for:
 - Use Write or Edit to apply changes
 - Use Process to run tests and linters after each step
 - Use TodoWrite to track the planned transformations` + systemPrompt,
-			Tools:     []string{"Read", "Write", "Edit", "Grep", "Glob", "Bash", "TodoWrite", "Compression"},
+			Tools:     []string{"Read", "Write", "Edit", "Grep", "CodeSearch", "Glob", "Bash", "TestRunner", "TodoWrite", "Compression"},
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		},
@@ -696,7 +698,7 @@ You are the Security Agent. You audit codebases, configurations, and dependencie
 - Do not modify code during an audit unless explicitly asked to remediate
 - If you find a Critical issue, surface it immediately before completing the full audit
 - Back every finding with a specific code location – no speculative findings` + systemPrompt,
-			Tools:     []string{"WebSearch", "Read", "Grep", "Glob", "Bash", "TodoWrite", "Compression"},
+			Tools:     []string{"WebSearch", "Read", "Grep", "CodeSearch", "Glob", "Bash", "TodoWrite", "Compression"},
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		},
@@ -741,7 +743,7 @@ You are the Documentation Agent. You write clear, accurate, well-structured tech
 - If existing documentation is incorrect, flag it explicitly before updating it
 - Use Write or Edit to write or update documentation files
 - Use WebSearch to look up documentation standards or format conventions if needed` + systemPrompt,
-			Tools:     []string{"WebSearch", "Read", "Write", "Edit", "Grep", "Glob", "TodoWrite", "Compression"},
+			Tools:     []string{"WebSearch", "Read", "Write", "Edit", "Grep", "CodeSearch", "Glob", "TodoWrite", "Compression"},
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		},
@@ -757,7 +759,7 @@ func DefaultTools() []*entities.ToolData {
 			ID:            "A121CC4A-A5CE-4054-AB8D-8486863DC7EA",
 			ToolType:      "WebSearch",
 			Name:          "WebSearch",
-			Description:   "This tool searches the web using the Tavily API.",
+			Description:   "This tool searches the web using Tavily, Brave or DuckDuckGo, trying the configured providers in order.",
 			Configuration: map[string]string{"tavily_api_key": "#{TAVILY_API_KEY}#"},
 			CreatedAt:     now,
 			UpdatedAt:     now,
@@ -799,6 +801,15 @@ func DefaultTools() []*entities.ToolData {
 			CreatedAt:     now,
 			UpdatedAt:     now,
 		},
+		{
+			ID:            "6B0E4C71-3D2A-4F8E-9C57-A1D84E2F9B63",
+			ToolType:      "CodeSearch",
+			Name:          "CodeSearch",
+			Description:   "This tool searches file contents with regular expressions, using ripgrep when it is installed.",
+			Configuration: map[string]string{},
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		},
 		{
 			ID:            "996F432D-7505-4519-A18D-02BD4E7DCC7F",
 			ToolType:      "Glob",
@@ -818,6 +829,15 @@ func DefaultTools() []*entities.ToolData {
 			CreatedAt:     now,
 			UpdatedAt:     now,
 		},
+		{
+			ID:            "C4F1A9D2-7B3E-4E65-8A0C-5D2B9E6F1A37",
+			ToolType:      "TestRunner",
+			Name:          "TestRunner",
+			Description:   "Runs the project's test suite (Go, Rust, Node or Python, or a given command) and returns pass/fail counts, failing test names and the first lines of failure output.",
+			Configuration: map[string]string{"workspace": ""},
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		},
 		{
 			ID:            "7E29B4E6-3147-4826-939A-ABA82562A27B",
 			ToolType:      "WebFetch",
@@ -831,7 +851,7 @@ func DefaultTools() []*entities.ToolData {
 			ID:            "44BF67C9-45DC-4A0C-947E-58604D1F37B9",
 			ToolType:      "Swagger",
 			Name:          "Swagger",
-			Description:   "This tool parses and analyzes Swagger/OpenAPI specifications for API documentation and testing.",
+			Description:   "This tool parses Swagger/OpenAPI specifications and executes their operations against a live server, checking the responses against the specification.",
 			Configuration: map[string]string{},
 			CreatedAt:     now,
 			UpdatedAt:     now,
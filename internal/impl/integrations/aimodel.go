@@ -29,6 +29,22 @@ type AIModelIntegration struct {
 	toolRepo   interfaces.ToolRepository
 	logger     *zap.Logger
 	lastUsage  *entities.Usage
+	limiter    *rateLimiter
+	// requestTimeout bounds each request; zero disables the deadline
+	requestTimeout time.Duration
+	// noJSONSchema marks APIs that only accept the json_object response format
+	noJSONSchema bool
+	// toolCallIDFormat is the shape of tool call IDs the API accepts
+	toolCallIDFormat entities.ToolCallIDFormat
+	// strictToolSchemas sends function definitions in strict mode
+	strictToolSchemas bool
+	// params is how the API takes max_tokens, temperature and reasoning_effort
+	params paramDialect
+	// secrets are values besides the API key to mask in what is logged
+	secrets []string
+	// quotaLow is set when the last response's rate limit headers showed
+	// little remaining
+	quotaLow bool
 }
 
 // NewAIModelIntegration creates a new base integration for OpenAI-compatible APIs
@@ -43,16 +59,32 @@ func NewAIModelIntegration(baseURL, apiKey, model string, toolRepo interfaces.To
 		return nil, fmt.Errorf("model cannot be empty")
 	}
 	return &AIModelIntegration{
-		baseURL:    baseURL,
-		apiKey:     apiKey,
-		httpClient: &http.Client{Timeout: 30 * time.Minute},
-		model:      model,
-		toolRepo:   toolRepo,
-		logger:     logger,
-		lastUsage:  &entities.Usage{},
+		baseURL:        baseURL,
+		apiKey:         apiKey,
+		httpClient:     newHTTPClient(),
+		model:          model,
+		toolRepo:       toolRepo,
+		logger:         logger,
+		lastUsage:      &entities.Usage{},
+		requestTimeout: entities.DefaultRequestTimeout,
+		params:         chatCompletionsParams,
 	}, nil
 }
 
+func (m *AIModelIntegration) setRateLimiter(limiter *rateLimiter) {
+	m.limiter = limiter
+}
+
+func (m *AIModelIntegration) setRequestTimeout(timeout time.Duration) {
+	m.requestTimeout = timeout
+}
+
+// CountTokens uses the model's tiktoken encoding, or gpt-4's for models
+// tiktoken doesn't know
+func (m *AIModelIntegration) CountTokens(messages []*entities.Message) (int, error) {
+	return countTiktokenTokens(m.model, messages)
+}
+
 // ModelName returns the name of the model being used
 func (m *AIModelIntegration) ModelName() string {
 	m.logger.Info("Using OpenAI-compatible model", zap.String("model", m.model))
@@ -90,7 +122,7 @@ func convertToOpenAIMessages(messages []*entities.Message) []map[string]any {
 			apiMsg["tool_call_id"] = msg.ToolCallID
 			apiMsg["content"] = msg.Content
 		} else {
-			apiMsg["content"] = msg.Content
+			apiMsg["content"] = openAIContent(msg)
 		}
 
 		apiMessages = append(apiMessages, apiMsg)
@@ -100,11 +132,14 @@ func convertToOpenAIMessages(messages []*entities.Message) []map[string]any {
 
 // toolExecResult holds the outcome of a single tool call execution.
 type toolExecResult struct {
-	ToolCall    entities.ToolCall
-	ToolName    string
-	ToolResult  string // raw result sent back to the LLM
-	Content     string // display content sent to the TUI / events
-	ToolError   string
+	ToolCall   entities.ToolCall
+	ToolName   string
+	ToolResult string // raw result sent back to the LLM
+	Content    string // display content sent to the TUI / events
+	ToolError  string
+	// Benign is set when the error is an answer rather than a failure, so
+	// it doesn't count toward max_consecutive_failures
+	Benign      bool
 	Diff        string
 	ToolEvent   *entities.ToolCallEvent
 	ToolMessage *entities.Message
@@ -141,53 +176,129 @@ func injectToolArgs(args, toolName, chatID string) string {
 }
 
 // extractDiffStatic extracts a diff string from a FileWrite tool result JSON.
+// The full diff is preferred when the tool capped the one for the model.
 func extractDiffStatic(result string) string {
 	var d struct {
-		Diff string `json:"diff"`
+		Diff     string `json:"diff"`
+		FullDiff string `json:"fullDiff"`
 	}
 	if err := json.Unmarshal([]byte(result), &d); err == nil {
+		if d.FullDiff != "" {
+			return d.FullDiff
+		}
 		return d.Diff
 	}
 	return ""
 }
 
-// executeToolsParallel runs all toolCalls concurrently, publishes ToolCallEvents
-// in real-time as each tool completes, and returns results in the original order.
+// splitDiffResult separates a FileWrite result into what is sent to the
+// model, without the full diff of a capped change, and the diff to display.
+func splitDiffResult(result string) (string, string) {
+	diff := extractDiffStatic(result)
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(result), &fields); err != nil {
+		return result, diff
+	}
+	if _, ok := fields["fullDiff"]; !ok {
+		return result, diff
+	}
+	delete(fields, "fullDiff")
+	forModel, err := json.Marshal(fields)
+	if err != nil {
+		return result, diff
+	}
+	return string(forModel), diff
+}
+
+// executeToolsParallel runs toolCalls concurrently, at most
+// max_tool_concurrency at a time, publishes ToolCallEvents in real-time as each
+// tool completes, and returns results in the original order. Calls that mutate
+// run one at a time, in order, as toolCallDependencies describes. Read-only
+// calls made before in the run are answered from history's cache. Results are
+// limited to the max_tool_result_tokens option as counted by counter.
 func executeToolsParallel(
 	ctx context.Context,
 	toolCalls []entities.ToolCall,
 	toolRepo interfaces.ToolRepository,
 	options map[string]any,
+	iteration int,
+	history *toolCallHistory,
+	counter tokenCounter,
 	logger *zap.Logger,
 ) []toolExecResult {
 	chatID, _ := options["session_id"].(string)
+	approver, _ := options["tool_approver"].(interfaces.ToolApprover)
+	logger = logger.With(zap.String("chat_id", chatID))
 	results := make([]toolExecResult, len(toolCalls))
 	var wg sync.WaitGroup
 
+	waits := toolCallDependencies(toolCalls, toolRepo)
+	done := make([]chan struct{}, len(toolCalls))
+	for i := range toolCalls {
+		done[i] = make(chan struct{})
+	}
+	slots := make(chan struct{}, toolConcurrency(options))
+
 	for i, toolCall := range toolCalls {
 		wg.Add(1)
 		go func(i int, toolCall entities.ToolCall) {
 			defer wg.Done()
+			defer close(done[i])
+			for _, j := range waits[i] {
+				<-done[j]
+			}
+			slots <- struct{}{}
+			defer func() { <-slots }()
 
+			started := time.Now()
 			toolName := toolCall.Function.Name
 			args := injectToolArgs(toolCall.Function.Arguments, toolName, chatID)
 
 			var toolResult, toolError, diff string
+			var benign bool
 			tool, err := toolRepo.GetToolByName(toolName)
-			if err != nil {
+			if approver != nil && !approver.IsApproved(ctx, chatID, toolName, toolCall.Function.Arguments) {
+				toolResult = fmt.Sprintf("Tool %s requires approval and no approval policy matches this call. Ask the user to approve it with /approve in the TUI or under Tool Approvals in the web UI", toolName)
+				toolError = "approval required"
+				benign = true
+				logger.Info("Tool call requires approval", zap.String("toolName", toolName))
+			} else if err != nil {
 				toolResult = fmt.Sprintf("Tool %s could not be retrieved: %v", toolName, err)
 				toolError = err.Error()
 				logger.Warn("Failed to get tool", zap.String("toolName", toolName), zap.Error(err))
+			} else if problems := toolArgumentProblems(tool, args); len(problems) > 0 {
+				toolResult = formatArgumentProblems(toolName, problems)
+				toolError = "invalid arguments: " + strings.Join(problems, "; ")
+				logger.Info("Tool call has invalid arguments", zap.String("toolName", toolName), zap.Strings("problems", problems))
+			} else if planned, ok := planToolCall(options, tool, toolName, args); ok {
+				toolResult = planned
+				logger.Info("Tool call added to plan", zap.String("toolName", toolName))
+			} else if note, ok := history.repeated(tool, toolName, args); ok {
+				toolResult = note
+				logger.Info("Repeated tool call skipped", zap.String("toolName", toolName))
+			} else if cached, ok := history.cache().get(tool, toolName, args); ok {
+				toolResult = cached
+				logger.Info("Tool result served from cache", zap.String("toolName", toolName))
+			} else if rejected, ok := reviewEdit(ctx, options, tool, toolName, toolCall.ID, args); ok {
+				toolResult = rejected
+				logger.Info("Tool call rejected in review", zap.String("toolName", toolName))
 			} else if tool != nil {
-				result, execErr := tool.Execute(ctx, args)
+				history.cache().invalidate(tool, args)
+				result, execErr := executeTool(ctx, tool, args)
 				if execErr != nil {
 					toolResult = fmt.Sprintf("Tool %s execution failed: %v", toolName, execErr)
 					toolError = execErr.Error()
+					benign = lookupMiss(tool, args, toolError)
 					logger.Warn("Tool execution failed", zap.String("toolName", toolName), zap.Error(execErr))
 				} else {
+					logger.Info("Tool executed", zap.String("toolName", toolName))
+					history.record(tool, toolName, args, result)
+					// Reads that ran alongside the call may have seen it half done
+					history.cache().invalidate(tool, args)
+					history.cache().put(tool, toolName, args, result)
 					toolResult = result
 					if toolName == "Write" || toolName == "Edit" {
-						diff = extractDiffStatic(result)
+						toolResult, diff = splitDiffResult(result)
 					}
 				}
 			} else {
@@ -196,6 +307,9 @@ func executeToolsParallel(
 				logger.Warn("Tool not found", zap.String("toolName", toolName))
 			}
 
+			auditToolCall(options, iteration, toolCall, toolResult, toolError, started)
+			toolResult = limitToolResult(toolResult, toolResultTokenLimit(options), counter)
+
 			content := toolResult
 			if toolError != "" {
 				content = fmt.Sprintf("Tool %s failed with error: %s", toolName, toolError)
@@ -219,6 +333,7 @@ func executeToolsParallel(
 				ToolResult:  toolResult,
 				Content:     content,
 				ToolError:   toolError,
+				Benign:      benign,
 				Diff:        diff,
 				ToolEvent:   toolEvent,
 				ToolMessage: toolMessage,
@@ -232,6 +347,8 @@ func executeToolsParallel(
 
 // GenerateResponse generates a response from the OpenAI-compatible API with incremental saving
 func (m *AIModelIntegration) GenerateResponse(ctx context.Context, messages []*entities.Message, toolList []entities.Tool, options map[string]any, callback interfaces.MessageCallback) ([]*entities.Message, error) {
+	messages = normalizeToolCallIDs(messages, m.toolCallIDFormat)
+
 	// Prepare tool definitions for OpenAI
 	tools := make([]map[string]any, len(toolList))
 	for i, tool := range toolList {
@@ -240,13 +357,17 @@ func (m *AIModelIntegration) GenerateResponse(ctx context.Context, messages []*e
 			return nil, fmt.Errorf("operation canceled by user")
 		}
 
+		function := map[string]any{
+			"name":        tool.Name(),
+			"description": tool.Description(),
+			"parameters":  toolParameters(tool.Schema(), m.strictToolSchemas),
+		}
+		if m.strictToolSchemas {
+			function["strict"] = true
+		}
 		tools[i] = map[string]any{
-			"type": "function",
-			"function": map[string]any{
-				"name":        tool.Name(),
-				"description": tool.Description(),
-				"parameters":  tool.Schema(),
-			},
+			"type":     "function",
+			"function": function,
 		}
 	}
 
@@ -256,89 +377,50 @@ func (m *AIModelIntegration) GenerateResponse(ctx context.Context, messages []*e
 		"messages": convertToOpenAIMessages(messages),
 	}
 
-	// Handle max tokens parameter (may be max_tokens or max_completion_tokens)
-	if maxCompletionTokens, ok := options["max_completion_tokens"]; ok {
-		reqBody["max_completion_tokens"] = maxCompletionTokens
-	} else if maxTokens, ok := options["max_tokens"]; ok {
-		reqBody["max_tokens"] = maxTokens
-	}
-	if temp, ok := options["temperature"]; ok {
-		reqBody["temperature"] = temp
-	}
+	m.params.apply(reqBody, options, m.logger)
 	if len(tools) > 0 {
 		reqBody["tools"] = tools
 	}
+	if cacheKey, ok := options["prompt_cache_key"].(string); ok && cacheKey != "" {
+		reqBody["prompt_cache_key"] = cacheKey
+	}
+	if format := responseFormatOption(options); format != nil {
+		reqBody["response_format"] = chatCompletionsResponseFormat(format, !m.noJSONSchema)
+	}
 
 	var newMessages []*entities.Message
 
 	// Tool call handling loop
+	iteration := 0
+	history := newToolCallHistory()
+	failures := newToolFailureCount(options)
 	for {
+		iteration++
 		// Check for cancellation before sending request
 		if ctx.Err() == context.Canceled {
-			return nil, fmt.Errorf("operation canceled by user")
+			return canceledResponse(newMessages, callback)
+		}
+
+		// Add the notes the user sent to steer the response since the last request
+		if notes := steeringNotes(options, callback, m.logger); len(notes) > 0 {
+			newMessages = append(newMessages, notes...)
+			reqBody["messages"] = append(reqBody["messages"].([]map[string]any), convertToOpenAIMessages(notes)...)
 		}
 
 		jsonBody, err := json.Marshal(reqBody)
 		if err != nil {
 			return nil, fmt.Errorf("error marshaling request: %v", err)
 		}
-		m.logger.Info("Sending request to OpenAI-compatible API", zap.String("body", string(jsonBody)))
+		m.logger.Info("Sending request to OpenAI-compatible API", zap.String("body", m.redact(string(jsonBody))))
 
-		req, err := http.NewRequestWithContext(ctx, "POST", m.baseURL, bytes.NewBuffer(jsonBody))
+		respBody, err := m.sendRequest(ctx, jsonBody)
 		if err != nil {
-			return nil, fmt.Errorf("error creating request: %v", err)
-		}
-
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set(m.authHeaderName(), m.authHeaderValue())
-
-		var resp *http.Response
-		for attempt := 0; attempt < 3; attempt++ {
-			// Check for cancellation before making request
 			if ctx.Err() == context.Canceled {
-				return nil, fmt.Errorf("operation canceled by user")
-			}
-
-			resp, err = m.httpClient.Do(req)
-			if err != nil {
-				if attempt < 2 {
-					m.logger.Warn("Error making request, retrying", zap.Error(err))
-					time.Sleep(time.Duration(attempt+1) * time.Second)
-					continue
-				}
-				return nil, fmt.Errorf("error making request: %v", err)
-			}
-			if resp.StatusCode == http.StatusTooManyRequests {
-				if attempt < 2 {
-					time.Sleep(time.Duration(attempt+1) * time.Second)
-					continue
-				}
-				return nil, fmt.Errorf("rate limit exceeded")
+				return canceledResponse(newMessages, callback)
 			}
-			if resp.StatusCode != http.StatusOK {
-				body, _ := io.ReadAll(resp.Body)
-				m.logger.Error("OpenAI-compatible API error",
-					zap.Int("status_code", resp.StatusCode),
-					zap.String("body", string(body)))
-
-				// Check for context window errors
-				if resp.StatusCode == http.StatusBadRequest {
-					if contextErr := m.parseOpenAIContextError(body); contextErr != nil {
-						return nil, contextErr
-					}
-				}
-
-				return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
-			}
-			break
+			return nil, err
 		}
-		defer resp.Body.Close()
-
-		respBody, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, fmt.Errorf("error reading response: %v", err)
-		}
-		m.logger.Info("OpenAI-compatible response", zap.String("body", string(respBody)))
+		m.logger.Info("OpenAI-compatible response", zap.String("body", m.redact(string(respBody))))
 
 		// Parse response
 		var responseBody struct {
@@ -349,16 +431,24 @@ func (m *AIModelIntegration) GenerateResponse(ctx context.Context, messages []*e
 			Choices []struct {
 				Index   int `json:"index"`
 				Message struct {
-					Role      string           `json:"role"`
-					Content   string           `json:"content"`
-					ToolCalls []map[string]any `json:"tool_calls"`
+					Role             string           `json:"role"`
+					Content          string           `json:"content"`
+					ReasoningContent string           `json:"reasoning_content"`
+					Reasoning        string           `json:"reasoning"`
+					ToolCalls        []map[string]any `json:"tool_calls"`
 				} `json:"message"`
 				FinishReason string `json:"finish_reason"`
 			} `json:"choices"`
 			Usage struct {
-				PromptTokens     int `json:"prompt_tokens"`
-				CompletionTokens int `json:"completion_tokens"`
-				TotalTokens      int `json:"total_tokens"`
+				PromptTokens            int `json:"prompt_tokens"`
+				CompletionTokens        int `json:"completion_tokens"`
+				TotalTokens             int `json:"total_tokens"`
+				CompletionTokensDetails struct {
+					ReasoningTokens int `json:"reasoning_tokens"`
+				} `json:"completion_tokens_details"`
+				PromptTokensDetails struct {
+					CachedTokens int `json:"cached_tokens"`
+				} `json:"prompt_tokens_details"`
 			} `json:"usage"`
 		}
 		if err := json.Unmarshal(respBody, &responseBody); err != nil {
@@ -371,13 +461,18 @@ func (m *AIModelIntegration) GenerateResponse(ctx context.Context, messages []*e
 
 		choice := responseBody.Choices[0]
 		message := choice.Message
+		// DeepSeek and vLLM return reasoning_content, OpenRouter reasoning
+		content, reasoning := splitReasoning(message.Content, message.ReasoningContent, message.Reasoning)
 
 		// Parse tool calls
 		var toolCalls []entities.ToolCall
 		for _, tcMap := range message.ToolCalls {
 			var tc entities.ToolCall
-			if id, ok := tcMap["id"].(string); ok {
-				tc.ID = m.customizeToolCallID(id)
+			if id, ok := tcMap["id"].(string); ok && id != "" {
+				tc.ID = id
+			} else {
+				// Some OpenAI-compatible servers leave the ID out
+				tc.ID = newToolCallID(m.toolCallIDFormat)
 			}
 			if typ, ok := tcMap["type"].(string); ok {
 				tc.Type = typ
@@ -399,10 +494,12 @@ func (m *AIModelIntegration) GenerateResponse(ctx context.Context, messages []*e
 		m.lastUsage.PromptTokens = responseBody.Usage.PromptTokens
 		m.lastUsage.CompletionTokens = responseBody.Usage.CompletionTokens
 		m.lastUsage.TotalTokens = responseBody.Usage.TotalTokens
+		m.lastUsage.ReasoningTokens = responseBody.Usage.CompletionTokensDetails.ReasoningTokens
+		m.lastUsage.CachedTokens = responseBody.Usage.PromptTokensDetails.CachedTokens
 
 		// Log tool calls
 		if len(toolCalls) > 0 {
-			m.logger.Info("Tool calls generated", zap.Any("toolCalls", toolCalls))
+			m.logger.Info("Tool calls generated", zap.Strings("tools", toolCallNames(toolCalls)))
 		} else {
 			m.logger.Info("No tool calls generated")
 		}
@@ -412,8 +509,9 @@ func (m *AIModelIntegration) GenerateResponse(ctx context.Context, messages []*e
 			toolCallMessage := &entities.Message{
 				ID:        uuid.New().String(),
 				Role:      "assistant",
-				Content:   message.Content,
+				Content:   content,
 				ToolCalls: toolCalls,
+				Reasoning: reasoning,
 				Timestamp: time.Now(),
 			}
 			newMessages = append(newMessages, toolCallMessage)
@@ -430,7 +528,7 @@ func (m *AIModelIntegration) GenerateResponse(ctx context.Context, messages []*e
 			reqBody["messages"] = append(reqBody["messages"].([]map[string]any), assistantMessageAPI)
 
 			// Execute all tool calls in parallel, then process results in order.
-			toolResults := executeToolsParallel(ctx, toolCalls, m.toolRepo, options, m.logger)
+			toolResults := executeToolsParallel(ctx, toolCalls, runTools(m.toolRepo, toolList), options, iteration, history, m, m.logger)
 			for _, r := range toolResults {
 				newMessages = append(newMessages, r.ToolMessage)
 
@@ -447,12 +545,18 @@ func (m *AIModelIntegration) GenerateResponse(ctx context.Context, messages []*e
 				}
 				reqBody["messages"] = append(reqBody["messages"].([]map[string]any), apiMsg)
 			}
+
+			if stop := failures.observe(content, toolResults, callback, m.logger); stop != nil {
+				newMessages = append(newMessages, stop)
+				break
+			}
 		} else {
 			// Any other finish_reason is treated as final
 			finalMessage := &entities.Message{
 				ID:        uuid.New().String(),
 				Role:      "assistant",
-				Content:   message.Content,
+				Content:   content,
+				Reasoning: reasoning,
 				Timestamp: time.Now(),
 			}
 			newMessages = append(newMessages, finalMessage)
@@ -464,6 +568,11 @@ func (m *AIModelIntegration) GenerateResponse(ctx context.Context, messages []*e
 				}
 			}
 
+			// Keep going when the user steered the response before it ended
+			if steeringPending(options) {
+				reqBody["messages"] = append(reqBody["messages"].([]map[string]any), convertToOpenAIMessages([]*entities.Message{finalMessage})...)
+				continue
+			}
 			break
 		}
 	}
@@ -471,7 +580,7 @@ func (m *AIModelIntegration) GenerateResponse(ctx context.Context, messages []*e
 	// Validate that all tool calls have responses before returning
 	newMessages = ensureToolCallResponsesOpenAI(newMessages, m.logger)
 
-	m.logger.Info("Generated messages", zap.Any("messages", newMessages))
+	logGeneratedMessages(m.logger, newMessages)
 	return newMessages, nil
 }
 
@@ -515,6 +624,76 @@ func ensureToolCallResponsesOpenAI(messages []*entities.Message, logger *zap.Log
 	return messages
 }
 
+// sendRequest posts the request body, retrying failed and rate limited
+// requests, and returns the response body. The request deadline ends with it.
+func (m *AIModelIntegration) sendRequest(ctx context.Context, jsonBody []byte) ([]byte, error) {
+	reqCtx, cancel := requestContext(ctx, m.requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, "POST", m.baseURL, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(m.authHeaderName(), m.authHeaderValue())
+
+	var resp *http.Response
+	for attempt := 0; attempt < 3; attempt++ {
+		// Check for cancellation before making request
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if err := m.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		resp, err = m.httpClient.Do(req)
+		if err != nil {
+			if attempt < 2 {
+				m.logger.Warn("Error making request, retrying", zap.Error(err))
+				time.Sleep(time.Duration(attempt+1) * time.Second)
+				continue
+			}
+			return nil, fmt.Errorf("error making request: %v", err)
+		}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			if attempt < 2 {
+				resp.Body.Close()
+				time.Sleep(time.Duration(attempt+1) * time.Second)
+				continue
+			}
+			return nil, errors.RateLimitErrorf("rate limit exceeded")
+		}
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			body = []byte(m.redact(string(body)))
+			m.logger.Error("OpenAI-compatible API error",
+				zap.Int("status_code", resp.StatusCode),
+				zap.String("body", string(body)))
+
+			// Check for context window errors
+			if resp.StatusCode == http.StatusBadRequest {
+				if contextErr := m.parseOpenAIContextError(body); contextErr != nil {
+					return nil, contextErr
+				}
+			}
+
+			return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+		}
+		m.quotaLow = quotaNearLimit(resp.Header)
+		break
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %v", err)
+	}
+	return respBody, nil
+}
+
 // extractDiffFromResult extracts diff from FileWrite tool result
 func (m *AIModelIntegration) extractDiffFromResult(result string) string {
 	var resultData struct {
@@ -536,11 +715,6 @@ func (m *AIModelIntegration) GetLastUsage() (*entities.Usage, error) {
 	return m.lastUsage, nil
 }
 
-// customizeToolCallID allows providers to customize tool call IDs (e.g., for format requirements)
-func (m *AIModelIntegration) customizeToolCallID(originalID string) string {
-	return originalID
-}
-
 // authHeaderName returns the header name for authentication
 func (m *AIModelIntegration) authHeaderName() string {
 	return "Authorization"
@@ -23,11 +23,40 @@ func NewAIModelFactory(toolRepo interfaces.ToolRepository, logger *zap.Logger) *
 
 // CreateModelIntegration creates an AI model integration based on the model configuration
 func (f *AIModelFactory) CreateModelIntegration(model *entities.Model, provider *entities.Provider, apiKey string) (interfaces.AIModelIntegration, error) {
+	integration, err := f.createIntegration(model, provider, apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if limited, ok := integration.(rateLimited); ok {
+		limited.setRateLimiter(limiterForProvider(provider))
+	}
+	if timed, ok := integration.(requestTimed); ok {
+		timed.setRequestTimeout(provider.RequestTimeout())
+	}
+	if formatted, ok := integration.(toolCallIDFormatted); ok && provider.ToolCallIDFormat != "" {
+		formatted.setToolCallIDFormat(provider.ToolCallIDFormat)
+	}
+	if strict, ok := integration.(strictToolSchemaSetter); ok {
+		strict.setStrictToolSchemas(provider.UsesStrictToolSchemas())
+	}
+	return integration, nil
+}
+
+func (f *AIModelFactory) createIntegration(model *entities.Model, provider *entities.Provider, apiKey string) (interfaces.AIModelIntegration, error) {
 	// Use provider's base URL as endpoint
 	endpoint := provider.BaseURL
 
 	// Create provider-specific integration
-	switch provider.Type {
+	providerType := provider.EffectiveType()
+	if providerType != provider.Type {
+		endpoint = nativeEndpoint(endpoint)
+		f.logger.Debug("Using detected provider type",
+			zap.String("provider", provider.Name),
+			zap.String("configured_type", string(provider.Type)),
+			zap.String("detected_type", string(providerType)))
+	}
+	switch providerType {
 	case entities.ProviderOpenAI:
 		return NewOpenAIIntegration(endpoint, apiKey, model, f.toolRepo, f.logger)
 	case entities.ProviderAnthropic:
@@ -44,6 +73,8 @@ func (f *AIModelFactory) CreateModelIntegration(model *entities.Model, provider
 		return NewGroqIntegration(endpoint, apiKey, model.ModelName, f.toolRepo, f.logger)
 	case entities.ProviderMistral:
 		return NewMistralIntegration(endpoint, apiKey, model.ModelName, f.toolRepo, f.logger)
+	case entities.ProviderOllama:
+		return NewOllamaIntegration(endpoint, apiKey, model, f.toolRepo, f.logger)
 	case entities.ProviderGeneric:
 		// For generic providers, use the OpenAI-compatible API
 		return NewGenericIntegration(endpoint, apiKey, model.ModelName, f.toolRepo, f.logger)
@@ -18,6 +18,10 @@ import (
 	"go.uber.org/zap"
 )
 
+// anthropicDefaultMaxTokens is sent when neither the model nor the message
+// sets max_tokens, which the Messages API requires.
+const anthropicDefaultMaxTokens = 16384
+
 // AnthropicIntegration implements the Anthropic Claude API
 type AnthropicIntegration struct {
 	baseURL    string
@@ -27,6 +31,16 @@ type AnthropicIntegration struct {
 	toolRepo   interfaces.ToolRepository
 	logger     *zap.Logger
 	lastUsage  *entities.Usage
+	limiter    *rateLimiter
+	// requestTimeout bounds each request; zero disables the deadline
+	requestTimeout time.Duration
+	// toolCallIDFormat is the shape of tool_use IDs the API accepts
+	toolCallIDFormat entities.ToolCallIDFormat
+	// secrets are values besides the API key to mask in what is logged
+	secrets []string
+	// quotaLow is set when the last response's rate limit headers showed
+	// little remaining
+	quotaLow bool
 }
 
 // NewAnthropicIntegration creates a new Anthropic integration
@@ -41,41 +55,69 @@ func NewAnthropicIntegration(baseURL, apiKey, model string, toolRepo interfaces.
 		return nil, fmt.Errorf("model cannot be empty")
 	}
 	return &AnthropicIntegration{
-		baseURL:    baseURL,
-		apiKey:     apiKey,
-		httpClient: &http.Client{Timeout: 30 * time.Minute},
-		model:      model,
-		toolRepo:   toolRepo,
-		logger:     logger,
-		lastUsage:  &entities.Usage{},
+		baseURL:          baseURL,
+		apiKey:           apiKey,
+		httpClient:       newHTTPClient(),
+		model:            model,
+		toolRepo:         toolRepo,
+		logger:           logger,
+		lastUsage:        &entities.Usage{},
+		requestTimeout:   entities.DefaultRequestTimeout,
+		toolCallIDFormat: entities.ToolCallIDAnthropic,
 	}, nil
 }
 
+// CountTokens estimates from message length, as Claude's tokenizer isn't
+// published
+func (m *AnthropicIntegration) CountTokens(messages []*entities.Message) (int, error) {
+	return countCharTokens(messages, anthropicCharsPerToken), nil
+}
+
 // ModelName returns the name of the model being used
 func (m *AnthropicIntegration) ModelName() string {
 	m.logger.Info("Using Anthropic model", zap.String("model", m.model))
 	return m.model
 }
 
+func (m *AnthropicIntegration) setRateLimiter(limiter *rateLimiter) {
+	m.limiter = limiter
+}
+
+func (m *AnthropicIntegration) setRequestTimeout(timeout time.Duration) {
+	m.requestTimeout = timeout
+}
+
+func (m *AnthropicIntegration) setToolCallIDFormat(format entities.ToolCallIDFormat) {
+	m.toolCallIDFormat = format
+}
+
 // ProviderType returns the type of provider
 func (m *AnthropicIntegration) ProviderType() entities.ProviderType {
 	return entities.ProviderAnthropic
 }
 
-// convertToAnthropicMessages converts message entities to Anthropic API format
+// convertToAnthropicMessages converts message entities to Anthropic API format.
+// The leading system messages go in the system prompt; the API has no system
+// role, so the notes further down the history, such as an approved plan, are
+// sent as user text where they occur.
 func convertToAnthropicMessages(messages []*entities.Message) []map[string]any {
 	apiMessages := make([]map[string]any, 0, len(messages))
+	leading := true
 	for _, msg := range messages {
-		if msg.Role == "system" { // Skip system for initial request
+		if msg.Role == "system" {
+			if !leading && msg.Content != "" {
+				apiMessages = appendAnthropicUserText(apiMessages, "System note: "+msg.Content)
+			}
 			continue
 		}
+		leading = false
 
 		apiMsg := map[string]any{}
 
 		switch msg.Role {
 		case "user":
 			apiMsg["role"] = "user"
-			apiMsg["content"] = msg.Content
+			apiMsg["content"] = anthropicContent(msg)
 		case "assistant":
 			apiMsg["role"] = "assistant"
 			if len(msg.ToolCalls) > 0 {
@@ -108,8 +150,28 @@ func convertToAnthropicMessages(messages []*entities.Message) []map[string]any {
 	return apiMessages
 }
 
+// appendAnthropicUserText adds text to the last message when it is the
+// user's, such as the tool results that follow a tool call, and as a user
+// message otherwise.
+func appendAnthropicUserText(apiMessages []map[string]any, text string) []map[string]any {
+	block := map[string]any{"type": "text", "text": text}
+	if n := len(apiMessages); n > 0 && apiMessages[n-1]["role"] == "user" {
+		switch content := apiMessages[n-1]["content"].(type) {
+		case string:
+			apiMessages[n-1]["content"] = []map[string]any{{"type": "text", "text": content}, block}
+			return apiMessages
+		case []map[string]any:
+			apiMessages[n-1]["content"] = append(content, block)
+			return apiMessages
+		}
+	}
+	return append(apiMessages, map[string]any{"role": "user", "content": []map[string]any{block}})
+}
+
 // GenerateResponse generates a response from the Anthropic API with incremental saving
 func (m *AnthropicIntegration) GenerateResponse(ctx context.Context, messages []*entities.Message, toolList []entities.Tool, options map[string]any, callback interfaces.MessageCallback) ([]*entities.Message, error) {
+	messages = normalizeToolCallIDs(messages, m.toolCallIDFormat)
+
 	// Prepare tool definitions for Anthropic
 	tools := make([]map[string]any, len(toolList))
 	for i, tool := range toolList {
@@ -125,27 +187,47 @@ func (m *AnthropicIntegration) GenerateResponse(ctx context.Context, messages []
 		}
 	}
 
-	// Extract system message if present
-	var systemPrompt string
+	// Extract the leading system messages; layered instructions may arrive as
+	// several. System notes further down the history (plans, results) stay
+	// out of the cached system prompt.
+	var systemParts []string
 	for _, msg := range messages {
-		if msg.Role == "system" {
-			systemPrompt = msg.Content
+		if msg.Role != "system" {
 			break
 		}
+		if msg.Content != "" {
+			systemParts = append(systemParts, msg.Content)
+		}
 	}
+	systemPrompt := strings.Join(systemParts, "\n\n")
 
 	// Format request body
 	reqBody := map[string]any{
-		"model":      m.model,
-		"max_tokens": options["max_tokens"],
+		"model": m.model,
 	}
-	if temp, ok := options["temperature"]; ok {
-		reqBody["temperature"] = temp
+	anthropicParams.apply(reqBody, options, m.logger)
+	if _, ok := reqBody["max_tokens"]; !ok {
+		reqBody["max_tokens"] = anthropicDefaultMaxTokens
 	}
+	// Mark the stable prefix (tools, then system prompt) as cacheable. The
+	// cache breakpoint on the system block covers the tool definitions too.
+	promptCaching, _ := options["prompt_caching"].(bool)
 	if systemPrompt != "" {
-		reqBody["system"] = systemPrompt
+		if promptCaching {
+			blocks := make([]map[string]any, len(systemParts))
+			for i, part := range systemParts {
+				blocks[i] = map[string]any{"type": "text", "text": part}
+			}
+			blocks[len(blocks)-1]["cache_control"] = map[string]any{"type": "ephemeral"}
+			reqBody["system"] = blocks
+		} else {
+			reqBody["system"] = systemPrompt
+		}
 	}
 	if len(tools) > 0 {
+		if promptCaching && systemPrompt == "" {
+			tools[len(tools)-1]["cache_control"] = map[string]any{"type": "ephemeral"}
+		}
 		reqBody["tools"] = tools
 	}
 
@@ -156,72 +238,37 @@ func (m *AnthropicIntegration) GenerateResponse(ctx context.Context, messages []
 	var newMessages []*entities.Message
 
 	// Tool call handling loop
+	iteration := 0
+	history := newToolCallHistory()
+	failures := newToolFailureCount(options)
 	for {
+		iteration++
 		// Check for cancellation before sending request
 		if ctx.Err() == context.Canceled {
-			return nil, fmt.Errorf("operation canceled by user")
+			return canceledResponse(newMessages, callback)
+		}
+
+		// Add the notes the user sent to steer the response since the last request
+		if notes := steeringNotes(options, callback, m.logger); len(notes) > 0 {
+			newMessages = append(newMessages, notes...)
+			apiMessages = append(apiMessages, convertToAnthropicMessages(notes)...)
+			reqBody["messages"] = apiMessages
 		}
 
 		jsonBody, err := json.Marshal(reqBody)
 		if err != nil {
 			return nil, fmt.Errorf("error marshaling request: %v", err)
 		}
-		m.logger.Info("Sending request to Anthropic", zap.String("body", string(jsonBody)))
+		m.logger.Info("Sending request to Anthropic", zap.String("body", m.redact(string(jsonBody))))
 
-		req, err := http.NewRequestWithContext(ctx, "POST", m.baseURL+"/v1/messages", bytes.NewBuffer(jsonBody))
+		respBody, err := m.sendRequest(ctx, jsonBody)
 		if err != nil {
-			return nil, fmt.Errorf("error creating request: %v", err)
-		}
-
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("x-api-key", m.apiKey)
-		req.Header.Set("anthropic-version", "2023-06-01")
-
-		var resp *http.Response
-		for attempt := 0; attempt < 3; attempt++ {
-			// Check for cancellation before making request
 			if ctx.Err() == context.Canceled {
-				return nil, fmt.Errorf("operation canceled by user")
-			}
-
-			resp, err = m.httpClient.Do(req)
-			if err != nil {
-				if attempt < 2 {
-					m.logger.Warn("Error making request, retrying", zap.Error(err))
-					time.Sleep(time.Duration(attempt+1) * time.Second)
-					continue
-				}
-				return nil, fmt.Errorf("error making request: %v", err)
+				return canceledResponse(newMessages, callback)
 			}
-			if resp.StatusCode == http.StatusTooManyRequests {
-				if attempt < 2 {
-					time.Sleep(time.Duration(attempt+1) * time.Second)
-					continue
-				}
-				return nil, fmt.Errorf("rate limit exceeded")
-			}
-			if resp.StatusCode != http.StatusOK {
-				body, _ := io.ReadAll(resp.Body)
-				m.logger.Error("Anthropic API error",
-					zap.Int("status_code", resp.StatusCode),
-					zap.String("body", string(body)))
-
-				// Check for context window errors on any error status
-				if contextErr := m.parseAnthropicContextError(body); contextErr != nil {
-					return nil, contextErr
-				}
-
-				return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
-			}
-			break
-		}
-		defer resp.Body.Close()
-
-		respBody, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, fmt.Errorf("error reading response: %v", err)
+			return nil, err
 		}
-		m.logger.Info("Anthropic response", zap.String("body", string(respBody)))
+		m.logger.Info("Anthropic response", zap.String("body", m.redact(string(respBody))))
 
 		// Parse response
 		var responseBody struct {
@@ -230,15 +277,18 @@ func (m *AnthropicIntegration) GenerateResponse(ctx context.Context, messages []
 			Model      string `json:"model"`
 			StopReason string `json:"stop_reason"`
 			Usage      struct {
-				InputTokens  int `json:"input_tokens"`
-				OutputTokens int `json:"output_tokens"`
+				InputTokens              int `json:"input_tokens"`
+				OutputTokens             int `json:"output_tokens"`
+				CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+				CacheReadInputTokens     int `json:"cache_read_input_tokens"`
 			} `json:"usage"`
 			Content []struct {
-				Type  string          `json:"type"`
-				Text  string          `json:"text,omitempty"`
-				Id    string          `json:"id"`
-				Name  string          `json:"name"`
-				Input json.RawMessage `json:"input"`
+				Type     string          `json:"type"`
+				Text     string          `json:"text,omitempty"`
+				Thinking string          `json:"thinking,omitempty"`
+				Id       string          `json:"id"`
+				Name     string          `json:"name"`
+				Input    json.RawMessage `json:"input"`
 			} `json:"content"`
 		}
 		if err := json.Unmarshal(respBody, &responseBody); err != nil {
@@ -262,17 +312,24 @@ func (m *AnthropicIntegration) GenerateResponse(ctx context.Context, messages []
 		}
 
 		// Track usage
-		m.lastUsage.PromptTokens = responseBody.Usage.InputTokens
+		// Anthropic reports cached tokens separately from the uncached input tokens
+		promptTokens := responseBody.Usage.InputTokens + responseBody.Usage.CacheCreationInputTokens + responseBody.Usage.CacheReadInputTokens
+		m.lastUsage.PromptTokens = promptTokens
 		m.lastUsage.CompletionTokens = responseBody.Usage.OutputTokens
-		m.lastUsage.TotalTokens = responseBody.Usage.InputTokens + responseBody.Usage.OutputTokens
+		m.lastUsage.TotalTokens = promptTokens + responseBody.Usage.OutputTokens
+		m.lastUsage.CachedTokens = responseBody.Usage.CacheReadInputTokens
+		m.lastUsage.CacheWriteTokens = responseBody.Usage.CacheCreationInputTokens
 
 		// Process response content
 		var toolCalls []entities.ToolCall
 		var textContent string
+		var thinking []string
 
 		for _, content := range responseBody.Content {
 			if content.Type == "text" {
 				textContent += content.Text
+			} else if content.Type == "thinking" {
+				thinking = append(thinking, content.Thinking)
 			} else if content.Type == "tool_use" {
 				toolCall := entities.ToolCall{
 					ID:   content.Id,
@@ -289,10 +346,11 @@ func (m *AnthropicIntegration) GenerateResponse(ctx context.Context, messages []
 		}
 
 		if len(toolCalls) > 0 {
-			m.logger.Info("Tool calls generated", zap.Any("toolCalls", toolCalls))
+			m.logger.Info("Tool calls generated", zap.Strings("tools", toolCallNames(toolCalls)))
 		} else {
 			m.logger.Info("No tool calls generated")
 		}
+		textContent, reasoning := splitReasoning(textContent, thinking...)
 
 		// Only continue if stop_reason indicates tool use
 		if responseBody.StopReason == "tool_use" {
@@ -301,6 +359,7 @@ func (m *AnthropicIntegration) GenerateResponse(ctx context.Context, messages []
 				Role:      "assistant",
 				Content:   textContent,
 				ToolCalls: toolCalls,
+				Reasoning: reasoning,
 				Timestamp: time.Now(),
 			}
 			newMessages = append(newMessages, toolCallMessage)
@@ -313,7 +372,7 @@ func (m *AnthropicIntegration) GenerateResponse(ctx context.Context, messages []
 			}
 
 			// Execute all tool calls in parallel, then process results in order.
-			toolResults := executeToolsParallel(ctx, toolCalls, m.toolRepo, options, m.logger)
+			toolResults := executeToolsParallel(ctx, toolCalls, runTools(m.toolRepo, toolList), options, iteration, history, m, m.logger)
 			for _, r := range toolResults {
 				newMessages = append(newMessages, r.ToolMessage)
 
@@ -349,12 +408,18 @@ func (m *AnthropicIntegration) GenerateResponse(ctx context.Context, messages []
 			}
 
 			reqBody["messages"] = apiMessages
+
+			if stop := failures.observe(textContent, toolResults, callback, m.logger); stop != nil {
+				newMessages = append(newMessages, stop)
+				break
+			}
 		} else {
 			// Any other stop_reason is treated as final
 			finalMessage := &entities.Message{
 				ID:        uuid.New().String(),
 				Role:      "assistant",
 				Content:   textContent,
+				Reasoning: reasoning,
 				Timestamp: time.Now(),
 			}
 			newMessages = append(newMessages, finalMessage)
@@ -366,6 +431,12 @@ func (m *AnthropicIntegration) GenerateResponse(ctx context.Context, messages []
 				}
 			}
 
+			// Keep going when the user steered the response before it ended
+			if steeringPending(options) {
+				apiMessages = append(apiMessages, convertToAnthropicMessages([]*entities.Message{finalMessage})...)
+				reqBody["messages"] = apiMessages
+				continue
+			}
 			break
 		}
 	}
@@ -373,10 +444,80 @@ func (m *AnthropicIntegration) GenerateResponse(ctx context.Context, messages []
 	// Validate that all tool calls have responses before returning
 	newMessages = ensureToolCallResponsesAnthropic(newMessages, m.logger)
 
-	m.logger.Info("Generated messages", zap.Any("messages", newMessages))
+	logGeneratedMessages(m.logger, newMessages)
 	return newMessages, nil
 }
 
+// sendRequest posts the request body to the messages API, retrying failed and
+// rate limited requests, and returns the response body. The request deadline
+// ends with it.
+func (m *AnthropicIntegration) sendRequest(ctx context.Context, jsonBody []byte) ([]byte, error) {
+	reqCtx, cancel := requestContext(ctx, m.requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, "POST", m.baseURL+"/v1/messages", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", m.apiKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+
+	var resp *http.Response
+	for attempt := 0; attempt < 3; attempt++ {
+		// Check for cancellation before making request
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if err := m.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		resp, err = m.httpClient.Do(req)
+		if err != nil {
+			if attempt < 2 {
+				m.logger.Warn("Error making request, retrying", zap.Error(err))
+				time.Sleep(time.Duration(attempt+1) * time.Second)
+				continue
+			}
+			return nil, fmt.Errorf("error making request: %v", err)
+		}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			if attempt < 2 {
+				resp.Body.Close()
+				time.Sleep(time.Duration(attempt+1) * time.Second)
+				continue
+			}
+			return nil, errors.RateLimitErrorf("rate limit exceeded")
+		}
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			body = []byte(m.redact(string(body)))
+			m.logger.Error("Anthropic API error",
+				zap.Int("status_code", resp.StatusCode),
+				zap.String("body", string(body)))
+
+			// Check for context window errors on any error status
+			if contextErr := m.parseAnthropicContextError(body); contextErr != nil {
+				return nil, contextErr
+			}
+
+			return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+		}
+		m.quotaLow = quotaNearLimit(resp.Header)
+		break
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %v", err)
+	}
+	return respBody, nil
+}
+
 // ensureToolCallResponsesAnthropic validates that every tool call has a corresponding response
 // and creates error responses for any orphaned tool calls
 func ensureToolCallResponsesAnthropic(messages []*entities.Message, logger *zap.Logger) []*entities.Message {
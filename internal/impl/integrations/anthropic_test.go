@@ -0,0 +1,42 @@
+package integrations
+
+import (
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+)
+
+func TestConvertToAnthropicMessages_SystemNotes(t *testing.T) {
+	call := entities.ToolCall{ID: "call_1", Type: "function"}
+	call.Function.Name = "Edit"
+	call.Function.Arguments = "{}"
+	messages := []*entities.Message{
+		{Role: "system", Content: "You are helpful."},
+		{Role: "user", Content: "Make a plan"},
+		{Role: "assistant", Content: "1. Edit main.go"},
+		{Role: "system", Content: "Plan approved: 1. Edit main.go"},
+		{Role: "assistant", ToolCalls: []entities.ToolCall{call}},
+		{Role: "tool", ToolCallID: "call_1", Content: "ok"},
+		{Role: "system", Content: "Verification passed."},
+	}
+
+	apiMessages := convertToAnthropicMessages(messages)
+	if len(apiMessages) != 5 {
+		t.Fatalf("Expected 5 messages, got %d: %v", len(apiMessages), apiMessages)
+	}
+
+	// The leading system prompt is sent separately
+	if apiMessages[0]["content"] != "Make a plan" {
+		t.Errorf("Expected the user's message first, got %v", apiMessages[0])
+	}
+	note, _ := apiMessages[2]["content"].([]map[string]any)
+	if apiMessages[2]["role"] != "user" || len(note) != 1 || note[0]["text"] != "System note: Plan approved: 1. Edit main.go" {
+		t.Errorf("Expected the plan note as user text, got %v", apiMessages[2])
+	}
+
+	// A note after tool results joins them, keeping the tool_result first
+	results, _ := apiMessages[4]["content"].([]map[string]any)
+	if len(results) != 2 || results[0]["type"] != "tool_result" || results[1]["text"] != "System note: Verification passed." {
+		t.Errorf("Expected the note appended to the tool results, got %v", apiMessages[4])
+	}
+}
@@ -0,0 +1,88 @@
+package integrations
+
+import (
+	"github.com/drujensen/aiagent/internal/domain/entities"
+)
+
+// openAIContent is a user message's content for the chat completions API:
+// the text alone, or content parts when images are attached.
+func openAIContent(msg *entities.Message) any {
+	if len(msg.Attachments) == 0 {
+		return msg.Content
+	}
+	parts := []map[string]any{}
+	if msg.Content != "" {
+		parts = append(parts, map[string]any{"type": "text", "text": msg.Content})
+	}
+	for _, attachment := range msg.Attachments {
+		parts = append(parts, map[string]any{
+			"type":      "image_url",
+			"image_url": map[string]any{"url": attachment.DataURL()},
+		})
+	}
+	return parts
+}
+
+// responsesContent is a user message's content for the /v1/responses API.
+func responsesContent(msg *entities.Message) any {
+	if len(msg.Attachments) == 0 {
+		return msg.Content
+	}
+	parts := []map[string]any{}
+	if msg.Content != "" {
+		parts = append(parts, map[string]any{"type": "input_text", "text": msg.Content})
+	}
+	for _, attachment := range msg.Attachments {
+		parts = append(parts, map[string]any{"type": "input_image", "image_url": attachment.DataURL()})
+	}
+	return parts
+}
+
+// anthropicContent is a user message's content for the Messages API, with
+// images ahead of the text as Anthropic recommends.
+func anthropicContent(msg *entities.Message) any {
+	if len(msg.Attachments) == 0 {
+		return msg.Content
+	}
+	blocks := []map[string]any{}
+	for _, attachment := range msg.Attachments {
+		source := map[string]any{"type": "url", "url": attachment.URL}
+		if attachment.URL == "" {
+			source = map[string]any{"type": "base64", "media_type": attachment.MimeType, "data": attachment.Data}
+		}
+		blocks = append(blocks, map[string]any{"type": "image", "source": source})
+	}
+	if msg.Content != "" {
+		blocks = append(blocks, map[string]any{"type": "text", "text": msg.Content})
+	}
+	return blocks
+}
+
+// geminiParts are a message's parts for the Gemini API. Gemini only reads
+// inline images; ones referenced by URL are passed on as their URL.
+func geminiParts(msg *entities.Message) []map[string]any {
+	parts := []map[string]any{{"text": msg.Content}}
+	for _, attachment := range msg.Attachments {
+		if attachment.URL != "" {
+			parts = append(parts, map[string]any{"text": "Image: " + attachment.URL})
+			continue
+		}
+		parts = append(parts, map[string]any{
+			"inlineData": map[string]any{"mimeType": attachment.MimeType, "data": attachment.Data},
+		})
+	}
+	return parts
+}
+
+// ollamaImages are a message's images for Ollama's /api/chat, which takes
+// plain base64 data. Ollama cannot fetch images, so ones referenced by URL
+// are named in the text instead.
+func ollamaImages(msg *entities.Message) []string {
+	var images []string
+	for _, attachment := range msg.Attachments {
+		if attachment.URL == "" {
+			images = append(images, attachment.Data)
+		}
+	}
+	return images
+}
@@ -0,0 +1,41 @@
+package integrations
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+)
+
+func TestAttachmentContent(t *testing.T) {
+	msg := entities.NewMessage("user", "what is this?")
+	if got := openAIContent(msg); got != "what is this?" {
+		t.Errorf("Expected plain text without attachments, got %v", got)
+	}
+
+	msg.Attachments = []entities.Attachment{{Type: entities.AttachmentImage, MimeType: "image/png", Name: "a.png", Data: "iVBORw0KGgo="}}
+	tests := []struct {
+		name    string
+		content any
+		want    []string
+	}{
+		{"openai", openAIContent(msg), []string{`"type":"text"`, `"image_url":{"url":"data:image/png;base64,iVBORw0KGgo="}`}},
+		{"responses", responsesContent(msg), []string{`"type":"input_text"`, `"type":"input_image"`}},
+		{"anthropic", anthropicContent(msg), []string{`"source":{"data":"iVBORw0KGgo=","media_type":"image/png","type":"base64"}`, `"type":"text"`}},
+		{"gemini", geminiParts(msg), []string{`"inlineData":{"data":"iVBORw0KGgo=","mimeType":"image/png"}`, `"text":"what is this?"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.content)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(data), want) {
+					t.Errorf("Expected %s in %s", want, data)
+				}
+			}
+		})
+	}
+}
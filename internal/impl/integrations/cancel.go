@@ -0,0 +1,37 @@
+package integrations
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/domain/interfaces"
+)
+
+// canceledResponse is returned when the user cancels in the middle of a turn.
+// The latest text the model produced in an earlier round of the turn was
+// already saved with that round, so it is marked truncated and saved again so
+// the answer so far is kept and shown as cut off. The messages generated so
+// far are returned along with the error.
+func canceledResponse(newMessages []*entities.Message, callback interfaces.MessageCallback) ([]*entities.Message, error) {
+	err := fmt.Errorf("operation canceled by user")
+
+	var partial *entities.Message
+	for i := len(newMessages) - 1; i >= 0; i-- {
+		if msg := newMessages[i]; msg.Role == "assistant" && strings.TrimSpace(msg.Content) != "" {
+			partial = msg
+			break
+		}
+	}
+	if partial == nil {
+		return newMessages, err
+	}
+
+	partial.Truncated = true
+	if callback != nil {
+		if cbErr := callback([]*entities.Message{partial}); cbErr != nil {
+			return newMessages, fmt.Errorf("%v (failed to save partial response: %v)", err, cbErr)
+		}
+	}
+	return newMessages, err
+}
@@ -0,0 +1,105 @@
+package integrations
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/domain/interfaces"
+
+	"go.uber.org/zap"
+)
+
+// cancelingTool simulates the user cancelling while a tool runs.
+type cancelingTool struct {
+	entities.Tool
+	cancel context.CancelFunc
+}
+
+func (t *cancelingTool) Name() string                     { return "Lookup" }
+func (t *cancelingTool) Schema() map[string]any           { return map[string]any{"type": "object"} }
+func (t *cancelingTool) Description() string              { return "looks things up" }
+func (t *cancelingTool) Configuration() map[string]string { return nil }
+func (t *cancelingTool) Execute(ctx context.Context, arguments string) (string, error) {
+	t.cancel()
+	return "found it", nil
+}
+
+type singleToolRepo struct {
+	interfaces.ToolRepository
+	tool entities.Tool
+}
+
+func (r *singleToolRepo) GetToolByName(name string) (entities.Tool, error) { return r.tool, nil }
+
+func TestGenerateResponse_CancelAfterToolRoundTrip(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"choices": [{
+				"finish_reason": "tool_calls",
+				"message": {
+					"content": "The answer is in the config, let me check it.",
+					"tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "Lookup", "arguments": "{}"}}]
+				}
+			}],
+			"usage": {"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15}
+		}`))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tool := &cancelingTool{cancel: cancel}
+
+	integration, err := NewAIModelIntegration(server.URL, "test-key", "test-model", &singleToolRepo{tool: tool}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewAIModelIntegration failed: %v", err)
+	}
+
+	var saved []*entities.Message
+	callback := func(messages []*entities.Message) error {
+		saved = append(saved, messages...)
+		return nil
+	}
+
+	messages := []*entities.Message{entities.NewMessage("user", "Where is the answer?")}
+	newMessages, err := integration.GenerateResponse(ctx, messages, []entities.Tool{tool}, map[string]any{}, callback)
+	if err == nil || !strings.Contains(err.Error(), "canceled") {
+		t.Fatalf("expected a cancellation error, got %v", err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("expected no request after cancelling, got %d requests", n)
+	}
+
+	if len(newMessages) != 2 {
+		t.Fatalf("expected the tool call and tool result, got %d messages", len(newMessages))
+	}
+	if newMessages[1].Role != "tool" || newMessages[1].ToolCallID != "call_1" {
+		t.Errorf("expected the tool result second, got %+v", newMessages[1])
+	}
+
+	partial := newMessages[0]
+	if partial.Role != "assistant" || len(partial.ToolCalls) != 1 || !partial.Truncated || partial.Content != "The answer is in the config, let me check it." {
+		t.Errorf("expected the tool call message marked truncated, got %+v", partial)
+	}
+	if len(saved) == 0 || saved[len(saved)-1] != partial {
+		t.Error("expected the partial answer to be saved again through the callback")
+	}
+}
+
+func TestCanceledResponse_NoText(t *testing.T) {
+	newMessages, err := canceledResponse(nil, nil)
+	if err == nil {
+		t.Fatal("expected a cancellation error")
+	}
+	if len(newMessages) != 0 {
+		t.Errorf("expected no partial answer without text, got %d messages", len(newMessages))
+	}
+}
@@ -21,6 +21,9 @@ func NewDeepseekIntegration(baseURL, apiKey, model string, toolRepo interfaces.T
 		return nil, err
 	}
 
+	// DeepSeek supports JSON mode but not JSON schemas
+	openAIIntegration.noJSONSchema = true
+
 	return &DeepseekIntegration{
 		AIModelIntegration: openAIIntegration,
 	}, nil
@@ -0,0 +1,49 @@
+package integrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/domain/interfaces"
+)
+
+// reviewEdit shows the user the change a call would make when the chat
+// reviews edits, and waits for them to apply or reject it. It returns the
+// result to give the model and whether the call was rejected. Calls that
+// don't mutate, tools that can't preview and previews that fail run as
+// usual.
+func reviewEdit(ctx context.Context, options map[string]any, tool entities.Tool, toolName, callID, arguments string) (string, bool) {
+	reviewer, _ := options["edit_reviewer"].(interfaces.EditReviewer)
+	if reviewer == nil {
+		return "", false
+	}
+	previewer, ok := tool.(entities.PreviewTool)
+	if !ok || !callMutates(tool, arguments) {
+		return "", false
+	}
+	// A call whose preview fails fails the same way when it runs
+	preview, err := previewer.Preview(ctx, arguments)
+	if err != nil {
+		return "", false
+	}
+
+	chatID, _ := options["session_id"].(string)
+	result, diff := splitDiffResult(preview)
+	decision := reviewer.Review(ctx, &entities.EditReview{
+		ID:        callID,
+		ChatID:    chatID,
+		ToolName:  toolName,
+		Arguments: arguments,
+		Result:    result,
+		Diff:      diff,
+	})
+	if decision.Apply {
+		return "", false
+	}
+	rejected := fmt.Sprintf("The user rejected this change, so %s was not run and nothing was changed.", toolName)
+	if decision.Note != "" {
+		rejected += " Their feedback: " + decision.Note
+	}
+	return rejected, true
+}
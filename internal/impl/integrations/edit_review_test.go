@@ -0,0 +1,65 @@
+package integrations
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+)
+
+// previewTool is a writeTool that previews its calls.
+type previewTool struct {
+	writeTool
+}
+
+func (t *previewTool) Preview(ctx context.Context, arguments string) (string, error) {
+	return `{"success": true, "summary": "Would edit a.go", "diff": "-a\n+b", "dryRun": true}`, nil
+}
+
+type decidingReviewer struct {
+	decision entities.EditDecision
+	reviews  []*entities.EditReview
+}
+
+func (r *decidingReviewer) Review(ctx context.Context, review *entities.EditReview) entities.EditDecision {
+	r.reviews = append(r.reviews, review)
+	return r.decision
+}
+
+func TestReviewEdit(t *testing.T) {
+	reviewer := &decidingReviewer{decision: entities.EditDecision{Note: "use a constant"}}
+	options := map[string]any{"edit_reviewer": reviewer, "session_id": "chat"}
+
+	result, rejected := reviewEdit(context.Background(), options, &previewTool{}, "Edit", "call-1", `{"operation":"replace"}`)
+	if !rejected {
+		t.Fatal("Expected the edit to be rejected")
+	}
+	if !strings.Contains(result, "rejected") || !strings.Contains(result, "use a constant") {
+		t.Errorf("Expected the rejection and note, got %q", result)
+	}
+	if len(reviewer.reviews) != 1 {
+		t.Fatalf("Expected 1 review, got %d", len(reviewer.reviews))
+	}
+	if review := reviewer.reviews[0]; review.ID != "call-1" || review.ChatID != "chat" || review.Diff != "-a\n+b" {
+		t.Errorf("Expected the review to carry the call and diff, got %+v", review)
+	}
+
+	reviewer.decision = entities.EditDecision{Apply: true}
+	if _, rejected := reviewEdit(context.Background(), options, &previewTool{}, "Edit", "call-2", `{}`); rejected {
+		t.Error("Expected an applied edit to run")
+	}
+	if _, rejected := reviewEdit(context.Background(), options, &writeTool{}, "Write", "call-3", `{}`); rejected {
+		t.Error("Expected a tool without a preview to run")
+	}
+	reviewer.decision = entities.EditDecision{}
+	if _, rejected := reviewEdit(context.Background(), options, &previewTool{}, "Edit", "call-4", `{"operation":"count"}`); rejected {
+		t.Error("Expected a read-only call to run")
+	}
+	if _, rejected := reviewEdit(context.Background(), map[string]any{}, &previewTool{}, "Edit", "call-5", `{}`); rejected {
+		t.Error("Expected edits to run without a reviewer")
+	}
+	if len(reviewer.reviews) != 2 {
+		t.Errorf("Expected 2 reviews, got %d", len(reviewer.reviews))
+	}
+}
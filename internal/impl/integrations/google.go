@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/drujensen/aiagent/internal/domain/entities"
+	errors "github.com/drujensen/aiagent/internal/domain/errs"
 	"github.com/drujensen/aiagent/internal/domain/interfaces"
 
 	"github.com/google/uuid"
@@ -31,18 +32,16 @@ func NewGoogleIntegration(baseURL, apiKey, model string, toolRepo interfaces.Too
 		nativeURL = "https://generativelanguage.googleapis.com/v1beta"
 	}
 
-	// Create a custom HTTP client for Gemini
-	httpClient := &http.Client{Timeout: 30 * time.Minute}
-
 	return &GoogleIntegration{
 		AIModelIntegration: &AIModelIntegration{
-			baseURL:    nativeURL,
-			apiKey:     apiKey,
-			httpClient: httpClient,
-			model:      model,
-			toolRepo:   toolRepo,
-			logger:     logger,
-			lastUsage:  &entities.Usage{},
+			baseURL:        nativeURL,
+			apiKey:         apiKey,
+			httpClient:     newHTTPClient(),
+			model:          model,
+			toolRepo:       toolRepo,
+			logger:         logger,
+			lastUsage:      &entities.Usage{},
+			requestTimeout: entities.DefaultRequestTimeout,
 		},
 	}, nil
 }
@@ -100,7 +99,7 @@ func (g *GoogleIntegration) convertMessagesToGeminiContents(messages []*entities
 			// Regular message
 			contents = append(contents, map[string]any{
 				"role":  msg.Role,
-				"parts": []map[string]any{{"text": msg.Content}},
+				"parts": geminiParts(msg),
 			})
 		}
 	}
@@ -188,12 +187,23 @@ func (g *GoogleIntegration) convertToolsToGeminiFormat(toolList []entities.Tool)
 // GenerateResponse implements native Gemini API with tool call handling
 func (g *GoogleIntegration) GenerateResponse(ctx context.Context, messages []*entities.Message, toolList []entities.Tool, options map[string]any, callback interfaces.MessageCallback) ([]*entities.Message, error) {
 	var newMessages []*entities.Message
+	messages = normalizeToolCallIDs(messages, g.toolCallIDFormat)
 
 	// Tool call handling loop (similar to OpenAI implementation)
+	iteration := 0
+	history := newToolCallHistory()
+	failures := newToolFailureCount(options)
 	for {
+		iteration++
 		// Check for cancellation
 		if ctx.Err() != nil {
-			return nil, fmt.Errorf("operation canceled by user")
+			return canceledResponse(newMessages, callback)
+		}
+
+		// Add the notes the user sent to steer the response since the last request
+		if notes := steeringNotes(options, callback, g.logger); len(notes) > 0 {
+			newMessages = append(newMessages, notes...)
+			messages = append(messages, notes...)
 		}
 
 		// Convert current messages to Gemini contents
@@ -207,18 +217,18 @@ func (g *GoogleIntegration) GenerateResponse(ctx context.Context, messages []*en
 		if len(tools) > 0 {
 			reqBody["tools"] = tools
 		}
-		if temp, ok := options["temperature"]; ok {
-			reqBody["generationConfig"] = map[string]any{
-				"temperature": temp,
+		googleParams.apply(reqBody, options, g.logger)
+		// Gemini rejects a JSON response type together with function calling,
+		// agents with tools rely on the prompt and local validation instead
+		if format := responseFormatOption(options); format != nil && len(tools) == 0 {
+			genConfig, ok := reqBody["generationConfig"].(map[string]any)
+			if !ok {
+				genConfig = map[string]any{}
+				reqBody["generationConfig"] = genConfig
 			}
-		}
-		if maxTokens, ok := options["max_tokens"]; ok {
-			if genConfig, ok := reqBody["generationConfig"].(map[string]any); ok {
-				genConfig["maxOutputTokens"] = maxTokens
-			} else {
-				reqBody["generationConfig"] = map[string]any{
-					"maxOutputTokens": maxTokens,
-				}
+			genConfig["responseMimeType"] = "application/json"
+			if format.Type == entities.ResponseFormatJSONSchema {
+				genConfig["responseJsonSchema"] = format.Schema
 			}
 		}
 
@@ -226,38 +236,17 @@ func (g *GoogleIntegration) GenerateResponse(ctx context.Context, messages []*en
 		if err != nil {
 			return nil, fmt.Errorf("error marshaling request: %v", err)
 		}
-		g.logger.Info("Sending Gemini request", zap.String("body", string(jsonBody)))
-
-		// Build URL
-		url := fmt.Sprintf("%s/models/%s:generateContent?key=%s", g.baseURL, g.model, g.apiKey)
-
-		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
-		if err != nil {
-			return nil, fmt.Errorf("error creating request: %v", err)
-		}
-
-		req.Header.Set("Content-Type", "application/json")
-
-		resp, err := g.httpClient.Do(req)
-		if err != nil {
-			return nil, fmt.Errorf("error making request: %v", err)
-		}
-
-		respBody, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
+		g.logger.Info("Sending Gemini request", zap.String("body", g.redact(string(jsonBody))))
 
+		respBody, err := g.sendGenerateRequest(ctx, jsonBody)
 		if err != nil {
-			return nil, fmt.Errorf("error reading response: %v", err)
-		}
-
-		if resp.StatusCode != http.StatusOK {
-			g.logger.Error("Gemini API error",
-				zap.Int("status_code", resp.StatusCode),
-				zap.String("body", string(respBody)))
-			return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(respBody))
+			if ctx.Err() == context.Canceled {
+				return canceledResponse(newMessages, callback)
+			}
+			return nil, err
 		}
 
-		g.logger.Info("Gemini response", zap.String("body", string(respBody)))
+		g.logger.Info("Gemini response", zap.String("body", g.redact(string(respBody))))
 
 		// Parse response
 		var responseBody struct {
@@ -272,6 +261,7 @@ func (g *GoogleIntegration) GenerateResponse(ctx context.Context, messages []*en
 				PromptTokenCount     int `json:"promptTokenCount"`
 				CandidatesTokenCount int `json:"candidatesTokenCount"`
 				TotalTokenCount      int `json:"totalTokenCount"`
+				ThoughtsTokenCount   int `json:"thoughtsTokenCount"`
 			} `json:"usageMetadata"`
 		}
 
@@ -287,15 +277,26 @@ func (g *GoogleIntegration) GenerateResponse(ctx context.Context, messages []*en
 
 		// Parse the response content
 		content := ""
+		var thoughts []string
 		var toolCalls []entities.ToolCall
 
 		for _, part := range candidate.Content.Parts {
 			if text, ok := part["text"].(string); ok && text != "" {
-				content += text
+				// Thought summaries are flagged with "thought": true
+				if thought, _ := part["thought"].(bool); thought {
+					thoughts = append(thoughts, text)
+				} else {
+					content += text
+				}
 			}
 			if functionCall, ok := part["functionCall"].(map[string]any); ok {
 				var tc entities.ToolCall
-				tc.ID = uuid.New().String() // Gemini doesn't provide IDs, so we generate them
+				// Older Gemini models don't provide IDs, so we generate them
+				if id, ok := functionCall["id"].(string); ok && id != "" {
+					tc.ID = id
+				} else {
+					tc.ID = newToolCallID(g.toolCallIDFormat)
+				}
 				tc.Type = "function"
 				if name, ok := functionCall["name"].(string); ok {
 					tc.Function.Name = name
@@ -317,20 +318,23 @@ func (g *GoogleIntegration) GenerateResponse(ctx context.Context, messages []*en
 		g.lastUsage.PromptTokens = responseBody.UsageMetadata.PromptTokenCount
 		g.lastUsage.CompletionTokens = responseBody.UsageMetadata.CandidatesTokenCount
 		g.lastUsage.TotalTokens = responseBody.UsageMetadata.TotalTokenCount
+		g.lastUsage.ReasoningTokens = responseBody.UsageMetadata.ThoughtsTokenCount
 
 		// Log tool calls
 		if len(toolCalls) > 0 {
-			g.logger.Info("Tool calls generated", zap.Any("toolCalls", toolCalls))
+			g.logger.Info("Tool calls generated", zap.Strings("tools", toolCallNames(toolCalls)))
 		} else {
 			g.logger.Info("No tool calls generated")
 		}
 
 		// Create assistant message
+		content, reasoning := splitReasoning(content, thoughts...)
 		assistantMessage := &entities.Message{
 			ID:        uuid.New().String(),
 			Role:      "assistant",
 			Content:   content,
 			ToolCalls: toolCalls,
+			Reasoning: reasoning,
 			Timestamp: time.Now(),
 		}
 		newMessages = append(newMessages, assistantMessage)
@@ -341,13 +345,17 @@ func (g *GoogleIntegration) GenerateResponse(ctx context.Context, messages []*en
 			}
 		}
 
-		// If no tool calls, we're done
+		// If no tool calls, we're done unless the user steered the response
 		if len(toolCalls) == 0 {
+			if steeringPending(options) {
+				messages = append(messages, assistantMessage)
+				continue
+			}
 			break
 		}
 
 		// Execute all tool calls in parallel, then process results in order.
-		toolResults := executeToolsParallel(ctx, toolCalls, g.toolRepo, options, g.logger)
+		toolResults := executeToolsParallel(ctx, toolCalls, runTools(g.toolRepo, toolList), options, iteration, history, g, g.logger)
 		for _, r := range toolResults {
 			newMessages = append(newMessages, r.ToolMessage)
 
@@ -360,11 +368,67 @@ func (g *GoogleIntegration) GenerateResponse(ctx context.Context, messages []*en
 			// Append to messages for next iteration (Google uses the full messages slice)
 			messages = append(messages, assistantMessage, r.ToolMessage)
 		}
+
+		if stop := failures.observe(content, toolResults, callback, g.logger); stop != nil {
+			newMessages = append(newMessages, stop)
+			break
+		}
 	}
 
 	return newMessages, nil
 }
 
+// sendGenerateRequest posts the request body to generateContent and returns
+// the response body. The request deadline ends with it.
+func (g *GoogleIntegration) sendGenerateRequest(ctx context.Context, jsonBody []byte) ([]byte, error) {
+	// Build URL
+	url := fmt.Sprintf("%s/models/%s:generateContent?key=%s", g.baseURL, g.model, g.apiKey)
+
+	reqCtx, cancel := requestContext(ctx, g.requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, "POST", url, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if err := g.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		// The key is in the URL, keep it out of the error
+		return nil, fmt.Errorf("error making request: %s", g.redact(err.Error()))
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		respBody = []byte(g.redact(string(respBody)))
+		g.logger.Error("Gemini API error",
+			zap.Int("status_code", resp.StatusCode),
+			zap.String("body", string(respBody)))
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return nil, errors.RateLimitErrorf("rate limit exceeded: %s", string(respBody))
+		}
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(respBody))
+	}
+	return respBody, nil
+}
+
+// CountTokens estimates from message length, as Gemini's tokenizer isn't
+// available locally
+func (g *GoogleIntegration) CountTokens(messages []*entities.Message) (int, error) {
+	return countCharTokens(messages, geminiCharsPerToken), nil
+}
+
 // ProviderType returns the type of provider
 func (m *GoogleIntegration) ProviderType() entities.ProviderType {
 	return entities.ProviderGoogle
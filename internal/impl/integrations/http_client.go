@@ -0,0 +1,47 @@
+package integrations
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+// sharedTransport bounds connecting, the TLS handshake and idle connections
+// but not the request itself, so a long response is not cut off by a client
+// wide timeout. Requests are bounded by a context deadline instead.
+var sharedTransport = &http.Transport{
+	Proxy: http.ProxyFromEnvironment,
+	DialContext: (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext,
+	ForceAttemptHTTP2:     true,
+	MaxIdleConns:          100,
+	IdleConnTimeout:       90 * time.Second,
+	TLSHandshakeTimeout:   30 * time.Second,
+	ExpectContinueTimeout: 1 * time.Second,
+}
+
+func newHTTPClient() *http.Client {
+	return &http.Client{Transport: sharedTransport}
+}
+
+// requestContext returns the context for a single model request. A timeout of
+// zero disables the deadline, which suits local models that take a long time
+// to respond.
+func requestContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// requestTimed is implemented by integrations whose requests are bounded by a
+// per-request timeout.
+type requestTimed interface {
+	setRequestTimeout(timeout time.Duration)
+}
+
+var _ requestTimed = (*AIModelIntegration)(nil)
+var _ requestTimed = (*AnthropicIntegration)(nil)
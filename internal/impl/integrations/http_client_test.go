@@ -0,0 +1,24 @@
+package integrations
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRequestContext(t *testing.T) {
+	ctx, cancel := requestContext(context.Background(), time.Minute)
+	defer cancel()
+	if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > time.Minute {
+		t.Errorf("Expected a deadline within a minute, got %v (set: %v)", deadline, ok)
+	}
+
+	ctx, cancel = requestContext(context.Background(), 0)
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("Expected no deadline when the timeout is disabled")
+	}
+	cancel()
+	if ctx.Err() == nil {
+		t.Error("Expected the request context to be canceled")
+	}
+}
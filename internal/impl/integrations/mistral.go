@@ -1,8 +1,6 @@
 package integrations
 
 import (
-	"crypto/rand"
-
 	"github.com/drujensen/aiagent/internal/domain/entities"
 	"github.com/drujensen/aiagent/internal/domain/interfaces"
 
@@ -19,6 +17,10 @@ func NewMistralIntegration(baseURL, apiKey, model string, toolRepo interfaces.To
 		return nil, err
 	}
 
+	// Mistral only accepts nine character alphanumeric tool call IDs
+	mistralIntegration.toolCallIDFormat = entities.ToolCallIDAlphanumeric9
+	mistralIntegration.params = mistralParams
+
 	return &MistralIntegration{
 		AIModelIntegration: mistralIntegration,
 	}, nil
@@ -28,15 +30,4 @@ func (m *MistralIntegration) ProviderType() entities.ProviderType {
 	return entities.ProviderMistral
 }
 
-// customizeToolCallID generates a valid 9-character alphanumeric tool call ID for Mistral
-func (m *MistralIntegration) customizeToolCallID(originalID string) string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	b := make([]byte, 9)
-	rand.Read(b)
-	for i := range b {
-		b[i] = charset[b[i]%byte(len(charset))]
-	}
-	return string(b)
-}
-
 var _ interfaces.AIModelIntegration = (*MistralIntegration)(nil)
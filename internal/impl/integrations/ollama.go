@@ -0,0 +1,363 @@
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	errors "github.com/drujensen/aiagent/internal/domain/errs"
+	"github.com/drujensen/aiagent/internal/domain/interfaces"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// defaultOllamaURL is where a local Ollama server listens.
+const defaultOllamaURL = "http://localhost:11434"
+
+// OllamaIntegration implements Ollama's native /api/chat API. Ollama runs
+// locally without an API key, reports usage as prompt_eval_count and
+// eval_count and cuts the prompt to a small context window unless num_ctx
+// asks for more.
+type OllamaIntegration struct {
+	*AIModelIntegration
+	contextWindow int
+	// noTools is set once the model turns out not to support tool calling
+	noTools bool
+}
+
+// NewOllamaIntegration creates a new Ollama integration. The API key is
+// optional, it is only sent for servers behind an authenticating proxy.
+func NewOllamaIntegration(baseURL, apiKey string, model *entities.Model, toolRepo interfaces.ToolRepository, logger *zap.Logger) (*OllamaIntegration, error) {
+	if model == nil || model.ModelName == "" {
+		return nil, fmt.Errorf("model cannot be empty")
+	}
+	endpoint := nativeEndpoint(baseURL)
+	if endpoint == "" {
+		endpoint = defaultOllamaURL
+	}
+
+	integration := &OllamaIntegration{
+		AIModelIntegration: &AIModelIntegration{
+			baseURL:        endpoint + "/api/chat",
+			apiKey:         apiKey,
+			httpClient:     newHTTPClient(),
+			model:          model.ModelName,
+			toolRepo:       toolRepo,
+			logger:         logger,
+			lastUsage:      &entities.Usage{},
+			requestTimeout: entities.DefaultRequestTimeout,
+		},
+	}
+	if model.ContextWindow != nil {
+		integration.contextWindow = *model.ContextWindow
+	}
+	return integration, nil
+}
+
+// convertToOllamaMessages converts message entities to the /api/chat format.
+// Tool call arguments are objects rather than strings, tool results name
+// their tool and images are sent as plain base64.
+func convertToOllamaMessages(messages []*entities.Message) []map[string]any {
+	toolNames := make(map[string]string)
+	apiMessages := make([]map[string]any, 0, len(messages))
+	for _, msg := range messages {
+		apiMsg := map[string]any{
+			"role":    msg.Role,
+			"content": msg.Content,
+		}
+
+		switch {
+		case msg.Role == "assistant" && len(msg.ToolCalls) > 0:
+			toolCalls := make([]map[string]any, 0, len(msg.ToolCalls))
+			for _, tc := range msg.ToolCalls {
+				toolNames[tc.ID] = tc.Function.Name
+				arguments := json.RawMessage(tc.Function.Arguments)
+				if !json.Valid(arguments) {
+					arguments = json.RawMessage("{}")
+				}
+				toolCalls = append(toolCalls, map[string]any{
+					"function": map[string]any{
+						"name":      tc.Function.Name,
+						"arguments": arguments,
+					},
+				})
+			}
+			apiMsg["tool_calls"] = toolCalls
+		case msg.Role == "tool":
+			if name := toolNames[msg.ToolCallID]; name != "" {
+				apiMsg["tool_name"] = name
+			}
+		default:
+			if images := ollamaImages(msg); len(images) > 0 {
+				apiMsg["images"] = images
+			}
+			for _, attachment := range msg.Attachments {
+				if attachment.URL != "" {
+					apiMsg["content"] = apiMsg["content"].(string) + "\nImage: " + attachment.URL
+				}
+			}
+		}
+
+		apiMessages = append(apiMessages, apiMsg)
+	}
+	return apiMessages
+}
+
+// GenerateResponse implements Ollama's /api/chat with tool call handling
+func (o *OllamaIntegration) GenerateResponse(ctx context.Context, messages []*entities.Message, toolList []entities.Tool, options map[string]any, callback interfaces.MessageCallback) ([]*entities.Message, error) {
+	messages = normalizeToolCallIDs(messages, o.toolCallIDFormat)
+
+	tools := make([]map[string]any, 0, len(toolList))
+	for _, tool := range toolList {
+		tools = append(tools, map[string]any{
+			"type": "function",
+			"function": map[string]any{
+				"name":        tool.Name(),
+				"description": tool.Description(),
+				"parameters":  toolParameters(tool.Schema(), o.strictToolSchemas),
+			},
+		})
+	}
+
+	modelOptions := map[string]any{}
+	if o.contextWindow > 0 {
+		modelOptions["num_ctx"] = o.contextWindow
+	}
+
+	reqBody := map[string]any{
+		"model":    o.model,
+		"messages": convertToOllamaMessages(messages),
+		"stream":   false,
+		"options":  modelOptions,
+	}
+	ollamaParams.apply(reqBody, options, o.logger)
+	if len(tools) > 0 && !o.noTools {
+		reqBody["tools"] = tools
+	}
+	if format := responseFormatOption(options); format != nil {
+		if format.Type == entities.ResponseFormatJSONSchema && format.Schema != nil {
+			reqBody["format"] = format.Schema
+		} else {
+			reqBody["format"] = "json"
+		}
+	}
+
+	var newMessages []*entities.Message
+
+	// Tool call handling loop
+	iteration := 0
+	history := newToolCallHistory()
+	failures := newToolFailureCount(options)
+	for {
+		iteration++
+		if ctx.Err() != nil {
+			return canceledResponse(newMessages, callback)
+		}
+
+		// Add the notes the user sent to steer the response since the last request
+		if notes := steeringNotes(options, callback, o.logger); len(notes) > 0 {
+			newMessages = append(newMessages, notes...)
+			messages = append(messages, notes...)
+			reqBody["messages"] = convertToOllamaMessages(messages)
+		}
+
+		respBody, err := o.send(ctx, reqBody)
+		if err == errToolsUnsupported {
+			o.logger.Warn("Model does not support tools, continuing without them", zap.String("model", o.model))
+			o.noTools = true
+			delete(reqBody, "tools")
+			respBody, err = o.send(ctx, reqBody)
+		}
+		if err != nil {
+			if ctx.Err() == context.Canceled {
+				return canceledResponse(newMessages, callback)
+			}
+			return nil, err
+		}
+
+		var responseBody struct {
+			Message struct {
+				Role      string `json:"role"`
+				Content   string `json:"content"`
+				Thinking  string `json:"thinking"`
+				ToolCalls []struct {
+					ID       string `json:"id"`
+					Function struct {
+						Name      string          `json:"name"`
+						Arguments json.RawMessage `json:"arguments"`
+					} `json:"function"`
+				} `json:"tool_calls"`
+			} `json:"message"`
+			PromptEvalCount int `json:"prompt_eval_count"`
+			EvalCount       int `json:"eval_count"`
+		}
+		if err := json.Unmarshal(respBody, &responseBody); err != nil {
+			return nil, fmt.Errorf("error decoding response: %v", err)
+		}
+
+		content, reasoning := splitReasoning(responseBody.Message.Content, responseBody.Message.Thinking)
+
+		// Ollama leaves tool call IDs out, so they are generated
+		var toolCalls []entities.ToolCall
+		for _, call := range responseBody.Message.ToolCalls {
+			var tc entities.ToolCall
+			tc.ID = call.ID
+			if tc.ID == "" {
+				tc.ID = newToolCallID(o.toolCallIDFormat)
+			}
+			tc.Type = "function"
+			tc.Function.Name = call.Function.Name
+			tc.Function.Arguments = ollamaArguments(call.Function.Arguments)
+			toolCalls = append(toolCalls, tc)
+		}
+
+		o.lastUsage.PromptTokens = responseBody.PromptEvalCount
+		o.lastUsage.CompletionTokens = responseBody.EvalCount
+		o.lastUsage.TotalTokens = responseBody.PromptEvalCount + responseBody.EvalCount
+
+		if len(toolCalls) > 0 {
+			o.logger.Info("Tool calls generated", zap.Strings("tools", toolCallNames(toolCalls)))
+		} else {
+			o.logger.Info("No tool calls generated")
+		}
+
+		assistantMessage := &entities.Message{
+			ID:        uuid.New().String(),
+			Role:      "assistant",
+			Content:   content,
+			ToolCalls: toolCalls,
+			Reasoning: reasoning,
+			Timestamp: time.Now(),
+		}
+		newMessages = append(newMessages, assistantMessage)
+		messages = append(messages, assistantMessage)
+
+		if callback != nil {
+			if err := callback([]*entities.Message{assistantMessage}); err != nil {
+				o.logger.Error("Failed to save assistant message incrementally", zap.Error(err))
+			}
+		}
+
+		// Ollama reports done_reason "stop" with tool calls too, so the calls decide
+		if len(toolCalls) == 0 {
+			if steeringPending(options) {
+				reqBody["messages"] = convertToOllamaMessages(messages)
+				continue
+			}
+			break
+		}
+
+		// Execute all tool calls in parallel, then process results in order.
+		toolResults := executeToolsParallel(ctx, toolCalls, runTools(o.toolRepo, toolList), options, iteration, history, o, o.logger)
+		for _, r := range toolResults {
+			newMessages = append(newMessages, r.ToolMessage)
+
+			if callback != nil {
+				if err := callback([]*entities.Message{r.ToolMessage}); err != nil {
+					o.logger.Error("Failed to save tool response message incrementally", zap.Error(err))
+				}
+			}
+
+			toolMessage := *r.ToolMessage
+			toolMessage.Content = r.ToolResult
+			messages = append(messages, &toolMessage)
+		}
+		reqBody["messages"] = convertToOllamaMessages(messages)
+
+		if stop := failures.observe(content, toolResults, callback, o.logger); stop != nil {
+			newMessages = append(newMessages, stop)
+			break
+		}
+	}
+
+	// Validate that all tool calls have responses before returning
+	newMessages = ensureToolCallResponsesOpenAI(newMessages, o.logger)
+
+	logGeneratedMessages(o.logger, newMessages)
+	return newMessages, nil
+}
+
+// errToolsUnsupported is returned by send when the model rejects tools.
+var errToolsUnsupported = fmt.Errorf("model does not support tools")
+
+// send posts the request and returns the response body.
+func (o *OllamaIntegration) send(ctx context.Context, reqBody map[string]any) ([]byte, error) {
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling request: %v", err)
+	}
+	o.logger.Info("Sending request to Ollama", zap.String("body", o.redact(string(jsonBody))))
+
+	if err := o.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	reqCtx, cancel := requestContext(ctx, o.requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, "POST", o.baseURL, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if o.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request to Ollama at %s: %v", o.baseURL, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		respBody = []byte(o.redact(string(respBody)))
+		o.logger.Error("Ollama API error",
+			zap.Int("status_code", resp.StatusCode),
+			zap.String("body", string(respBody)))
+		if resp.StatusCode == http.StatusBadRequest && strings.Contains(string(respBody), "does not support tools") {
+			return nil, errToolsUnsupported
+		}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return nil, errors.RateLimitErrorf("rate limit exceeded: %s", string(respBody))
+		}
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(respBody))
+	}
+	o.logger.Info("Ollama response", zap.String("body", o.redact(string(respBody))))
+	return respBody, nil
+}
+
+// ollamaArguments turns the arguments of a tool call, an object in Ollama's
+// responses, into the JSON string the tools take.
+func ollamaArguments(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "{}"
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text // Some models return the arguments as a string
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, raw); err != nil {
+		return string(raw)
+	}
+	return compact.String()
+}
+
+// ProviderType returns the type of provider
+func (o *OllamaIntegration) ProviderType() entities.ProviderType {
+	return entities.ProviderOllama
+}
+
+// Ensure OllamaIntegration implements AIModelIntegration
+var _ interfaces.AIModelIntegration = (*OllamaIntegration)(nil)
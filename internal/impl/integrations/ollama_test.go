@@ -0,0 +1,139 @@
+package integrations
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+
+	"go.uber.org/zap"
+)
+
+// lookupTool answers every call with the same result.
+type lookupTool struct {
+	entities.Tool
+	arguments string
+}
+
+func (t *lookupTool) Name() string                     { return "Lookup" }
+func (t *lookupTool) Schema() map[string]any           { return map[string]any{"type": "object"} }
+func (t *lookupTool) Description() string              { return "looks things up" }
+func (t *lookupTool) Configuration() map[string]string { return nil }
+func (t *lookupTool) Execute(ctx context.Context, arguments string) (string, error) {
+	t.arguments = arguments
+	return "42", nil
+}
+
+func TestOllamaIntegration_ToolRoundTrip(t *testing.T) {
+	var bodies []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("expected /api/chat, got %s", r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			t.Errorf("expected no Authorization header, got %q", auth)
+		}
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+
+		w.Header().Set("Content-Type", "application/json")
+		if len(bodies) == 1 {
+			w.Write([]byte(`{
+				"message": {"role": "assistant", "content": "", "tool_calls": [{"function": {"name": "Lookup", "arguments": {"key": "answer"}}}]},
+				"done": true, "done_reason": "stop", "prompt_eval_count": 20, "eval_count": 4
+			}`))
+			return
+		}
+		w.Write([]byte(`{
+			"message": {"role": "assistant", "content": "The answer is 42.", "thinking": "The tool said 42."},
+			"done": true, "done_reason": "stop", "prompt_eval_count": 30, "eval_count": 6
+		}`))
+	}))
+	defer server.Close()
+
+	contextWindow := 32768
+	model := &entities.Model{ModelName: "qwen3:8b", ContextWindow: &contextWindow}
+	tool := &lookupTool{}
+	integration, err := NewOllamaIntegration(server.URL+"/v1", "", model, &singleToolRepo{tool: tool}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewOllamaIntegration failed: %v", err)
+	}
+
+	messages := []*entities.Message{entities.NewMessage("user", "What is the answer?")}
+	newMessages, err := integration.GenerateResponse(context.Background(), messages, []entities.Tool{tool}, map[string]any{"max_tokens": 100}, nil)
+	if err != nil {
+		t.Fatalf("GenerateResponse failed: %v", err)
+	}
+
+	if len(newMessages) != 3 {
+		t.Fatalf("expected tool call, tool result and answer, got %d messages", len(newMessages))
+	}
+	if tool.arguments != `{"key":"answer"}` {
+		t.Errorf("expected the arguments object as JSON, got %s", tool.arguments)
+	}
+	if newMessages[1].ToolCallID != newMessages[0].ToolCalls[0].ID {
+		t.Error("expected the tool result to answer the generated tool call ID")
+	}
+	if got := newMessages[2]; got.Content != "The answer is 42." || got.Reasoning != "The tool said 42." {
+		t.Errorf("got content %q and reasoning %q", got.Content, got.Reasoning)
+	}
+
+	options, _ := bodies[0]["options"].(map[string]any)
+	if options["num_ctx"] != float64(32768) || options["num_predict"] != float64(100) {
+		t.Errorf("expected num_ctx and num_predict in the options, got %v", options)
+	}
+	if bodies[0]["stream"] != false {
+		t.Errorf("expected stream false, got %v", bodies[0]["stream"])
+	}
+	sent, _ := bodies[1]["messages"].([]any)
+	if len(sent) != 3 {
+		t.Fatalf("expected the tool call and result to be sent back, got %d messages", len(sent))
+	}
+	if result, _ := sent[2].(map[string]any); result["role"] != "tool" || result["tool_name"] != "Lookup" || result["content"] != "42" {
+		t.Errorf("unexpected tool result message: %v", sent[2])
+	}
+
+	usage, _ := integration.GetLastUsage()
+	if usage.PromptTokens != 30 || usage.CompletionTokens != 6 || usage.TotalTokens != 36 {
+		t.Errorf("unexpected usage: %+v", usage)
+	}
+}
+
+func TestOllamaIntegration_ModelWithoutTools(t *testing.T) {
+	var bodies []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		if _, ok := body["tools"]; ok {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "registry.ollama.ai/library/llama2:7b does not support tools"}`))
+			return
+		}
+		w.Write([]byte(`{"message": {"role": "assistant", "content": "Hello!"}, "done": true}`))
+	}))
+	defer server.Close()
+
+	integration, err := NewOllamaIntegration(server.URL, "", &entities.Model{ModelName: "llama2:7b"}, &singleToolRepo{}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewOllamaIntegration failed: %v", err)
+	}
+
+	messages := []*entities.Message{entities.NewMessage("user", "Hi")}
+	for i := 0; i < 2; i++ {
+		newMessages, err := integration.GenerateResponse(context.Background(), messages, []entities.Tool{&lookupTool{}}, map[string]any{}, nil)
+		if err != nil {
+			t.Fatalf("GenerateResponse failed: %v", err)
+		}
+		if len(newMessages) != 1 || newMessages[0].Content != "Hello!" {
+			t.Fatalf("unexpected messages: %+v", newMessages)
+		}
+	}
+	if len(bodies) != 3 {
+		t.Errorf("expected tools to be dropped after the first refusal, got %d requests", len(bodies))
+	}
+}
@@ -6,12 +6,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/drujensen/aiagent/internal/domain/entities"
-	"github.com/drujensen/aiagent/internal/domain/events"
+	errors "github.com/drujensen/aiagent/internal/domain/errs"
 	"github.com/drujensen/aiagent/internal/domain/interfaces"
 
 	"github.com/google/uuid"
@@ -49,6 +50,14 @@ func NewOpenAIIntegration(baseURL, apiKey string, model *entities.Model, toolRep
 		return nil, err
 	}
 
+	openAIIntegration.params = openAIChatParams
+	if model.Reasoning && !model.TemperatureCap {
+		openAIIntegration.params = openAIReasoningChatParams
+	}
+	if strings.HasSuffix(endpoint, "/v1/responses") {
+		openAIIntegration.params = openAIResponsesParams
+	}
+
 	return &OpenAIIntegration{
 		AIModelIntegration: openAIIntegration,
 	}, nil
@@ -56,6 +65,16 @@ func NewOpenAIIntegration(baseURL, apiKey string, model *entities.Model, toolRep
 
 // GenerateResponse generates a response using the appropriate OpenAI API based on the endpoint
 func (m *OpenAIIntegration) GenerateResponse(ctx context.Context, messages []*entities.Message, toolList []entities.Tool, options map[string]any, callback interfaces.MessageCallback) ([]*entities.Message, error) {
+	// OpenAI caches prompt prefixes automatically; a stable key per chat routes
+	// repeated requests to the same cache. The options are copied as they are
+	// shared with the other models of a fallback chain.
+	if promptCaching, _ := options["prompt_caching"].(bool); promptCaching {
+		if sessionID, ok := options["session_id"].(string); ok && sessionID != "" {
+			options = maps.Clone(options)
+			options["prompt_cache_key"] = sessionID
+		}
+	}
+
 	// Check if this is a responses API endpoint (for all o-series and codex models)
 	if strings.Contains(m.baseURL, "/v1/responses") {
 		return m.generateResponseV2(ctx, messages, toolList, options, callback)
@@ -67,6 +86,8 @@ func (m *OpenAIIntegration) GenerateResponse(ctx context.Context, messages []*en
 
 // generateResponseV2 handles the /v1/responses API for o1 and codex models with proper tool call support
 func (m *OpenAIIntegration) generateResponseV2(ctx context.Context, messages []*entities.Message, toolList []entities.Tool, options map[string]any, callback interfaces.MessageCallback) ([]*entities.Message, error) {
+	messages = normalizeToolCallIDs(messages, m.toolCallIDFormat)
+
 	// Prepare tool definitions for OpenAI responses API (flattened format)
 	tools := make([]map[string]any, len(toolList))
 	for i, tool := range toolList {
@@ -74,11 +95,13 @@ func (m *OpenAIIntegration) generateResponseV2(ctx context.Context, messages []*
 			return nil, fmt.Errorf("operation canceled by user")
 		}
 
+		// The responses API makes functions strict unless told otherwise
 		tools[i] = map[string]any{
 			"type":        "function",
 			"name":        tool.Name(),
 			"description": tool.Description(),
-			"parameters":  tool.Schema(),
+			"parameters":  toolParameters(tool.Schema(), m.strictToolSchemas),
+			"strict":      m.strictToolSchemas,
 		}
 	}
 
@@ -88,17 +111,35 @@ func (m *OpenAIIntegration) generateResponseV2(ctx context.Context, messages []*
 	var allMessages []*entities.Message
 	var previousResponseID string
 	var lastUsage struct {
-		InputTokens     int `json:"input_tokens"`
-		OutputTokens    int `json:"output_tokens"`
-		TotalTokens     int `json:"total_tokens"`
-		ReasoningTokens int `json:"reasoning_tokens,omitempty"`
+		InputTokens         int `json:"input_tokens"`
+		OutputTokens        int `json:"output_tokens"`
+		TotalTokens         int `json:"total_tokens"`
+		OutputTokensDetails struct {
+			ReasoningTokens int `json:"reasoning_tokens"`
+		} `json:"output_tokens_details"`
+		InputTokensDetails struct {
+			CachedTokens int `json:"cached_tokens"`
+		} `json:"input_tokens_details"`
 	}
 
 	// Tool call execution loop
+	iteration := 0
+	history := newToolCallHistory()
+	failures := newToolFailureCount(options)
 	for {
+		iteration++
 		// Check for cancellation
 		if ctx.Err() == context.Canceled {
-			return nil, fmt.Errorf("operation canceled by user")
+			return canceledResponse(allMessages, callback)
+		}
+
+		// Add the notes the user sent to steer the response since the last request
+		for _, note := range steeringNotes(options, callback, m.logger) {
+			allMessages = append(allMessages, note)
+			inputItems = append(inputItems, map[string]any{
+				"role":    note.Role,
+				"content": responsesContent(note),
+			})
 		}
 
 		// Format request body for /v1/responses API
@@ -109,20 +150,7 @@ func (m *OpenAIIntegration) generateResponseV2(ctx context.Context, messages []*
 		if instructions != "" {
 			reqBody["instructions"] = instructions
 		}
-		if maxTokens, ok := options["max_tokens"]; ok {
-			reqBody["max_output_tokens"] = maxTokens
-		}
-		// Note: temperature is not supported for o-series models using /v1/responses API
-		if len(tools) > 0 {
-			reqBody["tools"] = tools
-			reqBody["tool_choice"] = "auto"
-		}
-		if instructions != "" {
-			reqBody["instructions"] = instructions
-		}
-		if maxTokens, ok := options["max_tokens"]; ok {
-			reqBody["max_output_tokens"] = maxTokens
-		}
+		m.params.apply(reqBody, options, m.logger)
 		if len(tools) > 0 {
 			reqBody["tools"] = tools
 			reqBody["tool_choice"] = "auto"
@@ -130,60 +158,28 @@ func (m *OpenAIIntegration) generateResponseV2(ctx context.Context, messages []*
 		if previousResponseID != "" {
 			reqBody["previous_response_id"] = previousResponseID
 		}
+		if cacheKey, ok := options["prompt_cache_key"].(string); ok && cacheKey != "" {
+			reqBody["prompt_cache_key"] = cacheKey
+		}
+		if format := responseFormatOption(options); format != nil {
+			reqBody["text"] = map[string]any{"format": responsesTextFormat(format)}
+		}
 
 		// Make the API request
 		jsonBody, err := json.Marshal(reqBody)
 		if err != nil {
 			return nil, fmt.Errorf("error marshaling request: %v", err)
 		}
-		m.logger.Info("Sending request to OpenAI /v1/responses API", zap.String("body", string(jsonBody)))
+		m.logger.Info("Sending request to OpenAI /v1/responses API", zap.String("body", m.redact(string(jsonBody))))
 
-		req, err := http.NewRequestWithContext(ctx, "POST", m.baseURL, bytes.NewBuffer(jsonBody))
+		respBody, err := m.sendResponsesRequest(ctx, jsonBody)
 		if err != nil {
-			return nil, fmt.Errorf("error creating request: %v", err)
-		}
-
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Authorization", "Bearer "+m.apiKey)
-
-		var resp *http.Response
-		for attempt := 0; attempt < 3; attempt++ {
 			if ctx.Err() == context.Canceled {
-				return nil, fmt.Errorf("operation canceled by user")
-			}
-
-			resp, err = m.httpClient.Do(req)
-			if err != nil {
-				if attempt < 2 {
-					m.logger.Warn("Error making request, retrying", zap.Error(err))
-					time.Sleep(time.Duration(attempt+1) * time.Second)
-					continue
-				}
-				return nil, fmt.Errorf("error making request: %v", err)
-			}
-			if resp.StatusCode == http.StatusTooManyRequests {
-				if attempt < 2 {
-					time.Sleep(time.Duration(attempt+1) * time.Second)
-					continue
-				}
-				return nil, fmt.Errorf("rate limit exceeded")
+				return canceledResponse(allMessages, callback)
 			}
-			if resp.StatusCode != http.StatusOK {
-				body, _ := io.ReadAll(resp.Body)
-				m.logger.Error("OpenAI /v1/responses API error",
-					zap.Int("status_code", resp.StatusCode),
-					zap.String("body", string(body)))
-				return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
-			}
-			break
-		}
-		defer resp.Body.Close()
-
-		respBody, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return nil, fmt.Errorf("error reading response: %v", err)
+			return nil, err
 		}
-		m.logger.Info("OpenAI /v1/responses response", zap.String("body", string(respBody)))
+		m.logger.Info("OpenAI /v1/responses response", zap.String("body", m.redact(string(respBody))))
 
 		// Parse response from /v1/responses API
 		var responseBody struct {
@@ -211,10 +207,15 @@ func (m *OpenAIIntegration) generateResponseV2(ctx context.Context, messages []*
 				Role      string `json:"role,omitempty"`
 			} `json:"output"`
 			Usage struct {
-				InputTokens     int `json:"input_tokens"`
-				OutputTokens    int `json:"output_tokens"`
-				TotalTokens     int `json:"total_tokens"`
-				ReasoningTokens int `json:"reasoning_tokens,omitempty"`
+				InputTokens         int `json:"input_tokens"`
+				OutputTokens        int `json:"output_tokens"`
+				TotalTokens         int `json:"total_tokens"`
+				OutputTokensDetails struct {
+					ReasoningTokens int `json:"reasoning_tokens"`
+				} `json:"output_tokens_details"`
+				InputTokensDetails struct {
+					CachedTokens int `json:"cached_tokens"`
+				} `json:"input_tokens_details"`
 			} `json:"usage"`
 		}
 
@@ -236,7 +237,7 @@ func (m *OpenAIIntegration) generateResponseV2(ctx context.Context, messages []*
 		lastUsage = responseBody.Usage
 
 		// Extract content and tool calls from output items
-		var content strings.Builder
+		var content, reasoning strings.Builder
 		var toolCalls []entities.ToolCall
 
 		for i, item := range responseBody.Output {
@@ -247,13 +248,13 @@ func (m *OpenAIIntegration) generateResponseV2(ctx context.Context, messages []*
 
 			switch item.Type {
 			case "reasoning":
-				// Extract reasoning content
+				// Keep reasoning out of the answer
 				reasoningText := m.extractReasoningContent(item)
 				if reasoningText != "" {
-					if content.Len() > 0 {
-						content.WriteString("\n")
+					if reasoning.Len() > 0 {
+						reasoning.WriteString("\n")
 					}
-					content.WriteString(reasoningText)
+					reasoning.WriteString(reasoningText)
 				}
 			case "message":
 				// Extract text from message content
@@ -299,12 +300,14 @@ func (m *OpenAIIntegration) generateResponseV2(ctx context.Context, messages []*
 		}
 
 		// Create assistant message if we have content or tool calls
-		if content.Len() > 0 || len(toolCalls) > 0 {
+		if content.Len() > 0 || reasoning.Len() > 0 || len(toolCalls) > 0 {
+			text, reasoningText := splitReasoning(content.String(), reasoning.String())
 			assistantMessage := &entities.Message{
 				ID:        uuid.New().String(),
 				Role:      "assistant",
-				Content:   content.String(),
+				Content:   text,
 				ToolCalls: toolCalls,
+				Reasoning: reasoningText,
 				Timestamp: time.Now(),
 			}
 			allMessages = append(allMessages, assistantMessage)
@@ -329,86 +332,19 @@ func (m *OpenAIIntegration) generateResponseV2(ctx context.Context, messages []*
 
 		// If there are tool calls, execute them and continue the loop
 		if len(toolCalls) > 0 {
-			for _, toolCall := range toolCalls {
-				// Check for cancellation before executing tool
-				if ctx.Err() == context.Canceled {
-					return nil, fmt.Errorf("operation canceled by user")
-				}
-
-				toolName := toolCall.Function.Name
-				tool, err := m.toolRepo.GetToolByName(toolName)
-
-				var toolResult string
-				var toolError string
-				var diff string
-				if err != nil {
-					toolResult = fmt.Sprintf("Tool %s could not be retrieved: %v", toolName, err)
-					toolError = err.Error()
-					m.logger.Warn("Failed to get tool", zap.String("toolName", toolName), zap.Error(err))
-				} else if tool != nil {
-					// Inject session_id into TodoWrite tool arguments
-					args := toolCall.Function.Arguments
-					if toolName == "TodoWrite" {
-						if sessionID, ok := options["session_id"].(string); ok && sessionID != "" {
-							var argsMap map[string]any
-							if err := json.Unmarshal([]byte(args), &argsMap); err == nil {
-								if _, exists := argsMap["session_id"]; !exists {
-									argsMap["session_id"] = sessionID
-									if newArgs, err := json.Marshal(argsMap); err == nil {
-										args = string(newArgs)
-									}
-								}
-							}
-						}
-					}
-
-					result, err := tool.Execute(ctx, args)
-					if err != nil {
-						toolResult = fmt.Sprintf("Tool %s execution failed: %v", toolName, err)
-						toolError = err.Error()
-						m.logger.Warn("Tool execution failed", zap.String("toolName", toolName), zap.Error(err))
-					} else {
-						toolResult = result
-						// Extract diff if it's a file write operation
-						if toolName == "Write" || toolName == "Edit" {
-							diff = m.extractDiffFromResult(result)
-						}
-					}
-				} else {
-					toolResult = fmt.Sprintf("Tool %s not found", toolName)
-					toolError = "Tool not found"
-					m.logger.Warn("Tool not found", zap.String("toolName", toolName))
-				}
+			// Check for cancellation before executing tools
+			if ctx.Err() == context.Canceled {
+				return canceledResponse(allMessages, callback)
+			}
 
-				// Use raw tool result for both AI and UI display
-				var displayContent string
-				if toolError != "" {
-					displayContent = fmt.Sprintf("Tool %s failed with error: %s", toolName, toolError)
-				} else {
-					displayContent = toolResult
-				}
-
-				// Create tool call event
-				chatID, _ := options["session_id"].(string)
-				toolEvent := entities.NewToolCallEvent(toolCall.ID, toolName, toolCall.Function.Arguments, displayContent, toolError, diff, chatID, nil)
-
-				// Publish real-time event for TUI updates
-				events.PublishToolCallEvent(toolEvent)
-
-				// Create tool response message
-				toolResponseMessage := &entities.Message{
-					ID:             uuid.New().String(),
-					Role:           "tool",
-					Content:        displayContent,
-					ToolCallID:     toolCall.ID,
-					ToolCallEvents: []entities.ToolCallEvent{*toolEvent},
-					Timestamp:      time.Now(),
-				}
-				allMessages = append(allMessages, toolResponseMessage)
+			// Execute all tool calls in parallel, then process results in order.
+			toolResults := executeToolsParallel(ctx, toolCalls, runTools(m.toolRepo, toolList), options, iteration, history, m, m.logger)
+			for _, r := range toolResults {
+				allMessages = append(allMessages, r.ToolMessage)
 
 				// Save incrementally if callback provided
 				if callback != nil {
-					if err := callback([]*entities.Message{toolResponseMessage}); err != nil {
+					if err := callback([]*entities.Message{r.ToolMessage}); err != nil {
 						m.logger.Error("Failed to save tool response message incrementally", zap.Error(err))
 					}
 				}
@@ -416,13 +352,18 @@ func (m *OpenAIIntegration) generateResponseV2(ctx context.Context, messages []*
 				// Append tool result to input for next request
 				toolInputItem := map[string]any{
 					"type":    "function_call_output",
-					"call_id": toolCall.ID,
-					"output":  toolResult,
+					"call_id": r.ToolCall.ID,
+					"output":  r.ToolResult,
 				}
 				inputItems = append(inputItems, toolInputItem)
 			}
+
+			if stop := failures.observe(content.String(), toolResults, callback, m.logger); stop != nil {
+				allMessages = append(allMessages, stop)
+				break
+			}
 			// Continue the loop to make another API call with tool results
-		} else {
+		} else if !steeringPending(options) {
 			// No more tool calls, exit the loop
 			break
 		}
@@ -435,15 +376,79 @@ func (m *OpenAIIntegration) generateResponseV2(ctx context.Context, messages []*
 		m.lastUsage.PromptTokens = lastUsage.InputTokens
 		m.lastUsage.CompletionTokens = lastUsage.OutputTokens
 		m.lastUsage.TotalTokens = lastUsage.TotalTokens
+		m.lastUsage.ReasoningTokens = lastUsage.OutputTokensDetails.ReasoningTokens
+		m.lastUsage.CachedTokens = lastUsage.InputTokensDetails.CachedTokens
 	}
 
 	// Ensure tool call responses are validated
 	allMessages = ensureToolCallResponsesOpenAIResponses(allMessages, m.logger)
 
-	m.logger.Info("Generated messages from /v1/responses API", zap.Any("messages", allMessages))
+	logGeneratedMessages(m.logger, allMessages)
 	return allMessages, nil
 }
 
+// sendResponsesRequest posts the request body to the /v1/responses API,
+// retrying failed and rate limited requests, and returns the response body.
+// The request deadline ends with it.
+func (m *OpenAIIntegration) sendResponsesRequest(ctx context.Context, jsonBody []byte) ([]byte, error) {
+	reqCtx, cancel := requestContext(ctx, m.requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, "POST", m.baseURL, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+m.apiKey)
+
+	var resp *http.Response
+	for attempt := 0; attempt < 3; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if err := m.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		resp, err = m.httpClient.Do(req)
+		if err != nil {
+			if attempt < 2 {
+				m.logger.Warn("Error making request, retrying", zap.Error(err))
+				time.Sleep(time.Duration(attempt+1) * time.Second)
+				continue
+			}
+			return nil, fmt.Errorf("error making request: %v", err)
+		}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			if attempt < 2 {
+				resp.Body.Close()
+				time.Sleep(time.Duration(attempt+1) * time.Second)
+				continue
+			}
+			return nil, errors.RateLimitErrorf("rate limit exceeded")
+		}
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			body = []byte(m.redact(string(body)))
+			m.logger.Error("OpenAI /v1/responses API error",
+				zap.Int("status_code", resp.StatusCode),
+				zap.String("body", string(body)))
+			return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+		}
+		m.quotaLow = quotaNearLimit(resp.Header)
+		break
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %v", err)
+	}
+	return respBody, nil
+}
+
 // convertMessagesToInputItems converts message entities to /v1/responses input format
 func (m *OpenAIIntegration) convertMessagesToInputItems(messages []*entities.Message) ([]map[string]any, string) {
 	var inputItems []map[string]any
@@ -465,7 +470,7 @@ func (m *OpenAIIntegration) convertMessagesToInputItems(messages []*entities.Mes
 			// Convert to input item format for user/assistant messages
 			item := map[string]any{
 				"role":    msg.Role,
-				"content": msg.Content,
+				"content": responsesContent(msg),
 			}
 			inputItems = append(inputItems, item)
 		}
@@ -556,17 +561,6 @@ func (m *OpenAIIntegration) extractMessageContent(item struct {
 	return item.Text
 }
 
-// extractDiffFromResult extracts diff from FileWrite tool result
-func (m *OpenAIIntegration) extractDiffFromResult(result string) string {
-	var resultData struct {
-		Diff string `json:"diff"`
-	}
-	if err := json.Unmarshal([]byte(result), &resultData); err == nil && resultData.Diff != "" {
-		return resultData.Diff
-	}
-	return ""
-}
-
 // ensureToolCallResponsesOpenAIResponses validates that every tool call has a corresponding response
 // and creates error responses for any orphaned tool calls (specific to Responses API)
 func ensureToolCallResponsesOpenAIResponses(messages []*entities.Message, logger *zap.Logger) []*entities.Message {
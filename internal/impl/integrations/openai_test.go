@@ -1,6 +1,10 @@
 package integrations
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/drujensen/aiagent/internal/domain/entities"
@@ -68,6 +72,40 @@ func TestNewOpenAIIntegration_EndpointRouting(t *testing.T) {
 	}
 }
 
+func TestOpenAIIntegration_PromptCacheKey(t *testing.T) {
+	var cacheKey any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		cacheKey = body["prompt_cache_key"]
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices": [{"finish_reason": "stop", "message": {"content": "Done."}}],
+			"usage": {"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15}}`))
+	}))
+	defer server.Close()
+
+	integration, err := NewOpenAIIntegration(server.URL, "test-key", &entities.Model{ModelName: "gpt-4"}, nil, zaptest.NewLogger(t))
+	if err != nil {
+		t.Fatalf("Failed to create integration: %v", err)
+	}
+
+	options := map[string]any{"prompt_caching": true, "session_id": "chat-1"}
+	messages := []*entities.Message{entities.NewMessage("user", "hi")}
+	if _, err := integration.GenerateResponse(context.Background(), messages, nil, options, nil); err != nil {
+		t.Fatalf("GenerateResponse failed: %v", err)
+	}
+
+	if cacheKey != "chat-1" {
+		t.Errorf("Expected the request to carry prompt_cache_key chat-1, got %v", cacheKey)
+	}
+	// The options are shared with the next model of a fallback chain
+	if _, ok := options["prompt_cache_key"]; ok {
+		t.Error("Expected the caller's options to be left unchanged")
+	}
+}
+
 func containsString(s, substr string) bool {
 	return len(s) >= len(substr) && s[len(s)-len(substr):] == substr
 }
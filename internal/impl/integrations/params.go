@@ -0,0 +1,130 @@
+package integrations
+
+import (
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// paramDialect declares how an API takes the sampling options the service
+// sets under OpenAI's names: max_tokens, temperature and reasoning_effort.
+// Names are the request body fields to set, with dots for nested objects;
+// an empty name drops the option for APIs or models that reject it. Adding a
+// provider means declaring its dialect here.
+type paramDialect struct {
+	MaxTokens   string
+	Temperature string
+	// MaxTemperature clamps higher temperatures; zero leaves them as is
+	MaxTemperature  float64
+	ReasoningEffort string
+	// Effort converts an effort to the value the API takes; nil sends it
+	// as is. Efforts it doesn't know are dropped.
+	Effort func(effort string) (any, bool)
+}
+
+var (
+	// chatCompletionsParams is the dialect of OpenAI-compatible APIs
+	chatCompletionsParams = paramDialect{
+		MaxTokens:       "max_tokens",
+		Temperature:     "temperature",
+		ReasoningEffort: "reasoning_effort",
+	}
+	// openAIChatParams is OpenAI's own chat completions API, which replaced
+	// max_tokens with max_completion_tokens
+	openAIChatParams = paramDialect{
+		MaxTokens:       "max_completion_tokens",
+		Temperature:     "temperature",
+		MaxTemperature:  2,
+		ReasoningEffort: "reasoning_effort",
+	}
+	// openAIReasoningChatParams is the chat completions API for reasoning
+	// models, which reject any temperature
+	openAIReasoningChatParams = paramDialect{
+		MaxTokens:       "max_completion_tokens",
+		ReasoningEffort: "reasoning_effort",
+	}
+	// openAIResponsesParams is OpenAI's /v1/responses API for o-series and
+	// codex models, which reject any temperature
+	openAIResponsesParams = paramDialect{
+		MaxTokens:       "max_output_tokens",
+		ReasoningEffort: "reasoning.effort",
+	}
+	anthropicParams = paramDialect{
+		MaxTokens:      "max_tokens",
+		Temperature:    "temperature",
+		MaxTemperature: 1,
+	}
+	googleParams = paramDialect{
+		MaxTokens:       "generationConfig.maxOutputTokens",
+		Temperature:     "generationConfig.temperature",
+		MaxTemperature:  2,
+		ReasoningEffort: "generationConfig.thinkingConfig.thinkingBudget",
+		Effort: func(effort string) (any, bool) {
+			budget, ok := geminiThinkingBudgets[effort]
+			return budget, ok
+		},
+	}
+	mistralParams = paramDialect{
+		MaxTokens:      "max_tokens",
+		Temperature:    "temperature",
+		MaxTemperature: 1.5,
+	}
+	ollamaParams = paramDialect{
+		MaxTokens:   "options.num_predict",
+		Temperature: "options.temperature",
+	}
+)
+
+// apply sets the options in the request body under the dialect's names,
+// logging the ones it drops.
+func (d paramDialect) apply(reqBody map[string]any, options map[string]any, logger *zap.Logger) {
+	var dropped []string
+	set := func(option, name string, value any) {
+		if name == "" {
+			dropped = append(dropped, option)
+			return
+		}
+		setParam(reqBody, name, value)
+	}
+
+	if maxTokens, ok := options["max_tokens"]; ok && maxTokens != nil {
+		set("max_tokens", d.MaxTokens, maxTokens)
+	}
+	if temp, ok := options["temperature"]; ok && temp != nil {
+		if t, ok := temp.(float64); ok && d.MaxTemperature > 0 && t > d.MaxTemperature {
+			temp = d.MaxTemperature
+		}
+		set("temperature", d.Temperature, temp)
+	}
+	if effort := reasoningEffortOption(options); effort != "" {
+		var value any = effort
+		ok := true
+		if d.Effort != nil {
+			value, ok = d.Effort(effort)
+		}
+		if ok {
+			set("reasoning_effort", d.ReasoningEffort, value)
+		} else {
+			dropped = append(dropped, "reasoning_effort")
+		}
+	}
+
+	if len(dropped) > 0 && logger != nil {
+		logger.Debug("Dropped options the API doesn't accept", zap.Strings("options", dropped))
+	}
+}
+
+// setParam sets the value at a dotted name, creating the nested objects on
+// the way.
+func setParam(reqBody map[string]any, name string, value any) {
+	path := strings.Split(name, ".")
+	for _, key := range path[:len(path)-1] {
+		nested, ok := reqBody[key].(map[string]any)
+		if !ok {
+			nested = map[string]any{}
+			reqBody[key] = nested
+		}
+		reqBody = nested
+	}
+	reqBody[path[len(path)-1]] = value
+}
@@ -0,0 +1,77 @@
+package integrations
+
+import (
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+
+	"go.uber.org/zap"
+)
+
+func TestParamDialectApply(t *testing.T) {
+	options := map[string]any{"max_tokens": 1000, "temperature": 1.8, "reasoning_effort": "high"}
+	apply := func(d paramDialect) map[string]any {
+		reqBody := map[string]any{}
+		d.apply(reqBody, options, zap.NewNop())
+		return reqBody
+	}
+
+	body := apply(openAIChatParams)
+	if body["max_completion_tokens"] != 1000 || body["max_tokens"] != nil {
+		t.Errorf("expected max_completion_tokens for OpenAI, got %v", body)
+	}
+	if _, ok := apply(openAIReasoningChatParams)["temperature"]; ok {
+		t.Error("expected the temperature to be dropped for reasoning models")
+	}
+
+	body = apply(openAIResponsesParams)
+	if body["max_output_tokens"] != 1000 || body["reasoning"].(map[string]any)["effort"] != "high" {
+		t.Errorf("expected the responses API names, got %v", body)
+	}
+
+	body = apply(anthropicParams)
+	if body["max_tokens"] != 1000 || body["temperature"] != 1.0 {
+		t.Errorf("expected the temperature clamped to Anthropic's range, got %v", body)
+	}
+	if _, ok := body["reasoning_effort"]; ok {
+		t.Error("expected reasoning_effort to be dropped for Anthropic")
+	}
+
+	body = apply(googleParams)
+	config := body["generationConfig"].(map[string]any)
+	if config["maxOutputTokens"] != 1000 || config["temperature"] != 1.8 {
+		t.Errorf("expected the generation config, got %v", config)
+	}
+	if budget := config["thinkingConfig"].(map[string]any)["thinkingBudget"]; budget != geminiThinkingBudgets["high"] {
+		t.Errorf("expected the effort as a thinking budget, got %v", budget)
+	}
+
+	options["reasoning_effort"] = "extreme"
+	if _, ok := apply(googleParams)["generationConfig"].(map[string]any)["thinkingConfig"]; ok {
+		t.Error("expected an effort without a budget to be dropped")
+	}
+}
+
+func TestOpenAIParamDialect(t *testing.T) {
+	tests := []struct {
+		model           *entities.Model
+		wantMaxTokens   string
+		wantTemperature bool
+	}{
+		{&entities.Model{ModelName: "gpt-4o"}, "max_completion_tokens", true},
+		{&entities.Model{ModelName: "gpt-5", Reasoning: true}, "max_completion_tokens", false},
+		{&entities.Model{ModelName: "o3-mini", Reasoning: true}, "max_output_tokens", false},
+	}
+	for _, tt := range tests {
+		integration, err := NewOpenAIIntegration("https://api.openai.com", "key", tt.model, nil, zap.NewNop())
+		if err != nil {
+			t.Fatalf("NewOpenAIIntegration: %v", err)
+		}
+		if integration.params.MaxTokens != tt.wantMaxTokens {
+			t.Errorf("%s: expected %s, got %s", tt.model.ModelName, tt.wantMaxTokens, integration.params.MaxTokens)
+		}
+		if (integration.params.Temperature != "") != tt.wantTemperature {
+			t.Errorf("%s: expected temperature %v, got %q", tt.model.ModelName, tt.wantTemperature, integration.params.Temperature)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package integrations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+)
+
+// probeTimeout bounds each request made while probing a provider.
+const probeTimeout = 5 * time.Second
+
+// nativeEndpoint strips the API version a generic provider's base URL often
+// ends with, since the native integrations add their own paths.
+func nativeEndpoint(baseURL string) string {
+	endpoint := strings.TrimRight(baseURL, "/")
+	for _, suffix := range []string{"/v1beta", "/v1"} {
+		endpoint = strings.TrimSuffix(endpoint, suffix)
+	}
+	return endpoint
+}
+
+// ProbeProviderType asks the models endpoint behind baseURL which API it
+// speaks. It tells Anthropic's and Google's APIs apart from OpenAI-compatible
+// ones, for proxies and gateways whose URL gives nothing away.
+func ProbeProviderType(ctx context.Context, baseURL, apiKey string) (entities.ProviderType, error) {
+	base := nativeEndpoint(baseURL)
+	client := &http.Client{Timeout: probeTimeout}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", base+"/v1/models", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	req.Header.Set("x-api-key", apiKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+
+	resp, err := client.Do(req)
+	if err == nil {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		resp.Body.Close()
+
+		for name := range resp.Header {
+			if strings.HasPrefix(strings.ToLower(name), "anthropic-") {
+				return entities.ProviderAnthropic, nil
+			}
+		}
+		var list struct {
+			Object  string `json:"object"`
+			FirstID string `json:"first_id"`
+		}
+		if resp.StatusCode == http.StatusOK && json.Unmarshal(body, &list) == nil {
+			if list.FirstID != "" {
+				return entities.ProviderAnthropic, nil
+			}
+			if list.Object == "list" {
+				return entities.ProviderGeneric, nil
+			}
+		}
+	}
+
+	req, err = http.NewRequestWithContext(ctx, "GET", base+"/v1beta/models?key="+url.QueryEscape(apiKey), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err = client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to probe %s: %v", base, err)
+	}
+	defer resp.Body.Close()
+
+	var models struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if resp.StatusCode == http.StatusOK && json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&models) == nil &&
+		len(models.Models) > 0 && strings.HasPrefix(models.Models[0].Name, "models/") {
+		return entities.ProviderGoogle, nil
+	}
+	return entities.ProviderGeneric, nil
+}
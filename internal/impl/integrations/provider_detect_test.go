@@ -0,0 +1,47 @@
+package integrations
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+)
+
+func TestProbeProviderType(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    entities.ProviderType
+	}{
+		{"anthropic", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"data":[{"type":"model","id":"claude"}],"first_id":"claude","has_more":false}`))
+		}, entities.ProviderAnthropic},
+		{"openai compatible", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"object":"list","data":[{"id":"llama"}]}`))
+		}, entities.ProviderGeneric},
+		{"google", func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/v1beta/models" {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write([]byte(`{"models":[{"name":"models/gemini"}]}`))
+		}, entities.ProviderGoogle},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			got, err := ProbeProviderType(context.Background(), server.URL+"/v1/", "key")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ProbeProviderType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
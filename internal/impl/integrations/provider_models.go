@@ -0,0 +1,97 @@
+package integrations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/impl/redact"
+)
+
+// listModelsTimeout bounds the request for a provider's model list.
+const listModelsTimeout = 15 * time.Second
+
+// ListProviderModels returns the IDs of the models the provider's models
+// endpoint lists. The request is authenticated, so a successful call also
+// confirms the base URL and API key work.
+func ListProviderModels(ctx context.Context, provider *entities.Provider, apiKey string) ([]string, error) {
+	base := strings.TrimRight(provider.BaseURL, "/")
+	if base == "" {
+		return nil, fmt.Errorf("provider %s has no base URL", provider.Name)
+	}
+
+	var endpoint string
+	header := http.Header{}
+	switch provider.EffectiveType() {
+	case entities.ProviderAnthropic:
+		endpoint = base + "/v1/models?limit=1000"
+		header.Set("x-api-key", apiKey)
+		header.Set("anthropic-version", "2023-06-01")
+	case entities.ProviderGoogle:
+		endpoint = nativeEndpoint(base) + "/v1beta/models?pageSize=1000&key=" + url.QueryEscape(apiKey)
+	case entities.ProviderGroq:
+		endpoint = base + "/openai/v1/models"
+		header.Set("Authorization", "Bearer "+apiKey)
+	case entities.ProviderOllama:
+		endpoint = nativeEndpoint(base) + "/api/tags"
+		if apiKey != "" {
+			header.Set("Authorization", "Bearer "+apiKey)
+		}
+	default:
+		endpoint = base + "/v1/models"
+		header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+
+	client := &http.Client{Timeout: listModelsTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		// The Google key is in the URL, keep it out of the error
+		return nil, fmt.Errorf("failed to reach %s: %v", base, strings.ReplaceAll(err.Error(), url.QueryEscape(apiKey), "***"))
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the model list: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		snippet := strings.TrimSpace(redact.Secrets(string(body), apiKey))
+		if len(snippet) > 200 {
+			snippet = snippet[:200] + "..."
+		}
+		return nil, fmt.Errorf("model list request failed with status %d: %s", resp.StatusCode, snippet)
+	}
+
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse the model list: %v", err)
+	}
+
+	var ids []string
+	for _, model := range list.Data {
+		ids = append(ids, model.ID)
+	}
+	for _, model := range list.Models {
+		ids = append(ids, strings.TrimPrefix(model.Name, "models/"))
+	}
+	return ids, nil
+}
@@ -0,0 +1,61 @@
+package integrations
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+)
+
+func TestListProviderModels(t *testing.T) {
+	tests := []struct {
+		providerType entities.ProviderType
+		path         string
+		authorized   func(r *http.Request) bool
+		body         string
+	}{
+		{entities.ProviderOpenAI, "/v1/models", func(r *http.Request) bool { return r.Header.Get("Authorization") == "Bearer key" },
+			`{"object":"list","data":[{"id":"gpt-4o"},{"id":"gpt-4o-mini"}]}`},
+		{entities.ProviderAnthropic, "/v1/models", func(r *http.Request) bool { return r.Header.Get("x-api-key") == "key" },
+			`{"data":[{"id":"gpt-4o"},{"id":"gpt-4o-mini"}],"has_more":false}`},
+		{entities.ProviderGoogle, "/v1beta/models", func(r *http.Request) bool { return r.URL.Query().Get("key") == "key" },
+			`{"models":[{"name":"models/gpt-4o"},{"name":"models/gpt-4o-mini"}]}`},
+		{entities.ProviderGroq, "/openai/v1/models", func(r *http.Request) bool { return r.Header.Get("Authorization") == "Bearer key" },
+			`{"data":[{"id":"gpt-4o"},{"id":"gpt-4o-mini"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.providerType), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tt.path {
+					http.NotFound(w, r)
+					return
+				}
+				if !tt.authorized(r) {
+					w.WriteHeader(http.StatusUnauthorized)
+					w.Write([]byte(`{"error":"invalid api key"}`))
+					return
+				}
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			provider := &entities.Provider{Name: "test", Type: tt.providerType, BaseURL: server.URL + "/"}
+			models, err := ListProviderModels(context.Background(), provider, "key")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(models, []string{"gpt-4o", "gpt-4o-mini"}) {
+				t.Errorf("unexpected models: %v", models)
+			}
+
+			if _, err := ListProviderModels(context.Background(), provider, "wrong"); err == nil || !strings.Contains(err.Error(), "401") {
+				t.Errorf("expected an invalid key to fail with its status, got %v", err)
+			}
+		})
+	}
+}
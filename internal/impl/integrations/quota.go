@@ -0,0 +1,61 @@
+package integrations
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/drujensen/aiagent/internal/domain/interfaces"
+)
+
+// quotaLowFraction is how little of a rate limit may remain before the
+// provider is taken to be near it.
+const quotaLowFraction = 0.05
+
+// rateLimitHeaders pairs the remaining and limit headers providers send for
+// each of their rate limits.
+var rateLimitHeaders = [][2]string{
+	{"x-ratelimit-remaining-requests", "x-ratelimit-limit-requests"},
+	{"x-ratelimit-remaining-tokens", "x-ratelimit-limit-tokens"},
+	{"anthropic-ratelimit-requests-remaining", "anthropic-ratelimit-requests-limit"},
+	{"anthropic-ratelimit-tokens-remaining", "anthropic-ratelimit-tokens-limit"},
+	{"anthropic-ratelimit-input-tokens-remaining", "anthropic-ratelimit-input-tokens-limit"},
+	{"anthropic-ratelimit-output-tokens-remaining", "anthropic-ratelimit-output-tokens-limit"},
+}
+
+// quotaNearLimit reports whether the response headers show any rate limit
+// with less than quotaLowFraction of it remaining.
+func quotaNearLimit(header http.Header) bool {
+	for _, names := range rateLimitHeaders {
+		remaining, err := strconv.ParseFloat(header.Get(names[0]), 64)
+		if err != nil {
+			continue
+		}
+		limit, err := strconv.ParseFloat(header.Get(names[1]), 64)
+		if err != nil || limit <= 0 {
+			continue
+		}
+		if remaining < limit*quotaLowFraction {
+			return true
+		}
+	}
+	return false
+}
+
+type quotaReporter interface {
+	nearQuota() bool
+}
+
+// NearQuota reports whether the integration's last response showed one of
+// the provider's rate limits close to running out.
+func NearQuota(integration interfaces.AIModelIntegration) bool {
+	reporter, ok := integration.(quotaReporter)
+	return ok && reporter.nearQuota()
+}
+
+func (m *AIModelIntegration) nearQuota() bool {
+	return m.quotaLow
+}
+
+func (m *AnthropicIntegration) nearQuota() bool {
+	return m.quotaLow
+}
@@ -0,0 +1,72 @@
+package integrations
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+
+	"go.uber.org/zap"
+)
+
+func TestQuotaNearLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    bool
+	}{
+		{"no headers", nil, false},
+		{"plenty left", map[string]string{"x-ratelimit-remaining-requests": "400", "x-ratelimit-limit-requests": "500"}, false},
+		{"few requests left", map[string]string{"x-ratelimit-remaining-requests": "4", "x-ratelimit-limit-requests": "500"}, true},
+		{"few tokens left", map[string]string{"x-ratelimit-remaining-tokens": "1000", "x-ratelimit-limit-tokens": "100000"}, true},
+		{"anthropic", map[string]string{"anthropic-ratelimit-input-tokens-remaining": "0", "anthropic-ratelimit-input-tokens-limit": "40000"}, true},
+		{"no limit", map[string]string{"x-ratelimit-remaining-requests": "0"}, false},
+		{"unparsable", map[string]string{"x-ratelimit-remaining-requests": "n/a", "x-ratelimit-limit-requests": "500"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for k, v := range tt.headers {
+				header.Set(k, v)
+			}
+			if got := quotaNearLimit(header); got != tt.want {
+				t.Errorf("quotaNearLimit() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateResponse_NearQuota(t *testing.T) {
+	remaining := "499"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("x-ratelimit-limit-requests", "500")
+		w.Header().Set("x-ratelimit-remaining-requests", remaining)
+		w.Write([]byte(`{"choices": [{"finish_reason": "stop", "message": {"content": "Done."}}],
+			"usage": {"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15}}`))
+	}))
+	defer server.Close()
+
+	integration, err := NewAIModelIntegration(server.URL, "test-key", "test-model", nil, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewAIModelIntegration failed: %v", err)
+	}
+	messages := []*entities.Message{entities.NewMessage("user", "hi")}
+
+	if _, err := integration.GenerateResponse(context.Background(), messages, nil, map[string]any{}, nil); err != nil {
+		t.Fatalf("GenerateResponse failed: %v", err)
+	}
+	if NearQuota(integration) {
+		t.Error("Expected plenty of quota left")
+	}
+
+	remaining = "3"
+	if _, err := integration.GenerateResponse(context.Background(), messages, nil, map[string]any{}, nil); err != nil {
+		t.Fatalf("GenerateResponse failed: %v", err)
+	}
+	if !NearQuota(integration) {
+		t.Error("Expected the response to show the quota near its limit")
+	}
+}
@@ -0,0 +1,110 @@
+package integrations
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+)
+
+// rateLimiter is a token bucket allowing requestsPerMinute requests per minute
+// with bursts up to the same size.
+type rateLimiter struct {
+	mu                sync.Mutex
+	requestsPerMinute int
+	tokens            float64
+	last              time.Time
+}
+
+func newRateLimiter(requestsPerMinute int) *rateLimiter {
+	return &rateLimiter{
+		requestsPerMinute: requestsPerMinute,
+		tokens:            float64(requestsPerMinute),
+		last:              time.Now(),
+	}
+}
+
+// setRate changes the allowed requests per minute, keeping the current tokens.
+// It does nothing when the rate is unchanged.
+func (l *rateLimiter) setRate(requestsPerMinute int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.requestsPerMinute == requestsPerMinute {
+		return
+	}
+	l.requestsPerMinute = requestsPerMinute
+	l.tokens = min(l.tokens, float64(requestsPerMinute))
+}
+
+// reserve takes a token if one is available, otherwise it returns how long to
+// wait before trying again.
+func (l *rateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	perToken := time.Minute / time.Duration(l.requestsPerMinute)
+	l.tokens = min(l.tokens+float64(now.Sub(l.last))/float64(perToken), float64(l.requestsPerMinute))
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return time.Duration((1 - l.tokens) * float64(perToken))
+}
+
+// Wait blocks until a request may be made or the context is done. A nil
+// limiter never blocks.
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	for {
+		delay := l.reserve()
+		if delay == 0 {
+			return nil
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// providerLimiters shares one limiter per provider across every integration
+// created for that provider, so concurrent chats draw from the same budget.
+var providerLimiters = struct {
+	sync.Mutex
+	limiters map[string]*rateLimiter
+}{limiters: make(map[string]*rateLimiter)}
+
+// limiterForProvider returns the shared limiter for the provider, or nil when
+// the provider has no requests per minute limit.
+func limiterForProvider(provider *entities.Provider) *rateLimiter {
+	providerLimiters.Lock()
+	defer providerLimiters.Unlock()
+
+	if provider.RequestsPerMinute <= 0 {
+		delete(providerLimiters.limiters, provider.ID)
+		return nil
+	}
+
+	limiter, ok := providerLimiters.limiters[provider.ID]
+	if !ok {
+		limiter = newRateLimiter(provider.RequestsPerMinute)
+		providerLimiters.limiters[provider.ID] = limiter
+	} else {
+		limiter.setRate(provider.RequestsPerMinute)
+	}
+	return limiter
+}
+
+// rateLimited is implemented by integrations that throttle their requests.
+type rateLimited interface {
+	setRateLimiter(limiter *rateLimiter)
+}
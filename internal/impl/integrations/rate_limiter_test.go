@@ -0,0 +1,71 @@
+package integrations
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+)
+
+func TestRateLimiter_Wait(t *testing.T) {
+	limiter := newRateLimiter(2)
+	ctx := context.Background()
+
+	// The bucket starts full so the first requests go straight through
+	for i := 0; i < 2; i++ {
+		if err := limiter.Wait(ctx); err != nil {
+			t.Fatalf("Expected request %d to be allowed, got %v", i+1, err)
+		}
+	}
+
+	// The next request has to wait about 30 seconds, so it is canceled first
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx); err == nil {
+		t.Error("Expected waiting on an empty bucket to respect the context deadline")
+	}
+
+	var unlimited *rateLimiter
+	if err := unlimited.Wait(context.Background()); err != nil {
+		t.Errorf("Expected nil limiter to never block, got %v", err)
+	}
+}
+
+func TestLimiterForProvider(t *testing.T) {
+	provider := &entities.Provider{ID: "provider-rate-test", RequestsPerMinute: 60}
+
+	first := limiterForProvider(provider)
+	second := limiterForProvider(provider)
+	if first == nil || first != second {
+		t.Fatal("Expected integrations for the same provider to share a limiter")
+	}
+
+	provider.RequestsPerMinute = 0
+	if limiter := limiterForProvider(provider); limiter != nil {
+		t.Error("Expected no limiter for an unlimited provider")
+	}
+}
+
+func TestLimiterForProvider_ConcurrentRateChanges(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			provider := &entities.Provider{ID: "provider-rate-race-test", RequestsPerMinute: 60 + i%2}
+			for j := 0; j < 50; j++ {
+				limiterForProvider(provider).reserve()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	limiter := limiterForProvider(&entities.Provider{ID: "provider-rate-race-test", RequestsPerMinute: 30})
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	if limiter.requestsPerMinute != 30 {
+		t.Errorf("Expected the rate to be updated to 30, got %d", limiter.requestsPerMinute)
+	}
+}
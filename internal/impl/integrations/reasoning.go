@@ -0,0 +1,50 @@
+package integrations
+
+import (
+	"regexp"
+	"strings"
+)
+
+// thinkingTagPattern matches the <think> and <thinking> blocks reasoning
+// models such as DeepSeek-R1 and QwQ put in their content. An unclosed block
+// runs to the end of the content.
+var thinkingTagPattern = regexp.MustCompile(`(?s)<(think|thinking)>(.*?)(?:</(?:think|thinking)>|\z)`)
+
+// splitReasoning moves the thinking blocks of a response's content into its
+// reasoning. Reasoning the provider returned in a separate field comes first.
+func splitReasoning(content string, reasoning ...string) (string, string) {
+	var parts []string
+	for _, r := range reasoning {
+		if r = strings.TrimSpace(r); r != "" {
+			parts = append(parts, r)
+		}
+	}
+
+	if strings.Contains(content, "<think") {
+		content = thinkingTagPattern.ReplaceAllStringFunc(content, func(block string) string {
+			if r := strings.TrimSpace(thinkingTagPattern.FindStringSubmatch(block)[2]); r != "" {
+				parts = append(parts, r)
+			}
+			return ""
+		})
+		content = strings.TrimSpace(content)
+	}
+
+	return content, strings.Join(parts, "\n\n")
+}
+
+// reasoningEffortOption is the reasoning_effort option. The service only sets
+// it for models that accept it.
+func reasoningEffortOption(options map[string]any) string {
+	effort, _ := options["reasoning_effort"].(string)
+	return effort
+}
+
+// geminiThinkingBudgets maps reasoning efforts to Gemini thinking budgets in
+// tokens, since Gemini takes a budget rather than an effort level.
+var geminiThinkingBudgets = map[string]int{
+	"minimal": 512,
+	"low":     1024,
+	"medium":  8192,
+	"high":    24576,
+}
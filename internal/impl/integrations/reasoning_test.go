@@ -0,0 +1,105 @@
+package integrations
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+
+	"go.uber.org/zap"
+)
+
+func TestSplitReasoning(t *testing.T) {
+	tests := []struct {
+		name          string
+		content       string
+		fields        []string
+		wantContent   string
+		wantReasoning string
+	}{
+		{"no reasoning", "The answer is 4.", nil, "The answer is 4.", ""},
+		{"think tags", "<think>\n2 plus 2\n</think>\n\nThe answer is 4.", nil, "The answer is 4.", "2 plus 2"},
+		{"thinking tags", "<thinking>add them</thinking>4", nil, "4", "add them"},
+		{"unclosed tag", "<think>still going", nil, "", "still going"},
+		{"separate field", "The answer is 4.", []string{"2 plus 2", ""}, "The answer is 4.", "2 plus 2"},
+		{"field and tags", "<think>check</think>4", []string{"add"}, "4", "add\n\ncheck"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, reasoning := splitReasoning(tt.content, tt.fields...)
+			if content != tt.wantContent {
+				t.Errorf("content = %q, want %q", content, tt.wantContent)
+			}
+			if reasoning != tt.wantReasoning {
+				t.Errorf("reasoning = %q, want %q", reasoning, tt.wantReasoning)
+			}
+		})
+	}
+}
+
+func TestGenerateResponse_CapturesReasoningContent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"choices": [{
+				"finish_reason": "stop",
+				"message": {"content": "The answer is 4.", "reasoning_content": "2 plus 2 is 4."}
+			}],
+			"usage": {"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15}
+		}`))
+	}))
+	defer server.Close()
+
+	integration, err := NewAIModelIntegration(server.URL, "test-key", "deepseek-reasoner", &singleToolRepo{}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewAIModelIntegration failed: %v", err)
+	}
+
+	messages := []*entities.Message{entities.NewMessage("user", "What is 2 plus 2?")}
+	newMessages, err := integration.GenerateResponse(context.Background(), messages, nil, map[string]any{}, nil)
+	if err != nil {
+		t.Fatalf("GenerateResponse failed: %v", err)
+	}
+	if len(newMessages) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(newMessages))
+	}
+	if got := newMessages[0]; got.Content != "The answer is 4." || got.Reasoning != "2 plus 2 is 4." {
+		t.Errorf("got content %q and reasoning %q", got.Content, got.Reasoning)
+	}
+}
+
+func TestGenerateResponse_SendsReasoningEffort(t *testing.T) {
+	var bodies []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"choices": [{"finish_reason": "stop", "message": {"content": "4"}}],
+			"usage": {"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15}
+		}`))
+	}))
+	defer server.Close()
+
+	integration, err := NewAIModelIntegration(server.URL, "test-key", "o4-mini", &singleToolRepo{}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewAIModelIntegration failed: %v", err)
+	}
+
+	messages := []*entities.Message{entities.NewMessage("user", "What is 2 plus 2?")}
+	for _, options := range []map[string]any{{"reasoning_effort": "high"}, {}} {
+		if _, err := integration.GenerateResponse(context.Background(), messages, nil, options, nil); err != nil {
+			t.Fatalf("GenerateResponse failed: %v", err)
+		}
+	}
+	if got := bodies[0]["reasoning_effort"]; got != "high" {
+		t.Errorf("expected reasoning_effort high, got %v", got)
+	}
+	if _, ok := bodies[1]["reasoning_effort"]; ok {
+		t.Error("expected no reasoning_effort when the option is not set")
+	}
+}
@@ -0,0 +1,61 @@
+package integrations
+
+import (
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/domain/interfaces"
+	"github.com/drujensen/aiagent/internal/impl/redact"
+
+	"go.uber.org/zap"
+)
+
+// secretRedactor is implemented by integrations that can mask more secret
+// values than their API key.
+type secretRedactor interface {
+	addSecrets(secrets []string)
+}
+
+// RedactSecrets has the integration mask the secret values, such as those of
+// an agent's env, in the request and response bodies and errors it logs.
+func RedactSecrets(integration interfaces.AIModelIntegration, secrets []string) {
+	if redactor, ok := integration.(secretRedactor); ok && len(secrets) > 0 {
+		redactor.addSecrets(secrets)
+	}
+}
+
+func (m *AIModelIntegration) addSecrets(secrets []string) {
+	m.secrets = append(m.secrets, secrets...)
+}
+
+func (m *AnthropicIntegration) addSecrets(secrets []string) {
+	m.secrets = append(m.secrets, secrets...)
+}
+
+// redact masks the integration's API key and other credentials in text.
+func (m *AIModelIntegration) redact(text string) string {
+	return redact.Secrets(text, append([]string{m.apiKey}, m.secrets...)...)
+}
+
+// redact masks the integration's API key and other credentials in text.
+func (m *AnthropicIntegration) redact(text string) string {
+	return redact.Secrets(text, append([]string{m.apiKey}, m.secrets...)...)
+}
+
+// logGeneratedMessages logs what a response produced without the content of
+// its messages or the arguments of its tool calls, which can carry secrets.
+func logGeneratedMessages(logger *zap.Logger, messages []*entities.Message) {
+	var tools []string
+	for _, msg := range messages {
+		tools = append(tools, toolCallNames(msg.ToolCalls)...)
+	}
+	logger.Info("Generated messages", zap.Int("count", len(messages)), zap.Strings("tool_calls", tools))
+}
+
+// toolCallNames returns the names of the tools called, for logging the calls
+// without their arguments.
+func toolCallNames(toolCalls []entities.ToolCall) []string {
+	names := make([]string, len(toolCalls))
+	for i, toolCall := range toolCalls {
+		names[i] = toolCall.Function.Name
+	}
+	return names
+}
@@ -0,0 +1,98 @@
+package integrations
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestAIModelIntegration_RedactsErrorBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": {"message": "Incorrect API key provided: test-key-1234"}}`))
+	}))
+	defer server.Close()
+
+	core, logs := observer.New(zap.InfoLevel)
+	integration, err := NewAIModelIntegration(server.URL, "test-key-1234", "test-model", &singleToolRepo{}, zap.New(core))
+	if err != nil {
+		t.Fatalf("NewAIModelIntegration failed: %v", err)
+	}
+
+	messages := []*entities.Message{entities.NewMessage("user", "Hi")}
+	_, err = integration.GenerateResponse(context.Background(), messages, nil, map[string]any{}, nil)
+	if err == nil {
+		t.Fatal("expected an error for a 401")
+	}
+	if strings.Contains(err.Error(), "test-key-1234") {
+		t.Errorf("expected the key to be redacted from the error, got %v", err)
+	}
+	for _, entry := range logs.All() {
+		for key, value := range entry.ContextMap() {
+			if s, ok := value.(string); ok && strings.Contains(s, "test-key-1234") {
+				t.Errorf("expected the key to be redacted from the %q log field, got %s", key, s)
+			}
+		}
+	}
+}
+
+func TestRedactSecrets_Integration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": {"message": "unexpected value env-secret-5678"}}`))
+	}))
+	defer server.Close()
+
+	core, logs := observer.New(zap.DebugLevel)
+	integration, err := NewAIModelIntegration(server.URL, "test-key-1234", "test-model", &singleToolRepo{}, zap.New(core))
+	if err != nil {
+		t.Fatalf("NewAIModelIntegration failed: %v", err)
+	}
+	RedactSecrets(integration, []string{"env-secret-5678"})
+
+	messages := []*entities.Message{entities.NewMessage("user", "The token is env-secret-5678")}
+	_, err = integration.GenerateResponse(context.Background(), messages, nil, map[string]any{}, nil)
+	if err == nil {
+		t.Fatal("expected an error for a 400")
+	}
+	if strings.Contains(err.Error(), "env-secret-5678") {
+		t.Errorf("expected the secret to be redacted from the error, got %v", err)
+	}
+	for _, entry := range logs.All() {
+		for key, value := range entry.ContextMap() {
+			if s, ok := value.(string); ok && strings.Contains(s, "env-secret-5678") {
+				t.Errorf("expected the secret to be redacted from the %q log field, got %s", key, s)
+			}
+		}
+	}
+}
+
+func TestLogGeneratedMessages_LeavesOutContent(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	message := entities.NewMessage("assistant", "The key is env-secret-5678")
+	message.ToolCalls = []entities.ToolCall{{ID: "1", Type: "function"}}
+	message.ToolCalls[0].Function.Name = "Fetch"
+	message.ToolCalls[0].Function.Arguments = `{"auth":{"type":"bearer","token":"env-secret-5678"}}`
+
+	logGeneratedMessages(zap.New(core), []*entities.Message{message})
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 log entry, got %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if strings.Contains(fmt.Sprint(fields), "env-secret-5678") {
+		t.Errorf("Expected the content and arguments to be left out, got %v", fields)
+	}
+	if tools, _ := fields["tool_calls"].([]interface{}); len(tools) != 1 || tools[0] != "Fetch" {
+		t.Errorf("Expected the tool names to be logged, got %v", fields["tool_calls"])
+	}
+}
@@ -0,0 +1,45 @@
+package integrations
+
+import (
+	"github.com/drujensen/aiagent/internal/domain/entities"
+)
+
+// responseFormatOption returns the structured output format requested in the
+// options, or nil for plain text.
+func responseFormatOption(options map[string]any) *entities.ResponseFormat {
+	format, _ := options["response_format"].(*entities.ResponseFormat)
+	if !format.Structured() {
+		return nil
+	}
+	return format
+}
+
+// chatCompletionsResponseFormat builds the response_format parameter of the
+// chat completions API. APIs without schema support get plain JSON mode and
+// rely on the prompt and local validation for the schema.
+func chatCompletionsResponseFormat(format *entities.ResponseFormat, schemaSupported bool) map[string]any {
+	if format.Type != entities.ResponseFormatJSONSchema || !schemaSupported {
+		return map[string]any{"type": "json_object"}
+	}
+	return map[string]any{
+		"type": "json_schema",
+		"json_schema": map[string]any{
+			"name":   format.SchemaName(),
+			"schema": format.Schema,
+			"strict": format.Strict,
+		},
+	}
+}
+
+// responsesTextFormat builds the text.format parameter of the responses API.
+func responsesTextFormat(format *entities.ResponseFormat) map[string]any {
+	if format.Type != entities.ResponseFormatJSONSchema {
+		return map[string]any{"type": "json_object"}
+	}
+	return map[string]any{
+		"type":   "json_schema",
+		"name":   format.SchemaName(),
+		"schema": format.Schema,
+		"strict": format.Strict,
+	}
+}
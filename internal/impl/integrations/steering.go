@@ -0,0 +1,38 @@
+package integrations
+
+import (
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/domain/interfaces"
+
+	"go.uber.org/zap"
+)
+
+// steeringNotes takes the notes the user queued through the steering_queue
+// option since the last request and returns them as user messages, saved
+// through callback.
+func steeringNotes(options map[string]any, callback interfaces.MessageCallback, logger *zap.Logger) []*entities.Message {
+	queue, _ := options["steering_queue"].(interfaces.SteeringQueue)
+	if queue == nil {
+		return nil
+	}
+	var notes []*entities.Message
+	for _, text := range queue.Take() {
+		notes = append(notes, entities.NewMessage("user", text))
+	}
+	if len(notes) > 0 {
+		logger.Info("Steering the response with user notes", zap.Int("count", len(notes)))
+		if callback != nil {
+			if err := callback(notes); err != nil {
+				logger.Error("Failed to save steering messages incrementally", zap.Error(err))
+			}
+		}
+	}
+	return notes
+}
+
+// steeringPending reports whether notes are waiting, in which case a response
+// that would end makes another request to take them into account.
+func steeringPending(options map[string]any) bool {
+	queue, _ := options["steering_queue"].(interfaces.SteeringQueue)
+	return queue != nil && queue.Pending()
+}
@@ -0,0 +1,91 @@
+package integrations
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+
+	"go.uber.org/zap"
+)
+
+type noteQueue struct {
+	mu    sync.Mutex
+	notes []string
+}
+
+func (q *noteQueue) push(note string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.notes = append(q.notes, note)
+}
+
+func (q *noteQueue) Pending() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.notes) > 0
+}
+
+func (q *noteQueue) Take() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	notes := q.notes
+	q.notes = nil
+	return notes
+}
+
+func TestGenerateResponse_Steering(t *testing.T) {
+	queue := &noteQueue{}
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		answer := "Switching to Go."
+		if len(bodies) == 1 {
+			// The user steers while the first answer is being written
+			queue.push("Use Go instead")
+			answer = "Done porting it to Rust."
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"choices": [{"finish_reason": "stop", "message": {"content": %q}}], "usage": {"prompt_tokens": 10, "completion_tokens": 5, "total_tokens": 15}}`, answer)
+	}))
+	defer server.Close()
+
+	integration, err := NewAIModelIntegration(server.URL, "test-key", "test-model", &singleToolRepo{}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewAIModelIntegration failed: %v", err)
+	}
+
+	var saved []*entities.Message
+	callback := func(messages []*entities.Message) error {
+		saved = append(saved, messages...)
+		return nil
+	}
+	messages := []*entities.Message{entities.NewMessage("user", "Port the tool")}
+	newMessages, err := integration.GenerateResponse(context.Background(), messages, nil, map[string]any{"steering_queue": queue}, callback)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(bodies) != 2 {
+		t.Fatalf("Expected a second request for the note, got %d requests", len(bodies))
+	}
+	if !strings.Contains(bodies[1], "Use Go instead") || !strings.Contains(bodies[1], "Done porting it to Rust.") {
+		t.Errorf("Expected the second request to carry the answer and the note, got %s", bodies[1])
+	}
+	if len(newMessages) != 3 || newMessages[1].Role != "user" || newMessages[1].Content != "Use Go instead" {
+		t.Fatalf("Expected the note between the answers, got %+v", newMessages)
+	}
+	if len(saved) != 3 || saved[1] != newMessages[1] {
+		t.Error("Expected the note to be saved through the callback")
+	}
+	if queue.Pending() {
+		t.Error("Expected the note to be taken from the queue")
+	}
+}
@@ -0,0 +1,89 @@
+package integrations
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// messageTokenOverhead is the role and separator tokens chat formats add to
+// every message.
+const messageTokenOverhead = 4
+
+// Average characters per token for tokenizers that aren't available locally.
+const (
+	anthropicCharsPerToken = 3.5
+	geminiCharsPerToken    = 4.0
+)
+
+// tiktokenEncodings caches encodings by model name; loading one reads the
+// whole BPE table.
+var tiktokenEncodings sync.Map
+
+// tiktokenEncoding returns the encoding for an OpenAI model, and the gpt-4
+// encoding for models tiktoken doesn't know, such as Llama or Mistral.
+func tiktokenEncoding(model string) (*tiktoken.Tiktoken, error) {
+	// OpenRouter style names carry the vendor, e.g. "openai/gpt-4o"
+	if i := strings.LastIndex(model, "/"); i != -1 {
+		model = model[i+1:]
+	}
+	if enc, ok := tiktokenEncodings.Load(model); ok {
+		return enc.(*tiktoken.Tiktoken), nil
+	}
+
+	enc, err := tiktoken.EncodingForModel(model)
+	if err != nil {
+		if enc, err = tiktoken.EncodingForModel("gpt-4"); err != nil {
+			return nil, err
+		}
+	}
+	tiktokenEncodings.Store(model, enc)
+	return enc, nil
+}
+
+// messageText is the text a message adds to the prompt: its content and the
+// names and arguments of its tool calls.
+func messageText(msg *entities.Message) string {
+	if len(msg.ToolCalls) == 0 {
+		return msg.Content
+	}
+	var sb strings.Builder
+	sb.WriteString(msg.Content)
+	for _, tc := range msg.ToolCalls {
+		sb.WriteString(tc.Function.Name)
+		sb.WriteString(tc.Function.Arguments)
+	}
+	return sb.String()
+}
+
+// countTiktokenTokens counts the prompt tokens of messages with the model's
+// tiktoken encoding.
+func countTiktokenTokens(model string, messages []*entities.Message) (int, error) {
+	enc, err := tiktokenEncoding(model)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, msg := range messages {
+		if msg == nil {
+			continue
+		}
+		total += len(enc.Encode(messageText(msg), nil, nil)) + messageTokenOverhead
+	}
+	return total, nil
+}
+
+// countCharTokens estimates the prompt tokens of messages from their length.
+func countCharTokens(messages []*entities.Message, charsPerToken float64) int {
+	total := 0
+	for _, msg := range messages {
+		if msg == nil {
+			continue
+		}
+		total += int(float64(len(messageText(msg)))/charsPerToken) + messageTokenOverhead
+	}
+	return total
+}
@@ -0,0 +1,51 @@
+package integrations
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+
+	"go.uber.org/zap"
+)
+
+func TestCountCharTokens(t *testing.T) {
+	call := entities.ToolCall{ID: "call_1", Type: "function"}
+	call.Function.Name = "Read"
+	call.Function.Arguments = `{"path":"main.go"}`
+	withCall := &entities.Message{Role: "assistant", ToolCalls: []entities.ToolCall{call}}
+
+	tests := []struct {
+		name     string
+		messages []*entities.Message
+		want     int
+	}{
+		{"empty", nil, 0},
+		{"content", []*entities.Message{entities.NewMessage("user", strings.Repeat("a", 40))}, 10 + messageTokenOverhead},
+		{"nil message", []*entities.Message{nil}, 0},
+		{"tool calls count", []*entities.Message{withCall}, len("Read"+call.Function.Arguments)/4 + messageTokenOverhead},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := countCharTokens(tt.messages, 4); got != tt.want {
+				t.Errorf("countCharTokens() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnthropicCountTokens(t *testing.T) {
+	integration, err := NewAnthropicIntegration("https://api.anthropic.com", "test-key", "claude-sonnet-4", nil, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewAnthropicIntegration failed: %v", err)
+	}
+
+	messages := []*entities.Message{entities.NewMessage("user", strings.Repeat("a", 350))}
+	count, err := integration.CountTokens(messages)
+	if err != nil {
+		t.Fatalf("CountTokens failed: %v", err)
+	}
+	if want := 100 + messageTokenOverhead; count != want {
+		t.Errorf("CountTokens() = %d, want %d", count, want)
+	}
+}
@@ -0,0 +1,150 @@
+package integrations
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+)
+
+// validateToolArguments checks the arguments of a tool call against the
+// tool's JSON schema. Only required properties and the basic JSON types are
+// checked, and the check is lenient about the coercions the tools already
+// handle, such as numbers sent as strings. It returns one problem per
+// offending property, or nil when the arguments look valid.
+func validateToolArguments(schema map[string]any, arguments string) []string {
+	if schema == nil {
+		return nil
+	}
+
+	args := map[string]any{}
+	if strings.TrimSpace(arguments) != "" {
+		if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+			return []string{fmt.Sprintf("arguments are not a valid JSON object: %v", err)}
+		}
+	}
+
+	var problems []string
+	for _, name := range schemaRequired(schema) {
+		if value, ok := args[name]; !ok || value == nil {
+			problems = append(problems, fmt.Sprintf("missing required parameter %q", name))
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := args[name]
+		if value == nil {
+			continue
+		}
+		property, _ := properties[name].(map[string]any)
+		expected, _ := property["type"].(string)
+		if expected == "" || matchesSchemaType(expected, value) {
+			continue
+		}
+		problems = append(problems, fmt.Sprintf("parameter %q should be of type %s, got %s", name, expected, jsonTypeName(value)))
+	}
+
+	return problems
+}
+
+// toolArgumentProblems validates the arguments against the tool's schema. A
+// nil tool has nothing to validate against.
+func toolArgumentProblems(tool entities.Tool, arguments string) []string {
+	if tool == nil {
+		return nil
+	}
+	return validateToolArguments(tool.Schema(), arguments)
+}
+
+// formatArgumentProblems builds the tool result returned to the model so it
+// can correct the call.
+func formatArgumentProblems(toolName string, problems []string) string {
+	return fmt.Sprintf("Tool %s was called with invalid arguments:\n- %s\nPlease correct the arguments and try again.",
+		toolName, strings.Join(problems, "\n- "))
+}
+
+func schemaRequired(schema map[string]any) []string {
+	switch required := schema["required"].(type) {
+	case []string:
+		return required
+	case []any:
+		names := make([]string, 0, len(required))
+		for _, r := range required {
+			if name, ok := r.(string); ok {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+func matchesSchemaType(expected string, value any) bool {
+	switch expected {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		switch v := value.(type) {
+		case float64:
+			return true
+		case string:
+			_, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			return err == nil
+		}
+		return false
+	case "integer":
+		switch v := value.(type) {
+		case float64:
+			return v == math.Trunc(v)
+		case string:
+			_, err := strconv.Atoi(strings.TrimSpace(v))
+			return err == nil
+		}
+		return false
+	case "boolean":
+		switch v := value.(type) {
+		case bool:
+			return true
+		case string:
+			_, err := strconv.ParseBool(strings.TrimSpace(v))
+			return err == nil
+		}
+		return false
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	}
+	// Unknown or composite types are left to the tool.
+	return true
+}
+
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return "null"
+}
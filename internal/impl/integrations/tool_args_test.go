@@ -0,0 +1,77 @@
+package integrations
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateToolArguments(t *testing.T) {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path":      map[string]any{"type": "string"},
+			"limit":     map[string]any{"type": "integer"},
+			"ratio":     map[string]any{"type": "number"},
+			"recursive": map[string]any{"type": "boolean"},
+			"patterns":  map[string]any{"type": "array"},
+			"options":   map[string]any{"type": "object"},
+		},
+		"required": []any{"path"},
+	}
+
+	tests := []struct {
+		name      string
+		arguments string
+		problems  []string
+	}{
+		{"valid", `{"path":"a.go","limit":10,"ratio":0.5,"recursive":true,"patterns":["*.go"],"options":{}}`, nil},
+		{"numeric strings are accepted", `{"path":"a.go","limit":"10","ratio":"0.5","recursive":"false"}`, nil},
+		{"unknown parameters are left to the tool", `{"path":"a.go","extra":1}`, nil},
+		{"missing required", `{"limit":10}`, []string{`missing required parameter "path"`}},
+		{"null required", `{"path":null}`, []string{`missing required parameter "path"`}},
+		{"empty arguments", ``, []string{`missing required parameter "path"`}},
+		{"wrong types", `{"path":1,"limit":1.5,"patterns":"*.go"}`, []string{
+			`parameter "limit" should be of type integer, got number`,
+			`parameter "path" should be of type string, got number`,
+			`parameter "patterns" should be of type array, got string`,
+		}},
+		{"invalid json", `{"path":`, []string{"arguments are not a valid JSON object"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := validateToolArguments(schema, tt.arguments)
+			if len(problems) != len(tt.problems) {
+				t.Fatalf("Expected %d problems, got %v", len(tt.problems), problems)
+			}
+			for i, want := range tt.problems {
+				if !strings.HasPrefix(problems[i], want) {
+					t.Errorf("Expected problem %q, got %q", want, problems[i])
+				}
+			}
+		})
+	}
+
+	if problems := validateToolArguments(nil, `{}`); problems != nil {
+		t.Errorf("Expected no problems without a schema, got %v", problems)
+	}
+}
+
+func TestSplitDiffResult(t *testing.T) {
+	result := `{"success": true, "diff": "+a\n... 9 additional changed lines ...\n", "fullDiff": "+a\n+b\n"}`
+	forModel, diff := splitDiffResult(result)
+	if strings.Contains(forModel, "fullDiff") {
+		t.Errorf("Expected the full diff to be left out for the model, got %s", forModel)
+	}
+	if !strings.Contains(forModel, "additional changed lines") {
+		t.Errorf("Expected the capped diff for the model, got %s", forModel)
+	}
+	if diff != "+a\n+b\n" {
+		t.Errorf("Expected the full diff for display, got %q", diff)
+	}
+
+	plain := `{"success": true, "diff": "+a\n"}`
+	if forModel, diff := splitDiffResult(plain); forModel != plain || diff != "+a\n" {
+		t.Errorf("Expected an uncapped result to pass through, got %s / %q", forModel, diff)
+	}
+}
@@ -0,0 +1,29 @@
+package integrations
+
+import (
+	"time"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/domain/interfaces"
+)
+
+// auditToolCall hands a finished tool call to the auditor in the
+// "tool_auditor" option, when there is one.
+func auditToolCall(options map[string]any, iteration int, toolCall entities.ToolCall, result, toolError string, started time.Time) {
+	auditor, _ := options["tool_auditor"].(interfaces.ToolAuditor)
+	if auditor == nil {
+		return
+	}
+	chatID, _ := options["session_id"].(string)
+	auditor.RecordToolCall(entities.ToolAuditRecord{
+		Time:       started,
+		ChatID:     chatID,
+		Iteration:  iteration,
+		ToolCallID: toolCall.ID,
+		Tool:       toolCall.Function.Name,
+		Arguments:  toolCall.Function.Arguments,
+		Result:     result,
+		Error:      toolError,
+		DurationMs: time.Since(started).Milliseconds(),
+	})
+}
@@ -0,0 +1,150 @@
+package integrations
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+)
+
+// Bounds of the results a run keeps cached; the least recently used go
+// first.
+const (
+	maxCachedToolResults = 64
+	maxCachedToolBytes   = 4 * 1024 * 1024
+)
+
+// toolResultCache keeps the results of a run's read-only calls, so reading
+// the same file or running the same search again in the response is
+// answered without running the tool. A call that may change anything drops
+// the results of the calls that read the paths it touches, or every result
+// when the tool can't tell which paths those are.
+type toolResultCache struct {
+	mu      sync.Mutex
+	entries []cachedToolResult // least recently used first
+	bytes   int
+}
+
+type cachedToolResult struct {
+	fingerprint string
+	paths       []string
+	result      string
+}
+
+func newToolResultCache() *toolResultCache {
+	return &toolResultCache{}
+}
+
+func callCacheable(tool entities.Tool, arguments string) bool {
+	cacheable, ok := tool.(entities.CacheableTool)
+	return ok && cacheable.Cacheable(arguments)
+}
+
+func callPaths(tool entities.Tool, arguments string) []string {
+	if pathTool, ok := tool.(entities.PathTool); ok {
+		return pathTool.Paths(arguments)
+	}
+	return nil
+}
+
+// get returns the cached result of the call.
+func (c *toolResultCache) get(tool entities.Tool, toolName, arguments string) (string, bool) {
+	if c == nil || !callCacheable(tool, arguments) {
+		return "", false
+	}
+	fingerprint := toolCallFingerprint(toolName, arguments)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i := slices.IndexFunc(c.entries, func(entry cachedToolResult) bool {
+		return entry.fingerprint == fingerprint
+	})
+	if i < 0 {
+		return "", false
+	}
+	entry := c.entries[i]
+	c.entries = append(slices.Delete(c.entries, i, i+1), entry)
+	return entry.result, true
+}
+
+// put caches the result of a call that ran successfully, if the tool allows
+// it.
+func (c *toolResultCache) put(tool entities.Tool, toolName, arguments, result string) {
+	if c == nil || !callCacheable(tool, arguments) || len(result) > maxCachedToolBytes {
+		return
+	}
+	fingerprint := toolCallFingerprint(toolName, arguments)
+	paths := callPaths(tool, arguments)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(func(entry cachedToolResult) bool { return entry.fingerprint == fingerprint })
+	c.entries = append(c.entries, cachedToolResult{fingerprint: fingerprint, paths: paths, result: result})
+	c.bytes += len(result)
+	for len(c.entries) > maxCachedToolResults || c.bytes > maxCachedToolBytes {
+		c.bytes -= len(c.entries[0].result)
+		c.entries = c.entries[1:]
+	}
+}
+
+// callMayChange reports whether a call may change what cached calls read.
+// Only cacheable calls and those of tools that say they don't mutate are
+// known not to; anything else, like a sub-agent's run, may change any file.
+func callMayChange(tool entities.Tool, arguments string) bool {
+	if callCacheable(tool, arguments) {
+		return false
+	}
+	if mutating, ok := tool.(entities.MutatingTool); ok {
+		return mutating.Mutates(arguments)
+	}
+	return true
+}
+
+// invalidate drops the results a call that may change anything may have made
+// stale. Calls that only read change nothing.
+func (c *toolResultCache) invalidate(tool entities.Tool, arguments string) {
+	if c == nil || !callMayChange(tool, arguments) {
+		return
+	}
+	changed := callPaths(tool, arguments)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(func(entry cachedToolResult) bool {
+		if len(changed) == 0 {
+			return true
+		}
+		for _, read := range entry.paths {
+			for _, path := range changed {
+				if pathsOverlap(read, path) {
+					return true
+				}
+			}
+		}
+		return false
+	})
+}
+
+// remove drops the entries drop matches. The caller holds the lock.
+func (c *toolResultCache) remove(drop func(cachedToolResult) bool) {
+	c.entries = slices.DeleteFunc(c.entries, func(entry cachedToolResult) bool {
+		if drop(entry) {
+			c.bytes -= len(entry.result)
+			return true
+		}
+		return false
+	})
+}
+
+// pathsOverlap reports whether one path is the other or inside it, as a
+// change to a file affects a listing or search of its directory.
+func pathsOverlap(a, b string) bool {
+	return withinPath(a, b) || withinPath(b, a)
+}
+
+func withinPath(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
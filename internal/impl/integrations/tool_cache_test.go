@@ -0,0 +1,125 @@
+package integrations
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+)
+
+// readTool is cacheable and reads the "path" argument, when there is one.
+type readTool struct {
+	entities.Tool
+}
+
+func (t *readTool) Cacheable(arguments string) bool { return true }
+func (t *readTool) Paths(arguments string) []string { return pathArgument(arguments) }
+
+// editTool mutates the "path" argument, unless counting.
+type editTool struct {
+	writeTool
+}
+
+func (t *editTool) Paths(arguments string) []string { return pathArgument(arguments) }
+
+func pathArgument(arguments string) []string {
+	var args struct {
+		Path string `json:"path"`
+	}
+	if json.Unmarshal([]byte(arguments), &args) != nil || args.Path == "" {
+		return nil
+	}
+	return []string{args.Path}
+}
+
+func TestToolResultCache_Get(t *testing.T) {
+	cache := newToolResultCache()
+	cache.put(&readTool{}, "Read", `{"path":"/ws/a.go","limit":10}`, "package a")
+
+	if result, ok := cache.get(&readTool{}, "Read", `{"limit": 10, "path": "/ws/a.go"}`); !ok || result != "package a" {
+		t.Errorf("Expected the same read to be cached, got %q, %v", result, ok)
+	}
+	if _, ok := cache.get(&readTool{}, "Read", `{"path":"/ws/a.go","limit":20}`); ok {
+		t.Error("Expected other arguments to miss")
+	}
+
+	cache.put(&writeTool{}, "Edit", `{"operation":"count"}`, "3")
+	if _, ok := cache.get(&writeTool{}, "Edit", `{"operation":"count"}`); ok {
+		t.Error("Expected a tool that isn't cacheable not to be cached")
+	}
+	var none *toolResultCache
+	none.put(&readTool{}, "Read", `{}`, "x")
+	if _, ok := none.get(&readTool{}, "Read", `{}`); ok {
+		t.Error("Expected nothing to be cached without a cache")
+	}
+}
+
+func TestToolResultCache_Invalidate(t *testing.T) {
+	cache := newToolResultCache()
+	cache.put(&readTool{}, "Read", `{"path":"/ws/a.go"}`, "a")
+	cache.put(&readTool{}, "Read", `{"path":"/ws/b.go"}`, "b")
+	cache.put(&readTool{}, "Grep", `{"path":"/ws"}`, "matches")
+	cache.put(&readTool{}, "WebSearch", `{"query":"go"}`, "results")
+	cached := func(toolName, arguments string) bool {
+		_, ok := cache.get(&readTool{}, toolName, arguments)
+		return ok
+	}
+
+	cache.invalidate(&editTool{}, `{"operation":"count","path":"/ws/a.go"}`)
+	if !cached("Read", `{"path":"/ws/a.go"}`) {
+		t.Error("Expected a call that only reads to change nothing")
+	}
+
+	cache.invalidate(&editTool{}, `{"operation":"replace","path":"/ws/a.go"}`)
+	if cached("Read", `{"path":"/ws/a.go"}`) {
+		t.Error("Expected the edited file's read to be dropped")
+	}
+	if cached("Grep", `{"path":"/ws"}`) {
+		t.Error("Expected the search of its directory to be dropped")
+	}
+	if !cached("Read", `{"path":"/ws/b.go"}`) || !cached("WebSearch", `{"query":"go"}`) {
+		t.Error("Expected results the edit didn't touch to be kept")
+	}
+
+	cache.invalidate(&writeTool{}, `{"command":"make"}`)
+	if cached("Read", `{"path":"/ws/b.go"}`) || cached("WebSearch", `{"query":"go"}`) {
+		t.Error("Expected a change to unknown paths to drop every result")
+	}
+}
+
+func TestToolResultCache_InvalidateUndeclaredTool(t *testing.T) {
+	cache := newToolResultCache()
+	cache.put(&readTool{}, "Read", `{"path":"/ws/a.go"}`, "a")
+
+	// A tool that doesn't say whether it mutates, like Agent, may change anything
+	cache.invalidate(struct{ entities.Tool }{}, `{"prompt":"fix the build"}`)
+	if _, ok := cache.get(&readTool{}, "Read", `{"path":"/ws/a.go"}`); ok {
+		t.Error("Expected a call of an undeclared tool to drop every result")
+	}
+}
+
+func TestToolResultCache_Bounds(t *testing.T) {
+	cache := newToolResultCache()
+	for i := 0; i < maxCachedToolResults; i++ {
+		cache.put(&readTool{}, "Read", fmt.Sprintf(`{"path":"/ws/%d.go"}`, i), "x")
+	}
+	// Using the oldest result makes the second the least recently used
+	cache.get(&readTool{}, "Read", `{"path":"/ws/0.go"}`)
+	cache.put(&readTool{}, "Read", `{"path":"/ws/last.go"}`, "x")
+
+	if _, ok := cache.get(&readTool{}, "Read", `{"path":"/ws/0.go"}`); !ok {
+		t.Error("Expected a recently used result to be kept")
+	}
+	if _, ok := cache.get(&readTool{}, "Read", `{"path":"/ws/1.go"}`); ok {
+		t.Error("Expected the least recently used result to be dropped")
+	}
+	if len(cache.entries) != maxCachedToolResults {
+		t.Errorf("Expected %d results, got %d", maxCachedToolResults, len(cache.entries))
+	}
+
+	cache.put(&readTool{}, "Read", `{"path":"/ws/big.go"}`, string(make([]byte, maxCachedToolBytes)))
+	if cache.bytes > maxCachedToolBytes {
+		t.Errorf("Expected at most %d bytes, got %d", maxCachedToolBytes, cache.bytes)
+	}
+}
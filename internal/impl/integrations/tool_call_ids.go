@@ -0,0 +1,116 @@
+package integrations
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+
+	"github.com/google/uuid"
+)
+
+var (
+	anthropicToolCallID     = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+	alphanumeric9ToolCallID = regexp.MustCompile(`^[a-zA-Z0-9]{9}$`)
+)
+
+const toolCallIDCharset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// toolCallIDFormatted is implemented by integrations whose tool call ID
+// format can be overridden by the provider configuration.
+type toolCallIDFormatted interface {
+	setToolCallIDFormat(format entities.ToolCallIDFormat)
+}
+
+func (m *AIModelIntegration) setToolCallIDFormat(format entities.ToolCallIDFormat) {
+	m.toolCallIDFormat = format
+}
+
+// validToolCallID reports whether the provider accepts id as it is.
+func validToolCallID(format entities.ToolCallIDFormat, id string) bool {
+	switch format {
+	case entities.ToolCallIDAnthropic:
+		return anthropicToolCallID.MatchString(id)
+	case entities.ToolCallIDAlphanumeric9:
+		return alphanumeric9ToolCallID.MatchString(id)
+	default:
+		return id != ""
+	}
+}
+
+// syntheticToolCallID derives an ID in the provider's format from seed. The
+// same seed always gives the same ID, so a history maps the same way on
+// every request and prompt caches keep matching.
+func syntheticToolCallID(format entities.ToolCallIDFormat, seed string) string {
+	sum := sha256.Sum256([]byte(seed))
+	if format == entities.ToolCallIDAlphanumeric9 {
+		b := make([]byte, 9)
+		for i := range b {
+			b[i] = toolCallIDCharset[int(sum[i])%len(toolCallIDCharset)]
+		}
+		return string(b)
+	}
+	return "call_" + hex.EncodeToString(sum[:12])
+}
+
+// newToolCallID fills in the ID of a tool call the provider returned
+// without one.
+func newToolCallID(format entities.ToolCallIDFormat) string {
+	return syntheticToolCallID(format, uuid.New().String())
+}
+
+// normalizeToolCallIDs maps the tool call IDs in messages to the provider's
+// format. A chat's history may hold IDs from other providers, or none at all
+// from providers that omit them; each is replaced by a synthetic ID, and the
+// tool results answering it get the same one so no call is left orphaned.
+// Tool results without an ID answer the unanswered calls without one in
+// order. Messages that need no change are passed through; others are copied
+// so the stored history keeps its original IDs.
+func normalizeToolCallIDs(messages []*entities.Message, format entities.ToolCallIDFormat) []*entities.Message {
+	mapped := make(map[string]string)
+	var unnamed []string
+	result := make([]*entities.Message, len(messages))
+	for i, msg := range messages {
+		result[i] = msg
+		switch {
+		case msg.Role == "assistant" && len(msg.ToolCalls) > 0:
+			var toolCalls []entities.ToolCall
+			for j, tc := range msg.ToolCalls {
+				if validToolCallID(format, tc.ID) {
+					continue
+				}
+				if toolCalls == nil {
+					toolCalls = append([]entities.ToolCall(nil), msg.ToolCalls...)
+				}
+				if tc.ID == "" {
+					id := syntheticToolCallID(format, fmt.Sprintf("%s:%d:%d", msg.ID, i, j))
+					unnamed = append(unnamed, id)
+					toolCalls[j].ID = id
+					continue
+				}
+				if _, ok := mapped[tc.ID]; !ok {
+					mapped[tc.ID] = syntheticToolCallID(format, tc.ID)
+				}
+				toolCalls[j].ID = mapped[tc.ID]
+			}
+			if toolCalls != nil {
+				copied := *msg
+				copied.ToolCalls = toolCalls
+				result[i] = &copied
+			}
+		case msg.Role == "tool":
+			id, ok := mapped[msg.ToolCallID]
+			if !ok && msg.ToolCallID == "" && len(unnamed) > 0 {
+				id, unnamed, ok = unnamed[0], unnamed[1:], true
+			}
+			if ok {
+				copied := *msg
+				copied.ToolCallID = id
+				result[i] = &copied
+			}
+		}
+	}
+	return result
+}
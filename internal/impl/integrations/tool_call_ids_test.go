@@ -0,0 +1,68 @@
+package integrations
+
+import (
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+)
+
+func toolCallExchange(callID, resultID string) []*entities.Message {
+	return []*entities.Message{
+		{ID: "1", Role: "user", Content: "list files"},
+		{ID: "2", Role: "assistant", ToolCalls: []entities.ToolCall{{ID: callID, Type: "function"}}},
+		{ID: "3", Role: "tool", ToolCallID: resultID, Content: "a.go"},
+	}
+}
+
+func TestNormalizeToolCallIDs_MapsToFormat(t *testing.T) {
+	history := toolCallExchange("call_abc.123", "call_abc.123")
+
+	for _, format := range []entities.ToolCallIDFormat{entities.ToolCallIDAnthropic, entities.ToolCallIDAlphanumeric9} {
+		normalized := normalizeToolCallIDs(history, format)
+		callID := normalized[1].ToolCalls[0].ID
+		if !validToolCallID(format, callID) {
+			t.Errorf("%s: ID %q does not match the format", format, callID)
+		}
+		if normalized[2].ToolCallID != callID {
+			t.Errorf("%s: result ID %q does not match call ID %q", format, normalized[2].ToolCallID, callID)
+		}
+		if again := normalizeToolCallIDs(history, format); again[1].ToolCalls[0].ID != callID {
+			t.Errorf("%s: expected the same ID on every request", format)
+		}
+	}
+
+	if history[1].ToolCalls[0].ID != "call_abc.123" || history[2].ToolCallID != "call_abc.123" {
+		t.Error("Expected the original messages to keep their IDs")
+	}
+}
+
+func TestNormalizeToolCallIDs_FillsMissing(t *testing.T) {
+	normalized := normalizeToolCallIDs(toolCallExchange("", ""), entities.ToolCallIDPassthrough)
+
+	callID := normalized[1].ToolCalls[0].ID
+	if callID == "" {
+		t.Fatal("Expected a synthetic ID for the call")
+	}
+	if normalized[2].ToolCallID != callID {
+		t.Errorf("Expected the result to answer %q, got %q", callID, normalized[2].ToolCallID)
+	}
+}
+
+func TestNormalizeToolCallIDs_KeepsValid(t *testing.T) {
+	history := toolCallExchange("toolu_01A", "toolu_01A")
+	normalized := normalizeToolCallIDs(history, entities.ToolCallIDAnthropic)
+	for i := range history {
+		if normalized[i] != history[i] {
+			t.Errorf("Expected message %d to be passed through", i)
+		}
+	}
+}
+
+func TestNewToolCallID(t *testing.T) {
+	if id := newToolCallID(entities.ToolCallIDAlphanumeric9); !validToolCallID(entities.ToolCallIDAlphanumeric9, id) {
+		t.Errorf("Expected a nine character ID, got %q", id)
+	}
+	if newToolCallID("") == newToolCallID("") {
+		t.Error("Expected new IDs to differ")
+	}
+}
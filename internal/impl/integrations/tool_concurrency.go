@@ -0,0 +1,75 @@
+package integrations
+
+import (
+	"fmt"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/domain/interfaces"
+)
+
+// DefaultMaxToolConcurrency bounds how many tool calls of a batch run at once
+// when the global configuration sets no limit.
+const DefaultMaxToolConcurrency = 4
+
+// toolConcurrency is the max_tool_concurrency option, or the default when it
+// is not set.
+func toolConcurrency(options map[string]any) int {
+	if limit, ok := options["max_tool_concurrency"].(int); ok && limit > 0 {
+		return limit
+	}
+	return DefaultMaxToolConcurrency
+}
+
+// toolCallDependencies returns, for each call of a batch, the earlier calls it
+// waits for before running. Read-only calls run alongside each other; a call
+// that mutates waits for every earlier call, and later calls wait for it, so
+// changes run one at a time in the order the model gave them. That also makes
+// a repeated edit wait for the one it repeats, so history can tell.
+func toolCallDependencies(toolCalls []entities.ToolCall, toolRepo interfaces.ToolRepository) [][]int {
+	waits := make([][]int, len(toolCalls))
+	lastMutating := -1
+	for i, toolCall := range toolCalls {
+		tool, err := toolRepo.GetToolByName(toolCall.Function.Name)
+		if err == nil && tool != nil && callMutates(tool, toolCall.Function.Arguments) {
+			for j := lastMutating + 1; j < i; j++ {
+				waits[i] = append(waits[i], j)
+			}
+			if lastMutating >= 0 {
+				waits[i] = append(waits[i], lastMutating)
+			}
+			lastMutating = i
+		} else if lastMutating >= 0 {
+			waits[i] = []int{lastMutating}
+		}
+	}
+	return waits
+}
+
+// runToolRepository answers lookups with the tools a run was given, so calls
+// execute the copies configured for the chat rather than the shared
+// instances. Tools the run was not given come from the shared repository.
+type runToolRepository struct {
+	interfaces.ToolRepository
+	tools map[string]entities.Tool
+}
+
+// runTools returns a repository for a run of toolList over toolRepo.
+func runTools(toolRepo interfaces.ToolRepository, toolList []entities.Tool) interfaces.ToolRepository {
+	tools := make(map[string]entities.Tool, len(toolList))
+	for _, tool := range toolList {
+		if tool != nil {
+			tools[tool.Name()] = tool
+		}
+	}
+	return &runToolRepository{ToolRepository: toolRepo, tools: tools}
+}
+
+func (r *runToolRepository) GetToolByName(name string) (entities.Tool, error) {
+	if tool, ok := r.tools[name]; ok {
+		return tool, nil
+	}
+	if r.ToolRepository == nil {
+		return nil, fmt.Errorf("tool %s not found", name)
+	}
+	return r.ToolRepository.GetToolByName(name)
+}
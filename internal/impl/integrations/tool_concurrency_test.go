@@ -0,0 +1,101 @@
+package integrations
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/domain/interfaces"
+
+	"go.uber.org/zap"
+)
+
+// sleepTool takes a moment to run and tracks how many calls overlap. Its
+// "write" calls mutate.
+type sleepTool struct {
+	entities.Tool
+	mu       sync.Mutex
+	running  int
+	peak     int
+	writing  atomic.Bool
+	overlaps atomic.Int32
+}
+
+func (t *sleepTool) Name() string                     { return "Sleep" }
+func (t *sleepTool) Schema() map[string]any           { return map[string]any{"type": "object"} }
+func (t *sleepTool) Configuration() map[string]string { return nil }
+func (t *sleepTool) Mutates(arguments string) bool    { return arguments == `{"op":"write"}` }
+func (t *sleepTool) Execute(ctx context.Context, arguments string) (string, error) {
+	t.mu.Lock()
+	t.running++
+	t.peak = max(t.peak, t.running)
+	t.mu.Unlock()
+
+	writing := t.Mutates(arguments)
+	if writing && t.writing.Swap(true) {
+		t.overlaps.Add(1)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if writing {
+		t.writing.Store(false)
+	}
+
+	t.mu.Lock()
+	t.running--
+	t.mu.Unlock()
+	return "ok", nil
+}
+
+type sleepToolRepo struct {
+	interfaces.ToolRepository
+	tool *sleepTool
+}
+
+func (r *sleepToolRepo) GetToolByName(name string) (entities.Tool, error) {
+	if name != "Sleep" {
+		return nil, fmt.Errorf("tool %s not found", name)
+	}
+	return r.tool, nil
+}
+
+func sleepCalls(ops ...string) []entities.ToolCall {
+	calls := make([]entities.ToolCall, len(ops))
+	for i, op := range ops {
+		calls[i] = entities.ToolCall{ID: fmt.Sprintf("call_%d", i), Type: "function"}
+		calls[i].Function.Name = "Sleep"
+		calls[i].Function.Arguments = fmt.Sprintf(`{"op":%q}`, op)
+	}
+	return calls
+}
+
+func TestToolCallDependencies(t *testing.T) {
+	repo := &sleepToolRepo{tool: &sleepTool{}}
+	waits := toolCallDependencies(sleepCalls("read", "read", "write", "read", "write"), repo)
+	want := "[[] [] [0 1] [2] [3 2]]"
+	if got := fmt.Sprint(waits); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestExecuteToolsParallel_Concurrency(t *testing.T) {
+	tool := &sleepTool{}
+	calls := sleepCalls("read", "read", "read", "write", "read", "read", "write", "write")
+	options := map[string]any{"max_tool_concurrency": 2}
+
+	results := executeToolsParallel(context.Background(), calls, &sleepToolRepo{tool: tool}, options, 1, nil, nil, zap.NewNop())
+	for i, result := range results {
+		if result.ToolCall.ID != calls[i].ID || result.ToolResult != "ok" {
+			t.Errorf("Result %d out of order or failed: %+v", i, result)
+		}
+	}
+	if tool.peak != 2 {
+		t.Errorf("Expected at most 2 calls at once and some overlap, got a peak of %d", tool.peak)
+	}
+	if n := tool.overlaps.Load(); n != 0 {
+		t.Errorf("Expected writes to run one at a time, got %d overlaps", n)
+	}
+}
@@ -0,0 +1,96 @@
+package integrations
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/domain/interfaces"
+
+	"go.uber.org/zap"
+)
+
+// DefaultMaxConsecutiveFailures is how many tool calls in a row may fail
+// before a response is stopped, when the agent sets no limit.
+const DefaultMaxConsecutiveFailures = 3
+
+// lookupMisses mark the errors of a read-only lookup that found nothing, such
+// as a search without matches or a file that isn't there. They are an
+// answer rather than a malfunction.
+var lookupMisses = []string{
+	"not found",
+	"no matches",
+	"no such file",
+	"does not exist",
+}
+
+// lookupMiss reports whether a failed call is a read-only lookup that found
+// nothing. The same words from a call that changes something, like an edit
+// whose old text is not found, are a genuine failure.
+func lookupMiss(tool entities.Tool, arguments, toolError string) bool {
+	if tool == nil || callMayChange(tool, arguments) {
+		return false
+	}
+	lower := strings.ToLower(toolError)
+	for _, miss := range lookupMisses {
+		if strings.Contains(lower, miss) {
+			return true
+		}
+	}
+	return false
+}
+
+// toolFailureCount stops a response once too many tool calls in a row fail,
+// instead of letting the model retry a broken tool until it runs out of
+// turns.
+type toolFailureCount struct {
+	limit int
+	count int
+	last  toolExecResult
+}
+
+// newToolFailureCount reads the limit from the max_consecutive_failures
+// option.
+func newToolFailureCount(options map[string]any) *toolFailureCount {
+	limit := DefaultMaxConsecutiveFailures
+	if n, ok := options["max_consecutive_failures"].(int); ok && n > 0 {
+		limit = n
+	}
+	return &toolFailureCount{limit: limit}
+}
+
+// observe counts the failures of a batch of tool calls, in order, after the
+// assistant message that asked for them. A successful call or an assistant
+// message with text starts the count over; benign failures, like a lookup
+// that found nothing or a call waiting for approval, are not counted.
+// Once the limit is reached it returns the message that ends the response,
+// saved through callback.
+func (c *toolFailureCount) observe(text string, results []toolExecResult, callback interfaces.MessageCallback, logger *zap.Logger) *entities.Message {
+	if strings.TrimSpace(text) != "" {
+		c.count = 0
+	}
+	for _, r := range results {
+		switch {
+		case r.ToolError == "":
+			c.count = 0
+		case !r.Benign:
+			c.count++
+			c.last = r
+		}
+	}
+	if c.count < c.limit {
+		return nil
+	}
+
+	logger.Warn("Stopping after consecutive tool failures",
+		zap.Int("failures", c.count),
+		zap.String("toolName", c.last.ToolName),
+		zap.String("error", c.last.ToolError))
+	stop := entities.NewMessage("assistant", fmt.Sprintf("Stopped: %d tool calls in a row failed. The last was %s: %s. Check the tool's configuration, or ask me to try a different approach.", c.count, c.last.ToolName, c.last.ToolError))
+	if callback != nil {
+		if err := callback([]*entities.Message{stop}); err != nil {
+			logger.Error("Failed to save stop message incrementally", zap.Error(err))
+		}
+	}
+	return stop
+}
@@ -0,0 +1,70 @@
+package integrations
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+
+	"go.uber.org/zap"
+)
+
+func TestLookupMiss(t *testing.T) {
+	tests := []struct {
+		name      string
+		tool      entities.Tool
+		arguments string
+		toolError string
+		want      bool
+	}{
+		{"missing file", &readTool{}, `{"path":"a.go"}`, "file not found: a.go", true},
+		{"no matches", &readTool{}, `{"path":"."}`, "No matches for pattern", true},
+		{"no such file", &readTool{}, `{"path":"x"}`, "open x: no such file or directory", true},
+		{"read failure", &readTool{}, `{"path":"x"}`, "permission denied", false},
+		{"edit text not found", &editTool{}, `{"operation":"replace","path":"a.go"}`, "oldString not found in file", false},
+		{"undeclared tool", struct{ entities.Tool }{}, `{}`, "directory does not exist", false},
+		{"unknown tool", nil, `{}`, "Tool not found", false},
+	}
+	for _, tt := range tests {
+		if got := lookupMiss(tt.tool, tt.arguments, tt.toolError); got != tt.want {
+			t.Errorf("%s: lookupMiss(%q) = %v, want %v", tt.name, tt.toolError, got, tt.want)
+		}
+	}
+}
+
+func TestToolFailureCount(t *testing.T) {
+	failed := toolExecResult{ToolName: "Bash", ToolError: "exit status 1"}
+	missing := toolExecResult{ToolName: "FileRead", ToolError: "file not found", Benign: true}
+	ok := toolExecResult{ToolName: "FileRead"}
+
+	var saved []*entities.Message
+	callback := func(messages []*entities.Message) error {
+		saved = append(saved, messages...)
+		return nil
+	}
+
+	c := newToolFailureCount(map[string]any{"max_consecutive_failures": 2})
+	if stop := c.observe("", []toolExecResult{failed, ok}, callback, zap.NewNop()); stop != nil {
+		t.Fatal("expected a success to start the count over")
+	}
+	if stop := c.observe("", []toolExecResult{failed, missing}, callback, zap.NewNop()); stop != nil {
+		t.Fatal("expected benign failures not to count")
+	}
+	if stop := c.observe("Trying again.", []toolExecResult{failed}, callback, zap.NewNop()); stop != nil {
+		t.Fatal("expected assistant text to start the count over")
+	}
+	stop := c.observe("", []toolExecResult{failed}, callback, zap.NewNop())
+	if stop == nil {
+		t.Fatal("expected the response to stop at the limit")
+	}
+	if stop.Role != "assistant" || !strings.Contains(stop.Content, "Bash: exit status 1") {
+		t.Errorf("unexpected stop message: %s: %s", stop.Role, stop.Content)
+	}
+	if len(saved) != 1 || saved[0] != stop {
+		t.Errorf("expected the stop message to be saved, got %d messages", len(saved))
+	}
+
+	if c := newToolFailureCount(nil); c.limit != DefaultMaxConsecutiveFailures {
+		t.Errorf("expected default limit %d, got %d", DefaultMaxConsecutiveFailures, c.limit)
+	}
+}
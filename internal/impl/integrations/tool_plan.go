@@ -0,0 +1,24 @@
+package integrations
+
+import (
+	"fmt"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/domain/interfaces"
+)
+
+// planToolCall hands a mutating tool call to the plan mode planner instead of
+// running it. It returns the result to give the model and whether the call
+// was held back; read-only tools and chats without a planner run as usual.
+func planToolCall(options map[string]any, tool entities.Tool, toolName, arguments string) (string, bool) {
+	planner, _ := options["tool_planner"].(interfaces.ToolPlanner)
+	if planner == nil {
+		return "", false
+	}
+	mutating, ok := tool.(entities.MutatingTool)
+	if !ok || !mutating.Mutates(arguments) {
+		return "", false
+	}
+	step := planner.Plan(toolName, arguments)
+	return fmt.Sprintf("Plan mode: %s was not run. It was added to the plan as step %d and will run once the user approves the plan. Continue planning as if it succeeded.", toolName, step), true
+}
@@ -0,0 +1,52 @@
+package integrations
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+)
+
+// writeTool mutates unless asked to count.
+type writeTool struct {
+	entities.Tool
+}
+
+func (t *writeTool) Mutates(arguments string) bool {
+	return !strings.Contains(arguments, "count")
+}
+
+type recordingPlanner struct {
+	calls []string
+}
+
+func (p *recordingPlanner) Plan(toolName, arguments string) int {
+	p.calls = append(p.calls, toolName+" "+arguments)
+	return len(p.calls)
+}
+
+func TestPlanToolCall(t *testing.T) {
+	planner := &recordingPlanner{}
+	options := map[string]any{"tool_planner": planner}
+
+	result, planned := planToolCall(options, &writeTool{}, "Write", `{"operation":"replace"}`)
+	if !planned {
+		t.Fatal("Expected the mutating call to be planned")
+	}
+	if !strings.Contains(result, "step 1") {
+		t.Errorf("Expected the result to name the step, got %q", result)
+	}
+
+	if _, planned := planToolCall(options, &writeTool{}, "Write", `{"operation":"count"}`); planned {
+		t.Error("Expected a read-only call to run")
+	}
+	if _, planned := planToolCall(options, &slowTool{}, "Slow", `{}`); planned {
+		t.Error("Expected a tool without Mutates to run")
+	}
+	if _, planned := planToolCall(map[string]any{}, &writeTool{}, "Write", `{}`); planned {
+		t.Error("Expected calls to run without a planner")
+	}
+	if len(planner.calls) != 1 {
+		t.Errorf("Expected 1 planned call, got %d", len(planner.calls))
+	}
+}
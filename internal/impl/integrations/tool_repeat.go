@@ -0,0 +1,111 @@
+package integrations
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+)
+
+// toolCallWindow is how many of a run's latest tool calls are remembered.
+const toolCallWindow = 8
+
+// maxRepeatedResult caps the earlier result quoted back for a repeated call.
+const maxRepeatedResult = 2000
+
+// toolCallHistory remembers the latest tool calls of a run, so a file edit
+// the model repeats, typically because it retries an edit it already made,
+// is answered with the earlier result instead of being applied again. It
+// also holds the run's cached results of read-only calls.
+type toolCallHistory struct {
+	mu      sync.Mutex
+	recent  []toolCallRecord // oldest first
+	results *toolResultCache
+}
+
+type toolCallRecord struct {
+	fingerprint string
+	mutating    bool
+	result      string
+}
+
+func newToolCallHistory() *toolCallHistory {
+	return &toolCallHistory{results: newToolResultCache()}
+}
+
+// cache returns the run's tool result cache, or nil without a history.
+func (h *toolCallHistory) cache() *toolResultCache {
+	if h == nil {
+		return nil
+	}
+	return h.results
+}
+
+// toolCallFingerprint identifies a call by tool name and arguments, ignoring
+// the key order and spacing of the arguments.
+func toolCallFingerprint(toolName, arguments string) string {
+	var args any
+	if err := json.Unmarshal([]byte(arguments), &args); err == nil {
+		if normalized, err := json.Marshal(args); err == nil {
+			arguments = string(normalized)
+		}
+	}
+	return toolName + "\x00" + arguments
+}
+
+// skipsRepeats reports whether a repeat of the call is answered from the
+// history. Only file edits are; running a command or fetching a page again
+// can give a different answer.
+func skipsRepeats(tool entities.Tool, toolName, arguments string) bool {
+	return (toolName == "Write" || toolName == "Edit") && callMutates(tool, arguments)
+}
+
+func callMutates(tool entities.Tool, arguments string) bool {
+	mutating, ok := tool.(entities.MutatingTool)
+	return ok && mutating.Mutates(arguments)
+}
+
+// repeated returns the note to give the model when the call is a file edit
+// that already succeeded in this run with no other change made since. Read
+// only calls in between do not count as changes.
+func (h *toolCallHistory) repeated(tool entities.Tool, toolName, arguments string) (string, bool) {
+	if h == nil || !skipsRepeats(tool, toolName, arguments) {
+		return "", false
+	}
+	fingerprint := toolCallFingerprint(toolName, arguments)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i := len(h.recent) - 1; i >= 0; i-- {
+		record := h.recent[i]
+		if record.fingerprint == fingerprint {
+			result := record.result
+			if len(result) > maxRepeatedResult {
+				result = result[:maxRepeatedResult] + "... (truncated)"
+			}
+			return fmt.Sprintf("Repeated call skipped: %s was already run with these arguments in this response and succeeded, so it was not applied again. Its result was:\n%s\nDo not repeat it; continue with the next step.", toolName, result), true
+		}
+		if record.mutating {
+			break
+		}
+	}
+	return "", false
+}
+
+// record remembers a call that ran successfully.
+func (h *toolCallHistory) record(tool entities.Tool, toolName, arguments, result string) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.recent = append(h.recent, toolCallRecord{
+		fingerprint: toolCallFingerprint(toolName, arguments),
+		mutating:    callMutates(tool, arguments),
+		result:      result,
+	})
+	if len(h.recent) > toolCallWindow {
+		h.recent = h.recent[len(h.recent)-toolCallWindow:]
+	}
+}
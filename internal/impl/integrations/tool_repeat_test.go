@@ -0,0 +1,57 @@
+package integrations
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToolCallHistory_Repeated(t *testing.T) {
+	history := newToolCallHistory()
+	edit := `{"operation":"replace","path":"main.go","old":"a","new":"b"}`
+	reordered := `{"path": "main.go", "operation": "replace", "new": "b", "old": "a"}`
+
+	if _, ok := history.repeated(&writeTool{}, "Edit", edit); ok {
+		t.Fatal("Expected the first call to run")
+	}
+	history.record(&writeTool{}, "Edit", edit, `{"diff":"-a\n+b"}`)
+
+	note, ok := history.repeated(&writeTool{}, "Edit", reordered)
+	if !ok {
+		t.Fatal("Expected the same edit to be skipped")
+	}
+	if !strings.Contains(note, "already run") || !strings.Contains(note, "+b") {
+		t.Errorf("Expected the note to quote the earlier result, got %q", note)
+	}
+
+	// Reading in between is not a change
+	history.record(&writeTool{}, "Edit", `{"operation":"count"}`, "3")
+	if _, ok := history.repeated(&writeTool{}, "Edit", edit); !ok {
+		t.Error("Expected the edit to still be skipped after a read")
+	}
+
+	// Another edit may have undone it
+	history.record(&writeTool{}, "Edit", `{"operation":"replace","path":"main.go","old":"b","new":"a"}`, "{}")
+	if _, ok := history.repeated(&writeTool{}, "Edit", edit); ok {
+		t.Error("Expected the edit to run again after another change")
+	}
+
+	if _, ok := history.repeated(&writeTool{}, "Bash", edit); ok {
+		t.Error("Expected only file edits to be skipped")
+	}
+	var none *toolCallHistory
+	if _, ok := none.repeated(&writeTool{}, "Edit", edit); ok {
+		t.Error("Expected nothing to be skipped without a history")
+	}
+}
+
+func TestToolCallHistory_Window(t *testing.T) {
+	history := newToolCallHistory()
+	edit := `{"operation":"replace"}`
+	history.record(&writeTool{}, "Write", edit, "{}")
+	for i := 0; i < toolCallWindow; i++ {
+		history.record(&writeTool{}, "Write", `{"operation":"count"}`, "1")
+	}
+	if _, ok := history.repeated(&writeTool{}, "Write", edit); ok {
+		t.Error("Expected calls older than the window to be forgotten")
+	}
+}
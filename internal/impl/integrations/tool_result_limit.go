@@ -0,0 +1,81 @@
+package integrations
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+)
+
+// DefaultMaxToolResultTokens bounds a tool result sent to the model when the
+// global configuration sets no limit.
+const DefaultMaxToolResultTokens = 20000
+
+// maxBytesPerToken bounds how many bytes a token covers, so a result is only
+// tokenized up to the part that could fit.
+const maxBytesPerToken = 16
+
+// tokenCounter counts tokens with the model's tokenizer or estimate.
+type tokenCounter interface {
+	CountTokens(messages []*entities.Message) (int, error)
+}
+
+// toolResultTokenLimit is the max_tool_result_tokens option, or the default
+// when it is not set.
+func toolResultTokenLimit(options map[string]any) int {
+	if limit, ok := options["max_tool_result_tokens"].(int); ok && limit > 0 {
+		return limit
+	}
+	return DefaultMaxToolResultTokens
+}
+
+// textTokens counts the tokens of text, estimating from its length when the
+// tokenizer fails.
+func textTokens(counter tokenCounter, text string) int {
+	if counter != nil {
+		if count, err := counter.CountTokens([]*entities.Message{{Content: text}}); err == nil {
+			return max(count-messageTokenOverhead, 0)
+		}
+	}
+	return len(text) / 4
+}
+
+// limitToolResult cuts a result longer than limit tokens after the last whole
+// line that fits, or inside the first line when even that is too long, and
+// notes how much was left out so the model can ask for less.
+func limitToolResult(result string, limit int, counter tokenCounter) string {
+	if limit <= 0 || len(result) <= limit {
+		return result
+	}
+	total := textTokens(counter, result)
+	if total <= limit {
+		return result
+	}
+
+	// Candidate cuts are the ends of lines within reach of the limit
+	reach := min(len(result), limit*maxBytesPerToken)
+	var cuts []int
+	for i := 0; i < reach; i++ {
+		if result[i] == '\n' {
+			cuts = append(cuts, i)
+		}
+	}
+	fits := func(end int) bool { return textTokens(counter, result[:end]) <= limit }
+
+	end := 0
+	if n := sort.Search(len(cuts), func(i int) bool { return !fits(cuts[i]) }); n > 0 {
+		end = cuts[n-1]
+	} else {
+		end = sort.Search(reach, func(i int) bool { return !fits(i + 1) })
+		for end > 0 && !utf8.RuneStart(result[end]) {
+			end--
+		}
+	}
+
+	shown := result[:end]
+	lines := strings.Count(result, "\n") + 1
+	return fmt.Sprintf("%s\n\n[Tool result truncated: showing %d of %d lines, about %d of %d tokens. Narrow the request, e.g. read a range of lines or filter the output, to see the rest, or raise max_tool_result_tokens in ~/.aiagent/aiagent.json.]",
+		shown, strings.Count(shown, "\n")+1, lines, textTokens(counter, shown), total)
+}
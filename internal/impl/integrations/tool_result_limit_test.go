@@ -0,0 +1,48 @@
+package integrations
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+)
+
+// wordCounter counts a token per word.
+type wordCounter struct{}
+
+func (wordCounter) CountTokens(messages []*entities.Message) (int, error) {
+	total := 0
+	for _, msg := range messages {
+		total += len(strings.Fields(msg.Content)) + messageTokenOverhead
+	}
+	return total, nil
+}
+
+func TestLimitToolResult(t *testing.T) {
+	short := "one two\nthree"
+	if got := limitToolResult(short, 10, wordCounter{}); got != short {
+		t.Errorf("Expected a short result unchanged, got %q", got)
+	}
+
+	long := "a b c\nd e f\ng h i\nj k l"
+	got := limitToolResult(long, 7, wordCounter{})
+	if !strings.HasPrefix(got, "a b c\nd e f\n\n[Tool result truncated: showing 2 of 4 lines, about 6 of 12 tokens.") {
+		t.Errorf("Expected the result cut after the second line, got %q", got)
+	}
+
+	oneLine := strings.Repeat("word ", 50)
+	got = limitToolResult(oneLine, 10, wordCounter{})
+	shown, _, _ := strings.Cut(got, "\n\n[Tool result truncated")
+	if words := len(strings.Fields(shown)); words == 0 || words > 10 {
+		t.Errorf("Expected a long line cut to at most 10 words, got %d in %q", words, got)
+	}
+}
+
+func TestToolResultTokenLimit(t *testing.T) {
+	if got := toolResultTokenLimit(map[string]any{}); got != DefaultMaxToolResultTokens {
+		t.Errorf("Expected the default limit, got %d", got)
+	}
+	if got := toolResultTokenLimit(map[string]any{"max_tool_result_tokens": 500}); got != 500 {
+		t.Errorf("Expected the configured limit, got %d", got)
+	}
+}
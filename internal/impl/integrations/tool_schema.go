@@ -0,0 +1,108 @@
+package integrations
+
+import (
+	"slices"
+	"sort"
+)
+
+// strictToolSchemaSetter is implemented by integrations whose function
+// definitions can use strict schemas, as set in the provider configuration.
+type strictToolSchemaSetter interface {
+	setStrictToolSchemas(strict bool)
+}
+
+func (m *AIModelIntegration) setStrictToolSchemas(strict bool) {
+	m.strictToolSchemas = strict
+}
+
+// toolParameters is a tool's parameter schema as sent to the model. A strict
+// schema closes every object and requires every property, making the
+// optional ones nullable, as OpenAI's strict mode demands. Otherwise
+// additionalProperties: false is left out, since some OpenAI-compatible
+// servers reject it. The tool's own schema is not changed.
+func toolParameters(schema map[string]any, strict bool) map[string]any {
+	if strict {
+		return strictSchema(schema)
+	}
+	return looseSchema(schema)
+}
+
+// strictSchema returns a copy of schema in the form strict mode accepts.
+func strictSchema(schema map[string]any) map[string]any {
+	result := make(map[string]any, len(schema)+2)
+	for key, value := range schema {
+		result[key] = value
+	}
+
+	if properties, ok := schema["properties"].(map[string]any); ok {
+		required := schemaRequired(schema)
+		names := make([]string, 0, len(properties))
+		strictProperties := make(map[string]any, len(properties))
+		for name, property := range properties {
+			names = append(names, name)
+			propertySchema, ok := property.(map[string]any)
+			if !ok {
+				strictProperties[name] = property
+				continue
+			}
+			propertySchema = strictSchema(propertySchema)
+			if !slices.Contains(required, name) {
+				propertySchema = nullableSchema(propertySchema)
+			}
+			strictProperties[name] = propertySchema
+		}
+		sort.Strings(names)
+		result["properties"] = strictProperties
+		result["required"] = names
+		result["additionalProperties"] = false
+	}
+	if items, ok := schema["items"].(map[string]any); ok {
+		result["items"] = strictSchema(items)
+	}
+	return result
+}
+
+// nullableSchema lets an optional property be null, which is how strict mode
+// expresses leaving it out.
+func nullableSchema(schema map[string]any) map[string]any {
+	switch typ := schema["type"].(type) {
+	case string:
+		schema["type"] = []any{typ, "null"}
+	case []any:
+		if !slices.Contains(typ, any("null")) {
+			schema["type"] = append(slices.Clone(typ), "null")
+		}
+	default:
+		return map[string]any{"anyOf": []any{schema, map[string]any{"type": "null"}}}
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, nil) {
+		schema["enum"] = append(slices.Clone(enum), nil)
+	}
+	return schema
+}
+
+// looseSchema returns a copy of schema without additionalProperties: false.
+func looseSchema(schema map[string]any) map[string]any {
+	result := make(map[string]any, len(schema))
+	for key, value := range schema {
+		if key == "additionalProperties" && value == false {
+			continue
+		}
+		result[key] = value
+	}
+
+	if properties, ok := schema["properties"].(map[string]any); ok {
+		looseProperties := make(map[string]any, len(properties))
+		for name, property := range properties {
+			if propertySchema, ok := property.(map[string]any); ok {
+				property = looseSchema(propertySchema)
+			}
+			looseProperties[name] = property
+		}
+		result["properties"] = looseProperties
+	}
+	if items, ok := schema["items"].(map[string]any); ok {
+		result["items"] = looseSchema(items)
+	}
+	return result
+}
@@ -0,0 +1,78 @@
+package integrations
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func parseSchema(t *testing.T, text string) map[string]any {
+	t.Helper()
+	var schema map[string]any
+	if err := json.Unmarshal([]byte(text), &schema); err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+	return schema
+}
+
+const fileToolSchema = `{
+	"type": "object",
+	"properties": {
+		"path": {"type": "string"},
+		"mode": {"type": "string", "enum": ["read", "write"]},
+		"lines": {"type": "array", "items": {"type": "object", "properties": {"start": {"type": "integer"}}}},
+		"meta": {"description": "anything"}
+	},
+	"required": ["path"],
+	"additionalProperties": false
+}`
+
+func TestToolParameters_Strict(t *testing.T) {
+	schema := parseSchema(t, fileToolSchema)
+	want := parseSchema(t, `{
+		"type": "object",
+		"properties": {
+			"path": {"type": "string"},
+			"mode": {"type": ["string", "null"], "enum": ["read", "write", null]},
+			"lines": {"type": ["array", "null"], "items": {
+				"type": "object",
+				"properties": {"start": {"type": ["integer", "null"]}},
+				"required": ["start"],
+				"additionalProperties": false
+			}},
+			"meta": {"anyOf": [{"description": "anything"}, {"type": "null"}]}
+		},
+		"required": ["lines", "meta", "mode", "path"],
+		"additionalProperties": false
+	}`)
+
+	// Round trip so []string and []any compare alike
+	data, _ := json.Marshal(toolParameters(schema, true))
+	got := parseSchema(t, string(data))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected strict schema:\n%s", data)
+	}
+	if !reflect.DeepEqual(schema, parseSchema(t, fileToolSchema)) {
+		t.Error("Expected the tool's schema to be left alone")
+	}
+}
+
+func TestToolParameters_Loose(t *testing.T) {
+	schema := parseSchema(t, fileToolSchema)
+	schema["properties"].(map[string]any)["lines"].(map[string]any)["items"].(map[string]any)["additionalProperties"] = false
+
+	got := toolParameters(schema, false)
+	if _, ok := got["additionalProperties"]; ok {
+		t.Error("Expected additionalProperties to be left out")
+	}
+	items := got["properties"].(map[string]any)["lines"].(map[string]any)["items"].(map[string]any)
+	if _, ok := items["additionalProperties"]; ok {
+		t.Error("Expected nested additionalProperties to be left out")
+	}
+	if !reflect.DeepEqual(got["required"], []any{"path"}) {
+		t.Errorf("Expected required to be unchanged, got %v", got["required"])
+	}
+	if _, ok := schema["additionalProperties"]; !ok {
+		t.Error("Expected the tool's schema to be left alone")
+	}
+}
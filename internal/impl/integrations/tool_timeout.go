@@ -0,0 +1,55 @@
+package integrations
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+)
+
+// toolTimeout reads the timeout in seconds from the tool's tool_timeout
+// setting. Without one the tool runs as long as the turn does, as tools like
+// Agent, Bash and TestRunner bound their own work.
+func toolTimeout(tool entities.Tool) time.Duration {
+	seconds, err := strconv.Atoi(tool.Configuration()["tool_timeout"])
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// executeTool runs a tool within its timeout. The tool's context is canceled
+// when the timeout passes so tools that watch it stop their work; one that
+// does not is left behind and the call fails with an error the model can act
+// on instead of blocking the chat.
+func executeTool(ctx context.Context, tool entities.Tool, args string) (string, error) {
+	timeout := toolTimeout(tool)
+	if timeout <= 0 {
+		return tool.Execute(ctx, args)
+	}
+
+	toolCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type outcome struct {
+		result string
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := tool.Execute(toolCtx, args)
+		done <- outcome{result, err}
+	}()
+
+	select {
+	case out := <-done:
+		return out.result, out.err
+	case <-toolCtx.Done():
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("timed out after %s and was stopped; narrow the request (a smaller path, fewer results) or raise the tool's tool_timeout setting", timeout)
+	}
+}
@@ -0,0 +1,76 @@
+package integrations
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+)
+
+// slowTool blocks until its context is done or it is released.
+type slowTool struct {
+	entities.Tool
+	config  map[string]string
+	release chan struct{}
+}
+
+func (t *slowTool) Configuration() map[string]string { return t.config }
+func (t *slowTool) Execute(ctx context.Context, arguments string) (string, error) {
+	select {
+	case <-t.release:
+		return "done", nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func TestToolTimeout(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"30", 30 * time.Second},
+		{"0", 0},
+		{"soon", 0},
+	}
+	for _, tt := range tests {
+		tool := &slowTool{config: map[string]string{"tool_timeout": tt.value}}
+		if got := toolTimeout(tool); got != tt.want {
+			t.Errorf("toolTimeout(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestExecuteTool_TimesOut(t *testing.T) {
+	tool := &slowTool{config: map[string]string{"tool_timeout": "1"}, release: make(chan struct{})}
+
+	start := time.Now()
+	_, err := executeTool(context.Background(), tool, "{}")
+	if err == nil || !strings.Contains(err.Error(), "timed out after 1s") {
+		t.Fatalf("Expected a timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the call to return soon after the timeout, took %v", elapsed)
+	}
+}
+
+func TestExecuteTool_Completes(t *testing.T) {
+	tool := &slowTool{config: map[string]string{"tool_timeout": "10"}, release: make(chan struct{})}
+	close(tool.release)
+
+	result, err := executeTool(context.Background(), tool, "{}")
+	if err != nil || result != "done" {
+		t.Errorf("Expected the tool result, got %q, %v", result, err)
+	}
+}
+
+func TestToolTimeout_IgnoresToolTimeoutSettings(t *testing.T) {
+	// TestRunner and Fetch read their own timeout setting
+	tool := &slowTool{config: map[string]string{"timeout": "5"}}
+	if got := toolTimeout(tool); got != 0 {
+		t.Errorf("Expected the tool's own timeout setting to be left alone, got %v", got)
+	}
+}
@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/impl/redact"
+
+	"go.uber.org/zap"
+)
+
+// maxAuditResultBytes caps the tool result kept in an audit record.
+const maxAuditResultBytes = 4096
+
+// DefaultAuditLogMaxBytes is the size an audit log grows to before it is
+// rotated.
+const DefaultAuditLogMaxBytes = 10 * 1024 * 1024
+
+// AuditLog writes tool calls as JSON lines to a file per chat in dir. A file
+// that would grow past maxBytes is moved to <chat>.jsonl.1, replacing the
+// previous one.
+type AuditLog struct {
+	dir      string
+	maxBytes int64
+	logger   *zap.Logger
+	mu       sync.Mutex
+}
+
+func NewAuditLog(dir string, maxBytes int64, logger *zap.Logger) *AuditLog {
+	if maxBytes <= 0 {
+		maxBytes = DefaultAuditLogMaxBytes
+	}
+	return &AuditLog{dir: dir, maxBytes: maxBytes, logger: logger}
+}
+
+// Path returns the audit log file of a chat.
+func (a *AuditLog) Path(chatID string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, chatID)
+	if name == "" {
+		name = "unknown"
+	}
+	return filepath.Join(a.dir, "tools-"+name+".jsonl")
+}
+
+// RecordToolCall appends the record to the chat's audit log, with the
+// credentials in its arguments, result and error masked. Failures are logged
+// and never interrupt the tool call.
+func (a *AuditLog) RecordToolCall(record entities.ToolAuditRecord) {
+	record.Arguments = redact.ToolArguments(record.Arguments)
+	record.Result = redact.Secrets(record.Result)
+	record.Error = redact.Secrets(record.Error)
+	if len(record.Result) > maxAuditResultBytes {
+		record.Result = record.Result[:maxAuditResultBytes]
+		record.Truncated = true
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		a.logger.Warn("Failed to encode tool audit record", zap.Error(err))
+		return
+	}
+	line = append(line, '\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.write(a.Path(record.ChatID), line); err != nil {
+		a.logger.Warn("Failed to write tool audit log", zap.String("chat_id", record.ChatID), zap.Error(err))
+	}
+}
+
+func (a *AuditLog) write(path string, line []byte) error {
+	if err := os.MkdirAll(a.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create audit log directory: %v", err)
+	}
+	if info, err := os.Stat(path); err == nil && info.Size() > 0 && info.Size()+int64(len(line)) > a.maxBytes {
+		if err := os.Rename(path, path+".1"); err != nil {
+			return fmt.Errorf("failed to rotate audit log: %v", err)
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(line)
+	return err
+}
@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+
+	"go.uber.org/zap"
+)
+
+func readAuditRecords(t *testing.T, path string) []entities.ToolAuditRecord {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open %s: %v", path, err)
+	}
+	defer f.Close()
+
+	var records []entities.ToolAuditRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		var record entities.ToolAuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("invalid audit line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestAuditLog(t *testing.T) {
+	audit := NewAuditLog(t.TempDir(), 0, zap.NewNop())
+	audit.RecordToolCall(entities.ToolAuditRecord{Time: time.Now(), ChatID: "chat-1", Iteration: 1, Tool: "Bash", Arguments: `{"command":"ls"}`, Result: "main.go"})
+	audit.RecordToolCall(entities.ToolAuditRecord{Time: time.Now(), ChatID: "chat-1", Iteration: 2, Tool: "Read", Result: strings.Repeat("x", maxAuditResultBytes+1), Error: "boom"})
+	audit.RecordToolCall(entities.ToolAuditRecord{Time: time.Now(), ChatID: "../chat-2", Tool: "Bash"})
+
+	records := readAuditRecords(t, audit.Path("chat-1"))
+	if len(records) != 2 || records[0].Tool != "Bash" || records[0].Arguments != `{"command":"ls"}` || records[1].Iteration != 2 {
+		t.Fatalf("unexpected records: %+v", records)
+	}
+	if !records[1].Truncated || len(records[1].Result) != maxAuditResultBytes || records[1].Error != "boom" {
+		t.Errorf("expected the long result to be truncated, got %d bytes", len(records[1].Result))
+	}
+	if path := audit.Path("../chat-2"); strings.Contains(strings.TrimPrefix(path, audit.dir), "..") {
+		t.Errorf("expected the chat ID to be kept inside the log directory, got %s", path)
+	}
+}
+
+func TestAuditLogRotation(t *testing.T) {
+	audit := NewAuditLog(t.TempDir(), 300, zap.NewNop())
+	for i := 0; i < 5; i++ {
+		audit.RecordToolCall(entities.ToolAuditRecord{ChatID: "chat", Iteration: i, Tool: "Bash", Result: strings.Repeat("x", 100)})
+	}
+
+	current := readAuditRecords(t, audit.Path("chat"))
+	rotated := readAuditRecords(t, audit.Path("chat")+".1")
+	if len(current) == 0 || len(rotated) == 0 {
+		t.Fatalf("expected the log to be rotated, got %d and %d records", len(current), len(rotated))
+	}
+	if last := current[len(current)-1]; last.Iteration != 4 {
+		t.Errorf("expected the latest record in the current file, got %+v", last)
+	}
+	if info, _ := os.Stat(audit.Path("chat")); info.Size() > 300 {
+		t.Errorf("expected the current file to stay under the limit, got %d bytes", info.Size())
+	}
+}
+
+func TestAuditLogRedactsSecrets(t *testing.T) {
+	audit := NewAuditLog(t.TempDir(), 0, zap.NewNop())
+	audit.RecordToolCall(entities.ToolAuditRecord{
+		ChatID:    "chat",
+		Tool:      "Fetch",
+		Arguments: `{"url":"https://example.com","headers":{"X-Api-Key":"header-secret"},"auth":{"type":"bearer","token":"token-secret"}}`,
+		Result:    "echo: Bearer result-secret",
+		Error:     "denied for Bearer error-secret",
+	})
+
+	records := readAuditRecords(t, audit.Path("chat"))
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	}
+	for _, secret := range []string{"header-secret", "token-secret", "result-secret", "error-secret"} {
+		if line := records[0].Arguments + records[0].Result + records[0].Error; strings.Contains(line, secret) {
+			t.Errorf("Expected %s to be redacted, got %+v", secret, records[0])
+		}
+	}
+	if !strings.Contains(records[0].Arguments, `"url":"https://example.com"`) {
+		t.Errorf("Expected the other arguments to be kept, got %s", records[0].Arguments)
+	}
+}
@@ -0,0 +1,150 @@
+package logging
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Entry is a decoded log line with its fields as written by zap.
+type Entry map[string]any
+
+// Field names used across the code base for the same value.
+var (
+	chatIDKeys = []string{"chat_id", "chatID", "session_id"}
+	toolKeys   = []string{"toolName", "tool", "tool_name"}
+)
+
+// DefaultQueryLimit caps the entries returned when the filter sets no limit.
+const DefaultQueryLimit = 500
+
+// maxLineSize is the longest log line read; longer lines are skipped.
+const maxLineSize = 1024 * 1024
+
+// NewLogger builds the logger from config and, when logFile is set, also
+// writes JSON lines to it so they can be queried later.
+func NewLogger(config zap.Config, logFile string) (*zap.Logger, error) {
+	logger, err := config.Build()
+	if err != nil || logFile == "" {
+		return logger, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(logFile), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %v", err)
+	}
+	sink, _, err := zap.Open(logFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %v", err)
+	}
+
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.EncodeTime = zapcore.RFC3339NanoTimeEncoder
+	fileCore := zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), sink, config.Level)
+
+	return logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(core, fileCore)
+	})), nil
+}
+
+// Filter selects log entries. Empty fields match everything; Level is the
+// minimum level returned.
+type Filter struct {
+	Level  string
+	ChatID string
+	Tool   string
+	From   time.Time
+	To     time.Time
+	Limit  int
+}
+
+// Query reads the JSON log file and returns the most recent entries that
+// match the filter, oldest first. Lines that are not JSON are skipped.
+func Query(logFile string, filter Filter) ([]Entry, error) {
+	minLevel := zapcore.DebugLevel
+	if filter.Level != "" {
+		level, err := zapcore.ParseLevel(filter.Level)
+		if err != nil {
+			return nil, fmt.Errorf("invalid level: %s", filter.Level)
+		}
+		minLevel = level
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultQueryLimit
+	}
+
+	f, err := os.Open(logFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %v", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if !filter.matches(entry, minLevel) {
+			continue
+		}
+		entries = append(entries, entry)
+		if len(entries) > limit {
+			entries = entries[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read log file: %v", err)
+	}
+	return entries, nil
+}
+
+func (f Filter) matches(entry Entry, minLevel zapcore.Level) bool {
+	if levelName, _ := entry["level"].(string); levelName != "" {
+		if level, err := zapcore.ParseLevel(levelName); err == nil && level < minLevel {
+			return false
+		}
+	}
+	if f.ChatID != "" && !entry.has(chatIDKeys, f.ChatID) {
+		return false
+	}
+	if f.Tool != "" && !entry.has(toolKeys, f.Tool) {
+		return false
+	}
+	if !f.From.IsZero() || !f.To.IsZero() {
+		ts, ok := entry.time()
+		if !ok || (!f.From.IsZero() && ts.Before(f.From)) || (!f.To.IsZero() && ts.After(f.To)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e Entry) has(keys []string, value string) bool {
+	for _, key := range keys {
+		if v, ok := e[key].(string); ok && v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (e Entry) time() (time.Time, bool) {
+	switch ts := e["ts"].(type) {
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, ts)
+		return t, err == nil
+	case float64:
+		sec := int64(ts)
+		return time.Unix(sec, int64((ts-float64(sec))*1e9)), true
+	}
+	return time.Time{}, false
+}
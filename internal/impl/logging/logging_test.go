@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestQuery(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "aiagent.log")
+
+	config := zap.NewDevelopmentConfig()
+	config.OutputPaths = []string{os.DevNull}
+	config.ErrorOutputPaths = []string{os.DevNull}
+	logger, err := NewLogger(config, logFile)
+	if err != nil {
+		t.Fatalf("NewLogger failed: %v", err)
+	}
+	start := time.Now()
+	logger.Debug("Starting")
+	logger.Info("Tool executed", zap.String("chat_id", "chat-1"), zap.String("toolName", "Bash"))
+	logger.Warn("Tool execution failed", zap.String("chat_id", "chat-2"), zap.String("toolName", "Read"))
+	logger.Error("Request failed", zap.String("chatID", "chat-1"))
+	logger.Sync()
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"all", Filter{}, []string{"Starting", "Tool executed", "Tool execution failed", "Request failed"}},
+		{"level", Filter{Level: "warn"}, []string{"Tool execution failed", "Request failed"}},
+		{"chat", Filter{ChatID: "chat-1"}, []string{"Tool executed", "Request failed"}},
+		{"tool", Filter{Tool: "Read"}, []string{"Tool execution failed"}},
+		{"limit keeps the latest", Filter{Limit: 1}, []string{"Request failed"}},
+		{"time range", Filter{From: start.Add(-time.Minute), To: start.Add(time.Minute), Level: "error"}, []string{"Request failed"}},
+		{"future", Filter{From: start.Add(time.Hour)}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := Query(logFile, tt.filter)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			if len(entries) != len(tt.want) {
+				t.Fatalf("got %d entries, want %d: %v", len(entries), len(tt.want), entries)
+			}
+			for i, entry := range entries {
+				if entry["msg"] != tt.want[i] {
+					t.Errorf("entry %d = %v, want %q", i, entry["msg"], tt.want[i])
+				}
+			}
+		})
+	}
+
+	if _, err := Query(logFile, Filter{Level: "loud"}); err == nil {
+		t.Error("expected an error for an invalid level")
+	}
+}
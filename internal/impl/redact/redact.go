@@ -0,0 +1,120 @@
+// Package redact masks credentials in text bound for logs, errors and the
+// tool audit log.
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Mask replaces a redacted value.
+const Mask = "[REDACTED]"
+
+// DefaultHeaders are the headers whose values are never written to a log.
+var DefaultHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+var secretPatterns = []*regexp.Regexp{
+	// Authorization header values
+	regexp.MustCompile(`(?i)\b(bearer\s+)[A-Za-z0-9._~+/-]+=*`),
+	regexp.MustCompile(`(?i)(authorization:\s*basic\s+)[A-Za-z0-9+/]+=*`),
+	// Keys passed in a query string, as Gemini does
+	regexp.MustCompile(`([?&](?:api_?)?key=)[^&\s"']+`),
+	// OpenAI, Anthropic and OpenRouter style keys
+	regexp.MustCompile(`()\bsk-[A-Za-z0-9_-]{20,}`),
+	// Google API keys
+	regexp.MustCompile(`()\bAIza[0-9A-Za-z_-]{35}`),
+}
+
+// secretArguments are the tool argument names whose string values are masked
+// wherever they appear, such as the token and password of Fetch's auth.
+var secretArguments = map[string]bool{
+	"token":         true,
+	"password":      true,
+	"secret":        true,
+	"api_key":       true,
+	"apikey":        true,
+	"auth_token":    true,
+	"access_token":  true,
+	"client_secret": true,
+}
+
+// Values masks the given secret values, as is or query escaped, in text.
+func Values(text string, secrets ...string) string {
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		text = strings.ReplaceAll(text, secret, Mask)
+		if escaped := url.QueryEscape(secret); escaped != secret {
+			text = strings.ReplaceAll(text, escaped, Mask)
+		}
+	}
+	return text
+}
+
+// Secrets masks the given secret values, and anything shaped like a bearer
+// token, basic credentials or provider API key, in text.
+func Secrets(text string, secrets ...string) string {
+	text = Values(text, secrets...)
+	for _, pattern := range secretPatterns {
+		text = pattern.ReplaceAllString(text, "${1}"+Mask)
+	}
+	return text
+}
+
+// ToolArguments masks the credentials in a tool call's JSON arguments: the
+// values of secret fields such as auth's token and password, those of the
+// DefaultHeaders in a headers object, and whatever Secrets finds in the rest,
+// such as a token on a Bash command line. Arguments that aren't JSON are only
+// run through Secrets.
+func ToolArguments(arguments string) string {
+	var args any
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return Secrets(arguments)
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(redactValue(args, "")); err != nil {
+		return Secrets(arguments)
+	}
+	return Secrets(strings.TrimSuffix(buf.String(), "\n"))
+}
+
+// redactValue masks the secret fields of value, the field named key.
+func redactValue(value any, key string) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for name, item := range v {
+			_, isString := item.(string)
+			switch {
+			case isString && strings.EqualFold(key, "headers") && isRedactedHeader(name):
+				v[name] = Mask
+			case isString && secretArguments[strings.ToLower(name)]:
+				v[name] = Mask
+			default:
+				v[name] = redactValue(item, name)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = redactValue(item, key)
+		}
+	}
+	return value
+}
+
+func isRedactedHeader(name string) bool {
+	name = http.CanonicalHeaderKey(name)
+	for _, header := range DefaultHeaders {
+		if http.CanonicalHeaderKey(header) == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package redact
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSecrets(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"known value", `{"error": "invalid key my-secret-value"}`, `{"error": "invalid key [REDACTED]"}`},
+		{"escaped value", "key=my%2Bsecret", "key=[REDACTED]"},
+		{"bearer token", "Authorization: Bearer abc.def-123", "Authorization: Bearer [REDACTED]"},
+		{"basic credentials", "Authorization: Basic dXNlcjpwYXNz", "Authorization: Basic [REDACTED]"},
+		{"query key", "https://example.com/v1beta/models?key=AIzaXYZ&pageSize=10", "https://example.com/v1beta/models?key=[REDACTED]&pageSize=10"},
+		{"openai key", "Incorrect API key provided: sk-proj-abcdefghijklmnopqrstuvwxyz", "Incorrect API key provided: [REDACTED]"},
+		{"google key", "bad key AIza" + strings.Repeat("x", 35), "bad key [REDACTED]"},
+		{"plain text", "context length exceeded", "context length exceeded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Secrets(tt.text, "my-secret-value", "my+secret", ""); got != tt.want {
+				t.Errorf("Secrets() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToolArguments(t *testing.T) {
+	tests := []struct {
+		name      string
+		arguments string
+		want      string
+	}{
+		{
+			"fetch auth and headers",
+			`{"url":"https://example.com/a?b=1&c=2","headers":{"authorization":"Token abc","Accept":"application/json"},"auth":{"type":"basic","username":"user","password":"pass"}}`,
+			`{"auth":{"password":"[REDACTED]","type":"basic","username":"user"},"headers":{"Accept":"application/json","authorization":"[REDACTED]"},"url":"https://example.com/a?b=1&c=2"}`,
+		},
+		{
+			"swagger auth",
+			`{"operation":"execute","auth":{"type":"bearer","token":"own-token"}}`,
+			`{"auth":{"token":"[REDACTED]","type":"bearer"},"operation":"execute"}`,
+		},
+		{
+			"bash command",
+			`{"command":"curl -H 'Authorization: Bearer abc123' https://example.com"}`,
+			`{"command":"curl -H 'Authorization: Bearer [REDACTED]' https://example.com"}`,
+		},
+		{"not json", `curl -H "Authorization: Bearer abc123"`, `curl -H "Authorization: Bearer [REDACTED]"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToolArguments(tt.arguments); got != tt.want {
+				t.Errorf("ToolArguments() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,9 +3,11 @@ package repositories_json
 import (
 	"context"
 	"encoding/json"
+	"maps"
 	"os"
 	"path/filepath"
 	"slices"
+	"sync"
 	"time"
 
 	"github.com/drujensen/aiagent/internal/domain/entities"
@@ -16,7 +18,10 @@ import (
 )
 
 type JsonAgentRepository struct {
+	mu       sync.RWMutex
 	filePath string
+	lock     *fileLock
+	version  fileVersion
 	data     []*entities.Agent
 }
 
@@ -24,6 +29,7 @@ func NewJSONAgentRepository(storageDir string) (interfaces.AgentRepository, erro
 	filePath := filepath.Join(storageDir, "agents.json")
 	repo := &JsonAgentRepository{
 		filePath: filePath,
+		lock:     newFileLock(filePath),
 		data:     []*entities.Agent{},
 	}
 
@@ -35,6 +41,7 @@ func NewJSONAgentRepository(storageDir string) (interfaces.AgentRepository, erro
 }
 
 func (r *JsonAgentRepository) load() error {
+	r.version = statVersion(r.filePath)
 	data, err := os.ReadFile(r.filePath)
 	if os.IsNotExist(err) {
 		return nil // File doesn't exist yet, start with empty data
@@ -68,42 +75,75 @@ func (r *JsonAgentRepository) save() error {
 		return errors.InternalErrorf("failed to marshal agents: %v", err)
 	}
 
-	if err := os.MkdirAll(filepath.Dir(r.filePath), 0755); err != nil {
-		return errors.InternalErrorf("failed to create directory: %v", err)
-	}
-
-	if err := os.WriteFile(r.filePath, data, 0644); err != nil {
+	if err := writeFileAtomic(r.filePath, data); err != nil {
 		return errors.InternalErrorf("failed to write agents.json: %v", err)
 	}
+	r.version = statVersion(r.filePath)
 
 	return nil
 }
 
 func (r *JsonAgentRepository) ListAgents(ctx context.Context) ([]*entities.Agent, error) {
+	if err := r.refresh(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	agentsCopy := make([]*entities.Agent, len(r.data))
 	for i, a := range r.data {
 		agentsCopy[i] = &entities.Agent{
-			ID:           a.ID,
-			Name:         a.Name,
-			SystemPrompt: a.SystemPrompt,
-			Tools:        slices.Clone(a.Tools),
-			CreatedAt:    a.CreatedAt,
-			UpdatedAt:    a.UpdatedAt,
+			ID:                     a.ID,
+			Name:                   a.Name,
+			SystemPrompt:           a.SystemPrompt,
+			Tools:                  slices.Clone(a.Tools),
+			CreatedAt:              a.CreatedAt,
+			UpdatedAt:              a.UpdatedAt,
+			ReasoningStats:         cloneReasoningStats(a.ReasoningStats),
+			ResponseFormat:         a.ResponseFormat,
+			Fallbacks:              slices.Clone(a.Fallbacks),
+			OutputLimit:            a.OutputLimit,
+			PlanMode:               a.PlanMode,
+			VerifyLoop:             a.VerifyLoop,
+			Compression:            a.Compression,
+			SystemPromptPrefix:     a.SystemPromptPrefix,
+			SystemPromptSuffix:     a.SystemPromptSuffix,
+			MaxConsecutiveFailures: a.MaxConsecutiveFailures,
+			Env:                    maps.Clone(a.Env),
+			CompletionWebhook:      a.CompletionWebhook,
 		}
 	}
 	return agentsCopy, nil
 }
 
 func (r *JsonAgentRepository) GetAgent(ctx context.Context, id string) (*entities.Agent, error) {
+	if err := r.refresh(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	for _, agent := range r.data {
 		if agent.ID == id {
 			return &entities.Agent{
-				ID:           agent.ID,
-				Name:         agent.Name,
-				SystemPrompt: agent.SystemPrompt,
-				Tools:        slices.Clone(agent.Tools),
-				CreatedAt:    agent.CreatedAt,
-				UpdatedAt:    agent.UpdatedAt,
+				ID:                     agent.ID,
+				Name:                   agent.Name,
+				SystemPrompt:           agent.SystemPrompt,
+				Tools:                  slices.Clone(agent.Tools),
+				CreatedAt:              agent.CreatedAt,
+				UpdatedAt:              agent.UpdatedAt,
+				ReasoningStats:         cloneReasoningStats(agent.ReasoningStats),
+				ResponseFormat:         agent.ResponseFormat,
+				Fallbacks:              slices.Clone(agent.Fallbacks),
+				OutputLimit:            agent.OutputLimit,
+				PlanMode:               agent.PlanMode,
+				VerifyLoop:             agent.VerifyLoop,
+				Compression:            agent.Compression,
+				SystemPromptPrefix:     agent.SystemPromptPrefix,
+				SystemPromptSuffix:     agent.SystemPromptSuffix,
+				MaxConsecutiveFailures: agent.MaxConsecutiveFailures,
+				Env:                    maps.Clone(agent.Env),
+				CompletionWebhook:      agent.CompletionWebhook,
 			}, nil
 		}
 	}
@@ -117,29 +157,80 @@ func (r *JsonAgentRepository) CreateAgent(ctx context.Context, agent *entities.A
 	agent.CreatedAt = time.Now()
 	agent.UpdatedAt = agent.CreatedAt
 
-	r.data = append(r.data, agent)
-	return r.save()
+	return r.update(func() error {
+		r.data = append(r.data, agent)
+		return nil
+	})
 }
 
 func (r *JsonAgentRepository) UpdateAgent(ctx context.Context, agent *entities.Agent) error {
-	for i, a := range r.data {
-		if a.ID == agent.ID {
-			agent.UpdatedAt = time.Now()
-			r.data[i] = agent
-			return r.save()
+	return r.update(func() error {
+		for i, a := range r.data {
+			if a.ID == agent.ID {
+				agent.UpdatedAt = time.Now()
+				r.data[i] = agent
+				return nil
+			}
 		}
-	}
-	return errors.NotFoundErrorf("agent not found: %s", agent.ID)
+		return errors.NotFoundErrorf("agent not found: %s", agent.ID)
+	})
 }
 
 func (r *JsonAgentRepository) DeleteAgent(ctx context.Context, id string) error {
-	for i, a := range r.data {
-		if a.ID == id {
-			r.data = slices.Delete(r.data, i, i+1)
-			return r.save()
+	return r.update(func() error {
+		for i, a := range r.data {
+			if a.ID == id {
+				r.data = slices.Delete(r.data, i, i+1)
+				return nil
+			}
 		}
+		return errors.NotFoundErrorf("agent not found: %s", id)
+	})
+}
+
+// refresh reloads the agents when another process has saved them since
+// they were last read.
+func (r *JsonAgentRepository) refresh() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if statVersion(r.filePath).matches(r.version) {
+		return nil
+	}
+	return r.load()
+}
+
+// update applies change to the agents reloaded from disk and saves them
+// while holding the lock file, so processes sharing the storage directory do
+// not overwrite each other's changes.
+func (r *JsonAgentRepository) update(change func() error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	unlock, err := r.lock.Lock()
+	if err != nil {
+		return errors.InternalErrorf("failed to lock agents.json: %v", err)
+	}
+	defer unlock()
+
+	if err := r.load(); err != nil {
+		return err
+	}
+	if err := change(); err != nil {
+		return err
+	}
+	return r.save()
+}
+
+func cloneReasoningStats(stats map[string]*entities.ReasoningEffortStats) map[string]*entities.ReasoningEffortStats {
+	if stats == nil {
+		return nil
+	}
+	cloned := make(map[string]*entities.ReasoningEffortStats, len(stats))
+	for effort, s := range stats {
+		statsCopy := *s
+		cloned[effort] = &statsCopy
 	}
-	return errors.NotFoundErrorf("agent not found: %s", id)
+	return cloned
 }
 
 var _ interfaces.AgentRepository = (*JsonAgentRepository)(nil)
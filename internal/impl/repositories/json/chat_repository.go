@@ -5,7 +5,10 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/drujensen/aiagent/internal/domain/entities"
@@ -15,29 +18,54 @@ import (
 	"github.com/google/uuid"
 )
 
+// JsonChatRepository keeps each chat in its own file under the chats
+// directory, so saving a chat during a run rewrites only that chat rather
+// than the whole history.
 type JsonChatRepository struct {
-	filePath string
-	data     map[string]*entities.Chat
+	mu         sync.RWMutex
+	dir        string
+	legacyPath string
+	lock       *fileLock
+	versions   map[string]fileVersion
+	data       map[string]*entities.Chat
 }
 
 func NewJSONChatRepository(storageDir string) (interfaces.ChatRepository, error) {
-	filePath := filepath.Join(storageDir, "chats.json")
+	dir := filepath.Join(storageDir, "chats")
 	repo := &JsonChatRepository{
-		filePath: filePath,
-		data:     make(map[string]*entities.Chat),
+		dir:        dir,
+		legacyPath: filepath.Join(storageDir, "chats.json"),
+		lock:       newFileLock(dir),
+		versions:   make(map[string]fileVersion),
+		data:       make(map[string]*entities.Chat),
 	}
 
-	if err := repo.load(); err != nil {
+	if err := repo.migrate(); err != nil {
+		return nil, err
+	}
+	if err := repo.refresh(); err != nil {
 		return nil, err
 	}
 
 	return repo, nil
 }
 
-func (r *JsonChatRepository) load() error {
-	data, err := os.ReadFile(r.filePath)
+// migrate moves the chats out of the single chats.json file older versions
+// wrote into one file per chat. The old file is kept as chats.json.migrated.
+func (r *JsonChatRepository) migrate() error {
+	if _, err := os.Stat(r.legacyPath); os.IsNotExist(err) {
+		return nil
+	}
+
+	unlock, err := r.lock.Lock()
+	if err != nil {
+		return errors.InternalErrorf("failed to lock chats: %v", err)
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(r.legacyPath)
 	if os.IsNotExist(err) {
-		return nil // File doesn't exist yet, start with empty data
+		return nil // Another process migrated the chats first
 	}
 	if err != nil {
 		return errors.InternalErrorf("failed to read chats.json: %v", err)
@@ -47,55 +75,115 @@ func (r *JsonChatRepository) load() error {
 	if err := json.Unmarshal(data, &chats); err != nil {
 		return errors.InternalErrorf("failed to unmarshal chats.json: %v", err)
 	}
-
-	// Convert slice to map
-	r.data = make(map[string]*entities.Chat)
 	for _, chat := range chats {
-		r.data[chat.ID] = chat
+		if err := r.saveChat(chat); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(r.legacyPath, r.legacyPath+".migrated"); err != nil {
+		return errors.InternalErrorf("failed to move chats.json aside: %v", err)
 	}
 	return nil
 }
 
-func (r *JsonChatRepository) save() error {
-	// Convert map to slice for JSON serialization
-	chats := make([]*entities.Chat, 0, len(r.data))
-	for _, chat := range r.data {
-		chats = append(chats, chat)
+func (r *JsonChatRepository) chatPath(id string) string {
+	return filepath.Join(r.dir, id+".json")
+}
+
+// load brings the chats in memory in line with the chats directory, reading
+// only the files that changed since they were last read.
+func (r *JsonChatRepository) load() error {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.InternalErrorf("failed to read chats directory: %v", err)
 	}
 
-	data, err := json.MarshalIndent(chats, "", "  ")
-	if err != nil {
-		return errors.InternalErrorf("failed to marshal chats: %v", err)
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".json" {
+			continue
+		}
+		id := strings.TrimSuffix(name, ".json")
+		seen[id] = true
+
+		path := r.chatPath(id)
+		version := statVersion(path)
+		if _, ok := r.data[id]; ok && r.versions[id].matches(version) {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			delete(seen, id) // Deleted since the directory was read
+			continue
+		}
+		if err != nil {
+			return errors.InternalErrorf("failed to read chat %s: %v", id, err)
+		}
+		var chat entities.Chat
+		if err := json.Unmarshal(data, &chat); err != nil {
+			return errors.InternalErrorf("failed to unmarshal chat %s: %v", id, err)
+		}
+		r.data[id] = &chat
+		r.versions[id] = version
 	}
 
-	if err := os.MkdirAll(filepath.Dir(r.filePath), 0755); err != nil {
-		return errors.InternalErrorf("failed to create directory: %v", err)
+	for id := range r.data {
+		if !seen[id] {
+			delete(r.data, id)
+			delete(r.versions, id)
+		}
 	}
+	return nil
+}
 
-	if err := os.WriteFile(r.filePath, data, 0644); err != nil {
-		return errors.InternalErrorf("failed to write chats.json: %v", err)
+// saveChat writes one chat's file.
+func (r *JsonChatRepository) saveChat(chat *entities.Chat) error {
+	data, err := json.MarshalIndent(chat, "", "  ")
+	if err != nil {
+		return errors.InternalErrorf("failed to marshal chat: %v", err)
 	}
 
+	path := r.chatPath(chat.ID)
+	if err := writeFileAtomic(path, data); err != nil {
+		return errors.InternalErrorf("failed to write chat %s: %v", chat.ID, err)
+	}
+	r.data[chat.ID] = chat
+	r.versions[chat.ID] = statVersion(path)
+
+	return nil
+}
+
+// removeChat deletes one chat's file.
+func (r *JsonChatRepository) removeChat(id string) error {
+	if err := os.Remove(r.chatPath(id)); err != nil && !os.IsNotExist(err) {
+		return errors.InternalErrorf("failed to delete chat %s: %v", id, err)
+	}
+	delete(r.data, id)
+	delete(r.versions, id)
 	return nil
 }
 
 func (r *JsonChatRepository) ListChats(ctx context.Context) ([]*entities.Chat, error) {
+	return r.SearchChats(ctx, "", entities.ChatSearchFilter{})
+}
+
+// SearchChats returns copies of the chats matching the query and filter,
+// most recently updated first.
+func (r *JsonChatRepository) SearchChats(ctx context.Context, query string, filter entities.ChatSearchFilter) ([]*entities.Chat, error) {
+	if err := r.refresh(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	chatsCopy := make([]*entities.Chat, 0, len(r.data))
 	for _, c := range r.data {
-		messagesCopy := make([]entities.Message, len(c.Messages))
-		copy(messagesCopy, c.Messages)
-		chatsCopy = append(chatsCopy, &entities.Chat{
-			ID:           c.ID,
-			AgentID:      c.AgentID,
-			ModelID:      c.ModelID,
-			Name:         c.Name,
-			Messages:     messagesCopy,
-			Usage:        c.Usage,
-			Active:       c.Active,
-			ParentChatID: c.ParentChatID,
-			CreatedAt:    c.CreatedAt,
-			UpdatedAt:    c.UpdatedAt,
-		})
+		if !filter.Matches(c, query) {
+			continue
+		}
+		chatsCopy = append(chatsCopy, copyChat(c))
 	}
 	sort.Slice(chatsCopy, func(i, j int) bool {
 		return chatsCopy[i].UpdatedAt.After(chatsCopy[j].UpdatedAt)
@@ -104,25 +192,50 @@ func (r *JsonChatRepository) ListChats(ctx context.Context) ([]*entities.Chat, e
 }
 
 func (r *JsonChatRepository) GetChat(ctx context.Context, id string) (*entities.Chat, error) {
+	if err := r.refresh(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	chat, exists := r.data[id]
 	if !exists {
 		return nil, errors.NotFoundErrorf("chat not found: %s", id)
 	}
 
+	return copyChat(chat), nil
+}
+
+func copyChat(chat *entities.Chat) *entities.Chat {
 	messagesCopy := make([]entities.Message, len(chat.Messages))
 	copy(messagesCopy, chat.Messages)
+	var downgrade *entities.ModelDowngrade
+	if chat.Downgrade != nil {
+		d := *chat.Downgrade
+		downgrade = &d
+	}
 	return &entities.Chat{
-		ID:           chat.ID,
-		AgentID:      chat.AgentID,
-		ModelID:      chat.ModelID,
-		Name:         chat.Name,
-		Messages:     messagesCopy,
-		Usage:        chat.Usage,
-		Active:       chat.Active,
-		ParentChatID: chat.ParentChatID,
-		CreatedAt:    chat.CreatedAt,
-		UpdatedAt:    chat.UpdatedAt,
-	}, nil
+		ID:               chat.ID,
+		AgentID:          chat.AgentID,
+		ModelID:          chat.ModelID,
+		Name:             chat.Name,
+		Messages:         messagesCopy,
+		Usage:            chat.Usage,
+		Active:           chat.Active,
+		ParentChatID:     chat.ParentChatID,
+		ProjectID:        chat.ProjectID,
+		ApprovalPolicies: chat.ApprovalPolicies,
+		Budget:           chat.Budget,
+		Instructions:     chat.Instructions,
+		PendingPlan:      slices.Clone(chat.PendingPlan),
+		ReviewEdits:      chat.ReviewEdits,
+		ToolsOverride:    slices.Clone(chat.ToolsOverride),
+		Workspace:        chat.Workspace,
+		PinnedFiles:      slices.Clone(chat.PinnedFiles),
+		Downgrade:        downgrade,
+		CreatedAt:        chat.CreatedAt,
+		UpdatedAt:        chat.UpdatedAt,
+	}
 }
 
 func (r *JsonChatRepository) CreateChat(ctx context.Context, chat *entities.Chat) error {
@@ -132,25 +245,81 @@ func (r *JsonChatRepository) CreateChat(ctx context.Context, chat *entities.Chat
 	chat.CreatedAt = time.Now()
 	chat.UpdatedAt = chat.CreatedAt
 
-	r.data[chat.ID] = chat
-	return r.save()
+	return r.update(func() error {
+		return r.saveChat(chat)
+	})
 }
 
 func (r *JsonChatRepository) UpdateChat(ctx context.Context, chat *entities.Chat) error {
-	if _, exists := r.data[chat.ID]; !exists {
-		return errors.NotFoundErrorf("chat not found: %s", chat.ID)
-	}
-	chat.UpdatedAt = time.Now()
-	r.data[chat.ID] = chat
-	return r.save()
+	return r.update(func() error {
+		existing, exists := r.data[chat.ID]
+		if !exists {
+			return errors.NotFoundErrorf("chat not found: %s", chat.ID)
+		}
+		// The active flag is only changed through SetActiveChat, so saving a
+		// stale copy of a chat cannot reactivate it
+		chat.Active = existing.Active
+		chat.UpdatedAt = time.Now()
+		return r.saveChat(chat)
+	})
 }
 
 func (r *JsonChatRepository) DeleteChat(ctx context.Context, id string) error {
-	if _, exists := r.data[id]; !exists {
-		return errors.NotFoundErrorf("chat not found: %s", id)
+	return r.update(func() error {
+		if _, exists := r.data[id]; !exists {
+			return errors.NotFoundErrorf("chat not found: %s", id)
+		}
+		return r.removeChat(id)
+	})
+}
+
+// SetActiveChat marks chatID as the only active chat, so concurrent switches
+// from the TUI and the web UI cannot leave zero or several chats active.
+func (r *JsonChatRepository) SetActiveChat(ctx context.Context, chatID string) error {
+	return r.update(func() error {
+		if _, exists := r.data[chatID]; !exists {
+			return errors.NotFoundErrorf("chat not found: %s", chatID)
+		}
+		for id, chat := range r.data {
+			if chat.Active == (id == chatID) {
+				continue
+			}
+			chat.Active = id == chatID
+			if err := r.saveChat(chat); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// refresh picks up the chats another process saved or deleted since they
+// were last read.
+func (r *JsonChatRepository) refresh() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.load()
+}
+
+// update applies change, which saves or removes the chats it touches, while
+// holding the lock file. Every write goes through here, so the directory is
+// the latest state: it is reloaded first, so a write from the TUI neither
+// drops a chat the web server saved a moment earlier nor brings back one it
+// deleted.
+func (r *JsonChatRepository) update(change func() error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	unlock, err := r.lock.Lock()
+	if err != nil {
+		return errors.InternalErrorf("failed to lock chats: %v", err)
 	}
-	delete(r.data, id)
-	return r.save()
+	defer unlock()
+
+	if err := r.load(); err != nil {
+		return err
+	}
+	return change()
 }
 
 var _ interfaces.ChatRepository = (*JsonChatRepository)(nil)
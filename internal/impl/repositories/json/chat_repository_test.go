@@ -0,0 +1,224 @@
+package repositories_json
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+)
+
+func TestJsonChatRepository_SetActiveChat(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// Two repositories on the same directory stand in for the TUI and the
+	// web server running side by side
+	first, err := NewJSONChatRepository(dir)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	var ids []string
+	for i := 0; i < 4; i++ {
+		chat := entities.NewChat("agent", "model", "chat")
+		if err := first.CreateChat(ctx, chat); err != nil {
+			t.Fatalf("Failed to create chat: %v", err)
+		}
+		ids = append(ids, chat.ID)
+	}
+
+	second, err := NewJSONChatRepository(dir)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			repo := first
+			if i%2 == 1 {
+				repo = second
+			}
+			if err := repo.SetActiveChat(ctx, ids[i%len(ids)]); err != nil {
+				t.Errorf("SetActiveChat failed: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	reloaded, err := NewJSONChatRepository(dir)
+	if err != nil {
+		t.Fatalf("Failed to reload repository: %v", err)
+	}
+	chats, _ := reloaded.ListChats(ctx)
+	active := 0
+	for _, chat := range chats {
+		if chat.Active {
+			active++
+		}
+	}
+	if active != 1 {
+		t.Errorf("Expected exactly one active chat, got %d", active)
+	}
+
+	// Saving a stale copy must not reactivate a chat
+	stale, _ := reloaded.GetChat(ctx, ids[0])
+	if err := reloaded.SetActiveChat(ctx, ids[1]); err != nil {
+		t.Fatalf("SetActiveChat failed: %v", err)
+	}
+	stale.Active = true
+	if err := reloaded.UpdateChat(ctx, stale); err != nil {
+		t.Fatalf("UpdateChat failed: %v", err)
+	}
+	if chat, _ := reloaded.GetChat(ctx, ids[0]); chat.Active {
+		t.Error("Expected UpdateChat to leave the active flag unchanged")
+	}
+
+	if err := reloaded.SetActiveChat(ctx, "missing"); err == nil {
+		t.Error("Expected an error for an unknown chat")
+	}
+}
+
+func TestJsonChatRepository_ConcurrentUpdates(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	first, err := NewJSONChatRepository(dir)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	var ids []string
+	for i := 0; i < 10; i++ {
+		chat := entities.NewChat("agent", "model", "chat")
+		if err := first.CreateChat(ctx, chat); err != nil {
+			t.Fatalf("Failed to create chat: %v", err)
+		}
+		ids = append(ids, chat.ID)
+	}
+
+	second, err := NewJSONChatRepository(dir)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+
+	// Each goroutine adds messages to its own chat, alternating between the
+	// two repositories, so any update lost to another's write shows up
+	const rounds = 5
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			for round := 0; round < rounds; round++ {
+				repo := first
+				if (i+round)%2 == 1 {
+					repo = second
+				}
+				chat, err := repo.GetChat(ctx, id)
+				if err != nil {
+					t.Errorf("GetChat failed: %v", err)
+					return
+				}
+				chat.Messages = append(chat.Messages, *entities.NewMessage("user", fmt.Sprintf("round %d", round)))
+				if err := repo.UpdateChat(ctx, chat); err != nil {
+					t.Errorf("UpdateChat failed: %v", err)
+					return
+				}
+			}
+		}(i, id)
+	}
+	wg.Wait()
+
+	reloaded, err := NewJSONChatRepository(dir)
+	if err != nil {
+		t.Fatalf("Failed to reload repository: %v", err)
+	}
+	for _, id := range ids {
+		chat, err := reloaded.GetChat(ctx, id)
+		if err != nil {
+			t.Fatalf("Chat %s was lost: %v", id, err)
+		}
+		if len(chat.Messages) != rounds {
+			t.Errorf("Expected %d messages in chat %s, got %d", rounds, id, len(chat.Messages))
+		}
+	}
+
+	// A chat deleted by one repository stays deleted when the other writes
+	if err := first.DeleteChat(ctx, ids[0]); err != nil {
+		t.Fatalf("DeleteChat failed: %v", err)
+	}
+	if err := second.CreateChat(ctx, entities.NewChat("agent", "model", "other")); err != nil {
+		t.Fatalf("Failed to create chat: %v", err)
+	}
+	if _, err := first.GetChat(ctx, ids[0]); err == nil {
+		t.Error("Expected the deleted chat to stay deleted")
+	}
+
+	leftovers, _ := filepath.Glob(filepath.Join(dir, "chats", "*.tmp"))
+	if len(leftovers) > 0 {
+		t.Errorf("Expected no temporary files, got %v", leftovers)
+	}
+}
+
+func TestJsonChatRepository_PerChatFiles(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// Chats saved by older versions in a single file are moved into one file
+	// per chat
+	legacy := []*entities.Chat{
+		entities.NewChat("agent", "model", "first"),
+		entities.NewChat("agent", "model", "second"),
+	}
+	legacy[0].ID, legacy[1].ID = "first", "second"
+	data, _ := json.Marshal(legacy)
+	if err := os.WriteFile(filepath.Join(dir, "chats.json"), data, 0644); err != nil {
+		t.Fatalf("Failed to write chats.json: %v", err)
+	}
+
+	repo, err := NewJSONChatRepository(dir)
+	if err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	chats, _ := repo.ListChats(ctx)
+	if len(chats) != 2 {
+		t.Fatalf("Expected 2 migrated chats, got %d", len(chats))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "chats.json")); !os.IsNotExist(err) {
+		t.Error("Expected chats.json to be moved aside")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "chats.json.migrated")); err != nil {
+		t.Errorf("Expected chats.json.migrated to be kept: %v", err)
+	}
+
+	// Updating a chat leaves the other chat's file alone
+	before, err := os.Stat(filepath.Join(dir, "chats", "second.json"))
+	if err != nil {
+		t.Fatalf("Expected a file for the second chat: %v", err)
+	}
+	chat, _ := repo.GetChat(ctx, "first")
+	chat.Messages = append(chat.Messages, *entities.NewMessage("user", "hello"))
+	if err := repo.UpdateChat(ctx, chat); err != nil {
+		t.Fatalf("UpdateChat failed: %v", err)
+	}
+	after, _ := os.Stat(filepath.Join(dir, "chats", "second.json"))
+	if !os.SameFile(before, after) || !before.ModTime().Equal(after.ModTime()) {
+		t.Error("Expected updating one chat not to rewrite another")
+	}
+
+	reloaded, err := NewJSONChatRepository(dir)
+	if err != nil {
+		t.Fatalf("Failed to reload repository: %v", err)
+	}
+	if chat, err := reloaded.GetChat(ctx, "first"); err != nil || len(chat.Messages) != 1 {
+		t.Errorf("Expected the update to persist, got %v, %v", chat, err)
+	}
+}
@@ -0,0 +1,144 @@
+package repositories_json
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const fileLockRetry = 10 * time.Millisecond
+
+// Variables so tests can shorten them
+var (
+	fileLockTimeout = 5 * time.Second
+	// fileLockStale is how long a lock can go without being refreshed before
+	// it is taken to be left behind by a crashed process
+	fileLockStale = 30 * time.Second
+)
+
+// fileLock is a lock file next to a JSON store that serializes updates made
+// by separate processes sharing the storage directory, such as the TUI and
+// the web server. Creating the file with O_EXCL works on every platform.
+type fileLock struct {
+	path string
+}
+
+func newFileLock(filePath string) *fileLock {
+	return &fileLock{path: filePath + ".lock"}
+}
+
+// Lock waits for the lock and returns a function that releases it. While it
+// is held, the lock file's modification time is refreshed, so a slow writer
+// keeps it; a lock left behind by a crashed process stops being refreshed
+// and is taken over once it is stale.
+func (l *fileLock) Lock() (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	deadline := time.Now().Add(fileLockTimeout)
+	for {
+		f, err := os.OpenFile(l.path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return l.hold(), nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create lock file: %v", err)
+		}
+
+		if info, statErr := os.Stat(l.path); statErr == nil && time.Since(info.ModTime()) > fileLockStale {
+			os.Remove(l.path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for lock %s", l.path)
+		}
+		time.Sleep(fileLockRetry)
+	}
+}
+
+// hold refreshes the lock until the returned function releases it.
+func (l *fileLock) hold() func() {
+	done := make(chan struct{})
+	ticker := time.NewTicker(fileLockStale / 3)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				now := time.Now()
+				os.Chtimes(l.path, now, now)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			os.Remove(l.path)
+		})
+	}
+}
+
+// writeFileAtomic replaces the file with data by writing a temporary file in
+// the same directory and renaming it over the original, so a reader or a
+// crash never sees a half-written store.
+func writeFileAtomic(filePath string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filePath)
+}
+
+// fileVersion identifies what was last read from or written to a store, so a
+// repository can tell when another process has saved it since. Writes
+// replace the file, so the file itself changing catches saves that land
+// within the same timestamp tick.
+type fileVersion struct {
+	info os.FileInfo
+}
+
+func statVersion(filePath string) fileVersion {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return fileVersion{}
+	}
+	return fileVersion{info: info}
+}
+
+func (v fileVersion) matches(other fileVersion) bool {
+	if v.info == nil || other.info == nil {
+		return v.info == nil && other.info == nil
+	}
+	return os.SameFile(v.info, other.info) &&
+		v.info.ModTime().Equal(other.info.ModTime()) &&
+		v.info.Size() == other.info.Size()
+}
@@ -0,0 +1,57 @@
+package repositories_json
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func shortenFileLock(t *testing.T) {
+	t.Helper()
+	timeout, stale := fileLockTimeout, fileLockStale
+	fileLockTimeout, fileLockStale = 300*time.Millisecond, 150*time.Millisecond
+	t.Cleanup(func() { fileLockTimeout, fileLockStale = timeout, stale })
+}
+
+func TestFileLock_HeldLockIsNotTakenOver(t *testing.T) {
+	shortenFileLock(t)
+	lock := newFileLock(filepath.Join(t.TempDir(), "chats.json"))
+
+	unlock, err := lock.Lock()
+	if err != nil {
+		t.Fatalf("Lock failed: %v", err)
+	}
+	// A slow writer holds the lock for longer than it takes to go stale
+	time.Sleep(2 * fileLockStale)
+	if _, err := lock.Lock(); err == nil {
+		t.Fatal("Expected a held lock not to be taken over")
+	}
+
+	unlock()
+	unlock, err = lock.Lock()
+	if err != nil {
+		t.Fatalf("Expected the released lock to be free, got %v", err)
+	}
+	unlock()
+}
+
+func TestFileLock_TakesOverAbandonedLock(t *testing.T) {
+	shortenFileLock(t)
+	lock := newFileLock(filepath.Join(t.TempDir(), "chats.json"))
+
+	// A crashed process leaves its lock behind without refreshing it
+	if err := os.WriteFile(lock.path, []byte("12345\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * fileLockStale)
+	if err := os.Chtimes(lock.path, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	unlock, err := lock.Lock()
+	if err != nil {
+		t.Fatalf("Expected the abandoned lock to be taken over, got %v", err)
+	}
+	unlock()
+}
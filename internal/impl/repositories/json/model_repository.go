@@ -67,11 +67,7 @@ func (r *JsonModelRepository) save() error {
 		return errors.InternalErrorf("failed to marshal models: %v", err)
 	}
 
-	if err := os.MkdirAll(filepath.Dir(r.filePath), 0755); err != nil {
-		return errors.InternalErrorf("failed to create directory: %v", err)
-	}
-
-	if err := os.WriteFile(r.filePath, data, 0644); err != nil {
+	if err := writeFileAtomic(r.filePath, data); err != nil {
 		return errors.InternalErrorf("failed to write models.json: %v", err)
 	}
 
@@ -0,0 +1,139 @@
+package repositories_json
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/domain/errs"
+	"github.com/drujensen/aiagent/internal/domain/interfaces"
+
+	"github.com/google/uuid"
+)
+
+type JsonProjectRepository struct {
+	filePath string
+	data     []*entities.Project
+}
+
+func NewJSONProjectRepository(storageDir string) (interfaces.ProjectRepository, error) {
+	filePath := filepath.Join(storageDir, "projects.json")
+	repo := &JsonProjectRepository{
+		filePath: filePath,
+		data:     []*entities.Project{},
+	}
+
+	if err := repo.load(); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+func (r *JsonProjectRepository) load() error {
+	data, err := os.ReadFile(r.filePath)
+	if os.IsNotExist(err) {
+		return nil // File doesn't exist yet, start with empty data
+	}
+	if err != nil {
+		return errors.InternalErrorf("failed to read projects.json: %v", err)
+	}
+
+	var projects []*entities.Project
+	if err := json.Unmarshal(data, &projects); err != nil {
+		return errors.InternalErrorf("failed to unmarshal projects.json: %v", err)
+	}
+
+	// Validate UUIDs
+	for _, project := range projects {
+		if project.ID == "" {
+			return errors.InternalErrorf("project is missing an ID")
+		}
+		if _, err := uuid.Parse(project.ID); err != nil {
+			return errors.InternalErrorf("project has an invalid UUID: %v", err)
+		}
+	}
+
+	r.data = projects
+	return nil
+}
+
+func (r *JsonProjectRepository) save() error {
+	data, err := json.MarshalIndent(r.data, "", "  ")
+	if err != nil {
+		return errors.InternalErrorf("failed to marshal projects: %v", err)
+	}
+
+	if err := writeFileAtomic(r.filePath, data); err != nil {
+		return errors.InternalErrorf("failed to write projects.json: %v", err)
+	}
+
+	return nil
+}
+
+func (r *JsonProjectRepository) ListProjects(ctx context.Context) ([]*entities.Project, error) {
+	projectsCopy := make([]*entities.Project, len(r.data))
+	for i, p := range r.data {
+		projectsCopy[i] = &entities.Project{
+			ID:        p.ID,
+			Name:      p.Name,
+			Workspace: p.Workspace,
+			CreatedAt: p.CreatedAt,
+			UpdatedAt: p.UpdatedAt,
+		}
+	}
+	return projectsCopy, nil
+}
+
+func (r *JsonProjectRepository) GetProject(ctx context.Context, id string) (*entities.Project, error) {
+	for _, project := range r.data {
+		if project.ID == id {
+			return &entities.Project{
+				ID:        project.ID,
+				Name:      project.Name,
+				Workspace: project.Workspace,
+				CreatedAt: project.CreatedAt,
+				UpdatedAt: project.UpdatedAt,
+			}, nil
+		}
+	}
+	return nil, errors.NotFoundErrorf("project not found: %s", id)
+}
+
+func (r *JsonProjectRepository) CreateProject(ctx context.Context, project *entities.Project) error {
+	if project.ID == "" {
+		project.ID = uuid.New().String()
+	}
+	project.CreatedAt = time.Now()
+	project.UpdatedAt = project.CreatedAt
+
+	r.data = append(r.data, project)
+	return r.save()
+}
+
+func (r *JsonProjectRepository) UpdateProject(ctx context.Context, project *entities.Project) error {
+	for i, p := range r.data {
+		if p.ID == project.ID {
+			project.UpdatedAt = time.Now()
+			r.data[i] = project
+			return r.save()
+		}
+	}
+	return errors.NotFoundErrorf("project not found: %s", project.ID)
+}
+
+func (r *JsonProjectRepository) DeleteProject(ctx context.Context, id string) error {
+	for i, p := range r.data {
+		if p.ID == id {
+			r.data = slices.Delete(r.data, i, i+1)
+			return r.save()
+		}
+	}
+	return errors.NotFoundErrorf("project not found: %s", id)
+}
+
+var _ interfaces.ProjectRepository = (*JsonProjectRepository)(nil)
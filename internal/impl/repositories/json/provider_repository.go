@@ -71,11 +71,7 @@ func (r *JsonProviderRepository) save() error {
 		return errors.InternalErrorf("failed to marshal providers: %v", err)
 	}
 
-	if err := os.MkdirAll(filepath.Dir(r.filePath), 0755); err != nil {
-		return errors.InternalErrorf("failed to create directory: %v", err)
-	}
-
-	if err := os.WriteFile(r.filePath, data, 0644); err != nil {
+	if err := writeFileAtomic(r.filePath, data); err != nil {
 		return errors.InternalErrorf("failed to write providers.json: %v", err)
 	}
 
@@ -86,14 +82,20 @@ func (r *JsonProviderRepository) ListProviders(ctx context.Context) ([]*entities
 	providersCopy := make([]*entities.Provider, len(r.data))
 	for i, p := range r.data {
 		providersCopy[i] = &entities.Provider{
-			ID:         p.ID,
-			Name:       p.Name,
-			Type:       p.Type,
-			BaseURL:    p.BaseURL,
-			APIKeyName: p.APIKeyName,
-			Models:     p.Models,
-			CreatedAt:  p.CreatedAt,
-			UpdatedAt:  p.UpdatedAt,
+			ID:                    p.ID,
+			Name:                  p.Name,
+			Type:                  p.Type,
+			BaseURL:               p.BaseURL,
+			APIKeyName:            p.APIKeyName,
+			Models:                p.Models,
+			RequestsPerMinute:     p.RequestsPerMinute,
+			RequestTimeoutSeconds: p.RequestTimeoutSeconds,
+			DetectedType:          p.DetectedType,
+			DisableTypeDetection:  p.DisableTypeDetection,
+			ToolCallIDFormat:      p.ToolCallIDFormat,
+			StrictToolSchemas:     p.StrictToolSchemas,
+			CreatedAt:             p.CreatedAt,
+			UpdatedAt:             p.UpdatedAt,
 		}
 	}
 	return providersCopy, nil
@@ -103,14 +105,20 @@ func (r *JsonProviderRepository) GetProvider(ctx context.Context, id string) (*e
 	for _, provider := range r.data {
 		if provider.ID == id {
 			return &entities.Provider{
-				ID:         provider.ID,
-				Name:       provider.Name,
-				Type:       provider.Type,
-				BaseURL:    provider.BaseURL,
-				APIKeyName: provider.APIKeyName,
-				Models:     provider.Models,
-				CreatedAt:  provider.CreatedAt,
-				UpdatedAt:  provider.UpdatedAt,
+				ID:                    provider.ID,
+				Name:                  provider.Name,
+				Type:                  provider.Type,
+				BaseURL:               provider.BaseURL,
+				APIKeyName:            provider.APIKeyName,
+				Models:                provider.Models,
+				RequestsPerMinute:     provider.RequestsPerMinute,
+				RequestTimeoutSeconds: provider.RequestTimeoutSeconds,
+				DetectedType:          provider.DetectedType,
+				DisableTypeDetection:  provider.DisableTypeDetection,
+				ToolCallIDFormat:      provider.ToolCallIDFormat,
+				StrictToolSchemas:     provider.StrictToolSchemas,
+				CreatedAt:             provider.CreatedAt,
+				UpdatedAt:             provider.UpdatedAt,
 			}, nil
 		}
 	}
@@ -68,11 +68,7 @@ func (r *JsonTaskRepository) save() error {
 		return errors.InternalErrorf("failed to marshal tasks: %v", err)
 	}
 
-	if err := os.MkdirAll(filepath.Dir(r.filePath), 0755); err != nil {
-		return errors.InternalErrorf("failed to create directory: %v", err)
-	}
-
-	if err := os.WriteFile(r.filePath, data, 0644); err != nil {
+	if err := writeFileAtomic(r.filePath, data); err != nil {
 		return errors.InternalErrorf("failed to write tasks.json: %v", err)
 	}
 
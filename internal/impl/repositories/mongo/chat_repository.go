@@ -2,6 +2,7 @@ package repositories_mongo
 
 import (
 	"context"
+	"regexp"
 	"time"
 
 	"github.com/drujensen/aiagent/internal/domain/entities"
@@ -10,6 +11,7 @@ import (
 	"github.com/google/uuid"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -51,6 +53,54 @@ func (r *MongoChatRepository) ListChats(ctx context.Context) ([]*entities.Chat,
 	return chats, nil
 }
 
+// SearchChats matches the query against chat names and message content
+// without regard to case, most recently updated first.
+func (r *MongoChatRepository) SearchChats(ctx context.Context, query string, filter entities.ChatSearchFilter) ([]*entities.Chat, error) {
+	conditions := bson.M{}
+	if query != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(query), Options: "i"}
+		conditions["$or"] = bson.A{
+			bson.M{"name": pattern},
+			bson.M{"messages.content": pattern},
+		}
+	}
+	if filter.AgentID != "" {
+		conditions["agent_id"] = filter.AgentID
+	}
+	updated := bson.M{}
+	if !filter.From.IsZero() {
+		updated["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		updated["$lte"] = filter.To
+	}
+	if len(updated) > 0 {
+		conditions["updated_at"] = updated
+	}
+
+	sortOption := options.Find().SetSort(bson.D{{Key: "updated_at", Value: -1}})
+	cursor, err := r.collection.Find(ctx, conditions, sortOption)
+	if err != nil {
+		return nil, errors.InternalErrorf("failed to search chats: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	chats := []*entities.Chat{}
+	for cursor.Next(ctx) {
+		var chat entities.Chat
+		if err := cursor.Decode(&chat); err != nil {
+			return nil, errors.InternalErrorf("failed to decode chat: %v", err)
+		}
+		chats = append(chats, &chat)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, errors.InternalErrorf("failed to search chats: %v", err)
+	}
+
+	return chats, nil
+}
+
 func (r *MongoChatRepository) GetChat(ctx context.Context, id string) (*entities.Chat, error) {
 	var chat entities.Chat
 	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&chat)
@@ -79,14 +129,19 @@ func (r *MongoChatRepository) CreateChat(ctx context.Context, chat *entities.Cha
 func (r *MongoChatRepository) UpdateChat(ctx context.Context, chat *entities.Chat) error {
 	chat.UpdatedAt = time.Now()
 
-	update, err := bson.Marshal(bson.M{
-		"$set": chat,
-	})
+	// The active flag is only changed through SetActiveChat, so saving a
+	// stale copy of a chat cannot reactivate it
+	data, err := bson.Marshal(chat)
 	if err != nil {
 		return errors.InternalErrorf("failed to marshal chat: %v", err)
 	}
+	var fields bson.M
+	if err := bson.Unmarshal(data, &fields); err != nil {
+		return errors.InternalErrorf("failed to marshal chat: %v", err)
+	}
+	delete(fields, "active")
 
-	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": chat.ID}, update)
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": chat.ID}, bson.M{"$set": fields})
 	if err != nil {
 		return errors.InternalErrorf("failed to update chat: %v", err)
 	}
@@ -109,4 +164,62 @@ func (r *MongoChatRepository) DeleteChat(ctx context.Context, id string) error {
 	return nil
 }
 
+// mongoIllegalOperation is returned by standalone servers, which do not
+// support transactions.
+const mongoIllegalOperation = 20
+
+// SetActiveChat makes chatID the only active chat inside a transaction.
+// Standalone servers without transaction support fall back to ordered
+// updates that are checked and retried until exactly one chat is active.
+func (r *MongoChatRepository) SetActiveChat(ctx context.Context, chatID string) error {
+	session, err := r.collection.Database().Client().StartSession()
+	if err != nil {
+		return errors.InternalErrorf("failed to start session: %v", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (any, error) {
+		return nil, r.setActive(sessCtx, chatID)
+	})
+	if serverErr, ok := err.(mongo.ServerError); ok && serverErr.HasErrorCode(mongoIllegalOperation) {
+		return r.setActiveWithoutTransaction(ctx, chatID)
+	}
+	return err
+}
+
+func (r *MongoChatRepository) setActive(ctx context.Context, chatID string) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": chatID}, bson.M{"$set": bson.M{"active": true}})
+	if err != nil {
+		return errors.InternalErrorf("failed to activate chat: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return errors.NotFoundErrorf("chat not found: %s", chatID)
+	}
+
+	_, err = r.collection.UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$ne": chatID}, "active": true},
+		bson.M{"$set": bson.M{"active": false}})
+	if err != nil {
+		return errors.InternalErrorf("failed to deactivate chats: %v", err)
+	}
+	return nil
+}
+
+func (r *MongoChatRepository) setActiveWithoutTransaction(ctx context.Context, chatID string) error {
+	const attempts = 3
+	for attempt := 0; attempt < attempts; attempt++ {
+		if err := r.setActive(ctx, chatID); err != nil {
+			return err
+		}
+		active, err := r.collection.CountDocuments(ctx, bson.M{"active": true})
+		if err != nil {
+			return errors.InternalErrorf("failed to count active chats: %v", err)
+		}
+		if active == 1 {
+			return nil
+		}
+	}
+	return errors.InternalErrorf("failed to settle the active chat after %d attempts", attempts)
+}
+
 var _ interfaces.ChatRepository = (*MongoChatRepository)(nil)
@@ -0,0 +1,107 @@
+package repositories_mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/domain/errs"
+	"github.com/drujensen/aiagent/internal/domain/interfaces"
+	"github.com/google/uuid"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type MongoProjectRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoProjectRepository(collection *mongo.Collection) *MongoProjectRepository {
+	return &MongoProjectRepository{
+		collection: collection,
+	}
+}
+
+func (r *MongoProjectRepository) ListProjects(ctx context.Context) ([]*entities.Project, error) {
+	var projects []*entities.Project
+	cursor, err := r.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, errors.InternalErrorf("failed to list projects: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var project entities.Project
+		if err := cursor.Decode(&project); err != nil {
+			return nil, errors.InternalErrorf("failed to decode project: %v", err)
+		}
+		projects = append(projects, &project)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, errors.InternalErrorf("failed to list projects: %v", err)
+	}
+
+	return projects, nil
+}
+
+func (r *MongoProjectRepository) GetProject(ctx context.Context, id string) (*entities.Project, error) {
+	var project entities.Project
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&project)
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.NotFoundErrorf("project not found")
+	}
+	if err != nil {
+		return nil, errors.InternalErrorf("failed to get project: %v", err)
+	}
+
+	return &project, nil
+}
+
+func (r *MongoProjectRepository) CreateProject(ctx context.Context, project *entities.Project) error {
+	if project.ID == "" {
+		project.ID = uuid.New().String()
+	}
+	_, err := r.collection.InsertOne(ctx, project)
+	if err != nil {
+		return errors.InternalErrorf("failed to create project: %v", err)
+	}
+
+	return nil
+}
+
+func (r *MongoProjectRepository) UpdateProject(ctx context.Context, project *entities.Project) error {
+	project.UpdatedAt = time.Now()
+
+	update, err := bson.Marshal(bson.M{
+		"$set": project,
+	})
+	if err != nil {
+		return errors.InternalErrorf("failed to marshal project: %v", err)
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": project.ID}, update)
+	if err != nil {
+		return errors.InternalErrorf("failed to update project: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return errors.NotFoundErrorf("project not found: %s", project.ID)
+	}
+
+	return nil
+}
+
+func (r *MongoProjectRepository) DeleteProject(ctx context.Context, id string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return errors.InternalErrorf("failed to delete project: %v", err)
+	}
+	if result.DeletedCount == 0 {
+		return errors.NotFoundErrorf("project not found: %s", id)
+	}
+
+	return nil
+}
+
+var _ interfaces.ProjectRepository = (*MongoProjectRepository)(nil)
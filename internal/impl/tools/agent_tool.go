@@ -53,6 +53,12 @@ func (t *AgentTool) Configuration() map[string]string { return t.configuration }
 
 func (t *AgentTool) UpdateConfiguration(config map[string]string) { t.configuration = config }
 
+func (t *AgentTool) WithConfiguration(config map[string]string) entities.Tool {
+	c := *t
+	c.configuration = config
+	return &c
+}
+
 func (t *AgentTool) Schema() map[string]any {
 	agentNameDesc := "Name of the agent to launch."
 
@@ -170,7 +176,7 @@ func (t *AgentTool) Execute(ctx context.Context, arguments string) (string, erro
 		}
 	}
 
-	// Create a sub-chat for the agent — skips setOtherChatsInactive so the
+	// Create a sub-chat for the agent — it is not activated so the
 	// parent chat remains active and multiple sub-agents can run in parallel.
 	chatTitle := fmt.Sprintf("%s: %s", targetAgent.Name, truncateStr(args.Task, 50))
 	chat, err := chatService.CreateSubChat(ctx, targetAgent.ID, targetModel.ID, chatTitle, args.ParentChatID)
@@ -19,8 +19,15 @@ type BrowserTool struct {
 	description   string
 	configuration map[string]string
 	logger        *zap.Logger
-	browser       *rod.Browser
-	page          *rod.Page
+	session       *browserSession
+}
+
+// browserSession is the browser and page a BrowserTool drives. Copies of the
+// tool made for a run share it, so a page opened in one call is there for
+// the next.
+type browserSession struct {
+	browser *rod.Browser
+	page    *rod.Page
 }
 
 func NewBrowserTool(name, description string, configuration map[string]string, logger *zap.Logger) *BrowserTool {
@@ -29,6 +36,7 @@ func NewBrowserTool(name, description string, configuration map[string]string, l
 		description:   description,
 		configuration: configuration,
 		logger:        logger,
+		session:       &browserSession{},
 	}
 	return bt
 }
@@ -61,6 +69,12 @@ func (b *BrowserTool) UpdateConfiguration(config map[string]string) {
 	b.configuration = config
 }
 
+func (b *BrowserTool) WithConfiguration(config map[string]string) entities.Tool {
+	c := *b
+	c.configuration = config
+	return &c
+}
+
 func (b *BrowserTool) Schema() map[string]any {
 	return map[string]any{
 		"type": "object",
@@ -119,29 +133,29 @@ func (b *BrowserTool) Execute(ctx context.Context, arguments string) (string, er
 		if args.Url == "" {
 			return "", fmt.Errorf("url is required for navigate")
 		}
-		b.page = b.browser.MustPage(args.Url)
-		if b.page == nil {
+		b.session.page = b.session.browser.MustPage(args.Url)
+		if b.session.page == nil {
 			return "", fmt.Errorf("page is not initialized")
 		}
 		return fmt.Sprintf("Navigated to %s successfully", args.Url), nil
 	case "getTitle":
-		if b.page == nil {
+		if b.session.page == nil {
 			return "", fmt.Errorf("page is not initialized")
 		}
-		title := b.page.MustInfo().Title
+		title := b.session.page.MustInfo().Title
 		return title, nil
 	case "click":
-		if b.page == nil {
+		if b.session.page == nil {
 			return "", fmt.Errorf("page is not initialized")
 		}
 		if args.Selector == "" {
 			return "", fmt.Errorf("selector is required for click")
 		}
-		element := b.page.MustElement(args.Selector)
+		element := b.session.page.MustElement(args.Selector)
 		element.MustClick()
 		return fmt.Sprintf("Clicked element with selector %s", args.Selector), nil
 	case "screenshot":
-		if b.page == nil {
+		if b.session.page == nil {
 			return "", fmt.Errorf("page is not initialized")
 		}
 		workspace := b.configuration["workspace"]
@@ -153,7 +167,7 @@ func (b *BrowserTool) Execute(ctx context.Context, arguments string) (string, er
 			}
 		}
 		filename := workspace + "/" + args.Filename
-		screenshot, err := b.page.Screenshot(true, nil)
+		screenshot, err := b.session.page.Screenshot(true, nil)
 		if err != nil {
 			return "", fmt.Errorf("failed to take screenshot: %w", err)
 		}
@@ -163,12 +177,12 @@ func (b *BrowserTool) Execute(ctx context.Context, arguments string) (string, er
 		}
 		return "Screenshot saved successfully at " + filename, nil
 	case "close":
-		if b.page != nil {
-			b.page.MustClose()
+		if b.session.page != nil {
+			b.session.page.MustClose()
 		}
 		return "Browser closed successfully", nil
 	case "getPageSource":
-		source, err := b.page.HTML()
+		source, err := b.session.page.HTML()
 		if err != nil {
 			return "", fmt.Errorf("failed to get page source: %w", err)
 		}
@@ -177,7 +191,7 @@ func (b *BrowserTool) Execute(ctx context.Context, arguments string) (string, er
 		if args.Selector == "" {
 			return "", fmt.Errorf("selector is required for getElementText")
 		}
-		element := b.page.MustElement(args.Selector)
+		element := b.session.page.MustElement(args.Selector)
 		text, err := element.Text()
 		if err != nil {
 			return "", fmt.Errorf("failed to get element text: %w", err)
@@ -187,7 +201,7 @@ func (b *BrowserTool) Execute(ctx context.Context, arguments string) (string, er
 		if args.Selector == "" {
 			return "", fmt.Errorf("selector is required for getElementAttribute")
 		}
-		element := b.page.MustElement(args.Selector)
+		element := b.session.page.MustElement(args.Selector)
 		attribute, err := element.Attribute("value")
 		if err != nil {
 			return "", fmt.Errorf("failed to get element attribute: %w", err)
@@ -203,7 +217,7 @@ func (b *BrowserTool) Execute(ctx context.Context, arguments string) (string, er
 		if args.Value == "" {
 			return "", fmt.Errorf("value is required for setInputValue")
 		}
-		element := b.page.MustElement(args.Selector)
+		element := b.session.page.MustElement(args.Selector)
 		err := element.Input(args.Value)
 		if err != nil {
 			return "", fmt.Errorf("failed to set input value: %w", err)
@@ -217,7 +231,7 @@ func (b *BrowserTool) Execute(ctx context.Context, arguments string) (string, er
 
 // Helper method to initialize browser
 func (b *BrowserTool) initializeBrowser() error {
-	if b.browser != nil {
+	if b.session.browser != nil {
 		return nil // Already initialized
 	}
 	headless := b.configuration["headless"] == "true"
@@ -226,7 +240,7 @@ func (b *BrowserTool) initializeBrowser() error {
 	if err != nil {
 		return err
 	}
-	b.browser = rod.New().ControlURL(controlURL).MustConnect()
+	b.session.browser = rod.New().ControlURL(controlURL).MustConnect()
 	return nil
 }
 
@@ -42,6 +42,12 @@ func (t *CompressionTool) UpdateConfiguration(config map[string]string) {
 	t.configuration = config
 }
 
+func (t *CompressionTool) WithConfiguration(config map[string]string) entities.Tool {
+	c := *t
+	c.configuration = config
+	return &c
+}
+
 func (t *CompressionTool) FullDescription() string {
 	return fmt.Sprintf("%s\n\nParameters:\n- action: compress_range\n- start_message_index: starting message index (0-based)\n- end_message_index: ending message index (0-based)\n- summary_type: task_cleanup, plan_update, context_preservation, full_reset\n- description: human-readable description of compression purpose", t.Description())
 }
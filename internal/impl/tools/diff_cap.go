@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxDiffLines caps the diff a file change returns to the model when
+// the tool's configuration sets no max_diff_lines.
+const DefaultMaxDiffLines = 200
+
+// maxDiffLines reads max_diff_lines from the configuration. Zero returns the
+// whole diff.
+func (t *FileWriteTool) maxDiffLines() int {
+	value := t.configuration["max_diff_lines"]
+	if value == "" {
+		return DefaultMaxDiffLines
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return DefaultMaxDiffLines
+	}
+	return n
+}
+
+// modelDiff caps diff for the result sent back to the model. When it was
+// cut, the full diff is added as a fullDiff field for the UIs to display;
+// the integrations take it out before the result reaches the model.
+func (t *FileWriteTool) modelDiff(diff string) (string, string) {
+	capped, cut := capDiff(diff, t.maxDiffLines())
+	if !cut {
+		return diff, ""
+	}
+	return capped, fmt.Sprintf(`, "fullDiff": %q`, diff)
+}
+
+// capDiff shortens a unified diff to about maxLines lines. The file headers
+// are always kept and hunks are taken whole while they fit, so the model
+// sees complete changes rather than a hunk cut mid-way; only a first hunk
+// that alone exceeds the limit is cut. The changed lines left out are
+// summarized at the end.
+func capDiff(diff string, maxLines int) (string, bool) {
+	lines := strings.Split(strings.TrimSuffix(diff, "\n"), "\n")
+	if maxLines <= 0 || len(lines) <= maxLines {
+		return diff, false
+	}
+
+	var header []string
+	var hunks [][]string
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			hunks = append(hunks, []string{line})
+		case len(hunks) == 0:
+			header = append(header, line)
+		default:
+			hunks[len(hunks)-1] = append(hunks[len(hunks)-1], line)
+		}
+	}
+
+	kept := header
+	omitted := 0
+	for i, hunk := range hunks {
+		if omitted > 0 || len(kept)+len(hunk) > maxLines {
+			if i == 0 && omitted == 0 {
+				// Keep the start of an oversized first hunk
+				room := max(maxLines-len(kept), 1)
+				kept = append(kept, hunk[:room]...)
+				hunk = hunk[room:]
+			}
+			omitted += changedLines(hunk)
+			continue
+		}
+		kept = append(kept, hunk...)
+	}
+	if omitted == 0 {
+		return diff, false
+	}
+
+	return strings.Join(kept, "\n") + fmt.Sprintf("\n... %d additional changed lines ...\n", omitted), true
+}
+
+// changedLines counts the added and removed lines of a hunk.
+func changedLines(hunk []string) int {
+	n := 0
+	for _, line := range hunk {
+		if strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-") {
+			n++
+		}
+	}
+	return n
+}
@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/impl/workspacepath"
 
 	"go.uber.org/zap"
 )
@@ -21,6 +22,10 @@ type DirectoryTool struct {
 	logger        *zap.Logger
 }
 
+// DefaultTreeMaxEntries caps directory_tree when the call sets no max_entries,
+// so a tree of a large project does not flood the result.
+const DefaultTreeMaxEntries = 1000
+
 type TreeEntry struct {
 	Name     string      `json:"name"`
 	Path     string      `json:"path"`
@@ -53,8 +58,14 @@ func (t *DirectoryTool) UpdateConfiguration(config map[string]string) {
 	t.configuration = config
 }
 
+func (t *DirectoryTool) WithConfiguration(config map[string]string) entities.Tool {
+	c := *t
+	c.configuration = config
+	return &c
+}
+
 func (t *DirectoryTool) FullDescription() string {
-	return fmt.Sprintf("%s\n\nParameters:\n- operation: create_directory, list_directory, directory_tree, move, delete\n- path: directory path (required for create_directory, move, delete; optional for list_directory, directory_tree - defaults to current directory)\n- destination: destination path (for move)\n- confirm: boolean (required for delete)", t.Description())
+	return fmt.Sprintf("%s\n\nParameters:\n- operation: create_directory, list_directory, directory_tree, move, delete\n- path: directory path (required for create_directory, move, delete; optional for list_directory, directory_tree - defaults to current directory)\n- destination: destination path (for move)\n- overwrite: boolean (move - replace an existing destination, refused otherwise)\n- confirm: boolean (required for delete)\n- dry_run: boolean (move, delete - report what would happen without changing anything)\n- include_ignored: boolean (list_directory, directory_tree - include files matched by .gitignore or the ignore config)\n- exclude: gitignore-style patterns, relative to path, to leave out of directory_tree, e.g. node_modules or *.log\n- max_entries: most entries directory_tree returns (default: %d); the result says when the tree was truncated\n\nA deleted path, or a destination replaced by move, is kept in the chat's backups and can be restored with the Edit tool's undo operation", t.Description(), DefaultTreeMaxEntries)
 }
 
 func (t *DirectoryTool) Schema() map[string]any {
@@ -78,16 +89,37 @@ func (t *DirectoryTool) Schema() map[string]any {
 				"type":        "integer",
 				"description": "Maximum recursion depth for directory_tree (default: unlimited)",
 			},
+			"overwrite": map[string]any{
+				"type":        "boolean",
+				"description": "Replace an existing destination for move (default: false, an existing destination is refused)",
+			},
 			"confirm": map[string]any{
 				"type":        "boolean",
 				"description": "Confirm deletion for delete operation",
 			},
+			"dry_run": map[string]any{
+				"type":        "boolean",
+				"description": "For move and delete: report what would be moved or deleted without changing anything (default: false)",
+			},
+			"include_ignored": map[string]any{
+				"type":        "boolean",
+				"description": "Include files and directories matched by .gitignore or the ignore config (default: false)",
+			},
+			"exclude": map[string]any{
+				"type":        "array",
+				"description": "Gitignore-style patterns, relative to path, to leave out of directory_tree, e.g. node_modules or *.log",
+				"items":       map[string]any{"type": "string"},
+			},
+			"max_entries": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("Most entries directory_tree returns (default: %d)", DefaultTreeMaxEntries),
+			},
 		},
 		"required": []string{"operation"},
 	}
 }
 
-func (t *DirectoryTool) validatePath(path string) (string, error) {
+func (t *DirectoryTool) workspace() (string, error) {
 	workspace := t.configuration["workspace"]
 	if workspace == "" {
 		var err error
@@ -96,34 +128,94 @@ func (t *DirectoryTool) validatePath(path string) (string, error) {
 			return "", fmt.Errorf("could not get current directory: %v", err)
 		}
 	}
+	return workspace, nil
+}
 
-	var fullPath string
-	if filepath.IsAbs(path) {
-		if !strings.HasPrefix(path, workspace) {
-			t.logger.Error("Absolute path is outside workspace", zap.String("path", path))
-			return "", fmt.Errorf("absolute path is outside workspace")
-		}
-		fullPath = path
-	} else {
-		fullPath = filepath.Join(workspace, path)
+func (t *DirectoryTool) validatePath(path string) (string, error) {
+	workspace, err := t.workspace()
+	if err != nil {
+		return "", err
 	}
 
-	rel, err := filepath.Rel(workspace, fullPath)
-	if err != nil || strings.HasPrefix(rel, "..") {
-		t.logger.Error("Path is outside workspace", zap.String("path", path))
-		return "", fmt.Errorf("path is outside workspace")
+	fullPath, err := workspacepath.Resolve(workspace, path)
+	if err != nil {
+		t.logger.Error("Path is outside workspace", zap.String("path", path), zap.Error(err))
+		return "", err
 	}
 	return fullPath, nil
 }
 
-func (t *DirectoryTool) Execute(ctx context.Context, arguments string) (string, error) {
-	t.logger.Debug("Executing directory command", zap.String("arguments", arguments))
+// trash moves a file or directory that is about to be deleted or replaced
+// into the chat's backups, where the Edit tool's undo can restore it.
+func (t *DirectoryTool) trash(chatID, fullPath string) (string, error) {
+	workspace, err := t.workspace()
+	if err != nil {
+		return "", err
+	}
+	relPath, err := filepath.Rel(workspace, fullPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to back up %s: %v", fullPath, err)
+	}
+	store := newFileBackupStore(workspace, t.configuration)
+	if store.Contains(fullPath) {
+		return "", fmt.Errorf("%s holds the backups and can't be backed up into them", relPath)
+	}
+	return store.Trash(chatID, relPath, fullPath)
+}
+
+// Mutates reports whether the call creates, moves or deletes anything. Dry
+// runs only look.
+func (t *DirectoryTool) Mutates(arguments string) bool {
+	var args struct {
+		Operation string `json:"operation"`
+		DryRun    bool   `json:"dry_run"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return true
+	}
+	if args.DryRun && (args.Operation == "move" || args.Operation == "delete") {
+		return false
+	}
+	return args.Operation != "list_directory" && args.Operation != "directory_tree"
+}
+
+// Cacheable reports whether the call only looks, so a listing can be
+// answered again from the run's cache until something under it changes.
+func (t *DirectoryTool) Cacheable(arguments string) bool {
+	return !t.Mutates(arguments)
+}
+
+// Paths returns the directory the call lists or changes, and the destination
+// of a move.
+func (t *DirectoryTool) Paths(arguments string) []string {
 	var args struct {
-		Operation   string `json:"operation"`
 		Path        string `json:"path"`
 		Destination string `json:"destination"`
-		DepthLimit  int    `json:"depth_limit"`
-		Confirm     bool   `json:"confirm"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return nil
+	}
+	paths := []string{args.Path}
+	if args.Destination != "" {
+		paths = append(paths, args.Destination)
+	}
+	return toolPaths(t.configuration, paths...)
+}
+
+func (t *DirectoryTool) Execute(ctx context.Context, arguments string) (string, error) {
+	t.logger.Debug("Executing directory command", zap.String("arguments", arguments))
+	var args struct {
+		Operation      string   `json:"operation"`
+		Path           string   `json:"path"`
+		Destination    string   `json:"destination"`
+		DepthLimit     int      `json:"depth_limit"`
+		Confirm        bool     `json:"confirm"`
+		Overwrite      bool     `json:"overwrite"`
+		DryRun         bool     `json:"dry_run"`
+		IncludeIgnored bool     `json:"include_ignored"`
+		Exclude        []string `json:"exclude"`
+		MaxEntries     int      `json:"max_entries"`
+		ChatID         string   `json:"parent_chat_id"` // injected by the framework via injectToolArgs
 	}
 	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
 		t.logger.Error("Failed to parse arguments", zap.Error(err))
@@ -135,6 +227,11 @@ func (t *DirectoryTool) Execute(ctx context.Context, arguments string) (string,
 		return "", fmt.Errorf("operation is required")
 	}
 
+	var ignore *ignoreMatcher
+	if !args.IncludeIgnored {
+		ignore = loadIgnoreMatcher(t.configuration)
+	}
+
 	switch args.Operation {
 	case "list_directory":
 		if args.Path == "" {
@@ -156,6 +253,9 @@ func (t *DirectoryTool) Execute(ctx context.Context, arguments string) (string,
 			if entry.IsDir() && (entry.Name() == ".git" || entry.Name() == ".aiagent") {
 				continue
 			}
+			if ignore.Ignored(filepath.Join(fullPath, entry.Name()), entry.IsDir()) {
+				continue
+			}
 			prefix := "[FILE]"
 			if entry.IsDir() {
 				prefix = "[DIR]"
@@ -217,11 +317,19 @@ func (t *DirectoryTool) Execute(ctx context.Context, arguments string) (string,
 		if err != nil {
 			return "", fmt.Errorf("invalid path: %v", err)
 		}
-		depth := args.DepthLimit
-		if depth == 0 {
-			depth = -1 // unlimited
+		walk := &treeWalk{
+			depthLimit: args.DepthLimit,
+			maxEntries: args.MaxEntries,
+			ignore:     ignore,
+			exclude:    newPatternMatcher(fullPath, args.Exclude),
+		}
+		if walk.depthLimit == 0 {
+			walk.depthLimit = -1 // unlimited
+		}
+		if walk.maxEntries <= 0 {
+			walk.maxEntries = DefaultTreeMaxEntries
 		}
-		tree, err := t.buildDirectoryTree(fullPath, depth, 1)
+		tree, err := t.buildDirectoryTree(ctx, fullPath, 1, walk)
 		if err != nil {
 			t.logger.Error("Failed to build directory tree", zap.String("path", fullPath), zap.Error(err))
 			return "", fmt.Errorf("failed to build directory tree: %v", err)
@@ -237,6 +345,9 @@ func (t *DirectoryTool) Execute(ctx context.Context, arguments string) (string,
 		// Convert to readable text format (first 15 lines)
 		textTree := t.treeToText(tree, "", 0, 15)
 		summary.WriteString(textTree)
+		if walk.truncated {
+			summary.WriteString(fmt.Sprintf("\nTruncated at %d entries; use a narrower path, depth_limit or exclude to see the rest\n", walk.maxEntries))
+		}
 
 		// Create JSON response with summary for TUI and full data for AI
 		response := struct {
@@ -245,12 +356,14 @@ func (t *DirectoryTool) Execute(ctx context.Context, arguments string) (string,
 			Path       string      `json:"path"`
 			TotalDirs  int         `json:"total_dirs"`
 			TotalFiles int         `json:"total_files"`
+			Truncated  bool        `json:"truncated"`
 		}{
 			Summary:    summary.String(),
 			FullTree:   tree,
 			Path:       fullPath,
 			TotalDirs:  totalDirs,
 			TotalFiles: totalFiles,
+			Truncated:  walk.truncated,
 		}
 
 		jsonResult, err := json.Marshal(response)
@@ -294,32 +407,81 @@ func (t *DirectoryTool) Execute(ctx context.Context, arguments string) (string,
 		if err != nil {
 			return "", fmt.Errorf("invalid destination path: %v", err)
 		}
+		if _, err := os.Lstat(srcPath); err != nil {
+			return "", fmt.Errorf("source does not exist: %s", args.Path)
+		}
+		_, err = os.Lstat(dstPath)
+		destinationExists := err == nil
+		if destinationExists && !args.Overwrite {
+			return "", fmt.Errorf("destination %s already exists; set overwrite to true to replace it", args.Destination)
+		}
+
+		result := moveResult{
+			Success:           true,
+			Source:            srcPath,
+			Destination:       dstPath,
+			DestinationExists: destinationExists,
+			DryRun:            args.DryRun,
+		}
+		if args.DryRun {
+			result.Summary = fmt.Sprintf("Would move %s to %s", srcPath, dstPath)
+			if destinationExists {
+				result.Summary += ", replacing the existing destination"
+			}
+			return result.json()
+		}
+
+		if destinationExists {
+			if result.BackupPath, err = t.trash(args.ChatID, dstPath); err != nil {
+				return "", err
+			}
+		}
 		err = os.Rename(srcPath, dstPath)
 		if err != nil {
 			t.logger.Error("Failed to move file", zap.String("source", srcPath), zap.String("dest", dstPath), zap.Error(err))
+			if result.BackupPath != "" {
+				os.Rename(result.BackupPath, dstPath)
+			}
 			return "", fmt.Errorf("failed to move file: %v", err)
 		}
 		t.logger.Info("File moved successfully", zap.String("source", srcPath), zap.String("dest", dstPath))
-		return "File moved successfully", nil
+		result.Summary = fmt.Sprintf("Moved %s to %s", srcPath, dstPath)
+		if destinationExists {
+			result.Summary += ", replacing the existing destination"
+		}
+		return result.json()
 	case "delete":
 		if args.Path == "" {
 			return "", fmt.Errorf("path is required for delete")
 		}
-		if !args.Confirm {
+		if !args.Confirm && !args.DryRun {
 			t.logger.Warn("Deletion requires confirmation", zap.String("path", args.Path))
-			return "", fmt.Errorf("deletion requires confirm=true")
+			return "", fmt.Errorf("deletion requires confirm=true, or dry_run=true to see what would be deleted")
 		}
 		fullPath, err := t.validatePath(args.Path)
 		if err != nil {
 			return "", fmt.Errorf("invalid path: %v", err)
 		}
-		err = os.RemoveAll(fullPath)
+
+		result, err := deletePreview(ctx, fullPath)
 		if err != nil {
-			t.logger.Error("Failed to delete", zap.String("path", fullPath), zap.Error(err))
-			return "", fmt.Errorf("failed to delete: %v", err)
+			return "", err
+		}
+		if args.DryRun {
+			result.DryRun = true
+			result.Summary = "Would delete " + result.Summary
+			return result.json()
+		}
+
+		if result.Type != "missing" {
+			if result.BackupPath, err = t.trash(args.ChatID, fullPath); err != nil {
+				t.logger.Error("Failed to delete", zap.String("path", fullPath), zap.Error(err))
+				return "", fmt.Errorf("failed to delete: %v", err)
+			}
 		}
 		t.logger.Info("Deleted successfully", zap.String("path", fullPath))
-		return "Deleted successfully", nil
+		result.Summary = "Deleted " + result.Summary
+		return result.json()
 
 	default:
 		t.logger.Error("Unknown operation", zap.String("operation", args.Operation))
@@ -327,8 +489,102 @@ func (t *DirectoryTool) Execute(ctx context.Context, arguments string) (string,
 	}
 }
 
-func (t *DirectoryTool) buildDirectoryTree(path string, depthLimit int, currentDepth int) ([]TreeEntry, error) {
-	if depthLimit >= 0 && currentDepth > depthLimit {
+// moveResult is the result of move, in the shape of the edit operations'.
+type moveResult struct {
+	Success           bool   `json:"success"`
+	Summary           string `json:"summary"`
+	Source            string `json:"source"`
+	Destination       string `json:"destination"`
+	DestinationExists bool   `json:"destinationExists"`
+	BackupPath        string `json:"backupPath,omitempty"`
+	DryRun            bool   `json:"dryRun,omitempty"`
+}
+
+func (r moveResult) json() (string, error) {
+	data, err := json.Marshal(r)
+	return string(data), err
+}
+
+// deleteResult is the result of delete, in the shape of the edit operations'.
+type deleteResult struct {
+	Success     bool   `json:"success"`
+	Summary     string `json:"summary"`
+	Path        string `json:"path"`
+	Type        string `json:"type"`
+	Directories int    `json:"directories"`
+	Files       int    `json:"files"`
+	Bytes       int64  `json:"bytes"`
+	BackupPath  string `json:"backupPath,omitempty"`
+	DryRun      bool   `json:"dryRun,omitempty"`
+}
+
+func (r deleteResult) json() (string, error) {
+	data, err := json.Marshal(r)
+	return string(data), err
+}
+
+// deletePreview counts what deleting the path removes. Its summary names
+// the path and what it holds, for the caller to prefix.
+func deletePreview(ctx context.Context, path string) (deleteResult, error) {
+	result := deleteResult{Success: true, Path: path, Type: "missing"}
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		result.Summary = "nothing, " + path + " does not exist"
+		return result, nil
+	}
+	if err != nil {
+		return result, fmt.Errorf("failed to stat %s: %v", path, err)
+	}
+	if !info.IsDir() {
+		result.Type = "file"
+		result.Files = 1
+		result.Bytes = info.Size()
+		result.Summary = fmt.Sprintf("file %s (%s)", path, formatSize(info.Size()))
+		return result, nil
+	}
+
+	result.Type = "directory"
+	err = filepath.WalkDir(path, func(entryPath string, entry os.DirEntry, err error) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err != nil || entryPath == path {
+			return nil
+		}
+		if entry.IsDir() {
+			result.Directories++
+			return nil
+		}
+		result.Files++
+		if info, err := entry.Info(); err == nil {
+			result.Bytes += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+	result.Summary = fmt.Sprintf("directory %s (%d directories, %d files, %s)", path, result.Directories, result.Files, formatSize(result.Bytes))
+	return result, nil
+}
+
+// treeWalk holds the limits of a directory_tree walk and how far it got.
+type treeWalk struct {
+	depthLimit int
+	maxEntries int
+	ignore     *ignoreMatcher
+	exclude    *ignoreMatcher
+	entries    int
+	truncated  bool
+}
+
+// buildDirectoryTree lists a directory's entries before descending into its
+// subdirectories, so a capped tree still shows every entry near the top.
+func (t *DirectoryTool) buildDirectoryTree(ctx context.Context, path string, currentDepth int, walk *treeWalk) ([]TreeEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if walk.depthLimit >= 0 && currentDepth > walk.depthLimit {
 		return []TreeEntry{}, nil
 	}
 	entries, err := os.ReadDir(path)
@@ -343,6 +599,14 @@ func (t *DirectoryTool) buildDirectoryTree(path string, depthLimit int, currentD
 			continue
 		}
 		entryPath := filepath.Join(path, entry.Name())
+		if walk.ignore.Ignored(entryPath, entry.IsDir()) || walk.exclude.Ignored(entryPath, entry.IsDir()) {
+			continue
+		}
+		if walk.entries >= walk.maxEntries {
+			walk.truncated = true
+			break
+		}
+		walk.entries++
 		treeEntry := TreeEntry{
 			Name: entry.Name(),
 			Path: entryPath,
@@ -350,14 +614,23 @@ func (t *DirectoryTool) buildDirectoryTree(path string, depthLimit int, currentD
 		}
 		if entry.IsDir() {
 			treeEntry.Type = "directory"
-			children, err := t.buildDirectoryTree(entryPath, depthLimit, currentDepth+1)
-			if err != nil {
-				continue
-			}
-			treeEntry.Children = children
 		}
 		result = append(result, treeEntry)
 	}
+
+	for i := range result {
+		if result[i].Type != "directory" {
+			continue
+		}
+		children, err := t.buildDirectoryTree(ctx, result[i].Path, currentDepth+1, walk)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			continue
+		}
+		result[i].Children = children
+	}
 	return result, nil
 }
 
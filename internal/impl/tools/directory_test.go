@@ -1,6 +1,11 @@
 package tools
 
 import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"go.uber.org/zap"
@@ -36,3 +41,163 @@ func TestDirectoryTool_ValidatePath_Absolute(t *testing.T) {
 		t.Errorf("Expected /tmp/test/subdir, got %s", validPath)
 	}
 }
+
+func TestDirectoryTool_TreeCanceled(t *testing.T) {
+	workspace := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workspace, "a", "b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	tool := NewDirectoryTool("test", "Test directory tool", map[string]string{"workspace": workspace}, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := tool.Execute(ctx, `{"operation": "directory_tree"}`); err == nil {
+		t.Error("Expected a canceled tree walk to fail")
+	}
+}
+
+func TestDirectoryTool_TreeLimits(t *testing.T) {
+	workspace := t.TempDir()
+	for _, dir := range []string{".git/objects", "node_modules/pkg", "src/app", "logs"} {
+		if err := os.MkdirAll(filepath.Join(workspace, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"main.go", "src/app/app.go", "logs/run.log", "node_modules/pkg/index.js"} {
+		if err := os.WriteFile(filepath.Join(workspace, file), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tool := NewDirectoryTool("test", "Test directory tool", map[string]string{"workspace": workspace}, zap.NewNop())
+
+	var response struct {
+		FullTree   []TreeEntry `json:"full_tree"`
+		TotalDirs  int         `json:"total_dirs"`
+		TotalFiles int         `json:"total_files"`
+		Truncated  bool        `json:"truncated"`
+	}
+	result, err := tool.Execute(context.Background(), `{"operation": "directory_tree", "exclude": ["node_modules", "*.log"]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(result), &response); err != nil {
+		t.Fatal(err)
+	}
+	// logs, src, src/app; main.go, src/app/app.go
+	if response.TotalDirs != 3 || response.TotalFiles != 2 || response.Truncated {
+		t.Errorf("Expected 3 directories and 2 files untruncated, got %d, %d and %v", response.TotalDirs, response.TotalFiles, response.Truncated)
+	}
+
+	result, err = tool.Execute(context.Background(), `{"operation": "directory_tree", "max_entries": 4}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.FullTree = nil
+	if err := json.Unmarshal([]byte(result), &response); err != nil {
+		t.Fatal(err)
+	}
+	if !response.Truncated || response.TotalDirs+response.TotalFiles != 4 {
+		t.Errorf("Expected 4 entries and a truncated tree, got %d and %v", response.TotalDirs+response.TotalFiles, response.Truncated)
+	}
+	if len(response.FullTree) != 4 {
+		t.Errorf("Expected the top level to be listed first, got %+v", response.FullTree)
+	}
+}
+
+func TestDirectoryTool_MoveOverwrite(t *testing.T) {
+	workspace := t.TempDir()
+	os.WriteFile(filepath.Join(workspace, "a.txt"), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(workspace, "b.txt"), []byte("b"), 0644)
+	tool := NewDirectoryTool("test", "Test directory tool", map[string]string{"workspace": workspace}, zap.NewNop())
+	ctx := context.Background()
+
+	if _, err := tool.Execute(ctx, `{"operation": "move", "path": "a.txt", "destination": "b.txt"}`); err == nil {
+		t.Fatal("Expected moving onto an existing file to be refused")
+	}
+
+	dryRun := `{"operation": "move", "path": "a.txt", "destination": "b.txt", "overwrite": true, "dry_run": true}`
+	if tool.Mutates(dryRun) {
+		t.Error("Expected a dry run not to count as a change")
+	}
+	result, err := tool.Execute(ctx, dryRun)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var preview moveResult
+	json.Unmarshal([]byte(result), &preview)
+	if !preview.DryRun || !preview.DestinationExists {
+		t.Errorf("Expected a dry run reporting the existing destination, got %s", result)
+	}
+	if data, _ := os.ReadFile(filepath.Join(workspace, "b.txt")); string(data) != "b" {
+		t.Fatal("Expected the dry run to leave the destination alone")
+	}
+
+	if _, err := tool.Execute(ctx, `{"operation": "move", "path": "a.txt", "destination": "b.txt", "overwrite": true}`); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(workspace, "b.txt")); string(data) != "a" {
+		t.Errorf("Expected the destination to be replaced, got %q", data)
+	}
+
+	// The replaced destination was kept and undo brings it back
+	edit := NewFileWriteTool("Edit", "Test edit tool", map[string]string{"workspace": workspace}, zap.NewNop())
+	if _, err := edit.Execute(ctx, `{"filePath": "b.txt", "operation": "undo"}`); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(workspace, "b.txt")); string(data) != "b" {
+		t.Errorf("Expected undo to restore the replaced destination, got %q", data)
+	}
+}
+
+func TestDirectoryTool_DeleteDryRun(t *testing.T) {
+	workspace := t.TempDir()
+	os.MkdirAll(filepath.Join(workspace, "build", "obj"), 0755)
+	os.WriteFile(filepath.Join(workspace, "build", "app"), []byte("12345"), 0644)
+	os.WriteFile(filepath.Join(workspace, "build", "obj", "main.o"), []byte("123"), 0644)
+	tool := NewDirectoryTool("test", "Test directory tool", map[string]string{"workspace": workspace}, zap.NewNop())
+
+	result, err := tool.Execute(context.Background(), `{"operation": "delete", "path": "build", "dry_run": true}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var preview deleteResult
+	json.Unmarshal([]byte(result), &preview)
+	if !preview.DryRun || preview.Type != "directory" || preview.Directories != 1 || preview.Files != 2 || preview.Bytes != 8 {
+		t.Errorf("Unexpected preview: %s", result)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "build")); err != nil {
+		t.Fatal("Expected the dry run to leave the directory alone")
+	}
+
+	result, err = tool.Execute(context.Background(), `{"operation": "delete", "path": "build", "confirm": true, "parent_chat_id": "chat-1"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "build")); !os.IsNotExist(err) {
+		t.Error("Expected the directory to be deleted")
+	}
+	var deleted deleteResult
+	json.Unmarshal([]byte(result), &deleted)
+	if !strings.Contains(deleted.BackupPath, filepath.Join(".aiagent", "backups", "chat-1")) {
+		t.Errorf("Expected the directory to be kept in the chat's backups, got %s", result)
+	}
+
+	// Undo puts the whole directory back
+	edit := NewFileWriteTool("Edit", "Test edit tool", map[string]string{"workspace": workspace}, zap.NewNop())
+	if _, err := edit.Execute(context.Background(), `{"filePath": "build", "operation": "undo", "parent_chat_id": "chat-1"}`); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(workspace, "build", "obj", "main.o")); string(data) != "123" {
+		t.Errorf("Expected undo to restore the deleted directory, got %q", data)
+	}
+}
+
+func TestDirectoryTool_DeleteKeepsBackupStore(t *testing.T) {
+	workspace := t.TempDir()
+	os.MkdirAll(filepath.Join(workspace, ".aiagent", "backups"), 0755)
+	tool := NewDirectoryTool("test", "Test directory tool", map[string]string{"workspace": workspace}, zap.NewNop())
+
+	if _, err := tool.Execute(context.Background(), `{"operation": "delete", "path": ".aiagent", "confirm": true}`); err == nil {
+		t.Error("Expected deleting the directory holding the backups to be refused")
+	}
+}
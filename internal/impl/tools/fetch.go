@@ -3,20 +3,29 @@ package tools
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/impl/redact"
 
 	"go.uber.org/zap"
 )
 
 const (
 	defaultUserAgent = "AIAgents/1.0 (Autonomous; +https://github.com/drujensen/aiagents)"
+
+	defaultFetchMaxBytes     = 100000
+	defaultFetchMaxRedirects = 10
+	// maxFetchDownloadBytes bounds the body read before conversion
+	maxFetchDownloadBytes = 10 * 1024 * 1024
 )
 
 type FetchTool struct {
@@ -53,6 +62,12 @@ func (t *FetchTool) UpdateConfiguration(config map[string]string) {
 	t.configuration = config
 }
 
+func (t *FetchTool) WithConfiguration(config map[string]string) entities.Tool {
+	c := *t
+	c.configuration = config
+	return &c
+}
+
 func (t *FetchTool) FullDescription() string {
 	var b strings.Builder
 
@@ -79,26 +94,90 @@ func (t *FetchTool) Schema() map[string]any {
 		"properties": map[string]any{
 			"url": map[string]any{
 				"type":        "string",
-				"description": "The URL to fetch content from",
+				"description": "The URL to request",
+			},
+			"method": map[string]any{
+				"type":        "string",
+				"description": "The HTTP method (default GET)",
+				"enum":        []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
+			},
+			"headers": map[string]any{
+				"type":                 "object",
+				"description":          "Request headers, e.g. {\"Accept\": \"application/json\"}",
+				"additionalProperties": map[string]any{"type": "string"},
+			},
+			"body": map[string]any{
+				"description": "The request body. A string is sent as is; an object or array is sent as JSON with a JSON content type unless one is set.",
+			},
+			"auth": map[string]any{
+				"type":        "object",
+				"description": "Credentials sent in the Authorization header",
+				"properties": map[string]any{
+					"type":     map[string]any{"type": "string", "enum": []string{"bearer", "basic"}},
+					"token":    map[string]any{"type": "string", "description": "The bearer token"},
+					"username": map[string]any{"type": "string", "description": "The basic auth username"},
+					"password": map[string]any{"type": "string", "description": "The basic auth password"},
+				},
+				"required": []string{"type"},
 			},
 			"format": map[string]any{
 				"type":        "string",
-				"description": "The format to return the content in (text, markdown, or html). Defaults to markdown.",
-				"enum":        []string{"text", "markdown", "html"},
+				"description": "How to return HTML pages: markdown (readable content with links), text (readable content only) or raw (the unmodified body). Other content types are returned as is. Defaults to markdown.",
+				"enum":        []string{"markdown", "text", "raw"},
+			},
+			"max_bytes": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("Truncate the returned content to this many bytes (default %d)", defaultFetchMaxBytes),
+			},
+			"max_redirects": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum redirects to follow, 0 to follow none (default %d)", defaultFetchMaxRedirects),
 			},
 			"timeout": map[string]any{
 				"type":        "number",
 				"description": "Optional timeout in seconds (max 120)",
 			},
 		},
-		"required":             []string{"url", "format"},
+		"required":             []string{"url"},
 		"additionalProperties": false,
 	}
 }
 
-func (t *FetchTool) Execute(ctx context.Context, arguments string) (string, error) {
-	t.logger.Debug("Executing fetch operation", zap.String("arguments", arguments))
+// intSetting reads a numeric argument, falling back to the configuration and
+// then to the default.
+func (t *FetchTool) intSetting(args map[string]any, key string, def int) int {
+	if v, ok := args[key].(float64); ok && v >= 0 {
+		return int(v)
+	}
+	if v, err := strconv.Atoi(t.configuration[key]); err == nil && v >= 0 {
+		return v
+	}
+	return def
+}
+
+// fetchAuth is the auth argument: a bearer token or basic credentials.
+type fetchAuth struct {
+	Type     string `json:"type"`
+	Token    string `json:"token"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Mutates reports methods other than GET, HEAD and OPTIONS, which can change
+// the server's state.
+func (t *FetchTool) Mutates(arguments string) bool {
+	var args struct {
+		Method string `json:"method"`
+	}
+	json.Unmarshal([]byte(arguments), &args)
+	switch strings.ToUpper(args.Method) {
+	case "", "GET", "HEAD", "OPTIONS":
+		return false
+	}
+	return true
+}
 
+func (t *FetchTool) Execute(ctx context.Context, arguments string) (string, error) {
 	var rawArgs map[string]interface{}
 	if err := json.Unmarshal([]byte(arguments), &rawArgs); err != nil {
 		t.logger.Error("Failed to parse arguments", zap.Error(err))
@@ -109,10 +188,27 @@ func (t *FetchTool) Execute(ctx context.Context, arguments string) (string, erro
 	if u, ok := rawArgs["url"].(string); ok {
 		url = u
 	}
+	method := "GET"
+	if m, ok := rawArgs["method"].(string); ok && m != "" {
+		method = strings.ToUpper(m)
+	}
+	switch method {
+	case "GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS":
+	default:
+		return "", fmt.Errorf("unsupported method: %s", method)
+	}
 	format := "markdown"
-	if f, ok := rawArgs["format"].(string); ok {
+	if f, ok := rawArgs["format"].(string); ok && f != "" {
 		format = f
 	}
+	if format == "html" {
+		format = "raw" // the name used before raw was introduced
+	}
+	if format != "markdown" && format != "text" && format != "raw" {
+		return "", fmt.Errorf("unsupported format: %s", format)
+	}
+	maxBytes := t.intSetting(rawArgs, "max_bytes", defaultFetchMaxBytes)
+	maxRedirects := t.intSetting(rawArgs, "max_redirects", defaultFetchMaxRedirects)
 	timeoutVal, _ := rawArgs["timeout"].(float64)
 	timeout := int(timeoutVal)
 
@@ -121,8 +217,68 @@ func (t *FetchTool) Execute(ctx context.Context, arguments string) (string, erro
 		return "", fmt.Errorf("url is required")
 	}
 
+	headers := make(http.Header)
+	if h, ok := rawArgs["headers"].(map[string]any); ok {
+		for key, value := range h {
+			headers.Set(key, fmt.Sprint(value))
+		}
+	}
+
+	// A string body is sent as is; anything else is encoded as JSON
+	var body io.Reader
+	switch b := rawArgs["body"].(type) {
+	case nil:
+	case string:
+		body = strings.NewReader(b)
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode body: %v", err)
+		}
+		body = bytes.NewReader(data)
+		if headers.Get("Content-Type") == "" {
+			headers.Set("Content-Type", "application/json")
+		}
+	}
+
+	var typedArgs struct {
+		Auth *fetchAuth `json:"auth"`
+	}
+	json.Unmarshal([]byte(arguments), &typedArgs)
+	var secrets []string
+	if auth := typedArgs.Auth; auth != nil {
+		switch strings.ToLower(auth.Type) {
+		case "bearer":
+			if auth.Token == "" {
+				return "", fmt.Errorf("bearer auth requires a token")
+			}
+			headers.Set("Authorization", "Bearer "+auth.Token)
+			secrets = append(secrets, auth.Token)
+		case "basic":
+			credentials := base64.StdEncoding.EncodeToString([]byte(auth.Username + ":" + auth.Password))
+			headers.Set("Authorization", "Basic "+credentials)
+			secrets = append(secrets, auth.Password, credentials)
+		default:
+			return "", fmt.Errorf("unsupported auth type: %s", auth.Type)
+		}
+	}
+
+	redacted := t.redactedHeaders()
+	t.logger.Debug("Executing fetch operation",
+		zap.String("method", method),
+		zap.String("url", url),
+		zap.Any("headers", redactHeaders(headers, redacted)))
+
+	// Copy the client so concurrent calls don't share per-call settings
+	client := *t.client
 	if timeout > 0 && timeout <= 120 {
-		t.client.Timeout = time.Duration(timeout) * time.Second
+		client.Timeout = time.Duration(timeout) * time.Second
+	}
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) > maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		return nil
 	}
 
 	userAgent := t.configuration["user_agent"]
@@ -130,167 +286,119 @@ func (t *FetchTool) Execute(ctx context.Context, arguments string) (string, erro
 		userAgent = defaultUserAgent
 	}
 
-	// For webfetch, default to GET
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return "", err
 	}
 	req.Header.Set("User-Agent", userAgent)
+	for key, values := range headers {
+		req.Header[key] = values
+	}
 
-	resp, err := t.client.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("request failed: %v", err)
+		return "", fmt.Errorf("request failed: %v", redact.Values(err.Error(), secrets...))
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	// Read one byte past the cap to tell a capped body from one that fits
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxFetchDownloadBytes+1))
 	if err != nil {
 		return "", fmt.Errorf("failed to read response: %v", err)
 	}
-
-	content := string(body)
-
-	// Convert based on format
-	switch format {
-	case "text":
-		// Already text
-	case "html":
-		// Assume it's already HTML
-	case "markdown":
-		// Simple conversion: wrap in code blocks or something, but for now, return as is
-		content = fmt.Sprintf("```\n%s\n```", content)
-	default:
-		return "", fmt.Errorf("unsupported format: %s", format)
+	truncated := false
+	if len(respBody) > maxFetchDownloadBytes {
+		respBody = respBody[:maxFetchDownloadBytes]
+		truncated = true
 	}
 
-	return fmt.Sprintf(`{"content": %q, "status_code": %d}`, content, resp.StatusCode), nil
-}
-
-func (t *FetchTool) get(url string, headers []string) (string, error) {
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return "", err
+	content := string(respBody)
+	contentType := resp.Header.Get("Content-Type")
+	if format != "raw" && isHTMLContent(contentType, respBody) {
+		content = convertHTML(content, format == "markdown", resp.Request.URL)
 	}
-	return t.doRequest(req, headers)
-}
 
-func (t *FetchTool) post(url string, headers []string, body string) (string, error) {
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer([]byte(body)))
-	if err != nil {
-		return "", err
+	if maxBytes > 0 && len(content) > maxBytes {
+		content = truncateUTF8(content, maxBytes)
+		truncated = true
 	}
-	return t.doRequest(req, headers)
-}
 
-func (t *FetchTool) patch(url string, headers []string, body string) (string, error) {
-	req, err := http.NewRequest("PATCH", url, bytes.NewBuffer([]byte(body)))
-	if err != nil {
-		return "", err
+	responseHeaders := make(map[string]string, len(resp.Header))
+	for key, values := range resp.Header {
+		responseHeaders[key] = strings.Join(values, ", ")
 	}
-	return t.doRequest(req, headers)
-}
 
-func (t *FetchTool) put(url string, headers []string, body string) (string, error) {
-	req, err := http.NewRequest("PUT", url, bytes.NewBuffer([]byte(body)))
+	result, err := json.Marshal(struct {
+		Content     string            `json:"content"`
+		Method      string            `json:"method"`
+		StatusCode  int               `json:"status_code"`
+		URL         string            `json:"url"`
+		ContentType string            `json:"content_type"`
+		Headers     map[string]string `json:"headers,omitempty"`
+		Truncated   bool              `json:"truncated,omitempty"`
+	}{
+		Content:     content,
+		Method:      method,
+		StatusCode:  resp.StatusCode,
+		URL:         resp.Request.URL.String(),
+		ContentType: contentType,
+		Headers:     responseHeaders,
+		Truncated:   truncated,
+	})
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("failed to marshal response: %v", err)
 	}
-	return t.doRequest(req, headers)
-}
 
-func (t *FetchTool) deleteRequest(url string, headers []string) (string, error) {
-	req, err := http.NewRequest("DELETE", url, nil)
-	if err != nil {
-		return "", err
-	}
-	return t.doRequest(req, headers)
+	t.logger.Debug("Request completed",
+		zap.String("method", method),
+		zap.Int("status", resp.StatusCode),
+		zap.String("url", resp.Request.URL.String()),
+		zap.Any("headers", redactHeaders(resp.Header, redacted)))
+	return string(result), nil
 }
 
-func (t *FetchTool) head(url string, headers []string) (string, error) {
-	req, err := http.NewRequest("HEAD", url, nil)
-	if err != nil {
-		return "", err
+// redactedHeaders are the headers whose values are left out of the log: the
+// defaults and those named in the comma separated redact_headers setting.
+func (t *FetchTool) redactedHeaders() map[string]bool {
+	redacted := make(map[string]bool)
+	for _, name := range redact.DefaultHeaders {
+		redacted[http.CanonicalHeaderKey(name)] = true
 	}
-	return t.doRequest(req, headers)
-}
-
-func (t *FetchTool) options(url string, headers []string) (string, error) {
-	req, err := http.NewRequest("OPTIONS", url, nil)
-	if err != nil {
-		return "", err
+	for _, name := range strings.Split(t.configuration["redact_headers"], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			redacted[http.CanonicalHeaderKey(name)] = true
+		}
 	}
-	return t.doRequest(req, headers)
+	return redacted
 }
 
-func (t *FetchTool) doRequest(req *http.Request, headers []string) (string, error) {
-	// Set default User-Agent
-	userAgent := t.configuration["user_agent"]
-	if userAgent == "" {
-		userAgent = defaultUserAgent
-	}
-	req.Header.Set("User-Agent", userAgent)
-
-	// Add custom headers
-	for _, header := range headers {
-		parts := strings.SplitN(header, ":", 2)
-		if len(parts) == 2 {
-			key := strings.TrimSpace(parts[0])
-			value := strings.TrimSpace(parts[1])
-			req.Header.Set(key, value)
+// redactHeaders returns headers for logging with the redacted values
+// replaced.
+func redactHeaders(headers http.Header, redacted map[string]bool) map[string]string {
+	out := make(map[string]string, len(headers))
+	for key, values := range headers {
+		if redacted[http.CanonicalHeaderKey(key)] {
+			out[key] = redact.Mask
+			continue
 		}
+		out[key] = strings.Join(values, ", ")
 	}
+	return out
+}
 
-	resp, err := t.client.Do(req)
-	if err != nil {
-		t.logger.Error("Request failed", zap.Error(err))
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		t.logger.Error("Failed to read response body", zap.Error(err))
-		return "", err
-	}
-
-	// Create TUI-friendly summary
-	var summary strings.Builder
-	summary.WriteString(fmt.Sprintf("🌐 HTTP %s: %s\n", req.Method, req.URL.String()))
-	summary.WriteString(fmt.Sprintf("📊 Status: %d %s\n", resp.StatusCode, http.StatusText(resp.StatusCode)))
-
-	// Show response body preview (first 200 characters)
-	bodyStr := string(body)
-	if len(bodyStr) > 200 {
-		summary.WriteString(fmt.Sprintf("📄 Response: %s...\n", bodyStr[:200]))
-	} else {
-		summary.WriteString(fmt.Sprintf("📄 Response: %s\n", bodyStr))
-	}
-
-	// Create JSON response with summary for TUI and full data for AI
-	response := struct {
-		Summary string `json:"summary"`
-		Method  string `json:"method"`
-		URL     string `json:"url"`
-		Status  int    `json:"status"`
-		Body    string `json:"body"`
-	}{
-		Summary: summary.String(),
-		Method:  req.Method,
-		URL:     req.URL.String(),
-		Status:  resp.StatusCode,
-		Body:    bodyStr,
+func isHTMLContent(contentType string, body []byte) bool {
+	if contentType != "" {
+		return strings.Contains(strings.ToLower(contentType), "html")
 	}
+	return strings.Contains(strings.ToLower(http.DetectContentType(body)), "html")
+}
 
-	jsonResult, err := json.Marshal(response)
-	if err != nil {
-		t.logger.Error("Failed to marshal fetch response", zap.Error(err))
-		return summary.String(), nil // Fallback to summary only
+// truncateUTF8 cuts s to at most n bytes without splitting a character.
+func truncateUTF8(s string, n int) string {
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
 	}
-
-	t.logger.Debug("Request completed",
-		zap.Int("status", resp.StatusCode),
-		zap.String("url", req.URL.String()))
-	return string(jsonResult), nil
+	return s[:n]
 }
 
 func (t *FetchTool) DisplayName(ui string, arguments string) (string, string) {
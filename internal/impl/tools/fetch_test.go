@@ -0,0 +1,238 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+const testPage = `<!DOCTYPE html>
+<html>
+<head><title>Docs</title><style>body { color: red; }</style></head>
+<body>
+<script>var tracking = "<p>not content</p>";</script>
+<nav><a href="/">Home</a></nav>
+<h1>Getting  Started</h1>
+<p>Install the <strong>aiagent</strong> binary &amp; read the <a href="/guide">guide</a>.</p>
+<ul><li>First</li><li>Second <em>step</em></li></ul>
+<pre><code>go build ./...
+go test ./...</code></pre>
+</body>
+</html>`
+
+type fetchResult struct {
+	Content     string            `json:"content"`
+	StatusCode  int               `json:"status_code"`
+	URL         string            `json:"url"`
+	ContentType string            `json:"content_type"`
+	Method      string            `json:"method"`
+	Headers     map[string]string `json:"headers"`
+	Truncated   bool              `json:"truncated"`
+}
+
+func newFetchTestServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(testPage))
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page", http.StatusFound)
+	})
+	mux.HandleFunc("/moved-twice", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/moved", http.StatusFound)
+	})
+	mux.HandleFunc("/data.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name": "<b>not html</b>"}`))
+	})
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", "42")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{
+			"method":        r.Method,
+			"content_type":  r.Header.Get("Content-Type"),
+			"authorization": r.Header.Get("Authorization"),
+			"accept":        r.Header.Get("Accept"),
+			"body":          string(body),
+		})
+	})
+	return httptest.NewServer(mux)
+}
+
+func fetch(t *testing.T, tool *FetchTool, args map[string]any) fetchResult {
+	t.Helper()
+	arguments, _ := json.Marshal(args)
+	out, err := tool.Execute(context.Background(), string(arguments))
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	var result fetchResult
+	if err := json.Unmarshal([]byte(out), &result); err != nil {
+		t.Fatalf("result is not JSON: %v\n%s", err, out)
+	}
+	return result
+}
+
+func TestFetchTool_Formats(t *testing.T) {
+	server := newFetchTestServer()
+	defer server.Close()
+	tool := NewFetchTool("WebFetch", "test", map[string]string{}, zap.NewNop())
+
+	markdown := fetch(t, tool, map[string]any{"url": server.URL + "/page"})
+	want := "# Getting Started\n\n" +
+		"Install the **aiagent** binary & read the [guide](" + server.URL + "/guide).\n\n" +
+		"- First\n- Second _step_\n\n" +
+		"```\ngo build ./...\ngo test ./...\n```"
+	if markdown.Content != want {
+		t.Errorf("markdown content =\n%s\nwant\n%s", markdown.Content, want)
+	}
+	if !strings.HasPrefix(markdown.ContentType, "text/html") || markdown.StatusCode != http.StatusOK {
+		t.Errorf("unexpected content type %q or status %d", markdown.ContentType, markdown.StatusCode)
+	}
+
+	text := fetch(t, tool, map[string]any{"url": server.URL + "/page", "format": "text"})
+	for _, unwanted := range []string{"#", "**", "](", "tracking", "color: red", "Home"} {
+		if strings.Contains(text.Content, unwanted) {
+			t.Errorf("text content should not contain %q:\n%s", unwanted, text.Content)
+		}
+	}
+	if !strings.Contains(text.Content, "Install the aiagent binary & read the guide.") {
+		t.Errorf("text content is missing the paragraph:\n%s", text.Content)
+	}
+
+	raw := fetch(t, tool, map[string]any{"url": server.URL + "/page", "format": "raw"})
+	if raw.Content != testPage {
+		t.Errorf("raw content should be the unmodified body")
+	}
+
+	data := fetch(t, tool, map[string]any{"url": server.URL + "/data.json"})
+	if data.Content != `{"name": "<b>not html</b>"}` {
+		t.Errorf("non-HTML content should be returned as is, got %q", data.Content)
+	}
+}
+
+func TestFetchTool_Redirects(t *testing.T) {
+	server := newFetchTestServer()
+	defer server.Close()
+	tool := NewFetchTool("WebFetch", "test", map[string]string{}, zap.NewNop())
+
+	result := fetch(t, tool, map[string]any{"url": server.URL + "/moved-twice"})
+	if result.URL != server.URL+"/page" {
+		t.Errorf("expected the final URL after redirects, got %s", result.URL)
+	}
+
+	_, err := tool.Execute(context.Background(), `{"url": "`+server.URL+`/moved-twice", "max_redirects": 1}`)
+	if err == nil || !strings.Contains(err.Error(), "stopped after 1 redirects") {
+		t.Errorf("expected the redirect limit to stop the request, got %v", err)
+	}
+}
+
+func TestFetchTool_MaxBytes(t *testing.T) {
+	server := newFetchTestServer()
+	defer server.Close()
+	tool := NewFetchTool("WebFetch", "test", map[string]string{"max_bytes": "20"}, zap.NewNop())
+
+	result := fetch(t, tool, map[string]any{"url": server.URL + "/page"})
+	if !result.Truncated || len(result.Content) > 20 {
+		t.Errorf("expected content truncated to 20 bytes, got %d bytes (truncated=%v)", len(result.Content), result.Truncated)
+	}
+
+	result = fetch(t, tool, map[string]any{"url": server.URL + "/page", "max_bytes": 0})
+	if result.Truncated {
+		t.Error("max_bytes 0 should disable truncation")
+	}
+}
+
+func TestFetchTool_Requests(t *testing.T) {
+	server := newFetchTestServer()
+	defer server.Close()
+	core, logs := observer.New(zapcore.DebugLevel)
+	tool := NewFetchTool("WebFetch", "test", map[string]string{"redact_headers": "X-Secret"}, zap.New(core))
+
+	result := fetch(t, tool, map[string]any{
+		"url":     server.URL + "/echo",
+		"method":  "post",
+		"headers": map[string]any{"Accept": "application/json", "X-Secret": "hidden-value"},
+		"body":    map[string]any{"name": "widget"},
+		"auth":    map[string]any{"type": "bearer", "token": "secret-token"},
+	})
+	if result.StatusCode != http.StatusCreated || result.Method != "POST" || result.Headers["X-Request-Id"] != "42" {
+		t.Errorf("unexpected status %d, method %s or headers %v", result.StatusCode, result.Method, result.Headers)
+	}
+	var echo map[string]string
+	if err := json.Unmarshal([]byte(result.Content), &echo); err != nil {
+		t.Fatalf("echo is not JSON: %v", err)
+	}
+	want := map[string]string{
+		"method":        "POST",
+		"content_type":  "application/json",
+		"authorization": "Bearer secret-token",
+		"accept":        "application/json",
+		"body":          `{"name":"widget"}`,
+	}
+	for key, value := range want {
+		if echo[key] != value {
+			t.Errorf("%s = %q, want %q", key, echo[key], value)
+		}
+	}
+
+	for _, entry := range logs.All() {
+		for _, field := range entry.Context {
+			logged := fmt.Sprint(field.Interface, field.String)
+			if strings.Contains(logged, "secret-token") || strings.Contains(logged, "hidden-value") {
+				t.Errorf("secret logged in %q: %s", entry.Message, logged)
+			}
+		}
+	}
+
+	result = fetch(t, tool, map[string]any{
+		"url":    server.URL + "/echo",
+		"method": "PUT",
+		"body":   "plain",
+		"auth":   map[string]any{"type": "basic", "username": "user", "password": "pass"},
+	})
+	json.Unmarshal([]byte(result.Content), &echo)
+	if echo["authorization"] != "Basic dXNlcjpwYXNz" || echo["body"] != "plain" || echo["content_type"] != "" {
+		t.Errorf("unexpected basic auth request: %v", echo)
+	}
+
+	if _, err := tool.Execute(context.Background(), `{"url": "`+server.URL+`/echo", "method": "TRACE"}`); err == nil {
+		t.Error("expected an unsupported method to be rejected")
+	}
+	if tool.Mutates(`{"url": "x"}`) || !tool.Mutates(`{"url": "x", "method": "delete"}`) {
+		t.Error("expected only methods with side effects to mutate")
+	}
+}
+
+func TestConvertHTML_Markup(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{"quoted > in an attribute", `<p><a href="/x" title="a > b">link</a> text</p>`, "[link](/x) text"},
+		{"comment with markup", `<p>before<!-- <b>not bold</b> --> after</p>`, "before after"},
+		{"cdata", `<p>kept<![CDATA[dropped]]> text</p>`, "kept text"},
+		{"script with a closing tag in a string", `<p>one</p><script>var s = "<p>two</p>";</script><p>three</p>`, "one\n\nthree"},
+		{"entities", `<p>Go &amp; &lt;tools&gt;</p>`, "Go & <tools>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := convertHTML(tt.doc, true, nil); got != tt.want {
+				t.Errorf("convertHTML(%q) = %q, want %q", tt.doc, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package tools
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultArchiveMaxBytes   = 10 * 1024 * 1024 // 10MB of decompressed content
+	defaultArchiveMaxEntries = 1000
+	defaultArchiveMaxRatio   = 100
+)
+
+var errDecompressionRatio = errors.New("decompression ratio exceeds limit, the archive may be a zip bomb")
+
+// archiveKind returns the archive format implied by the file name, or "" for
+// regular files.
+func archiveKind(path string) string {
+	lower := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "tar.gz"
+	case strings.HasSuffix(lower, ".tar"):
+		return "tar"
+	case strings.HasSuffix(lower, ".zip"):
+		return "zip"
+	case strings.HasSuffix(lower, ".gz"):
+		return "gzip"
+	}
+	return ""
+}
+
+// archiveLimits bound how much an archive read may decompress.
+type archiveLimits struct {
+	MaxBytes   int64
+	MaxEntries int
+	MaxRatio   int64
+}
+
+func archiveLimitsFromConfig(configuration map[string]string) archiveLimits {
+	limits := archiveLimits{
+		MaxBytes:   defaultArchiveMaxBytes,
+		MaxEntries: defaultArchiveMaxEntries,
+		MaxRatio:   defaultArchiveMaxRatio,
+	}
+	if n, err := strconv.ParseInt(configuration["archive_max_bytes"], 10, 64); err == nil && n > 0 {
+		limits.MaxBytes = n
+	}
+	if n, err := strconv.Atoi(configuration["archive_max_entries"]); err == nil && n > 0 {
+		limits.MaxEntries = n
+	}
+	if n, err := strconv.ParseInt(configuration["archive_max_ratio"], 10, 64); err == nil && n > 0 {
+		limits.MaxRatio = n
+	}
+	return limits
+}
+
+type archiveEntry struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	Dir  bool   `json:"dir,omitempty"`
+}
+
+type archiveListing struct {
+	Archive   string         `json:"archive"`
+	Entries   []archiveEntry `json:"entries"`
+	Total     int            `json:"total"`
+	Truncated bool           `json:"truncated"`
+	Error     string         `json:"error"`
+}
+
+// ratioReader fails once more than limit bytes have been read, which caps the
+// output of a decompressor relative to its compressed input.
+type ratioReader struct {
+	r     io.Reader
+	limit int64
+	n     int64
+}
+
+func (r *ratioReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	if r.n > r.limit {
+		return n, errDecompressionRatio
+	}
+	return n, err
+}
+
+func (l archiveLimits) guard(r io.Reader, compressedSize int64) io.Reader {
+	if compressedSize <= 0 {
+		compressedSize = 1
+	}
+	return &ratioReader{r: r, limit: compressedSize * l.MaxRatio}
+}
+
+// readAll reads the decompressed content, failing when it exceeds MaxBytes.
+func (l archiveLimits) readAll(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, l.MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > l.MaxBytes {
+		return nil, fmt.Errorf("decompressed content exceeds the %d byte limit", l.MaxBytes)
+	}
+	return data, nil
+}
+
+// readArchive decompresses a gzip file or an entry of a zip or tar archive.
+// Without an entry, archives are listed instead and the listing is returned
+// as the JSON result. Entries are only ever read into memory, never
+// extracted to disk.
+func readArchive(path, kind, entry string, limits archiveLimits) ([]byte, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open file: %v", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch kind {
+	case "gzip":
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid gzip file: %v", err)
+		}
+		defer gz.Close()
+		data, err := limits.readAll(limits.guard(gz, info.Size()))
+		return data, "", err
+	case "zip":
+		return readZip(file, info.Size(), entry, limits)
+	case "tar", "tar.gz":
+		var r io.Reader = file
+		if kind == "tar.gz" {
+			gz, err := gzip.NewReader(file)
+			if err != nil {
+				return nil, "", fmt.Errorf("invalid gzip file: %v", err)
+			}
+			defer gz.Close()
+			r = limits.guard(gz, info.Size())
+		}
+		return readTar(r, kind, entry, limits)
+	}
+	return nil, "", fmt.Errorf("unsupported archive format: %s", kind)
+}
+
+func readZip(file *os.File, size int64, entry string, limits archiveLimits) ([]byte, string, error) {
+	zr, err := zip.NewReader(file, size)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid zip file: %v", err)
+	}
+
+	if entry == "" {
+		listing := archiveListing{Archive: "zip", Total: len(zr.File)}
+		for _, f := range zr.File {
+			if len(listing.Entries) >= limits.MaxEntries {
+				listing.Truncated = true
+				break
+			}
+			listing.Entries = append(listing.Entries, archiveEntry{
+				Name: f.Name,
+				Size: int64(f.UncompressedSize64),
+				Dir:  f.FileInfo().IsDir(),
+			})
+		}
+		return encodeListing(listing)
+	}
+
+	for _, f := range zr.File {
+		if f.Name != entry {
+			continue
+		}
+		if f.FileInfo().IsDir() {
+			return nil, "", fmt.Errorf("entry %s is a directory", entry)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to open entry %s: %v", entry, err)
+		}
+		defer rc.Close()
+		data, err := limits.readAll(limits.guard(rc, int64(f.CompressedSize64)))
+		return data, "", err
+	}
+	return nil, "", fmt.Errorf("entry %s not found in archive", entry)
+}
+
+func readTar(r io.Reader, kind, entry string, limits archiveLimits) ([]byte, string, error) {
+	tr := tar.NewReader(r)
+	listing := archiveListing{Archive: kind}
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read tar archive: %v", err)
+		}
+
+		if entry == "" {
+			listing.Total++
+			if len(listing.Entries) >= limits.MaxEntries {
+				listing.Truncated = true
+				continue
+			}
+			listing.Entries = append(listing.Entries, archiveEntry{
+				Name: header.Name,
+				Size: header.Size,
+				Dir:  header.Typeflag == tar.TypeDir,
+			})
+			continue
+		}
+
+		if header.Name != entry {
+			continue
+		}
+		if header.Typeflag != tar.TypeReg {
+			return nil, "", fmt.Errorf("entry %s is not a regular file", entry)
+		}
+		data, err := limits.readAll(tr)
+		return data, "", err
+	}
+
+	if entry == "" {
+		return encodeListing(listing)
+	}
+	return nil, "", fmt.Errorf("entry %s not found in archive", entry)
+}
+
+func encodeListing(listing archiveListing) ([]byte, string, error) {
+	if listing.Entries == nil {
+		listing.Entries = []archiveEntry{}
+	}
+	data, err := json.Marshal(listing)
+	if err != nil {
+		return nil, "", err
+	}
+	return nil, string(data), nil
+}
@@ -0,0 +1,220 @@
+package tools
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultBackupMaxBytes = 50 * 1024 * 1024 // 50MB across all chats
+
+// absentSuffix marks a backup of a file that did not exist yet, so undoing
+// the change removes the file again.
+const absentSuffix = ".absent"
+
+// fileBackupStore keeps the content of files before they are changed under
+// .aiagent/backups/<chatID>/ in the workspace. Backups are named after the
+// escaped workspace relative path and the time of the change.
+type fileBackupStore struct {
+	root     string
+	maxBytes int64
+}
+
+func newFileBackupStore(workspace string, configuration map[string]string) *fileBackupStore {
+	maxBytes := int64(defaultBackupMaxBytes)
+	if n, err := strconv.ParseInt(configuration["backup_max_bytes"], 10, 64); err == nil && n > 0 {
+		maxBytes = n
+	}
+	return &fileBackupStore{
+		root:     filepath.Join(workspace, ".aiagent", "backups"),
+		maxBytes: maxBytes,
+	}
+}
+
+func (s *fileBackupStore) chatDir(chatID string) string {
+	if chatID == "" {
+		chatID = "default"
+	}
+	return filepath.Join(s.root, url.PathEscape(chatID))
+}
+
+// Save copies the current content of fullPath into the store and returns the
+// backup path. relPath identifies the file within the workspace.
+func (s *fileBackupStore) Save(chatID, relPath, fullPath string) (string, error) {
+	dir := s.chatDir(chatID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %v", err)
+	}
+
+	name := backupName(relPath)
+	content, err := os.ReadFile(fullPath)
+	if os.IsNotExist(err) {
+		name += absentSuffix
+		content = nil
+	} else if err != nil {
+		return "", fmt.Errorf("failed to read file for backup: %v", err)
+	}
+
+	backupPath := filepath.Join(dir, name)
+	if err := os.WriteFile(backupPath, content, 0644); err != nil {
+		return "", fmt.Errorf("failed to write backup: %v", err)
+	}
+
+	s.evict(backupPath)
+	return backupPath, nil
+}
+
+// Trash moves fullPath, a file or a whole directory, into the store instead
+// of deleting it and returns the backup path, so an undo can put it back.
+func (s *fileBackupStore) Trash(chatID, relPath, fullPath string) (string, error) {
+	dir := s.chatDir(chatID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %v", err)
+	}
+
+	backupPath := filepath.Join(dir, backupName(relPath))
+	if err := os.Rename(fullPath, backupPath); err != nil {
+		return "", fmt.Errorf("failed to move %s to the backups: %v", relPath, err)
+	}
+
+	s.evict(backupPath)
+	return backupPath, nil
+}
+
+// Contains reports whether fullPath is the store or holds it, which can't be
+// moved into itself.
+func (s *fileBackupStore) Contains(fullPath string) bool {
+	rel, err := filepath.Rel(fullPath, s.root)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func backupName(relPath string) string {
+	return fmt.Sprintf("%s.%d", url.PathEscape(filepath.ToSlash(relPath)), time.Now().UnixNano())
+}
+
+// Restore puts back the most recent backup of relPath and removes it from the
+// store, so repeated undos walk back through earlier versions. It returns the
+// backup that was restored.
+func (s *fileBackupStore) Restore(chatID, relPath, fullPath string) (string, error) {
+	dir := s.chatDir(chatID)
+	prefix := url.PathEscape(filepath.ToSlash(relPath)) + "."
+
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read backups: %v", err)
+	}
+
+	var latest string
+	var latestStamp int64
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(name, prefix), absentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		if latest == "" || stamp > latestStamp {
+			latest, latestStamp = name, stamp
+		}
+	}
+	if latest == "" {
+		return "", fmt.Errorf("no backup found for %s", relPath)
+	}
+
+	backupPath := filepath.Join(dir, latest)
+	if info, err := os.Stat(backupPath); err == nil && info.IsDir() {
+		// A deleted directory goes back as a whole
+		if _, err := os.Lstat(fullPath); err == nil {
+			return "", fmt.Errorf("%s exists; move it away before restoring the deleted directory", relPath)
+		}
+		if err := os.Rename(backupPath, fullPath); err != nil {
+			return "", fmt.Errorf("failed to restore directory: %v", err)
+		}
+		return backupPath, nil
+	}
+	if strings.HasSuffix(latest, absentSuffix) {
+		if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to remove file: %v", err)
+		}
+	} else {
+		content, err := os.ReadFile(backupPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read backup: %v", err)
+		}
+		if err := os.WriteFile(fullPath, content, 0644); err != nil {
+			return "", fmt.Errorf("failed to restore file: %v", err)
+		}
+	}
+
+	if err := os.Remove(backupPath); err != nil {
+		return "", fmt.Errorf("failed to remove restored backup: %v", err)
+	}
+	return backupPath, nil
+}
+
+// evict removes the least recently written backups until the store fits in
+// maxBytes. A deleted directory counts as one backup. The backup that was
+// just written is always kept.
+func (s *fileBackupStore) evict(keep string) {
+	type backup struct {
+		path    string
+		size    int64
+		written int64
+	}
+
+	var backups []backup
+	var total int64
+	chats, _ := os.ReadDir(s.root)
+	for _, chat := range chats {
+		entries, _ := os.ReadDir(filepath.Join(s.root, chat.Name()))
+		for _, entry := range entries {
+			path := filepath.Join(s.root, chat.Name(), entry.Name())
+			name := strings.TrimSuffix(entry.Name(), absentSuffix)
+			written, err := strconv.ParseInt(name[strings.LastIndex(name, ".")+1:], 10, 64)
+			if err != nil {
+				continue
+			}
+			size := backupSize(path)
+			backups = append(backups, backup{path: path, size: size, written: written})
+			total += size
+		}
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].written < backups[j].written
+	})
+	for _, b := range backups {
+		if total <= s.maxBytes {
+			break
+		}
+		if b.path == keep {
+			continue
+		}
+		if os.RemoveAll(b.path) == nil {
+			total -= b.size
+		}
+	}
+}
+
+// backupSize is the size of a backup file, or of everything in a deleted
+// directory.
+func backupSize(path string) int64 {
+	var size int64
+	filepath.WalkDir(path, func(_ string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
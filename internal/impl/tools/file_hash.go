@@ -0,0 +1,79 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// maxHashedFileSize is the largest file reads report a hash for; larger files
+// are too big to edit anyway.
+const maxHashedFileSize = 10 * 1024 * 1024
+
+// contentHash identifies a version of a file's content. Reads return it so an
+// edit can pass it back as expected_hash and fail if the file has changed
+// since, instead of applying a change made against stale content.
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:8])
+}
+
+// fileHash returns the content hash of the file, or an empty hash when it is
+// larger than maxHashedFileSize.
+func fileHash(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if info, err := file.Stat(); err != nil || info.Size() > maxHashedFileSize {
+		return "", err
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8]), nil
+}
+
+// hashReader returns a reader that passes r's bytes through while hashing
+// them, so the hash describes exactly the content that was read. sum reads
+// and hashes whatever the caller left unread and returns the content hash of
+// all of r.
+func hashReader(r io.Reader) (io.Reader, func() (string, error)) {
+	h := sha256.New()
+	sum := func() (string, error) {
+		if _, err := io.Copy(h, r); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(h.Sum(nil)[:8]), nil
+	}
+	return io.TeeReader(r, h), sum
+}
+
+// checkExpectedHash fails when the file's content no longer has the hash the
+// caller read. An empty expected hash skips the check.
+func checkExpectedHash(fullPath, filePath, expected string) error {
+	if expected == "" {
+		return nil
+	}
+	actual, err := fileHash(fullPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("file changed since read: %s no longer exists", filePath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to hash file: %s", err.Error())
+	}
+	if actual == "" {
+		return fmt.Errorf("%s is too large to check its hash", filePath)
+	}
+	if actual != expected {
+		return fmt.Errorf("file changed since read: %s has hash %s, not the expected %s. Read it again and make the change against its current content", filePath, actual, expected)
+	}
+	return nil
+}
@@ -2,15 +2,19 @@ package tools
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"html"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/impl/workspacepath"
 
 	"go.uber.org/zap"
 )
@@ -47,8 +51,14 @@ func (t *FileReadTool) UpdateConfiguration(config map[string]string) {
 	t.configuration = config
 }
 
+func (t *FileReadTool) WithConfiguration(config map[string]string) entities.Tool {
+	c := *t
+	c.configuration = config
+	return &c
+}
+
 func (t *FileReadTool) FullDescription() string {
-	return fmt.Sprintf("%s\n\nParameters:\n- filePath: The absolute path to the file or directory to read\n- offset: The line number to start reading from (1-indexed)\n- limit: The maximum number of lines to read (defaults to 2000)", t.Description())
+	return fmt.Sprintf("%s\n\nParameters:\n- filePath: The absolute path to the file or directory to read\n- offset: The line number to start reading from (1-indexed)\n- limit: The maximum number of lines to read (defaults to 2000)\n- offset_line: The line number to start paging from (1-indexed, same as offset)\n- end_line: The last line to read (inclusive)\n- max_bytes: The maximum number of bytes of content to return\n- entry: The entry to read from a zip or tar archive\n- symbol: The function, type, class, variable, constant or method (Type.Method) whose current source to read, in Go, Python, JavaScript or TypeScript\n- list_symbols: List the file's imports and declarations with their lines instead of reading it\n\nWhen paging parameters are supplied, files larger than 10MB can be read, binary files are reported instead of read, and the result includes has_more and next_line to continue from. Gzip files are decompressed transparently, and zip and tar archives are listed unless an entry is given. Reads of a file return its hash, which FileWrite takes as expected_hash to refuse changes if the file changed since.", t.Description())
 }
 
 func (t *FileReadTool) Schema() map[string]any {
@@ -67,6 +77,30 @@ func (t *FileReadTool) Schema() map[string]any {
 				"type":        "number",
 				"description": "The maximum number of lines to read (defaults to 2000)",
 			},
+			"offset_line": map[string]any{
+				"type":        "number",
+				"description": "The line number to start paging from (1-indexed). Enables paging of large files",
+			},
+			"end_line": map[string]any{
+				"type":        "number",
+				"description": "The last line to read (inclusive). Enables paging of large files",
+			},
+			"max_bytes": map[string]any{
+				"type":        "number",
+				"description": "The maximum number of bytes of content to return. Enables paging of large files",
+			},
+			"entry": map[string]any{
+				"type":        "string",
+				"description": "The entry to read from a zip or tar archive. Omit it to list the archive's entries",
+			},
+			"symbol": map[string]any{
+				"type":        "string",
+				"description": "Read only this declaration, found wherever it currently is: the name of a top-level declaration, or Type.Method for a method",
+			},
+			"list_symbols": map[string]any{
+				"type":        "boolean",
+				"description": "List the file's imports and declarations with their lines instead of reading it (default false)",
+			},
 		},
 		"required":             []string{"filePath"},
 		"additionalProperties": false,
@@ -83,21 +117,10 @@ func (t *FileReadTool) validatePath(path string) (string, error) {
 		}
 	}
 
-	var fullPath string
-	if filepath.IsAbs(path) {
-		if !strings.HasPrefix(path, workspace) {
-			t.logger.Error("Absolute path is outside workspace", zap.String("path", path))
-			return "", fmt.Errorf("absolute path is outside workspace")
-		}
-		fullPath = path
-	} else {
-		fullPath = filepath.Join(workspace, path)
-	}
-
-	rel, err := filepath.Rel(workspace, fullPath)
-	if err != nil || strings.HasPrefix(rel, "..") {
-		t.logger.Error("Path is outside workspace", zap.String("path", path))
-		return "", fmt.Errorf("path is outside workspace")
+	fullPath, err := workspacepath.Resolve(workspace, path)
+	if err != nil {
+		t.logger.Error("Path is outside workspace", zap.String("path", path), zap.Error(err))
+		return "", err
 	}
 	return fullPath, nil
 }
@@ -115,6 +138,23 @@ func (t *FileReadTool) checkFileSize(path string) (bool, error) {
 	return true, nil
 }
 
+// Cacheable reports that reads can be answered again from the run's cache
+// until the file changes.
+func (t *FileReadTool) Cacheable(arguments string) bool {
+	return true
+}
+
+// Paths returns the file the call reads.
+func (t *FileReadTool) Paths(arguments string) []string {
+	var args struct {
+		FilePath string `json:"filePath"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil || args.FilePath == "" {
+		return nil
+	}
+	return toolPaths(t.configuration, args.FilePath)
+}
+
 func (t *FileReadTool) Execute(ctx context.Context, arguments string) (string, error) {
 	t.logger.Debug("Executing file read command", zap.String("arguments", arguments))
 	var rawArgs map[string]interface{}
@@ -126,18 +166,31 @@ func (t *FileReadTool) Execute(ctx context.Context, arguments string) (string, e
 	filePath, _ := rawArgs["filePath"].(string)
 	offsetVal, _ := rawArgs["offset"].(float64)
 	limitVal, _ := rawArgs["limit"].(float64)
+	offsetLineVal, hasOffsetLine := rawArgs["offset_line"].(float64)
+	endLineVal, hasEndLine := rawArgs["end_line"].(float64)
+	maxBytesVal, hasMaxBytes := rawArgs["max_bytes"].(float64)
 	offset := int(offsetVal)
 	limit := int(limitVal)
+	endLine := int(endLineVal)
+	maxBytes := int(maxBytesVal)
+	paging := hasOffsetLine || hasEndLine || hasMaxBytes
 
 	if filePath == "" {
 		t.logger.Error("filePath is required")
 		return `{"content": "", "error": "filePath is required"}`, nil
 	}
 
+	if hasOffsetLine {
+		offset = int(offsetLineVal)
+	}
+
 	// Default values
 	if offset <= 0 {
 		offset = 1 // 1-indexed
 	}
+	if hasEndLine && endLine < offset {
+		return fmt.Sprintf(`{"content": "", "error": "end_line %d is before the first line read, %d"}`, endLine, offset), nil
+	}
 	if limit <= 0 {
 		limit = 1000
 	}
@@ -146,8 +199,32 @@ func (t *FileReadTool) Execute(ctx context.Context, arguments string) (string, e
 	if err != nil {
 		return fmt.Sprintf(`{"content": "", "error": "%s"}`, err.Error()), nil
 	}
-	if ok, err := t.checkFileSize(fullPath); !ok {
-		return fmt.Sprintf(`{"content": "", "error": "%s"}`, err.Error()), nil
+
+	symbol, _ := rawArgs["symbol"].(string)
+	if listSymbols, _ := rawArgs["list_symbols"].(bool); listSymbols || symbol != "" {
+		return t.readSymbols(fullPath, filePath, symbol)
+	}
+
+	if kind := archiveKind(fullPath); kind != "" {
+		entry, _ := rawArgs["entry"].(string)
+		data, listing, err := readArchive(fullPath, kind, entry, archiveLimitsFromConfig(t.configuration))
+		if err != nil {
+			return fmt.Sprintf(`{"content": "", "error": %q}`, err.Error()), nil
+		}
+		if listing != "" {
+			return listing, nil
+		}
+		if isBinarySample(data, false) {
+			return fmt.Sprintf(`{"content": "", "binary": true, "size": %d, "error": "archived file appears to be binary and cannot be read as text"}`, len(data)), nil
+		}
+		return t.readLines(ctx, bytes.NewReader(data), offset, limit, endLine, maxBytes, paging, nil)
+	}
+
+	// Paged reads stream the file, so the size limit only applies to full reads
+	if !paging {
+		if ok, err := t.checkFileSize(fullPath); !ok {
+			return fmt.Sprintf(`{"content": "", "error": "%s"}`, err.Error()), nil
+		}
 	}
 	file, err := os.Open(fullPath)
 	if err != nil {
@@ -155,25 +232,106 @@ func (t *FileReadTool) Execute(ctx context.Context, arguments string) (string, e
 	}
 	defer file.Close()
 
+	// Plain reads return whatever the file holds, as they always have
+	if paging {
+		if binary, err := isBinaryFile(file); err != nil {
+			return fmt.Sprintf(`{"content": "", "error": "error reading file: %s"}`, err.Error()), nil
+		} else if binary {
+			info, _ := file.Stat()
+			var size int64
+			if info != nil {
+				size = info.Size()
+			}
+			return fmt.Sprintf(`{"content": "", "binary": true, "size": %d, "error": "file appears to be binary and cannot be read as text"}`, size), nil
+		}
+	}
+
+	// The hash covers the whole file, so FileWrite can tell if it changed.
+	// It is taken from the bytes the lines are read from, in the same pass.
+	var r io.Reader = file
+	var sum func() (string, error)
+	if info, err := file.Stat(); err == nil && info.Size() <= maxHashedFileSize {
+		r, sum = hashReader(file)
+	}
+	return t.readLines(ctx, r, offset, limit, endLine, maxBytes, paging, sum)
+}
+
+// readSymbols lists the file's declarations, or returns the current source
+// of the one called symbol with the lines it is on and the file's hash.
+func (t *FileReadTool) readSymbols(fullPath, filePath, symbol string) (string, error) {
+	if ok, err := t.checkFileSize(fullPath); !ok {
+		return fmt.Sprintf(`{"content": "", "error": %q}`, err.Error()), nil
+	}
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		return fmt.Sprintf(`{"content": "", "error": %q}`, "failed to read file: "+err.Error()), nil
+	}
+
+	if symbol != "" {
+		found, err := findSymbol(fullPath, content, symbol)
+		if err != nil {
+			return fmt.Sprintf(`{"content": "", "error": %q}`, err.Error()), nil
+		}
+		return fmt.Sprintf(`{"path": %q, "symbol": %q, "kind": %q, "startLine": %d, "endLine": %d, "content": %q, "hash": %q}`,
+			filePath, found.Name, found.Kind, found.StartLine, found.EndLine, string(content[found.start:found.end]), contentHash(content)), nil
+	}
+
+	symbols, err := fileSymbols(fullPath, content)
+	if err != nil {
+		return fmt.Sprintf(`{"content": "", "error": %q}`, err.Error()), nil
+	}
+	if symbols == nil {
+		symbols = []codeSymbol{}
+	}
+	symbolsJSON, err := json.Marshal(symbols)
+	if err != nil {
+		return fmt.Sprintf(`{"content": "", "error": %q}`, "failed to encode symbols: "+err.Error()), nil
+	}
+	return fmt.Sprintf(`{"path": %q, "symbols": %s}`, filePath, symbolsJSON), nil
+}
+
+// readLines returns the requested window of lines from r, in the paged format
+// when any paging parameter was supplied. When sum is set, the hash it returns
+// once the lines are read is returned with them.
+func (t *FileReadTool) readLines(ctx context.Context, r io.Reader, offset, limit, endLine, maxBytes int, paging bool, sum func() (string, error)) (string, error) {
 	const maxLines = 2000
 	if limit > maxLines {
 		limit = maxLines
 	}
+	if endLine > 0 && endLine-offset+1 < limit {
+		limit = endLine - offset + 1
+	}
 
 	var lines []string
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(r)
+	if paging {
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	}
 	lineNum := 0
 	readCount := 0
+	byteCount := 0
+	hasMore := false
 
 	for scanner.Scan() {
 		lineNum++
+		// Skipping to a far offset in a large file can take a while
+		if lineNum%10000 == 0 && ctx.Err() != nil {
+			return "", ctx.Err()
+		}
 		if lineNum < offset {
 			continue
 		}
 		if readCount >= limit {
+			hasMore = true
+			break
+		}
+		line := scanner.Text()
+		if maxBytes > 0 && readCount > 0 && byteCount+len(line)+1 > maxBytes {
+			hasMore = true
 			break
 		}
-		lines = append(lines, scanner.Text())
+		lines = append(lines, line)
+		byteCount += len(line) + 1
 		readCount++
 	}
 
@@ -181,9 +339,85 @@ func (t *FileReadTool) Execute(ctx context.Context, arguments string) (string, e
 		return fmt.Sprintf(`{"content": "", "error": "error reading file: %s"}`, err.Error()), nil
 	}
 
+	var hash string
+	if sum != nil {
+		var err error
+		if hash, err = sum(); err != nil {
+			return fmt.Sprintf(`{"content": "", "error": "error reading file: %s"}`, err.Error()), nil
+		}
+	}
+
 	content := strings.Join(lines, "\n")
 
-	return fmt.Sprintf(`{"content": %q, "lines": %d, "error": ""}`, content, len(lines)), nil
+	if !paging {
+		if hash != "" {
+			return fmt.Sprintf(`{"content": %q, "lines": %d, "hash": %q, "error": ""}`, content, len(lines), hash), nil
+		}
+		return fmt.Sprintf(`{"content": %q, "lines": %d, "error": ""}`, content, len(lines)), nil
+	}
+
+	// A single line longer than max_bytes is truncated rather than skipped
+	if maxBytes > 0 && len(content) > maxBytes {
+		content = strings.ToValidUTF8(content[:maxBytes], "")
+	}
+
+	response := struct {
+		Content   string `json:"content"`
+		Lines     int    `json:"lines"`
+		StartLine int    `json:"start_line"`
+		HasMore   bool   `json:"has_more"`
+		NextLine  int    `json:"next_line,omitempty"`
+		Hash      string `json:"hash,omitempty"`
+		Error     string `json:"error"`
+	}{
+		Content:   content,
+		Lines:     len(lines),
+		StartLine: offset,
+		HasMore:   hasMore,
+		Hash:      hash,
+	}
+	if hasMore {
+		response.NextLine = offset + len(lines)
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Sprintf(`{"content": "", "error": "failed to encode result: %s"}`, err.Error()), nil
+	}
+	return string(data), nil
+}
+
+// isBinaryFile sniffs the start of the file for NUL bytes or invalid UTF-8 and
+// rewinds it so the caller can read from the beginning.
+func isBinaryFile(file *os.File) (bool, error) {
+	buf := make([]byte, 8000)
+	n, err := file.Read(buf)
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return false, err
+	}
+
+	return isBinarySample(buf[:n], n == len(buf)), nil
+}
+
+// isBinarySample reports whether data looks binary. Only the first 8000 bytes
+// are inspected; truncated is set when data was cut from a longer file.
+func isBinarySample(data []byte, truncated bool) bool {
+	sample := data
+	if len(sample) > 8000 {
+		sample = sample[:8000]
+		truncated = true
+	}
+	if bytes.IndexByte(sample, 0) != -1 {
+		return true
+	}
+	// Allow a multi-byte rune to be cut off at the end of a full sample
+	for i := 0; truncated && i < utf8.UTFMax-1 && !utf8.Valid(sample); i++ {
+		sample = sample[:len(sample)-1]
+	}
+	return !utf8.Valid(sample)
 }
 
 func (t *FileReadTool) DisplayName(ui string, arguments string) (string, string) {
@@ -207,8 +441,12 @@ func (t *FileReadTool) FormatResult(ui string, result string, diff string, argum
 
 func (t *FileReadTool) formatResultTUI(result string, arguments string) string {
 	var response struct {
-		Content string `json:"content"`
-		Error   string `json:"error"`
+		Content string         `json:"content"`
+		Error   string         `json:"error"`
+		Archive string         `json:"archive"`
+		Entries []archiveEntry `json:"entries"`
+		Total   int            `json:"total"`
+		Symbols []codeSymbol   `json:"symbols"`
 	}
 
 	if err := json.Unmarshal([]byte(result), &response); err != nil {
@@ -219,6 +457,27 @@ func (t *FileReadTool) formatResultTUI(result string, arguments string) string {
 		return fmt.Sprintf("Error reading file: %s", response.Error)
 	}
 
+	if response.Symbols != nil {
+		var summary strings.Builder
+		summary.WriteString(fmt.Sprintf("🔎 %d symbol(s)\n\n", len(response.Symbols)))
+		for _, symbol := range response.Symbols {
+			summary.WriteString(fmt.Sprintf("%4d-%-4d %s %s\n", symbol.StartLine, symbol.EndLine, symbol.Kind, symbol.Name))
+		}
+		return summary.String()
+	}
+
+	if response.Archive != "" {
+		var summary strings.Builder
+		summary.WriteString(fmt.Sprintf("📦 %s archive (%d entries)\n\n", response.Archive, response.Total))
+		for _, entry := range response.Entries {
+			summary.WriteString(fmt.Sprintf("%10d  %s\n", entry.Size, entry.Name))
+		}
+		if response.Total > len(response.Entries) {
+			summary.WriteString(fmt.Sprintf("\n... and %d more entries\n", response.Total-len(response.Entries)))
+		}
+		return summary.String()
+	}
+
 	// Extract filename from arguments
 	var args struct {
 		FilePath string `json:"filePath"`
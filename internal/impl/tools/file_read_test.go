@@ -1,8 +1,12 @@
 package tools
 
 import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -156,3 +160,245 @@ func TestFileReadTool_ErrorCases(t *testing.T) {
 		t.Errorf("Expected error for absolute path outside workspace, got: %s", errorStr)
 	}
 }
+
+func TestFileReadTool_Paging(t *testing.T) {
+	tempDir := t.TempDir()
+	tool := NewFileReadTool("test-file-read", "Test File Read Tool", map[string]string{"workspace": tempDir}, zap.NewNop())
+
+	var lines []string
+	for i := 1; i <= 10; i++ {
+		lines = append(lines, fmt.Sprintf("Line %d", i))
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "big.log"), []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		args     string
+		content  string
+		hasMore  bool
+		nextLine float64
+	}{
+		{"line range", `{"filePath": "big.log", "offset_line": 3, "end_line": 5}`, "Line 3\nLine 4\nLine 5", true, 6},
+		{"last page", `{"filePath": "big.log", "offset_line": 9}`, "Line 9\nLine 10", false, 0},
+		{"max bytes", `{"filePath": "big.log", "max_bytes": 14}`, "Line 1\nLine 2", true, 3},
+	}
+
+	// A range that ends before it starts would page forever
+	result, err := tool.Execute(context.Background(), `{"filePath": "big.log", "offset_line": 5, "end_line": 3}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(result, "end_line 3 is before the first line read") {
+		t.Errorf("Expected an error for end_line before offset_line, got %s", result)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tool.Execute(context.Background(), tt.args)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var resultData map[string]interface{}
+			if err := json.Unmarshal([]byte(result), &resultData); err != nil {
+				t.Fatalf("Failed to parse JSON result: %v", err)
+			}
+			if resultData["content"] != tt.content {
+				t.Errorf("Expected content %q, got %q", tt.content, resultData["content"])
+			}
+			if resultData["has_more"] != tt.hasMore {
+				t.Errorf("Expected has_more %v, got %v", tt.hasMore, resultData["has_more"])
+			}
+			if next, _ := resultData["next_line"].(float64); next != tt.nextLine {
+				t.Errorf("Expected next_line %v, got %v", tt.nextLine, next)
+			}
+		})
+	}
+}
+
+func TestFileReadTool_Hash(t *testing.T) {
+	tempDir := t.TempDir()
+	tool := NewFileReadTool("test-file-read", "Test File Read Tool", map[string]string{"workspace": tempDir}, zap.NewNop())
+	content := "one\ntwo\nthree\n"
+	if err := os.WriteFile(filepath.Join(tempDir, "a.txt"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	// A window of the file still carries the hash of all of it
+	for _, args := range []string{`{"filePath": "a.txt"}`, `{"filePath": "a.txt", "offset_line": 2, "end_line": 2}`} {
+		result, err := tool.Execute(context.Background(), args)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var resultData struct {
+			Hash string `json:"hash"`
+		}
+		if err := json.Unmarshal([]byte(result), &resultData); err != nil {
+			t.Fatalf("Failed to parse JSON result: %v", err)
+		}
+		if resultData.Hash != contentHash([]byte(content)) {
+			t.Errorf("%s: expected the hash of the content read, got %s", args, result)
+		}
+	}
+}
+
+func TestFileReadTool_Symbols(t *testing.T) {
+	tempDir := t.TempDir()
+	tool := NewFileReadTool("test-file-read", "Test File Read Tool", map[string]string{"workspace": tempDir}, zap.NewNop())
+	source := `package shapes
+
+// Area returns the area.
+func (s *Square) Area() int {
+	return s.side * s.side
+}
+
+func (c Circle) Area() int { return 3 * c.r * c.r }
+`
+	if err := os.WriteFile(filepath.Join(tempDir, "shapes.go"), []byte(source), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "app.rb"), []byte("def main\nend\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	type symbolResponse struct {
+		Content   string       `json:"content"`
+		Error     string       `json:"error"`
+		StartLine int          `json:"startLine"`
+		EndLine   int          `json:"endLine"`
+		Hash      string       `json:"hash"`
+		Symbols   []codeSymbol `json:"symbols"`
+	}
+	var response symbolResponse
+	read := func(args string) string {
+		response = symbolResponse{}
+		result, err := tool.Execute(context.Background(), args)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := json.Unmarshal([]byte(result), &response); err != nil {
+			t.Fatalf("Failed to parse JSON result: %v", err)
+		}
+		return result
+	}
+
+	result := read(`{"filePath": "shapes.go", "symbol": "Square.Area"}`)
+	if response.StartLine != 4 || response.EndLine != 6 || !strings.HasPrefix(response.Content, "func (s *Square) Area() int {") ||
+		response.Hash != contentHash([]byte(source)) {
+		t.Errorf("Expected the Square.Area method, got %s", result)
+	}
+
+	// A bare method name defined on two types is ambiguous
+	read(`{"filePath": "shapes.go", "symbol": "Area"}`)
+	if !strings.Contains(response.Error, "ambiguous") {
+		t.Errorf("Expected ambiguous symbol error, got %q", response.Error)
+	}
+
+	result = read(`{"filePath": "shapes.go", "list_symbols": true}`)
+	if len(response.Symbols) != 2 || response.Symbols[0].Name != "Square.Area" {
+		t.Errorf("Expected both methods listed, got %s", result)
+	}
+
+	read(`{"filePath": "app.rb", "symbol": "main"}`)
+	if !strings.Contains(response.Error, "not supported") {
+		t.Errorf("Expected unsupported language error, got %q", response.Error)
+	}
+}
+
+func TestFileReadTool_Binary(t *testing.T) {
+	tempDir := t.TempDir()
+	tool := NewFileReadTool("test-file-read", "Test File Read Tool", map[string]string{"workspace": tempDir}, zap.NewNop())
+
+	if err := os.WriteFile(filepath.Join(tempDir, "image.bin"), []byte{0x89, 'P', 'N', 'G', 0x00, 0x01}, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	result, err := tool.Execute(context.Background(), `{"filePath": "image.bin", "offset_line": 1}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var resultData map[string]interface{}
+	if err := json.Unmarshal([]byte(result), &resultData); err != nil {
+		t.Fatalf("Failed to parse JSON result: %v", err)
+	}
+	if resultData["binary"] != true {
+		t.Errorf("Expected binary file to be detected, got %s", result)
+	}
+
+	// Without paging parameters the file is read as before
+	result, err = tool.Execute(context.Background(), `{"filePath": "image.bin"}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Contains(result, `"binary"`) || !strings.Contains(result, "PNG") {
+		t.Errorf("Expected a plain read to return the content, got %s", result)
+	}
+}
+
+func TestFileReadTool_Archives(t *testing.T) {
+	tempDir := t.TempDir()
+	config := map[string]string{"workspace": tempDir, "archive_max_ratio": "1000"}
+	tool := NewFileReadTool("test-file-read", "Test File Read Tool", config, zap.NewNop())
+
+	var gzBuf bytes.Buffer
+	gz := gzip.NewWriter(&gzBuf)
+	gz.Write([]byte("first line\nsecond line"))
+	gz.Close()
+	if err := os.WriteFile(filepath.Join(tempDir, "app.log.gz"), gzBuf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to create gzip file: %v", err)
+	}
+
+	var zipBuf bytes.Buffer
+	zw := zip.NewWriter(&zipBuf)
+	w, _ := zw.Create("logs/app.log")
+	w.Write([]byte("zipped line"))
+	zw.Close()
+	if err := os.WriteFile(filepath.Join(tempDir, "bundle.zip"), zipBuf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to create zip file: %v", err)
+	}
+
+	parse := func(result string) map[string]interface{} {
+		var data map[string]interface{}
+		if err := json.Unmarshal([]byte(result), &data); err != nil {
+			t.Fatalf("Failed to parse JSON result: %v", err)
+		}
+		return data
+	}
+
+	result, _ := tool.Execute(context.Background(), `{"filePath": "app.log.gz"}`)
+	if data := parse(result); data["content"] != "first line\nsecond line" {
+		t.Errorf("Expected decompressed gzip content, got %s", result)
+	}
+
+	result, _ = tool.Execute(context.Background(), `{"filePath": "bundle.zip"}`)
+	data := parse(result)
+	entries, _ := data["entries"].([]interface{})
+	if data["archive"] != "zip" || len(entries) != 1 {
+		t.Errorf("Expected a zip listing with one entry, got %s", result)
+	}
+
+	result, _ = tool.Execute(context.Background(), `{"filePath": "bundle.zip", "entry": "logs/app.log"}`)
+	if data := parse(result); data["content"] != "zipped line" {
+		t.Errorf("Expected zip entry content, got %s", result)
+	}
+
+	result, _ = tool.Execute(context.Background(), `{"filePath": "bundle.zip", "entry": "missing.log"}`)
+	if data := parse(result); !strings.Contains(data["error"].(string), "not found") {
+		t.Errorf("Expected missing entry error, got %s", result)
+	}
+
+	// A highly compressible file trips the decompression ratio guard
+	var bombBuf bytes.Buffer
+	gz = gzip.NewWriter(&bombBuf)
+	gz.Write(bytes.Repeat([]byte("a"), 1024*1024))
+	gz.Close()
+	if err := os.WriteFile(filepath.Join(tempDir, "bomb.gz"), bombBuf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to create gzip file: %v", err)
+	}
+	tool.UpdateConfiguration(map[string]string{"workspace": tempDir, "archive_max_ratio": "10"})
+	result, _ = tool.Execute(context.Background(), `{"filePath": "bomb.gz"}`)
+	if data := parse(result); !strings.Contains(data["error"].(string), "ratio") {
+		t.Errorf("Expected decompression ratio error, got %s", result)
+	}
+}
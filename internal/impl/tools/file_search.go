@@ -8,9 +8,11 @@ import (
 	"html"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/impl/workspacepath"
 
 	"go.uber.org/zap"
 )
@@ -47,8 +49,14 @@ func (t *FileSearchTool) UpdateConfiguration(config map[string]string) {
 	t.configuration = config
 }
 
+func (t *FileSearchTool) WithConfiguration(config map[string]string) entities.Tool {
+	c := *t
+	c.configuration = config
+	return &c
+}
+
 func (t *FileSearchTool) FullDescription() string {
-	return fmt.Sprintf("%s\n\nParameters:\n- pattern: The regex pattern to search for in file contents\n- path: The directory to search in. Defaults to the current working directory.\n- include: File pattern to include in the search (e.g., \"*.js\", \"*.{ts,tsx}\")", t.Description())
+	return fmt.Sprintf("%s\n\nParameters:\n- pattern: The regex pattern to search for in file contents\n- patterns: Several patterns to search for at once; lines matching any of them are returned, tagged with the pattern that matched\n- path: The directory to search in. Defaults to the current working directory.\n- include: File pattern to include in the search (e.g., \"*.js\", \"*.{ts,tsx}\")\n- include_ignored: Also search files matched by .gitignore or the ignore config (default: false)", t.Description())
 }
 
 func (t *FileSearchTool) Schema() map[string]any {
@@ -59,6 +67,11 @@ func (t *FileSearchTool) Schema() map[string]any {
 				"type":        "string",
 				"description": "The regex pattern to search for in file contents",
 			},
+			"patterns": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "Several patterns to search for at once; lines matching any of them are returned, tagged with the pattern that matched. Use instead of or with pattern.",
+			},
 			"path": map[string]any{
 				"type":        "string",
 				"description": "The directory to search in. Defaults to the current working directory.",
@@ -67,8 +80,12 @@ func (t *FileSearchTool) Schema() map[string]any {
 				"type":        "string",
 				"description": "File pattern to include in the search (e.g., \"*.js\", \"*.{ts,tsx}\")",
 			},
+			"include_ignored": map[string]any{
+				"type":        "boolean",
+				"description": "Also search files matched by .gitignore or the ignore config (default: false)",
+			},
 		},
-		"required":             []string{"pattern"},
+		"required":             []string{},
 		"additionalProperties": false,
 	}
 }
@@ -83,21 +100,10 @@ func (t *FileSearchTool) validatePath(path string) (string, error) {
 		}
 	}
 
-	var fullPath string
-	if filepath.IsAbs(path) {
-		if !strings.HasPrefix(path, workspace) {
-			t.logger.Error("Absolute path is outside workspace", zap.String("path", path))
-			return "", fmt.Errorf("absolute path is outside workspace")
-		}
-		fullPath = path
-	} else {
-		fullPath = filepath.Join(workspace, path)
-	}
-
-	rel, err := filepath.Rel(workspace, fullPath)
-	if err != nil || strings.HasPrefix(rel, "..") {
-		t.logger.Error("Path is outside workspace", zap.String("path", path))
-		return "", fmt.Errorf("path is outside workspace")
+	fullPath, err := workspacepath.Resolve(workspace, path)
+	if err != nil {
+		t.logger.Error("Path is outside workspace", zap.String("path", path), zap.Error(err))
+		return "", err
 	}
 	return fullPath, nil
 }
@@ -115,6 +121,24 @@ func (t *FileSearchTool) checkFileSize(path string) (bool, error) {
 	return true, nil
 }
 
+// Cacheable reports that searches can be answered again from the run's cache
+// until a searched file changes.
+func (t *FileSearchTool) Cacheable(arguments string) bool {
+	return true
+}
+
+// Paths returns the file or directory the call searches, the workspace when
+// no path is given.
+func (t *FileSearchTool) Paths(arguments string) []string {
+	var args struct {
+		Path string `json:"path"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return nil
+	}
+	return toolPaths(t.configuration, args.Path)
+}
+
 func (t *FileSearchTool) Execute(ctx context.Context, arguments string) (string, error) {
 	t.logger.Debug("Executing code search", zap.String("arguments", arguments))
 	var rawArgs map[string]interface{}
@@ -122,9 +146,16 @@ func (t *FileSearchTool) Execute(ctx context.Context, arguments string) (string,
 		return `{"results": [], "error": "failed to parse arguments"}`, nil
 	}
 
-	pattern := ""
-	if p, ok := rawArgs["pattern"].(string); ok {
-		pattern = p
+	var patterns []string
+	if p, ok := rawArgs["pattern"].(string); ok && p != "" {
+		patterns = append(patterns, p)
+	}
+	if ps, ok := rawArgs["patterns"].([]interface{}); ok {
+		for _, p := range ps {
+			if p, ok := p.(string); ok && p != "" && !slices.Contains(patterns, p) {
+				patterns = append(patterns, p)
+			}
+		}
 	}
 	path := ""
 	if p, ok := rawArgs["path"].(string); ok {
@@ -135,8 +166,10 @@ func (t *FileSearchTool) Execute(ctx context.Context, arguments string) (string,
 		include = i
 	}
 
-	if pattern == "" {
-		return `{"results": [], "error": "pattern is required"}`, nil
+	includeIgnored, _ := rawArgs["include_ignored"].(bool)
+
+	if len(patterns) == 0 {
+		return `{"results": [], "error": "pattern is required, or patterns to search for several"}`, nil
 	}
 	if path == "" {
 		path = "." // default to current directory
@@ -152,19 +185,28 @@ func (t *FileSearchTool) Execute(ctx context.Context, arguments string) (string,
 		filePattern = include
 	}
 
-	results, err := t.searchMultipleFiles(fullPath, pattern, filePattern, false)
+	var ignore *ignoreMatcher
+	if !includeIgnored {
+		ignore = loadIgnoreMatcher(t.configuration)
+	}
+
+	results, err := t.searchMultipleFiles(ctx, fullPath, patterns, filePattern, false, ignore)
 	if err != nil {
 		return fmt.Sprintf(`{"results": [], "error": "search failed: %s"}`, err.Error()), nil
 	}
 
 	var grokResults []map[string]any
-	for filePath, fileResults := range results {
-		for _, result := range fileResults {
-			grokResults = append(grokResults, map[string]any{
-				"file":    filePath,
+	for _, file := range results {
+		for _, result := range file.Matches {
+			match := map[string]any{
+				"file":    file.Path,
 				"line":    result.Line,
 				"snippet": result.Text,
-			})
+			}
+			if len(patterns) > 1 {
+				match["pattern"] = result.Pattern
+			}
+			grokResults = append(grokResults, match)
 		}
 	}
 
@@ -179,7 +221,17 @@ func (t *FileSearchTool) Execute(ctx context.Context, arguments string) (string,
 	return string(jsonResult), nil
 }
 
-func (t *FileSearchTool) search(filePath, pattern string, caseSensitive bool) ([]LineResult, error) {
+// fileMatches are the matching lines of one file, by its path relative to the
+// searched directory.
+type fileMatches struct {
+	Path    string
+	Matches []LineResult
+}
+
+// search returns the lines of filePath that contain any of the patterns,
+// each tagged with the first pattern it contains. Binary files have no
+// matches.
+func (t *FileSearchTool) search(filePath string, patterns []string, caseSensitive bool) ([]LineResult, error) {
 	if ok, err := t.checkFileSize(filePath); !ok {
 		return nil, err
 	}
@@ -190,6 +242,13 @@ func (t *FileSearchTool) search(filePath, pattern string, caseSensitive bool) ([
 	}
 	defer file.Close()
 
+	if binary, err := isBinaryFile(file); err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	} else if binary {
+		t.logger.Debug("Skipping binary file during search", zap.String("path", filePath))
+		return []LineResult{}, nil
+	}
+
 	var results []LineResult
 	scanner := bufio.NewScanner(file)
 	lineNum := 0
@@ -202,19 +261,22 @@ func (t *FileSearchTool) search(filePath, pattern string, caseSensitive bool) ([
 			return nil, fmt.Errorf("file exceeds line limit of %d lines", maxLines)
 		}
 		line := scanner.Text()
-		if caseSensitive {
-			if strings.Contains(line, pattern) {
-				results = append(results, LineResult{
-					Line: lineNum,
-					Text: line,
-				})
+		haystack := line
+		if !caseSensitive {
+			haystack = strings.ToLower(line)
+		}
+		for _, pattern := range patterns {
+			needle := pattern
+			if !caseSensitive {
+				needle = strings.ToLower(pattern)
 			}
-		} else {
-			if strings.Contains(strings.ToLower(line), strings.ToLower(pattern)) {
+			if strings.Contains(haystack, needle) {
 				results = append(results, LineResult{
-					Line: lineNum,
-					Text: line,
+					Line:    lineNum,
+					Text:    line,
+					Pattern: pattern,
 				})
+				break
 			}
 		}
 	}
@@ -224,16 +286,22 @@ func (t *FileSearchTool) search(filePath, pattern string, caseSensitive bool) ([
 	}
 
 	if len(results) == 0 {
-		t.logger.Info("No matches found during file search", zap.String("pattern", pattern), zap.String("path", filePath))
+		t.logger.Info("No matches found during file search", zap.Strings("patterns", patterns), zap.String("path", filePath))
 		return []LineResult{}, nil
 	}
 	t.logger.Info("File searched successfully", zap.String("path", filePath), zap.Int("matches", len(results)))
 	return results, nil
 }
 
-func (t *FileSearchTool) searchMultipleFiles(dirPath, pattern, filePattern string, caseSensitive bool) (map[string][]LineResult, error) {
-	results := make(map[string][]LineResult)
+// searchMultipleFiles searches the files under dirPath, skipping .git,
+// .aiagent and ignored paths, and returns the files with matches in walk
+// order, which is lexical, so the same tree always gives the same result.
+func (t *FileSearchTool) searchMultipleFiles(ctx context.Context, dirPath string, patterns []string, filePattern string, caseSensitive bool, ignore *ignoreMatcher) ([]fileMatches, error) {
+	results := []fileMatches{}
 	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
 		if err != nil {
 			t.logger.Warn("Error accessing path", zap.String("path", path), zap.Error(err))
 			return nil // Continue walking despite errors
@@ -243,6 +311,12 @@ func (t *FileSearchTool) searchMultipleFiles(dirPath, pattern, filePattern strin
 			if info.Name() == ".git" || info.Name() == ".aiagent" {
 				return filepath.SkipDir
 			}
+			if path != dirPath && ignore.Ignored(path, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ignore.Ignored(path, false) || hasBinaryExtension(path) {
 			return nil
 		}
 		if filePattern != "" {
@@ -256,9 +330,13 @@ func (t *FileSearchTool) searchMultipleFiles(dirPath, pattern, filePattern strin
 			t.logger.Warn("Failed to get relative path", zap.String("path", path), zap.Error(err))
 			return nil
 		}
-		fileResults, err := t.search(path, pattern, caseSensitive)
+		if relPath == "." {
+			// A single file was searched
+			relPath = info.Name()
+		}
+		fileResults, err := t.search(path, patterns, caseSensitive)
 		if err == nil && len(fileResults) > 0 {
-			results[relPath] = fileResults
+			results = append(results, fileMatches{Path: relPath, Matches: fileResults})
 		}
 		return nil
 	})
@@ -266,8 +344,8 @@ func (t *FileSearchTool) searchMultipleFiles(dirPath, pattern, filePattern strin
 		return nil, fmt.Errorf("error walking directory: %v", err)
 	}
 	if len(results) == 0 {
-		t.logger.Info("No matches found in directory", zap.String("pattern", pattern), zap.String("path", dirPath))
-		return make(map[string][]LineResult), nil
+		t.logger.Info("No matches found in directory", zap.Strings("patterns", patterns), zap.String("path", dirPath))
+		return results, nil
 	}
 	t.logger.Info("Multiple files searched successfully", zap.String("path", dirPath), zap.Int("files_with_matches", len(results)))
 	return results, nil
@@ -275,11 +353,18 @@ func (t *FileSearchTool) searchMultipleFiles(dirPath, pattern, filePattern strin
 
 func (t *FileSearchTool) DisplayName(ui string, arguments string) (string, string) {
 	var args struct {
-		Pattern string `json:"pattern"`
-		Path    string `json:"path"`
+		Pattern  string   `json:"pattern"`
+		Patterns []string `json:"patterns"`
+		Path     string   `json:"path"`
 	}
 	if err := json.Unmarshal([]byte(arguments), &args); err == nil {
-		detail := args.Pattern
+		var patterns []string
+		for _, p := range append([]string{args.Pattern}, args.Patterns...) {
+			if p != "" && !slices.Contains(patterns, p) {
+				patterns = append(patterns, p)
+			}
+		}
+		detail := strings.Join(patterns, " | ")
 		if args.Path != "" && args.Path != "." {
 			detail += " in " + args.Path
 		}
@@ -186,3 +186,108 @@ func TestFileSearchTool_ErrorCases(t *testing.T) {
 		t.Errorf("Expected absolute path outside error, got: %s", errorStr)
 	}
 }
+
+func TestFileSearchTool_MultiplePatterns(t *testing.T) {
+	tempDir := t.TempDir()
+	tool := NewFileSearchTool("test-file-search", "Test File Search Tool", map[string]string{"workspace": tempDir}, zap.NewNop())
+
+	if err := os.WriteFile(filepath.Join(tempDir, "main.go"), []byte("func NewServer() {}\nfunc handle() {}\nvar router = NewRouter()\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "other.go"), []byte("// uses NewRouter\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	type match struct {
+		File    string `json:"file"`
+		Line    int    `json:"line"`
+		Pattern string `json:"pattern"`
+	}
+	run := func(args string) []match {
+		t.Helper()
+		result, err := tool.Execute(context.Background(), args)
+		if err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+		var response struct {
+			Results []match `json:"results"`
+			Error   string  `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(result), &response); err != nil || response.Error != "" {
+			t.Fatalf("unexpected result %s: %v", result, err)
+		}
+		return response.Results
+	}
+
+	results := run(`{"pattern": "NewServer", "patterns": ["newrouter", "NewServer"]}`)
+	if len(results) != 3 {
+		t.Fatalf("expected 3 matches, got %+v", results)
+	}
+	for _, r := range results {
+		want := "newrouter"
+		if r.File == "main.go" && r.Line == 1 {
+			want = "NewServer"
+		}
+		if r.Pattern != want {
+			t.Errorf("expected %s:%d to be tagged %s, got %q", r.File, r.Line, want, r.Pattern)
+		}
+	}
+
+	// A single file, and a single pattern without tags
+	results = run(`{"patterns": ["handle"], "path": "main.go"}`)
+	if len(results) != 1 || results[0].File != "main.go" || results[0].Line != 2 || results[0].Pattern != "" {
+		t.Errorf("unexpected single file results: %+v", results)
+	}
+}
+
+func TestFileSearchTool_OrderAndBinaryFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	tool := NewFileSearchTool("test-file-search", "Test File Search Tool", map[string]string{"workspace": tempDir}, zap.NewNop())
+
+	files := map[string]string{
+		"b.go":         "// TODO: b\n",
+		"a/z.go":       "// TODO: z\n",
+		"a.go":         "// TODO: a\n",
+		"c.go":         "// TODO: c1\n// TODO: c2\n",
+		"logo.png":     "\x89PNG\x00\x00TODO\x00",
+		"notes.pdf":    "TODO: text in a binary type\n",
+		".git/HEAD.go": "// TODO: git\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tempDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first, err := tool.Execute(context.Background(), `{"pattern": "TODO"}`)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	var response struct {
+		Results []struct {
+			File string `json:"file"`
+			Line int    `json:"line"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal([]byte(first), &response); err != nil {
+		t.Fatalf("Failed to parse JSON result: %v", err)
+	}
+	var got []string
+	for _, r := range response.Results {
+		got = append(got, r.File)
+	}
+	want := "a/z.go a.go b.go c.go c.go"
+	if strings.Join(got, " ") != want {
+		t.Errorf("Expected files %q, got %q", want, strings.Join(got, " "))
+	}
+
+	for i := 0; i < 5; i++ {
+		if again, _ := tool.Execute(context.Background(), `{"pattern": "TODO"}`); again != first {
+			t.Fatalf("Expected the same result on every run, got %s and %s", first, again)
+		}
+	}
+}
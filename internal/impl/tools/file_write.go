@@ -7,11 +7,13 @@ import (
 	"html"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 	"unicode/utf8"
 
 	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/impl/workspacepath"
 	"github.com/drujensen/aiagent/internal/tui/formatters"
 
 	"go.uber.org/zap"
@@ -49,11 +51,17 @@ func (t *FileWriteTool) UpdateConfiguration(config map[string]string) {
 	t.configuration = config
 }
 
+func (t *FileWriteTool) WithConfiguration(config map[string]string) entities.Tool {
+	c := *t
+	c.configuration = config
+	return &c
+}
+
 func (t *FileWriteTool) Parameters() []entities.Parameter {
 	return []entities.Parameter{
 		{Name: "filePath", Type: "string", Description: "The absolute path to the file to modify", Required: true},
-		{Name: "oldString", Type: "string", Description: "The text to replace", Required: true},
-		{Name: "newString", Type: "string", Description: "The text to replace it with (must be different from oldString)", Required: true},
+		{Name: "oldString", Type: "string", Description: "The text to replace", Required: false},
+		{Name: "newString", Type: "string", Description: "The text to replace it with (must be different from oldString)", Required: false},
 		{Name: "replaceAll", Type: "boolean", Description: "Replace all occurrences of oldString (default false)", Required: false},
 	}
 }
@@ -66,6 +74,10 @@ func (t *FileWriteTool) FullDescription() string {
 - **oldString**: The text to replace (exact match from FileRead)
 - **newString**: The replacement text
 - **replaceAll**: Replace all occurrences (default false)
+- **operation**: "replace" to require a unique match (or replaceAll), "count" to count occurrences of oldString, "undo" to restore the file from the backup taken before its last change, or a file or directory that Directory deleted or replaced, or "edit_symbol" to replace a declaration with newString
+- **symbol**: For edit_symbol, the function, type, class, variable, constant or method (Type.Method) to find wherever it currently is, in Go, Python, JavaScript or TypeScript
+- **dry_run**: Return the diff without changing the file
+- **expected_hash**: The hash FileRead returned; the change fails if the file has changed since. Each change returns the file's new hash
 
 **Best Practice**:
 1. FileRead → copy exact snippet (indent/whitespace preserved) as oldString
@@ -97,19 +109,31 @@ func (t *FileWriteTool) Schema() map[string]any {
 				"description": "Replace all occurrences of oldString (default false)",
 				"default":     false,
 			},
+			"operation": map[string]any{
+				"type":        "string",
+				"enum":        []string{"replace", "count", "undo", "edit_symbol"},
+				"description": "replace: replace oldString, which must match exactly once unless replaceAll is set. count: report the occurrences of oldString without changing the file. undo: restore the file from the backup taken before its last change, or a file or directory that Directory deleted or replaced, ignoring oldString and newString. edit_symbol: replace the whole declaration of symbol with newString",
+			},
+			"symbol": map[string]any{
+				"type":        "string",
+				"description": "For edit_symbol: the name of a top-level declaration, or Type.Method for a method",
+			},
+			"dry_run": map[string]any{
+				"type":        "boolean",
+				"description": "Return the diff without changing the file (default false)",
+				"default":     false,
+			},
+			"expected_hash": map[string]any{
+				"type":        "string",
+				"description": "The file's hash from when it was read; the change fails if the file has changed since",
+			},
 		},
-		"required":             []string{"filePath", "oldString", "newString"},
+		"required":             []string{"filePath"},
 		"additionalProperties": false,
 	}
 }
 
-func (t *FileWriteTool) validatePath(path string) (string, error) {
-	// Ensure path is valid UTF-8
-	if !utf8.ValidString(path) {
-		t.logger.Error("Path contains invalid UTF-8", zap.String("path", path))
-		return "", fmt.Errorf("path contains invalid UTF-8")
-	}
-
+func (t *FileWriteTool) workspace() (string, error) {
 	workspace := t.configuration["workspace"]
 	if workspace == "" {
 		var err error
@@ -118,26 +142,241 @@ func (t *FileWriteTool) validatePath(path string) (string, error) {
 			return "", fmt.Errorf("could not get current directory: %v", err)
 		}
 	}
+	return workspace, nil
+}
 
-	var fullPath string
-	if filepath.IsAbs(path) {
-		if !strings.HasPrefix(path, workspace) {
-			t.logger.Error("Absolute path is outside workspace", zap.String("path", path))
-			return "", fmt.Errorf("absolute path is outside workspace")
-		}
-		fullPath = path
-	} else {
-		fullPath = filepath.Join(workspace, path)
+// backup saves the current content of the file before it is changed. A
+// failed backup is logged but does not block the change.
+func (t *FileWriteTool) backup(args fileWriteArgs, fullPath string) string {
+	workspace, err := t.workspace()
+	if err != nil {
+		t.logger.Warn("Failed to back up file", zap.String("path", fullPath), zap.Error(err))
+		return ""
+	}
+	relPath, err := filepath.Rel(workspace, fullPath)
+	if err != nil {
+		relPath = args.FilePath
 	}
+	backupPath, err := newFileBackupStore(workspace, t.configuration).Save(args.ChatID, relPath, fullPath)
+	if err != nil {
+		t.logger.Warn("Failed to back up file", zap.String("path", fullPath), zap.Error(err))
+		return ""
+	}
+	return backupPath
+}
 
-	rel, err := filepath.Rel(workspace, fullPath)
-	if err != nil || strings.HasPrefix(rel, "..") {
-		t.logger.Error("Path is outside workspace", zap.String("path", path))
-		return "", fmt.Errorf("path is outside workspace")
+// executeReplaceOperation replaces oldString, which has to match exactly once
+// unless replaceAll is set. Unlike edit it refuses ambiguous matches instead
+// of replacing the first one.
+func (t *FileWriteTool) executeReplaceOperation(args fileWriteArgs, fullPath string) (string, error) {
+	if args.OldString == "" {
+		return "", fmt.Errorf("oldString is required for replace operation")
+	}
+	if !args.HasNewString {
+		return "", fmt.Errorf("newString is required for replace operation; pass an empty string to remove oldString")
+	}
+
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %s", err.Error())
+	}
+	fileContent := string(content)
+
+	occurrences := strings.Count(fileContent, args.OldString)
+	if occurrences == 0 {
+		return "", fmt.Errorf("oldString not found in file.\nSearched for: %q", args.OldString)
+	}
+	if occurrences > 1 && !args.ReplaceAll {
+		return "", fmt.Errorf("oldString appears %d times in the file; set replaceAll to true or include more surrounding context to make it unique", occurrences)
+	}
+
+	newContent := strings.ReplaceAll(fileContent, args.OldString, args.NewString)
+	diff := t.generateEditDiff(args.FilePath, fileContent, newContent, args.OldString, args.NewString, occurrences)
+
+	if args.DryRun {
+		summary := fmt.Sprintf("Would replace %d occurrence(s)", occurrences)
+		shownDiff, fullDiff := t.modelDiff(diff)
+		return fmt.Sprintf(`{"success": true, "summary": %q, "diff": %q%s, "filePath": %q, "occurrences": %d, "replacedAll": %t, "dryRun": true}`, summary, shownDiff, fullDiff, args.FilePath, occurrences, args.ReplaceAll), nil
+	}
+
+	backupPath := t.backup(args, fullPath)
+	if err := os.WriteFile(fullPath, []byte(newContent), 0644); err != nil {
+		return "", fmt.Errorf("failed to write modified content: %s", err.Error())
+	}
+
+	summary := fmt.Sprintf("Replaced %d occurrence(s)", occurrences)
+	shownDiff, fullDiff := t.modelDiff(diff)
+	return fmt.Sprintf(`{"success": true, "summary": %q, "diff": %q%s, "filePath": %q, "occurrences": %d, "replacedAll": %t, "backupPath": %q, "hash": %q}`, summary, shownDiff, fullDiff, args.FilePath, occurrences, args.ReplaceAll, backupPath, contentHash([]byte(newContent))), nil
+}
+
+// executeCountOperation reports how often oldString occurs without changing
+// the file.
+func (t *FileWriteTool) executeCountOperation(args fileWriteArgs, fullPath string) (string, error) {
+	if args.OldString == "" {
+		return "", fmt.Errorf("oldString is required for count operation")
+	}
+
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %s", err.Error())
+	}
+
+	occurrences := strings.Count(string(content), args.OldString)
+	summary := fmt.Sprintf("Found %d occurrence(s)", occurrences)
+	return fmt.Sprintf(`{"success": true, "summary": %q, "filePath": %q, "occurrences": %d}`, summary, args.FilePath, occurrences), nil
+}
+
+// executeUndoOperation restores the most recent backup of the file made in
+// this chat.
+func (t *FileWriteTool) executeUndoOperation(args fileWriteArgs, fullPath string) (string, error) {
+	workspace, err := t.workspace()
+	if err != nil {
+		return "", err
+	}
+	relPath, err := filepath.Rel(workspace, fullPath)
+	if err != nil {
+		relPath = args.FilePath
+	}
+
+	backupPath, err := newFileBackupStore(workspace, t.configuration).Restore(args.ChatID, relPath, fullPath)
+	if err != nil {
+		return "", fmt.Errorf("undo failed: %s", err.Error())
+	}
+
+	summary := fmt.Sprintf("Restored %s from backup", args.FilePath)
+	return fmt.Sprintf(`{"success": true, "summary": %q, "filePath": %q, "backupPath": %q}`, summary, args.FilePath, backupPath), nil
+}
+
+// executeEditSymbolOperation replaces a declaration with newString. The
+// declaration is located by parsing the file just before the change, so
+// earlier edits that moved it don't matter, and a Go change is refused when
+// the result no longer parses. The first line of newString goes where the
+// declaration starts, after any indentation.
+func (t *FileWriteTool) executeEditSymbolOperation(args fileWriteArgs, fullPath string) (string, error) {
+	if args.Symbol == "" {
+		return "", fmt.Errorf("symbol is required for edit_symbol operation")
+	}
+	if strings.TrimSpace(args.NewString) == "" {
+		return "", fmt.Errorf("newString is required for edit_symbol operation")
+	}
+
+	content, err := os.ReadFile(fullPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %s", err.Error())
+	}
+	symbol, err := findSymbol(fullPath, content, args.Symbol)
+	if err != nil {
+		return "", err
+	}
+
+	fileContent := string(content)
+	oldString := fileContent[symbol.start:symbol.end]
+	newString := strings.TrimSuffix(args.NewString, "\n")
+	newContent := fileContent[:symbol.start] + newString + fileContent[symbol.end:]
+	if _, err := fileSymbols(fullPath, []byte(newContent)); err != nil {
+		return "", fmt.Errorf("the new declaration of %s does not parse: %s", symbol.Name, err.Error())
+	}
+
+	diff := t.generateEditDiff(args.FilePath, fileContent, newContent, oldString, newString, 1)
+	shownDiff, fullDiff := t.modelDiff(diff)
+	if args.DryRun {
+		summary := fmt.Sprintf("Would replace %s %s at lines %d-%d", symbol.Kind, symbol.Name, symbol.StartLine, symbol.EndLine)
+		return fmt.Sprintf(`{"success": true, "summary": %q, "diff": %q%s, "filePath": %q, "symbol": %q, "dryRun": true}`, summary, shownDiff, fullDiff, args.FilePath, symbol.Name), nil
+	}
+
+	backupPath := t.backup(args, fullPath)
+	if err := os.WriteFile(fullPath, []byte(newContent), 0644); err != nil {
+		return "", fmt.Errorf("failed to write modified content: %s", err.Error())
+	}
+
+	summary := fmt.Sprintf("Replaced %s %s at lines %d-%d", symbol.Kind, symbol.Name, symbol.StartLine, symbol.EndLine)
+	return fmt.Sprintf(`{"success": true, "summary": %q, "diff": %q%s, "filePath": %q, "symbol": %q, "backupPath": %q, "hash": %q}`, summary, shownDiff, fullDiff, args.FilePath, symbol.Name, backupPath, contentHash([]byte(newContent))), nil
+}
+
+func (t *FileWriteTool) validatePath(path string) (string, error) {
+	// Ensure path is valid UTF-8
+	if !utf8.ValidString(path) {
+		t.logger.Error("Path contains invalid UTF-8", zap.String("path", path))
+		return "", fmt.Errorf("path contains invalid UTF-8")
+	}
+
+	workspace, err := t.workspace()
+	if err != nil {
+		return "", err
+	}
+
+	fullPath, err := workspacepath.Resolve(workspace, path)
+	if err != nil {
+		t.logger.Error("Path is outside workspace", zap.String("path", path), zap.Error(err))
+		return "", err
 	}
 	return fullPath, nil
 }
 
+type fileWriteArgs struct {
+	Operation  string
+	FilePath   string
+	NewString  string
+	OldString  string
+	ReplaceAll bool
+	DryRun     bool
+	Symbol     string
+	// HasNewString is set when newString was passed, even if empty
+	HasNewString bool
+	// ExpectedHash is the content hash the file must still have
+	ExpectedHash string
+	ChatID       string // injected by the framework via injectToolArgs
+}
+
+// Mutates reports whether the call changes a file. Counting and dry runs only
+// read it.
+func (t *FileWriteTool) Mutates(arguments string) bool {
+	var rawArgs map[string]interface{}
+	if err := json.Unmarshal([]byte(arguments), &rawArgs); err != nil {
+		return true
+	}
+	if getBoolField(rawArgs, "dry_run", "dryRun") {
+		return false
+	}
+	operation := getStringField(rawArgs, "operation")
+	return operation != "count"
+}
+
+// Paths returns the file the call edits.
+func (t *FileWriteTool) Paths(arguments string) []string {
+	var rawArgs map[string]interface{}
+	if err := json.Unmarshal([]byte(arguments), &rawArgs); err != nil {
+		return nil
+	}
+	filePath := getStringField(rawArgs, "filePath")
+	if filePath == "" {
+		return nil
+	}
+	return toolPaths(t.configuration, filePath)
+}
+
+// Preview returns the result of the call as a dry run, with the diff it would
+// make. Undo has no dry run, so its preview only names the file it restores.
+func (t *FileWriteTool) Preview(ctx context.Context, arguments string) (string, error) {
+	var rawArgs map[string]interface{}
+	if err := json.Unmarshal([]byte(arguments), &rawArgs); err != nil {
+		return "", fmt.Errorf("failed to parse arguments")
+	}
+	if getStringField(rawArgs, "operation") == "undo" {
+		filePath := getStringField(rawArgs, "filePath")
+		summary := fmt.Sprintf("Would restore %s from the backup taken before its last change", filePath)
+		return fmt.Sprintf(`{"success": true, "summary": %q, "filePath": %q, "dryRun": true}`, summary, filePath), nil
+	}
+
+	delete(rawArgs, "dryRun")
+	rawArgs["dry_run"] = true
+	dryRun, err := json.Marshal(rawArgs)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode arguments: %v", err)
+	}
+	return t.Execute(ctx, string(dryRun))
+}
+
 func (t *FileWriteTool) Execute(ctx context.Context, arguments string) (string, error) {
 	t.logger.Debug("Executing file write command", zap.String("arguments", arguments))
 
@@ -147,24 +386,47 @@ func (t *FileWriteTool) Execute(ctx context.Context, arguments string) (string,
 	}
 
 	// Extract fields with proper defaults
-	args := struct {
-		Operation  string
-		FilePath   string
-		NewString  string
-		OldString  string
-		ReplaceAll bool
-	}{
+	args := fileWriteArgs{
 		Operation:  getStringField(rawArgs, "operation"),
 		FilePath:   getStringField(rawArgs, "filePath"),
-		NewString:  getStringField(rawArgs, "newString"),
-		OldString:  getStringField(rawArgs, "oldString"),
-		ReplaceAll: getBoolField(rawArgs, "replaceAll"),
+		NewString:  getStringField(rawArgs, "newString", "new_string"),
+		OldString:  getStringField(rawArgs, "oldString", "old_string"),
+		ReplaceAll: getBoolField(rawArgs, "replaceAll", "replace_all"),
+		DryRun:     getBoolField(rawArgs, "dry_run", "dryRun"),
+		Symbol:     getStringField(rawArgs, "symbol"),
+		ChatID:     getStringField(rawArgs, "parent_chat_id"),
+
+		ExpectedHash: getStringField(rawArgs, "expected_hash", "expectedHash"),
+		HasNewString: hasStringField(rawArgs, "newString", "new_string"),
 	}
 
 	if args.FilePath == "" {
 		return "", fmt.Errorf("filePath is required")
 	}
 
+	switch args.Operation {
+	case "undo", "replace", "count", "edit_symbol":
+		fullPath, err := t.validatePath(args.FilePath)
+		if err != nil {
+			return "", fmt.Errorf("invalid path: %s", err.Error())
+		}
+		if t.Mutates(arguments) {
+			if err := checkExpectedHash(fullPath, args.FilePath, args.ExpectedHash); err != nil {
+				return "", err
+			}
+		}
+		switch args.Operation {
+		case "undo":
+			return t.executeUndoOperation(args, fullPath)
+		case "replace":
+			return t.executeReplaceOperation(args, fullPath)
+		case "edit_symbol":
+			return t.executeEditSymbolOperation(args, fullPath)
+		default:
+			return t.executeCountOperation(args, fullPath)
+		}
+	}
+
 	// Auto-detect operation: if oldString provided, edit; else write
 	if args.OldString != "" {
 		args.Operation = "edit"
@@ -187,6 +449,11 @@ func (t *FileWriteTool) Execute(ctx context.Context, arguments string) (string,
 	if err != nil {
 		return "", fmt.Errorf("invalid path: %s", err.Error())
 	}
+	if !args.DryRun {
+		if err := checkExpectedHash(fullPath, args.FilePath, args.ExpectedHash); err != nil {
+			return "", err
+		}
+	}
 
 	if args.Operation == "write" {
 		return t.executeWriteOperation(args, fullPath)
@@ -198,13 +465,7 @@ func (t *FileWriteTool) Execute(ctx context.Context, arguments string) (string,
 }
 
 // executeWriteOperation handles write operations (create/overwrite/append)
-func (t *FileWriteTool) executeWriteOperation(args struct {
-	Operation  string
-	FilePath   string
-	NewString  string
-	OldString  string
-	ReplaceAll bool
-}, fullPath string) (string, error) {
+func (t *FileWriteTool) executeWriteOperation(args fileWriteArgs, fullPath string) (string, error) {
 	// Determine the operation type
 	fileExisted := false
 	operation := "create"
@@ -213,6 +474,15 @@ func (t *FileWriteTool) executeWriteOperation(args struct {
 		operation = "overwrite"
 	}
 
+	if args.DryRun {
+		diff := t.generateDiff(args.FilePath, operation, args.NewString, false)
+		summary := fmt.Sprintf("Would %s file", operation)
+		shownDiff, fullDiff := t.modelDiff(diff)
+		return fmt.Sprintf(`{"success": true, "summary": %q, "diff": %q%s, "filePath": %q, "occurrences": 0, "replacedAll": false, "dryRun": true}`, summary, shownDiff, fullDiff, args.FilePath), nil
+	}
+
+	backupPath := t.backup(args, fullPath)
+
 	var file *os.File
 	var err error
 	file, err = os.OpenFile(fullPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
@@ -234,17 +504,12 @@ func (t *FileWriteTool) executeWriteOperation(args struct {
 		summary = "File overwritten successfully"
 	}
 
-	return fmt.Sprintf(`{"success": true, "summary": %q, "diff": %q, "filePath": %q, "occurrences": 0, "replacedAll": false}`, summary, diff, args.FilePath), nil
+	shownDiff, fullDiff := t.modelDiff(diff)
+	return fmt.Sprintf(`{"success": true, "summary": %q, "diff": %q%s, "filePath": %q, "occurrences": 0, "replacedAll": false, "backupPath": %q, "hash": %q}`, summary, shownDiff, fullDiff, args.FilePath, backupPath, contentHash([]byte(args.NewString))), nil
 }
 
 // executeEditOperation handles edit operations (find and replace)
-func (t *FileWriteTool) executeEditOperation(args struct {
-	Operation  string
-	FilePath   string
-	NewString  string
-	OldString  string
-	ReplaceAll bool
-}, fullPath string) (string, error) {
+func (t *FileWriteTool) executeEditOperation(args fileWriteArgs, fullPath string) (string, error) {
 	// Read the current file content
 	content, err := os.ReadFile(fullPath)
 	if err != nil {
@@ -289,6 +554,15 @@ func (t *FileWriteTool) executeEditOperation(args struct {
 		}
 	}
 
+	if args.DryRun {
+		diff := t.generateEditDiff(args.FilePath, fileContent, newContent, args.OldString, args.NewString, occurrences)
+		summary := fmt.Sprintf("Would replace %d occurrence(s)", occurrences)
+		shownDiff, fullDiff := t.modelDiff(diff)
+		return fmt.Sprintf(`{"success": true, "summary": %q, "diff": %q%s, "filePath": %q, "occurrences": %d, "replacedAll": %t, "dryRun": true}`, summary, shownDiff, fullDiff, args.FilePath, occurrences, args.ReplaceAll), nil
+	}
+
+	backupPath := t.backup(args, fullPath)
+
 	// Write the modified content back
 	if err := os.WriteFile(fullPath, []byte(newContent), 0644); err != nil {
 		return "", fmt.Errorf("failed to write modified content: %s", err.Error())
@@ -299,7 +573,8 @@ func (t *FileWriteTool) executeEditOperation(args struct {
 
 	summary := fmt.Sprintf("Replaced %d occurrence(s)", occurrences)
 
-	return fmt.Sprintf(`{"success": true, "summary": %q, "diff": %q, "filePath": %q, "occurrences": %d, "replacedAll": %t}`, summary, diff, args.FilePath, occurrences, args.ReplaceAll), nil
+	shownDiff, fullDiff := t.modelDiff(diff)
+	return fmt.Sprintf(`{"success": true, "summary": %q, "diff": %q%s, "filePath": %q, "occurrences": %d, "replacedAll": %t, "backupPath": %q, "hash": %q}`, summary, shownDiff, fullDiff, args.FilePath, occurrences, args.ReplaceAll, backupPath, contentHash([]byte(newContent))), nil
 }
 
 // generateDiff creates a simple unified diff showing the changes made
@@ -384,19 +659,40 @@ func (t *FileWriteTool) generateEditDiff(filePath, oldContent, newContent, oldSt
 }
 
 // Helper functions for parsing JSON fields
-func getStringField(data map[string]interface{}, key string) string {
-	if val, ok := data[key]; ok {
-		if str, ok := val.(string); ok {
-			return str
+// getStringField returns the first of keys present as a string, so aliases
+// such as old_string can be accepted alongside oldString.
+func getStringField(data map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if val, ok := data[key]; ok {
+			if str, ok := val.(string); ok {
+				return str
+			}
 		}
 	}
 	return ""
 }
 
-func getBoolField(data map[string]interface{}, key string) bool {
-	if val, ok := data[key]; ok {
-		if b, ok := val.(bool); ok {
-			return b
+// hasStringField reports whether any of keys is present as a string.
+func hasStringField(data map[string]interface{}, keys ...string) bool {
+	for _, key := range keys {
+		if _, ok := data[key].(string); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// getBoolField returns the first of keys present as a boolean. Booleans sent
+// as strings are accepted too.
+func getBoolField(data map[string]interface{}, keys ...string) bool {
+	for _, key := range keys {
+		switch val := data[key].(type) {
+		case bool:
+			return val
+		case string:
+			if b, err := strconv.ParseBool(val); err == nil {
+				return b
+			}
 		}
 	}
 	return false
@@ -430,6 +726,7 @@ func (t *FileWriteTool) formatResultTUI(result string, diff string) string {
 		Occurrences int    `json:"occurrences"`
 		ReplacedAll bool   `json:"replaced_all"`
 		Diff        string `json:"diff"`
+		BackupPath  string `json:"backupPath"`
 	}
 
 	// First, try to use the diff parameter if available
@@ -438,6 +735,9 @@ func (t *FileWriteTool) formatResultTUI(result string, diff string) string {
 		if err := json.Unmarshal([]byte(result), &resultData); err == nil && resultData.Summary != "" {
 			var output strings.Builder
 			output.WriteString(resultData.Summary)
+			if resultData.BackupPath != "" {
+				output.WriteString("\nBackup: " + resultData.BackupPath)
+			}
 			output.WriteString("\n\n" + formatters.FormatDiff(diff))
 			return output.String()
 		} else {
@@ -465,6 +765,9 @@ func (t *FileWriteTool) formatResultTUI(result string, diff string) string {
 
 	// Use the summary from the JSON response
 	output.WriteString(resultData.Summary)
+	if resultData.BackupPath != "" {
+		output.WriteString("\nBackup: " + resultData.BackupPath)
+	}
 
 	// Add the diff from JSON if available
 	if resultData.Diff != "" {
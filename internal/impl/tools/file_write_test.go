@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -341,11 +342,11 @@ func TestFileWriteTool_Parameters(t *testing.T) {
 		t.Errorf("Invalid filePath parameter: %v", params[0])
 	}
 
-	if params[1].Type != "string" || !params[1].Required {
+	if params[1].Type != "string" || params[1].Required {
 		t.Errorf("Invalid oldString parameter: %v", params[1])
 	}
 
-	if params[2].Type != "string" || !params[2].Required {
+	if params[2].Type != "string" || params[2].Required {
 		t.Errorf("Invalid newString parameter: %v", params[2])
 	}
 
@@ -371,10 +372,11 @@ func TestFileWriteTool_Schema(t *testing.T) {
 		t.Fatalf("Schema properties not found or not a map")
 	}
 
-	// Check required properties
+	// Only filePath is required, so undo and the old_string/new_string
+	// aliases get past the schema and each operation checks its own fields
 	required, ok := schema["required"].([]string)
-	if !ok || len(required) != 3 || required[0] != "filePath" || required[1] != "oldString" || required[2] != "newString" {
-		t.Errorf("Expected required fields ['filePath', 'oldString', 'newString'], got %v", required)
+	if !ok || len(required) != 1 || required[0] != "filePath" {
+		t.Errorf("Expected required fields ['filePath'], got %v", required)
 	}
 
 	// Check that all expected properties are present
@@ -415,3 +417,362 @@ func TestFileWriteTool_Schema(t *testing.T) {
 		}
 	}
 }
+
+func TestFileWriteTool_Undo(t *testing.T) {
+	tempDir := t.TempDir()
+	tool := NewFileWriteTool("test-undo", "Test Undo Tool", map[string]string{"workspace": tempDir}, zap.NewNop())
+	filePath := filepath.Join(tempDir, "notes.txt")
+
+	result, err := tool.Execute(context.Background(), `{"filePath": "notes.txt", "newString": "v1", "parent_chat_id": "chat-1"}`)
+	if err != nil {
+		t.Fatalf("Unexpected error creating file: %v", err)
+	}
+	var resultData map[string]interface{}
+	if err := json.Unmarshal([]byte(result), &resultData); err != nil {
+		t.Fatalf("Failed to parse JSON result: %v", err)
+	}
+	backupPath, _ := resultData["backupPath"].(string)
+	if !strings.Contains(backupPath, filepath.Join(".aiagent", "backups", "chat-1")) {
+		t.Errorf("Expected backup under the chat's backup directory, got %q", backupPath)
+	}
+
+	if _, err := tool.Execute(context.Background(), `{"filePath": "notes.txt", "oldString": "v1", "newString": "v2", "parent_chat_id": "chat-1"}`); err != nil {
+		t.Fatalf("Unexpected error editing file: %v", err)
+	}
+
+	// The first undo restores the content before the edit
+	if _, err := tool.Execute(context.Background(), `{"filePath": "notes.txt", "operation": "undo", "parent_chat_id": "chat-1"}`); err != nil {
+		t.Fatalf("Unexpected error undoing edit: %v", err)
+	}
+	if content, _ := os.ReadFile(filePath); string(content) != "v1" {
+		t.Errorf("Expected content 'v1' after undo, got %q", content)
+	}
+
+	// Backups are kept per chat
+	if _, err := tool.Execute(context.Background(), `{"filePath": "notes.txt", "operation": "undo", "parent_chat_id": "chat-2"}`); err == nil {
+		t.Error("Expected undo from another chat to find no backup")
+	}
+
+	// The second undo removes the file that did not exist before
+	if _, err := tool.Execute(context.Background(), `{"filePath": "notes.txt", "operation": "undo", "parent_chat_id": "chat-1"}`); err != nil {
+		t.Fatalf("Unexpected error undoing create: %v", err)
+	}
+	if _, err := os.Stat(filePath); !os.IsNotExist(err) {
+		t.Errorf("Expected file to be removed after undoing its creation, got %v", err)
+	}
+}
+
+func TestFileBackupStore_Eviction(t *testing.T) {
+	tempDir := t.TempDir()
+	store := newFileBackupStore(tempDir, map[string]string{"backup_max_bytes": "10"})
+	filePath := filepath.Join(tempDir, "big.txt")
+
+	for i := 0; i < 3; i++ {
+		if err := os.WriteFile(filePath, []byte("0123456789"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		if _, err := store.Save("chat-1", "big.txt", filePath); err != nil {
+			t.Fatalf("Unexpected error saving backup: %v", err)
+		}
+	}
+
+	entries, err := os.ReadDir(store.chatDir("chat-1"))
+	if err != nil {
+		t.Fatalf("Failed to read backups: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected older backups to be evicted, got %d backups", len(entries))
+	}
+}
+
+func TestFileWriteTool_ReplaceAndCount(t *testing.T) {
+	tempDir := t.TempDir()
+	tool := NewFileWriteTool("test-replace", "Test Replace Tool", map[string]string{"workspace": tempDir}, zap.NewNop())
+	filePath := filepath.Join(tempDir, "replace.txt")
+	if err := os.WriteFile(filePath, []byte("foo bar\nfoo baz\n"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	var response struct {
+		Occurrences int    `json:"occurrences"`
+		Diff        string `json:"diff"`
+		DryRun      bool   `json:"dryRun"`
+	}
+
+	result, err := tool.Execute(context.Background(), `{"filePath": "replace.txt", "operation": "count", "old_string": "foo"}`)
+	if err != nil {
+		t.Fatalf("Unexpected error counting: %v", err)
+	}
+	if err := json.Unmarshal([]byte(result), &response); err != nil || response.Occurrences != 2 {
+		t.Errorf("Expected 2 occurrences, got %s", result)
+	}
+
+	// An ambiguous match is refused unless replace_all is set
+	_, err = tool.Execute(context.Background(), `{"filePath": "replace.txt", "operation": "replace", "old_string": "foo", "new_string": "qux"}`)
+	if err == nil || !strings.Contains(err.Error(), "appears 2 times") {
+		t.Errorf("Expected ambiguous match error, got %v", err)
+	}
+
+	_, err = tool.Execute(context.Background(), `{"filePath": "replace.txt", "operation": "replace", "old_string": "missing", "new_string": "qux"}`)
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+
+	// A dry run returns the diff without touching the file
+	result, err = tool.Execute(context.Background(), `{"filePath": "replace.txt", "operation": "replace", "old_string": "foo", "new_string": "qux", "replace_all": true, "dry_run": true}`)
+	if err != nil {
+		t.Fatalf("Unexpected error in dry run: %v", err)
+	}
+	if err := json.Unmarshal([]byte(result), &response); err != nil || !response.DryRun || !strings.Contains(response.Diff, "+qux") {
+		t.Errorf("Expected a dry run diff, got %s", result)
+	}
+	if content, _ := os.ReadFile(filePath); string(content) != "foo bar\nfoo baz\n" {
+		t.Errorf("Expected dry run to leave the file unchanged, got %q", content)
+	}
+
+	result, err = tool.Execute(context.Background(), `{"filePath": "replace.txt", "operation": "replace", "old_string": "foo", "new_string": "qux", "replace_all": true}`)
+	if err != nil {
+		t.Fatalf("Unexpected error replacing: %v", err)
+	}
+	if err := json.Unmarshal([]byte(result), &response); err != nil || response.Occurrences != 2 {
+		t.Errorf("Expected 2 replacements, got %s", result)
+	}
+	if content, _ := os.ReadFile(filePath); string(content) != "qux bar\nqux baz\n" {
+		t.Errorf("Expected all occurrences replaced, got %q", content)
+	}
+
+	// Leaving out newString is refused, while an empty one removes the match
+	_, err = tool.Execute(context.Background(), `{"filePath": "replace.txt", "operation": "replace", "old_string": " bar"}`)
+	if err == nil || !strings.Contains(err.Error(), "newString is required") {
+		t.Errorf("Expected missing newString error, got %v", err)
+	}
+	if _, err := tool.Execute(context.Background(), `{"filePath": "replace.txt", "operation": "replace", "old_string": " bar", "new_string": ""}`); err != nil {
+		t.Fatalf("Unexpected error removing text: %v", err)
+	}
+	if content, _ := os.ReadFile(filePath); string(content) != "qux\nqux baz\n" {
+		t.Errorf("Expected the match removed, got %q", content)
+	}
+}
+
+func TestFileWriteTool_Symbols(t *testing.T) {
+	tempDir := t.TempDir()
+	tool := NewFileWriteTool("test-symbols", "Test Symbol Tool", map[string]string{"workspace": tempDir}, zap.NewNop())
+	source := `package shapes
+
+// Area returns the area.
+func (s *Square) Area() int {
+	return s.side * s.side
+}
+
+func (c Circle) Area() int { return 3 * c.r * c.r }
+
+type Square struct {
+	side int
+}
+`
+	filePath := filepath.Join(tempDir, "shapes.go")
+	if err := os.WriteFile(filePath, []byte(source), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	// Edits find the symbol's current range, not the lines it used to be on
+	if err := os.WriteFile(filePath, []byte(strings.Replace(source, "package shapes\n", "package shapes\n\nimport \"fmt\"\n", 1)), 0644); err != nil {
+		t.Fatalf("Failed to update file: %v", err)
+	}
+	_, err := tool.Execute(context.Background(), `{"filePath": "shapes.go", "operation": "edit_symbol", "symbol": "Square", "newString": "type Square struct {\n\tside, scale int\n}"}`)
+	if err != nil {
+		t.Fatalf("Unexpected error editing symbol: %v", err)
+	}
+	content, _ := os.ReadFile(filePath)
+	if !strings.Contains(string(content), "type Square struct {\n\tside, scale int\n}\n") || !strings.Contains(string(content), "import \"fmt\"") {
+		t.Errorf("Expected the type to be replaced, got %q", content)
+	}
+
+	_, err = tool.Execute(context.Background(), `{"filePath": "shapes.go", "operation": "edit_symbol", "symbol": "Circle.Area", "newString": "func (c Circle) Area() int {"}`)
+	if err == nil || !strings.Contains(err.Error(), "does not parse") {
+		t.Errorf("Expected a parse error for a broken declaration, got %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(tempDir, "app.rb"), []byte("def main\nend\n"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	_, err = tool.Execute(context.Background(), `{"filePath": "app.rb", "operation": "edit_symbol", "symbol": "main", "newString": "def main\nend"}`)
+	if err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("Expected unsupported language error, got %v", err)
+	}
+}
+
+func TestFileWriteTool_Mutates(t *testing.T) {
+	tool := NewFileWriteTool("test-file-write", "Test File Write Tool", map[string]string{}, zap.NewNop())
+
+	tests := []struct {
+		arguments string
+		want      bool
+	}{
+		{`{"filePath":"a.txt","newString":"hi"}`, true},
+		{`{"operation":"replace","filePath":"a.txt","oldString":"a","newString":"b"}`, true},
+		{`{"operation":"replace","filePath":"a.txt","oldString":"a","newString":"b","dry_run":true}`, false},
+		{`{"operation":"count","filePath":"a.txt","oldString":"a"}`, false},
+		{`{"operation":"edit_symbol","filePath":"a.go","symbol":"main","newString":"func main() {}"}`, true},
+		{`not json`, true},
+	}
+	for _, tt := range tests {
+		if got := tool.Mutates(tt.arguments); got != tt.want {
+			t.Errorf("Mutates(%s) = %v, want %v", tt.arguments, got, tt.want)
+		}
+	}
+}
+
+func TestFileWriteTool_Preview(t *testing.T) {
+	tempDir := t.TempDir()
+	tool := NewFileWriteTool("test-file-write", "Test File Write Tool", map[string]string{"workspace": tempDir}, zap.NewNop())
+	path := filepath.Join(tempDir, "a.txt")
+	if err := os.WriteFile(path, []byte("hello world\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := tool.Preview(context.Background(), `{"filePath":"a.txt","oldString":"world","newString":"there"}`)
+	if err != nil {
+		t.Fatalf("Preview failed: %v", err)
+	}
+	if !strings.Contains(result, `"dryRun": true`) || !strings.Contains(result, "there") {
+		t.Errorf("Expected a dry run with the diff, got %s", result)
+	}
+	if content, _ := os.ReadFile(path); string(content) != "hello world\n" {
+		t.Errorf("Expected the file to be unchanged, got %q", content)
+	}
+
+	result, err = tool.Preview(context.Background(), `{"operation":"undo","filePath":"a.txt","oldString":"","newString":""}`)
+	if err != nil {
+		t.Fatalf("Preview of undo failed: %v", err)
+	}
+	if !strings.Contains(result, "Would restore a.txt") {
+		t.Errorf("Expected undo to be described, got %s", result)
+	}
+	if content, _ := os.ReadFile(path); string(content) != "hello world\n" {
+		t.Errorf("Expected undo not to run, got %q", content)
+	}
+}
+
+func TestFileWriteTool_ExpectedHash(t *testing.T) {
+	tempDir := t.TempDir()
+	config := map[string]string{"workspace": tempDir}
+	reader := NewFileReadTool("test-file-read", "Test File Read Tool", config, zap.NewNop())
+	writer := NewFileWriteTool("test-file-write", "Test File Write Tool", config, zap.NewNop())
+	path := filepath.Join(tempDir, "a.txt")
+	if err := os.WriteFile(path, []byte("hello world\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := reader.Execute(context.Background(), `{"filePath":"a.txt"}`)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	var read struct {
+		Hash string `json:"hash"`
+	}
+	if err := json.Unmarshal([]byte(result), &read); err != nil || read.Hash == "" {
+		t.Fatalf("Expected the read to return a hash, got %s", result)
+	}
+
+	edit := fmt.Sprintf(`{"filePath":"a.txt","oldString":"world","newString":"there","expected_hash":%q}`, read.Hash)
+	result, err = writer.Execute(context.Background(), edit)
+	if err != nil {
+		t.Fatalf("Edit with the read's hash failed: %v", err)
+	}
+	var edited struct {
+		Hash string `json:"hash"`
+	}
+	if err := json.Unmarshal([]byte(result), &edited); err != nil || edited.Hash == "" || edited.Hash == read.Hash {
+		t.Errorf("Expected the edit to return the new hash, got %s", result)
+	}
+
+	// The first edit changed the file, so the old hash is stale
+	stale := fmt.Sprintf(`{"filePath":"a.txt","oldString":"there","newString":"again","expected_hash":%q}`, read.Hash)
+	if _, err := writer.Execute(context.Background(), stale); err == nil || !strings.Contains(err.Error(), "file changed since read") {
+		t.Errorf("Expected a conflict with the stale hash, got %v", err)
+	}
+	if content, _ := os.ReadFile(path); string(content) != "hello there\n" {
+		t.Errorf("Expected the conflicting edit not to apply, got %q", content)
+	}
+
+	// A hash the file no longer matches also refuses undo
+	undo := fmt.Sprintf(`{"operation":"undo","filePath":"a.txt","oldString":"","newString":"","expected_hash":%q}`, read.Hash)
+	if _, err := writer.Execute(context.Background(), undo); err == nil || !strings.Contains(err.Error(), "file changed since read") {
+		t.Errorf("Expected undo to conflict with the stale hash, got %v", err)
+	}
+
+	next := fmt.Sprintf(`{"filePath":"a.txt","oldString":"there","newString":"again","expected_hash":%q}`, edited.Hash)
+	if _, err := writer.Execute(context.Background(), next); err != nil {
+		t.Errorf("Expected the edit's hash to be current, got %v", err)
+	}
+}
+
+func TestCapDiff(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("--- a.go\n+++ a.go\n@@ -1 +1 @@\n")
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(&b, "+line %d\n", i)
+	}
+	diff := b.String()
+
+	capped, cut := capDiff(diff, 10)
+	if !cut {
+		t.Fatal("Expected the diff to be cut")
+	}
+	if !strings.HasPrefix(capped, "--- a.go\n+++ a.go\n@@ -1 +1 @@\n") {
+		t.Errorf("Expected the headers to be kept, got %q", capped)
+	}
+	if !strings.HasSuffix(capped, "... 43 additional changed lines ...\n") {
+		t.Errorf("Expected a summary of the omitted lines, got %q", capped)
+	}
+
+	if same, cut := capDiff(diff, 0); cut || same != diff {
+		t.Error("Expected no cap with a limit of zero")
+	}
+	if same, cut := capDiff(diff, 100); cut || same != diff {
+		t.Error("Expected a short diff to be kept whole")
+	}
+}
+
+func TestCapDiff_KeepsWholeHunks(t *testing.T) {
+	diff := "--- a.go\n+++ a.go\n@@ -1,2 +1,2 @@\n-a\n+b\n@@ -10,2 +10,2 @@\n-c\n+d\n@@ -20,3 +20,3 @@\n-e\n+f\n+g\n"
+
+	capped, cut := capDiff(diff, 7)
+	if !cut {
+		t.Fatal("Expected the diff to be cut")
+	}
+	want := "--- a.go\n+++ a.go\n@@ -1,2 +1,2 @@\n-a\n+b\n... 5 additional changed lines ...\n"
+	if capped != want {
+		t.Errorf("capDiff() = %q, want %q", capped, want)
+	}
+}
+
+func TestFileWriteTool_CapsDiffForModel(t *testing.T) {
+	tempDir := t.TempDir()
+	tool := NewFileWriteTool("test-file-write", "Test File Write Tool", map[string]string{"workspace": tempDir, "max_diff_lines": "5"}, zap.NewNop())
+
+	content := strings.Repeat("hello\n", 40)
+	args, _ := json.Marshal(map[string]any{"filePath": "big.txt", "newString": content})
+	result, err := tool.Execute(context.Background(), string(args))
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	var resp struct {
+		Diff     string `json:"diff"`
+		FullDiff string `json:"fullDiff"`
+	}
+	if err := json.Unmarshal([]byte(result), &resp); err != nil {
+		t.Fatalf("Failed to parse result: %v", err)
+	}
+	if !strings.Contains(resp.Diff, "additional changed lines") {
+		t.Errorf("Expected a capped diff, got %q", resp.Diff)
+	}
+	if strings.Count(resp.FullDiff, "+hello") != 40 {
+		t.Errorf("Expected the full diff to keep every line")
+	}
+	if written, _ := os.ReadFile(filepath.Join(tempDir, "big.txt")); string(written) != content {
+		t.Error("Expected the whole file to be written")
+	}
+}
@@ -0,0 +1,190 @@
+package tools
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreRule is a single compiled .gitignore pattern.
+type ignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreMatcher applies .gitignore semantics to workspace-relative paths.
+// Rules are evaluated in order and the last matching rule wins. A path inside
+// an ignored directory stays ignored, as git does not descend into it.
+type ignoreMatcher struct {
+	root  string
+	rules []ignoreRule
+}
+
+// newIgnoreMatcher loads the .gitignore at the workspace root followed by the
+// comma separated patterns from the tool's "ignore" configuration.
+func newIgnoreMatcher(workspace string, configured string) *ignoreMatcher {
+	m := &ignoreMatcher{root: workspace}
+
+	if file, err := os.Open(filepath.Join(workspace, ".gitignore")); err == nil {
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			m.addPattern(scanner.Text())
+		}
+		file.Close()
+	}
+
+	for _, pattern := range strings.Split(configured, ",") {
+		m.addPattern(pattern)
+	}
+
+	return m
+}
+
+// newPatternMatcher builds a matcher of the given gitignore-style patterns
+// alone, or nil when there are none.
+func newPatternMatcher(root string, patterns []string) *ignoreMatcher {
+	if len(patterns) == 0 {
+		return nil
+	}
+	m := &ignoreMatcher{root: root}
+	for _, pattern := range patterns {
+		m.addPattern(pattern)
+	}
+	return m
+}
+
+// loadIgnoreMatcher builds the matcher for a tool's configured workspace.
+func loadIgnoreMatcher(configuration map[string]string) *ignoreMatcher {
+	workspace := configuration["workspace"]
+	if workspace == "" {
+		var err error
+		workspace, err = os.Getwd()
+		if err != nil {
+			return nil
+		}
+	}
+	return newIgnoreMatcher(workspace, configuration["ignore"])
+}
+
+func (m *ignoreMatcher) addPattern(line string) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return
+	}
+	line = strings.TrimLeft(line, " \t")
+
+	rule := ignoreRule{}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return
+	}
+
+	// Patterns containing a slash are relative to the root, others match at any depth
+	prefix := "(.*/)?"
+	if strings.Contains(line, "/") {
+		prefix = ""
+		line = strings.TrimPrefix(line, "/")
+	}
+
+	re, err := regexp.Compile("^" + prefix + globToRegexp(line) + "$")
+	if err != nil {
+		return
+	}
+	rule.re = re
+	m.rules = append(m.rules, rule)
+}
+
+// globToRegexp converts a gitignore glob into a regular expression body.
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			sb.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end == -1 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return sb.String()
+}
+
+// matches reports whether the rules ignore the path itself, ignoring parents.
+func (m *ignoreMatcher) matches(relPath string, isDir bool) bool {
+	ignored := false
+	for _, rule := range m.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.re.MatchString(relPath) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// Ignored reports whether an absolute or workspace-relative path is ignored.
+func (m *ignoreMatcher) Ignored(path string, isDir bool) bool {
+	if m == nil || len(m.rules) == 0 {
+		return false
+	}
+
+	relPath := path
+	if filepath.IsAbs(path) {
+		rel, err := filepath.Rel(m.root, path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return false
+		}
+		relPath = rel
+	}
+	relPath = filepath.ToSlash(relPath)
+	if relPath == "." || relPath == "" {
+		return false
+	}
+
+	parts := strings.Split(relPath, "/")
+	for i := 1; i < len(parts); i++ {
+		if m.matches(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return m.matches(relPath, isDir)
+}
@@ -0,0 +1,53 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnoreMatcher_Ignored(t *testing.T) {
+	tempDir := t.TempDir()
+	gitignore := "# dependencies\nnode_modules/\n*.log\n!keep.log\n/build\ndocs/**/*.tmp\nvendor/\n"
+	if err := os.WriteFile(filepath.Join(tempDir, ".gitignore"), []byte(gitignore), 0644); err != nil {
+		t.Fatalf("Failed to write .gitignore: %v", err)
+	}
+
+	matcher := newIgnoreMatcher(tempDir, "go.sum, *.lock")
+
+	tests := []struct {
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"node_modules", true, true},
+		{"web/node_modules", true, true},
+		{"node_modules", false, false},
+		{"node_modules/react/index.js", false, true},
+		{"debug.log", false, true},
+		{"logs/app/debug.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"src/build", true, false},
+		{"docs/a/b/c.tmp", false, true},
+		{"docs/c.tmp", false, true},
+		{"src/c.tmp", false, false},
+		{"go.sum", false, true},
+		{"Cargo.lock", false, true},
+		{"main.go", false, false},
+		{filepath.Join(tempDir, "vendor", "lib.go"), false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := matcher.Ignored(tt.path, tt.isDir); got != tt.expected {
+				t.Errorf("Ignored(%q, %v) = %v, expected %v", tt.path, tt.isDir, got, tt.expected)
+			}
+		})
+	}
+
+	var nilMatcher *ignoreMatcher
+	if nilMatcher.Ignored("debug.log", false) {
+		t.Error("Expected nil matcher to ignore nothing")
+	}
+}
@@ -0,0 +1,647 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io/fs"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/impl/workspacepath"
+
+	"go.uber.org/zap"
+)
+
+// DefaultGrepMaxResults caps the matches returned when neither the call nor
+// the tool's configuration sets max_results.
+const DefaultGrepMaxResults = 200
+
+// grepMaxFileSize skips files the Go search would have to hold in memory.
+const grepMaxFileSize = 10 * 1024 * 1024
+
+// GrepTool searches file contents with ripgrep when it is on the PATH and
+// with a Go implementation otherwise, so the results look the same on every
+// platform.
+type GrepTool struct {
+	name          string
+	description   string
+	configuration map[string]string
+	logger        *zap.Logger
+}
+
+// grepMatch is a matching line with its surrounding context.
+type grepMatch struct {
+	Path   string   `json:"path"`
+	Line   int      `json:"line"`
+	Column int      `json:"column"`
+	Text   string   `json:"text"`
+	Before []string `json:"before,omitempty"`
+	After  []string `json:"after,omitempty"`
+}
+
+type grepArgs struct {
+	Pattern         string
+	Path            string
+	Include         []string
+	Exclude         []string
+	Languages       []string
+	Context         int
+	CaseInsensitive bool
+	MaxResults      int
+	IncludeIgnored  bool
+}
+
+func NewGrepTool(name, description string, configuration map[string]string, logger *zap.Logger) *GrepTool {
+	return &GrepTool{
+		name:          name,
+		description:   description,
+		configuration: configuration,
+		logger:        logger,
+	}
+}
+
+func (t *GrepTool) Name() string {
+	return t.name
+}
+
+func (t *GrepTool) Description() string {
+	return t.description
+}
+
+func (t *GrepTool) Configuration() map[string]string {
+	return t.configuration
+}
+
+func (t *GrepTool) UpdateConfiguration(config map[string]string) {
+	t.configuration = config
+}
+
+func (t *GrepTool) WithConfiguration(config map[string]string) entities.Tool {
+	c := *t
+	c.configuration = config
+	return &c
+}
+
+func (t *GrepTool) FullDescription() string {
+	return fmt.Sprintf(`%s
+
+Parameters:
+- pattern: The regular expression to search for
+- path: The file or directory to search, relative to the workspace (default ".")
+- include: Glob patterns of files to search, e.g. ["*.go", "*.{ts,tsx}"]
+- exclude: Glob patterns of files or directories to skip, e.g. ["vendor/**"]
+- language: Search only the source files of these languages (%s), or "auto" for those detected from manifest files such as go.mod or package.json. Without it every file is searched
+- context: Number of lines to show before and after each match (default 0)
+- case_insensitive: Ignore case when matching (default false)
+- max_results: Maximum number of matches to return (default %d)
+- include_ignored: Also search files matched by .gitignore or the ignore config (default false)
+
+Images, archives, compiled objects and other binary files are always skipped.
+
+Returns matches as {path, line, column, text} with the context lines in before and after, and the languages searched.`, t.Description(), sourceLanguageNames(), DefaultGrepMaxResults)
+}
+
+func (t *GrepTool) Schema() map[string]any {
+	globs := map[string]any{
+		"type":  "array",
+		"items": map[string]any{"type": "string"},
+	}
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"pattern": map[string]any{
+				"type":        "string",
+				"description": "The regular expression to search for",
+			},
+			"path": map[string]any{
+				"type":        "string",
+				"description": "The file or directory to search, relative to the workspace (default \".\")",
+			},
+			"include":  withDescription(globs, "Glob patterns of files to search, e.g. [\"*.go\", \"*.{ts,tsx}\"]"),
+			"exclude":  withDescription(globs, "Glob patterns of files or directories to skip, e.g. [\"vendor/**\"]"),
+			"language": withDescription(globs, fmt.Sprintf("Search only the source files of these languages (%s), or \"auto\" for those detected from manifest files such as go.mod. Without it every file is searched", sourceLanguageNames())),
+			"context": map[string]any{
+				"type":        "integer",
+				"description": "Number of lines to show before and after each match (default 0)",
+			},
+			"case_insensitive": map[string]any{
+				"type":        "boolean",
+				"description": "Ignore case when matching (default false)",
+			},
+			"max_results": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum number of matches to return (default %d)", DefaultGrepMaxResults),
+			},
+			"include_ignored": map[string]any{
+				"type":        "boolean",
+				"description": "Also search files matched by .gitignore or the ignore config (default false)",
+			},
+		},
+		"required": []string{"pattern"},
+	}
+}
+
+func withDescription(schema map[string]any, description string) map[string]any {
+	result := make(map[string]any, len(schema)+1)
+	for k, v := range schema {
+		result[k] = v
+	}
+	result["description"] = description
+	return result
+}
+
+func (t *GrepTool) workspace() (string, error) {
+	workspace := t.configuration["workspace"]
+	if workspace == "" {
+		var err error
+		workspace, err = os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("could not get current directory: %v", err)
+		}
+	}
+	return workspace, nil
+}
+
+func (t *GrepTool) validatePath(path string) (string, error) {
+	workspace, err := t.workspace()
+	if err != nil {
+		return "", err
+	}
+
+	fullPath, err := workspacepath.Resolve(workspace, path)
+	if err != nil {
+		t.logger.Error("Path is outside workspace", zap.String("path", path), zap.Error(err))
+		return "", err
+	}
+	return fullPath, nil
+}
+
+// maxResults is the call's limit, else the configured one, else the default.
+func (t *GrepTool) maxResults(requested int) int {
+	if requested > 0 {
+		return requested
+	}
+	if n, err := strconv.Atoi(t.configuration["max_results"]); err == nil && n > 0 {
+		return n
+	}
+	return DefaultGrepMaxResults
+}
+
+// stringList accepts a single string or an array of strings, as models send
+// either for glob lists.
+func stringList(value any) []string {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+		return []string{v}
+	case []any:
+		var list []string
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+func grepError(message string) (string, error) {
+	data, _ := json.Marshal(map[string]any{"matches": []grepMatch{}, "error": message})
+	return string(data), nil
+}
+
+// Cacheable reports that searches can be answered again from the run's cache
+// until a searched file changes.
+func (t *GrepTool) Cacheable(arguments string) bool {
+	return true
+}
+
+// Paths returns the file or directory the call searches, the workspace when
+// no path is given.
+func (t *GrepTool) Paths(arguments string) []string {
+	var args struct {
+		Path string `json:"path"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return nil
+	}
+	return toolPaths(t.configuration, args.Path)
+}
+
+func (t *GrepTool) Execute(ctx context.Context, arguments string) (string, error) {
+	t.logger.Debug("Executing grep", zap.String("arguments", arguments))
+
+	var rawArgs map[string]any
+	if err := json.Unmarshal([]byte(arguments), &rawArgs); err != nil {
+		return grepError("failed to parse arguments")
+	}
+	args := grepArgs{
+		Pattern:         getStringField(rawArgs, "pattern"),
+		Path:            getStringField(rawArgs, "path"),
+		Include:         stringList(rawArgs["include"]),
+		Exclude:         stringList(rawArgs["exclude"]),
+		Languages:       stringList(rawArgs["language"]),
+		CaseInsensitive: getBoolField(rawArgs, "case_insensitive"),
+		IncludeIgnored:  getBoolField(rawArgs, "include_ignored"),
+	}
+	if n, ok := rawArgs["context"].(float64); ok && n > 0 {
+		args.Context = int(n)
+	}
+	if n, ok := rawArgs["max_results"].(float64); ok {
+		args.MaxResults = int(n)
+	}
+	args.MaxResults = t.maxResults(args.MaxResults)
+
+	if args.Pattern == "" {
+		return grepError("pattern is required")
+	}
+	if args.Path == "" {
+		args.Path = "."
+	}
+
+	fullPath, err := t.validatePath(args.Path)
+	if err != nil {
+		return grepError("invalid path: " + err.Error())
+	}
+	workspace, err := t.workspace()
+	if err != nil {
+		return grepError(err.Error())
+	}
+
+	// Narrow the search to the source files of the languages asked for, or
+	// with "auto", of those the manifest files show
+	languages, detected := args.Languages, false
+	if len(languages) == 1 && strings.EqualFold(languages[0], "auto") {
+		languages, detected = searchLanguages(workspace, fullPath), true
+	}
+	if len(languages) == 1 && strings.EqualFold(languages[0], "all") {
+		languages = nil
+	}
+	languageIncludes, err := languageGlobs(languages)
+	if err != nil {
+		return grepError(err.Error())
+	}
+	args.Include = append(args.Include, languageIncludes...)
+
+	engine := "go"
+	var matches []grepMatch
+	var truncated bool
+	if rg, lookErr := exec.LookPath("rg"); lookErr == nil {
+		engine = "rg"
+		matches, truncated, err = t.searchRipgrep(ctx, rg, workspace, fullPath, args)
+	} else {
+		matches, truncated, err = t.searchGo(ctx, workspace, fullPath, args)
+	}
+	if err != nil {
+		return grepError("search failed: " + err.Error())
+	}
+
+	files := make(map[string]bool)
+	for _, m := range matches {
+		files[m.Path] = true
+	}
+	summary := fmt.Sprintf("%d matches in %d files", len(matches), len(files))
+	if truncated {
+		summary += fmt.Sprintf(" (stopped at %d; narrow the search to see more)", args.MaxResults)
+	}
+	if detected && len(languages) > 0 {
+		summary += fmt.Sprintf(" in %s source files; leave out language to search every file", strings.Join(languages, ", "))
+	}
+	if matches == nil {
+		matches = []grepMatch{}
+	}
+
+	response := map[string]any{
+		"matches":   matches,
+		"truncated": truncated,
+		"engine":    engine,
+		"summary":   summary,
+	}
+	if len(languages) > 0 {
+		response["languages"] = languages
+	}
+	data, err := json.Marshal(response)
+	if err != nil {
+		return grepError("failed to marshal response")
+	}
+	t.logger.Info("Grep completed", zap.String("engine", engine), zap.Int("matches", len(matches)), zap.Bool("truncated", truncated))
+	return string(data), nil
+}
+
+// searchRipgrep runs rg with JSON output from the workspace so the reported
+// paths are workspace relative. Hidden files are searched like the Go
+// implementation does, except for .git and .aiagent.
+func (t *GrepTool) searchRipgrep(ctx context.Context, rg, workspace, fullPath string, args grepArgs) ([]grepMatch, bool, error) {
+	target, err := filepath.Rel(workspace, fullPath)
+	if err != nil {
+		return nil, false, err
+	}
+
+	cmdArgs := []string{"--json", "--sort", "path", "--hidden", "--no-require-git", "--glob", "!.git", "--glob", "!.aiagent"}
+	if args.CaseInsensitive {
+		cmdArgs = append(cmdArgs, "--ignore-case")
+	}
+	if args.Context > 0 {
+		cmdArgs = append(cmdArgs, "--context", strconv.Itoa(args.Context))
+	}
+	if args.IncludeIgnored {
+		cmdArgs = append(cmdArgs, "--no-ignore")
+	} else {
+		for _, pattern := range strings.Split(t.configuration["ignore"], ",") {
+			if pattern = strings.TrimSpace(pattern); pattern != "" {
+				cmdArgs = append(cmdArgs, "--glob", "!"+pattern)
+			}
+		}
+	}
+	for _, glob := range args.Include {
+		cmdArgs = append(cmdArgs, "--glob", glob)
+	}
+	for _, glob := range args.Exclude {
+		cmdArgs = append(cmdArgs, "--glob", "!"+glob)
+	}
+	for _, ext := range slices.Sorted(maps.Keys(binaryExtensions)) {
+		cmdArgs = append(cmdArgs, "--glob", "!*"+ext)
+	}
+	cmdArgs = append(cmdArgs, "--regexp", args.Pattern, "--", target)
+
+	cmd := exec.CommandContext(ctx, rg, cmdArgs...)
+	cmd.Dir = workspace
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, false, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, false, err
+	}
+
+	matches, truncated := parseRipgrepJSON(stdout, args.Context, args.MaxResults)
+	if truncated {
+		cmd.Process.Kill()
+	}
+	err = cmd.Wait()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, false, ctxErr
+	}
+	// rg exits with 1 when nothing matched
+	if exitErr, ok := err.(*exec.ExitError); ok && !truncated && exitErr.ExitCode() != 1 {
+		return nil, false, fmt.Errorf("%s", strings.TrimSpace(stderr.String()))
+	}
+	return matches, truncated, nil
+}
+
+// ripgrepLine is the part of an rg --json "match" or "context" message used.
+type ripgrepLine struct {
+	Type string `json:"type"`
+	Data struct {
+		Path struct {
+			Text string `json:"text"`
+		} `json:"path"`
+		Lines struct {
+			Text string `json:"text"`
+		} `json:"lines"`
+		LineNumber int `json:"line_number"`
+		Submatches []struct {
+			Start int `json:"start"`
+		} `json:"submatches"`
+	} `json:"data"`
+}
+
+// parseRipgrepJSON reads rg --json output. Context lines after a match within
+// the context distance belong to it; the others are held for the next match.
+func parseRipgrepJSON(r interface{ Read([]byte) (int, error) }, contextLines, maxResults int) ([]grepMatch, bool) {
+	var matches []grepMatch
+	var pending []ripgrepLine
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var msg ripgrepLine
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			continue
+		}
+		path := filepath.ToSlash(filepath.Clean(msg.Data.Path.Text))
+		text := strings.TrimRight(msg.Data.Lines.Text, "\r\n")
+
+		switch msg.Type {
+		case "begin":
+			pending = nil
+		case "context":
+			if n := len(matches); n > 0 {
+				last := &matches[n-1]
+				if last.Path == path && msg.Data.LineNumber > last.Line && msg.Data.LineNumber-last.Line <= contextLines {
+					last.After = append(last.After, text)
+					continue
+				}
+			}
+			msg.Data.Lines.Text = text
+			pending = append(pending, msg)
+		case "match":
+			if len(matches) == maxResults {
+				return matches, true
+			}
+			m := grepMatch{Path: path, Line: msg.Data.LineNumber, Column: 1, Text: text}
+			if len(msg.Data.Submatches) > 0 {
+				m.Column = msg.Data.Submatches[0].Start + 1
+			}
+			for _, c := range pending {
+				if m.Line-c.Data.LineNumber <= contextLines {
+					m.Before = append(m.Before, c.Data.Lines.Text)
+				}
+			}
+			pending = nil
+			matches = append(matches, m)
+		}
+	}
+	return matches, false
+}
+
+// searchGo walks the search path in lexical order and matches each text
+// file line by line.
+func (t *GrepTool) searchGo(ctx context.Context, workspace, fullPath string, args grepArgs) ([]grepMatch, bool, error) {
+	pattern := args.Pattern
+	if args.CaseInsensitive {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid pattern: %v", err)
+	}
+
+	var ignore *ignoreMatcher
+	if !args.IncludeIgnored {
+		ignore = loadIgnoreMatcher(t.configuration)
+	}
+	include := globMatcher(workspace, args.Include)
+	exclude := globMatcher(workspace, args.Exclude)
+
+	var matches []grepMatch
+	truncated := false
+	err = filepath.WalkDir(fullPath, func(path string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path == fullPath {
+				return nil
+			}
+			if d.Name() == ".git" || d.Name() == ".aiagent" || ignore.Ignored(path, true) || exclude.Ignored(path, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ignore.Ignored(path, false) || exclude.Ignored(path, false) || hasBinaryExtension(path) {
+			return nil
+		}
+		if include != nil && !include.Ignored(path, false) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(workspace, path)
+		if err != nil {
+			return nil
+		}
+		fileMatches := grepFile(path, filepath.ToSlash(rel), re, args.Context)
+		for _, m := range fileMatches {
+			if len(matches) == args.MaxResults {
+				truncated = true
+				return filepath.SkipAll
+			}
+			matches = append(matches, m)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return matches, truncated, nil
+}
+
+// globMatcher matches paths against glob patterns with gitignore semantics,
+// so patterns without a slash match at any depth. Braces such as
+// "*.{ts,tsx}" are expanded. It returns nil without patterns.
+func globMatcher(workspace string, globs []string) *ignoreMatcher {
+	if len(globs) == 0 {
+		return nil
+	}
+	m := &ignoreMatcher{root: workspace}
+	for _, glob := range globs {
+		for _, expanded := range expandBraces(glob) {
+			m.addPattern(expanded)
+		}
+	}
+	return m
+}
+
+// expandBraces expands the first {a,b} group of a glob, recursively.
+func expandBraces(glob string) []string {
+	start := strings.IndexByte(glob, '{')
+	if start == -1 {
+		return []string{glob}
+	}
+	end := strings.IndexByte(glob[start:], '}')
+	if end == -1 {
+		return []string{glob}
+	}
+	end += start
+
+	var result []string
+	for _, alt := range strings.Split(glob[start+1:end], ",") {
+		result = append(result, expandBraces(glob[:start]+alt+glob[end+1:])...)
+	}
+	return result
+}
+
+// grepFile returns the matching lines of a file. Large and binary files are
+// skipped.
+func grepFile(path, relPath string, re *regexp.Regexp, contextLines int) []grepMatch {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > grepMaxFileSize {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || bytes.IndexByte(data[:min(len(data), 8000)], 0) != -1 {
+		return nil
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	var matches []grepMatch
+	for i, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		loc := re.FindStringIndex(line)
+		if loc == nil {
+			continue
+		}
+		m := grepMatch{Path: relPath, Line: i + 1, Column: loc[0] + 1, Text: line}
+		if contextLines > 0 {
+			m.Before = trimCR(lines[max(0, i-contextLines):i])
+			m.After = trimCR(lines[i+1 : min(len(lines), i+1+contextLines)])
+		}
+		matches = append(matches, m)
+	}
+	return matches
+}
+
+func trimCR(lines []string) []string {
+	if len(lines) == 0 {
+		return nil
+	}
+	result := make([]string, len(lines))
+	for i, line := range lines {
+		result[i] = strings.TrimSuffix(line, "\r")
+	}
+	return result
+}
+
+func (t *GrepTool) DisplayName(ui string, arguments string) (string, string) {
+	var args struct {
+		Pattern string `json:"pattern"`
+		Path    string `json:"path"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err == nil {
+		detail := args.Pattern
+		if args.Path != "" && args.Path != "." {
+			detail += " in " + args.Path
+		}
+		return t.Name(), detail
+	}
+	return t.Name(), ""
+}
+
+func (t *GrepTool) FormatResult(ui string, result string, diff string, arguments string) string {
+	var response struct {
+		Summary string `json:"summary"`
+		Error   string `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(result), &response); err != nil {
+		return result
+	}
+
+	summary := response.Summary
+	if response.Error != "" {
+		summary = "Failed: " + response.Error
+	}
+	if ui == "webui" {
+		return fmt.Sprintf("<div class=\"tool-summary\">%s</div>", html.EscapeString(summary))
+	}
+	return summary
+}
+
+var _ entities.Tool = (*GrepTool)(nil)
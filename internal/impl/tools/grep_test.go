@@ -0,0 +1,200 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func writeGrepFixture(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.go":            "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n",
+		"web/app.ts":         "const hello = 1;\n",
+		"web/view.tsx":       "export const Hello = () => null;\n",
+		"vendor/lib/lib.go":  "package lib // hello\n",
+		"build/out.txt":      "hello from build\n",
+		".gitignore":         "build/\n",
+		"data.bin":           "hello\x00world\n",
+		".aiagent/state.txt": "hello\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func matchPaths(matches []grepMatch) []string {
+	var paths []string
+	for _, m := range matches {
+		paths = append(paths, m.Path)
+	}
+	return paths
+}
+
+func TestGrepTool_SearchGo(t *testing.T) {
+	dir := writeGrepFixture(t)
+	tool := NewGrepTool("CodeSearch", "", map[string]string{"workspace": dir}, zap.NewNop())
+
+	tests := []struct {
+		name string
+		args grepArgs
+		want []string
+	}{
+		{"skips ignored and binary", grepArgs{Pattern: "hello"}, []string{"main.go", "vendor/lib/lib.go", "web/app.ts"}},
+		{"case insensitive", grepArgs{Pattern: "hello", CaseInsensitive: true}, []string{"main.go", "vendor/lib/lib.go", "web/app.ts", "web/view.tsx"}},
+		{"include braces", grepArgs{Pattern: "(?i)hello", Include: []string{"*.{ts,tsx}"}}, []string{"web/app.ts", "web/view.tsx"}},
+		{"exclude", grepArgs{Pattern: "hello", Exclude: []string{"vendor/"}}, []string{"main.go", "web/app.ts"}},
+		{"include ignored", grepArgs{Pattern: "from build", IncludeIgnored: true}, []string{"build/out.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.args.MaxResults = 100
+			matches, truncated, err := tool.searchGo(context.Background(), dir, dir, tt.args)
+			if err != nil {
+				t.Fatalf("searchGo: %v", err)
+			}
+			if truncated {
+				t.Errorf("expected no truncation")
+			}
+			if got := matchPaths(matches); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("paths = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGrepTool_SearchGoContextAndCap(t *testing.T) {
+	dir := writeGrepFixture(t)
+	tool := NewGrepTool("CodeSearch", "", map[string]string{"workspace": dir}, zap.NewNop())
+
+	matches, _, err := tool.searchGo(context.Background(), dir, filepath.Join(dir, "main.go"), grepArgs{Pattern: "println", Context: 1, MaxResults: 10})
+	if err != nil {
+		t.Fatalf("searchGo: %v", err)
+	}
+	want := grepMatch{Path: "main.go", Line: 4, Column: 2, Text: "\tprintln(\"hello\")", Before: []string{"func main() {"}, After: []string{"}"}}
+	if len(matches) != 1 || !reflect.DeepEqual(matches[0], want) {
+		t.Errorf("matches = %+v, want %+v", matches, want)
+	}
+
+	matches, truncated, err := tool.searchGo(context.Background(), dir, dir, grepArgs{Pattern: "hello", MaxResults: 2})
+	if err != nil {
+		t.Fatalf("searchGo: %v", err)
+	}
+	if len(matches) != 2 || !truncated {
+		t.Errorf("expected 2 truncated matches, got %d (truncated=%v)", len(matches), truncated)
+	}
+}
+
+func TestGrepTool_ExecuteDetectsLanguage(t *testing.T) {
+	dir := writeGrepFixture(t)
+	for name, content := range map[string]string{
+		"go.mod":    "module example\n",
+		"README.md": "hello readme\n",
+		"logo.png":  "hello as text\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tool := NewGrepTool("CodeSearch", "", map[string]string{"workspace": dir}, zap.NewNop())
+
+	tests := []struct {
+		arguments string
+		want      []string
+		languages []string
+	}{
+		{`{"pattern": "hello"}`, []string{"README.md", "main.go", "vendor/lib/lib.go", "web/app.ts"}, nil},
+		{`{"pattern": "hello", "language": "auto"}`, []string{"main.go", "vendor/lib/lib.go"}, []string{"go"}},
+		{`{"pattern": "hello", "include": ["*.md"]}`, []string{"README.md"}, nil},
+		{`{"pattern": "hello", "language": "javascript"}`, []string{"web/app.ts"}, []string{"javascript"}},
+		{`{"pattern": "hello", "language": "all"}`, []string{"README.md", "main.go", "vendor/lib/lib.go", "web/app.ts"}, nil},
+	}
+	for _, tt := range tests {
+		result, err := tool.Execute(context.Background(), tt.arguments)
+		if err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+		var response struct {
+			Matches   []grepMatch `json:"matches"`
+			Languages []string    `json:"languages"`
+		}
+		if err := json.Unmarshal([]byte(result), &response); err != nil {
+			t.Fatalf("Failed to parse %s: %v", result, err)
+		}
+		if got := matchPaths(response.Matches); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: paths = %v, want %v", tt.arguments, got, tt.want)
+		}
+		if !reflect.DeepEqual(response.Languages, tt.languages) {
+			t.Errorf("%s: languages = %v, want %v", tt.arguments, response.Languages, tt.languages)
+		}
+	}
+
+	result, _ := tool.Execute(context.Background(), `{"pattern": "hello", "language": "cobol"}`)
+	if !strings.Contains(result, "unknown language cobol") {
+		t.Errorf("Expected an unknown language error, got %s", result)
+	}
+}
+
+func TestGrepTool_ExecuteRejectsPathOutsideWorkspace(t *testing.T) {
+	dir := writeGrepFixture(t)
+	tool := NewGrepTool("CodeSearch", "", map[string]string{"workspace": dir}, zap.NewNop())
+
+	result, err := tool.Execute(context.Background(), `{"pattern": "hello", "path": "../"}`)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if !strings.Contains(result, "outside workspace") {
+		t.Errorf("expected workspace error, got %s", result)
+	}
+}
+
+func TestParseRipgrepJSON(t *testing.T) {
+	output := strings.Join([]string{
+		`{"type":"begin","data":{"path":{"text":"./a.go"}}}`,
+		`{"type":"context","data":{"path":{"text":"./a.go"},"lines":{"text":"one\n"},"line_number":1}}`,
+		`{"type":"match","data":{"path":{"text":"./a.go"},"lines":{"text":"two hello\n"},"line_number":2,"submatches":[{"start":4}]}}`,
+		`{"type":"context","data":{"path":{"text":"./a.go"},"lines":{"text":"three\n"},"line_number":3}}`,
+		`{"type":"end","data":{}}`,
+		`{"type":"begin","data":{"path":{"text":"./b.go"}}}`,
+		`{"type":"match","data":{"path":{"text":"./b.go"},"lines":{"text":"hello\n"},"line_number":7,"submatches":[{"start":0}]}}`,
+	}, "\n")
+
+	matches, truncated := parseRipgrepJSON(strings.NewReader(output), 1, 10)
+	if truncated {
+		t.Errorf("expected no truncation")
+	}
+	want := []grepMatch{
+		{Path: "a.go", Line: 2, Column: 5, Text: "two hello", Before: []string{"one"}, After: []string{"three"}},
+		{Path: "b.go", Line: 7, Column: 1, Text: "hello"},
+	}
+	if !reflect.DeepEqual(matches, want) {
+		t.Errorf("matches = %+v, want %+v", matches, want)
+	}
+
+	matches, truncated = parseRipgrepJSON(strings.NewReader(output), 1, 1)
+	if len(matches) != 1 || !truncated {
+		t.Errorf("expected 1 truncated match, got %d (truncated=%v)", len(matches), truncated)
+	}
+}
+
+func TestExpandBraces(t *testing.T) {
+	got := expandBraces("src/*.{ts,tsx}")
+	want := []string{"src/*.ts", "src/*.tsx"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expandBraces = %v, want %v", got, want)
+	}
+}
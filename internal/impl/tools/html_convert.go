@@ -0,0 +1,326 @@
+package tools
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	nethtml "golang.org/x/net/html"
+)
+
+// htmlToken is a piece of an HTML document: text, or a start, end or
+// self-closing tag with its lowercased name and attributes.
+type htmlToken struct {
+	kind  htmlTokenKind
+	name  string
+	attrs map[string]string
+	text  string
+}
+
+type htmlTokenKind int
+
+const (
+	htmlText htmlTokenKind = iota
+	htmlStartTag
+	htmlEndTag
+	htmlSelfClosingTag
+)
+
+// htmlSkippedElements are dropped together with their content.
+var htmlSkippedElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "head": true,
+	"svg": true, "iframe": true, "template": true, "form": true,
+	"nav": true, "footer": true,
+}
+
+// htmlVoidElements never have an end tag.
+var htmlVoidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"source": true, "track": true, "wbr": true,
+}
+
+var htmlBlockElements = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "main": true,
+	"header": true, "aside": true, "blockquote": true, "table": true,
+	"tr": true, "ul": true, "ol": true, "dl": true, "dt": true, "dd": true,
+	"figure": true, "figcaption": true, "hr": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "pre": true,
+}
+
+// tokenizeHTML splits a document into tokens with the HTML5 tokenizer, so
+// quoted '>' in attributes, comments, CDATA and the raw text of script and
+// style are handled as browsers do. Comments, doctypes and processing
+// instructions are dropped, and text and attribute values are unescaped.
+func tokenizeHTML(doc string) []htmlToken {
+	var tokens []htmlToken
+	z := nethtml.NewTokenizer(strings.NewReader(doc))
+	for {
+		switch z.Next() {
+		case nethtml.ErrorToken:
+			return tokens
+		case nethtml.TextToken:
+			tokens = append(tokens, htmlToken{kind: htmlText, text: string(z.Text())})
+		case nethtml.StartTagToken, nethtml.SelfClosingTagToken:
+			tokens = append(tokens, htmlTag(z.Token()))
+		case nethtml.EndTagToken:
+			tokens = append(tokens, htmlToken{kind: htmlEndTag, name: z.Token().Data})
+		}
+	}
+}
+
+func htmlTag(t nethtml.Token) htmlToken {
+	token := htmlToken{kind: htmlStartTag, name: t.Data, attrs: make(map[string]string, len(t.Attr))}
+	if t.Type == nethtml.SelfClosingTagToken || htmlVoidElements[token.name] {
+		token.kind = htmlSelfClosingTag
+	}
+	for _, attr := range t.Attr {
+		token.attrs[attr.Key] = attr.Val
+	}
+	return token
+}
+
+// htmlRenderer turns tokens into readable text, optionally with markdown
+// syntax for headings, emphasis, links, lists and code.
+type htmlRenderer struct {
+	markdown bool
+	base     *url.URL
+	out      strings.Builder
+
+	skipDepth   int
+	preDepth    int
+	lists       []listState
+	links       []string
+	afterMarker bool
+}
+
+type listState struct {
+	ordered bool
+	next    int
+}
+
+// convertHTML extracts the readable content of an HTML document as plain
+// text or markdown. Relative links are resolved against baseURL.
+func convertHTML(doc string, markdown bool, baseURL *url.URL) string {
+	r := &htmlRenderer{markdown: markdown, base: baseURL}
+	var skipping string
+	for _, token := range tokenizeHTML(doc) {
+		if r.skipDepth > 0 {
+			switch {
+			case token.kind == htmlStartTag && token.name == skipping:
+				r.skipDepth++
+			case token.kind == htmlEndTag && token.name == skipping:
+				r.skipDepth--
+			}
+			continue
+		}
+		if token.kind == htmlStartTag && htmlSkippedElements[token.name] {
+			skipping = token.name
+			r.skipDepth = 1
+			continue
+		}
+		r.render(token)
+	}
+	return cleanConvertedText(r.out.String())
+}
+
+func (r *htmlRenderer) render(token htmlToken) {
+	switch token.kind {
+	case htmlText:
+		r.text(token.text)
+	case htmlStartTag, htmlSelfClosingTag:
+		r.start(token)
+	case htmlEndTag:
+		r.end(token.name)
+	}
+}
+
+func (r *htmlRenderer) text(text string) {
+	if strings.TrimSpace(text) != "" {
+		r.afterMarker = false
+	}
+	if r.preDepth > 0 {
+		r.out.WriteString(text)
+		return
+	}
+	collapsed := strings.Join(strings.Fields(text), " ")
+	if collapsed == "" {
+		if text != "" && !r.atLineStart() && !strings.HasSuffix(r.out.String(), " ") {
+			r.out.WriteByte(' ')
+		}
+		return
+	}
+	if startsWithSpace(text) && !r.atLineStart() && !strings.HasSuffix(r.out.String(), " ") {
+		r.out.WriteByte(' ')
+	}
+	r.out.WriteString(collapsed)
+	if endsWithSpace(text) {
+		r.out.WriteByte(' ')
+	}
+}
+
+func (r *htmlRenderer) start(token htmlToken) {
+	name := token.name
+	if htmlBlockElements[name] {
+		r.block()
+	}
+
+	switch name {
+	case "br":
+		r.out.WriteString("\n")
+	case "hr":
+		if r.markdown {
+			r.out.WriteString("---")
+		}
+		r.block()
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		if r.markdown {
+			r.out.WriteString(strings.Repeat("#", int(name[1]-'0')) + " ")
+		}
+	case "ul", "ol":
+		r.lists = append(r.lists, listState{ordered: name == "ol", next: 1})
+	case "li":
+		r.listItem()
+	case "pre":
+		if r.markdown {
+			r.out.WriteString("```\n")
+		}
+		r.preDepth++
+	case "code":
+		if r.markdown && r.preDepth == 0 {
+			r.out.WriteString("`")
+		}
+	case "strong", "b":
+		if r.markdown {
+			r.out.WriteString("**")
+		}
+	case "em", "i":
+		if r.markdown {
+			r.out.WriteString("_")
+		}
+	case "a":
+		href := r.resolve(token.attrs["href"])
+		r.links = append(r.links, href)
+		if r.markdown && href != "" {
+			r.out.WriteString("[")
+		}
+	case "img":
+		alt := token.attrs["alt"]
+		if r.markdown && alt != "" {
+			r.out.WriteString("![" + alt + "](" + r.resolve(token.attrs["src"]) + ")")
+		} else if alt != "" {
+			r.out.WriteString(alt)
+		}
+	case "td", "th":
+		r.out.WriteString(" | ")
+	}
+}
+
+func (r *htmlRenderer) end(name string) {
+	switch name {
+	case "ul", "ol":
+		if len(r.lists) > 0 {
+			r.lists = r.lists[:len(r.lists)-1]
+		}
+	case "pre":
+		if r.preDepth > 0 {
+			r.preDepth--
+			if r.markdown {
+				if !strings.HasSuffix(r.out.String(), "\n") {
+					r.out.WriteString("\n")
+				}
+				r.out.WriteString("```")
+			}
+		}
+	case "code":
+		if r.markdown && r.preDepth == 0 {
+			r.out.WriteString("`")
+		}
+	case "strong", "b":
+		if r.markdown {
+			r.out.WriteString("**")
+		}
+	case "em", "i":
+		if r.markdown {
+			r.out.WriteString("_")
+		}
+	case "a":
+		if len(r.links) > 0 {
+			href := r.links[len(r.links)-1]
+			r.links = r.links[:len(r.links)-1]
+			if r.markdown && href != "" {
+				r.out.WriteString("](" + href + ")")
+			}
+		}
+	}
+
+	if htmlBlockElements[name] {
+		r.block()
+	}
+}
+
+func (r *htmlRenderer) listItem() {
+	if !r.atLineStart() {
+		r.out.WriteString("\n")
+	}
+	indent := ""
+	if len(r.lists) > 1 {
+		indent = strings.Repeat("  ", len(r.lists)-1)
+	}
+	marker := "- "
+	if n := len(r.lists); n > 0 && r.lists[n-1].ordered {
+		marker = strconv.Itoa(r.lists[n-1].next) + ". "
+		r.lists[n-1].next++
+	}
+	r.out.WriteString(indent + marker)
+	r.afterMarker = true
+}
+
+// block ends the current paragraph. Runs of blank lines are squeezed later.
+func (r *htmlRenderer) block() {
+	if r.preDepth > 0 || r.afterMarker {
+		return
+	}
+	r.out.WriteString("\n\n")
+}
+
+func (r *htmlRenderer) atLineStart() bool {
+	s := r.out.String()
+	return s == "" || strings.HasSuffix(s, "\n")
+}
+
+func (r *htmlRenderer) resolve(ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "javascript:") {
+		return ""
+	}
+	if r.base == nil {
+		return ref
+	}
+	u, err := r.base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// cleanConvertedText trims trailing spaces from lines and squeezes blank lines.
+func cleanConvertedText(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	text = strings.Join(lines, "\n")
+	return strings.TrimSpace(blankLines.ReplaceAllString(text, "\n\n"))
+}
+
+func startsWithSpace(s string) bool {
+	return s != "" && strings.TrimLeft(s, " \t\r\n") != s
+}
+
+func endsWithSpace(s string) bool {
+	return s != "" && strings.TrimRight(s, " \t\r\n") != s
+}
@@ -47,6 +47,12 @@ func (t *ImageTool) UpdateConfiguration(config map[string]string) {
 	t.configuration = config
 }
 
+func (t *ImageTool) WithConfiguration(config map[string]string) entities.Tool {
+	c := *t
+	c.configuration = config
+	return &c
+}
+
 func (t *ImageTool) FullDescription() string {
 	var b strings.Builder
 	b.WriteString(t.Description() + "\n\n")
@@ -144,7 +150,7 @@ func (t *ImageTool) Execute(ctx context.Context, arguments string) (string, erro
 		return "", err
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
 	if err != nil {
 		t.logger.Error("Failed to create request", zap.Error(err), zap.String("url", url))
 		return "", err
@@ -4,21 +4,19 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/drujensen/aiagent/internal/domain/entities"
 
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.uber.org/zap"
 )
 
-// KnowledgeGraph represents the structure stored in MongoDB
+// KnowledgeGraph is the graph persisted by a GraphStore
 type KnowledgeGraph struct {
-	Entities  []Entity   `bson:"entities"`
-	Relations []Relation `bson:"relations"`
+	Entities  []Entity   `json:"entities" bson:"entities"`
+	Relations []Relation `json:"relations" bson:"relations"`
 }
 
 // Entity represents a node in the knowledge graph
@@ -35,37 +33,55 @@ type Relation struct {
 	RelationType string `json:"relationType" bson:"relationType"`
 }
 
+// maxMemoryEntities caps the entities read_graph and search_nodes return in
+// one call, with or without a limit, and maxMemoryRelations the relations.
+const (
+	maxMemoryEntities  = 200
+	maxMemoryRelations = 500
+)
+
+// graphPage is a page of entities with the relations from them.
+type graphPage struct {
+	Entities       []Entity   `json:"entities"`
+	Relations      []Relation `json:"relations"`
+	TotalEntities  int        `json:"total_entities"`
+	TotalRelations int        `json:"total_relations"`
+	Offset         int        `json:"offset"`
+	Truncated      bool       `json:"truncated"`
+	NextOffset     int        `json:"next_offset,omitempty"`
+	// RelationsTruncated is set when the page's entities have more than
+	// maxMemoryRelations relations; open_nodes returns them all.
+	RelationsTruncated bool `json:"relations_truncated,omitempty"`
+}
+
 type MemoryTool struct {
 	name          string
 	description   string
 	configuration map[string]string
 	logger        *zap.Logger
-	collection    *mongo.Collection
+	store         GraphStore
+	storeErr      error
 }
 
 func NewMemoryTool(name, description string, configuration map[string]string, logger *zap.Logger) entities.Tool {
-	// Expect MongoDB collection to be passed in configuration
-	collectionName, ok := configuration["mongo_collection"]
-	if !ok {
-		logger.Error("mongo_collection not specified in configuration")
-		collectionName = "knowledge_graph"
-	}
+	return newMemoryTool(name, description, configuration, "", "", logger)
+}
 
-	// Assume MongoDB client is initialized elsewhere (in main.go)
-	// For this tool, we'll use the same database as other repositories
-	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(configuration["mongo_uri"]))
+// newMemoryTool creates a Memory tool that defaults to the storage the
+// application runs with.
+func newMemoryTool(name, description string, configuration map[string]string, appStorage, appMongoURI string, logger *zap.Logger) entities.Tool {
+	store, err := newGraphStore(configuration, appStorage, appMongoURI)
 	if err != nil {
-		logger.Error("Failed to connect to MongoDB", zap.Error(err))
+		logger.Error("Failed to initialize memory storage", zap.Error(err))
 	}
-	db := client.Database("aiagent")
-	collection := db.Collection(collectionName)
 
 	return &MemoryTool{
 		name:          name,
 		description:   description,
 		configuration: configuration,
 		logger:        logger,
-		collection:    collection,
+		store:         store,
+		storeErr:      err,
 	}
 }
 
@@ -85,6 +101,12 @@ func (t *MemoryTool) UpdateConfiguration(config map[string]string) {
 	t.configuration = config
 }
 
+func (t *MemoryTool) WithConfiguration(config map[string]string) entities.Tool {
+	c := *t
+	c.configuration = config
+	return &c
+}
+
 func (t *MemoryTool) FullDescription() string {
 	var b strings.Builder
 	b.WriteString(t.Description())
@@ -144,7 +166,7 @@ func (t *MemoryTool) Schema() map[string]any {
 			},
 			"query": map[string]any{
 				"type":        "string",
-				"description": "Search query for nodes (for search_nodes)",
+				"description": "Words to search for (for search_nodes). Entities with a word starting with each of them are returned, ranked by name, type then observation matches; when none match, those containing the query anywhere are.",
 			},
 			"names": map[string]any{
 				"type":        "array",
@@ -153,11 +175,38 @@ func (t *MemoryTool) Schema() map[string]any {
 					"type": "string",
 				},
 			},
+			"entity_type": map[string]any{
+				"type":        "string",
+				"description": "Only return entities of this type (for read_graph, search_nodes)",
+			},
+			"limit": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum number of entities to return (for read_graph, search_nodes; at most %d)", maxMemoryEntities),
+			},
+			"offset": map[string]any{
+				"type":        "integer",
+				"description": "Number of matching entities to skip; use next_offset from a truncated result (for read_graph, search_nodes)",
+			},
 		},
 		"required": []string{"operation"},
 	}
 }
 
+// Mutates reports whether the call changes the knowledge graph.
+func (t *MemoryTool) Mutates(arguments string) bool {
+	var args struct {
+		Operation string `json:"operation"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return true
+	}
+	switch args.Operation {
+	case "read_graph", "search_nodes", "open_nodes":
+		return false
+	}
+	return true
+}
+
 func (t *MemoryTool) Execute(ctx context.Context, arguments string) (string, error) {
 	t.logger.Debug("Executing memory operation", zap.String("arguments", arguments))
 
@@ -170,6 +219,9 @@ func (t *MemoryTool) Execute(ctx context.Context, arguments string) (string, err
 		Deletions    []map[string]any `json:"deletions"`
 		Query        string           `json:"query"`
 		Names        []string         `json:"names"`
+		EntityType   string           `json:"entity_type"`
+		Limit        int              `json:"limit"`
+		Offset       int              `json:"offset"`
 	}
 	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
 		t.logger.Error("Failed to parse arguments", zap.Error(err))
@@ -181,7 +233,11 @@ func (t *MemoryTool) Execute(ctx context.Context, arguments string) (string, err
 		return "", fmt.Errorf("operation is required")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	if args.Limit < 0 || args.Offset < 0 {
+		return "", fmt.Errorf("limit and offset must not be negative")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	switch args.Operation {
@@ -198,9 +254,9 @@ func (t *MemoryTool) Execute(ctx context.Context, arguments string) (string, err
 	case "delete_relations":
 		return t.deleteRelations(ctx, args.Relations)
 	case "read_graph":
-		return t.readGraph(ctx)
+		return t.readGraph(ctx, args.EntityType, args.Offset, args.Limit)
 	case "search_nodes":
-		return t.searchNodes(ctx, args.Query)
+		return t.searchNodes(ctx, args.Query, args.EntityType, args.Offset, args.Limit)
 	case "open_nodes":
 		return t.openNodes(ctx, args.Names)
 	default:
@@ -210,26 +266,22 @@ func (t *MemoryTool) Execute(ctx context.Context, arguments string) (string, err
 }
 
 func (t *MemoryTool) loadGraph(ctx context.Context) (*KnowledgeGraph, error) {
-	var graph KnowledgeGraph
-	err := t.collection.FindOne(ctx, bson.M{"_id": "graph"}).Decode(&graph)
-	if err == mongo.ErrNoDocuments {
-		return &KnowledgeGraph{Entities: []Entity{}, Relations: []Relation{}}, nil
+	if t.storeErr != nil {
+		return nil, t.storeErr
 	}
+	graph, err := t.store.Load(ctx)
 	if err != nil {
 		t.logger.Error("Failed to load graph", zap.Error(err))
 		return nil, err
 	}
-	return &graph, nil
+	return graph, nil
 }
 
 func (t *MemoryTool) saveGraph(ctx context.Context, graph *KnowledgeGraph) error {
-	_, err := t.collection.UpdateOne(
-		ctx,
-		bson.M{"_id": "graph"},
-		bson.M{"$set": graph},
-		options.Update().SetUpsert(true),
-	)
-	if err != nil {
+	if t.storeErr != nil {
+		return t.storeErr
+	}
+	if err := t.store.Save(ctx, graph); err != nil {
 		t.logger.Error("Failed to save graph", zap.Error(err))
 		return err
 	}
@@ -428,53 +480,147 @@ func (t *MemoryTool) deleteRelations(ctx context.Context, relations []Relation)
 	return "Relations deleted successfully", nil
 }
 
-func (t *MemoryTool) readGraph(ctx context.Context) (string, error) {
+func (t *MemoryTool) readGraph(ctx context.Context, entityType string, offset, limit int) (string, error) {
 	graph, err := t.loadGraph(ctx)
 	if err != nil {
 		return "", err
 	}
 
-	result, _ := json.MarshalIndent(graph, "", "  ")
-	t.logger.Info("Graph read successfully")
+	var matched []Entity
+	for _, entity := range graph.Entities {
+		if entityType == "" || strings.EqualFold(entity.EntityType, entityType) {
+			matched = append(matched, entity)
+		}
+	}
+
+	page := paginateGraph(matched, graph.Relations, offset, limit)
+	result, _ := json.MarshalIndent(page, "", "  ")
+	t.logger.Info("Graph read successfully", zap.Int("entities", len(page.Entities)), zap.Int("total_entities", page.TotalEntities))
 	return string(result), nil
 }
 
-func (t *MemoryTool) searchNodes(ctx context.Context, query string) (string, error) {
+func (t *MemoryTool) searchNodes(ctx context.Context, query, entityType string, offset, limit int) (string, error) {
 	graph, err := t.loadGraph(ctx)
 	if err != nil {
 		return "", err
 	}
 
-	query = strings.ToLower(query)
 	var filteredEntities []Entity
+	for _, entity := range t.rankEntities(ctx, graph, query) {
+		if entityType == "" || strings.EqualFold(entity.EntityType, entityType) {
+			filteredEntities = append(filteredEntities, entity)
+		}
+	}
+
+	page := paginateGraph(filteredEntities, graph.Relations, offset, limit)
+	result, _ := json.MarshalIndent(page, "", "  ")
+	t.logger.Info("Nodes searched", zap.String("query", query), zap.Int("total_entities", page.TotalEntities))
+	return string(result), nil
+}
+
+// rankEntities returns the graph's entities matching the words of the query,
+// most relevant first, using the store's index when it has one. A query
+// without words matches every entity. When no entity has words starting with
+// each word of the query, those whose name, type or observations contain the
+// query are returned in the graph's order, so a part of a word still finds
+// them.
+func (t *MemoryTool) rankEntities(ctx context.Context, graph *KnowledgeGraph, query string) []Entity {
+	if len(searchTerms(query)) == 0 {
+		return graph.Entities
+	}
+
+	var names []string
+	searcher, ok := t.store.(GraphSearcher)
+	if ok {
+		var err error
+		if names, err = searcher.Search(ctx, query); err != nil {
+			t.logger.Warn("Memory search index failed, searching the loaded graph", zap.Error(err))
+			ok = false
+		}
+	}
+	if !ok {
+		names = newGraphIndex(graph).search(query)
+	}
+
+	byName := make(map[string]Entity, len(graph.Entities))
+	for _, entity := range graph.Entities {
+		byName[entity.Name] = entity
+	}
+	ranked := make([]Entity, 0, len(names))
+	for _, name := range names {
+		// The index may lag behind a graph another process changed
+		if entity, ok := byName[name]; ok {
+			ranked = append(ranked, entity)
+		}
+	}
+	if len(ranked) == 0 {
+		return containingEntities(graph, query)
+	}
+	return ranked
+}
+
+// containingEntities returns the entities whose name, type or observations
+// contain the query, ignoring case.
+func containingEntities(graph *KnowledgeGraph, query string) []Entity {
+	query = strings.ToLower(strings.TrimSpace(query))
+	var matched []Entity
 	for _, entity := range graph.Entities {
 		if strings.Contains(strings.ToLower(entity.Name), query) ||
 			strings.Contains(strings.ToLower(entity.EntityType), query) ||
-			containsMatchingString(entity.Observations, query) {
-			filteredEntities = append(filteredEntities, entity)
+			slices.ContainsFunc(entity.Observations, func(o string) bool { return strings.Contains(strings.ToLower(o), query) }) {
+			matched = append(matched, entity)
 		}
 	}
+	return matched
+}
 
-	filteredEntityNames := make(map[string]bool)
-	for _, entity := range filteredEntities {
-		filteredEntityNames[entity.Name] = true
+// paginateGraph returns a page of entities, with the relations from them
+// to any of the matched entities. A limit of zero, or one above
+// maxMemoryEntities, returns up to maxMemoryEntities.
+func paginateGraph(matched []Entity, relations []Relation, offset, limit int) graphPage {
+	if limit <= 0 || limit > maxMemoryEntities {
+		limit = maxMemoryEntities
 	}
 
-	var filteredRelations []Relation
-	for _, relation := range graph.Relations {
-		if filteredEntityNames[relation.From] && filteredEntityNames[relation.To] {
-			filteredRelations = append(filteredRelations, relation)
-		}
+	matchedNames := make(map[string]bool, len(matched))
+	for _, entity := range matched {
+		matchedNames[entity.Name] = true
+	}
+	page := graphPage{
+		Entities:      []Entity{},
+		Relations:     []Relation{},
+		TotalEntities: len(matched),
+		Offset:        offset,
 	}
 
-	filteredGraph := KnowledgeGraph{
-		Entities:  filteredEntities,
-		Relations: filteredRelations,
+	if offset < len(matched) {
+		end := min(offset+limit, len(matched))
+		page.Entities = matched[offset:end]
+		if end < len(matched) {
+			page.Truncated = true
+			page.NextOffset = end
+		}
+	}
+	pageNames := make(map[string]bool, len(page.Entities))
+	for _, entity := range page.Entities {
+		pageNames[entity.Name] = true
 	}
 
-	result, _ := json.MarshalIndent(filteredGraph, "", "  ")
-	t.logger.Info("Nodes searched", zap.String("query", query))
-	return string(result), nil
+	for _, relation := range relations {
+		if !matchedNames[relation.From] || !matchedNames[relation.To] {
+			continue
+		}
+		page.TotalRelations++
+		if !pageNames[relation.From] {
+			continue
+		}
+		if len(page.Relations) == maxMemoryRelations {
+			page.RelationsTruncated = true
+			continue
+		}
+		page.Relations = append(page.Relations, relation)
+	}
+	return page
 }
 
 func (t *MemoryTool) openNodes(ctx context.Context, names []string) (string, error) {
@@ -540,16 +686,6 @@ func containsString(slice []string, value string) bool {
 	return false
 }
 
-func containsMatchingString(slice []string, query string) bool {
-	query = strings.ToLower(query)
-	for _, s := range slice {
-		if strings.Contains(strings.ToLower(s), query) {
-			return true
-		}
-	}
-	return false
-}
-
 func (t *MemoryTool) DisplayName(ui string, arguments string) (string, string) {
 	var args struct {
 		Action string `json:"action"`
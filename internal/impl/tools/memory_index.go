@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Weights of a word by the field it is in; a word of an entity's name says
+// more about it than one of its observations.
+const (
+	nameWeight        = 3
+	entityTypeWeight  = 2
+	observationWeight = 1
+)
+
+// graphIndex is an inverted index of the words of a graph's entities, so
+// search_nodes doesn't scan every observation.
+type graphIndex struct {
+	names    []string             // entity names in graph order
+	terms    []string             // sorted, for prefix lookups
+	postings map[string][]posting // entities with the term, in graph order
+}
+
+// posting is the weight a term has in an entity.
+type posting struct {
+	entity int
+	weight int
+}
+
+func newGraphIndex(graph *KnowledgeGraph) *graphIndex {
+	index := &graphIndex{postings: make(map[string][]posting)}
+	for i, entity := range graph.Entities {
+		index.names = append(index.names, entity.Name)
+		add := func(text string, weight int) {
+			for _, term := range searchTerms(text) {
+				postings := index.postings[term]
+				if n := len(postings); n > 0 && postings[n-1].entity == i {
+					postings[n-1].weight += weight
+					continue
+				}
+				index.postings[term] = append(postings, posting{entity: i, weight: weight})
+			}
+		}
+		add(entity.Name, nameWeight)
+		add(entity.EntityType, entityTypeWeight)
+		for _, observation := range entity.Observations {
+			add(observation, observationWeight)
+		}
+	}
+	for term := range index.postings {
+		index.terms = append(index.terms, term)
+	}
+	sort.Strings(index.terms)
+	return index
+}
+
+// search returns the names of the entities with a word starting with each
+// of the query's words, most relevant first. Words are compared without case
+// and without stemming. An entity scores the weight of the field of each word
+// that matched, doubled for whole words, and ties keep the graph's order.
+func (x *graphIndex) search(query string) []string {
+	var scores map[int]int
+	for _, queryTerm := range searchTerms(query) {
+		termScores := make(map[int]int)
+		for j := sort.SearchStrings(x.terms, queryTerm); j < len(x.terms) && strings.HasPrefix(x.terms[j], queryTerm); j++ {
+			exact := 1
+			if x.terms[j] == queryTerm {
+				exact = 2
+			}
+			for _, p := range x.postings[x.terms[j]] {
+				termScores[p.entity] += p.weight * exact
+			}
+		}
+		// Every word has to match
+		if scores != nil {
+			for entity := range termScores {
+				if previous, ok := scores[entity]; ok {
+					termScores[entity] += previous
+				} else {
+					delete(termScores, entity)
+				}
+			}
+		}
+		scores = termScores
+	}
+
+	matched := make([]int, 0, len(scores))
+	for entity := range scores {
+		matched = append(matched, entity)
+	}
+	slices.SortFunc(matched, func(a, b int) int {
+		if scores[a] != scores[b] {
+			return scores[b] - scores[a]
+		}
+		return a - b
+	})
+
+	names := make([]string, len(matched))
+	for i, entity := range matched {
+		names[i] = x.names[entity]
+	}
+	return names
+}
+
+// entityTerms returns the distinct words of an entity's name, type and
+// observations.
+func entityTerms(entity Entity) []string {
+	terms := append(searchTerms(entity.Name), searchTerms(entity.EntityType)...)
+	for _, observation := range entity.Observations {
+		terms = append(terms, searchTerms(observation)...)
+	}
+	slices.Sort(terms)
+	return slices.Compact(terms)
+}
+
+// searchTerms splits text into lowercase words.
+func searchTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
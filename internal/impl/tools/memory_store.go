@@ -0,0 +1,314 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GraphStore persists the knowledge graph of the Memory tool.
+type GraphStore interface {
+	// Load returns the stored graph, or an empty graph if none was saved yet.
+	Load(ctx context.Context) (*KnowledgeGraph, error)
+	Save(ctx context.Context, graph *KnowledgeGraph) error
+}
+
+// GraphSearcher is implemented by stores that index the graph for
+// search_nodes. Search returns the names of the entities matching the words
+// of the query, most relevant first, by the rules of graphIndex.search, so
+// a query finds the same entities whatever the storage.
+type GraphSearcher interface {
+	Search(ctx context.Context, query string) ([]string, error)
+}
+
+func emptyGraph() *KnowledgeGraph {
+	return &KnowledgeGraph{Entities: []Entity{}, Relations: []Relation{}}
+}
+
+// newGraphStore selects the store from the tool configuration. The storage
+// key is "file" or "mongo". Without it, a tool with a mongo_uri keeps its
+// graph in MongoDB, as it always has, and any other uses the storage the
+// application runs with, appStorage, and its appMongoURI.
+func newGraphStore(configuration map[string]string, appStorage, appMongoURI string) (GraphStore, error) {
+	storage := configuration["storage"]
+	if storage == "" {
+		storage = appStorage
+		if configuration["mongo_uri"] != "" {
+			storage = "mongo"
+		}
+	}
+	switch storage {
+	case "", "file":
+		return NewFileGraphStore(memoryFilePath(configuration)), nil
+	case "mongo":
+		mongoURI := configuration["mongo_uri"]
+		if mongoURI == "" {
+			mongoURI = appMongoURI
+		}
+		return newMongoGraphStore(configuration, mongoURI)
+	default:
+		return nil, fmt.Errorf("unknown memory storage: %s", configuration["storage"])
+	}
+}
+
+func memoryFilePath(configuration map[string]string) string {
+	workspace := configuration["workspace"]
+	if workspace == "" {
+		workspace, _ = os.Getwd()
+	}
+	return filepath.Join(workspace, ".aiagent", "memory.json")
+}
+
+// FileGraphStore keeps the graph in a JSON file. Its search index is built
+// once and again only when the file changes.
+type FileGraphStore struct {
+	path string
+	mu   sync.Mutex
+
+	index     *graphIndex
+	indexedAs os.FileInfo // the file as it was when indexed
+}
+
+func NewFileGraphStore(path string) *FileGraphStore {
+	return &FileGraphStore{path: path}
+}
+
+func (s *FileGraphStore) Load(ctx context.Context) (*KnowledgeGraph, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load()
+}
+
+// load reads the graph. The caller holds the lock.
+func (s *FileGraphStore) load() (*KnowledgeGraph, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return emptyGraph(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", s.path, err)
+	}
+
+	graph := emptyGraph()
+	if err := json.Unmarshal(data, graph); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", s.path, err)
+	}
+	return graph, nil
+}
+
+// Save writes to a temporary file first so that a crash never leaves a
+// truncated graph behind.
+func (s *FileGraphStore) Save(ctx context.Context, graph *KnowledgeGraph) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+	data, err := json.MarshalIndent(graph, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal graph: %v", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", tmp, err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to replace %s: %v", s.path, err)
+	}
+
+	s.index = nil
+	if info, err := os.Stat(s.path); err == nil {
+		s.index, s.indexedAs = newGraphIndex(graph), info
+	}
+	return nil
+}
+
+func (s *FileGraphStore) Search(ctx context.Context, query string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, err := os.Stat(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %v", s.path, err)
+	}
+	// Another process may have written the file since it was indexed
+	if s.index == nil || !info.ModTime().Equal(s.indexedAs.ModTime()) || info.Size() != s.indexedAs.Size() {
+		graph, err := s.load()
+		if err != nil {
+			return nil, err
+		}
+		s.index, s.indexedAs = newGraphIndex(graph), info
+	}
+	return s.index.search(query), nil
+}
+
+// mongoGraphStore keeps the graph in a single document of a collection.
+// Each entity is copied, with its words, to a document of a second
+// collection with an index on the words, which search_nodes queries.
+type mongoGraphStore struct {
+	collection *mongo.Collection
+	search     *mongo.Collection
+
+	mu      sync.Mutex
+	indexed bool
+}
+
+// searchDocument is an entity as indexed for search.
+type searchDocument struct {
+	Name         string   `bson:"_id"`
+	EntityType   string   `bson:"entityType"`
+	Observations []string `bson:"observations"`
+	// Terms are the searchTerms of the entity, for prefix lookups
+	Terms []string `bson:"terms"`
+	// Position keeps the graph's order, which breaks ties in the ranking
+	Position int `bson:"position"`
+}
+
+func newMongoGraphStore(configuration map[string]string, mongoURI string) (GraphStore, error) {
+	collectionName := configuration["mongo_collection"]
+	if collectionName == "" {
+		collectionName = "knowledge_graph"
+	}
+
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to MongoDB: %v", err)
+	}
+	database := client.Database("aiagent")
+	return &mongoGraphStore{
+		collection: database.Collection(collectionName),
+		search:     database.Collection(collectionName + "_search"),
+	}, nil
+}
+
+func (s *mongoGraphStore) Load(ctx context.Context) (*KnowledgeGraph, error) {
+	var graph KnowledgeGraph
+	err := s.collection.FindOne(ctx, bson.M{"_id": "graph"}).Decode(&graph)
+	if err == mongo.ErrNoDocuments {
+		return emptyGraph(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &graph, nil
+}
+
+func (s *mongoGraphStore) Save(ctx context.Context, graph *KnowledgeGraph) error {
+	_, err := s.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": "graph"},
+		bson.M{"$set": graph},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.indexEntities(ctx, graph)
+}
+
+func (s *mongoGraphStore) Search(ctx context.Context, query string) ([]string, error) {
+	if err := s.ensureIndexed(ctx); err != nil {
+		return nil, err
+	}
+
+	// Find the entities with a word starting with each word of the query,
+	// then rank them as the file store does
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	conditions := make(bson.A, len(terms))
+	for i, term := range terms {
+		conditions[i] = bson.M{"terms": bson.M{"$regex": "^" + regexp.QuoteMeta(term)}}
+	}
+	cursor, err := s.search.Find(ctx, bson.M{"$and": conditions}, options.Find().SetSort(bson.M{"position": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var documents []searchDocument
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+
+	matched := &KnowledgeGraph{Entities: make([]Entity, len(documents))}
+	for i, document := range documents {
+		matched.Entities[i] = Entity{Name: document.Name, EntityType: document.EntityType, Observations: document.Observations}
+	}
+	return newGraphIndex(matched).search(query), nil
+}
+
+// ensureIndexed creates the words index and indexes the graph saved before
+// this process started, or before the index existed, once.
+func (s *mongoGraphStore) ensureIndexed(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.indexed {
+		return nil
+	}
+
+	if err := s.createSearchIndex(ctx); err != nil {
+		return err
+	}
+	graph, err := s.Load(ctx)
+	if err != nil {
+		return err
+	}
+	if err := s.indexEntities(ctx, graph); err != nil {
+		return err
+	}
+	s.indexed = true
+	return nil
+}
+
+// createSearchIndex indexes the entities' words, which anchored regular
+// expressions look up by prefix.
+func (s *mongoGraphStore) createSearchIndex(ctx context.Context) error {
+	_, err := s.search.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "terms", Value: 1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create the memory search index: %v", err)
+	}
+	return nil
+}
+
+// indexEntities replaces the search documents with the graph's entities.
+func (s *mongoGraphStore) indexEntities(ctx context.Context, graph *KnowledgeGraph) error {
+	if _, err := s.search.DeleteMany(ctx, bson.M{}); err != nil {
+		return fmt.Errorf("failed to clear the memory search index: %v", err)
+	}
+	if len(graph.Entities) == 0 {
+		return nil
+	}
+	documents := make([]any, len(graph.Entities))
+	for i, entity := range graph.Entities {
+		documents[i] = searchDocument{
+			Name:         entity.Name,
+			EntityType:   entity.EntityType,
+			Observations: entity.Observations,
+			Terms:        entityTerms(entity),
+			Position:     i,
+		}
+	}
+	if _, err := s.search.InsertMany(ctx, documents); err != nil {
+		return fmt.Errorf("failed to index the memory: %v", err)
+	}
+	return nil
+}
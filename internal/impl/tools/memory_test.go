@@ -0,0 +1,216 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestMemoryTool_FileStorage(t *testing.T) {
+	workspace := t.TempDir()
+	config := map[string]string{"workspace": workspace}
+
+	tool := NewMemoryTool("Memory", "test", config, zap.NewNop())
+	_, err := tool.Execute(context.Background(), `{"operation": "create_entities", "entities": [{"name": "aiagent", "entityType": "project", "observations": ["written in Go"]}]}`)
+	if err != nil {
+		t.Fatalf("create_entities failed: %v", err)
+	}
+	_, err = tool.Execute(context.Background(), `{"operation": "create_relations", "relations": [{"from": "aiagent", "to": "aiagent", "relationType": "depends_on"}]}`)
+	if err != nil {
+		t.Fatalf("create_relations failed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(workspace, ".aiagent", "memory.json")); err != nil {
+		t.Fatalf("expected memory.json to be written: %v", err)
+	}
+
+	// A new tool instance must see the persisted graph
+	reloaded := NewMemoryTool("Memory", "test", config, zap.NewNop())
+	result, err := reloaded.Execute(context.Background(), `{"operation": "search_nodes", "query": "go"}`)
+	if err != nil {
+		t.Fatalf("search_nodes failed: %v", err)
+	}
+	if !strings.Contains(result, "aiagent") || !strings.Contains(result, "depends_on") {
+		t.Errorf("expected persisted entity and relation, got %s", result)
+	}
+}
+
+func TestMemoryTool_UnknownStorage(t *testing.T) {
+	tool := NewMemoryTool("Memory", "test", map[string]string{"storage": "redis"}, zap.NewNop())
+	if _, err := tool.Execute(context.Background(), `{"operation": "read_graph"}`); err == nil {
+		t.Error("expected an error for an unknown storage")
+	}
+}
+
+func TestNewGraphStore_Selection(t *testing.T) {
+	workspace := t.TempDir()
+	tests := []struct {
+		name          string
+		configuration map[string]string
+		appStorage    string
+		mongo         bool
+	}{
+		{"file app", map[string]string{"workspace": workspace}, "file", false},
+		{"mongo app", map[string]string{"workspace": workspace}, "mongo", true},
+		{"existing mongo tool", map[string]string{"workspace": workspace, "mongo_uri": "mongodb://localhost:1"}, "file", true},
+		{"file chosen", map[string]string{"workspace": workspace, "storage": "file"}, "mongo", false},
+		{"mongo chosen", map[string]string{"workspace": workspace, "storage": "mongo"}, "file", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := newGraphStore(tt.configuration, tt.appStorage, "mongodb://localhost:1")
+			if err != nil {
+				t.Fatalf("newGraphStore failed: %v", err)
+			}
+			if _, isMongo := store.(*mongoGraphStore); isMongo != tt.mongo {
+				t.Errorf("Expected mongo store %v, got %T", tt.mongo, store)
+			}
+		})
+	}
+}
+
+func TestMemoryTool_Pagination(t *testing.T) {
+	tool := NewMemoryTool("Memory", "test", map[string]string{"workspace": t.TempDir()}, zap.NewNop())
+	var entities []string
+	for i := 0; i < maxMemoryEntities+5; i++ {
+		entityType := "file"
+		if i%2 == 1 {
+			entityType = "person"
+		}
+		entities = append(entities, fmt.Sprintf(`{"name": "e%d", "entityType": %q, "observations": ["note"]}`, i, entityType))
+	}
+	if _, err := tool.Execute(context.Background(), `{"operation": "create_entities", "entities": [`+strings.Join(entities, ",")+`]}`); err != nil {
+		t.Fatalf("create_entities failed: %v", err)
+	}
+	if _, err := tool.Execute(context.Background(), `{"operation": "create_relations", "relations": [{"from": "e0", "to": "e2", "relationType": "links"}, {"from": "e2", "to": "e1", "relationType": "owned_by"}]}`); err != nil {
+		t.Fatalf("create_relations failed: %v", err)
+	}
+
+	read := func(arguments string) graphPage {
+		t.Helper()
+		result, err := tool.Execute(context.Background(), arguments)
+		if err != nil {
+			t.Fatalf("%s failed: %v", arguments, err)
+		}
+		var page graphPage
+		if err := json.Unmarshal([]byte(result), &page); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		return page
+	}
+
+	// Without a limit the hard cap applies
+	page := read(`{"operation": "read_graph"}`)
+	if len(page.Entities) != maxMemoryEntities || !page.Truncated || page.NextOffset != maxMemoryEntities || page.TotalEntities != maxMemoryEntities+5 {
+		t.Errorf("unexpected unbounded page: %d entities, truncated %v, next %d, total %d", len(page.Entities), page.Truncated, page.NextOffset, page.TotalEntities)
+	}
+
+	page = read(`{"operation": "read_graph", "limit": 2}`)
+	if len(page.Entities) != 2 || page.NextOffset != 2 || len(page.Relations) != 1 || page.TotalRelations != 2 {
+		t.Errorf("unexpected first page: %+v", page)
+	}
+	page = read(fmt.Sprintf(`{"operation": "read_graph", "limit": 10, "offset": %d}`, maxMemoryEntities))
+	if len(page.Entities) != 5 || page.Truncated {
+		t.Errorf("expected the last 5 entities, got %d, truncated %v", len(page.Entities), page.Truncated)
+	}
+
+	page = read(`{"operation": "read_graph", "entity_type": "file", "limit": 2}`)
+	if page.TotalEntities != (maxMemoryEntities+6)/2 || page.Entities[1].Name != "e2" || page.TotalRelations != 1 {
+		t.Errorf("unexpected filtered page: %+v", page)
+	}
+
+	page = read(`{"operation": "search_nodes", "query": "note", "limit": 3}`)
+	if len(page.Entities) != 3 || !page.Truncated || page.TotalEntities != maxMemoryEntities+5 {
+		t.Errorf("unexpected search page: %d entities, truncated %v, total %d", len(page.Entities), page.Truncated, page.TotalEntities)
+	}
+
+	if _, err := tool.Execute(context.Background(), `{"operation": "read_graph", "offset": -1}`); err == nil {
+		t.Error("expected a negative offset to be rejected")
+	}
+}
+
+func TestGraphIndex_Search(t *testing.T) {
+	graph := &KnowledgeGraph{Entities: []Entity{
+		{Name: "billing", EntityType: "service", Observations: []string{"Talks to the payments gateway"}},
+		{Name: "payments", EntityType: "service", Observations: []string{"Written in Go", "Owned by the payments team"}},
+		{Name: "alice", EntityType: "person", Observations: []string{"Maintains payments-gateway"}},
+	}}
+	index := newGraphIndex(graph)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"payments", []string{"payments", "billing", "alice"}},
+		{"PAY", []string{"payments", "billing", "alice"}},
+		{"payments gateway", []string{"billing", "alice"}},
+		{"service go", []string{"payments"}},
+		{"kafka", []string{}},
+	}
+	for _, tt := range tests {
+		if got := index.search(tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryTool_SearchRanking(t *testing.T) {
+	workspace := t.TempDir()
+	tool := NewMemoryTool("Memory", "test", map[string]string{"workspace": workspace}, zap.NewNop())
+	_, err := tool.Execute(context.Background(), `{"operation": "create_entities", "entities": [
+		{"name": "release notes", "entityType": "document", "observations": ["Mentions the deploy script"]},
+		{"name": "deploy", "entityType": "script", "observations": ["Runs the release"]}]}`)
+	if err != nil {
+		t.Fatalf("create_entities failed: %v", err)
+	}
+
+	search := func(query string) []string {
+		t.Helper()
+		result, err := tool.Execute(context.Background(), `{"operation": "search_nodes", "query": "`+query+`"}`)
+		if err != nil {
+			t.Fatalf("search_nodes failed: %v", err)
+		}
+		var page graphPage
+		if err := json.Unmarshal([]byte(result), &page); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		var names []string
+		for _, entity := range page.Entities {
+			names = append(names, entity.Name)
+		}
+		return names
+	}
+
+	if got := search("deploy"); !slices.Equal(got, []string{"deploy", "release notes"}) {
+		t.Errorf("expected the entity named deploy first, got %v", got)
+	}
+
+	// A change written by another process is picked up
+	other := NewMemoryTool("Memory", "test", map[string]string{"workspace": workspace}, zap.NewNop())
+	if _, err := other.Execute(context.Background(), `{"operation": "add_observations", "observations": [{"entityName": "release notes", "contents": ["Deploy deploy deploy"]}]}`); err != nil {
+		t.Fatalf("add_observations failed: %v", err)
+	}
+	if got := search("deploy"); len(got) != 2 || got[0] != "release notes" {
+		t.Errorf("expected the changed graph to be searched, got %v", got)
+	}
+
+	// A part of a word falls back to substring matching
+	if got := search("lease"); !slices.Equal(got, []string{"release notes", "deploy"}) {
+		t.Errorf("expected the entities containing the query, got %v", got)
+	}
+}
+
+func TestEntityTerms(t *testing.T) {
+	entity := Entity{Name: "Payments API", EntityType: "service", Observations: []string{"Calls the payments gateway", "Owned by team-a"}}
+	want := []string{"a", "api", "by", "calls", "gateway", "owned", "payments", "service", "team", "the"}
+	if got := entityTerms(entity); !slices.Equal(got, want) {
+		t.Errorf("entityTerms() = %v, want %v", got, want)
+	}
+}
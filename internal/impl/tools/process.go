@@ -11,6 +11,7 @@ import (
 	"os/exec"
 	"runtime"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -26,6 +27,13 @@ type ProcessInfo struct {
 	Stderr       io.ReadCloser
 	StdoutBuffer *bytes.Buffer
 	StderrBuffer *bytes.Buffer
+	ChatID       string
+	exited       bool
+	// done is closed once the process has exited
+	done chan struct{}
+	// shutdownGrace is how long the process gets to exit after SIGTERM
+	// when aiagent shuts down
+	shutdownGrace time.Duration
 }
 
 type ProcessTool struct {
@@ -33,7 +41,6 @@ type ProcessTool struct {
 	description   string
 	configuration map[string]string // Includes "workspace"
 	logger        *zap.Logger
-	processes     map[int]*ProcessInfo // Track background processes by PID
 }
 
 func NewProcessTool(name, description string, configuration map[string]string, logger *zap.Logger) *ProcessTool {
@@ -42,7 +49,6 @@ func NewProcessTool(name, description string, configuration map[string]string, l
 		description:   description,
 		configuration: configuration,
 		logger:        logger,
-		processes:     make(map[int]*ProcessInfo),
 	}
 }
 
@@ -62,8 +68,14 @@ func (t *ProcessTool) UpdateConfiguration(config map[string]string) {
 	t.configuration = config
 }
 
+func (t *ProcessTool) WithConfiguration(config map[string]string) entities.Tool {
+	c := *t
+	c.configuration = config
+	return &c
+}
+
 func (t *ProcessTool) FullDescription() string {
-	return fmt.Sprintf("%s\n\nParameters:\n- command: The command to execute.\n- timeout: Optional timeout in milliseconds.\n- workdir: The working directory to run the command in. Defaults to /Users/drujensen/workspace/go/ai/aiagent.\n- description: Clear, concise description of what this command does in 5-10 words.", t.Description())
+	return fmt.Sprintf("%s\n\nParameters:\n- command: The command to execute.\n- timeout: Optional timeout in milliseconds.\n- workdir: The working directory to run the command in. Defaults to /Users/drujensen/workspace/go/ai/aiagent.\n- description: Clear, concise description of what this command does in 5-10 words.\n- background: Run the command in the background and return its pid.\n- action: status, kill, read or write a background process by pid.\n- pid: The pid of the background process for an action.\n- input: Input to send to the process on stdin.", t.Description())
 }
 
 func (t *ProcessTool) Schema() map[string]any {
@@ -86,6 +98,23 @@ func (t *ProcessTool) Schema() map[string]any {
 				"type":        "string",
 				"description": "Clear, concise description of what this command does in 5-10 words.",
 			},
+			"background": map[string]any{
+				"type":        "boolean",
+				"description": "Run the command in the background and return its pid. The number of concurrent background processes is limited.",
+			},
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"status", "kill", "read", "write"},
+				"description": "Manage a background process by pid instead of running a new command.",
+			},
+			"pid": map[string]any{
+				"type":        "integer",
+				"description": "The pid of the background process for an action.",
+			},
+			"input": map[string]any{
+				"type":        "string",
+				"description": "Input to send to the process on stdin.",
+			},
 		},
 		"required":             []string{"command", "description"},
 		"additionalProperties": false,
@@ -109,6 +138,20 @@ type ProcessArgs struct {
 	Env        []string `json:"env"`
 	PID        int      `json:"pid"`
 	Action     string   `json:"action"`
+	ChatID     string   `json:"parent_chat_id"` // injected by the framework via injectToolArgs
+}
+
+// Mutates reports whether the call can have side effects. Only checking on or
+// reading from a background process is treated as read-only; any command may
+// change the workspace.
+func (t *ProcessTool) Mutates(arguments string) bool {
+	var args struct {
+		Action string `json:"action"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return true
+	}
+	return args.Action != "status" && args.Action != "read"
 }
 
 func (t *ProcessTool) Execute(ctx context.Context, arguments string) (string, error) {
@@ -128,10 +171,26 @@ func (t *ProcessTool) Execute(ctx context.Context, arguments string) (string, er
 		args.Shell = true // Default to shell mode
 	}
 
+	switch args.Action {
+	case "status":
+		return t.checkStatus(args.PID)
+	case "kill":
+		return t.killProcess(args.PID)
+	case "write":
+		return t.writeToProcess(args)
+	case "read":
+		return t.readFromProcess(args)
+	}
+
 	if args.Command == "" {
 		return `{"output": "", "exit_code": 1, "error": "command is required"}`, nil
 	}
 
+	if name, reason := loadCommandPolicy(t.configuration).check(args.Command, args.Shell); reason != "" {
+		t.logger.Warn("Command refused by policy", zap.String("command", args.Command), zap.String("name", name))
+		return t.toJSON(ProcessResponse{Command: args.Command, Stderr: reason, Status: "denied"})
+	}
+
 	workspace := t.configuration["workspace"]
 	if workspace == "" {
 		var err error
@@ -141,7 +200,7 @@ func (t *ProcessTool) Execute(ctx context.Context, arguments string) (string, er
 		}
 	}
 
-	return t.runCommand(args, workspace)
+	return t.runCommand(ctx, args, workspace)
 }
 
 // splitShellArgs splits a command string into arguments, respecting quoted strings and escapes
@@ -203,9 +262,9 @@ func splitShellArgs(input string) []string {
 	return args
 }
 
-func (t *ProcessTool) runCommand(args ProcessArgs, workspace string) (string, error) {
+func (t *ProcessTool) runCommand(ctx context.Context, args ProcessArgs, workspace string) (string, error) {
 	// Parse full command if not shell mode
-	var cmd *exec.Cmd
+	var argv []string
 	cmdArgs := splitShellArgs(args.Command)
 	if args.Shell {
 		var shell, flag string
@@ -216,17 +275,45 @@ func (t *ProcessTool) runCommand(args ProcessArgs, workspace string) (string, er
 			shell = "bash"
 			flag = "-c"
 		}
-		cmd = exec.Command(shell, flag, args.Command)
+		argv = []string{shell, flag, args.Command}
 	} else {
 		if len(cmdArgs) == 0 {
 			return "", fmt.Errorf("no command specified")
 		}
-		cmd = exec.Command(cmdArgs[0], cmdArgs[1:]...)
+		argv = cmdArgs
+	}
+
+	// Run inside the configured sandbox, if any
+	if sandbox := t.configuration["sandbox"]; sandbox != "" {
+		wrapped, err := wrapCommand(sandbox, argv, workspace)
+		if err != nil {
+			return "", err
+		}
+		t.logger.Debug("Running command in sandbox", zap.String("sandbox", sandbox), zap.Strings("argv", wrapped))
+		argv = wrapped
 	}
+	cmd := exec.Command(argv[0], argv[1:]...)
 	cmd.Dir = workspace
-	cmd.Env = append(os.Environ(), args.Env...)
+	setProcessGroup(cmd)
+	// The agent's env overrides the process's, and the call's overrides both
+	cmd.Env = os.Environ()
+	for name, value := range entities.ToolEnv(ctx) {
+		cmd.Env = append(cmd.Env, name+"="+value)
+	}
+	cmd.Env = append(cmd.Env, args.Env...)
 
 	if args.Background {
+		maxPerChat := processLimit(t.configuration, "max_background_per_chat", defaultMaxBackgroundProcessesPerChat)
+		maxGlobal := processLimit(t.configuration, "max_background_processes", defaultMaxBackgroundProcesses)
+		backgroundProcesses.startMu.Lock()
+		defer backgroundProcesses.startMu.Unlock()
+		if err := backgroundProcesses.reserve(args.ChatID, maxPerChat, maxGlobal); err != nil {
+			t.logger.Warn("Background process limit reached",
+				zap.String("command", args.Command),
+				zap.String("chat_id", args.ChatID),
+				zap.Error(err))
+			return "", err
+		}
 		cmd.Stdout = nil
 		cmd.Stderr = nil
 		stdin, err := cmd.StdinPipe()
@@ -251,16 +338,27 @@ func (t *ProcessTool) runCommand(args ProcessArgs, workspace string) (string, er
 		}
 		pid := cmd.Process.Pid
 		pi := &ProcessInfo{
-			Cmd:          cmd,
-			Stdin:        stdin,
-			Stdout:       stdout,
-			Stderr:       stderr,
-			StdoutBuffer: &bytes.Buffer{},
-			StderrBuffer: &bytes.Buffer{},
-		}
-		go io.Copy(pi.StdoutBuffer, stdout)
-		go io.Copy(pi.StderrBuffer, stderr)
-		t.processes[pid] = pi
+			Cmd:           cmd,
+			Stdin:         stdin,
+			Stdout:        stdout,
+			Stderr:        stderr,
+			StdoutBuffer:  &bytes.Buffer{},
+			StderrBuffer:  &bytes.Buffer{},
+			ChatID:        args.ChatID,
+			done:          make(chan struct{}),
+			shutdownGrace: processDuration(t.configuration, "shutdown_grace", defaultShutdownGrace),
+		}
+		backgroundProcesses.add(pid, pi)
+		go func() {
+			// Wait closes the pipes, so the output has to be drained first
+			var copies sync.WaitGroup
+			copies.Add(2)
+			go func() { defer copies.Done(); io.Copy(pi.StdoutBuffer, stdout) }()
+			go func() { defer copies.Done(); io.Copy(pi.StderrBuffer, stderr) }()
+			copies.Wait()
+			cmd.Wait()
+			backgroundProcesses.markExited(pid)
+		}()
 		t.logger.Info("Background command started",
 			zap.String("command", args.Command),
 			zap.Strings("arguments", cmdArgs),
@@ -298,79 +396,61 @@ func (t *ProcessTool) runCommand(args ProcessArgs, workspace string) (string, er
 			args.Timeout = 30 // Default timeout of 30 seconds
 		}
 
+		// A negative timeout waits for the command however long it takes
+		var timeout <-chan time.Time
 		if args.Timeout > 0 {
 			timer := time.NewTimer(time.Duration(args.Timeout) * time.Second)
 			defer timer.Stop()
+			timeout = timer.C
+		}
 
-			errChan := make(chan error, 1)
-			go func() {
-				errChan <- cmd.Run()
-			}()
+		// Start before waiting so a kill always has a process to stop, and
+		// don't let a child that keeps the output open hold up the result
+		cmd.WaitDelay = time.Second
+		if err := cmd.Start(); err != nil {
+			t.logger.Error("Failed to start command", zap.String("command", args.Command), zap.Error(err))
+			return t.toJSON(ProcessResponse{Command: args.Command, Stderr: err.Error(), Status: "failed"})
+		}
+		errChan := make(chan error, 1)
+		go func() {
+			errChan <- cmd.Wait()
+		}()
 
-			select {
-			case err := <-errChan:
-				if err != nil {
-					t.logger.Error("Command execution failed",
-						zap.String("command", args.Command),
-						zap.Strings("arguments", cmdArgs),
-						zap.Error(err),
-						zap.String("stdout", out.String()),
-						zap.String("stderr", stderr.String()))
-					resp := ProcessResponse{
-						Command: args.Command,
-						Stdout:  out.String(),
-						Stderr:  stderr.String(),
-						Status:  "failed",
-					}
-					return t.toJSON(resp)
-				}
-				resp := ProcessResponse{
-					Command: args.Command,
-					Stdout:  out.String(),
-					Stderr:  stderr.String(),
-					Status:  "completed",
-				}
+		resp := ProcessResponse{Command: args.Command}
+		select {
+		case err := <-errChan:
+			if err != nil {
+				resp.Status = "failed"
+				t.logger.Error("Command execution failed",
+					zap.String("command", args.Command),
+					zap.Strings("arguments", cmdArgs),
+					zap.Error(err),
+					zap.String("stdout", out.String()),
+					zap.String("stderr", stderr.String()))
+			} else {
+				resp.Status = "completed"
 				t.logger.Info("Command executed successfully",
 					zap.String("command", args.Command),
 					zap.Strings("arguments", cmdArgs))
-				return t.toJSON(resp)
-			case <-timer.C:
-				if cmd.Process != nil {
-					cmd.Process.Kill()
-				}
-				t.logger.Warn("Command timed out",
-					zap.String("command", args.Command),
-					zap.Strings("arguments", cmdArgs),
-					zap.Int("timeout", args.Timeout))
-				resp := ProcessResponse{
-					Command: args.Command,
-					Stdout:  out.String(),
-					Stderr:  stderr.String(),
-					Status:  "timeout",
-				}
-				return t.toJSON(resp)
 			}
-		}
-
-		err := cmd.Run()
-		resp := ProcessResponse{
-			Command: args.Command,
-			Stdout:  out.String(),
-			Stderr:  stderr.String(),
-		}
-		if err != nil {
-			resp.Status = "failed"
-			t.logger.Error("Command execution failed",
+		case <-timeout:
+			resp.Status = "timeout"
+			t.logger.Warn("Command timed out",
 				zap.String("command", args.Command),
 				zap.Strings("arguments", cmdArgs),
-				zap.Error(err),
-				zap.String("stderr", stderr.String()))
-		} else {
-			resp.Status = "completed"
-			t.logger.Info("Command executed successfully",
+				zap.Int("timeout", args.Timeout))
+			signalProcessGroup(cmd.Process, syscall.SIGKILL)
+			<-errChan
+		case <-ctx.Done():
+			resp.Status = "canceled"
+			t.logger.Info("Command canceled",
 				zap.String("command", args.Command),
 				zap.Strings("arguments", cmdArgs))
+			signalProcessGroup(cmd.Process, syscall.SIGKILL)
+			<-errChan
 		}
+		resp.Stdout = out.String()
+		resp.Stderr = stderr.String()
 		return t.toJSON(resp)
 	}
 }
@@ -380,7 +460,7 @@ func (t *ProcessTool) checkStatus(pid int) (string, error) {
 		t.logger.Error("PID is required for status check")
 		return "", fmt.Errorf("PID is required for status check")
 	}
-	pi, exists := t.processes[pid]
+	pi, exists := backgroundProcesses.get(pid)
 	if !exists {
 		resp := ProcessResponse{
 			Command: "status",
@@ -388,11 +468,9 @@ func (t *ProcessTool) checkStatus(pid int) (string, error) {
 		}
 		return t.toJSON(resp)
 	}
-	if pi.Cmd.ProcessState != nil && pi.Cmd.ProcessState.Exited() {
+	if backgroundProcesses.hasExited(pid) {
 		pi.Stdin.Close()
-		pi.Stdout.Close()
-		pi.Stderr.Close()
-		delete(t.processes, pid)
+		backgroundProcesses.remove(pid)
 		resp := ProcessResponse{
 			Command: "status",
 			PID:     pid,
@@ -415,7 +493,7 @@ func (t *ProcessTool) killProcess(pid int) (string, error) {
 		t.logger.Error("PID is required for kill")
 		return "", fmt.Errorf("PID is required for kill")
 	}
-	pi, exists := t.processes[pid]
+	pi, exists := backgroundProcesses.get(pid)
 	if !exists {
 		resp := ProcessResponse{
 			Command: "kill",
@@ -423,7 +501,7 @@ func (t *ProcessTool) killProcess(pid int) (string, error) {
 		}
 		return t.toJSON(resp)
 	}
-	err := pi.Cmd.Process.Signal(syscall.SIGTERM)
+	err := signalProcessGroup(pi.Cmd.Process, syscall.SIGTERM)
 	if err != nil {
 		t.logger.Error("Failed to terminate process",
 			zap.Int("pid", pid),
@@ -431,7 +509,7 @@ func (t *ProcessTool) killProcess(pid int) (string, error) {
 		return "", err
 	}
 	pi.Stdin.Close()
-	delete(t.processes, pid)
+	backgroundProcesses.remove(pid)
 	resp := ProcessResponse{
 		Command: "kill",
 		PID:     pid,
@@ -648,7 +726,7 @@ func (t *ProcessTool) writeToProcess(args ProcessArgs) (string, error) {
 	if args.PID == 0 {
 		return "", fmt.Errorf("PID required for write")
 	}
-	pi, exists := t.processes[args.PID]
+	pi, exists := backgroundProcesses.get(args.PID)
 	if !exists {
 		return "", fmt.Errorf("process not found")
 	}
@@ -670,7 +748,7 @@ func (t *ProcessTool) readFromProcess(args ProcessArgs) (string, error) {
 	if args.PID == 0 {
 		return "", fmt.Errorf("PID required for read")
 	}
-	pi, exists := t.processes[args.PID]
+	pi, exists := backgroundProcesses.get(args.PID)
 	if !exists {
 		return "", fmt.Errorf("process not found")
 	}
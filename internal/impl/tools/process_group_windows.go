@@ -0,0 +1,21 @@
+//go:build windows
+
+package tools
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup leaves cmd as is; Windows has no process groups to signal.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// signalProcessGroup kills the process for SIGKILL. Other signals can't be
+// delivered on Windows and return an error.
+func signalProcessGroup(process *os.Process, sig syscall.Signal) error {
+	if sig == syscall.SIGKILL {
+		return process.Kill()
+	}
+	return process.Signal(sig)
+}
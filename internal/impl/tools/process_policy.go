@@ -0,0 +1,356 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// commandPolicy restricts which commands the Bash tool runs. The
+// allowed_commands and denied_commands config keys list command names,
+// separated by commas, that may be globs such as "git*". Denied commands
+// never run; when commands are allowed, nothing else runs.
+type commandPolicy struct {
+	allowed []string
+	denied  []string
+}
+
+func loadCommandPolicy(configuration map[string]string) commandPolicy {
+	return commandPolicy{
+		allowed: splitCommandList(configuration["allowed_commands"]),
+		denied:  splitCommandList(configuration["denied_commands"]),
+	}
+}
+
+func splitCommandList(value string) []string {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// check returns the first command in the command line the policy refuses and
+// why, or empty strings when all of them may run. In shell mode every
+// command of a pipeline, list or substitution is checked, not only the
+// first, and wrappers such as env, sudo, xargs and bash -c are unwrapped to
+// the commands they run. A command whose name isn't known until it runs,
+// like $CMD or a script piped to bash, is refused.
+func (p commandPolicy) check(command string, shell bool) (name, reason string) {
+	if len(p.allowed) == 0 && len(p.denied) == 0 {
+		return "", ""
+	}
+	for _, ref := range commandNames(command, shell) {
+		if !ref.resolved {
+			return ref.name, fmt.Sprintf("command %q can't be checked against the allowed_commands and denied_commands settings, as what it runs isn't known until it runs", ref.name)
+		}
+		if matchesCommand(p.denied, ref.name) {
+			return ref.name, fmt.Sprintf("command %q is denied by the denied_commands setting", ref.name)
+		}
+		if len(p.allowed) > 0 && !matchesCommand(p.allowed, ref.name) {
+			return ref.name, fmt.Sprintf("command %q is not in the allowed_commands setting (%s)", ref.name, strings.Join(p.allowed, ", "))
+		}
+	}
+	return "", ""
+}
+
+func matchesCommand(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, err := filepath.Match(pattern, name); (err == nil && matched) || pattern == name {
+			return true
+		}
+	}
+	return false
+}
+
+// maxCommandDepth bounds how deeply nested shells, wrappers and
+// substitutions are followed. Anything deeper can't be resolved.
+const maxCommandDepth = 8
+
+// commandRef is a command the command line runs, without its directory. It
+// isn't resolved when its name comes from an expansion or from a script
+// that isn't part of the command line.
+type commandRef struct {
+	name     string
+	resolved bool
+}
+
+// commandNames returns the commands the command line runs.
+func commandNames(command string, shell bool) []commandRef {
+	return resolveCommands(command, shell, 0)
+}
+
+func resolveCommands(command string, shell bool, depth int) []commandRef {
+	if depth > maxCommandDepth {
+		return []commandRef{{name: strings.TrimSpace(command)}}
+	}
+	if !shell {
+		return resolveArgs(splitShellArgs(command), depth)
+	}
+
+	var refs []commandRef
+	segments, substitutions := splitShellCommands(command)
+	for _, segment := range segments {
+		refs = append(refs, resolveArgs(splitShellArgs(segment), depth)...)
+	}
+	for _, substitution := range substitutions {
+		refs = append(refs, resolveCommands(substitution, true, depth+1)...)
+	}
+	return refs
+}
+
+// commandShells run the script given with -c.
+var commandShells = map[string]bool{
+	"sh": true, "bash": true, "dash": true, "zsh": true, "ksh": true, "ash": true,
+}
+
+// commandWrappers run the command that follows their options, with the
+// short options of each that take a value and how many arguments come
+// between the options and the command.
+var commandWrappers = map[string]struct {
+	valueOptions string
+	positional   int
+}{
+	"env":      {valueOptions: "uCS"},
+	"sudo":     {valueOptions: "ughpCDrtTUR"},
+	"doas":     {valueOptions: "uC"},
+	"nice":     {valueOptions: "n"},
+	"ionice":   {valueOptions: "cnp"},
+	"nohup":    {},
+	"setsid":   {},
+	"time":     {valueOptions: "fo"},
+	"timeout":  {valueOptions: "sk", positional: 1},
+	"chroot":   {positional: 1},
+	"stdbuf":   {valueOptions: "ioe"},
+	"xargs":    {valueOptions: "IaLnPsdE"},
+	"exec":     {valueOptions: "a"},
+	"command":  {},
+	"builtin":  {},
+	"unbuffer": {},
+}
+
+// resolveArgs returns the commands run by the arguments of one simple
+// command: the command itself and whatever it runs in turn.
+func resolveArgs(args []string, depth int) []commandRef {
+	for len(args) > 0 && isAssignment(args[0]) {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	if depth > maxCommandDepth || strings.ContainsAny(args[0], "$`*?") {
+		return []commandRef{{name: args[0]}}
+	}
+
+	name := filepath.Base(args[0])
+	refs := []commandRef{{name: name, resolved: true}}
+	rest := args[1:]
+	switch {
+	case commandShells[name]:
+		script, ok := shellScript(rest)
+		if !ok {
+			return append(refs, commandRef{name: name})
+		}
+		return append(refs, resolveCommands(script, true, depth+1)...)
+	case name == "eval":
+		return append(refs, resolveCommands(strings.Join(rest, " "), true, depth+1)...)
+	case name == "find":
+		for i, arg := range rest {
+			if arg == "-exec" || arg == "-execdir" || arg == "-ok" || arg == "-okdir" {
+				refs = append(refs, resolveArgs(rest[i+1:], depth+1)...)
+			}
+		}
+		return refs
+	}
+
+	wrapper, ok := commandWrappers[name]
+	if !ok {
+		return refs
+	}
+	wrapped, ok := wrappedCommand(name, rest, wrapper.valueOptions, wrapper.positional)
+	if !ok {
+		return append(refs, commandRef{name: name})
+	}
+	return append(refs, resolveArgs(wrapped, depth+1)...)
+}
+
+// isAssignment reports whether the argument is a VAR=value assignment.
+func isAssignment(arg string) bool {
+	name, _, found := strings.Cut(arg, "=")
+	return found && name != "" && !strings.ContainsAny(name, "/.$`")
+}
+
+// wrappedCommand returns the command a wrapper runs, skipping its options
+// and positional arguments. It isn't known when a long option might take
+// the next argument as its value.
+func wrappedCommand(name string, args []string, valueOptions string, positional int) ([]string, bool) {
+	for len(args) > 0 {
+		arg := args[0]
+		if arg == "--" {
+			args = args[1:]
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			break
+		}
+		if strings.HasPrefix(arg, "--") {
+			if !strings.Contains(arg, "=") {
+				return nil, false
+			}
+			if name == "env" && strings.HasPrefix(arg, "--split-string=") {
+				return append(splitShellArgs(strings.TrimPrefix(arg, "--split-string=")), args[1:]...), true
+			}
+			args = args[1:]
+			continue
+		}
+
+		// A short option taking a value has it attached or as the next argument
+		args = args[1:]
+		for i, option := range arg[1:] {
+			if !strings.ContainsRune(valueOptions, option) {
+				continue
+			}
+			value := arg[2+i:]
+			if value == "" {
+				if len(args) == 0 {
+					return nil, true
+				}
+				value, args = args[0], args[1:]
+			}
+			if name == "env" && option == 'S' {
+				return append(splitShellArgs(value), args...), true
+			}
+			break
+		}
+	}
+
+	if len(args) < positional {
+		return nil, true
+	}
+	return args[positional:], true
+}
+
+// shellScript returns the script a shell runs with -c. A shell without one
+// runs a script file or reads its commands from its input, neither of which
+// is in the command line.
+func shellScript(args []string) (string, bool) {
+	command := false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-o" || arg == "+o" || arg == "-O" || arg == "+O":
+			i++
+		case arg == "--" || strings.HasPrefix(arg, "--"):
+		case len(arg) > 1 && (arg[0] == '-' || arg[0] == '+'):
+			if strings.ContainsRune(arg[1:], 'c') {
+				command = true
+			}
+		default:
+			return arg, command
+		}
+	}
+	return "", false
+}
+
+// splitShellCommands splits a shell command line into its commands at the
+// operators and grouping characters outside quotes: ; & | newlines,
+// parentheses and braces. The commands of $( ) and backtick substitutions,
+// including those within double quotes, are returned separately, and the
+// substitutions are left in the commands as $ so an expanded command name
+// stays unresolved.
+func splitShellCommands(command string) (segments, substitutions []string) {
+	var current strings.Builder
+	var quote rune
+	escaped := false
+
+	flush := func() {
+		if segment := strings.TrimSpace(current.String()); segment != "" {
+			segments = append(segments, segment)
+		}
+		current.Reset()
+	}
+
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		ch := runes[i]
+		switch {
+		case escaped:
+			escaped = false
+		case ch == '\\' && quote != '\'':
+			escaped = true
+		case quote == '\'':
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '$' && i+1 < len(runes) && runes[i+1] == '(':
+			end := closingParen(runes, i+1)
+			inner := string(runes[i+2 : end])
+			if strings.HasPrefix(inner, "(") {
+				// $(( )) is arithmetic; only substitutions within it run
+				_, nested := splitShellCommands(inner)
+				substitutions = append(substitutions, nested...)
+			} else {
+				substitutions = append(substitutions, inner)
+			}
+			current.WriteString("$")
+			i = end
+			continue
+		case ch == '`':
+			end := i + 1
+			for end < len(runes) && runes[end] != '`' {
+				if runes[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			substitutions = append(substitutions, string(runes[i+1:min(end, len(runes))]))
+			current.WriteString("$")
+			i = end
+			continue
+		case quote == '"':
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case strings.ContainsRune(";&|\n()", ch),
+			(ch == '{' || ch == '}') && strings.TrimSpace(current.String()) == "":
+			flush()
+			continue
+		}
+		current.WriteRune(ch)
+	}
+	flush()
+	return segments, substitutions
+}
+
+// closingParen returns the index of the parenthesis closing the one at
+// open, skipping quoted text, or the end of the command line when it isn't
+// closed.
+func closingParen(runes []rune, open int) int {
+	depth := 0
+	var quote rune
+	for i := open; i < len(runes); i++ {
+		ch := runes[i]
+		switch {
+		case ch == '\\' && quote != '\'':
+			i++
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(runes)
+}
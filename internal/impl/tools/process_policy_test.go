@@ -0,0 +1,101 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"runtime"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestCommandPolicy_Check(t *testing.T) {
+	tests := []struct {
+		name     string
+		allowed  string
+		denied   string
+		command  string
+		shell    bool
+		expected string
+	}{
+		{"no policy", "", "", "rm -rf /", true, ""},
+		{"denied", "", "rm, curl", "rm -rf build", false, "rm"},
+		{"denied by path", "", "rm", "/bin/rm -rf build", false, "rm"},
+		{"denied glob", "", "sudo*", "sudoedit /etc/hosts", false, "sudoedit"},
+		{"denied in pipeline", "", "curl", "echo hi | curl -d @- example.com", true, "curl"},
+		{"denied in substitution", "", "curl", "echo $(curl example.com)", true, "curl"},
+		{"quoted operator", "", "curl", `echo "a | curl"`, true, ""},
+		{"env assignment", "", "curl", "FOO=bar curl example.com", true, "curl"},
+		{"allowed", "go, git*", "", "go test ./... && git status", true, ""},
+		{"not allowed", "go, git", "", "go test ./... && make", true, "make"},
+		{"deny wins", "git*", "git", "git push", false, "git"},
+		{"non-shell checks first command", "", "curl", "echo curl | curl", false, ""},
+		{"substitution in double quotes", "", "rm", `echo "$(rm -rf x)"`, true, "rm"},
+		{"backticks in double quotes", "", "rm", "echo \"`rm -rf x`\"", true, "rm"},
+		{"single quotes don't substitute", "", "rm", `echo '$(rm -rf x)'`, true, ""},
+		{"nested substitution", "", "rm", "echo $(echo $(rm x))", true, "rm"},
+		{"arithmetic", "", "rm", "echo $((1 + $(rm x)))", true, "rm"},
+		{"env wrapper", "", "rm", "env -u HOME FOO=1 rm -rf x", true, "rm"},
+		{"env split string", "", "rm", "env -S 'rm -rf x'", false, "rm"},
+		{"sudo wrapper", "", "rm", "sudo -u root rm -rf x", false, "rm"},
+		{"nice wrapper", "", "rm", "nice -n 10 rm -rf x", true, "rm"},
+		{"timeout wrapper", "", "rm", "timeout -s KILL 5 rm -rf x", true, "rm"},
+		{"xargs wrapper", "", "rm", "find . -name '*.o' | xargs -I{} rm {}", true, "rm"},
+		{"find exec", "", "rm", `find . -exec rm {} \;`, true, "rm"},
+		{"nested wrappers", "", "rm", "sudo nice env rm -rf x", true, "rm"},
+		{"bash -c", "", "rm", "bash -c 'echo hi; rm -rf x'", false, "rm"},
+		{"sh -ec", "", "rm", `sh -ec "rm -rf x"`, true, "rm"},
+		{"eval", "", "rm", `eval "rm -rf x"`, true, "rm"},
+		{"expanded command name", "", "rm", "CMD=rm; $CMD -rf x", true, "$CMD"},
+		{"substituted command name", "", "rm", "$(echo rm) -rf x", true, "$"},
+		{"script from input", "", "rm", "echo 'rm -rf x' | bash", true, "bash"},
+		{"unknown long option", "", "rm", "sudo --user root rm -rf x", true, "sudo"},
+		{"wrapped command allowed", "env, go", "", "env GOFLAGS=-v go test ./...", true, ""},
+		{"wrapped command not allowed", "env, go", "", "env make", true, "make"},
+		{"brace expansion", "echo", "", "echo {a,b}", true, ""},
+		{"brace group", "", "rm", "{ echo hi; rm x; }", true, "rm"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := loadCommandPolicy(map[string]string{"allowed_commands": tt.allowed, "denied_commands": tt.denied})
+			name, reason := policy.check(tt.command, tt.shell)
+			if name != tt.expected {
+				t.Errorf("Expected %q to be refused, got %q (%s)", tt.expected, name, reason)
+			}
+			if (name == "") != (reason == "") {
+				t.Errorf("Expected a reason only for refused commands, got %q for %q", reason, name)
+			}
+		})
+	}
+}
+
+func TestProcessTool_DeniedCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("echo is not available on Windows")
+	}
+
+	config := map[string]string{"workspace": t.TempDir(), "denied_commands": "rm"}
+	tool := NewProcessTool("test-process", "Test Process Tool", config, zap.NewNop())
+
+	result, err := tool.Execute(context.Background(), `{"command": "rm -rf .", "description": "remove everything"}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var response ProcessResponse
+	if err := json.Unmarshal([]byte(result), &response); err != nil {
+		t.Fatalf("Expected a JSON response, got %s", result)
+	}
+	if response.Status != "denied" || !strings.Contains(response.Stderr, `"rm"`) || !strings.Contains(response.Stderr, "denied_commands") {
+		t.Errorf("Expected rm to be denied, got %s", result)
+	}
+
+	result, err = tool.Execute(context.Background(), `{"command": "echo allowed", "description": "print text"}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(result, "allowed") || strings.Contains(result, `"denied"`) {
+		t.Errorf("Expected echo to run, got %s", result)
+	}
+}
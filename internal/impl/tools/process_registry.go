@@ -0,0 +1,205 @@
+package tools
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultMaxBackgroundProcesses        = 20
+	defaultMaxBackgroundProcessesPerChat = 5
+	defaultShutdownGrace                 = 5 * time.Second
+)
+
+// processRegistry tracks the background processes started by every
+// ProcessTool instance so the limits apply across agents and chats.
+type processRegistry struct {
+	mu        sync.Mutex
+	processes map[int]*ProcessInfo
+	startMu   sync.Mutex // held from the limit check until the process is added
+}
+
+var backgroundProcesses = &processRegistry{processes: make(map[int]*ProcessInfo)}
+
+// ProcessLimitError is returned when starting another background process
+// would exceed the per-chat or global limit.
+type ProcessLimitError struct {
+	Scope   string
+	Limit   int
+	Running []int
+}
+
+func (e *ProcessLimitError) Error() string {
+	pids := make([]string, len(e.Running))
+	for i, pid := range e.Running {
+		pids[i] = strconv.Itoa(pid)
+	}
+	return fmt.Sprintf("too many background processes: the %s limit of %d is reached (running PIDs: %v). Kill one with action \"kill\" and its pid before starting another",
+		e.Scope, e.Limit, pids)
+}
+
+// reserve checks the limits for chatID. A limit of zero or less disables it.
+func (r *processRegistry) reserve(chatID string, maxPerChat, maxGlobal int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var all, chat []int
+	for pid, pi := range r.processes {
+		if pi.exited {
+			continue
+		}
+		all = append(all, pid)
+		if pi.ChatID == chatID {
+			chat = append(chat, pid)
+		}
+	}
+	sort.Ints(all)
+	sort.Ints(chat)
+
+	if maxPerChat > 0 && len(chat) >= maxPerChat {
+		return &ProcessLimitError{Scope: "per-chat", Limit: maxPerChat, Running: chat}
+	}
+	if maxGlobal > 0 && len(all) >= maxGlobal {
+		return &ProcessLimitError{Scope: "global", Limit: maxGlobal, Running: all}
+	}
+	return nil
+}
+
+func (r *processRegistry) add(pid int, pi *ProcessInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.processes[pid] = pi
+}
+
+func (r *processRegistry) get(pid int) (*ProcessInfo, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pi, ok := r.processes[pid]
+	return pi, ok
+}
+
+func (r *processRegistry) remove(pid int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.processes, pid)
+}
+
+// markExited records that the process finished so it no longer counts
+// against the limits. It stays registered until its status is checked.
+func (r *processRegistry) markExited(pid int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if pi, ok := r.processes[pid]; ok && !pi.exited {
+		pi.exited = true
+		if pi.done != nil {
+			close(pi.done)
+		}
+	}
+}
+
+func (r *processRegistry) hasExited(pid int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pi, ok := r.processes[pid]
+	return ok && pi.exited
+}
+
+// StopBackgroundProcesses terminates the background processes still running,
+// so they don't outlive aiagent. It returns how many were stopped.
+func StopBackgroundProcesses() int {
+	return backgroundProcesses.stopAll()
+}
+
+// stopAll stops the running processes together, then unregisters every
+// process. They stay registered while stopping so their exit is recorded.
+func (r *processRegistry) stopAll() int {
+	r.mu.Lock()
+	var running []*ProcessInfo
+	for _, pi := range r.processes {
+		if !pi.exited {
+			running = append(running, pi)
+		}
+	}
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.processes = make(map[int]*ProcessInfo)
+		r.mu.Unlock()
+	}()
+
+	var wg sync.WaitGroup
+	for _, pi := range running {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pi.stop()
+		}()
+	}
+	wg.Wait()
+	return len(running)
+}
+
+// stop sends SIGTERM to the process's group, then SIGKILL if the process is
+// still running after its grace period, and closes its pipes. Signaling the
+// group stops the commands the shell started as well.
+func (pi *ProcessInfo) stop() {
+	defer pi.closePipes()
+	if pi.Cmd == nil || pi.Cmd.Process == nil {
+		return
+	}
+	if err := signalProcessGroup(pi.Cmd.Process, syscall.SIGTERM); err != nil {
+		// Windows can't deliver SIGTERM
+		pi.kill()
+		return
+	}
+	timer := time.NewTimer(pi.shutdownGrace)
+	defer timer.Stop()
+	select {
+	case <-pi.done:
+	case <-timer.C:
+		pi.kill()
+	}
+}
+
+// kill sends SIGKILL to the process's group and waits for its exit to be
+// recorded, which stopAll needs before it unregisters the process.
+func (pi *ProcessInfo) kill() {
+	if signalProcessGroup(pi.Cmd.Process, syscall.SIGKILL) == nil && pi.done != nil {
+		<-pi.done
+	}
+}
+
+func (pi *ProcessInfo) closePipes() {
+	for _, pipe := range []io.Closer{pi.Stdin, pi.Stdout, pi.Stderr} {
+		if pipe != nil {
+			pipe.Close()
+		}
+	}
+}
+
+// processLimit reads a limit from the tool configuration, falling back to
+// the default when it is unset or invalid.
+func processLimit(configuration map[string]string, key string, fallback int) int {
+	if value, ok := configuration[key]; ok && value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
+
+// processDuration reads a duration such as "10s" from the tool configuration,
+// falling back to the default when it is unset or invalid.
+func processDuration(configuration map[string]string, key string, fallback time.Duration) time.Duration {
+	if value, ok := configuration[key]; ok && value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+			return d
+		}
+	}
+	return fallback
+}
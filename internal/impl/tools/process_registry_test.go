@@ -0,0 +1,140 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProcessRegistry_Reserve(t *testing.T) {
+	registry := &processRegistry{processes: make(map[int]*ProcessInfo)}
+	registry.add(101, &ProcessInfo{ChatID: "chat-1"})
+	registry.add(102, &ProcessInfo{ChatID: "chat-1"})
+	registry.add(201, &ProcessInfo{ChatID: "chat-2"})
+
+	if err := registry.reserve("chat-1", 3, 10); err != nil {
+		t.Errorf("Expected chat-1 to be under its limit, got %v", err)
+	}
+
+	var limitErr *ProcessLimitError
+	err := registry.reserve("chat-1", 2, 10)
+	if !errors.As(err, &limitErr) || limitErr.Scope != "per-chat" {
+		t.Fatalf("Expected a per-chat limit error, got %v", err)
+	}
+	if len(limitErr.Running) != 2 || limitErr.Running[0] != 101 {
+		t.Errorf("Expected the running chat-1 pids, got %v", limitErr.Running)
+	}
+
+	err = registry.reserve("chat-3", 2, 3)
+	if !errors.As(err, &limitErr) || limitErr.Scope != "global" {
+		t.Errorf("Expected a global limit error, got %v", err)
+	}
+
+	// Exited processes no longer count against the limits
+	registry.markExited(101)
+	if err := registry.reserve("chat-1", 2, 3); err != nil {
+		t.Errorf("Expected exited processes to be ignored, got %v", err)
+	}
+
+	if err := registry.reserve("chat-1", 0, 0); err != nil {
+		t.Errorf("Expected zero limits to disable the check, got %v", err)
+	}
+}
+
+func TestProcessLimit(t *testing.T) {
+	config := map[string]string{"max_background_processes": "3", "max_background_per_chat": "many"}
+	if got := processLimit(config, "max_background_processes", 20); got != 3 {
+		t.Errorf("Expected configured limit 3, got %d", got)
+	}
+	if got := processLimit(config, "max_background_per_chat", 5); got != 5 {
+		t.Errorf("Expected fallback for an invalid limit, got %d", got)
+	}
+	if got := processLimit(nil, "max_background_processes", 20); got != 20 {
+		t.Errorf("Expected fallback for a missing limit, got %d", got)
+	}
+}
+
+func TestProcessRegistry_StopAll(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs SIGTERM")
+	}
+	registry := &processRegistry{processes: make(map[int]*ProcessInfo)}
+	start := func(grace time.Duration, script string) *ProcessInfo {
+		cmd := exec.Command("bash", "-c", script)
+		setProcessGroup(cmd)
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("Failed to start %q: %v", script, err)
+		}
+		pi := &ProcessInfo{Cmd: cmd, done: make(chan struct{}), shutdownGrace: grace}
+		registry.add(cmd.Process.Pid, pi)
+		go func() {
+			cmd.Wait()
+			registry.markExited(cmd.Process.Pid)
+		}()
+		return pi
+	}
+	polite := start(time.Minute, "sleep 30")
+	// Ignores SIGTERM, so it has to be killed after the grace period
+	stubborn := start(200*time.Millisecond, `trap "" TERM; sleep 30 & wait`)
+	// Its child has to be stopped along with it
+	childFile := filepath.Join(t.TempDir(), "child")
+	parent := start(time.Minute, fmt.Sprintf(`sleep 31 & echo $! > %q; wait`, childFile))
+	time.Sleep(100 * time.Millisecond)
+
+	started := time.Now()
+	if n := registry.stopAll(); n != 3 {
+		t.Errorf("Expected 3 processes stopped, got %d", n)
+	}
+	if elapsed := time.Since(started); elapsed > 10*time.Second {
+		t.Errorf("Expected the processes to stop promptly, took %v", elapsed)
+	}
+	for _, pi := range []*ProcessInfo{polite, stubborn, parent} {
+		select {
+		case <-pi.done:
+		case <-time.After(5 * time.Second):
+			t.Errorf("Expected pid %d to have exited", pi.Cmd.Process.Pid)
+		}
+	}
+	if child, err := os.ReadFile(childFile); err == nil && processRunning(strings.TrimSpace(string(child))) {
+		t.Errorf("Expected the child process %s to be stopped with its shell", strings.TrimSpace(string(child)))
+	}
+	if len(registry.processes) != 0 {
+		t.Errorf("Expected the registry to be emptied, got %d processes", len(registry.processes))
+	}
+}
+
+func TestProcessDuration(t *testing.T) {
+	config := map[string]string{"shutdown_grace": "2s", "bad": "soon"}
+	if got := processDuration(config, "shutdown_grace", time.Second); got != 2*time.Second {
+		t.Errorf("Expected the configured 2s, got %v", got)
+	}
+	if got := processDuration(config, "bad", time.Second); got != time.Second {
+		t.Errorf("Expected fallback for an invalid duration, got %v", got)
+	}
+}
+
+// processRunning reports whether /proc shows the pid running, rather than
+// gone or a zombie waiting to be reaped.
+func processRunning(pid string) bool {
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		stat, err := os.ReadFile(filepath.Join("/proc", pid, "stat"))
+		if err != nil {
+			return false
+		}
+		fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+		if len(fields) > 0 && fields[0] == "Z" {
+			return false
+		}
+		if time.Now().After(deadline) {
+			return true
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,37 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+// wrapCommand runs argv inside the sandbox described by template, for example
+// "firejail --quiet --net=none --whitelist={workspace} {command}" or
+// "docker exec -i sandbox {command}". {command} is replaced by the command's
+// arguments and {workspace} by the workspace path. A template without
+// {command} has the command appended. An empty template leaves argv as is.
+func wrapCommand(template string, argv []string, workspace string) ([]string, error) {
+	if strings.TrimSpace(template) == "" {
+		return argv, nil
+	}
+
+	parts := splitShellArgs(template)
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("invalid sandbox template: %q", template)
+	}
+
+	wrapped := make([]string, 0, len(parts)+len(argv))
+	placed := false
+	for _, part := range parts {
+		if part == "{command}" {
+			wrapped = append(wrapped, argv...)
+			placed = true
+			continue
+		}
+		wrapped = append(wrapped, strings.ReplaceAll(part, "{workspace}", workspace))
+	}
+	if !placed {
+		wrapped = append(wrapped, argv...)
+	}
+	return wrapped, nil
+}
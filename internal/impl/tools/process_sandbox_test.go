@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestWrapCommand(t *testing.T) {
+	argv := []string{"bash", "-c", "ls -la"}
+
+	tests := []struct {
+		name     string
+		template string
+		expected []string
+	}{
+		{"no sandbox", "", argv},
+		{"placeholder", "firejail --net=none --whitelist={workspace} {command}", []string{"firejail", "--net=none", "--whitelist=/work", "bash", "-c", "ls -la"}},
+		{"appended", "docker exec -i box", []string{"docker", "exec", "-i", "box", "bash", "-c", "ls -la"}},
+		{"quoted template", `nsjail --config "/etc/my jail.cfg" -- {command}`, []string{"nsjail", "--config", "/etc/my jail.cfg", "--", "bash", "-c", "ls -la"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := wrapCommand(tt.template, argv, "/work")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestProcessTool_Sandbox(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("env is not available on Windows")
+	}
+
+	config := map[string]string{"workspace": t.TempDir(), "sandbox": "env SANDBOXED=yes {command}"}
+	tool := NewProcessTool("test-process", "Test Process Tool", config, zap.NewNop())
+
+	result, err := tool.Execute(context.Background(), `{"command": "printenv SANDBOXED", "description": "print sandbox marker"}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(result, "yes") {
+		t.Errorf("Expected the command to run through the sandbox wrapper, got %s", result)
+	}
+}
+
+func TestProcessTool_Canceled(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sleep is not available on Windows")
+	}
+
+	tool := NewProcessTool("test-process", "Test Process Tool", map[string]string{"workspace": t.TempDir()}, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	result, err := tool.Execute(ctx, `{"command": "sleep 10", "description": "wait"}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(result, `"canceled"`) {
+		t.Errorf("Expected the command to be canceled, got %s", result)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the command to stop soon after cancellation, took %v", elapsed)
+	}
+}
@@ -0,0 +1,103 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sourceLanguage is a language a search can be narrowed to: the manifest
+// files that mark a project written in it and the globs of its source files.
+type sourceLanguage struct {
+	Name      string
+	Manifests []string
+	Globs     []string
+}
+
+var sourceLanguages = []sourceLanguage{
+	{"go", []string{"go.mod"}, []string{"*.go", "go.mod"}},
+	{"rust", []string{"Cargo.toml"}, []string{"*.rs", "Cargo.toml"}},
+	{"javascript", []string{"package.json"}, []string{"*.{js,jsx,mjs,cjs,ts,tsx,vue,svelte}", "package.json"}},
+	{"python", []string{"pyproject.toml", "setup.py", "requirements.txt", "Pipfile"}, []string{"*.{py,pyi}", "pyproject.toml", "setup.py", "requirements*.txt"}},
+	{"ruby", []string{"Gemfile"}, []string{"*.{rb,rake,gemspec}", "Gemfile"}},
+	{"java", []string{"pom.xml", "build.gradle", "build.gradle.kts"}, []string{"*.{java,kt,kts,gradle}", "pom.xml"}},
+	{"php", []string{"composer.json"}, []string{"*.php", "composer.json"}},
+	{"elixir", []string{"mix.exs"}, []string{"*.{ex,exs}"}},
+	{"crystal", []string{"shard.yml"}, []string{"*.cr", "shard.yml"}},
+	{"swift", []string{"Package.swift"}, []string{"*.swift"}},
+	{"dart", []string{"pubspec.yaml"}, []string{"*.dart", "pubspec.yaml"}},
+}
+
+// sourceLanguageNames lists the languages a search can be narrowed to.
+func sourceLanguageNames() string {
+	names := make([]string, len(sourceLanguages))
+	for i, language := range sourceLanguages {
+		names[i] = language.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// detectLanguages returns the languages whose manifest files are in dir, such
+// as go for a go.mod, in the order of sourceLanguages.
+func detectLanguages(dir string) []string {
+	var detected []string
+	for _, language := range sourceLanguages {
+		for _, manifest := range language.Manifests {
+			if info, err := os.Stat(filepath.Join(dir, manifest)); err == nil && !info.IsDir() {
+				detected = append(detected, language.Name)
+				break
+			}
+		}
+	}
+	return detected
+}
+
+// searchLanguages detects the languages of the searched directory from its
+// manifest files, else those of the workspace. A single file is searched
+// whatever its language, so it has none.
+func searchLanguages(workspace, fullPath string) []string {
+	if info, err := os.Stat(fullPath); err != nil || !info.IsDir() {
+		return nil
+	}
+	if languages := detectLanguages(fullPath); len(languages) > 0 {
+		return languages
+	}
+	return detectLanguages(workspace)
+}
+
+// languageGlobs returns the source file globs of the named languages.
+func languageGlobs(names []string) ([]string, error) {
+	var globs []string
+	for _, name := range names {
+		found := false
+		for _, language := range sourceLanguages {
+			if strings.EqualFold(language.Name, name) {
+				globs = append(globs, language.Globs...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown language %s, expected one of %s, auto or all", name, sourceLanguageNames())
+		}
+	}
+	return globs, nil
+}
+
+// binaryExtensions are file types that are never worth searching: images,
+// media, archives, compiled objects and the like. Searches skip them by name
+// without reading them.
+var binaryExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".bmp": true, ".ico": true, ".webp": true, ".tiff": true, ".psd": true,
+	".mp3": true, ".mp4": true, ".wav": true, ".ogg": true, ".mov": true, ".avi": true, ".webm": true, ".flac": true,
+	".zip": true, ".tar": true, ".gz": true, ".tgz": true, ".bz2": true, ".xz": true, ".7z": true, ".rar": true, ".jar": true, ".war": true,
+	".exe": true, ".dll": true, ".so": true, ".dylib": true, ".a": true, ".o": true, ".obj": true, ".class": true, ".pyc": true, ".wasm": true,
+	".pdf": true, ".woff": true, ".woff2": true, ".ttf": true, ".otf": true, ".eot": true,
+	".db": true, ".sqlite": true, ".bin": true, ".dat": true,
+}
+
+// hasBinaryExtension reports whether the path's extension is a binary type.
+func hasBinaryExtension(path string) bool {
+	return binaryExtensions[strings.ToLower(filepath.Ext(path))]
+}
@@ -9,11 +9,15 @@ import (
 	"strings"
 
 	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/impl/redact"
 
 	"github.com/go-openapi/spec"
 	"go.uber.org/zap"
 )
 
+// swaggerSecretKeys are the settings that hold credentials.
+var swaggerSecretKeys = map[string]bool{"auth_token": true, "auth_password": true, "api_key": true}
+
 type SwaggerTool struct {
 	name          string
 	description   string
@@ -48,6 +52,12 @@ func (t *SwaggerTool) UpdateConfiguration(config map[string]string) {
 	t.configuration = config
 }
 
+func (t *SwaggerTool) WithConfiguration(config map[string]string) entities.Tool {
+	c := *t
+	c.configuration = config
+	return &c
+}
+
 func (t *SwaggerTool) FullDescription() string {
 	var b strings.Builder
 
@@ -60,8 +70,12 @@ func (t *SwaggerTool) FullDescription() string {
 	b.WriteString("| Key           | Value         |\n")
 	b.WriteString("|---------------|---------------|\n")
 
-	// Loop through configuration and add key-value pairs to the table
+	// Loop through configuration and add key-value pairs to the table,
+	// leaving out the credentials execute sends
 	for key, value := range t.Configuration() {
+		if swaggerSecretKeys[key] && value != "" {
+			value = redact.Mask
+		}
 		b.WriteString(fmt.Sprintf("| %-13s | %-13s |\n", key, value))
 	}
 
@@ -72,10 +86,54 @@ func (t *SwaggerTool) Schema() map[string]any {
 	return map[string]any{
 		"type": "object",
 		"properties": map[string]any{
+			"operation": map[string]any{
+				"type":        "string",
+				"description": "fetch returns the specification and its endpoints; execute sends a request for one of its operations to the live server and checks the response against the specification (default fetch)",
+				"enum":        []string{"fetch", "execute"},
+			},
 			"fetch": map[string]any{
 				"type":        "boolean",
 				"description": "Whether to fetch and return the Swagger API specification (default: true)",
 			},
+			"operation_id": map[string]any{
+				"type":        "string",
+				"description": "For execute, the operationId of the operation to call",
+			},
+			"path": map[string]any{
+				"type":        "string",
+				"description": "For execute without an operation_id, the specification's path of the operation, e.g. /pets/{id}",
+			},
+			"method": map[string]any{
+				"type":        "string",
+				"description": "For execute without an operation_id, the HTTP method of the operation",
+				"enum":        []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
+			},
+			"parameters": map[string]any{
+				"type":        "object",
+				"description": "For execute, the path, query and header parameters by name, e.g. {\"id\": 1, \"limit\": 10}",
+			},
+			"body": map[string]any{
+				"description": "For execute, the request body. A string is sent as is; an object or array is sent as JSON.",
+			},
+			"base_url": map[string]any{
+				"type":        "string",
+				"description": "For execute, the server to call; defaults to the base_url setting and then the specification's host and basePath. The configured credentials are only sent to those servers; pass auth to call another one",
+			},
+			"auth": map[string]any{
+				"type":        "object",
+				"description": "For execute, credentials sent in the Authorization header instead of the configured ones",
+				"properties": map[string]any{
+					"type":     map[string]any{"type": "string", "enum": []string{"bearer", "basic"}},
+					"token":    map[string]any{"type": "string", "description": "The bearer token"},
+					"username": map[string]any{"type": "string", "description": "The basic auth username"},
+					"password": map[string]any{"type": "string", "description": "The basic auth password"},
+				},
+				"required": []string{"type"},
+			},
+			"timeout": map[string]any{
+				"type":        "number",
+				"description": "For execute, an optional timeout in seconds (max 120)",
+			},
 		},
 		"required": []string{},
 	}
@@ -96,53 +154,32 @@ func (t *SwaggerTool) Execute(ctx context.Context, arguments string) (string, er
 	t.logger.Debug("Executing Swagger tool", zap.String("arguments", arguments))
 
 	var args struct {
-		Fetch bool `json:"fetch"`
+		Operation string `json:"operation"`
+		Fetch     *bool  `json:"fetch"`
 	}
-	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
-		t.logger.Error("Failed to parse arguments", zap.Error(err))
-		return "", err
+	if arguments != "" {
+		if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+			t.logger.Error("Failed to parse arguments", zap.Error(err))
+			return "", err
+		}
 	}
 
-	// Default to true if not specified
-	if arguments == "" {
-		args.Fetch = true
+	switch args.Operation {
+	case "execute":
+		return t.executeOperation(ctx, arguments)
+	case "", "fetch":
+	default:
+		return "", fmt.Errorf("unsupported operation: %s", args.Operation)
 	}
 
-	if !args.Fetch {
+	// Default to true if not specified
+	if args.Fetch != nil && !*args.Fetch {
 		return "", fmt.Errorf("no action requested")
 	}
 
 	swaggerURL := t.configuration["swagger_url"]
-	if swaggerURL == "" {
-		t.logger.Error("Swagger URL not configured")
-		return "", fmt.Errorf("swagger_url configuration is required")
-	}
-
-	// Fetch the Swagger JSON
-	req, err := http.NewRequest("GET", swaggerURL, nil)
-	if err != nil {
-		t.logger.Error("Failed to create request", zap.Error(err))
-		return "", err
-	}
-	req.Header.Set("User-Agent", defaultUserAgent)
-
-	resp, err := t.client.Do(req)
-	if err != nil {
-		t.logger.Error("Failed to fetch Swagger spec", zap.Error(err))
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	swaggerSpec, body, err := t.fetchSpec(ctx)
 	if err != nil {
-		t.logger.Error("Failed to read response body", zap.Error(err))
-		return "", err
-	}
-
-	// Parse the Swagger JSON
-	var swaggerSpec spec.Swagger
-	if err := json.Unmarshal(body, &swaggerSpec); err != nil {
-		t.logger.Error("Failed to parse Swagger JSON", zap.Error(err))
 		return "", err
 	}
 
@@ -233,14 +270,82 @@ func (t *SwaggerTool) Execute(ctx context.Context, arguments string) (string, er
 	return string(jsonResult), nil
 }
 
+// fetchSpec downloads and parses the specification at swagger_url.
+func (t *SwaggerTool) fetchSpec(ctx context.Context) (*spec.Swagger, []byte, error) {
+	swaggerURL := t.configuration["swagger_url"]
+	if swaggerURL == "" {
+		t.logger.Error("Swagger URL not configured")
+		return nil, nil, fmt.Errorf("swagger_url configuration is required")
+	}
+
+	// Fetch the Swagger JSON
+	req, err := http.NewRequestWithContext(ctx, "GET", swaggerURL, nil)
+	if err != nil {
+		t.logger.Error("Failed to create request", zap.Error(err))
+		return nil, nil, err
+	}
+	req.Header.Set("User-Agent", defaultUserAgent)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		t.logger.Error("Failed to fetch Swagger spec", zap.Error(err))
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.logger.Error("Failed to read response body", zap.Error(err))
+		return nil, nil, err
+	}
+
+	// Parse the Swagger JSON
+	var swaggerSpec spec.Swagger
+	if err := json.Unmarshal(body, &swaggerSpec); err != nil {
+		t.logger.Error("Failed to parse Swagger JSON", zap.Error(err))
+		return nil, nil, err
+	}
+	if swaggerSpec.Paths == nil {
+		return nil, nil, fmt.Errorf("the specification at %s has no paths", swaggerURL)
+	}
+	return &swaggerSpec, body, nil
+}
+
+// Mutates reports executed operations unless they are known to use GET, HEAD
+// or OPTIONS. An operation named by its operationId could use any method.
+func (t *SwaggerTool) Mutates(arguments string) bool {
+	var args struct {
+		Operation   string `json:"operation"`
+		OperationID string `json:"operation_id"`
+		Method      string `json:"method"`
+	}
+	json.Unmarshal([]byte(arguments), &args)
+	if args.Operation != "execute" {
+		return false
+	}
+	if args.OperationID == "" {
+		switch strings.ToUpper(args.Method) {
+		case "GET", "HEAD", "OPTIONS":
+			return false
+		}
+	}
+	return true
+}
+
 func (t *SwaggerTool) DisplayName(ui string, arguments string) (string, string) {
 	var args struct {
-		URL string `json:"url"`
+		Operation   string `json:"operation"`
+		OperationID string `json:"operation_id"`
+		Method      string `json:"method"`
+		Path        string `json:"path"`
 	}
-	if err := json.Unmarshal([]byte(arguments), &args); err == nil && args.URL != "" {
-		return t.Name(), args.URL
+	if err := json.Unmarshal([]byte(arguments), &args); err == nil && args.Operation == "execute" {
+		if args.OperationID != "" {
+			return t.Name(), args.OperationID
+		}
+		return t.Name(), strings.TrimSpace(strings.ToUpper(args.Method) + " " + args.Path)
 	}
-	return t.Name(), ""
+	return t.Name(), t.configuration["swagger_url"]
 }
 
 func (t *SwaggerTool) FormatResult(ui string, result string, diff string, arguments string) string {
@@ -254,3 +359,4 @@ func (t *SwaggerTool) FormatResult(ui string, result string, diff string, argume
 }
 
 var _ entities.Tool = (*SwaggerTool)(nil)
+var _ entities.MutatingTool = (*SwaggerTool)(nil)
@@ -0,0 +1,555 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/drujensen/aiagent/internal/impl/redact"
+
+	"github.com/go-openapi/spec"
+	"go.uber.org/zap"
+)
+
+// maxContractProblems bounds the schema mismatches reported for a response.
+const maxContractProblems = 20
+
+// swaggerExecuteArgs are the arguments of the execute operation.
+type swaggerExecuteArgs struct {
+	OperationID string         `json:"operation_id"`
+	Path        string         `json:"path"`
+	Method      string         `json:"method"`
+	Parameters  map[string]any `json:"parameters"`
+	Body        any            `json:"body"`
+	BaseURL     string         `json:"base_url"`
+	Auth        *fetchAuth     `json:"auth"`
+	Timeout     float64        `json:"timeout"`
+}
+
+// swaggerOperation is an operation of the specification with the parameters
+// of its path item merged in.
+type swaggerOperation struct {
+	method     string
+	path       string
+	operation  *spec.Operation
+	parameters []spec.Parameter
+}
+
+// executeOperation sends a request for an operation of the specification to
+// the live server through the WebFetch tool and checks that the response has
+// a declared status code and matches its schema.
+func (t *SwaggerTool) executeOperation(ctx context.Context, arguments string) (string, error) {
+	var args swaggerExecuteArgs
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %v", err)
+	}
+	if args.OperationID == "" && (args.Path == "" || args.Method == "") {
+		return "", fmt.Errorf("operation_id or path and method are required to execute an operation")
+	}
+
+	swaggerSpec, _, err := t.fetchSpec(ctx)
+	if err != nil {
+		return "", err
+	}
+	op, err := findOperation(swaggerSpec, args.OperationID, args.Path, args.Method)
+	if err != nil {
+		return "", err
+	}
+
+	baseURL, err := t.baseURL(swaggerSpec, args.BaseURL)
+	if err != nil {
+		return "", err
+	}
+	requestURL, headers, err := buildOperationRequest(op, baseURL, args.Parameters)
+	if err != nil {
+		return "", err
+	}
+
+	// Credentials come from the call or the configuration and never appear
+	// in the result. The configured ones are only sent to the configured
+	// server, so a call's base_url can't send them anywhere else.
+	trusted := t.trustedServer(swaggerSpec, requestURL)
+	if !trusted && args.Auth == nil && (t.configuration["auth_type"] != "" || t.configuration["api_key"] != "") {
+		return "", fmt.Errorf("the configured credentials are only sent to the configured server; pass auth to call %s", baseURL)
+	}
+	auth := args.Auth
+	if auth == nil && t.configuration["auth_type"] != "" {
+		auth = &fetchAuth{
+			Type:     t.configuration["auth_type"],
+			Token:    t.configuration["auth_token"],
+			Username: t.configuration["auth_username"],
+			Password: t.configuration["auth_password"],
+		}
+	}
+	var secrets []string
+	if auth != nil {
+		secrets = append(secrets, auth.Token, auth.Password)
+		if strings.EqualFold(auth.Type, "basic") && (auth.Username != "" || auth.Password != "") {
+			secrets = append(secrets, base64.StdEncoding.EncodeToString([]byte(auth.Username+":"+auth.Password)))
+		}
+	}
+	redactSetting := t.configuration["redact_headers"]
+	if apiKey := t.configuration["api_key"]; apiKey != "" && trusted {
+		header := t.configuration["api_key_header"]
+		if header == "" {
+			header = "X-Api-Key"
+		}
+		headers[header] = apiKey
+		secrets = append(secrets, apiKey)
+		redactSetting += "," + header
+	}
+
+	fetchArgs := map[string]any{
+		"url":     requestURL,
+		"method":  op.method,
+		"headers": headers,
+		"format":  "raw",
+	}
+	if args.Body != nil {
+		fetchArgs["body"] = args.Body
+	}
+	if auth != nil {
+		fetchArgs["auth"] = auth
+	}
+	if args.Timeout > 0 {
+		fetchArgs["timeout"] = args.Timeout
+	}
+	fetchArguments, err := json.Marshal(fetchArgs)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode request: %v", err)
+	}
+
+	fetch := NewFetchTool(t.name, t.description, map[string]string{
+		"user_agent":     t.configuration["user_agent"],
+		"redact_headers": redactSetting,
+	}, t.logger)
+	fetched, err := fetch.Execute(ctx, string(fetchArguments))
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		Content     string            `json:"content"`
+		StatusCode  int               `json:"status_code"`
+		URL         string            `json:"url"`
+		ContentType string            `json:"content_type"`
+		Headers     map[string]string `json:"headers"`
+		Truncated   bool              `json:"truncated"`
+	}
+	if err := json.Unmarshal([]byte(fetched), &resp); err != nil {
+		return "", fmt.Errorf("failed to parse response: %v", err)
+	}
+
+	problems := checkResponse(swaggerSpec, op.operation, resp.StatusCode, resp.ContentType, resp.Content, resp.Truncated)
+
+	name := op.method + " " + op.path
+	if op.operation.ID != "" {
+		name += " (" + op.operation.ID + ")"
+	}
+	var summary strings.Builder
+	if len(problems) == 0 {
+		summary.WriteString(fmt.Sprintf("✅ PASS %s → %d\n", name, resp.StatusCode))
+	} else {
+		summary.WriteString(fmt.Sprintf("❌ FAIL %s → %d\n", name, resp.StatusCode))
+		for _, problem := range problems {
+			summary.WriteString(fmt.Sprintf("  - %s\n", problem))
+		}
+	}
+
+	responseHeaders := make(map[string]string, len(resp.Headers))
+	for key, value := range redactHeaders(toHeader(resp.Headers), fetch.redactedHeaders()) {
+		responseHeaders[key] = redact.Values(value, secrets...)
+	}
+
+	result, err := json.Marshal(struct {
+		Summary     string            `json:"summary"`
+		Passed      bool              `json:"passed"`
+		Problems    []string          `json:"problems,omitempty"`
+		Method      string            `json:"method"`
+		Path        string            `json:"path"`
+		OperationID string            `json:"operation_id,omitempty"`
+		URL         string            `json:"url"`
+		StatusCode  int               `json:"status_code"`
+		ContentType string            `json:"content_type"`
+		Headers     map[string]string `json:"headers,omitempty"`
+		Content     string            `json:"content"`
+		Truncated   bool              `json:"truncated,omitempty"`
+	}{
+		Summary:     redact.Values(summary.String(), secrets...),
+		Passed:      len(problems) == 0,
+		Problems:    problems,
+		Method:      op.method,
+		Path:        op.path,
+		OperationID: op.operation.ID,
+		URL:         redact.Values(resp.URL, secrets...),
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.ContentType,
+		Headers:     responseHeaders,
+		Content:     redact.Values(resp.Content, secrets...),
+		Truncated:   resp.Truncated,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal response: %v", err)
+	}
+
+	t.logger.Info("Swagger operation executed",
+		zap.String("operation", name),
+		zap.Int("status", resp.StatusCode),
+		zap.Bool("passed", len(problems) == 0))
+	return string(result), nil
+}
+
+func toHeader(headers map[string]string) http.Header {
+	h := make(http.Header, len(headers))
+	for key, value := range headers {
+		h[key] = []string{value}
+	}
+	return h
+}
+
+// findOperation looks an operation up by its operationId, or by its path and
+// method.
+func findOperation(swaggerSpec *spec.Swagger, operationID, path, method string) (*swaggerOperation, error) {
+	method = strings.ToUpper(method)
+	paths := make([]string, 0, len(swaggerSpec.Paths.Paths))
+	for p := range swaggerSpec.Paths.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		item := swaggerSpec.Paths.Paths[p]
+		for _, candidate := range []struct {
+			method    string
+			operation *spec.Operation
+		}{
+			{"GET", item.Get}, {"POST", item.Post}, {"PUT", item.Put}, {"PATCH", item.Patch},
+			{"DELETE", item.Delete}, {"HEAD", item.Head}, {"OPTIONS", item.Options},
+		} {
+			if candidate.operation == nil {
+				continue
+			}
+			if operationID != "" && candidate.operation.ID != operationID {
+				continue
+			}
+			if operationID == "" && (p != path || candidate.method != method) {
+				continue
+			}
+			return &swaggerOperation{
+				method:     candidate.method,
+				path:       p,
+				operation:  candidate.operation,
+				parameters: mergeParameters(swaggerSpec, item.Parameters, candidate.operation.Parameters),
+			}, nil
+		}
+	}
+	if operationID != "" {
+		return nil, fmt.Errorf("no operation with operationId %s in the specification", operationID)
+	}
+	return nil, fmt.Errorf("no %s %s operation in the specification", method, path)
+}
+
+// mergeParameters resolves the parameters of a path item and its operation;
+// the operation's override those of the path item with the same name and
+// location.
+func mergeParameters(swaggerSpec *spec.Swagger, pathParams, opParams []spec.Parameter) []spec.Parameter {
+	var merged []spec.Parameter
+	index := make(map[string]int)
+	for _, param := range append(append([]spec.Parameter{}, pathParams...), opParams...) {
+		if ref := param.Ref.String(); ref != "" {
+			resolved, ok := swaggerSpec.Parameters[strings.TrimPrefix(ref, "#/parameters/")]
+			if !ok {
+				continue
+			}
+			param = resolved
+		}
+		key := param.In + ":" + param.Name
+		if i, ok := index[key]; ok {
+			merged[i] = param
+			continue
+		}
+		index[key] = len(merged)
+		merged = append(merged, param)
+	}
+	return merged
+}
+
+// baseURL is the server operations are sent to: the argument, the base_url
+// setting, or the specification's scheme, host and basePath, defaulting to
+// those of swagger_url.
+func (t *SwaggerTool) baseURL(swaggerSpec *spec.Swagger, override string) (string, error) {
+	if override == "" {
+		override = t.configuration["base_url"]
+	}
+	if override != "" {
+		return strings.TrimSuffix(override, "/"), nil
+	}
+	return t.specBaseURL(swaggerSpec)
+}
+
+// specBaseURL returns the server the specification names, falling back to
+// the scheme and host it was fetched from.
+func (t *SwaggerTool) specBaseURL(swaggerSpec *spec.Swagger) (string, error) {
+	specURL, err := url.Parse(t.configuration["swagger_url"])
+	if err != nil {
+		return "", fmt.Errorf("invalid swagger_url: %v", err)
+	}
+	scheme := specURL.Scheme
+	if len(swaggerSpec.Schemes) > 0 && !containsFold(swaggerSpec.Schemes, scheme) {
+		scheme = swaggerSpec.Schemes[0]
+	}
+	host := swaggerSpec.Host
+	if host == "" {
+		host = specURL.Host
+	}
+	if scheme == "" || host == "" {
+		return "", fmt.Errorf("base_url is required: the specification names no server")
+	}
+	return scheme + "://" + host + strings.TrimSuffix(swaggerSpec.BasePath, "/"), nil
+}
+
+// trustedServer reports whether target has the scheme and host of the
+// configured base_url or of the server the specification names.
+func (t *SwaggerTool) trustedServer(swaggerSpec *spec.Swagger, target string) bool {
+	targetURL, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	servers := []string{t.configuration["base_url"]}
+	if specBase, err := t.specBaseURL(swaggerSpec); err == nil {
+		servers = append(servers, specBase)
+	}
+	for _, server := range servers {
+		serverURL, err := url.Parse(server)
+		if err == nil && serverURL.Host != "" &&
+			strings.EqualFold(serverURL.Scheme, targetURL.Scheme) && strings.EqualFold(serverURL.Host, targetURL.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildOperationRequest fills the operation's path with the path parameters
+// and returns its URL with the query parameters and the header parameters.
+// Parameters the operation doesn't declare and missing required ones are
+// errors, so a call doesn't silently test something else.
+func buildOperationRequest(op *swaggerOperation, baseURL string, params map[string]any) (string, map[string]string, error) {
+	path := op.path
+	query := url.Values{}
+	headers := make(map[string]string)
+	used := make(map[string]bool)
+
+	for _, param := range op.parameters {
+		value, ok := params[param.Name]
+		if !ok || value == nil {
+			if param.Required && param.In != "body" && param.In != "formData" {
+				return "", nil, fmt.Errorf("missing required %s parameter: %s", param.In, param.Name)
+			}
+			continue
+		}
+		switch param.In {
+		case "path":
+			path = strings.ReplaceAll(path, "{"+param.Name+"}", url.PathEscape(paramString(value)))
+		case "query":
+			if values, ok := value.([]any); ok {
+				for _, v := range values {
+					query.Add(param.Name, paramString(v))
+				}
+			} else {
+				query.Set(param.Name, paramString(value))
+			}
+		case "header":
+			headers[param.Name] = paramString(value)
+		default:
+			// Body and form data parameters are sent in the body
+			continue
+		}
+		used[param.Name] = true
+	}
+
+	var unknown []string
+	for name := range params {
+		if !used[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return "", nil, fmt.Errorf("the operation has no path, query or header parameters named %s", strings.Join(unknown, ", "))
+	}
+
+	requestURL := baseURL + path
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+	return requestURL, headers, nil
+}
+
+// paramString renders a parameter value as it is sent, without the decimal
+// point JSON numbers decode with.
+func paramString(value any) string {
+	if f, ok := value.(float64); ok && f == math.Trunc(f) {
+		return strconv.FormatInt(int64(f), 10)
+	}
+	return fmt.Sprint(value)
+}
+
+// checkResponse returns how the response breaks the operation's contract:
+// a status code it doesn't declare or a JSON body that doesn't match the
+// declared schema.
+func checkResponse(swaggerSpec *spec.Swagger, operation *spec.Operation, statusCode int, contentType, content string, truncated bool) []string {
+	if operation.Responses == nil {
+		return nil
+	}
+	response, ok := operation.Responses.StatusCodeResponses[statusCode]
+	if !ok {
+		if operation.Responses.Default == nil {
+			codes := make([]string, 0, len(operation.Responses.StatusCodeResponses))
+			for code := range operation.Responses.StatusCodeResponses {
+				codes = append(codes, strconv.Itoa(code))
+			}
+			sort.Strings(codes)
+			return []string{fmt.Sprintf("status %d is not declared; expected %s", statusCode, strings.Join(codes, ", "))}
+		}
+		response = *operation.Responses.Default
+	}
+	if ref := response.Ref.String(); ref != "" {
+		response = swaggerSpec.Responses[strings.TrimPrefix(ref, "#/responses/")]
+	}
+	if response.Schema == nil {
+		return nil
+	}
+	if truncated {
+		return []string{"the body was truncated, so it could not be checked against the schema"}
+	}
+	if contentType != "" && !strings.Contains(strings.ToLower(contentType), "json") {
+		return []string{fmt.Sprintf("expected a JSON body, got %s", contentType)}
+	}
+
+	var body any
+	if err := json.Unmarshal([]byte(content), &body); err != nil {
+		return []string{fmt.Sprintf("the body is not valid JSON: %v", err)}
+	}
+	var problems []string
+	checkSchema(swaggerSpec, response.Schema, body, "$", &problems, 0)
+	if len(problems) > maxContractProblems {
+		problems = append(problems[:maxContractProblems], fmt.Sprintf("... and %d more", len(problems)-maxContractProblems))
+	}
+	return problems
+}
+
+// checkSchema appends where value doesn't match the schema's types, required
+// properties and enums. It is not a full JSON Schema validator; formats,
+// patterns and bounds are not checked.
+func checkSchema(swaggerSpec *spec.Swagger, schema *spec.Schema, value any, at string, problems *[]string, depth int) {
+	// Recursive definitions stop somewhere
+	if schema == nil || depth > 32 {
+		return
+	}
+	if ref := schema.Ref.String(); ref != "" {
+		resolved, ok := swaggerSpec.Definitions[strings.TrimPrefix(ref, "#/definitions/")]
+		if !ok {
+			return
+		}
+		schema = &resolved
+	}
+	for i := range schema.AllOf {
+		checkSchema(swaggerSpec, &schema.AllOf[i], value, at, problems, depth+1)
+	}
+
+	if value == nil {
+		if nullable, _ := schema.Extensions.GetBool("x-nullable"); schema.Nullable || nullable || len(schema.Type) == 0 || schema.Type.Contains("null") {
+			return
+		}
+		*problems = append(*problems, fmt.Sprintf("%s: expected %s, got null", at, strings.Join(schema.Type, " or ")))
+		return
+	}
+	if len(schema.Type) > 0 && !matchesType(schema.Type, value) {
+		*problems = append(*problems, fmt.Sprintf("%s: expected %s, got %s", at, strings.Join(schema.Type, " or "), jsonType(value)))
+		return
+	}
+	if len(schema.Enum) > 0 {
+		found := false
+		for _, allowed := range schema.Enum {
+			if fmt.Sprint(allowed) == fmt.Sprint(value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			*problems = append(*problems, fmt.Sprintf("%s: %v is not one of %v", at, value, schema.Enum))
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for _, name := range schema.Required {
+			if _, ok := v[name]; !ok {
+				*problems = append(*problems, fmt.Sprintf("%s: missing required property %s", at, name))
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := schema.Properties[name]; ok {
+				checkSchema(swaggerSpec, &property, v[name], at+"."+name, problems, depth+1)
+			}
+		}
+	case []any:
+		if schema.Items != nil && schema.Items.Schema != nil {
+			for i, item := range v {
+				checkSchema(swaggerSpec, schema.Items.Schema, item, fmt.Sprintf("%s[%d]", at, i), problems, depth+1)
+			}
+		}
+	}
+}
+
+func matchesType(types spec.StringOrArray, value any) bool {
+	actual := jsonType(value)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType names the JSON Schema type of a decoded value.
+func jsonType(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,252 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+const testSwaggerSpec = `{
+	"swagger": "2.0",
+	"info": {"title": "Pets", "version": "1.0"},
+	"basePath": "/api",
+	"paths": {
+		"/pets/{id}": {
+			"parameters": [{"name": "id", "in": "path", "required": true, "type": "integer"}],
+			"get": {
+				"operationId": "getPet",
+				"parameters": [{"name": "verbose", "in": "query", "type": "boolean"}],
+				"responses": {
+					"200": {"description": "A pet", "schema": {"$ref": "#/definitions/Pet"}},
+					"404": {"description": "Not found"}
+				}
+			}
+		},
+		"/pets": {
+			"post": {
+				"operationId": "createPet",
+				"parameters": [{"name": "pet", "in": "body", "required": true, "schema": {"$ref": "#/definitions/Pet"}}],
+				"responses": {"201": {"description": "Created", "schema": {"$ref": "#/definitions/Pet"}}}
+			}
+		}
+	},
+	"definitions": {
+		"Pet": {
+			"type": "object",
+			"required": ["id", "name"],
+			"properties": {
+				"id": {"type": "integer"},
+				"name": {"type": "string"},
+				"tags": {"type": "array", "items": {"type": "string"}},
+				"status": {"type": "string", "enum": ["available", "sold"]}
+			}
+		}
+	}
+}`
+
+func newSwaggerTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/swagger.json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testSwaggerSpec)
+	})
+	mux.HandleFunc("/api/pets/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch strings.TrimPrefix(r.URL.Path, "/api/pets/") {
+		case "1":
+			fmt.Fprint(w, `{"id": 1, "name": "Rex", "tags": ["dog"], "status": "available"}`)
+		case "2":
+			fmt.Fprint(w, `{"id": 2, "name": 7, "status": "lost"}`)
+		case "3":
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"error": "boom"}`)
+		default:
+			// Echoes the credentials, as a careless server might
+			fmt.Fprintf(w, `{"id": 4, "name": %q}`, r.Header.Get("Authorization"))
+		}
+	})
+	mux.HandleFunc("/api/pets", func(w http.ResponseWriter, r *http.Request) {
+		var pet map[string]any
+		json.NewDecoder(r.Body).Decode(&pet)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(pet)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+type swaggerExecuteResult struct {
+	Summary    string   `json:"summary"`
+	Passed     bool     `json:"passed"`
+	Problems   []string `json:"problems"`
+	StatusCode int      `json:"status_code"`
+	URL        string   `json:"url"`
+	Content    string   `json:"content"`
+}
+
+func executeSwagger(t *testing.T, tool *SwaggerTool, arguments string) swaggerExecuteResult {
+	t.Helper()
+	result, err := tool.Execute(context.Background(), arguments)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	var parsed swaggerExecuteResult
+	if err := json.Unmarshal([]byte(result), &parsed); err != nil {
+		t.Fatalf("Failed to parse result %s: %v", result, err)
+	}
+	return parsed
+}
+
+func TestSwaggerTool_Execute(t *testing.T) {
+	server := newSwaggerTestServer(t)
+	tool := NewSwaggerTool("Swagger", "", map[string]string{"swagger_url": server.URL + "/swagger.json"}, zap.NewNop())
+
+	result := executeSwagger(t, tool, `{"operation": "execute", "operation_id": "getPet", "parameters": {"id": 1, "verbose": true}}`)
+	if !result.Passed || result.StatusCode != 200 {
+		t.Errorf("Expected getPet to pass, got %+v", result)
+	}
+	if !strings.HasSuffix(result.URL, "/api/pets/1?verbose=true") {
+		t.Errorf("Expected the path and query parameters in the URL, got %s", result.URL)
+	}
+
+	result = executeSwagger(t, tool, `{"operation": "execute", "path": "/pets/{id}", "method": "get", "parameters": {"id": 2}}`)
+	if result.Passed || len(result.Problems) != 2 {
+		t.Fatalf("Expected two schema problems, got %+v", result)
+	}
+	if !strings.Contains(result.Problems[0], "$.name: expected string, got integer") || !strings.Contains(result.Problems[1], "$.status: lost is not one of") {
+		t.Errorf("Unexpected problems: %v", result.Problems)
+	}
+
+	result = executeSwagger(t, tool, `{"operation": "execute", "operation_id": "getPet", "parameters": {"id": 3}}`)
+	if result.Passed || len(result.Problems) != 1 || !strings.Contains(result.Problems[0], "status 500 is not declared") {
+		t.Errorf("Expected an undeclared status, got %+v", result)
+	}
+
+	result = executeSwagger(t, tool, `{"operation": "execute", "operation_id": "createPet", "body": {"id": 5, "name": "Tom"}}`)
+	if !result.Passed || result.StatusCode != 201 {
+		t.Errorf("Expected createPet to pass, got %+v", result)
+	}
+}
+
+func TestSwaggerTool_ExecuteErrors(t *testing.T) {
+	server := newSwaggerTestServer(t)
+	tool := NewSwaggerTool("Swagger", "", map[string]string{"swagger_url": server.URL + "/swagger.json"}, zap.NewNop())
+
+	tests := []struct {
+		arguments string
+		want      string
+	}{
+		{`{"operation": "execute"}`, "operation_id or path and method are required"},
+		{`{"operation": "execute", "operation_id": "deletePet"}`, "no operation with operationId deletePet"},
+		{`{"operation": "execute", "path": "/pets", "method": "GET"}`, "no GET /pets operation"},
+		{`{"operation": "execute", "operation_id": "getPet"}`, "missing required path parameter: id"},
+		{`{"operation": "execute", "operation_id": "getPet", "parameters": {"id": 1, "limit": 5}}`, "no path, query or header parameters named limit"},
+	}
+	for _, tt := range tests {
+		_, err := tool.Execute(context.Background(), tt.arguments)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.arguments, tt.want, err)
+		}
+	}
+}
+
+func TestSwaggerTool_ExecuteRedactsSecrets(t *testing.T) {
+	server := newSwaggerTestServer(t)
+	tool := NewSwaggerTool("Swagger", "", map[string]string{
+		"swagger_url": server.URL + "/swagger.json",
+		"auth_type":   "bearer",
+		"auth_token":  "s3cret-token",
+	}, zap.NewNop())
+
+	result, err := tool.Execute(context.Background(), `{"operation": "execute", "operation_id": "getPet", "parameters": {"id": 4}}`)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if strings.Contains(result, "s3cret-token") || !strings.Contains(result, "Bearer [REDACTED]") {
+		t.Errorf("Expected the echoed token to be redacted, got %s", result)
+	}
+	if strings.Contains(tool.FullDescription(), "s3cret-token") {
+		t.Errorf("Expected the token to be left out of the description")
+	}
+
+	// Only basic auth sends credentials worth redacting; with a bearer token
+	// the encoding of an empty username and password is just text
+	server.Config.Handler.(*http.ServeMux).HandleFunc("/api/pets/5", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id": 5, "name": "Og=="}`)
+	})
+	result, err = tool.Execute(context.Background(), `{"operation": "execute", "operation_id": "getPet", "parameters": {"id": 5}}`)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !strings.Contains(result, "Og==") {
+		t.Errorf("Expected only the credentials to be redacted, got %s", result)
+	}
+}
+
+func TestSwaggerTool_ExecuteKeepsCredentialsOnConfiguredServer(t *testing.T) {
+	server := newSwaggerTestServer(t)
+	var received []string
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Header.Get("Authorization")+r.Header.Get("X-Api-Key"))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id": 1, "name": "Rex"}`)
+	}))
+	defer other.Close()
+
+	tool := NewSwaggerTool("Swagger", "", map[string]string{
+		"swagger_url": server.URL + "/swagger.json",
+		"base_url":    server.URL + "/api",
+		"auth_type":   "bearer",
+		"auth_token":  "s3cret-token",
+		"api_key":     "s3cret-key",
+	}, zap.NewNop())
+
+	_, err := tool.Execute(context.Background(), fmt.Sprintf(`{"operation": "execute", "operation_id": "getPet", "parameters": {"id": 1}, "base_url": %q}`, other.URL))
+	if err == nil || !strings.Contains(err.Error(), "pass auth") {
+		t.Errorf("Expected a call to another server without auth to be refused, got %v", err)
+	}
+	if len(received) != 0 {
+		t.Fatalf("Expected no request to reach the other server, got %v", received)
+	}
+
+	result := executeSwagger(t, tool, fmt.Sprintf(`{"operation": "execute", "operation_id": "getPet", "parameters": {"id": 1}, "base_url": %q, "auth": {"type": "bearer", "token": "own-token"}}`, other.URL))
+	if !result.Passed {
+		t.Errorf("Expected the call with its own auth to pass, got %+v", result)
+	}
+	if len(received) != 1 || received[0] != "Bearer own-token" {
+		t.Errorf("Expected only the call's own credentials to be sent, got %v", received)
+	}
+
+	// The configured server still gets the configured credentials
+	result = executeSwagger(t, tool, `{"operation": "execute", "operation_id": "getPet", "parameters": {"id": 4}}`)
+	if !strings.Contains(result.Content, "Bearer [REDACTED]") {
+		t.Errorf("Expected the configured token to be sent to the configured server, got %+v", result)
+	}
+}
+
+func TestSwaggerTool_Mutates(t *testing.T) {
+	tool := NewSwaggerTool("Swagger", "", map[string]string{}, zap.NewNop())
+	tests := []struct {
+		arguments string
+		want      bool
+	}{
+		{`{"fetch": true}`, false},
+		{`{"operation": "execute", "path": "/pets", "method": "GET"}`, false},
+		{`{"operation": "execute", "path": "/pets", "method": "POST"}`, true},
+		{`{"operation": "execute", "operation_id": "getPet"}`, true},
+	}
+	for _, tt := range tests {
+		if got := tool.Mutates(tt.arguments); got != tt.want {
+			t.Errorf("Mutates(%s) = %v, want %v", tt.arguments, got, tt.want)
+		}
+	}
+}
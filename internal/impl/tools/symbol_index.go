@@ -0,0 +1,346 @@
+package tools
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// codeSymbol is a declaration and its byte range in the file, so it can be
+// replaced wherever it currently is. The range starts after the doc comment.
+type codeSymbol struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	StartLine int    `json:"startLine"`
+	EndLine   int    `json:"endLine"`
+	start     int
+	end       int
+}
+
+// findSymbol returns the declaration called name, either a function, type,
+// class, variable or constant name or a method as Type.Method. A bare method
+// name matches the method of any type, so it has to be unique. Imports are
+// not matched.
+func findSymbol(path string, content []byte, name string) (codeSymbol, error) {
+	symbols, err := fileSymbols(path, content)
+	if err != nil {
+		return codeSymbol{}, err
+	}
+
+	var matches []codeSymbol
+	for _, symbol := range symbols {
+		if symbol.Kind == "import" {
+			continue
+		}
+		if symbol.Name == name || (symbol.Kind == "method" && strings.HasSuffix(symbol.Name, "."+name)) {
+			matches = append(matches, symbol)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return codeSymbol{}, fmt.Errorf("symbol %q not found in %s", name, path)
+	case 1:
+		return matches[0], nil
+	}
+	var candidates []string
+	for _, match := range matches {
+		candidates = append(candidates, fmt.Sprintf("%s (line %d)", match.Name, match.StartLine))
+	}
+	return codeSymbol{}, fmt.Errorf("symbol %q is ambiguous in %s; use one of: %s", name, path, strings.Join(candidates, ", "))
+}
+
+// fileSymbols lists the file's imports and declarations in order. Go files
+// are parsed with go/parser; Python, JavaScript and TypeScript files are
+// scanned line by line, which needs no parser for the language and works
+// the same on every platform.
+func fileSymbols(path string, content []byte) ([]codeSymbol, error) {
+	switch ext := filepath.Ext(path); ext {
+	case ".go":
+		return goSymbols(path, content)
+	case ".py":
+		return pythonSymbols(content), nil
+	case ".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx":
+		return scriptSymbols(content), nil
+	default:
+		return nil, fmt.Errorf("symbol lookup is not supported for %q files; Go, Python, JavaScript and TypeScript are supported", ext)
+	}
+}
+
+func goSymbols(path string, content []byte) ([]codeSymbol, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, content, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	var symbols []codeSymbol
+	add := func(name, kind string, node ast.Node) {
+		start, end := fset.Position(node.Pos()), fset.Position(node.End())
+		symbols = append(symbols, codeSymbol{
+			Name:      name,
+			Kind:      kind,
+			StartLine: start.Line,
+			EndLine:   end.Line,
+			start:     start.Offset,
+			end:       end.Offset,
+		})
+	}
+
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				add(receiverName(decl.Recv.List[0].Type)+"."+decl.Name.Name, "method", decl)
+			} else {
+				add(decl.Name.Name, "function", decl)
+			}
+		case *ast.GenDecl:
+			kind := strings.ToLower(decl.Tok.String())
+			for _, spec := range decl.Specs {
+				// A declaration of its own is replaced with its keyword
+				var node ast.Node = spec
+				if !decl.Lparen.IsValid() {
+					node = decl
+				}
+				switch spec := spec.(type) {
+				case *ast.ImportSpec:
+					importPath, _ := strconv.Unquote(spec.Path.Value)
+					add(importPath, kind, node)
+				case *ast.TypeSpec:
+					add(spec.Name.Name, kind, node)
+				case *ast.ValueSpec:
+					for _, ident := range spec.Names {
+						add(ident.Name, kind, node)
+					}
+				}
+			}
+		}
+	}
+	return symbols, nil
+}
+
+// sourceLines splits content into lines with the offset each starts at.
+type sourceLines struct {
+	text   []string
+	offset []int
+}
+
+func splitSourceLines(content []byte) sourceLines {
+	var lines sourceLines
+	offset := 0
+	for _, line := range strings.SplitAfter(string(content), "\n") {
+		if line == "" {
+			break
+		}
+		lines.text = append(lines.text, strings.TrimRight(line, "\r\n"))
+		lines.offset = append(lines.offset, offset)
+		offset += len(line)
+	}
+	return lines
+}
+
+// symbol returns the symbol from the indentation of line first to the end of
+// line last, both counted from zero.
+func (l sourceLines) symbol(name, kind string, first, last, indent int) codeSymbol {
+	return codeSymbol{
+		Name:      name,
+		Kind:      kind,
+		StartLine: first + 1,
+		EndLine:   last + 1,
+		start:     l.offset[first] + indent,
+		end:       l.offset[last] + len(l.text[last]),
+	}
+}
+
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+var (
+	pythonDef    = regexp.MustCompile(`^(?:async\s+)?def\s+(\w+)`)
+	pythonClass  = regexp.MustCompile(`^class\s+(\w+)`)
+	pythonImport = regexp.MustCompile(`^(?:from\s+([\w.]+)\s+import|import\s+([\w.]+))`)
+)
+
+// pythonSymbols finds top-level imports, functions and classes and the
+// methods of those classes. A block ends before the next line indented no
+// deeper than its first line, and decorators belong to the definition.
+func pythonSymbols(content []byte) []codeSymbol {
+	lines := splitSourceLines(content)
+
+	blockEnd := func(first, indent int) int {
+		last := first
+		for i := first + 1; i < len(lines.text); i++ {
+			if strings.TrimSpace(lines.text[i]) == "" {
+				continue
+			}
+			if indentation(lines.text[i]) <= indent {
+				break
+			}
+			last = i
+		}
+		return last
+	}
+	decorated := func(line, indent int) int {
+		for line > 0 && indentation(lines.text[line-1]) == indent && strings.HasPrefix(strings.TrimSpace(lines.text[line-1]), "@") {
+			line--
+		}
+		return line
+	}
+
+	var symbols []codeSymbol
+	class, classIndent, classEnd := "", -1, -1
+	for i, line := range lines.text {
+		indent := indentation(line)
+		code := strings.TrimSpace(line)
+		if i > classEnd {
+			class = ""
+		}
+
+		if indent == 0 {
+			if match := pythonImport.FindStringSubmatch(code); match != nil {
+				symbols = append(symbols, lines.symbol(match[1]+match[2], "import", i, i, 0))
+			} else if match := pythonDef.FindStringSubmatch(code); match != nil {
+				symbols = append(symbols, lines.symbol(match[1], "function", decorated(i, 0), blockEnd(i, 0), 0))
+			} else if match := pythonClass.FindStringSubmatch(code); match != nil {
+				classEnd = blockEnd(i, 0)
+				class, classIndent = match[1], -1
+				symbols = append(symbols, lines.symbol(class, "class", decorated(i, 0), classEnd, 0))
+			}
+			continue
+		}
+
+		// Methods are the definitions at the indentation of the class body
+		if class != "" && code != "" {
+			if classIndent < 0 {
+				classIndent = indent
+			}
+			if match := pythonDef.FindStringSubmatch(code); match != nil && indent == classIndent {
+				symbols = append(symbols, lines.symbol(class+"."+match[1], "method", decorated(i, indent), blockEnd(i, indent), indent))
+			}
+		}
+	}
+	return symbols
+}
+
+var scriptDeclarations = []struct {
+	kind    string
+	pattern *regexp.Regexp
+	block   bool
+}{
+	{"function", regexp.MustCompile(`^(?:export\s+)?(?:default\s+)?(?:async\s+)?function\s*\*?\s*(\w+)`), true},
+	{"class", regexp.MustCompile(`^(?:export\s+)?(?:default\s+)?(?:abstract\s+)?class\s+(\w+)`), true},
+	{"interface", regexp.MustCompile(`^(?:export\s+)?interface\s+(\w+)`), true},
+	{"enum", regexp.MustCompile(`^(?:export\s+)?(?:const\s+)?enum\s+(\w+)`), true},
+	{"type", regexp.MustCompile(`^(?:export\s+)?type\s+(\w+)`), false},
+	{"variable", regexp.MustCompile(`^(?:export\s+)?(?:const|let|var)\s+(\w+)`), false},
+}
+
+var scriptImport = regexp.MustCompile(`^import\b.*?['"]([^'"]+)['"]`)
+
+// scriptSymbols finds top-level imports and declarations in JavaScript and
+// TypeScript. Declarations start at the beginning of a line; functions,
+// classes, interfaces and enums end at their closing brace and other
+// declarations at a semicolon or line break outside brackets.
+func scriptSymbols(content []byte) []codeSymbol {
+	lines := splitSourceLines(content)
+	source := string(content)
+
+	var symbols []codeSymbol
+	for i := 0; i < len(lines.text); i++ {
+		line := lines.text[i]
+		if indentation(line) > 0 {
+			continue
+		}
+		if match := scriptImport.FindStringSubmatch(line); match != nil {
+			symbols = append(symbols, lines.symbol(match[1], "import", i, i, 0))
+			continue
+		}
+		for _, declaration := range scriptDeclarations {
+			match := declaration.pattern.FindStringSubmatch(line)
+			if match == nil {
+				continue
+			}
+			end := scriptDeclarationEnd(source, lines.offset[i], declaration.block)
+			last := i
+			for last+1 < len(lines.offset) && lines.offset[last+1] < end {
+				last++
+			}
+			symbol := lines.symbol(match[1], declaration.kind, i, last, 0)
+			symbol.end = end
+			symbols = append(symbols, symbol)
+			i = last
+			break
+		}
+	}
+	return symbols
+}
+
+// scriptDeclarationEnd returns the offset just past the declaration starting
+// at start, skipping strings and comments. A block declaration ends with the
+// brace that closes its body.
+func scriptDeclarationEnd(source string, start int, block bool) int {
+	depth, opened := 0, false
+	for i := start; i < len(source); i++ {
+		switch ch := source[i]; ch {
+		case '"', '\'', '`':
+			for i++; i < len(source) && source[i] != ch; i++ {
+				if source[i] == '\\' {
+					i++
+				}
+			}
+		case '/':
+			if strings.HasPrefix(source[i:], "//") {
+				for i < len(source) && source[i] != '\n' {
+					i++
+				}
+				i--
+			} else if strings.HasPrefix(source[i:], "/*") {
+				if end := strings.Index(source[i+2:], "*/"); end >= 0 {
+					i += end + 3
+				} else {
+					return len(source)
+				}
+			}
+		case '{', '(', '[':
+			depth++
+			opened = opened || ch == '{'
+		case '}', ')', ']':
+			depth--
+			if block && opened && depth == 0 && ch == '}' {
+				return i + 1
+			}
+		case ';':
+			if depth == 0 {
+				return i + 1
+			}
+		case '\n':
+			// A line ending in an operator continues on the next one
+			if text := strings.TrimRight(source[start:i], " \t\r"); depth == 0 && !block && !strings.HasSuffix(text, "=") && !strings.HasSuffix(text, ",") {
+				return len(strings.TrimRight(source[:i], "\r"))
+			}
+		}
+	}
+	return len(strings.TrimRight(source, "\r\n"))
+}
+
+// receiverName is the type name of a method receiver, without the pointer or
+// type parameters.
+func receiverName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(expr.X)
+	case *ast.IndexExpr:
+		return receiverName(expr.X)
+	case *ast.IndexListExpr:
+		return receiverName(expr.X)
+	case *ast.Ident:
+		return expr.Name
+	}
+	return ""
+}
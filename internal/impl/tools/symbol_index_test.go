@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// describeSymbols renders symbols as "kind name start-end" for comparison.
+func describeSymbols(symbols []codeSymbol) string {
+	var parts []string
+	for _, symbol := range symbols {
+		parts = append(parts, fmt.Sprintf("%s %s %d-%d", symbol.Kind, symbol.Name, symbol.StartLine, symbol.EndLine))
+	}
+	return strings.Join(parts, "\n")
+}
+
+func TestFileSymbols_Go(t *testing.T) {
+	source := "package app\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nconst Version = \"1\"\n\ntype Server[T any] struct {\n\tname T\n}\n\nfunc (s *Server[T]) Start() {\n\tfmt.Println(os.Args)\n}\n\nfunc main() {}\n"
+
+	symbols, err := fileSymbols("main.go", []byte(source))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := "import fmt 4-4\nimport os 5-5\nconst Version 8-8\ntype Server 10-12\nmethod Server.Start 14-16\nfunction main 18-18"
+	if got := describeSymbols(symbols); got != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestFileSymbols_Python(t *testing.T) {
+	source := "import os\nfrom app.models import User\n\n\n@cache\ndef load(path):\n    return open(path)\n\n\nclass Store(Base):\n    \"\"\"Keeps users.\"\"\"\n\n    def __init__(self):\n        self.users = []\n\n    @property\n    def count(self):\n        return len(self.users)\n"
+
+	symbols, err := fileSymbols("store.py", []byte(source))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := "import os 1-1\nimport app.models 2-2\nfunction load 5-7\nclass Store 10-18\nmethod Store.__init__ 13-14\nmethod Store.count 16-18"
+	if got := describeSymbols(symbols); got != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, got)
+	}
+
+	method, err := findSymbol("store.py", []byte(source), "count")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := source[method.start:method.end]; got != "@property\n    def count(self):\n        return len(self.users)" {
+		t.Errorf("Unexpected method source: %q", got)
+	}
+}
+
+func TestFileSymbols_TypeScript(t *testing.T) {
+	source := "import { useState } from 'react';\n\nexport interface Props {\n  label: string; // closes with }\n}\n\nexport const add = (a: number, b: number) => {\n  return a + b;\n};\n\nconst braces = \"}{\"\n\nexport default class Button extends Base {\n  render() { return `}`; }\n}\n\ntype Id =\n  string | number\n\nfunction noop() {}\n"
+
+	symbols, err := fileSymbols("button.tsx", []byte(source))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := "import react 1-1\ninterface Props 3-5\nvariable add 7-9\nvariable braces 11-11\nclass Button 13-15\ntype Id 17-18\nfunction noop 20-20"
+	if got := describeSymbols(symbols); got != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, got)
+	}
+
+	class, err := findSymbol("button.tsx", []byte(source), "Button")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := source[class.start:class.end]; !strings.HasSuffix(got, "render() { return `}`; }\n}") {
+		t.Errorf("Expected the class to end at its closing brace, got %q", got)
+	}
+}
+
+func TestFileSymbols_Unsupported(t *testing.T) {
+	if _, err := fileSymbols("main.rs", []byte("fn main() {}")); err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("Expected an unsupported language error, got %v", err)
+	}
+}
@@ -0,0 +1,712 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/impl/workspacepath"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultTestTimeout bounds a test run, in seconds.
+	DefaultTestTimeout = 600
+	// DefaultTestOutputLines caps the failure output returned to the model.
+	DefaultTestOutputLines = 50
+)
+
+// TestRunnerTool runs a project's test suite and returns counts, failing
+// test names and the start of the failure output instead of the whole log.
+type TestRunnerTool struct {
+	name          string
+	description   string
+	configuration map[string]string
+	logger        *zap.Logger
+}
+
+// testFailure is a failing test with its output.
+type testFailure struct {
+	Name   string `json:"name"`
+	Output string `json:"output,omitempty"`
+}
+
+// testReport is the structured result of a run.
+type testReport struct {
+	Framework string        `json:"framework"`
+	Command   string        `json:"command"`
+	Status    string        `json:"status"`
+	ExitCode  int           `json:"exit_code"`
+	Passed    int           `json:"passed"`
+	Failed    int           `json:"failed"`
+	Skipped   int           `json:"skipped"`
+	Failures  []testFailure `json:"failures,omitempty"`
+	Output    string        `json:"output,omitempty"`
+	Summary   string        `json:"summary"`
+	Error     string        `json:"error,omitempty"`
+}
+
+func NewTestRunnerTool(name, description string, configuration map[string]string, logger *zap.Logger) *TestRunnerTool {
+	return &TestRunnerTool{
+		name:          name,
+		description:   description,
+		configuration: configuration,
+		logger:        logger,
+	}
+}
+
+func (t *TestRunnerTool) Name() string {
+	return t.name
+}
+
+func (t *TestRunnerTool) Description() string {
+	return t.description
+}
+
+func (t *TestRunnerTool) Configuration() map[string]string {
+	return t.configuration
+}
+
+func (t *TestRunnerTool) UpdateConfiguration(config map[string]string) {
+	t.configuration = config
+}
+
+func (t *TestRunnerTool) WithConfiguration(config map[string]string) entities.Tool {
+	c := *t
+	c.configuration = config
+	return &c
+}
+
+func (t *TestRunnerTool) FullDescription() string {
+	return fmt.Sprintf(`%s
+
+Detects Go (go.mod), Rust (Cargo.toml), Node (package.json) and Python (pytest) projects.
+
+Parameters:
+- command: Test command with extra arguments, such as "go test -v ./pkg/..." (optional). It must start with the configured or detected test command ("go test", "cargo test", "npm test" or "pytest"); the arguments after it are passed as is, not run by the shell
+- filter: Run only matching tests: go test -run, cargo test <filter>, npm test -- -t, pytest -k; appended to an explicit command
+- path: Directory to run in, relative to the workspace (default ".")
+- timeout: Seconds to wait for the run (default %d)
+- max_output_lines: Lines of failure output to return (default %d)
+
+Returns passed/failed/skipped counts, failing test names and failure output. Output from a framework that isn't recognized is returned as is.`, t.Description(), DefaultTestTimeout, DefaultTestOutputLines)
+}
+
+func (t *TestRunnerTool) Schema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"command": map[string]any{
+				"type":        "string",
+				"description": "Test command with extra arguments; it must start with the configured or detected test command",
+			},
+			"filter": map[string]any{
+				"type":        "string",
+				"description": "Run only the tests matching this name or pattern",
+			},
+			"path": map[string]any{
+				"type":        "string",
+				"description": "Directory to run in, relative to the workspace (default \".\")",
+			},
+			"timeout": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("Seconds to wait for the run (default %d)", DefaultTestTimeout),
+			},
+			"max_output_lines": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("Lines of failure output to return (default %d)", DefaultTestOutputLines),
+			},
+		},
+	}
+}
+
+// Mutates reports an explicit command, whose arguments can change what the
+// run writes; detected test suites are run even in plan mode.
+func (t *TestRunnerTool) Mutates(arguments string) bool {
+	var args struct {
+		Command string `json:"command"`
+	}
+	json.Unmarshal([]byte(arguments), &args)
+	return strings.TrimSpace(args.Command) != ""
+}
+
+func (t *TestRunnerTool) workspace() (string, error) {
+	workspace := t.configuration["workspace"]
+	if workspace == "" {
+		var err error
+		workspace, err = os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("could not get current directory: %v", err)
+		}
+	}
+	return workspace, nil
+}
+
+func (t *TestRunnerTool) validatePath(path string) (string, error) {
+	workspace, err := t.workspace()
+	if err != nil {
+		return "", err
+	}
+
+	fullPath, err := workspacepath.Resolve(workspace, path)
+	if err != nil {
+		t.logger.Error("Path is outside workspace", zap.String("path", path), zap.Error(err))
+		return "", err
+	}
+	return fullPath, nil
+}
+
+func testError(message string) (string, error) {
+	data, _ := json.Marshal(testReport{Status: "error", Error: message, Summary: "Failed: " + message})
+	return string(data), nil
+}
+
+func (t *TestRunnerTool) Execute(ctx context.Context, arguments string) (string, error) {
+	t.logger.Debug("Executing test runner", zap.String("arguments", arguments))
+
+	var args struct {
+		Command        string `json:"command"`
+		Filter         string `json:"filter"`
+		Path           string `json:"path"`
+		Timeout        int    `json:"timeout"`
+		MaxOutputLines int    `json:"max_output_lines"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return testError("failed to parse arguments")
+	}
+	if args.Path == "" {
+		args.Path = "."
+	}
+	if args.Timeout <= 0 {
+		args.Timeout = processLimit(t.configuration, "timeout", DefaultTestTimeout)
+	}
+	if args.MaxOutputLines <= 0 {
+		args.MaxOutputLines = DefaultTestOutputLines
+	}
+
+	dir, err := t.validatePath(args.Path)
+	if err != nil {
+		return testError("invalid path: " + err.Error())
+	}
+
+	// The model only adds arguments to the configured or detected command, so
+	// a call can't run anything else through the shell
+	base := t.configuration["command"]
+	framework := ""
+	if base == "" {
+		framework = detectTestFramework(dir)
+		base = testRunnerCommand(framework)
+		if base == "" {
+			return testError("no test framework detected; set the tool's command setting")
+		}
+	}
+	var command string
+	switch {
+	case strings.TrimSpace(args.Command) != "":
+		extra, ok := testArguments(strings.TrimSpace(args.Command), base)
+		if !ok {
+			return testError(fmt.Sprintf("command must be %q followed by its arguments", base))
+		}
+		command = base + extra
+		if args.Filter != "" {
+			command += " " + shellQuote(args.Filter)
+		}
+	case framework != "":
+		command = testCommand(framework, args.Filter)
+	default:
+		command = base
+		if args.Filter != "" {
+			command += " " + shellQuote(args.Filter)
+		}
+	}
+
+	workspace, err := t.workspace()
+	if err != nil {
+		return testError(err.Error())
+	}
+	stdout, stderr, exitCode, status, err := t.run(ctx, command, dir, workspace, time.Duration(args.Timeout)*time.Second)
+	if err != nil {
+		return testError(err.Error())
+	}
+
+	report := parseTestOutput(stdout, stderr, args.MaxOutputLines)
+	report.Command = command
+	report.ExitCode = exitCode
+	report.Status = status
+	report.Summary = report.summarize()
+
+	t.logger.Info("Test run completed",
+		zap.String("command", command),
+		zap.String("status", status),
+		zap.Int("passed", report.Passed),
+		zap.Int("failed", report.Failed))
+	data, err := json.Marshal(report)
+	if err != nil {
+		return testError("failed to marshal response")
+	}
+	return string(data), nil
+}
+
+// run executes command through the shell, inside the configured sandbox.
+func (t *TestRunnerTool) run(ctx context.Context, command, dir, workspace string, timeout time.Duration) (string, string, int, string, error) {
+	argv := []string{"bash", "-c", command}
+	if runtime.GOOS == "windows" {
+		argv = []string{"pwsh", "-Command", command}
+	}
+	if sandbox := t.configuration["sandbox"]; sandbox != "" {
+		wrapped, err := wrapCommand(sandbox, argv, workspace)
+		if err != nil {
+			return "", "", 0, "", err
+		}
+		argv = wrapped
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	cmd := exec.CommandContext(runCtx, argv[0], argv[1:]...)
+	cmd.Dir = dir
+	cmd.WaitDelay = time.Second
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	switch {
+	case ctx.Err() != nil:
+		return stdout.String(), stderr.String(), -1, "canceled", nil
+	case runCtx.Err() != nil:
+		return stdout.String(), stderr.String(), -1, "timeout", nil
+	case err == nil:
+		return stdout.String(), stderr.String(), 0, "passed", nil
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return stdout.String(), stderr.String(), exitErr.ExitCode(), "failed", nil
+	}
+	return "", "", 0, "", fmt.Errorf("failed to run %q: %v", command, err)
+}
+
+// detectTestFramework looks for a project file in dir.
+func detectTestFramework(dir string) string {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+		return err == nil
+	}
+	switch {
+	case exists("go.mod"):
+		return "go"
+	case exists("Cargo.toml"):
+		return "cargo"
+	case exists("package.json"):
+		return "npm"
+	case exists("pytest.ini"), exists("conftest.py"), exists("pyproject.toml"), exists("setup.cfg"), exists("tox.ini"):
+		return "pytest"
+	}
+	return ""
+}
+
+// testCommand is the command that runs a framework's tests, optionally only
+// those matching filter.
+func testCommand(framework, filter string) string {
+	var command string
+	switch framework {
+	case "go":
+		command = "go test -json ./..."
+		if filter != "" {
+			command += " -run " + shellQuote(filter)
+		}
+	case "cargo":
+		command = "cargo test"
+		if filter != "" {
+			command += " " + shellQuote(filter)
+		}
+	case "npm":
+		command = "npm test"
+		if filter != "" {
+			command += " -- -t " + shellQuote(filter)
+		}
+	case "pytest":
+		command = "pytest -rfE"
+		if filter != "" {
+			command += " -k " + shellQuote(filter)
+		}
+	}
+	return command
+}
+
+// testRunnerCommand is the command a call's command has to start with for a
+// detected framework.
+func testRunnerCommand(framework string) string {
+	switch framework {
+	case "go":
+		return "go test"
+	case "cargo":
+		return "cargo test"
+	case "npm":
+		return "npm test"
+	case "pytest":
+		return "pytest"
+	}
+	return ""
+}
+
+// testArguments returns the arguments that follow base in command, each
+// quoted for the shell, or false when command doesn't start with base.
+func testArguments(command, base string) (string, bool) {
+	rest, ok := strings.CutPrefix(command, base)
+	if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+		return "", false
+	}
+	var extra strings.Builder
+	for _, arg := range splitShellArgs(rest) {
+		extra.WriteString(" " + shellQuote(arg))
+	}
+	return extra.String(), true
+}
+
+// shellQuote quotes s as a single argument for the shell commands run in.
+func shellQuote(s string) string {
+	if runtime.GOOS == "windows" {
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func (r *testReport) summarize() string {
+	switch r.Status {
+	case "timeout":
+		return "Tests timed out"
+	case "canceled":
+		return "Test run canceled"
+	}
+	if r.Framework == "" {
+		return fmt.Sprintf("Tests %s (exit code %d); output not recognized", r.Status, r.ExitCode)
+	}
+	summary := fmt.Sprintf("%d passed, %d failed, %d skipped", r.Passed, r.Failed, r.Skipped)
+	if r.Status == "failed" && r.Failed == 0 {
+		summary += fmt.Sprintf(" (exit code %d)", r.ExitCode)
+	}
+	return summary
+}
+
+// parseTestOutput builds a report from the output of a run: go test -json
+// events, or else the first text format recognized. When none is, the last
+// lines of the output are returned.
+func parseTestOutput(stdout, stderr string, maxLines int) testReport {
+	if report, ok := parseGoTestJSON(stdout, stderr, maxLines); ok {
+		return report
+	}
+	combined := stdout
+	if stderr != "" {
+		combined = strings.TrimRight(stdout, "\n") + "\n" + stderr
+	}
+	for _, parser := range []struct {
+		framework string
+		parse     func(string, int) (testReport, bool)
+	}{
+		{"cargo", parseCargoOutput},
+		{"pytest", parsePytestOutput},
+		{"npm", parseJestOutput},
+		{"go", parseGoTestText},
+	} {
+		if report, ok := parser.parse(combined, maxLines); ok {
+			report.Framework = parser.framework
+			return report
+		}
+	}
+	return testReport{Output: lastLines(combined, maxLines)}
+}
+
+// goTestEvent is a line of go test -json output.
+type goTestEvent struct {
+	Action  string `json:"Action"`
+	Package string `json:"Package"`
+	Test    string `json:"Test"`
+	Output  string `json:"Output"`
+}
+
+func parseGoTestJSON(stdout, stderr string, maxLines int) (testReport, bool) {
+	report := testReport{Framework: "go"}
+	outputs := make(map[string][]string)
+	var failed []string
+	var failedPackages []string
+	var raw []string
+	seen := false
+
+	scanner := bufio.NewScanner(strings.NewReader(stdout))
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var event goTestEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil || event.Action == "" {
+			raw = append(raw, scanner.Text())
+			continue
+		}
+		seen = true
+		key := event.Package + " " + event.Test
+		switch event.Action {
+		case "build-output":
+			raw = append(raw, strings.TrimRight(event.Output, "\n"))
+		case "output":
+			line := strings.TrimRight(event.Output, "\n")
+			if !strings.HasPrefix(strings.TrimSpace(line), "=== ") {
+				outputs[key] = append(outputs[key], line)
+			}
+		case "pass":
+			if event.Test != "" {
+				report.Passed++
+			}
+		case "skip":
+			if event.Test != "" {
+				report.Skipped++
+			}
+		case "fail":
+			if event.Test != "" {
+				report.Failed++
+				failed = append(failed, key)
+			} else {
+				failedPackages = append(failedPackages, event.Package)
+			}
+		}
+	}
+	if !seen {
+		return report, false
+	}
+
+	// A failing subtest also fails its parent; report only the subtest
+	budget := maxLines
+	for _, key := range failed {
+		if hasFailedChild(failed, key) {
+			continue
+		}
+		name := key[strings.Index(key, " ")+1:]
+		report.Failures = append(report.Failures, testFailure{Name: name, Output: takeLines(outputs[key], &budget)})
+	}
+	// Packages that failed without a failing test didn't build
+	for _, pkg := range failedPackages {
+		if !hasFailedChild(failed, pkg+" ") {
+			report.Failures = append(report.Failures, testFailure{Name: pkg, Output: takeLines(outputs[pkg+" "], &budget)})
+		}
+	}
+	// Build errors and anything else that isn't a test event
+	if stderr != "" {
+		raw = append(raw, stderr)
+	}
+	if len(report.Failures) > 0 && len(raw) > 0 {
+		report.Output = lastLines(strings.Join(raw, "\n"), maxLines)
+	}
+	return report, true
+}
+
+func hasFailedChild(failed []string, key string) bool {
+	prefix := key
+	if !strings.HasSuffix(prefix, " ") {
+		prefix += "/"
+	}
+	for _, other := range failed {
+		if other != key && strings.HasPrefix(other, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// takeLines joins lines, spending at most *budget of them.
+func takeLines(lines []string, budget *int) string {
+	if *budget <= 0 {
+		return ""
+	}
+	if len(lines) > *budget {
+		lines = lines[:*budget]
+	}
+	*budget -= len(lines)
+	return strings.Join(lines, "\n")
+}
+
+var (
+	goTestResultPattern = regexp.MustCompile(`(?m)^\s*--- (PASS|FAIL|SKIP): (\S+)`)
+	cargoResultPattern  = regexp.MustCompile(`test result: \w+\. (\d+) passed; (\d+) failed; (\d+) ignored`)
+	cargoFailedPattern  = regexp.MustCompile(`(?m)^test (\S+) \.\.\. FAILED`)
+	pytestSummaryLine   = regexp.MustCompile(`(?m)^=+ (.*\d+ (?:passed|failed|skipped|errors?).*) in [\d.]+s.*=+$`)
+	pytestFailedPattern = regexp.MustCompile(`(?m)^(?:FAILED|ERROR) (\S+)`)
+	jestSummaryLine     = regexp.MustCompile(`(?m)^Tests:\s+(.+)$`)
+	jestFailedPattern   = regexp.MustCompile(`(?m)^\s+(?:✕|×) (.+?)(?: \(\d+ ?m?s\))?$`)
+	mochaCountPattern   = regexp.MustCompile(`(?m)^\s+(\d+) (passing|failing|pending)`)
+	countPattern        = regexp.MustCompile(`(\d+) (\w+)`)
+)
+
+func parseGoTestText(output string, maxLines int) (testReport, bool) {
+	matches := goTestResultPattern.FindAllStringSubmatch(output, -1)
+	if len(matches) == 0 {
+		return testReport{}, false
+	}
+	var report testReport
+	for _, m := range matches {
+		switch m[1] {
+		case "PASS":
+			report.Passed++
+		case "FAIL":
+			report.Failed++
+			report.Failures = append(report.Failures, testFailure{Name: m[2]})
+		case "SKIP":
+			report.Skipped++
+		}
+	}
+	report.Output = linesFrom(output, "--- FAIL", maxLines)
+	return report, true
+}
+
+func parseCargoOutput(output string, maxLines int) (testReport, bool) {
+	results := cargoResultPattern.FindAllStringSubmatch(output, -1)
+	if len(results) == 0 {
+		return testReport{}, false
+	}
+	var report testReport
+	for _, m := range results {
+		passed, _ := strconv.Atoi(m[1])
+		failed, _ := strconv.Atoi(m[2])
+		ignored, _ := strconv.Atoi(m[3])
+		report.Passed += passed
+		report.Failed += failed
+		report.Skipped += ignored
+	}
+	for _, m := range cargoFailedPattern.FindAllStringSubmatch(output, -1) {
+		report.Failures = append(report.Failures, testFailure{Name: m[1]})
+	}
+	report.Output = linesFrom(output, "failures:", maxLines)
+	return report, true
+}
+
+func parsePytestOutput(output string, maxLines int) (testReport, bool) {
+	summaries := pytestSummaryLine.FindAllStringSubmatch(output, -1)
+	if len(summaries) == 0 {
+		return testReport{}, false
+	}
+	var report testReport
+	for _, m := range countPattern.FindAllStringSubmatch(summaries[len(summaries)-1][1], -1) {
+		n, _ := strconv.Atoi(m[1])
+		switch m[2] {
+		case "passed", "xpassed":
+			report.Passed += n
+		case "failed", "error", "errors":
+			report.Failed += n
+		case "skipped", "xfailed", "deselected":
+			report.Skipped += n
+		}
+	}
+	for _, m := range pytestFailedPattern.FindAllStringSubmatch(output, -1) {
+		report.Failures = append(report.Failures, testFailure{Name: m[1]})
+	}
+	report.Output = linesFrom(output, "= FAILURES =", maxLines)
+	return report, true
+}
+
+// parseJestOutput reads Jest's and Mocha's summaries, the usual npm test
+// runners.
+func parseJestOutput(output string, maxLines int) (testReport, bool) {
+	var report testReport
+	if summaries := jestSummaryLine.FindAllStringSubmatch(output, -1); len(summaries) > 0 {
+		for _, m := range countPattern.FindAllStringSubmatch(summaries[len(summaries)-1][1], -1) {
+			n, _ := strconv.Atoi(m[1])
+			switch m[2] {
+			case "passed":
+				report.Passed += n
+			case "failed":
+				report.Failed += n
+			case "skipped", "todo":
+				report.Skipped += n
+			}
+		}
+	} else if counts := mochaCountPattern.FindAllStringSubmatch(output, -1); len(counts) > 0 {
+		for _, m := range counts {
+			n, _ := strconv.Atoi(m[1])
+			switch m[2] {
+			case "passing":
+				report.Passed += n
+			case "failing":
+				report.Failed += n
+			case "pending":
+				report.Skipped += n
+			}
+		}
+	} else {
+		return report, false
+	}
+	for _, m := range jestFailedPattern.FindAllStringSubmatch(output, -1) {
+		report.Failures = append(report.Failures, testFailure{Name: m[1]})
+	}
+	report.Output = linesFrom(output, "●", maxLines)
+	return report, true
+}
+
+// linesFrom returns up to maxLines lines of output starting at the first
+// line containing marker, or nothing when marker doesn't occur.
+func linesFrom(output, marker string, maxLines int) string {
+	i := strings.Index(output, marker)
+	if i == -1 {
+		return ""
+	}
+	start := strings.LastIndex(output[:i], "\n") + 1
+	lines := strings.Split(output[start:], "\n")
+	if len(lines) > maxLines {
+		lines = lines[:maxLines]
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+// lastLines returns the last maxLines lines of output.
+func lastLines(output string, maxLines int) string {
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if len(lines) > maxLines {
+		lines = lines[len(lines)-maxLines:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (t *TestRunnerTool) DisplayName(ui string, arguments string) (string, string) {
+	var args struct {
+		Command string `json:"command"`
+		Filter  string `json:"filter"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err == nil {
+		detail := args.Command
+		if args.Filter != "" {
+			detail = strings.TrimSpace(detail + " " + args.Filter)
+		}
+		return t.Name(), detail
+	}
+	return t.Name(), ""
+}
+
+func (t *TestRunnerTool) FormatResult(ui string, result string, diff string, arguments string) string {
+	var report testReport
+	if err := json.Unmarshal([]byte(result), &report); err != nil {
+		return result
+	}
+
+	var sb strings.Builder
+	sb.WriteString(report.Summary)
+	for _, failure := range report.Failures {
+		sb.WriteString("\n✗ " + failure.Name)
+	}
+	if ui == "webui" {
+		return fmt.Sprintf("<div class=\"tool-summary\">%s</div>", strings.ReplaceAll(html.EscapeString(sb.String()), "\n", "<br>"))
+	}
+	return sb.String()
+}
+
+var _ entities.Tool = (*TestRunnerTool)(nil)
+var _ entities.MutatingTool = (*TestRunnerTool)(nil)
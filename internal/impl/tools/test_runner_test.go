@@ -0,0 +1,201 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestParseGoTestJSON(t *testing.T) {
+	stdout := strings.Join([]string{
+		`{"Action":"run","Package":"example/pkg","Test":"TestAdd"}`,
+		`{"Action":"pass","Package":"example/pkg","Test":"TestAdd"}`,
+		`{"Action":"run","Package":"example/pkg","Test":"TestSub"}`,
+		`{"Action":"output","Package":"example/pkg","Test":"TestSub/negative","Output":"    sub_test.go:12: got 1, want -1\n"}`,
+		`{"Action":"fail","Package":"example/pkg","Test":"TestSub/negative"}`,
+		`{"Action":"fail","Package":"example/pkg","Test":"TestSub"}`,
+		`{"Action":"skip","Package":"example/pkg","Test":"TestSlow"}`,
+		`{"Action":"fail","Package":"example/pkg"}`,
+	}, "\n")
+
+	report := parseTestOutput(stdout, "", 50)
+	if report.Framework != "go" || report.Passed != 1 || report.Failed != 2 || report.Skipped != 1 {
+		t.Fatalf("unexpected counts: %+v", report)
+	}
+	if len(report.Failures) != 1 || report.Failures[0].Name != "TestSub/negative" {
+		t.Fatalf("expected only the failing subtest, got %+v", report.Failures)
+	}
+	if !strings.Contains(report.Failures[0].Output, "got 1, want -1") {
+		t.Errorf("expected the failure output, got %q", report.Failures[0].Output)
+	}
+}
+
+func TestParseGoTestJSONBuildFailure(t *testing.T) {
+	stdout := strings.Join([]string{
+		`{"Action":"build-output","ImportPath":"example/pkg","Output":"pkg/add.go:3:1: syntax error\n"}`,
+		`{"Action":"fail","Package":"example/pkg"}`,
+	}, "\n")
+
+	report := parseTestOutput(stdout, "", 50)
+	if len(report.Failures) != 1 || report.Failures[0].Name != "example/pkg" {
+		t.Fatalf("expected the package to fail, got %+v", report.Failures)
+	}
+	if !strings.Contains(report.Output, "syntax error") {
+		t.Errorf("expected the build output, got %q", report.Output)
+	}
+}
+
+func TestParseTestOutputText(t *testing.T) {
+	tests := []struct {
+		name      string
+		output    string
+		framework string
+		passed    int
+		failed    int
+		skipped   int
+		failure   string
+	}{
+		{
+			name:      "cargo",
+			output:    "running 3 tests\ntest tests::adds ... ok\ntest tests::subs ... FAILED\ntest tests::slow ... ignored\n\nfailures:\n\n---- tests::subs stdout ----\nassertion failed\n\ntest result: FAILED. 1 passed; 1 failed; 1 ignored; 0 measured; 0 filtered out\n",
+			framework: "cargo",
+			passed:    1, failed: 1, skipped: 1,
+			failure: "tests::subs",
+		},
+		{
+			name:      "pytest",
+			output:    "collected 4 items\n\n=================================== FAILURES ===================================\n___ test_sub ___\nassert 1 == -1\n=========================== short test summary info ============================\nFAILED tests/test_math.py::test_sub - assert 1 == -1\n==================== 1 failed, 2 passed, 1 skipped in 0.12s ====================\n",
+			framework: "pytest",
+			passed:    2, failed: 1, skipped: 1,
+			failure: "tests/test_math.py::test_sub",
+		},
+		{
+			name:      "jest",
+			output:    " FAIL  src/math.test.js\n  math\n    ✓ adds (2 ms)\n    ✕ subtracts (3 ms)\n\n  ● math › subtracts\n\n    expect(received).toBe(expected)\n\nTests:       1 failed, 1 passed, 2 total\n",
+			framework: "npm",
+			passed:    1, failed: 1,
+			failure: "subtracts",
+		},
+		{
+			name:      "mocha",
+			output:    "  math\n    ✓ adds\n\n  3 passing (12ms)\n  1 pending\n",
+			framework: "npm",
+			passed:    3, skipped: 1,
+		},
+		{
+			name:      "go text",
+			output:    "--- FAIL: TestSub (0.00s)\n    sub_test.go:12: got 1\nFAIL\n",
+			framework: "go",
+			failed:    1,
+			failure:   "TestSub",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := parseTestOutput(tt.output, "", 50)
+			if report.Framework != tt.framework {
+				t.Errorf("framework = %q, want %q", report.Framework, tt.framework)
+			}
+			if report.Passed != tt.passed || report.Failed != tt.failed || report.Skipped != tt.skipped {
+				t.Errorf("counts = %d/%d/%d, want %d/%d/%d", report.Passed, report.Failed, report.Skipped, tt.passed, tt.failed, tt.skipped)
+			}
+			if tt.failure != "" && (len(report.Failures) == 0 || report.Failures[0].Name != tt.failure) {
+				t.Errorf("failures = %+v, want %s", report.Failures, tt.failure)
+			}
+		})
+	}
+}
+
+func TestParseTestOutputUnrecognized(t *testing.T) {
+	var lines []string
+	for i := 0; i < 100; i++ {
+		lines = append(lines, "line")
+	}
+	lines = append(lines, "something broke")
+	report := parseTestOutput(strings.Join(lines, "\n"), "", 10)
+	if report.Framework != "" {
+		t.Errorf("expected no framework, got %q", report.Framework)
+	}
+	if got := strings.Split(report.Output, "\n"); len(got) != 10 || got[9] != "something broke" {
+		t.Errorf("expected the last 10 lines, got %q", report.Output)
+	}
+}
+
+func TestDetectTestFramework(t *testing.T) {
+	tests := map[string]string{
+		"go.mod":         "go",
+		"Cargo.toml":     "cargo",
+		"package.json":   "npm",
+		"pyproject.toml": "pytest",
+		"README.md":      "",
+	}
+	for file, want := range tests {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, file), []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got := detectTestFramework(dir); got != want {
+			t.Errorf("detectTestFramework(%s) = %q, want %q", file, got, want)
+		}
+	}
+	if got := testCommand("go", "TestSub"); got != "go test -json ./... -run 'TestSub'" {
+		t.Errorf("unexpected go command: %s", got)
+	}
+}
+
+func TestTestRunnerMutates(t *testing.T) {
+	tool := NewTestRunnerTool("TestRunner", "", map[string]string{}, zap.NewNop())
+	if tool.Mutates(`{"filter": "TestSub"}`) {
+		t.Errorf("expected a detected test run not to mutate")
+	}
+	if !tool.Mutates(`{"command": "make test"}`) {
+		t.Errorf("expected an explicit command to mutate")
+	}
+}
+
+func TestTestRunnerCommandArguments(t *testing.T) {
+	tests := []struct {
+		command string
+		base    string
+		want    string
+		ok      bool
+	}{
+		{"go test", "go test", "", true},
+		{"go test -v ./pkg/...", "go test", " '-v' './pkg/...'", true},
+		{"go test -run 'A|B'", "go test", " '-run' 'A|B'", true},
+		{"go test ./...; rm -rf x", "go test", " './...;' 'rm' '-rf' 'x'", true},
+		{"go test $(rm -rf x)", "go test", " '$(rm' '-rf' 'x)'", true},
+		{"go testing", "go test", "", false},
+		{"rm -rf x", "go test", "", false},
+		{"make test", "make test", "", true},
+	}
+	for _, tt := range tests {
+		got, ok := testArguments(tt.command, tt.base)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("testArguments(%q, %q) = %q, %v, want %q, %v", tt.command, tt.base, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestTestRunnerRefusesOtherCommands(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tool := NewTestRunnerTool("TestRunner", "", map[string]string{"workspace": dir}, zap.NewNop())
+
+	result, err := tool.Execute(context.Background(), `{"command": "touch pwned"}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(result, `\"go test\" followed by its arguments`) {
+		t.Errorf("Expected the command to be refused, got %s", result)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "pwned")); err == nil {
+		t.Error("Expected the refused command not to run")
+	}
+}
@@ -8,14 +8,18 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/domain/events"
 
 	"go.uber.org/zap"
 )
 
 type TodoItem struct {
+	// ID stays with the todo for the life of the list
+	ID        int       `json:"id"`
 	Content   string    `json:"content"`
 	Status    string    `json:"status"`
 	Priority  string    `json:"priority"`
@@ -24,7 +28,8 @@ type TodoItem struct {
 }
 
 type TodoList struct {
-	Todos []TodoItem `json:"todos"`
+	Todos  []TodoItem `json:"todos"`
+	NextID int        `json:"next_id"`
 }
 
 type TodoTool struct {
@@ -32,6 +37,9 @@ type TodoTool struct {
 	description   string
 	configuration map[string]string
 	logger        *zap.Logger
+	// mu serializes changes, since calls of a batch may run concurrently.
+	// Copies made for a run share it.
+	mu *sync.Mutex
 }
 
 func NewTodoTool(name, description string, configuration map[string]string, logger *zap.Logger) *TodoTool {
@@ -40,6 +48,7 @@ func NewTodoTool(name, description string, configuration map[string]string, logg
 		description:   description,
 		configuration: configuration,
 		logger:        logger,
+		mu:            &sync.Mutex{},
 	}
 }
 
@@ -59,6 +68,12 @@ func (t *TodoTool) UpdateConfiguration(config map[string]string) {
 	t.configuration = config
 }
 
+func (t *TodoTool) WithConfiguration(config map[string]string) entities.Tool {
+	c := *t
+	c.configuration = config
+	return &c
+}
+
 func (t *TodoTool) FullDescription() string {
 	var b strings.Builder
 	b.WriteString(t.Description())
@@ -66,7 +81,9 @@ func (t *TodoTool) FullDescription() string {
 	b.WriteString("## Usage Instructions\n")
 	b.WriteString("This tool manages a structured task list for complex tasks. It supports creating, reading, updating, and managing tasks with statuses and workflow grouping.\n")
 	b.WriteString("Tasks can have statuses: pending, in_progress, completed, cancelled\n")
-	b.WriteString("Workflows: Use workflow_id to group related tasks (e.g., 'auth-feature', 'user-registration')\n")
+	b.WriteString("Actions: add (alias write) appends todos, list (alias read) shows them, complete marks one completed, update_status sets any status, clear deletes them all.\n")
+	b.WriteString("Each todo has an id that never changes; use it with complete and update_status.\n")
+	b.WriteString("The list is kept per chat in .aiagent and survives across messages and restarts.\n")
 	b.WriteString("\nPer-session support: session_id is required and auto-injected by the chat service using current chat.ID.\n")
 	b.WriteString("LLMs do not need to provide it. Clear: Use action='clear' to delete all todos for the session.\n")
 	b.WriteString("\n## Configuration\n")
@@ -84,20 +101,24 @@ func (t *TodoTool) Schema() map[string]any {
 		"properties": map[string]any{
 			"action": map[string]any{
 				"type":        "string",
-				"description": "The action to perform: write (create todos), read (list todos), update_status (update a todo status), clear (delete all todos)",
-				"enum":        []string{"write", "read", "update_status", "clear"},
+				"description": "The action to perform: add (append todos), list (show todos), complete (mark a todo completed), update_status (set a todo's status), clear (delete all todos). write and read are aliases of add and list.",
+				"enum":        []string{"add", "list", "complete", "update_status", "clear", "write", "read"},
 			},
 			"todos": map[string]any{
 				"type":        "array",
-				"description": "The updated todo list (required for write action)",
+				"description": "The todos to append (required for add)",
 				"items": map[string]any{
 					"type":        "string",
 					"description": "Brief description of the task",
 				},
 			},
+			"id": map[string]any{
+				"type":        "integer",
+				"description": "For complete and update_status: the id of the todo",
+			},
 			"index": map[string]any{
 				"type":        "integer",
-				"description": "For update_status: the 1-based index of the todo to update",
+				"description": "For update_status: the 1-based position of the todo, when no id is given",
 			},
 			"status": map[string]any{
 				"type":        "string",
@@ -134,9 +155,41 @@ func (t *TodoTool) loadTodos(sessionID string) (*TodoList, error) {
 		return nil, err
 	}
 
+	// Todos are kept in the order they were added, and lists saved before
+	// todos had IDs are numbered in that order
+	sort.SliceStable(todoList.Todos, func(i, j int) bool {
+		return todoList.Todos[i].CreatedAt.Before(todoList.Todos[j].CreatedAt)
+	})
+	for _, todo := range todoList.Todos {
+		todoList.NextID = max(todoList.NextID, todo.ID+1)
+	}
+	for i := range todoList.Todos {
+		if todoList.Todos[i].ID == 0 {
+			todoList.NextID = max(todoList.NextID, 1)
+			todoList.Todos[i].ID = todoList.NextID
+			todoList.NextID++
+		}
+	}
+
 	return &todoList, nil
 }
 
+// Plan returns the todo list of the chat as plan progress, so a ui can show
+// it when the chat is opened. It is nil when the chat has no todos.
+func (t *TodoTool) Plan(chatID string) (*entities.ProgressEvent, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	todoList, err := t.loadTodos(chatID)
+	if err != nil {
+		return nil, err
+	}
+	if len(todoList.Todos) == 0 {
+		return nil, nil
+	}
+	return todoProgress(chatID, todoList), nil
+}
+
 func (t *TodoTool) saveTodos(sessionID string, todoList *TodoList) error {
 	path := t.getTodoFilePath(sessionID)
 	dir := filepath.Dir(path)
@@ -149,7 +202,25 @@ func (t *TodoTool) saveTodos(sessionID string, todoList *TodoList) error {
 		return err
 	}
 
-	return os.WriteFile(path, data, 0644)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+
+	t.publishProgress(sessionID, todoList)
+	return nil
+}
+
+// publishProgress reports the todo list as the plan progress for the session.
+func (t *TodoTool) publishProgress(sessionID string, todoList *TodoList) {
+	events.PublishProgressEvent(todoProgress(sessionID, todoList))
+}
+
+func todoProgress(sessionID string, todoList *TodoList) *entities.ProgressEvent {
+	items := make([]entities.ProgressItem, 0, len(todoList.Todos))
+	for _, todo := range todoList.Todos {
+		items = append(items, entities.ProgressItem{Content: todo.Content, Status: todo.Status})
+	}
+	return entities.NewProgressEvent(sessionID, items)
 }
 
 func (t *TodoTool) Execute(ctx context.Context, arguments string) (string, error) {
@@ -157,6 +228,7 @@ func (t *TodoTool) Execute(ctx context.Context, arguments string) (string, error
 	var args struct {
 		Action    string   `json:"action,omitempty"`
 		Todos     []string `json:"todos"`
+		ID        int      `json:"id,omitempty"`
 		Index     int      `json:"index,omitempty"`
 		Status    string   `json:"status,omitempty"`
 		SessionID string   `json:"session_id"`
@@ -175,21 +247,29 @@ func (t *TodoTool) Execute(ctx context.Context, arguments string) (string, error
 	action := args.Action
 	if action == "" {
 		if len(args.Todos) > 0 {
-			action = "write"
-		} else if args.Index != 0 && args.Status != "" {
+			action = "add"
+		} else if (args.ID != 0 || args.Index != 0) && args.Status != "" {
 			action = "update_status"
 		} else {
-			action = "read"
+			action = "list"
 		}
 	}
 
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
 	switch action {
-	case "write":
-		return t.writeTodos(sessionID, args.Todos)
-	case "read":
-		return t.readTodos(sessionID)
+	case "add", "write":
+		return t.addTodos(sessionID, args.Todos)
+	case "list", "read":
+		return t.listTodos(sessionID)
+	case "complete":
+		if args.ID == 0 {
+			return "", fmt.Errorf("id is required for complete")
+		}
+		return t.updateStatus(sessionID, args.ID, 0, "completed")
 	case "update_status":
-		return t.updateStatus(sessionID, args.Index, args.Status)
+		return t.updateStatus(sessionID, args.ID, args.Index, args.Status)
 	case "clear":
 		return t.clearTodos(sessionID)
 	default:
@@ -197,14 +277,16 @@ func (t *TodoTool) Execute(ctx context.Context, arguments string) (string, error
 	}
 }
 
-func (t *TodoTool) writeTodos(sessionID string, todos []string) (string, error) {
+func (t *TodoTool) addTodos(sessionID string, todos []string) (string, error) {
 	todoList, err := t.loadTodos(sessionID)
 	if err != nil {
 		return "", err
 	}
 
+	todoList.NextID = max(todoList.NextID, 1)
 	for _, content := range todos {
 		newTodo := TodoItem{
+			ID:        todoList.NextID,
 			Content:   content,
 			Status:    "pending",
 			Priority:  "medium",
@@ -212,87 +294,59 @@ func (t *TodoTool) writeTodos(sessionID string, todos []string) (string, error)
 			UpdatedAt: time.Now(),
 		}
 		todoList.Todos = append(todoList.Todos, newTodo)
+		todoList.NextID++
 	}
 
 	if err := t.saveTodos(sessionID, todoList); err != nil {
 		return "", err
 	}
 
-	// Sort todos by CreatedAt ascending
-	sort.Slice(todoList.Todos, func(i, j int) bool {
-		return todoList.Todos[i].CreatedAt.Before(todoList.Todos[j].CreatedAt)
-	})
-
-	// Build the response with action message and full task plan
-	var result strings.Builder
-	result.WriteString(fmt.Sprintf("Added %d todos to the list\n\n", len(todos)))
-
-	// Add the full task plan
-	if len(todoList.Todos) > 0 {
-		result.WriteString("📋 Task Plan:\n\n")
-		for i, todo := range todoList.Todos {
-			checkbox := ""
-			switch todo.Status {
-			case "pending":
-				checkbox = "- [ ]"
-			case "in_progress":
-				checkbox = "- [🔄]"
-			case "completed":
-				checkbox = "- [x]"
-			case "cancelled":
-				checkbox = "- [❌]"
-			}
-
-			result.WriteString(fmt.Sprintf("%d. %s %s\n", i+1, checkbox, todo.Content))
-		}
-	}
-
-	// Return JSON with summary and todos
-	jsonResult, _ := json.Marshal(map[string]interface{}{
-		"summary": result.String(),
-		"todos":   todoList.Todos,
-	})
-
-	return string(jsonResult), nil
+	return todoResult(fmt.Sprintf("Added %d todos to the list\n\n", len(todos)), todoList), nil
 }
 
-func (t *TodoTool) readTodos(sessionID string) (string, error) {
+func (t *TodoTool) listTodos(sessionID string) (string, error) {
 	todoList, err := t.loadTodos(sessionID)
 	if err != nil {
 		return "", err
 	}
 
 	if len(todoList.Todos) == 0 {
-		jsonResult, _ := json.Marshal(map[string]interface{}{
-			"summary": "No todos found",
-			"todos":   []TodoItem{},
-		})
-		return string(jsonResult), nil
+		return todoResult("No todos found", todoList), nil
 	}
+	return todoResult("", todoList), nil
+}
 
-	// Sort todos by CreatedAt ascending
-	sort.Slice(todoList.Todos, func(i, j int) bool {
-		return todoList.Todos[i].CreatedAt.Before(todoList.Todos[j].CreatedAt)
-	})
-
+// todoResult is the JSON returned for every action: a summary for the model
+// and the todos with their completion state for the ui.
+func todoResult(message string, todoList *TodoList) string {
 	var result strings.Builder
-	result.WriteString("📋 Task Plan:\n\n")
+	result.WriteString(message)
 
-	// Display todos without workflow grouping
-	for i, todo := range todoList.Todos {
-		result.WriteString(t.formatTodo(i+1, todo))
+	completed := 0
+	if len(todoList.Todos) > 0 {
+		result.WriteString("📋 Task Plan:\n\n")
+		for _, todo := range todoList.Todos {
+			result.WriteString(formatTodo(todo))
+			if todo.Status == "completed" {
+				completed++
+			}
+		}
 	}
 
-	// JSON for AI processing
+	todos := todoList.Todos
+	if todos == nil {
+		todos = []TodoItem{}
+	}
 	jsonResult, _ := json.Marshal(map[string]interface{}{
-		"summary": result.String(),
-		"todos":   todoList.Todos,
+		"summary":   result.String(),
+		"todos":     todos,
+		"completed": completed,
+		"total":     len(todos),
 	})
-
-	return string(jsonResult), nil
+	return string(jsonResult)
 }
 
-func (t *TodoTool) formatTodo(index int, todo TodoItem) string {
+func formatTodo(todo TodoItem) string {
 	checkbox := ""
 	switch todo.Status {
 	case "pending":
@@ -305,73 +359,62 @@ func (t *TodoTool) formatTodo(index int, todo TodoItem) string {
 		checkbox = "- [❌]"
 	}
 
-	return fmt.Sprintf("%d. %s %s\n", index, checkbox, todo.Content)
+	return fmt.Sprintf("%d. %s %s\n", todo.ID, checkbox, todo.Content)
 }
 
-func (t *TodoTool) updateStatus(sessionID string, index int, status string) (string, error) {
+// updateStatus sets the status of the todo with the ID or, without one, at the
+// 1-based index.
+func (t *TodoTool) updateStatus(sessionID string, id, index int, status string) (string, error) {
+	switch status {
+	case "pending", "in_progress", "completed", "cancelled":
+	default:
+		return "", fmt.Errorf("invalid status %q, must be pending, in_progress, completed or cancelled", status)
+	}
+
 	todoList, err := t.loadTodos(sessionID)
 	if err != nil {
 		return "", err
 	}
 
-	// Sort todos by CreatedAt ascending
-	sort.Slice(todoList.Todos, func(i, j int) bool {
-		return todoList.Todos[i].CreatedAt.Before(todoList.Todos[j].CreatedAt)
-	})
-
-	if index < 1 || index > len(todoList.Todos) {
-		return "", fmt.Errorf("invalid index %d, must be between 1 and %d", index, len(todoList.Todos))
+	position := -1
+	if id != 0 {
+		for i, todo := range todoList.Todos {
+			if todo.ID == id {
+				position = i
+				break
+			}
+		}
+		if position < 0 {
+			return "", fmt.Errorf("no todo with id %d", id)
+		}
+	} else {
+		if index < 1 || index > len(todoList.Todos) {
+			return "", fmt.Errorf("invalid index %d, must be between 1 and %d", index, len(todoList.Todos))
+		}
+		position = index - 1
 	}
 
-	todoList.Todos[index-1].Status = status
-	todoList.Todos[index-1].UpdatedAt = time.Now()
+	todo := &todoList.Todos[position]
+	todo.Status = status
+	todo.UpdatedAt = time.Now()
 
 	if err := t.saveTodos(sessionID, todoList); err != nil {
 		return "", err
 	}
 
-	// Build the response
-	var result strings.Builder
-	result.WriteString(fmt.Sprintf("Updated todo at index %d to status %s\n\n", index, status))
-
-	// Add the full task plan
-	if len(todoList.Todos) > 0 {
-		result.WriteString("📋 Task Plan:\n\n")
-		for i, todo := range todoList.Todos {
-			checkbox := ""
-			switch todo.Status {
-			case "pending":
-				checkbox = "- [ ]"
-			case "in_progress":
-				checkbox = "- [🔄]"
-			case "completed":
-				checkbox = "- [x]"
-			case "cancelled":
-				checkbox = "- [❌]"
-			}
-
-			result.WriteString(fmt.Sprintf("%d. %s %s\n", i+1, checkbox, todo.Content))
-		}
-	}
-
-	// Return JSON with summary and todos
-	jsonResult, _ := json.Marshal(map[string]interface{}{
-		"summary": result.String(),
-		"todos":   todoList.Todos,
-	})
-
-	return string(jsonResult), nil
+	return todoResult(fmt.Sprintf("Updated todo %d to status %s\n\n", todo.ID, status), todoList), nil
 }
 
 func (t *TodoTool) clearTodos(sessionID string) (string, error) {
 	path := t.getTodoFilePath(sessionID)
 	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return `{"summary": "No todos found for this session."}`, nil
+		return todoResult("No todos found for this session.", &TodoList{}), nil
 	}
 	if err := os.Remove(path); err != nil {
 		return "", err
 	}
-	return `{"summary": "Cleared all todos for this session."}`, nil
+	t.publishProgress(sessionID, &TodoList{})
+	return todoResult("Cleared all todos for this session.", &TodoList{}), nil
 }
 
 func (t *TodoTool) DisplayName(ui string, arguments string) (string, string) {
@@ -395,3 +438,4 @@ func (t *TodoTool) FormatResult(ui string, result string, diff string, arguments
 }
 
 var _ entities.Tool = (*TodoTool)(nil)
+var _ entities.PlanTool = (*TodoTool)(nil)
@@ -196,3 +196,86 @@ func TestTodoTool_UpdateStatusInvalidIndex(t *testing.T) {
 	// Cleanup
 	os.Remove(filepath.Join(wd, ".aiagent", "todos_"+session+".json"))
 }
+
+func TestTodoTool_StableIDs(t *testing.T) {
+	workspace := t.TempDir()
+	tool := NewTodoTool("Todo", "test", map[string]string{"workspace": workspace}, zap.NewNop())
+	session := "test-ids-session"
+
+	if _, err := tool.Execute(context.Background(), `{"action": "add", "todos": ["Design", "Build"], "session_id": "`+session+`"}`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tool.Execute(context.Background(), `{"action": "add", "todos": ["Test"], "session_id": "`+session+`"}`); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := tool.Execute(context.Background(), `{"action": "complete", "id": 2, "session_id": "`+session+`"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var response struct {
+		Todos     []TodoItem `json:"todos"`
+		Completed int        `json:"completed"`
+		Total     int        `json:"total"`
+	}
+	if err := json.Unmarshal([]byte(result), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Completed != 1 || response.Total != 3 {
+		t.Errorf("Expected 1 of 3 completed, got %d of %d", response.Completed, response.Total)
+	}
+	for i, todo := range response.Todos {
+		if todo.ID != i+1 {
+			t.Errorf("Expected todo %q to have id %d, got %d", todo.Content, i+1, todo.ID)
+		}
+	}
+	if response.Todos[1].Content != "Build" || response.Todos[1].Status != "completed" {
+		t.Errorf("Expected Build to be completed, got %+v", response.Todos[1])
+	}
+
+	if _, err := tool.Execute(context.Background(), `{"action": "complete", "id": 9, "session_id": "`+session+`"}`); err == nil {
+		t.Error("Expected an error for an unknown id")
+	}
+	if _, err := tool.Execute(context.Background(), `{"action": "update_status", "id": 1, "status": "done", "session_id": "`+session+`"}`); err == nil {
+		t.Error("Expected an error for an unknown status")
+	}
+
+	plan, err := tool.Plan(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan == nil || plan.Completed != 1 || plan.Total != 3 || plan.Current != "Design" {
+		t.Errorf("Unexpected plan: %+v", plan)
+	}
+	if plan, _ := tool.Plan("no-todos"); plan != nil {
+		t.Errorf("Expected no plan for a chat without todos, got %+v", plan)
+	}
+}
+
+func TestTodoTool_NumbersOldLists(t *testing.T) {
+	workspace := t.TempDir()
+	tool := NewTodoTool("Todo", "test", map[string]string{"workspace": workspace}, zap.NewNop())
+	session := "test-old-session"
+
+	path := tool.getTodoFilePath(session)
+	os.MkdirAll(filepath.Dir(path), 0755)
+	os.WriteFile(path, []byte(`{"todos": [
+		{"content": "Second", "status": "pending", "created_at": "2025-01-02T00:00:00Z"},
+		{"content": "First", "status": "completed", "created_at": "2025-01-01T00:00:00Z"}
+	]}`), 0644)
+
+	result, err := tool.Execute(context.Background(), `{"action": "add", "todos": ["Third"], "session_id": "`+session+`"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var response struct {
+		Todos []TodoItem `json:"todos"`
+	}
+	json.Unmarshal([]byte(result), &response)
+	want := []string{"First", "Second", "Third"}
+	for i, todo := range response.Todos {
+		if todo.Content != want[i] || todo.ID != i+1 {
+			t.Errorf("Expected %s with id %d, got %s with id %d", want[i], i+1, todo.Content, todo.ID)
+		}
+	}
+}
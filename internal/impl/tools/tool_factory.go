@@ -1,6 +1,8 @@
 package tools
 
 import (
+	"slices"
+
 	"github.com/drujensen/aiagent/internal/domain/entities"
 	errors "github.com/drujensen/aiagent/internal/domain/errs"
 	"github.com/drujensen/aiagent/internal/domain/services"
@@ -12,7 +14,7 @@ type ToolFactoryEntry struct {
 	Name        string
 	Description string
 	ConfigKeys  []string
-	Factory     func(name, description string, configuration map[string]string, logger *zap.Logger) entities.Tool
+	Factory     ToolConstructor
 }
 
 type ToolFactory struct {
@@ -20,6 +22,9 @@ type ToolFactory struct {
 	chatService   services.ChatService
 	agentService  services.AgentService
 	modelService  services.ModelService
+	// storage and mongoURI are what the application stores its data in
+	storage  string
+	mongoURI string
 }
 
 // SetServices wires the application services into the factory so that
@@ -31,95 +36,138 @@ func (t *ToolFactory) SetServices(chatService services.ChatService, agentService
 	t.modelService = modelService
 }
 
+// SetStorage tells the factory the storage the application runs with, which
+// tools that keep their own data, like Memory, default to. Call this in main
+// before the tool repository creates the tools.
+func (t *ToolFactory) SetStorage(storage, mongoURI string) {
+	t.storage = storage
+	t.mongoURI = mongoURI
+}
+
 func (t *ToolFactory) GetChatService() services.ChatService   { return t.chatService }
 func (t *ToolFactory) GetAgentService() services.AgentService { return t.agentService }
 func (t *ToolFactory) GetModelService() services.ModelService { return t.modelService }
 
+// NewToolFactory creates a factory for the built-in tool types and those
+// added with RegisterToolType.
 func NewToolFactory() (*ToolFactory, error) {
 	toolFactory := &ToolFactory{}
-	toolFactory.toolFactories = make(map[string]*ToolFactoryEntry)
+	toolFactory.toolFactories = builtinToolFactories(toolFactory)
+	for name, entry := range customToolFactories() {
+		toolFactory.toolFactories[name] = entry
+	}
 
-	toolFactory.toolFactories["Bash"] = &ToolFactoryEntry{
+	// Every tool can be given a timeout in seconds for each call
+	for _, entry := range toolFactory.toolFactories {
+		if !slices.Contains(entry.ConfigKeys, "tool_timeout") {
+			entry.ConfigKeys = append(entry.ConfigKeys, "tool_timeout")
+		}
+	}
+	return toolFactory, nil
+}
+
+// builtinToolFactories lists the tool types that ship with aiagent. Tools
+// that call back into the application, like Agent, get it from toolFactory.
+func builtinToolFactories(toolFactory *ToolFactory) map[string]*ToolFactoryEntry {
+	toolFactories := make(map[string]*ToolFactoryEntry)
+
+	toolFactories["Bash"] = &ToolFactoryEntry{
 		Name:        "Bash",
 		Description: `This tool executes a configured CLI command with support for background processes, timeouts, and full output. The command is executed in the workspace directory. The extraArgs are prepended with the arguments passed to the tool.`,
-		ConfigKeys:  []string{"workspace", "command", "extraArgs"},
+		ConfigKeys:  []string{"workspace", "command", "extraArgs", "max_background_processes", "max_background_per_chat", "shutdown_grace", "sandbox", "allowed_commands", "denied_commands"},
 		Factory: func(name, description string, configuration map[string]string, logger *zap.Logger) entities.Tool {
 			return NewProcessTool(name, description, configuration, logger)
 		},
 	}
-	toolFactory.toolFactories["Grep"] = &ToolFactoryEntry{
+	toolFactories["TestRunner"] = &ToolFactoryEntry{
+		Name:        "TestRunner",
+		Description: `This tool runs the project's test suite, detecting Go, Rust, Node and Python projects, and returns pass/fail counts, failing tests and failure output. It runs in the workspace directory.`,
+		ConfigKeys:  []string{"workspace", "command", "timeout", "sandbox"},
+		Factory: func(name, description string, configuration map[string]string, logger *zap.Logger) entities.Tool {
+			return NewTestRunnerTool(name, description, configuration, logger)
+		},
+	}
+	toolFactories["Grep"] = &ToolFactoryEntry{
 		Name:        "Grep",
 		Description: `This tool provides the ability to search for text in files. The workspace directory is prepended to any file paths specified.`,
-		ConfigKeys:  []string{"workspace"},
+		ConfigKeys:  []string{"workspace", "ignore"},
 		Factory: func(name, description string, configuration map[string]string, logger *zap.Logger) entities.Tool {
 			return NewFileSearchTool(name, description, configuration, logger)
 		},
 	}
-	toolFactory.toolFactories["Read"] = &ToolFactoryEntry{
+	toolFactories["CodeSearch"] = &ToolFactoryEntry{
+		Name:        "CodeSearch",
+		Description: `This tool provides the ability to search file contents with regular expressions, using ripgrep when it is installed. The workspace directory is prepended to any file paths specified.`,
+		ConfigKeys:  []string{"workspace", "ignore", "max_results"},
+		Factory: func(name, description string, configuration map[string]string, logger *zap.Logger) entities.Tool {
+			return NewGrepTool(name, description, configuration, logger)
+		},
+	}
+	toolFactories["Read"] = &ToolFactoryEntry{
 		Name:        "Read",
-		Description: `This tool provides the ability to read files. The workspace directory is prepended to any file paths specified.`,
-		ConfigKeys:  []string{"workspace"},
+		Description: `This tool provides the ability to read files, or the declarations found by name in Go, Python, JavaScript and TypeScript files. The workspace directory is prepended to any file paths specified.`,
+		ConfigKeys:  []string{"workspace", "archive_max_bytes", "archive_max_entries", "archive_max_ratio"},
 		Factory: func(name, description string, configuration map[string]string, logger *zap.Logger) entities.Tool {
 			return NewFileReadTool(name, description, configuration, logger)
 		},
 	}
-	toolFactory.toolFactories["Write"] = &ToolFactoryEntry{
+	toolFactories["Write"] = &ToolFactoryEntry{
 		Name:        "Write",
 		Description: `This tool creates or overwrites files. The workspace directory is prepended to relative file paths specified. Absolute paths are used as-is.`,
-		ConfigKeys:  []string{"workspace"},
+		ConfigKeys:  []string{"workspace", "max_diff_lines"},
 		Factory: func(name, description string, configuration map[string]string, logger *zap.Logger) entities.Tool {
 			return NewFileWriteTool(name, description, configuration, logger)
 		},
 	}
-	toolFactory.toolFactories["Edit"] = &ToolFactoryEntry{
+	toolFactories["Edit"] = &ToolFactoryEntry{
 		Name:        "Edit",
-		Description: `This tool edits existing files by replacing or inserting content. The workspace directory is prepended to relative file paths specified. Absolute paths are used as-is.`,
-		ConfigKeys:  []string{"workspace"},
+		Description: `This tool edits existing files by replacing or inserting content, or by replacing declarations found by name in Go, Python, JavaScript and TypeScript files. The workspace directory is prepended to relative file paths specified. Absolute paths are used as-is.`,
+		ConfigKeys:  []string{"workspace", "max_diff_lines"},
 		Factory: func(name, description string, configuration map[string]string, logger *zap.Logger) entities.Tool {
 			return NewFileWriteTool(name, description, configuration, logger)
 		},
 	}
-	toolFactory.toolFactories["Glob"] = &ToolFactoryEntry{
+	toolFactories["Glob"] = &ToolFactoryEntry{
 		Name:        "Glob",
 		Description: `This tool provides directory and file management operations, including creating directories, listing directory contents, building directory trees, and moving files or directories. The workspace directory is prepended to any paths specified.`,
-		ConfigKeys:  []string{"workspace"},
+		ConfigKeys:  []string{"workspace", "ignore"},
 		Factory: func(name, description string, configuration map[string]string, logger *zap.Logger) entities.Tool {
 			return NewDirectoryTool(name, description, configuration, logger)
 		},
 	}
-	toolFactory.toolFactories["WebSearch"] = &ToolFactoryEntry{
+	toolFactories["WebSearch"] = &ToolFactoryEntry{
 		Name:        "WebSearch",
-		Description: `This tool searches the web using the Tavily API.`,
-		ConfigKeys:  []string{"tavily_api_key"},
+		Description: `This tool searches the web using Tavily, Brave or DuckDuckGo, trying the configured providers in order.`,
+		ConfigKeys:  []string{"providers", "tavily_api_key", "brave_api_key"},
 		Factory: func(name, description string, configuration map[string]string, logger *zap.Logger) entities.Tool {
 			return NewWebSearchTool(name, description, configuration, logger)
 		},
 	}
-	toolFactory.toolFactories["WebFetch"] = &ToolFactoryEntry{
+	toolFactories["WebFetch"] = &ToolFactoryEntry{
 		Name:        "WebFetch",
-		Description: `This tool provides the ability to fetch content from the internet using the HTTP 1.1 protocol. This is useful when paired with the Swagger tool.`,
-		ConfigKeys:  []string{"user_agent"},
+		Description: `This tool makes HTTP requests with any method, headers, a JSON or text body and bearer or basic auth, returning the status code, response headers and body. HTML pages are returned as markdown or plain text unless the raw body is requested. This is useful when paired with the Swagger tool.`,
+		ConfigKeys:  []string{"user_agent", "max_bytes", "max_redirects", "redact_headers"},
 		Factory: func(name, description string, configuration map[string]string, logger *zap.Logger) entities.Tool {
 			return NewFetchTool(name, description, configuration, logger)
 		},
 	}
-	toolFactory.toolFactories["Swagger"] = &ToolFactoryEntry{
+	toolFactories["Swagger"] = &ToolFactoryEntry{
 		Name:        "Swagger",
-		Description: `This tool provides a Swagger/OpenAPI specification for a configured URL, providing available endpoints for REST API interactions. Use this in conjunction with the Fetch tool to perform the actions in the specification.`,
-		ConfigKeys:  []string{"swagger_url"},
+		Description: `This tool provides a Swagger/OpenAPI specification for a configured URL, providing available endpoints for REST API interactions. Its execute operation calls an operation on the live server and checks the response's status code and body against the specification, reporting pass or fail, which is useful for contract testing.`,
+		ConfigKeys:  []string{"swagger_url", "base_url", "auth_type", "auth_token", "auth_username", "auth_password", "api_key", "api_key_header", "redact_headers"},
 		Factory: func(name, description string, configuration map[string]string, logger *zap.Logger) entities.Tool {
 			return NewSwaggerTool(name, description, configuration, logger)
 		},
 	}
-	toolFactory.toolFactories["Memory"] = &ToolFactoryEntry{
+	toolFactories["Memory"] = &ToolFactoryEntry{
 		Name:        "Memory",
-		Description: `This tool manages a knowledge graph with entities, relations, and observations, allowing creation, modification, deletion, and querying of structured data.`,
-		ConfigKeys:  []string{"mongo_uri", "mongo_collection"},
+		Description: `This tool manages a knowledge graph with entities, relations, and observations, allowing creation, modification, deletion, and querying of structured data. The graph is stored where the application stores its data: in .aiagent/memory.json under the workspace with file storage, or in MongoDB with mongo storage or a mongo_uri. Set storage to file or mongo to choose.`,
+		ConfigKeys:  []string{"storage", "workspace", "mongo_uri", "mongo_collection"},
 		Factory: func(name, description string, configuration map[string]string, logger *zap.Logger) entities.Tool {
-			return NewMemoryTool(name, description, configuration, logger)
+			return newMemoryTool(name, description, configuration, toolFactory.storage, toolFactory.mongoURI, logger)
 		},
 	}
-	toolFactory.toolFactories["Browser"] = &ToolFactoryEntry{
+	toolFactories["Browser"] = &ToolFactoryEntry{
 		Name:        "Browser",
 		Description: `This tool provides headless browser control using the Rod library for navigation and interaction.`,
 		ConfigKeys:  []string{"headless", "workspace"},
@@ -127,7 +175,7 @@ func NewToolFactory() (*ToolFactory, error) {
 			return NewBrowserTool(name, description, configuration, logger)
 		},
 	}
-	toolFactory.toolFactories["Image"] = &ToolFactoryEntry{
+	toolFactories["Image"] = &ToolFactoryEntry{
 		Name:        "Image",
 		Description: `This tool generates images using AI providers like XAI or OpenAI.`,
 		ConfigKeys:  []string{"provider", "api_key", "base_url", "model"},
@@ -135,7 +183,7 @@ func NewToolFactory() (*ToolFactory, error) {
 			return NewImageTool(name, description, configuration, logger)
 		},
 	}
-	toolFactory.toolFactories["Vision"] = &ToolFactoryEntry{
+	toolFactories["Vision"] = &ToolFactoryEntry{
 		Name:        "Vision",
 		Description: "This tool provides image understanding capabilities using providers like XAI or OpenAI, allowing processing of images via base64 or URLs combined with text prompts.",
 		ConfigKeys:  []string{"provider", "api_key", "base_url", "model"},
@@ -148,7 +196,7 @@ func NewToolFactory() (*ToolFactory, error) {
 			}
 		},
 	}
-	toolFactory.toolFactories["TodoWrite"] = &ToolFactoryEntry{
+	toolFactories["TodoWrite"] = &ToolFactoryEntry{
 		Name:        "TodoWrite",
 		Description: "This tool manages a structured task list for complex tasks, allowing creation, reading, and status updates of todos with workflow grouping support.",
 		ConfigKeys:  []string{"workspace"},
@@ -156,7 +204,7 @@ func NewToolFactory() (*ToolFactory, error) {
 			return NewTodoTool(name, description, configuration, logger)
 		},
 	}
-	toolFactory.toolFactories["Compression"] = &ToolFactoryEntry{
+	toolFactories["Compression"] = &ToolFactoryEntry{
 		Name:        "Compression",
 		Description: "This tool provides intelligent context compression for managing conversation history, allowing selective summarization of message ranges based on different compression strategies.",
 		ConfigKeys:  []string{"workspace"},
@@ -164,7 +212,7 @@ func NewToolFactory() (*ToolFactory, error) {
 			return NewCompressionTool(name, description, configuration, logger)
 		},
 	}
-	toolFactory.toolFactories["Agent"] = &ToolFactoryEntry{
+	toolFactories["Agent"] = &ToolFactoryEntry{
 		Name:        "Agent",
 		Description: "Launches a sub-agent by name to complete a specific task and returns its response. Use this to delegate work to specialised agents such as Architect, Coder, QA, or DevOps.",
 		ConfigKeys:  []string{},
@@ -174,7 +222,7 @@ func NewToolFactory() (*ToolFactory, error) {
 			return NewAgentTool(name, description, configuration, toolFactory, logger)
 		},
 	}
-	return toolFactory, nil
+	return toolFactories
 }
 
 func (t *ToolFactory) ListFactories() ([]*ToolFactoryEntry, error) {
@@ -0,0 +1,201 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+
+	"go.uber.org/zap"
+)
+
+// pluginDescribeTimeout bounds the describe call made for each plugin at
+// startup.
+const pluginDescribeTimeout = 10 * time.Second
+
+// pluginManifest is what a plugin prints when run with "describe".
+type pluginManifest struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Schema      map[string]any `json:"schema"`
+	ConfigKeys  []string       `json:"config_keys"`
+}
+
+// LoadToolPlugins registers a tool type for each executable in dir, so tools
+// can be added without rebuilding aiagent. Each plugin is run once as
+// "<plugin> describe" and prints its manifest as JSON: the type's name
+// (the file name without extension by default), description, the JSON schema
+// of its arguments and the configuration keys the tool form offers. A call
+// runs "<plugin> execute" in the tool's workspace with
+// {"arguments": ..., "configuration": ...} on stdin; what it prints is the
+// result, and a non-zero exit fails the call with what it printed to stderr.
+// Plugins that can't be described are logged and skipped. A missing dir is
+// not an error.
+func LoadToolPlugins(dir string, logger *zap.Logger) error {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read plugins directory: %v", err)
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		info, err := os.Stat(path)
+		if strings.HasPrefix(entry.Name(), ".") || err != nil || !info.Mode().IsRegular() || info.Mode()&0111 == 0 {
+			continue
+		}
+
+		manifest, err := describePlugin(path)
+		if err != nil {
+			logger.Warn("Skipping tool plugin", zap.String("path", path), zap.Error(err))
+			continue
+		}
+		if err := RegisterToolType(manifest.Name, pluginConstructor(path, manifest)); err != nil {
+			logger.Warn("Skipping tool plugin", zap.String("path", path), zap.Error(err))
+			continue
+		}
+		logger.Info("Loaded tool plugin", zap.String("name", manifest.Name), zap.String("path", path))
+	}
+	return nil
+}
+
+func describePlugin(path string) (*pluginManifest, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), pluginDescribeTimeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, "describe")
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("describe failed: %v %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var manifest pluginManifest
+	if err := json.Unmarshal(output, &manifest); err != nil {
+		return nil, fmt.Errorf("describe printed an invalid manifest: %v", err)
+	}
+	if manifest.Name == "" {
+		manifest.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if manifest.Schema == nil {
+		manifest.Schema = map[string]any{"type": "object", "properties": map[string]any{}}
+	}
+	return &manifest, nil
+}
+
+func pluginConstructor(path string, manifest *pluginManifest) ToolConstructor {
+	return func(name, description string, configuration map[string]string, logger *zap.Logger) entities.Tool {
+		if description == "" {
+			description = manifest.Description
+		}
+		if configuration == nil {
+			configuration = map[string]string{}
+		}
+		for _, key := range manifest.ConfigKeys {
+			if _, ok := configuration[key]; !ok {
+				configuration[key] = ""
+			}
+		}
+		return &pluginTool{
+			name:          name,
+			description:   description,
+			configuration: configuration,
+			path:          path,
+			schema:        manifest.Schema,
+			logger:        logger,
+		}
+	}
+}
+
+// pluginTool runs a tool plugin's executable for each call.
+type pluginTool struct {
+	name          string
+	description   string
+	configuration map[string]string
+	path          string
+	schema        map[string]any
+	logger        *zap.Logger
+}
+
+func (t *pluginTool) Name() string {
+	return t.name
+}
+
+func (t *pluginTool) Description() string {
+	return t.description
+}
+
+func (t *pluginTool) FullDescription() string {
+	return t.Description()
+}
+
+func (t *pluginTool) Configuration() map[string]string {
+	return t.configuration
+}
+
+func (t *pluginTool) UpdateConfiguration(config map[string]string) {
+	t.configuration = config
+}
+
+func (t *pluginTool) WithConfiguration(config map[string]string) entities.Tool {
+	c := *t
+	c.configuration = config
+	return &c
+}
+
+func (t *pluginTool) Schema() map[string]any {
+	return t.schema
+}
+
+func (t *pluginTool) Execute(ctx context.Context, arguments string) (string, error) {
+	t.logger.Debug("Executing tool plugin", zap.String("name", t.name), zap.String("path", t.path))
+
+	if strings.TrimSpace(arguments) == "" {
+		arguments = "{}"
+	}
+	input, err := json.Marshal(map[string]any{
+		"arguments":     json.RawMessage(arguments),
+		"configuration": t.configuration,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode arguments: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, t.path, "execute")
+	cmd.Dir = t.configuration["workspace"]
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("%s failed: %s", t.name, message)
+		}
+		return "", fmt.Errorf("%s failed: %v", t.name, err)
+	}
+	return stdout.String(), nil
+}
+
+func (t *pluginTool) FormatResult(ui string, result string, diff string, arguments string) string {
+	if ui == "webui" {
+		return fmt.Sprintf("<div class=\"tool-result\"><pre>%s</pre></div>", html.EscapeString(result))
+	}
+	return result
+}
+
+func (t *pluginTool) DisplayName(ui string, arguments string) (string, string) {
+	return t.Name(), ""
+}
+
+var _ entities.Tool = (*pluginTool)(nil)
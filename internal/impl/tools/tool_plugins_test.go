@@ -0,0 +1,65 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestLoadToolPlugins(t *testing.T) {
+	dir := t.TempDir()
+	plugin := `#!/bin/sh
+if [ "$1" = "describe" ]; then
+	echo '{"name": "TestPluginGreet", "description": "Greets someone.", "schema": {"type": "object", "properties": {"who": {"type": "string"}}}, "config_keys": ["greeting"]}'
+	exit 0
+fi
+input=$(cat)
+case "$input" in
+	*'"who":"nobody"'*) echo "no one to greet" >&2; exit 1 ;;
+esac
+echo "$input"
+`
+	os.WriteFile(filepath.Join(dir, "greet.sh"), []byte(plugin), 0755)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a plugin"), 0644)
+	os.WriteFile(filepath.Join(dir, "broken.sh"), []byte("#!/bin/sh\nexit 1\n"), 0755)
+
+	if err := LoadToolPlugins(dir, zap.NewNop()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := LoadToolPlugins(filepath.Join(dir, "missing"), zap.NewNop()); err != nil {
+		t.Errorf("Expected a missing plugins directory to be ignored, got %v", err)
+	}
+
+	factory, err := NewToolFactory()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	entry, err := factory.GetFactoryByName("TestPluginGreet")
+	if err != nil {
+		t.Fatalf("Expected the plugin to be offered: %v", err)
+	}
+	if entry.Description != "Greets someone." || !slices.Equal(entry.ConfigKeys, []string{"greeting", "tool_timeout"}) {
+		t.Errorf("Unexpected factory entry: %+v", entry)
+	}
+	if _, err := factory.GetFactoryByName("broken"); err == nil {
+		t.Error("Expected a plugin that can't describe itself to be skipped")
+	}
+
+	tool := entry.Factory("Greet", "", map[string]string{"greeting": "hello"}, zap.NewNop())
+	result, err := tool.Execute(context.Background(), `{"who":"world"}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(result, `"arguments":{"who":"world"}`) || !strings.Contains(result, `"greeting":"hello"`) {
+		t.Errorf("Expected the plugin to get the arguments and configuration, got %q", result)
+	}
+
+	if _, err := tool.Execute(context.Background(), `{"who":"nobody"}`); err == nil || !strings.Contains(err.Error(), "no one to greet") {
+		t.Errorf("Expected the plugin's stderr as the error, got %v", err)
+	}
+}
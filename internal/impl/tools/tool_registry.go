@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+
+	"go.uber.org/zap"
+)
+
+// ToolConstructor creates a tool of a type from the name, description and
+// configuration stored in its ToolData.
+type ToolConstructor func(name, description string, configuration map[string]string, logger *zap.Logger) entities.Tool
+
+var (
+	customToolTypesMu sync.Mutex
+	customToolTypes   = make(map[string]ToolConstructor)
+)
+
+// RegisterToolType adds a tool type that every ToolFactory created afterwards
+// offers alongside the built-in ones, so tools of that type can be created in
+// the UI and loaded from the tool repository. Call it before the factory is
+// created; LoadToolPlugins registers the plugins found at startup with it.
+//
+// The constructor must return an entities.Tool; see that interface for what
+// each method is used for. It is also called once with an empty configuration
+// when a factory is created, to read the type's description and the
+// configuration keys the tool form offers, so it must not fail or have side
+// effects without configuration.
+func RegisterToolType(name string, constructor ToolConstructor) error {
+	if name == "" || constructor == nil {
+		return fmt.Errorf("a tool type needs a name and a constructor")
+	}
+	if _, builtin := builtinToolFactories(nil)[name]; builtin {
+		return fmt.Errorf("tool type %q is built in and cannot be replaced", name)
+	}
+
+	customToolTypesMu.Lock()
+	defer customToolTypesMu.Unlock()
+	if _, exists := customToolTypes[name]; exists {
+		return fmt.Errorf("tool type %q is already registered", name)
+	}
+	customToolTypes[name] = constructor
+	return nil
+}
+
+// customToolFactories builds factory entries for the registered tool types.
+func customToolFactories() map[string]*ToolFactoryEntry {
+	customToolTypesMu.Lock()
+	defer customToolTypesMu.Unlock()
+
+	entries := make(map[string]*ToolFactoryEntry, len(customToolTypes))
+	for name, constructor := range customToolTypes {
+		probe := constructor(name, "", map[string]string{}, zap.NewNop())
+		entry := &ToolFactoryEntry{Name: name, Factory: constructor}
+		if probe != nil {
+			entry.Description = probe.Description()
+			for key := range probe.Configuration() {
+				entry.ConfigKeys = append(entry.ConfigKeys, key)
+			}
+			sort.Strings(entry.ConfigKeys)
+		}
+		entries[name] = entry
+	}
+	return entries
+}
@@ -0,0 +1,64 @@
+package tools
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+
+	"go.uber.org/zap"
+)
+
+// echoTool is a custom tool that returns its arguments.
+type echoTool struct {
+	entities.Tool
+	name          string
+	configuration map[string]string
+}
+
+func (t *echoTool) Name() string                     { return t.name }
+func (t *echoTool) Description() string              { return "Echoes its arguments." }
+func (t *echoTool) Configuration() map[string]string { return t.configuration }
+func (t *echoTool) Execute(ctx context.Context, arguments string) (string, error) {
+	return arguments, nil
+}
+
+func newEchoTool(name, description string, configuration map[string]string, logger *zap.Logger) entities.Tool {
+	if _, ok := configuration["prefix"]; !ok {
+		configuration["prefix"] = ""
+	}
+	return &echoTool{name: name, configuration: configuration}
+}
+
+func TestRegisterToolType(t *testing.T) {
+	if err := RegisterToolType("Bash", newEchoTool); err == nil {
+		t.Error("Expected a built-in tool type to be refused")
+	}
+	if err := RegisterToolType("TestEcho", newEchoTool); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := RegisterToolType("TestEcho", newEchoTool); err == nil {
+		t.Error("Expected a tool type to be registered only once")
+	}
+
+	factory, err := NewToolFactory()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	entry, err := factory.GetFactoryByName("TestEcho")
+	if err != nil {
+		t.Fatalf("Expected the registered type to be offered: %v", err)
+	}
+	if entry.Description != "Echoes its arguments." || !slices.Equal(entry.ConfigKeys, []string{"prefix", "tool_timeout"}) {
+		t.Errorf("Unexpected factory entry: %+v", entry)
+	}
+
+	tool := entry.Factory("Echo", "", map[string]string{}, zap.NewNop())
+	if result, _ := tool.Execute(context.Background(), `{"a":1}`); result != `{"a":1}` {
+		t.Errorf("Expected the custom tool to run, got %q", result)
+	}
+	if _, err := factory.GetFactoryByName("Bash"); err != nil {
+		t.Errorf("Expected built-in types to remain: %v", err)
+	}
+}
@@ -5,8 +5,9 @@ import (
 )
 
 type LineResult struct {
-	Line int    `json:"line"`
-	Text string `json:"text"`
+	Line    int    `json:"line"`
+	Text    string `json:"text"`
+	Pattern string `json:"pattern,omitempty"` // the pattern that matched, when searching for several
 }
 
 func formatSize(size int64) string {
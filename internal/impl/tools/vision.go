@@ -98,13 +98,19 @@ func (v *VisionTool) Execute(ctx context.Context, arguments string) (string, err
 		},
 	}
 
-	return v.VisionAPIRequest(messages)
+	return v.VisionAPIRequest(ctx, messages)
 }
 
 func (v *VisionTool) UpdateConfiguration(config map[string]string) {
 	v.ConfigurationField = config
 }
 
+func (v *VisionTool) WithConfiguration(config map[string]string) entities.Tool {
+	c := *v
+	c.ConfigurationField = config
+	return &c
+}
+
 type MessageContent struct {
 	Type     string `json:"type"`
 	Text     string `json:"text,omitempty"`
@@ -124,7 +130,7 @@ type RequestBody struct {
 	Messages []Message `json:"messages"`
 }
 
-func (v *VisionTool) VisionAPIRequest(messages []Message) (string, error) {
+func (v *VisionTool) VisionAPIRequest(ctx context.Context, messages []Message) (string, error) {
 	provider := v.ConfigurationField["provider"]
 	apiKey := v.ConfigurationField["api_key"]
 	baseURL := v.ConfigurationField["base_url"]
@@ -160,7 +166,7 @@ func (v *VisionTool) VisionAPIRequest(messages []Message) (string, error) {
 		return "", err
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return "", err
 	}
@@ -1,12 +1,10 @@
 package tools
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"html"
-	"io"
 	"net/http"
 	"strings"
 
@@ -15,7 +13,7 @@ import (
 	"go.uber.org/zap"
 )
 
-// WebSearchTool represents a tool for searching using the Tavily API.
+// WebSearchTool searches the web with the configured SearchProviders.
 type WebSearchTool struct {
 	name          string
 	description   string
@@ -49,6 +47,12 @@ func (t *WebSearchTool) UpdateConfiguration(config map[string]string) {
 	t.configuration = config
 }
 
+func (t *WebSearchTool) WithConfiguration(config map[string]string) entities.Tool {
+	c := *t
+	c.configuration = config
+	return &c
+}
+
 func (t *WebSearchTool) FullDescription() string {
 	var b strings.Builder
 
@@ -87,7 +91,13 @@ func (t *WebSearchTool) Schema() map[string]any {
 	}
 }
 
-// Execute performs the search and returns both the answer and results.
+// Cacheable reports that a search can be answered again from the run's
+// cache, as the results won't change within a response.
+func (t *WebSearchTool) Cacheable(arguments string) bool {
+	return true
+}
+
+// Execute returns the results of the first provider whose search succeeds.
 func (t *WebSearchTool) Execute(ctx context.Context, arguments string) (string, error) {
 	// Log the search query
 	t.logger.Debug("Executing search", zap.String("arguments", arguments))
@@ -116,97 +126,43 @@ func (t *WebSearchTool) Execute(ctx context.Context, arguments string) (string,
 		return `{"results": [], "error": "query is required"}`, nil
 	}
 
-	// Get the Tavily API key from configuration
-	apiKey, ok := t.configuration["tavily_api_key"]
-	if !ok {
-		t.logger.Error("TAVILY_API_KEY not found in configuration")
-		return "", fmt.Errorf("tavily_api_key not found in configuration")
-	}
-
-	// Create JSON payload for Tavily API
-	payload := map[string]string{"query": query}
-	payloadBytes, err := json.Marshal(payload)
-	if err != nil {
-		t.logger.Error("Failed to marshal payload", zap.Error(err))
-		return "", err
-	}
-
-	// Set up the HTTP request
-	apiURL := "https://api.tavily.com/search"
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(payloadBytes))
-	if err != nil {
-		t.logger.Error("Failed to create HTTP request", zap.Error(err))
-		return "", err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-
-	// Execute the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		t.logger.Error("Failed to execute search request", zap.Error(err))
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	// Read the response body
-	bodyBytes, err := io.ReadAll(resp.Body)
+	providers, err := searchProviders(t.configuration, &http.Client{})
 	if err != nil {
-		t.logger.Error("Failed to read response body", zap.Error(err))
+		t.logger.Error("Failed to configure search providers", zap.Error(err))
 		return "", err
 	}
 
-	bodyString := string(bodyBytes)
-	t.logger.Debug("Search API response body", zap.String("body", bodyString))
-
-	// Check the response status
-	if resp.StatusCode != http.StatusOK {
-		t.logger.Error("Search API request failed", zap.Int("status_code", resp.StatusCode))
-		return "", fmt.Errorf("API request failed with status code: %d", resp.StatusCode)
-	}
-
-	// Define the structure for Tavily's response
-	type TavilyResponse struct {
-		Answer  string `json:"answer"`
-		Results []struct {
-			Title   string  `json:"title"`
-			URL     string  `json:"url"`
-			Content string  `json:"content"`
-			Score   float64 `json:"score"`
-		} `json:"results"`
-	}
-
-	// Parse the response
-	var result TavilyResponse
-	if err := json.Unmarshal(bodyBytes, &result); err != nil {
-		return `{"results": [], "error": "failed to parse API response"}`, nil
-	}
-
-	// Limit results
-	if len(result.Results) > numResults {
-		result.Results = result.Results[:numResults]
-	}
+	// Fall back to the next provider when one fails or is over its quota
+	var failures []string
+	for _, provider := range providers {
+		results, err := provider.Search(ctx, query, numResults)
+		if err != nil {
+			t.logger.Warn("Search provider failed", zap.String("provider", provider.Name()), zap.Error(err))
+			failures = append(failures, fmt.Sprintf("%s: %v", provider.Name(), err))
+			continue
+		}
+		if len(results) > numResults {
+			results = results[:numResults]
+		}
+		if results == nil {
+			results = []searchResult{}
+		}
 
-	var grokResults []map[string]any
-	for _, res := range result.Results {
-		grokResults = append(grokResults, map[string]any{
-			"title":   res.Title,
-			"url":     res.URL,
-			"snippet": res.Content,
+		jsonResult, err := json.Marshal(map[string]any{
+			"results":  results,
+			"provider": provider.Name(),
+			"error":    "",
 		})
-	}
+		if err != nil {
+			return `{"results": [], "error": "failed to marshal response"}`, nil
+		}
 
-	jsonResult, err := json.Marshal(map[string]any{
-		"results": grokResults,
-		"error":   "",
-	})
-	if err != nil {
-		return `{"results": [], "error": "failed to marshal response"}`, nil
+		t.logger.Info("Web search completed", zap.String("query", query), zap.String("provider", provider.Name()), zap.Int("results", len(results)))
+		return string(jsonResult), nil
 	}
 
-	t.logger.Info("Web search completed", zap.String("query", query), zap.Int("results", len(grokResults)))
-	return string(jsonResult), nil
+	t.logger.Error("All search providers failed", zap.Strings("failures", failures))
+	return "", fmt.Errorf("all search providers failed: %s", strings.Join(failures, "; "))
 }
 
 func (t *WebSearchTool) DisplayName(ui string, arguments string) (string, string) {
@@ -0,0 +1,293 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Search endpoints, variables so tests can point them at a local server.
+var (
+	tavilySearchURL     = "https://api.tavily.com/search"
+	braveSearchURL      = "https://api.search.brave.com/res/v1/web/search"
+	duckDuckGoSearchURL = "https://html.duckduckgo.com/html/"
+)
+
+// searchResult is a web search hit in the same shape for every provider.
+type searchResult struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Snippet string `json:"snippet"`
+}
+
+// SearchProvider is a web search backend used by WebSearchTool.
+type SearchProvider interface {
+	Name() string
+	Search(ctx context.Context, query string, numResults int) ([]searchResult, error)
+}
+
+// searchProviders builds the providers in the order they are tried. The
+// providers key lists them by name; without it, every provider with an API
+// key is used. DuckDuckGo scrapes a web page, so it is only used when the
+// providers key names it.
+func searchProviders(config map[string]string, client *http.Client) ([]SearchProvider, error) {
+	var names []string
+	for _, name := range strings.Split(config["providers"], ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		if config["tavily_api_key"] != "" {
+			names = append(names, "tavily")
+		}
+		if config["brave_api_key"] != "" {
+			names = append(names, "brave")
+		}
+	}
+
+	var providers []SearchProvider
+	for _, name := range names {
+		switch name {
+		case "tavily":
+			if config["tavily_api_key"] != "" {
+				providers = append(providers, &tavilyProvider{apiKey: config["tavily_api_key"], client: client})
+			}
+		case "brave":
+			if config["brave_api_key"] != "" {
+				providers = append(providers, &braveProvider{apiKey: config["brave_api_key"], client: client})
+			}
+		case "duckduckgo":
+			providers = append(providers, &duckDuckGoProvider{client: client})
+		default:
+			return nil, fmt.Errorf("unknown search provider: %s", name)
+		}
+	}
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("no search provider is configured; set tavily_api_key or brave_api_key, or add duckduckgo to providers")
+	}
+	return providers, nil
+}
+
+// doSearchRequest executes req and returns the body of a successful
+// response.
+func doSearchRequest(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request failed with status code: %d", resp.StatusCode)
+	}
+	return body, nil
+}
+
+type tavilyProvider struct {
+	apiKey string
+	client *http.Client
+}
+
+func (p *tavilyProvider) Name() string { return "tavily" }
+
+func (p *tavilyProvider) Search(ctx context.Context, query string, numResults int) ([]searchResult, error) {
+	payload, err := json.Marshal(map[string]any{"query": query, "max_results": numResults})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", tavilySearchURL, bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+	body, err := doSearchRequest(p.client, req)
+	if err != nil {
+		return nil, err
+	}
+	var response struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse API response: %v", err)
+	}
+
+	var results []searchResult
+	for _, r := range response.Results {
+		results = append(results, searchResult{Title: r.Title, URL: r.URL, Snippet: r.Content})
+	}
+	return results, nil
+}
+
+type braveProvider struct {
+	apiKey string
+	client *http.Client
+}
+
+func (p *braveProvider) Name() string { return "brave" }
+
+func (p *braveProvider) Search(ctx context.Context, query string, numResults int) ([]searchResult, error) {
+	// Brave returns at most 20 results per request
+	params := url.Values{"q": {query}, "count": {strconv.Itoa(min(numResults, 20))}}
+	req, err := http.NewRequestWithContext(ctx, "GET", braveSearchURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Subscription-Token", p.apiKey)
+
+	body, err := doSearchRequest(p.client, req)
+	if err != nil {
+		return nil, err
+	}
+	var response struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse API response: %v", err)
+	}
+
+	var results []searchResult
+	for _, r := range response.Web.Results {
+		// Titles and descriptions highlight the query with <strong> tags
+		results = append(results, searchResult{Title: htmlPlainText(r.Title), URL: r.URL, Snippet: htmlPlainText(r.Description)})
+	}
+	return results, nil
+}
+
+// duckDuckGoProvider scrapes DuckDuckGo's HTML results page, which needs no
+// API key.
+type duckDuckGoProvider struct {
+	client *http.Client
+}
+
+func (p *duckDuckGoProvider) Name() string { return "duckduckgo" }
+
+func (p *duckDuckGoProvider) Search(ctx context.Context, query string, numResults int) ([]searchResult, error) {
+	form := url.Values{"q": {query}}
+	req, err := http.NewRequestWithContext(ctx, "POST", duckDuckGoSearchURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", defaultUserAgent)
+
+	body, err := doSearchRequest(p.client, req)
+	if err != nil {
+		return nil, err
+	}
+	results := parseDuckDuckGoHTML(string(body))
+	if len(results) > numResults {
+		results = results[:numResults]
+	}
+	return results, nil
+}
+
+// parseDuckDuckGoHTML reads the results from a DuckDuckGo HTML page: a
+// result__a link with the title, then a result__snippet element.
+func parseDuckDuckGoHTML(doc string) []searchResult {
+	var results []searchResult
+	var field *string
+	var text strings.Builder
+	var fieldTag string
+
+	for _, token := range tokenizeHTML(doc) {
+		switch token.kind {
+		case htmlStartTag:
+			if field != nil {
+				continue
+			}
+			classes := strings.Fields(token.attrs["class"])
+			switch {
+			case hasClass(classes, "result__a"):
+				results = append(results, searchResult{URL: duckDuckGoTarget(token.attrs["href"])})
+				field = &results[len(results)-1].Title
+			case hasClass(classes, "result__snippet") && len(results) > 0:
+				field = &results[len(results)-1].Snippet
+			default:
+				continue
+			}
+			fieldTag = token.name
+			text.Reset()
+		case htmlText:
+			if field != nil {
+				text.WriteString(token.text)
+			}
+		case htmlEndTag:
+			if field != nil && token.name == fieldTag {
+				*field = strings.Join(strings.Fields(text.String()), " ")
+				field = nil
+			}
+		}
+	}
+
+	// Ads link through a redirect without a target
+	var kept []searchResult
+	for _, r := range results {
+		if r.URL != "" && r.Title != "" {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+func hasClass(classes []string, class string) bool {
+	for _, c := range classes {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// duckDuckGoTarget unwraps DuckDuckGo's redirect links, which carry the
+// result URL in the uddg parameter.
+func duckDuckGoTarget(href string) string {
+	if strings.HasPrefix(href, "//") {
+		href = "https:" + href
+	}
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if target := u.Query().Get("uddg"); target != "" {
+		return target
+	}
+	if u.Host == "duckduckgo.com" || strings.HasSuffix(u.Host, ".duckduckgo.com") {
+		return ""
+	}
+	return href
+}
+
+// htmlPlainText drops the tags from an HTML fragment.
+func htmlPlainText(fragment string) string {
+	var text strings.Builder
+	for _, token := range tokenizeHTML(fragment) {
+		if token.kind == htmlText {
+			text.WriteString(token.text)
+		}
+	}
+	return strings.Join(strings.Fields(text.String()), " ")
+}
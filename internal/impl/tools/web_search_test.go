@@ -0,0 +1,121 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+const duckDuckGoPage = `<html><body>
+<div class="result results_links">
+  <a rel="nofollow" class="result__a" href="//duckduckgo.com/l/?uddg=https%3A%2F%2Fgo.dev%2Fdoc%2F&amp;rut=abc">The <b>Go</b> Programming Language</a>
+  <a class="result__snippet" href="//duckduckgo.com/l/?uddg=https%3A%2F%2Fgo.dev%2Fdoc%2F">Documentation for <b>Go</b> &amp; its tools.</a>
+</div>
+<div class="result result--ad">
+  <a class="result__a" href="https://duckduckgo.com/y.js?ad_provider=x">Sponsored</a>
+</div>
+<div class="result">
+  <a class="result__a" href="https://example.com/direct">Direct link</a>
+</div>
+</body></html>`
+
+func TestParseDuckDuckGoHTML(t *testing.T) {
+	results := parseDuckDuckGoHTML(duckDuckGoPage)
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results without the ad, got %+v", results)
+	}
+	want := searchResult{Title: "The Go Programming Language", URL: "https://go.dev/doc/", Snippet: "Documentation for Go & its tools."}
+	if results[0] != want {
+		t.Errorf("got %+v, want %+v", results[0], want)
+	}
+	if results[1].URL != "https://example.com/direct" {
+		t.Errorf("expected a direct link to be kept, got %q", results[1].URL)
+	}
+}
+
+func TestSearchProviders(t *testing.T) {
+	providerNames := func(config map[string]string) []string {
+		providers, err := searchProviders(config, http.DefaultClient)
+		if err != nil {
+			t.Fatalf("searchProviders: %v", err)
+		}
+		var names []string
+		for _, p := range providers {
+			names = append(names, p.Name())
+		}
+		return names
+	}
+
+	if got := providerNames(map[string]string{"tavily_api_key": "key", "brave_api_key": "key"}); len(got) != 2 || got[0] != "tavily" || got[1] != "brave" {
+		t.Errorf("expected tavily then brave by default, got %v", got)
+	}
+	if got := providerNames(map[string]string{"providers": "tavily,duckduckgo", "tavily_api_key": "key"}); len(got) != 2 || got[1] != "duckduckgo" {
+		t.Errorf("expected duckduckgo when listed, got %v", got)
+	}
+	if _, err := searchProviders(map[string]string{}, http.DefaultClient); err == nil {
+		t.Errorf("expected duckduckgo to be left out unless listed")
+	}
+	if got := providerNames(map[string]string{"providers": "brave, tavily", "tavily_api_key": "key"}); len(got) != 1 || got[0] != "tavily" {
+		t.Errorf("expected brave without a key to be skipped, got %v", got)
+	}
+	if _, err := searchProviders(map[string]string{"providers": "altavista"}, http.DefaultClient); err == nil {
+		t.Errorf("expected an unknown provider to be rejected")
+	}
+	if _, err := searchProviders(map[string]string{"providers": "brave"}, http.DefaultClient); err == nil {
+		t.Errorf("expected an error when no provider is usable")
+	}
+}
+
+func TestWebSearchFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tavily":
+			http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+		case "/brave":
+			if r.Header.Get("X-Subscription-Token") != "brave-key" || r.URL.Query().Get("q") != "golang" {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"web": {"results": [{"title": "<strong>Go</strong>", "url": "https://go.dev", "description": "Build <strong>simple</strong> software"}]}}`))
+		}
+	}))
+	defer server.Close()
+
+	defer func(tavily, brave string) { tavilySearchURL, braveSearchURL = tavily, brave }(tavilySearchURL, braveSearchURL)
+	tavilySearchURL = server.URL + "/tavily"
+	braveSearchURL = server.URL + "/brave"
+
+	tool := NewWebSearchTool("WebSearch", "", map[string]string{
+		"providers":      "tavily,brave",
+		"tavily_api_key": "tavily-key",
+		"brave_api_key":  "brave-key",
+	}, zap.NewNop())
+	result, err := tool.Execute(context.Background(), `{"query": "golang"}`)
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	var response struct {
+		Results  []searchResult `json:"results"`
+		Provider string         `json:"provider"`
+	}
+	if err := json.Unmarshal([]byte(result), &response); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if response.Provider != "brave" {
+		t.Errorf("expected brave after tavily failed, got %q", response.Provider)
+	}
+	want := searchResult{Title: "Go", URL: "https://go.dev", Snippet: "Build simple software"}
+	if len(response.Results) != 1 || response.Results[0] != want {
+		t.Errorf("got %+v, want %+v", response.Results, want)
+	}
+
+	tool.UpdateConfiguration(map[string]string{"providers": "tavily", "tavily_api_key": "tavily-key"})
+	if _, err := tool.Execute(context.Background(), `{"query": "golang"}`); err == nil {
+		t.Errorf("expected an error when every provider fails")
+	}
+}
@@ -0,0 +1,27 @@
+package tools
+
+import (
+	"os"
+
+	"github.com/drujensen/aiagent/internal/impl/workspacepath"
+)
+
+// toolPaths resolves the paths a call names against the tool's workspace,
+// following symlinks, for the tool's Paths method. An empty path is the
+// workspace itself; paths outside it are left out.
+func toolPaths(configuration map[string]string, paths ...string) []string {
+	workspace := configuration["workspace"]
+	if workspace == "" {
+		var err error
+		if workspace, err = os.Getwd(); err != nil {
+			return nil
+		}
+	}
+	var resolved []string
+	for _, path := range paths {
+		if fullPath, err := workspacepath.Resolve(workspace, path); err == nil {
+			resolved = append(resolved, workspacepath.Real(fullPath))
+		}
+	}
+	return resolved
+}
@@ -0,0 +1,55 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestFileTools_RefuseSymlinksOutOfWorkspace(t *testing.T) {
+	root := t.TempDir()
+	workspace := filepath.Join(root, "ws")
+	outside := filepath.Join(root, "outside")
+	for _, dir := range []string{workspace, outside} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		"dir-link":      outside,
+		"dangling-link": filepath.Join(outside, "new.txt"),
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(workspace, name)); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+	}
+
+	// The file tools refuse to write through a link out of the workspace
+	tool := NewFileWriteTool("Write", "", map[string]string{"workspace": workspace}, zap.NewNop())
+	if _, err := tool.validatePath("dangling-link"); err == nil {
+		t.Error("Expected FileWrite to refuse a dangling link out of the workspace")
+	}
+	dirTool := NewDirectoryTool("Directory", "", map[string]string{"workspace": workspace}, zap.NewNop())
+	if _, err := dirTool.validatePath("dir-link"); err == nil {
+		t.Error("Expected Directory to refuse a link out of the workspace")
+	}
+}
+
+func TestToolPaths(t *testing.T) {
+	workspace := t.TempDir()
+	root, _ := filepath.EvalSymlinks(workspace)
+	os.MkdirAll(filepath.Join(workspace, "src"), 0755)
+	if err := os.Symlink(filepath.Join(workspace, "src"), filepath.Join(workspace, "link")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	got := toolPaths(map[string]string{"workspace": workspace}, "", "link/main.go", "../outside.go")
+	want := []string{root, filepath.Join(root, "src", "main.go")}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,86 @@
+// Package workspacepath resolves paths against a workspace, keeping them
+// inside it.
+package workspacepath
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Resolve joins a path given to a tool or named by the user with the
+// workspace and checks the result stays inside it. Absolute paths must already be inside
+// the workspace. The check is repeated after following symlinks, so a link in
+// the workspace cannot point a tool at files outside it; a path that does not
+// exist yet is checked through its nearest existing parent.
+func Resolve(workspace, path string) (string, error) {
+	workspace = filepath.Clean(workspace)
+	realWorkspace, err := filepath.EvalSymlinks(workspace)
+	if err != nil {
+		realWorkspace = workspace
+	}
+
+	var fullPath string
+	if filepath.IsAbs(path) {
+		fullPath = filepath.Clean(path)
+		if !Within(workspace, fullPath) && !Within(realWorkspace, fullPath) {
+			return "", fmt.Errorf("absolute path is outside workspace")
+		}
+	} else {
+		fullPath = filepath.Join(workspace, path)
+		if !Within(workspace, fullPath) {
+			return "", fmt.Errorf("path is outside workspace")
+		}
+	}
+
+	if !Within(realWorkspace, Real(fullPath)) {
+		return "", fmt.Errorf("path is outside workspace through a symlink")
+	}
+	return fullPath, nil
+}
+
+// Real follows the symlinks of the longest existing part of path and
+// appends the rest unchanged. A dangling symlink is followed to its target,
+// since writing through it would create the target.
+func Real(path string) string {
+	return realPathDepth(path, 0)
+}
+
+func realPathDepth(path string, depth int) string {
+	var rest []string
+	for current := path; ; current = filepath.Dir(current) {
+		if resolved, err := filepath.EvalSymlinks(current); err == nil {
+			return joinRest(resolved, rest)
+		}
+		if info, err := os.Lstat(current); err == nil && info.Mode()&os.ModeSymlink != 0 && depth < maxSymlinkDepth {
+			if target, err := os.Readlink(current); err == nil {
+				if !filepath.IsAbs(target) {
+					target = filepath.Join(filepath.Dir(current), target)
+				}
+				return joinRest(realPathDepth(target, depth+1), rest)
+			}
+		}
+		if filepath.Dir(current) == current {
+			return path
+		}
+		rest = append(rest, filepath.Base(current))
+	}
+}
+
+// maxSymlinkDepth stops following a chain of dangling symlinks that loops.
+const maxSymlinkDepth = 40
+
+// joinRest appends the path elements collected walking up, innermost last.
+func joinRest(base string, rest []string) string {
+	for i := len(rest) - 1; i >= 0; i-- {
+		base = filepath.Join(base, rest[i])
+	}
+	return base
+}
+
+// Within reports whether path is dir or inside it.
+func Within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,56 @@
+package workspacepath
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolve_Symlinks(t *testing.T) {
+	root := t.TempDir()
+	workspace := filepath.Join(root, "ws")
+	outside := filepath.Join(root, "outside")
+	for _, dir := range []string{filepath.Join(workspace, "src"), outside, workspace + "2"} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644)
+	links := map[string]string{
+		"dir-link":      outside,
+		"file-link":     filepath.Join(outside, "secret.txt"),
+		"dangling-link": filepath.Join(outside, "new.txt"),
+		"inside-link":   filepath.Join(workspace, "src"),
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(workspace, name)); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+	}
+
+	tests := []struct {
+		path    string
+		wantErr string
+	}{
+		{"src/main.go", ""},
+		{"inside-link/main.go", ""},
+		{filepath.Join(workspace, "src"), ""},
+		{"../outside/secret.txt", "path is outside workspace"},
+		{filepath.Join(outside, "secret.txt"), "absolute path is outside workspace"},
+		{filepath.Join(workspace+"2", "file.txt"), "absolute path is outside workspace"},
+		{"dir-link/secret.txt", "through a symlink"},
+		{"dir-link/missing/new.txt", "through a symlink"},
+		{"file-link", "through a symlink"},
+		{"dangling-link", "through a symlink"},
+	}
+	for _, tt := range tests {
+		_, err := Resolve(workspace, tt.path)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: unexpected error: %v", tt.path, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: expected error %q, got %v", tt.path, tt.wantErr, err)
+		}
+	}
+}
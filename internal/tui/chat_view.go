@@ -28,6 +28,7 @@ type ChatView struct {
 	modelService       services.ModelService
 	toolService        services.ToolService
 	skillService       services.SkillService
+	approvalService    services.ApprovalService
 	logger             *zap.Logger
 	activeChat         *entities.Chat
 	editor             vimtea.Editor
@@ -39,6 +40,7 @@ type ChatView struct {
 	err                error
 	cancel             context.CancelFunc
 	isProcessing       bool
+	steering           bool // typing a note to steer the response being generated
 	startTime          time.Time
 	focused            string // "textarea" or "editor"
 	width              int
@@ -50,9 +52,24 @@ type ChatView struct {
 	eventCancel        func()                    // Event subscription cancel function
 	eventChan          chan interface{}          // Channel for receiving events (ToolCallEvent or MessageHistoryEvent)
 	lineNumbersEnabled bool                      // Track whether line numbers are enabled
+	showReasoning      bool                      // Show the model's reasoning above its answers
 	toolCallStatus     map[string]bool           // Track completion status of tool calls (toolCallID -> completed)
 	subAgents          map[string]*subAgentState // keyed by sub-chat ID
 	subAgentOrder      []string                  // insertion-ordered sub-chat IDs for stable rendering
+	progress           *entities.ProgressEvent   // latest plan progress for the active chat
+	tail               int                       // latest exchanges shown, zero shows the whole chat
+	contextUsage       *entities.ContextUsage    // estimated context window use of the active chat
+	overrides          messageOverrides          // temperature and max tokens for the next message
+	runUsage           runUsage                  // usage of the response being generated, as last reported
+	shownRunUsage      runUsage                  // runUsage as of the last spinner tick, shown in the footer
+	notify             bool                      // announce the end of long responses to the terminal
+}
+
+// runUsage is the tokens used by the response being generated and their
+// estimated cost.
+type runUsage struct {
+	tokens int
+	cost   float64
 }
 
 // subAgentState tracks the live status of a sub-agent launched by the Agent tool.
@@ -65,7 +82,7 @@ type subAgentState struct {
 	failedErr   string
 }
 
-func NewChatView(chatService services.ChatService, agentService services.AgentService, modelService services.ModelService, toolService services.ToolService, skillService services.SkillService, logger *zap.Logger, activeChat *entities.Chat) ChatView {
+func NewChatView(chatService services.ChatService, agentService services.AgentService, modelService services.ModelService, toolService services.ToolService, skillService services.SkillService, approvalService services.ApprovalService, logger *zap.Logger, activeChat *entities.Chat, tail int, spinnerName string, notify bool) ChatView {
 	ta := textarea.New()
 	ta.Placeholder = "Type your message..."
 	ta.Focus()
@@ -81,7 +98,7 @@ func NewChatView(chatService services.ChatService, agentService services.AgentSe
 	ss := lipgloss.NewStyle().Foreground(lipgloss.Color("7"))
 
 	s := spinner.New()
-	s.Spinner = spinner.Dot
+	s.Spinner = spinnerFor(spinnerName)
 	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
 
 	cv := ChatView{
@@ -90,6 +107,7 @@ func NewChatView(chatService services.ChatService, agentService services.AgentSe
 		modelService:       modelService,
 		toolService:        toolService,
 		skillService:       skillService,
+		approvalService:    approvalService,
 		logger:             logger,
 		activeChat:         activeChat,
 		textarea:           ta,
@@ -103,6 +121,8 @@ func NewChatView(chatService services.ChatService, agentService services.AgentSe
 		height:             5,
 		lineNumbersEnabled: false, // Start with line numbers disabled
 		toolCallStatus:     make(map[string]bool),
+		tail:               tail,
+		notify:             notify,
 	}
 
 	// Initialize the vimtea editor
@@ -181,6 +201,14 @@ func NewChatView(chatService services.ChatService, agentService services.AgentSe
 		}
 	})
 
+	// Subscribe to plan progress events
+	progressCancel := events.SubscribeToProgressEvents(func(data events.ProgressEventData) {
+		select {
+		case cv.eventChan <- data.Event:
+		default:
+		}
+	})
+
 	// Combine cancel functions
 	cv.eventCancel = func() {
 		toolCancel()
@@ -188,6 +216,7 @@ func NewChatView(chatService services.ChatService, agentService services.AgentSe
 		processFailedCancel()
 		chatUpdateCancel()
 		subAgentCancel()
+		progressCancel()
 	}
 
 	return cv
@@ -207,6 +236,31 @@ func (c *ChatView) getToolByName(name string) entities.Tool {
 	return nil
 }
 
+// chatPlan returns the plan a tool keeps for the chat, so an unfinished plan
+// is shown again when the chat is opened.
+func (c *ChatView) chatPlan(chatID string) *entities.ProgressEvent {
+	tools, err := c.toolService.ListTools()
+	if err != nil {
+		c.logger.Error("Failed to list tools", zap.Error(err))
+		return nil
+	}
+	for _, tool := range tools {
+		planTool, ok := tool.(entities.PlanTool)
+		if !ok {
+			continue
+		}
+		plan, err := planTool.Plan(chatID)
+		if err != nil {
+			c.logger.Warn("Failed to load the chat's plan", zap.String("tool", tool.Name()), zap.Error(err))
+			continue
+		}
+		if plan != nil && plan.Total > 0 {
+			return plan
+		}
+	}
+	return nil
+}
+
 func (c *ChatView) SetActiveChat(chat *entities.Chat) {
 	// Check if agent is changing
 	agentChanged := c.activeChat == nil || c.activeChat.AgentID != chat.AgentID
@@ -216,6 +270,8 @@ func (c *ChatView) SetActiveChat(chat *entities.Chat) {
 	}
 
 	c.activeChat = chat
+	c.progress = c.chatPlan(chat.ID)
+	c.contextUsage = nil
 	ctx := context.Background()
 	agent, err := c.agentService.GetAgent(ctx, chat.AgentID)
 	if err != nil {
@@ -261,6 +317,58 @@ func (c *ChatView) SetActiveChat(chat *entities.Chat) {
 	}
 }
 
+// toggleReasoning shows or hides the reasoning saved with assistant messages.
+func (c *ChatView) toggleReasoning() {
+	c.showReasoning = !c.showReasoning
+	c.updateEditorContent()
+}
+
+// addNotice shows a system notice in the active chat, or as an error line
+// when no chat is open.
+func (c *ChatView) addNotice(notice string) {
+	if c.activeChat == nil {
+		c.err = fmt.Errorf("%s", notice)
+		return
+	}
+	c.activeChat.Messages = append(c.activeChat.Messages, entities.Message{Role: "system", Content: notice})
+	c.updateEditorContent()
+}
+
+// tailMessages returns the messages from the start of the last tail
+// exchanges, each starting with a user message, and how many exchanges are
+// left out. A tail of zero keeps every message.
+func tailMessages(messages []entities.Message, tail int) ([]entities.Message, int) {
+	if tail <= 0 {
+		return messages, 0
+	}
+	exchanges := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role != "user" {
+			continue
+		}
+		exchanges++
+		if exchanges == tail {
+			hidden := 0
+			for _, msg := range messages[:i] {
+				if msg.Role == "user" {
+					hidden++
+				}
+			}
+			return messages[i:], hidden
+		}
+	}
+	return messages, 0
+}
+
+// tailHeader summarizes the chat above the exchanges shown by "/resume".
+func (c *ChatView) tailHeader(hidden int) string {
+	header := fmt.Sprintf("Showing the last %d of %d exchanges", c.tail, c.tail+hidden)
+	if usage := c.activeChat.Usage; usage != nil {
+		header += fmt.Sprintf(" · %d tokens · $%.4f", usage.TotalTokens, usage.TotalCost)
+	}
+	return header + " · /resume all shows everything"
+}
+
 func (c *ChatView) updateEditorContent() {
 	if c.activeChat == nil || (len(c.activeChat.Messages) == 0 && len(c.tempMessages) == 0) {
 		c.editor = vimtea.NewEditor(
@@ -279,13 +387,28 @@ func (c *ChatView) updateEditorContent() {
 	}
 
 	var sb strings.Builder
-	for _, message := range c.activeChat.Messages {
+	messages, hidden := tailMessages(c.activeChat.Messages, c.tail)
+	if hidden > 0 {
+		sb.WriteString(c.systemStyle.Render(c.tailHeader(hidden)) + "\n")
+	}
+	for _, message := range messages {
 		if message.Role == "user" {
 			sb.WriteString("\n" + c.userStyle.Render("User: ") + message.Content + "\n\n")
 		} else if message.Role == "assistant" {
+			if c.showReasoning && message.Reasoning != "" {
+				sb.WriteString(c.systemStyle.Render("Reasoning: ") + message.Reasoning + "\n")
+			}
 			// Skip displaying tool execution announcements in TUI
 			if len(message.ToolCalls) == 0 {
-				sb.WriteString(c.asstStyle.Render("Assistant: ") + message.Content + "\n")
+				label := "Assistant: "
+				if message.Model != "" {
+					label = fmt.Sprintf("Assistant (%s): ", message.Model)
+				}
+				content := message.Content
+				if message.Truncated {
+					content += "\n" + c.systemStyle.Render("[partial answer, cancelled]")
+				}
+				sb.WriteString(c.asstStyle.Render(label) + content + "\n")
 			}
 		} else if message.Role == "tool" {
 			sb.WriteString(c.systemStyle.Render("Tool: ") + "\n")
@@ -385,6 +508,25 @@ func (c *ChatView) updateEditorContent() {
 		}
 	}
 
+	// Render the live plan checklist while the agent is working through it
+	if c.progress != nil && c.progress.Completed < c.progress.Total {
+		sb.WriteString(c.systemStyle.Render(fmt.Sprintf("Progress: %d of %d", c.progress.Completed, c.progress.Total)) + "\n")
+		for _, item := range c.progress.Items {
+			var statusIcon string
+			switch item.Status {
+			case "completed":
+				statusIcon = "✓"
+			case "cancelled":
+				statusIcon = "✗"
+			case "in_progress":
+				statusIcon = "⟳"
+			default:
+				statusIcon = "○"
+			}
+			sb.WriteString(c.systemStyle.Render("  ↳ ") + statusIcon + " " + item.Content + "\n")
+		}
+	}
+
 	// Add error as temporary system message if present
 	if c.err != nil {
 		if sb.Len() > 0 {
@@ -451,6 +593,8 @@ func (c *ChatView) listenForEvents() tea.Cmd {
 			return chatUpdateEventMsg(e)
 		case *entities.SubAgentEvent:
 			return subAgentEventMsg(e)
+		case *entities.ProgressEvent:
+			return progressEventMsg(e)
 		default:
 			return nil
 		}
@@ -529,6 +673,33 @@ func (c ChatView) Update(msg tea.Msg) (ChatView, tea.Cmd) {
 
 	case tea.KeyMsg:
 		if c.isProcessing {
+			if c.steering {
+				switch m.Type {
+				case tea.KeyEsc:
+					c.steering = false
+					return c, nil
+				case tea.KeyEnter:
+					note := strings.TrimSpace(c.textarea.Value())
+					c.steering = false
+					if note == "" || c.activeChat == nil {
+						return c, nil
+					}
+					c.textarea.Reset()
+					return c, steerCmd(c.chatService, c.activeChat.ID, note)
+				}
+				var cmd tea.Cmd
+				c.textarea, cmd = c.textarea.Update(m)
+				return c, cmd
+			}
+			if m.String() == "ctrl+j" {
+				c.steering = true
+				c.focused = "textarea"
+				c.textarea.Focus()
+				if c.editor != nil {
+					c.editor.SetFocus(false)
+				}
+				return c, textarea.Blink
+			}
 			if m.Type == tea.KeyEsc {
 				if c.cancel != nil {
 					c.cancel()
@@ -566,6 +737,9 @@ func (c ChatView) Update(msg tea.Msg) (ChatView, tea.Cmd) {
 			c.lineNumbersEnabled = !c.lineNumbersEnabled
 			c.updateEditorContent()
 			return c, nil
+		case "ctrl+r":
+			c.toggleReasoning()
+			return c, nil
 		case "ctrl+s":
 			return c, func() tea.Msg { return startSkillsMsg{} }
 		case "ctrl+u":
@@ -579,11 +753,140 @@ func (c ChatView) Update(msg tea.Msg) (ChatView, tea.Cmd) {
 					c.err = fmt.Errorf("message cannot be empty")
 					return c, nil
 				}
+				if query, ok := historyCommand(input); ok {
+					c.textarea.Reset()
+					return c, func() tea.Msg { return startHistoryMsg{query: query} }
+				}
+				if action, arg, ok := agentsCommand(input); ok {
+					switch {
+					case action == "export" && arg != "":
+						c.textarea.Reset()
+						return c, exportAgentCmd(c.agentService, arg)
+					case action == "import" && arg != "":
+						c.textarea.Reset()
+						return c, importAgentCmd(c.agentService, arg)
+					}
+					c.err = fmt.Errorf("usage: /agents export <name> or /agents import <file>")
+					return c, nil
+				}
+				if since, ok, err := reportCommand(input, time.Now()); ok {
+					if err != nil {
+						c.err = err
+						return c, nil
+					}
+					c.textarea.Reset()
+					return c, func() tea.Msg { return startReportMsg{since: since} }
+				}
 				if c.activeChat == nil {
 					c.err = fmt.Errorf("no active chat")
 					return c, nil
 				}
+				if tail, ok, err := resumeCommand(input); ok {
+					if err != nil {
+						c.err = err
+						return c, nil
+					}
+					c.textarea.Reset()
+					c.tail = tail
+					c.updateEditorContent()
+					return c, nil
+				}
+				if clearCommand(input) {
+					c.textarea.Reset()
+					c.addNotice("Summarizing the chat history...")
+					return c, clearContextCmd(c.chatService, c.activeChat.ID)
+				}
+				if preview, ok, err := compactCommand(input); ok {
+					if err != nil {
+						c.err = err
+						return c, nil
+					}
+					c.textarea.Reset()
+					if preview {
+						return c, previewCompactionCmd(c.chatService, c.activeChat.ID)
+					}
+					c.addNotice("Compacting the chat history...")
+					return c, compactChatCmd(c.chatService, c.activeChat.ID)
+				}
+				if regenerateCommand(input) {
+					c.textarea.Reset()
+					return c, c.resendLast("")
+				}
+				if content, ok, err := editCommand(input); ok {
+					if err != nil {
+						c.err = err
+						return c, nil
+					}
+					c.textarea.Reset()
+					return c, c.resendLast(content)
+				}
+				if pinsCommand(input) {
+					c.textarea.Reset()
+					c.addNotice(pinnedNotice(c.activeChat.PinnedFiles))
+					return c, nil
+				}
+				if paths, ok, err := unpinCommand(input); ok {
+					if err != nil {
+						c.err = err
+						return c, nil
+					}
+					c.textarea.Reset()
+					return c, unpinFilesCmd(c.chatService, c.activeChat.ID, paths)
+				}
+				if deleteCommand(input) {
+					c.textarea.Reset()
+					return c, deleteLastExchangeCmd(c.chatService, c.activeChat)
+				}
+				if instructions, ok := instructionsCommand(input); ok {
+					c.textarea.Reset()
+					return c, setInstructionsCmd(c.chatService, c.activeChat.ID, instructions)
+				}
+				if tools, ok, err := toolsCommand(input); ok {
+					if err != nil {
+						c.err = err
+						return c, nil
+					}
+					c.textarea.Reset()
+					return c, setToolsOverrideCmd(c.chatService, c.activeChat.ID, tools)
+				}
+				if path, show, ok := workspaceCommand(input); ok {
+					c.textarea.Reset()
+					if show {
+						c.addNotice(workspaceNotice(c.activeChat.Workspace))
+						return c, nil
+					}
+					return c, setWorkspaceCmd(c.chatService, c.activeChat.ID, path)
+				}
+				if notice, ok, err := overrideCommand(input, &c.overrides); ok {
+					if err != nil {
+						c.err = err
+						return c, nil
+					}
+					c.textarea.Reset()
+					c.addNotice(notice)
+					return c, nil
+				}
+				if action, policy, id, ok, err := approvalCommand(input, c.activeChat.ID); ok {
+					if err != nil {
+						c.err = err
+						return c, nil
+					}
+					c.textarea.Reset()
+					return c, approvalPolicyCmd(c.approvalService, c.activeChat.ID, action, policy, id)
+				}
+				if approve, ok := planCommand(input); ok {
+					c.textarea.Reset()
+					return c, resolvePlanCmd(c.chatService, c.activeChat.ID, approve)
+				}
+				attachments, err := messageAttachments(input)
+				if err != nil {
+					c.err = err
+					return c, nil
+				}
+				pins := messagePins(input, c.activeChat.Workspace)
 				message := entities.NewMessage("user", input)
+				message.Attachments = attachments
+				c.overrides.apply(message)
 				c.textarea.Reset()
 				c.textarea.SetHeight(2)
 				c.setEditorSize()
@@ -601,8 +904,15 @@ func (c ChatView) Update(msg tea.Msg) (ChatView, tea.Cmd) {
 				ctx, cancel := context.WithCancel(context.Background())
 				c.cancel = cancel
 				c.isProcessing = true
+				c.steering = false
 				c.startTime = time.Now()
-				return c, tea.Batch(commands.SendMessageCmd(c.chatService, c.activeChat.ID, message, ctx), c.spinner.Tick)
+				c.runUsage, c.shownRunUsage = runUsage{}, runUsage{}
+				send := commands.SendMessageCmd(c.chatService, c.activeChat.ID, message, ctx)
+				if len(pins) > 0 {
+					// Pin the files first so the answer to this message sees them
+					send = pinFilesCmd(c.chatService, c.activeChat.ID, pins, send)
+				}
+				return c, tea.Batch(send, c.spinner.Tick)
 			}
 		case "tab", "shift+tab":
 			if c.focused == "textarea" {
@@ -655,6 +965,7 @@ func (c ChatView) Update(msg tea.Msg) (ChatView, tea.Cmd) {
 
 	case spinner.TickMsg:
 		if c.isProcessing {
+			c.shownRunUsage = c.runUsage
 			var cmd tea.Cmd
 			c.spinner, cmd = c.spinner.Update(m)
 			return c, cmd
@@ -689,7 +1000,9 @@ func (c ChatView) Update(msg tea.Msg) (ChatView, tea.Cmd) {
 		ctx, cancel := context.WithCancel(context.Background())
 		c.cancel = cancel
 		c.isProcessing = true
+		c.steering = false
 		c.startTime = time.Now()
+		c.runUsage, c.shownRunUsage = runUsage{}, runUsage{}
 		return c, tea.Batch(commands.SendMessageCmd(c.chatService, c.activeChat.ID, message, ctx), c.spinner.Tick)
 
 	case toolCallEventMsg:
@@ -759,7 +1072,9 @@ func (c ChatView) Update(msg tea.Msg) (ChatView, tea.Cmd) {
 
 	case processFinishedEventMsg:
 		// Handle process finished event
+		var notify tea.Cmd
 		if c.isProcessing && c.activeChat != nil && m.ChatID == c.activeChat.ID {
+			notify = c.notifyEnd("response ready")
 			c.isProcessing = false
 			c.tempMessages = nil
 			c.toolCallStatus = make(map[string]bool)
@@ -777,11 +1092,13 @@ func (c ChatView) Update(msg tea.Msg) (ChatView, tea.Cmd) {
 
 			c.updateEditorContent()
 		}
-		return c, c.listenForEvents()
+		return c, tea.Batch(c.listenForEvents(), notify)
 
 	case processFailedEventMsg:
 		// Handle process failed event
+		var notify tea.Cmd
 		if c.isProcessing && c.activeChat != nil && m.ChatID == c.activeChat.ID {
+			notify = c.notifyEnd("response failed")
 			c.isProcessing = false
 			c.tempMessages = nil
 			c.toolCallStatus = make(map[string]bool)
@@ -805,6 +1122,17 @@ func (c ChatView) Update(msg tea.Msg) (ChatView, tea.Cmd) {
 			c.activeChat.Messages = append(c.activeChat.Messages, *errorMsg)
 			c.updateEditorContent()
 		}
+		return c, tea.Batch(c.listenForEvents(), notify)
+
+	case progressEventMsg:
+		if c.activeChat != nil && m.ChatID == c.activeChat.ID {
+			if m.Total == 0 {
+				c.progress = nil
+			} else {
+				c.progress = m
+			}
+			c.updateEditorContent()
+		}
 		return c, c.listenForEvents()
 
 	case chatUpdateEventMsg:
@@ -819,6 +1147,31 @@ func (c ChatView) Update(msg tea.Msg) (ChatView, tea.Cmd) {
 				if err == nil {
 					c.activeChat = updatedChat
 				}
+			} else if m.UpdateType == "run_usage" {
+				// Shown on the next spinner tick
+				if tokens, ok := m.Data["run_tokens"].(int); ok {
+					c.runUsage.tokens = tokens
+				}
+				if cost, ok := m.Data["run_cost"].(float64); ok {
+					c.runUsage.cost = cost
+				}
+			} else if m.UpdateType == "model" {
+				// The service switched models because of rate limits
+				ctx := context.Background()
+				if modelID, ok := m.Data["model_id"].(string); ok {
+					if model, err := c.modelService.GetModel(ctx, modelID); err == nil {
+						c.currentModel = model
+						c.activeChat.ModelID = model.ID
+					}
+				}
+				if notice, ok := m.Data["message"].(string); ok && notice != "" {
+					c.tempMessages = append(c.tempMessages, entities.Message{Role: "system", Content: notice})
+					c.updateEditorContent()
+				}
+			} else if m.UpdateType == "title" {
+				if name, ok := m.Data["name"].(string); ok && name != "" {
+					c.activeChat.Name = name
+				}
 			}
 		}
 		return c, c.listenForEvents()
@@ -923,6 +1276,56 @@ func (c ChatView) Update(msg tea.Msg) (ChatView, tea.Cmd) {
 	return c, tea.Batch(cmds...)
 }
 
+// resendLast answers the chat's last user message again, changed to content
+// unless it is empty, in place of the response shown.
+func (c *ChatView) resendLast(content string) tea.Cmd {
+	i := lastUserMessage(c.activeChat.Messages)
+	if i < 0 {
+		c.err = fmt.Errorf("the chat has no message to send again")
+		return nil
+	}
+	message := c.activeChat.Messages[i]
+	if content != "" {
+		message.Content = content
+	}
+	c.activeChat.Messages = append(c.activeChat.Messages[:i:i], message)
+	c.toolCallStatus = make(map[string]bool)
+	c.err = nil
+	c.updateEditorContent()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.isProcessing = true
+	c.steering = false
+	c.startTime = time.Now()
+	c.runUsage, c.shownRunUsage = runUsage{}, runUsage{}
+	cmd := commands.RegenerateCmd(c.chatService, c.activeChat.ID, ctx)
+	if content != "" {
+		cmd = commands.EditMessageCmd(c.chatService, c.activeChat.ID, message.ID, content, ctx)
+	}
+	return tea.Batch(cmd, c.spinner.Tick)
+}
+
+// runStatus is the elapsed time of the response being generated and, once
+// the provider has reported any, the tokens it used and their estimated
+// cost.
+func (c ChatView) runStatus() string {
+	status := fmt.Sprintf("%ds", int(time.Since(c.startTime).Round(time.Second).Seconds()))
+	if c.shownRunUsage.tokens > 0 {
+		status += fmt.Sprintf(", %s tokens, ~$%.2f", formatters.FormatTokenCount(c.shownRunUsage.tokens), c.shownRunUsage.cost)
+	}
+	return status
+}
+
+// notifyEnd announces the end of a response that ran for at least
+// notifyAfter, when notifications are on.
+func (c ChatView) notifyEnd(status string) tea.Cmd {
+	if !c.notify || time.Since(c.startTime) < notifyAfter {
+		return nil
+	}
+	return notifyCmd(c.activeChat.Name + ": " + status)
+}
+
 // setEditorSize calculates and sets the editor size based on available screen space
 func (c *ChatView) setEditorSize() {
 	// Set editor size to fit screen minus textarea, footer, separators, and header
@@ -936,6 +1339,25 @@ func (c *ChatView) setEditorSize() {
 	}
 }
 
+// contextInfo renders the chat's estimated context use, e.g. "42k/131k",
+// turning yellow as it nears the point the history is compressed and red
+// when it is about to be.
+func (c ChatView) contextInfo() string {
+	usage := c.contextUsage
+	if usage == nil || usage.Limit <= 0 {
+		return ""
+	}
+	color := lipgloss.Color("#888888")
+	switch trigger := float64(usage.Limit) * usage.TriggerRatio; {
+	case float64(usage.Tokens) >= trigger*0.9:
+		color = lipgloss.Color("1")
+	case float64(usage.Tokens) >= trigger*0.75:
+		color = lipgloss.Color("3")
+	}
+	text := fmt.Sprintf("%s/%s", formatters.FormatCompactTokenCount(usage.Tokens), formatters.FormatCompactTokenCount(usage.Limit))
+	return lipgloss.NewStyle().Foreground(color).Inline(true).Render(text)
+}
+
 func (c ChatView) View() string {
 	// Define styles
 	style := lipgloss.NewStyle().Width(c.width)
@@ -979,8 +1401,14 @@ func (c ChatView) View() string {
 
 	instructions := "Ctrl+P: menu | Tab: focus | Ctrl+C: exit"
 	if c.isProcessing {
-		elapsed := time.Since(c.startTime).Round(time.Second)
-		instructions = c.spinner.View() + fmt.Sprintf(" Working... (%ds) esc to interrupt", int(elapsed.Seconds()))
+		status := c.runStatus()
+		instructions = c.spinner.View() + fmt.Sprintf(" Working... (%s) ctrl+j to steer, esc to interrupt", status)
+		if c.progress != nil && c.progress.Current != "" {
+			instructions = c.spinner.View() + fmt.Sprintf(" Step %d of %d: %s (%s) ctrl+j to steer, esc to interrupt", min(c.progress.Completed+1, c.progress.Total), c.progress.Total, c.progress.Current, status)
+		}
+		if c.steering {
+			instructions = c.spinner.View() + " Steering: enter to send the note to the agent, esc to go back"
+		}
 	}
 
 	agentInfo := "No agent selected"
@@ -997,8 +1425,13 @@ func (c ChatView) View() string {
 
 	footerStyle := lipgloss.NewStyle().Width(c.width)
 	leftStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#888888")).Inline(true)
-	rightStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#888888")).Align(lipgloss.Right).Inline(true).Width(c.width - lipgloss.Width(instructions))
-	footerPart := footerStyle.Render(lipgloss.JoinHorizontal(lipgloss.Top, leftStyle.Render(instructions), rightStyle.Render(footerInfo)))
+	rightStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#888888")).Align(lipgloss.Right).Inline(true)
+	footerRight := rightStyle.Render(footerInfo)
+	if contextInfo := c.contextInfo(); contextInfo != "" {
+		footerRight = contextInfo + rightStyle.Render(" | ") + footerRight
+	}
+	footerRight = lipgloss.NewStyle().Align(lipgloss.Right).Width(c.width - lipgloss.Width(instructions)).Render(footerRight)
+	footerPart := footerStyle.Render(lipgloss.JoinHorizontal(lipgloss.Top, leftStyle.Render(instructions), footerRight))
 
 	return lipgloss.JoinVertical(lipgloss.Top, editorPart, header, separator, textareaPart, separator, footerPart)
 }
@@ -30,6 +30,9 @@ func NewCommandMenu() CommandMenu {
 		CommandItem{name: "tools", desc: "View available tools (Ctrl+T)"},
 		CommandItem{name: "models", desc: "View available models (Ctrl+O)"},
 		CommandItem{name: "usage", desc: "Show usage statistics (Ctrl+U)"},
+		CommandItem{name: "report", desc: "Show usage and cost across all chats for the last 30 days"},
+		CommandItem{name: "reasoning", desc: "Show or hide model reasoning (Ctrl+R)"},
+		CommandItem{name: "export", desc: "Export the chat to markdown in .aiagent/exports"},
 		CommandItem{name: "exit", desc: "Exit the application (Ctrl+C)"},
 	}
 
@@ -10,16 +10,44 @@ import (
 	"github.com/drujensen/aiagent/internal/domain/services"
 )
 
-type updatedChatMsg *entities.Chat
+// UpdatedChatMsg carries the chat as saved once SendMessageCmd finishes,
+// including the partial answer of a cancelled turn.
+type UpdatedChatMsg *entities.Chat
 type errMsg error
 
 func SendMessageCmd(cs services.ChatService, chatID string, msg *entities.Message, ctx context.Context) tea.Cmd {
+	return turnCmd(cs, chatID, ctx, func(ctx context.Context) error {
+		_, err := cs.SendMessage(ctx, chatID, msg)
+		return err
+	})
+}
+
+// EditMessageCmd changes a user message and answers it again, finishing like
+// SendMessageCmd.
+func EditMessageCmd(cs services.ChatService, chatID, messageID, content string, ctx context.Context) tea.Cmd {
+	return turnCmd(cs, chatID, ctx, func(ctx context.Context) error {
+		_, err := cs.EditMessage(ctx, chatID, messageID, content)
+		return err
+	})
+}
+
+// RegenerateCmd replaces the response to the last user message, finishing
+// like SendMessageCmd.
+func RegenerateCmd(cs services.ChatService, chatID string, ctx context.Context) tea.Cmd {
+	return turnCmd(cs, chatID, ctx, func(ctx context.Context) error {
+		_, err := cs.RegenerateLast(ctx, chatID)
+		return err
+	})
+}
+
+// turnCmd runs a turn of the chat and reports the chat as saved afterwards.
+func turnCmd(cs services.ChatService, chatID string, ctx context.Context, run func(ctx context.Context) error) tea.Cmd {
 	return func() tea.Msg {
 		// Add a very long timeout to allow for complex operations (1 hour)
 		cmdCtx, cmdCancel := context.WithTimeout(ctx, 1*time.Hour)
 		defer cmdCancel()
 
-		_, err := cs.SendMessage(cmdCtx, chatID, msg)
+		err := run(cmdCtx)
 		if err != nil {
 			if cmdCtx.Err() == context.Canceled {
 				// User cancelled - try to get partial results
@@ -32,7 +60,7 @@ func SendMessageCmd(cs services.ChatService, chatID string, msg *entities.Messag
 						Role:    "system",
 					}
 					updatedChat.Messages = append(updatedChat.Messages, *noticeMsg)
-					return updatedChatMsg(updatedChat)
+					return UpdatedChatMsg(updatedChat)
 				}
 				return errMsg(fmt.Errorf("operation cancelled by user - no results available"))
 			} else if cmdCtx.Err() == context.DeadlineExceeded {
@@ -46,7 +74,7 @@ func SendMessageCmd(cs services.ChatService, chatID string, msg *entities.Messag
 						Role:    "system",
 					}
 					updatedChat.Messages = append(updatedChat.Messages, *noticeMsg)
-					return updatedChatMsg(updatedChat)
+					return UpdatedChatMsg(updatedChat)
 				}
 				return errMsg(fmt.Errorf("operation timed out after 1 hour - no results available"))
 			} else {
@@ -61,7 +89,7 @@ func SendMessageCmd(cs services.ChatService, chatID string, msg *entities.Messag
 						Role:    "system",
 					}
 					updatedChat.Messages = append(updatedChat.Messages, *errorMsg)
-					return updatedChatMsg(updatedChat)
+					return UpdatedChatMsg(updatedChat)
 				}
 				return errMsg(err)
 			}
@@ -73,6 +101,6 @@ func SendMessageCmd(cs services.ChatService, chatID string, msg *entities.Messag
 		if err != nil {
 			return errMsg(err)
 		}
-		return updatedChatMsg(updatedChat)
+		return UpdatedChatMsg(updatedChat)
 	}
 }
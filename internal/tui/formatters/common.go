@@ -132,6 +132,18 @@ func FormatTokenCount(count int) string {
 	return reversedString(result.String())
 }
 
+// FormatCompactTokenCount formats a token count in thousands or millions,
+// e.g. 42k or 1.2M
+func FormatCompactTokenCount(count int) string {
+	switch {
+	case count < 1000:
+		return fmt.Sprintf("%d", count)
+	case count < 1000000:
+		return fmt.Sprintf("%dk", (count+500)/1000)
+	}
+	return fmt.Sprintf("%.1fM", float64(count)/1000000)
+}
+
 // reversedString returns the reverse of a string
 func reversedString(s string) string {
 	runes := []rune(s)
@@ -3,6 +3,10 @@ package tui
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
@@ -19,33 +23,401 @@ type HistoryView struct {
 }
 
 func NewHistoryView(chatService services.ChatService) HistoryView {
-	ctx := context.Background()
-	chats, err := chatService.ListChats(ctx)
-	if err != nil {
-		fmt.Printf("Error listing chats: %v\n", err)
-		chats = []*entities.Chat{}
-	}
-
-	items := make([]list.Item, len(chats))
-	for i, chat := range chats {
-		items[i] = chat
-	}
-
 	delegate := list.NewDefaultDelegate()
 	delegate.Styles.SelectedTitle = delegate.Styles.SelectedTitle.Foreground(lipgloss.Color("6")).Bold(true)
 	delegate.Styles.SelectedDesc = delegate.Styles.SelectedDesc.Foreground(lipgloss.Color("7"))
 	delegate.SetHeight(2)
 
-	l := list.New(items, delegate, 100, 10)
-	l.Title = "Chat History"
+	l := list.New([]list.Item{}, delegate, 100, 10)
 	l.SetShowStatusBar(false)
 	l.SetFilteringEnabled(true)
 	l.SetShowPagination(true)
 
-	return HistoryView{
+	h := HistoryView{
 		chatService: chatService,
 		list:        l,
 	}
+	h.Load("")
+	return h
+}
+
+// Load refreshes the list with the chats matching query, or all chats when
+// query is empty.
+func (h *HistoryView) Load(query string) {
+	ctx := context.Background()
+	chats, err := h.chatService.SearchChats(ctx, query)
+	if err != nil {
+		fmt.Printf("Error listing chats: %v\n", err)
+		chats = []*entities.Chat{}
+	}
+
+	h.list.ResetFilter()
+	h.list.SetItems(historyItems(chats, projectNames(ctx, h.chatService)))
+	h.list.Select(0)
+	if query == "" {
+		h.list.Title = "Chat History"
+	} else {
+		h.list.Title = fmt.Sprintf("Chat History: %q (%d)", query, len(chats))
+	}
+}
+
+// historyCommand parses "/history [query]" typed in the message input.
+func historyCommand(input string) (string, bool) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 || fields[0] != "/history" {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(input), "/history")), true
+}
+
+// planCommand recognizes "/approve" and "/discard" typed in the message input,
+// which approve or discard the chat's pending plan.
+func planCommand(input string) (approve bool, ok bool) {
+	switch strings.TrimSpace(input) {
+	case "/approve":
+		return true, true
+	case "/discard":
+		return false, true
+	}
+	return false, false
+}
+
+// approvalCommand parses the approval policy commands typed in the message
+// input: "/approve list", "/approve remove <id>" and
+// "/approve session|chat|global [tool|*] [pattern]". A missing tool or "*"
+// approves every tool, and the pattern is matched against the call's command.
+// A bare "/approve" is left to planCommand.
+func approvalCommand(input, chatID string) (action string, policy *entities.ApprovalPolicy, id string, ok bool, err error) {
+	fields := strings.Fields(input)
+	if len(fields) < 2 || fields[0] != "/approve" {
+		return "", nil, "", false, nil
+	}
+	usage := fmt.Errorf("usage: /approve session|chat|global [tool|*] [pattern], /approve list or /approve remove <id>")
+	switch fields[1] {
+	case "list":
+		if len(fields) != 2 {
+			return "", nil, "", true, usage
+		}
+		return "list", nil, "", true, nil
+	case "remove":
+		if len(fields) != 3 {
+			return "", nil, "", true, usage
+		}
+		return "remove", nil, fields[2], true, nil
+	case string(entities.ApprovalScopeSession), string(entities.ApprovalScopeChat), string(entities.ApprovalScopeGlobal):
+		scope := entities.ApprovalScope(fields[1])
+		toolName, pattern := "", ""
+		if len(fields) > 2 && fields[2] != "*" {
+			toolName = fields[2]
+		}
+		if len(fields) > 3 {
+			rest := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(input), "/approve"))
+			rest = strings.TrimSpace(strings.TrimPrefix(rest, fields[1]))
+			pattern = strings.TrimSpace(strings.TrimPrefix(rest, fields[2]))
+		}
+		policyChatID := ""
+		if scope == entities.ApprovalScopeChat {
+			policyChatID = chatID
+		}
+		return "add", entities.NewApprovalPolicy(scope, policyChatID, toolName, pattern), "", true, nil
+	}
+	return "", nil, "", true, usage
+}
+
+// instructionsCommand parses "/instructions [text]" typed in the message
+// input. Without text the chat's instructions are removed.
+func instructionsCommand(input string) (string, bool) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 || fields[0] != "/instructions" {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(input), "/instructions")), true
+}
+
+// toolsCommand parses "/tools set <names>|none" and "/tools reset" typed in
+// the message input. Reset returns nil tools, going back to the agent's.
+func toolsCommand(input string) (tools []string, ok bool, err error) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 || fields[0] != "/tools" {
+		return nil, false, nil
+	}
+	switch {
+	case len(fields) == 2 && fields[1] == "reset":
+		return nil, true, nil
+	case len(fields) > 2 && fields[1] == "set":
+		rest := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(input), "/tools"))
+		return entities.ParseToolNames(strings.TrimPrefix(rest, "set")), true, nil
+	}
+	return nil, true, fmt.Errorf("usage: /tools set <name, ...>|none or /tools reset")
+}
+
+// workspaceCommand parses "/workspace [path|reset]" typed in the message
+// input. Without a path show is set, and reset returns an empty path, going
+// back to the workspace the tools are configured with.
+func workspaceCommand(input string) (path string, show, ok bool) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 || fields[0] != "/workspace" {
+		return "", false, false
+	}
+	rest := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(input), "/workspace"))
+	switch rest {
+	case "":
+		return "", true, true
+	case "reset":
+		return "", false, true
+	}
+	return rest, false, true
+}
+
+// messageOverrides holds the temperature and max tokens set with "/temp" and
+// "/maxtokens" for the next message.
+type messageOverrides struct {
+	temperature *float64
+	maxTokens   *int
+}
+
+// apply moves the overrides onto the message, so they are used once.
+func (o *messageOverrides) apply(message *entities.Message) {
+	message.Temperature = o.temperature
+	message.MaxTokens = o.maxTokens
+	*o = messageOverrides{}
+}
+
+// overrideCommand parses "/temp <value>|reset" and "/maxtokens <n>|reset"
+// typed in the message input into the overrides and returns a notice.
+func overrideCommand(input string, overrides *messageOverrides) (notice string, ok bool, err error) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 || (fields[0] != "/temp" && fields[0] != "/maxtokens") {
+		return "", false, nil
+	}
+	if len(fields) != 2 {
+		return "", true, fmt.Errorf("usage: %s <value>|reset", fields[0])
+	}
+
+	next := entities.Message{Temperature: overrides.temperature, MaxTokens: overrides.maxTokens}
+	switch {
+	case fields[0] == "/temp" && fields[1] == "reset":
+		next.Temperature = nil
+		notice = "The next message uses the model's temperature"
+	case fields[0] == "/temp":
+		temperature, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return "", true, fmt.Errorf("invalid temperature: %s", fields[1])
+		}
+		next.Temperature = &temperature
+		notice = fmt.Sprintf("The next message uses temperature %g", temperature)
+	case fields[1] == "reset":
+		next.MaxTokens = nil
+		notice = "The next message uses the model's max tokens"
+	default:
+		maxTokens, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return "", true, fmt.Errorf("invalid max tokens: %s", fields[1])
+		}
+		next.MaxTokens = &maxTokens
+		notice = fmt.Sprintf("The next message uses up to %d tokens", maxTokens)
+	}
+	if err := next.ValidateOverrides(); err != nil {
+		return "", true, err
+	}
+
+	overrides.temperature = next.Temperature
+	overrides.maxTokens = next.MaxTokens
+	return notice, true, nil
+}
+
+// agentsCommand parses "/agents export <name>" and "/agents import <file>"
+// typed in the message input.
+func agentsCommand(input string) (action, arg string, ok bool) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 || fields[0] != "/agents" {
+		return "", "", false
+	}
+	if len(fields) > 1 {
+		action = fields[1]
+		rest := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(input), "/agents"))
+		arg = strings.TrimSpace(strings.TrimPrefix(rest, action))
+	}
+	return action, arg, true
+}
+
+// defaultResumeTail is how many exchanges "/resume" shows without a count.
+const defaultResumeTail = 10
+
+// resumeCommand parses "/resume [N|all]" typed in the message input. It
+// returns how many of the latest exchanges to show, where zero shows them
+// all.
+func resumeCommand(input string) (tail int, ok bool, err error) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 || fields[0] != "/resume" {
+		return 0, false, nil
+	}
+	switch {
+	case len(fields) == 1:
+		return defaultResumeTail, true, nil
+	case len(fields) == 2 && fields[1] == "all":
+		return 0, true, nil
+	case len(fields) == 2:
+		n, err := strconv.Atoi(fields[1])
+		if err != nil || n < 0 {
+			return 0, true, fmt.Errorf("usage: /resume [N|all]")
+		}
+		return n, true, nil
+	}
+	return 0, true, fmt.Errorf("usage: /resume [N|all]")
+}
+
+// defaultReportDays is how many days "/report" covers without a count.
+const defaultReportDays = 30
+
+// reportCommand parses "/report [days|all]" typed in the message input. It
+// returns the start of the reported period, which is zero for all time.
+func reportCommand(input string, now time.Time) (since time.Time, ok bool, err error) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 || fields[0] != "/report" {
+		return time.Time{}, false, nil
+	}
+	days := defaultReportDays
+	switch {
+	case len(fields) == 2 && fields[1] == "all":
+		return time.Time{}, true, nil
+	case len(fields) == 2:
+		n, err := strconv.Atoi(fields[1])
+		if err != nil || n <= 0 {
+			return time.Time{}, true, fmt.Errorf("usage: /report [days|all]")
+		}
+		days = n
+	case len(fields) > 2:
+		return time.Time{}, true, fmt.Errorf("usage: /report [days|all]")
+	}
+	year, month, day := now.Date()
+	return time.Date(year, month, day-days+1, 0, 0, 0, 0, now.Location()), true, nil
+}
+
+// clearCommand recognizes "/clear" typed in the message input, which
+// replaces the chat's history with a summary of it.
+func clearCommand(input string) bool {
+	return strings.TrimSpace(input) == "/clear"
+}
+
+// compactCommand parses "/compact [preview]" typed in the message input,
+// which summarizes the older messages now, or shows which would be.
+func compactCommand(input string) (preview bool, ok bool, err error) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 || fields[0] != "/compact" {
+		return false, false, nil
+	}
+	switch {
+	case len(fields) == 1:
+		return false, true, nil
+	case len(fields) == 2 && fields[1] == "preview":
+		return true, true, nil
+	}
+	return false, true, fmt.Errorf("usage: /compact [preview]")
+}
+
+// regenerateCommand recognizes "/regenerate" typed in the message input,
+// which replaces the response to the last message.
+func regenerateCommand(input string) bool {
+	return strings.TrimSpace(input) == "/regenerate"
+}
+
+// editCommand parses "/edit <text>" typed in the message input, which
+// changes the last message to the text and sends it again.
+func editCommand(input string) (content string, ok bool, err error) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 || fields[0] != "/edit" {
+		return "", false, nil
+	}
+	content = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(input), "/edit"))
+	if content == "" {
+		return "", true, fmt.Errorf("usage: /edit <new message>")
+	}
+	return content, true, nil
+}
+
+// deleteCommand recognizes "/delete" typed in the message input, which
+// deletes the last message and its responses.
+func deleteCommand(input string) bool {
+	return strings.TrimSpace(input) == "/delete"
+}
+
+// pinsCommand recognizes "/pins" typed in the message input, which lists
+// the files pinned to the chat.
+func pinsCommand(input string) bool {
+	return strings.TrimSpace(input) == "/pins"
+}
+
+// unpinCommand parses "/unpin <path>..." and "/unpin all" typed in the
+// message input. All returns no paths, unpinning every file.
+func unpinCommand(input string) (paths []string, ok bool, err error) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 || fields[0] != "/unpin" {
+		return nil, false, nil
+	}
+	switch {
+	case len(fields) == 1:
+		return nil, true, fmt.Errorf("usage: /unpin <path>... or /unpin all")
+	case len(fields) == 2 && fields[1] == "all":
+		return nil, true, nil
+	}
+	return fields[1:], true, nil
+}
+
+// historyItem is a chat in the history list labelled with its project.
+type historyItem struct {
+	chat    *entities.Chat
+	project string
+}
+
+func (i historyItem) FilterValue() string {
+	return i.project + " " + i.chat.FilterValue()
+}
+
+func (i historyItem) Title() string {
+	return i.chat.Title()
+}
+
+func (i historyItem) Description() string {
+	if i.project == "" {
+		return i.chat.Description()
+	}
+	return i.project + " | " + i.chat.Description()
+}
+
+func projectNames(ctx context.Context, chatService services.ChatService) map[string]string {
+	names := make(map[string]string)
+	projects, err := chatService.ListProjects(ctx)
+	if err != nil {
+		return names
+	}
+	for _, project := range projects {
+		names[project.ID] = project.Name
+	}
+	return names
+}
+
+// historyItems groups the chats by project name, keeping the most recent
+// chats first within each project. Chats without a project come last.
+func historyItems(chats []*entities.Chat, projects map[string]string) []list.Item {
+	grouped := make([]historyItem, len(chats))
+	for i, chat := range chats {
+		grouped[i] = historyItem{chat: chat, project: projects[chat.ProjectID]}
+	}
+
+	sort.SliceStable(grouped, func(i, j int) bool {
+		a, b := grouped[i].project, grouped[j].project
+		if a == "" || b == "" {
+			return a != "" && b == ""
+		}
+		return strings.ToLower(a) < strings.ToLower(b)
+	})
+
+	items := make([]list.Item, len(grouped))
+	for i, item := range grouped {
+		items[i] = item
+	}
+	return items
 }
 
 func (h HistoryView) Init() tea.Cmd {
@@ -66,8 +438,8 @@ func (h HistoryView) Update(msg tea.Msg) (HistoryView, tea.Cmd) {
 			return h, func() tea.Msg { return historyCancelledMsg{} }
 		case "enter":
 			if selected := h.list.SelectedItem(); selected != nil {
-				chat := selected.(*entities.Chat)
-				return h, func() tea.Msg { return historySelectedMsg{chatID: chat.ID} }
+				item := selected.(historyItem)
+				return h, func() tea.Msg { return historySelectedMsg{chatID: item.chat.ID} }
 			}
 		}
 	}
@@ -1,7 +1,10 @@
 package tui
 
 import (
+	"time"
+
 	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/drujensen/aiagent/internal/domain/entities"
 )
 
@@ -15,10 +18,13 @@ type (
 	processFailedEventMsg   *entities.ProcessFailedEvent
 	chatUpdateEventMsg      *entities.ChatUpdateEvent
 	subAgentEventMsg        *entities.SubAgentEvent
+	progressEventMsg        *entities.ProgressEvent
 )
 
 type (
-	startHistoryMsg    struct{}
+	startHistoryMsg struct {
+		query string
+	}
 	historySelectedMsg struct {
 		chatID string
 	}
@@ -31,6 +37,10 @@ type (
 		info string
 	}
 	usageCancelledMsg struct{}
+	// startReportMsg shows the usage of all chats since a time; zero is all time
+	startReportMsg struct {
+		since time.Time
+	}
 )
 
 type (
@@ -53,8 +63,94 @@ type (
 	startCommandsMsg     struct{}
 	executeCommandMsg    struct{ command string }
 	commandsCancelledMsg struct{}
+	chatExportedMsg      struct {
+		path string
+		err  error
+	}
 )
 
+type (
+	agentExportedMsg struct {
+		path string
+		err  error
+	}
+	agentImportedMsg struct {
+		agent *entities.Agent
+		err   error
+	}
+)
+
+type instructionsSetMsg struct {
+	instructions string
+	err          error
+}
+
+type steeredMsg struct {
+	note string
+	err  error
+}
+
+type toolsOverrideSetMsg struct {
+	tools []string
+	err   error
+}
+
+type workspaceSetMsg struct {
+	workspace string
+	err       error
+}
+
+type contextUsageMsg struct {
+	chatID string
+	usage  *entities.ContextUsage
+}
+
+type contextClearedMsg struct {
+	chat *entities.Chat
+	err  error
+}
+
+type chatCompactedMsg struct {
+	chat          *entities.Chat
+	before, after *entities.ContextUsage
+	err           error
+}
+
+type compactionPreviewMsg struct {
+	preview *entities.CompactionPreview
+	err     error
+}
+
+// filesPinnedMsg reports files pinned for a message, with the command that
+// sends it.
+type filesPinnedMsg struct {
+	chatID string
+	pinned []string
+	send   tea.Cmd
+}
+
+type filesUnpinnedMsg struct {
+	chatID string
+	pinned []string
+	err    error
+}
+
+type exchangeDeletedMsg struct {
+	chat *entities.Chat
+	err  error
+}
+
+type approvalPoliciesMsg struct {
+	notice string
+	err    error
+}
+
+type planResolvedMsg struct {
+	approved bool
+	result   *entities.Message
+	err      error
+}
+
 type errMsg error
 
 type (
@@ -3,6 +3,8 @@ package tui
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -10,6 +12,8 @@ import (
 	"github.com/drujensen/aiagent/internal/domain/entities"
 	"github.com/drujensen/aiagent/internal/domain/services"
 	"github.com/drujensen/aiagent/internal/impl/config"
+	"github.com/drujensen/aiagent/internal/tui/commands"
+	"github.com/drujensen/aiagent/internal/tui/formatters"
 	"go.uber.org/zap"
 )
 
@@ -20,6 +24,7 @@ type TUI struct {
 	providerService    services.ProviderService
 	toolService        services.ToolService
 	skillService       services.SkillService
+	approvalService    services.ApprovalService
 	modelFilterService *services.ModelFilterService
 	globalConfig       *config.GlobalConfig
 	logger             *zap.Logger
@@ -38,7 +43,7 @@ type TUI struct {
 	err   error
 }
 
-func NewTUI(chatService services.ChatService, agentService services.AgentService, modelService services.ModelService, providerService services.ProviderService, toolService services.ToolService, skillService services.SkillService, modelFilterService *services.ModelFilterService, globalConfig *config.GlobalConfig, logger *zap.Logger) TUI {
+func NewTUI(chatService services.ChatService, agentService services.AgentService, modelService services.ModelService, providerService services.ProviderService, toolService services.ToolService, skillService services.SkillService, approvalService services.ApprovalService, modelFilterService *services.ModelFilterService, globalConfig *config.GlobalConfig, logger *zap.Logger, tail int) TUI {
 	ctx := context.Background()
 
 	activeChat, err := chatService.GetActiveChat(ctx)
@@ -55,12 +60,13 @@ func NewTUI(chatService services.ChatService, agentService services.AgentService
 		providerService:    providerService,
 		toolService:        toolService,
 		skillService:       skillService,
+		approvalService:    approvalService,
 		modelFilterService: modelFilterService,
 		globalConfig:       globalConfig,
 		logger:             logger,
 		activeChat:         activeChat,
 
-		chatView:    NewChatView(chatService, agentService, modelService, toolService, skillService, logger, activeChat),
+		chatView:    NewChatView(chatService, agentService, modelService, toolService, skillService, approvalService, logger, activeChat, tail, globalConfig.Spinner, globalConfig.DesktopNotify),
 		historyView: NewHistoryView(chatService),
 		usageView:   NewUsageView(chatService, agentService, modelService),
 		agentView:   NewAgentView(agentService),
@@ -87,6 +93,8 @@ func (t TUI) Init() tea.Cmd {
 
 	if t.activeChat == nil {
 		cmds = append(cmds, t.autoCreateChatCmd())
+	} else {
+		cmds = append(cmds, contextUsageCmd(t.chatService, t.activeChat.ID))
 	}
 
 	return tea.Batch(cmds...)
@@ -98,6 +106,8 @@ func (t TUI) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	// Handle chat view messages
 	case startAutoCreateChatMsg:
 		return t, t.autoCreateChatCmd()
+	case commands.UpdatedChatMsg:
+		return t.Update(updatedChatMsg(msg))
 	case updatedChatMsg:
 		t.activeChat = msg
 		t.state = "chat/view"
@@ -105,7 +115,7 @@ func (t TUI) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		t.chatView, cmd = t.chatView.Update(msg)
 		// Clear any potential error that occurred during update
 		t.chatView.err = nil
-		return t, cmd
+		return t, tea.Batch(cmd, contextUsageCmd(t.chatService, msg.ID))
 	case chatCreatedMsg:
 		ctx := context.Background()
 		err := t.chatService.SetActiveChat(ctx, msg.ID)
@@ -117,9 +127,10 @@ func (t TUI) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		t.state = "chat/view"
 		// Clear any error that might have occurred during initialization
 		t.chatView.err = nil
-		return t, nil
+		return t, contextUsageCmd(t.chatService, msg.ID)
 	// Handle history view messages
 	case startHistoryMsg:
+		t.historyView.Load(msg.query)
 		t.state = "chat/history"
 		return t, t.historyView.Init()
 	case historySelectedMsg:
@@ -134,6 +145,7 @@ func (t TUI) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		t.activeChat = chat
 		t.chatView.activeChat = chat
+		t.chatView.contextUsage = nil
 		ctx2 := context.Background()
 		agent, err := t.agentService.GetAgent(ctx2, chat.AgentID)
 		if err != nil {
@@ -151,7 +163,7 @@ func (t TUI) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		t.chatView.updateEditorContent()
 		t.state = "chat/view"
-		return t, nil
+		return t, contextUsageCmd(t.chatService, chat.ID)
 	case historyCancelledMsg:
 		t.state = "chat/view"
 		if t.activeChat != nil {
@@ -163,6 +175,9 @@ func (t TUI) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case startUsageMsg:
 		t.state = "chat/usage"
 		return t, t.usageView.Init()
+	case startReportMsg:
+		t.state = "chat/usage"
+		return t, t.usageView.ReportCmd(msg.since)
 	case usageCancelledMsg:
 		t.state = "chat/view"
 		if t.activeChat != nil {
@@ -301,6 +316,7 @@ func (t TUI) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "new":
 			return t, t.autoCreateChatCmd()
 		case "history":
+			t.historyView.Load("")
 			t.state = "chat/history"
 			return t, t.historyView.Init()
 		case "agents":
@@ -312,6 +328,14 @@ func (t TUI) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "usage":
 			t.state = "chat/usage"
 			return t, t.usageView.Init()
+		case "report":
+			since, _, _ := reportCommand("/report", time.Now())
+			t.state = "chat/usage"
+			return t, t.usageView.ReportCmd(since)
+		case "reasoning":
+			t.chatView.toggleReasoning()
+		case "export":
+			return t, t.exportChatCmd()
 		case "models":
 			t.state = "models/list"
 			t.modelView.SetMode("switch")
@@ -321,6 +345,189 @@ func (t TUI) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return t, nil
 
+	case chatExportedMsg:
+		notice := "Chat exported to " + msg.path
+		if msg.err != nil {
+			notice = "Failed to export chat: " + msg.err.Error()
+		}
+		if t.chatView.activeChat != nil {
+			t.chatView.activeChat.Messages = append(t.chatView.activeChat.Messages, entities.Message{Role: "system", Content: notice})
+			t.chatView.updateEditorContent()
+		}
+		return t, nil
+
+	case agentExportedMsg:
+		notice := "Agent exported to " + msg.path
+		if msg.err != nil {
+			notice = "Failed to export agent: " + msg.err.Error()
+		}
+		t.chatView.addNotice(notice)
+		return t, nil
+
+	case agentImportedMsg:
+		if msg.err != nil {
+			t.chatView.addNotice("Failed to import agent: " + msg.err.Error())
+			return t, nil
+		}
+		t.chatView.addNotice(fmt.Sprintf("Imported agent %s", msg.agent.Name))
+		return t, nil
+
+	case instructionsSetMsg:
+		notice := "Session instructions set"
+		switch {
+		case msg.err != nil:
+			notice = "Failed to set instructions: " + msg.err.Error()
+		case msg.instructions == "":
+			notice = "Session instructions removed"
+		}
+		if t.chatView.activeChat != nil {
+			if msg.err == nil {
+				t.chatView.activeChat.Instructions = msg.instructions
+			}
+			t.chatView.activeChat.Messages = append(t.chatView.activeChat.Messages, entities.Message{Role: "system", Content: notice})
+			t.chatView.updateEditorContent()
+		}
+		return t, nil
+
+	case steeredMsg:
+		if msg.err != nil {
+			t.chatView.addNotice("Failed to steer the response: " + msg.err.Error())
+			return t, nil
+		}
+		t.chatView.tempMessages = append(t.chatView.tempMessages, *entities.NewMessage("user", msg.note))
+		t.chatView.updateEditorContent()
+		return t, nil
+
+	case toolsOverrideSetMsg:
+		notice := "Chat tools set to " + entities.FormatToolNames(msg.tools)
+		switch {
+		case msg.err != nil:
+			notice = "Failed to set tools: " + msg.err.Error()
+		case msg.tools == nil:
+			notice = "Chat tools reset to the agent's"
+		}
+		if t.chatView.activeChat != nil {
+			if msg.err == nil {
+				t.chatView.activeChat.ToolsOverride = msg.tools
+			}
+			t.chatView.activeChat.Messages = append(t.chatView.activeChat.Messages, entities.Message{Role: "system", Content: notice})
+			t.chatView.updateEditorContent()
+		}
+		return t, nil
+
+	case workspaceSetMsg:
+		if msg.err != nil {
+			t.chatView.addNotice("Failed to set the workspace: " + msg.err.Error())
+			return t, nil
+		}
+		if t.chatView.activeChat != nil {
+			t.chatView.activeChat.Workspace = msg.workspace
+		}
+		t.chatView.addNotice(workspaceNotice(msg.workspace))
+		return t, nil
+
+	case contextClearedMsg:
+		if msg.err != nil {
+			t.chatView.addNotice("Failed to clear the context: " + msg.err.Error())
+			return t, nil
+		}
+		if t.activeChat != nil && t.activeChat.ID == msg.chat.ID {
+			t.activeChat = msg.chat
+			t.chatView.activeChat = msg.chat
+			t.chatView.addNotice("Context cleared; the earlier messages were replaced by a summary")
+			return t, contextUsageCmd(t.chatService, msg.chat.ID)
+		}
+		return t, nil
+
+	case chatCompactedMsg:
+		if msg.err != nil {
+			t.chatView.addNotice("Failed to compact the chat: " + msg.err.Error())
+			return t, nil
+		}
+		if t.activeChat != nil && t.activeChat.ID == msg.chat.ID {
+			t.activeChat = msg.chat
+			t.chatView.activeChat = msg.chat
+			t.chatView.contextUsage = msg.after
+			t.chatView.addNotice(fmt.Sprintf("Compacted the chat: %s → %s tokens, saving %s",
+				formatters.FormatTokenCount(msg.before.Tokens),
+				formatters.FormatTokenCount(msg.after.Tokens),
+				formatters.FormatTokenCount(msg.before.Tokens-msg.after.Tokens)))
+		}
+		return t, nil
+
+	case compactionPreviewMsg:
+		if msg.err != nil {
+			t.chatView.addNotice("Failed to preview compaction: " + msg.err.Error())
+			return t, nil
+		}
+		t.chatView.addNotice(fmt.Sprintf("/compact would summarize messages 1-%d of %d (~%s tokens) and keep the rest",
+			msg.preview.To, msg.preview.Total, formatters.FormatTokenCount(msg.preview.SummarizedTokens)))
+		return t, nil
+
+	case filesPinnedMsg:
+		if t.chatView.activeChat != nil && t.chatView.activeChat.ID == msg.chatID {
+			t.chatView.activeChat.PinnedFiles = msg.pinned
+			t.chatView.addNotice(pinnedNotice(msg.pinned))
+		}
+		return t, msg.send
+
+	case filesUnpinnedMsg:
+		if msg.err != nil {
+			t.chatView.addNotice("Failed to unpin: " + msg.err.Error())
+			return t, nil
+		}
+		if t.chatView.activeChat != nil && t.chatView.activeChat.ID == msg.chatID {
+			t.chatView.activeChat.PinnedFiles = msg.pinned
+			t.chatView.addNotice(pinnedNotice(msg.pinned))
+		}
+		return t, nil
+
+	case exchangeDeletedMsg:
+		if msg.err != nil {
+			t.chatView.addNotice("Failed to delete the message: " + msg.err.Error())
+			return t, nil
+		}
+		if t.activeChat != nil && t.activeChat.ID == msg.chat.ID {
+			t.activeChat = msg.chat
+			t.chatView.activeChat = msg.chat
+			t.chatView.addNotice("Deleted the last message and its responses")
+			return t, contextUsageCmd(t.chatService, msg.chat.ID)
+		}
+		return t, nil
+
+	case contextUsageMsg:
+		if t.chatView.activeChat != nil && t.chatView.activeChat.ID == msg.chatID {
+			t.chatView.contextUsage = msg.usage
+		}
+		return t, nil
+
+	case approvalPoliciesMsg:
+		if msg.err != nil {
+			t.chatView.addNotice("Failed to update approvals: " + msg.err.Error())
+			return t, nil
+		}
+		t.chatView.addNotice(msg.notice)
+		return t, nil
+
+	case planResolvedMsg:
+		if t.chatView.activeChat != nil {
+			var notice entities.Message
+			switch {
+			case msg.err != nil:
+				notice = entities.Message{Role: "system", Content: "Failed to resolve plan: " + msg.err.Error()}
+			case msg.approved:
+				notice = *msg.result
+			default:
+				notice = entities.Message{Role: "system", Content: "Plan discarded."}
+			}
+			if msg.err == nil {
+				t.chatView.activeChat.PendingPlan = nil
+			}
+			t.chatView.activeChat.Messages = append(t.chatView.activeChat.Messages, notice)
+			t.chatView.updateEditorContent()
+		}
+		return t, nil
+
 	case commandsCancelledMsg:
 		t.state = "chat/view"
 		if t.activeChat != nil {
@@ -515,3 +722,287 @@ func (t *TUI) autoCreateChatCmd() tea.Cmd {
 		return chatCreatedMsg(chat)
 	}
 }
+
+// setInstructionsCmd saves the chat's session instructions.
+func setInstructionsCmd(chatService services.ChatService, chatID, instructions string) tea.Cmd {
+	return func() tea.Msg {
+		err := chatService.SetInstructions(context.Background(), chatID, instructions)
+		return instructionsSetMsg{instructions: instructions, err: err}
+	}
+}
+
+// steerCmd queues a note for the response being generated in the chat.
+func steerCmd(chatService services.ChatService, chatID, note string) tea.Cmd {
+	return func() tea.Msg {
+		err := chatService.Steer(context.Background(), chatID, note)
+		return steeredMsg{note: note, err: err}
+	}
+}
+
+// setToolsOverrideCmd saves the tools the chat uses in place of its agent's.
+func setToolsOverrideCmd(chatService services.ChatService, chatID string, tools []string) tea.Cmd {
+	return func() tea.Msg {
+		err := chatService.SetToolsOverride(context.Background(), chatID, tools)
+		return toolsOverrideSetMsg{tools: tools, err: err}
+	}
+}
+
+// setWorkspaceCmd points the chat's tools at another directory.
+func setWorkspaceCmd(chatService services.ChatService, chatID, path string) tea.Cmd {
+	return func() tea.Msg {
+		workspace, err := chatService.SetWorkspace(context.Background(), chatID, path)
+		return workspaceSetMsg{workspace: workspace, err: err}
+	}
+}
+
+// workspaceNotice describes the directory the chat's tools work in.
+func workspaceNotice(workspace string) string {
+	if workspace == "" {
+		return "Workspace: the tools' configured directory. Use /workspace <path> to switch"
+	}
+	return "Workspace: " + workspace + ". Use /workspace reset to go back to the tools' configured directory"
+}
+
+// pinnedNotice lists the files sent with every message of the chat.
+func pinnedNotice(pinned []string) string {
+	if len(pinned) == 0 {
+		return "No files are pinned. Mention @path in a message to pin a file or directory"
+	}
+	return "Pinned, sent with every message: " + strings.Join(pinned, ", ") + ". Use /unpin <path> or /unpin all to remove them"
+}
+
+// pinFilesCmd pins files to the chat, then runs send, the command sending
+// the message that mentioned them. A failure to pin fails the message.
+func pinFilesCmd(chatService services.ChatService, chatID string, paths []string, send tea.Cmd) tea.Cmd {
+	return func() tea.Msg {
+		pinned, err := chatService.PinFiles(context.Background(), chatID, paths)
+		if err != nil {
+			return errMsg(err)
+		}
+		return filesPinnedMsg{chatID: chatID, pinned: pinned, send: send}
+	}
+}
+
+// unpinFilesCmd stops sending the given pinned files with the chat's
+// messages, or all of them when no paths are given.
+func unpinFilesCmd(chatService services.ChatService, chatID string, paths []string) tea.Cmd {
+	return func() tea.Msg {
+		pinned, err := chatService.UnpinFiles(context.Background(), chatID, paths)
+		return filesUnpinnedMsg{chatID: chatID, pinned: pinned, err: err}
+	}
+}
+
+// contextUsageCmd estimates how much of the context window the chat fills.
+func contextUsageCmd(chatService services.ChatService, chatID string) tea.Cmd {
+	return func() tea.Msg {
+		usage, err := chatService.ContextUsage(context.Background(), chatID)
+		if err != nil {
+			return contextUsageMsg{chatID: chatID}
+		}
+		return contextUsageMsg{chatID: chatID, usage: usage}
+	}
+}
+
+// clearContextCmd replaces the chat's history with a summary of it.
+func clearContextCmd(chatService services.ChatService, chatID string) tea.Cmd {
+	return func() tea.Msg {
+		chat, err := chatService.ClearContext(context.Background(), chatID)
+		return contextClearedMsg{chat: chat, err: err}
+	}
+}
+
+// compactChatCmd summarizes the chat's older messages, measuring the context
+// before and after to show what was saved.
+func compactChatCmd(chatService services.ChatService, chatID string) tea.Cmd {
+	return func() tea.Msg {
+		ctx := context.Background()
+		before, err := chatService.ContextUsage(ctx, chatID)
+		if err != nil {
+			return chatCompactedMsg{err: err}
+		}
+		if err := chatService.CompactChat(ctx, chatID); err != nil {
+			return chatCompactedMsg{err: err}
+		}
+		chat, err := chatService.GetChat(ctx, chatID)
+		if err != nil {
+			return chatCompactedMsg{err: err}
+		}
+		after, err := chatService.ContextUsage(ctx, chatID)
+		return chatCompactedMsg{chat: chat, before: before, after: after, err: err}
+	}
+}
+
+// previewCompactionCmd reports which messages compacting would summarize.
+func previewCompactionCmd(chatService services.ChatService, chatID string) tea.Cmd {
+	return func() tea.Msg {
+		preview, err := chatService.PreviewCompaction(context.Background(), chatID)
+		return compactionPreviewMsg{preview: preview, err: err}
+	}
+}
+
+// deleteLastExchangeCmd deletes the chat's last user message and the
+// responses to it.
+func deleteLastExchangeCmd(chatService services.ChatService, chat *entities.Chat) tea.Cmd {
+	return func() tea.Msg {
+		i := lastUserMessage(chat.Messages)
+		if i < 0 {
+			return exchangeDeletedMsg{err: fmt.Errorf("the chat has no message to delete")}
+		}
+		ctx := context.Background()
+		if err := chatService.DeleteMessage(ctx, chat.ID, chat.Messages[i].ID); err != nil {
+			return exchangeDeletedMsg{err: err}
+		}
+		updated, err := chatService.GetChat(ctx, chat.ID)
+		return exchangeDeletedMsg{chat: updated, err: err}
+	}
+}
+
+// resolvePlanCmd approves or discards the chat's pending plan.
+func resolvePlanCmd(chatService services.ChatService, chatID string, approve bool) tea.Cmd {
+	return func() tea.Msg {
+		if !approve {
+			return planResolvedMsg{err: chatService.DiscardPlan(context.Background(), chatID)}
+		}
+		result, err := chatService.ApprovePlan(context.Background(), chatID)
+		return planResolvedMsg{approved: true, result: result, err: err}
+	}
+}
+
+// approvalPolicyCmd lists, adds or removes approval policies as parsed by
+// approvalCommand. Policies to remove can be given by a prefix of their ID,
+// as listed.
+func approvalPolicyCmd(approvalService services.ApprovalService, chatID, action string, policy *entities.ApprovalPolicy, id string) tea.Cmd {
+	return func() tea.Msg {
+		ctx := context.Background()
+		switch action {
+		case "add":
+			if err := approvalService.AddPolicy(ctx, policy); err != nil {
+				return approvalPoliciesMsg{err: err}
+			}
+			return approvalPoliciesMsg{notice: "Approved " + approvalPolicyText(policy) + ". Use /approve list to see the policies"}
+		case "remove":
+			policies, err := approvalService.ListPolicies(ctx, chatID)
+			if err != nil {
+				return approvalPoliciesMsg{err: err}
+			}
+			var matched []*entities.ApprovalPolicy
+			for _, p := range policies {
+				if strings.HasPrefix(p.ID, id) {
+					matched = append(matched, p)
+				}
+			}
+			if len(matched) != 1 {
+				return approvalPoliciesMsg{err: fmt.Errorf("%d approval policies match %s, use /approve list to see their IDs", len(matched), id)}
+			}
+			if err := approvalService.RemovePolicy(ctx, chatID, matched[0].ID); err != nil {
+				return approvalPoliciesMsg{err: err}
+			}
+			return approvalPoliciesMsg{notice: "Removed the approval of " + approvalPolicyText(matched[0])}
+		}
+		policies, err := approvalService.ListPolicies(ctx, chatID)
+		if err != nil {
+			return approvalPoliciesMsg{err: err}
+		}
+		if len(policies) == 0 {
+			return approvalPoliciesMsg{notice: "No approval policies. Use /approve session|chat|global [tool|*] [pattern] to add one"}
+		}
+		lines := []string{"Approval policies:"}
+		for _, p := range policies {
+			lines = append(lines, fmt.Sprintf("- %s: %s", p.ID[:min(8, len(p.ID))], approvalPolicyText(p)))
+		}
+		lines = append(lines, "Use /approve remove <id> to remove one")
+		return approvalPoliciesMsg{notice: strings.Join(lines, "\n")}
+	}
+}
+
+// approvalPolicyText describes what a policy approves and for how long.
+func approvalPolicyText(policy *entities.ApprovalPolicy) string {
+	text := "every tool"
+	if policy.ToolName != "" {
+		text = policy.ToolName
+	}
+	if policy.Pattern != "" {
+		text += fmt.Sprintf(" calls matching %q", policy.Pattern)
+	}
+	switch policy.Scope {
+	case entities.ApprovalScopeSession:
+		return text + " for this session"
+	case entities.ApprovalScopeChat:
+		return text + " in this chat"
+	}
+	return text + " everywhere"
+}
+
+// exportAgentCmd writes the agent with the given name as JSON to
+// .aiagent/exports.
+func exportAgentCmd(agentService services.AgentService, name string) tea.Cmd {
+	return func() tea.Msg {
+		agents, err := agentService.ListAgents(context.Background())
+		if err != nil {
+			return agentExportedMsg{err: err}
+		}
+		var agent *entities.Agent
+		for _, a := range agents {
+			if strings.EqualFold(a.Name, name) {
+				agent = a
+				break
+			}
+		}
+		if agent == nil {
+			return agentExportedMsg{err: fmt.Errorf("no agent named %q", name)}
+		}
+
+		data, err := agentService.ExportAgent(context.Background(), agent.ID)
+		if err != nil {
+			return agentExportedMsg{err: err}
+		}
+
+		dir := filepath.Join(".aiagent", "exports")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return agentExportedMsg{err: err}
+		}
+		fileName := strings.ToLower(strings.Join(strings.Fields(agent.Name), "-"))
+		path := filepath.Join(dir, fmt.Sprintf("agent-%s.json", fileName))
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return agentExportedMsg{err: err}
+		}
+		return agentExportedMsg{path: path}
+	}
+}
+
+// importAgentCmd creates an agent from a file written by exportAgentCmd.
+func importAgentCmd(agentService services.AgentService, path string) tea.Cmd {
+	return func() tea.Msg {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return agentImportedMsg{err: err}
+		}
+		agent, err := agentService.ImportAgent(context.Background(), data)
+		return agentImportedMsg{agent: agent, err: err}
+	}
+}
+
+// exportChatCmd writes the active chat as markdown to .aiagent/exports.
+func (t *TUI) exportChatCmd() tea.Cmd {
+	chat := t.activeChat
+	return func() tea.Msg {
+		if chat == nil {
+			return chatExportedMsg{err: fmt.Errorf("no active chat")}
+		}
+
+		data, err := t.chatService.ExportChat(context.Background(), chat.ID, "markdown")
+		if err != nil {
+			return chatExportedMsg{err: err}
+		}
+
+		dir := filepath.Join(".aiagent", "exports")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return chatExportedMsg{err: err}
+		}
+		path := filepath.Join(dir, fmt.Sprintf("chat-%s.md", chat.ID))
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return chatExportedMsg{err: err}
+		}
+		return chatExportedMsg{path: path}
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/drujensen/aiagent/internal/domain/entities"
 	"github.com/drujensen/aiagent/internal/domain/services"
 )
 
@@ -114,7 +116,61 @@ func (u UsageView) fetchUsageCmd() tea.Cmd {
 		sb.WriteString(fmt.Sprintf("Completion Tokens: %d\n", activeChat.Usage.TotalCompletionTokens))
 		sb.WriteString(fmt.Sprintf("Total Tokens: %d\n", activeChat.Usage.TotalTokens))
 		sb.WriteString(fmt.Sprintf("Total Cost: $%.2f\n", activeChat.Usage.TotalCost))
+		if activeChat.Usage.TotalCachedTokens > 0 {
+			sb.WriteString(fmt.Sprintf("Cached Tokens: %d (saved $%.2f)\n", activeChat.Usage.TotalCachedTokens, activeChat.Usage.TotalCacheSavings))
+		}
+
+		if budget := activeChat.Budget; budget != nil {
+			tokensLeft, costLeft := budget.Remaining(activeChat.Usage)
+			if budget.MaxCost > 0 {
+				sb.WriteString(fmt.Sprintf("Cost Budget: $%.2f ($%.2f remaining)\n", budget.MaxCost, costLeft))
+			}
+			if budget.MaxTokens > 0 {
+				sb.WriteString(fmt.Sprintf("Token Budget: %d (%d remaining)\n", budget.MaxTokens, tokensLeft))
+			}
+		}
 
 		return updatedUsageMsg{info: sb.String()}
 	}
 }
+
+// ReportCmd fetches and formats the usage of all chats since a time
+func (u UsageView) ReportCmd(since time.Time) tea.Cmd {
+	return func() tea.Msg {
+		report, err := u.chatService.UsageReport(context.Background(), since)
+		if err != nil {
+			return errMsg(err)
+		}
+		return updatedUsageMsg{info: formatUsageReport(report)}
+	}
+}
+
+func formatUsageReport(report *entities.UsageReport) string {
+	var sb strings.Builder
+	if report.Since.IsZero() {
+		sb.WriteString("Usage Report: all time\n")
+	} else {
+		sb.WriteString(fmt.Sprintf("Usage Report: since %s\n", report.Since.Format("2006-01-02")))
+	}
+	sb.WriteString(fmt.Sprintf("Chats: %d  Tokens: %d  Cost: $%.2f\n", report.Chats, report.Total.TotalTokens, report.Total.Cost))
+
+	sections := []struct {
+		title  string
+		groups []entities.UsageGroup
+	}{
+		{"By Agent", report.ByAgent},
+		{"By Provider", report.ByProvider},
+		{"By Model", report.ByModel},
+		{"By Day", report.ByDay},
+	}
+	for _, section := range sections {
+		if len(section.groups) == 0 {
+			continue
+		}
+		sb.WriteString("\n" + section.title + "\n")
+		for _, group := range section.groups {
+			sb.WriteString(fmt.Sprintf("  %-30s %12d tokens  $%9.4f\n", group.Name, group.TotalTokens, group.Cost))
+		}
+	}
+	return sb.String()
+}
@@ -1,5 +1,18 @@
 package tui
 
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/drujensen/aiagent/internal/domain/entities"
+)
+
 // getToolStatusIcon returns an appropriate icon based on tool execution status
 func getToolStatusIcon(hasError bool) string {
 	if hasError {
@@ -7,3 +20,126 @@ func getToolStatusIcon(hasError bool) string {
 	}
 	return "✅"
 }
+
+// spinners are the animations the spinner setting can name.
+var spinners = map[string]spinner.Spinner{
+	"line":      spinner.Line,
+	"dot":       spinner.Dot,
+	"minidot":   spinner.MiniDot,
+	"jump":      spinner.Jump,
+	"pulse":     spinner.Pulse,
+	"points":    spinner.Points,
+	"meter":     spinner.Meter,
+	"hamburger": spinner.Hamburger,
+	"ellipsis":  spinner.Ellipsis,
+}
+
+// spinnerFor returns the named spinner, the dot when the name is empty or
+// unknown. "none" shows nothing but still ticks once a second, so the
+// elapsed time and usage keep updating.
+func spinnerFor(name string) spinner.Spinner {
+	if name == "none" {
+		return spinner.Spinner{Frames: []string{""}, FPS: time.Second}
+	}
+	if s, ok := spinners[strings.ToLower(name)]; ok {
+		return s
+	}
+	return spinner.Dot
+}
+
+// notifyAfter is how long a response runs before its end is worth a
+// notification.
+const notifyAfter = 10 * time.Second
+
+// Output is the terminal the TUI is drawn on. Pass it to tea.WithOutput, so
+// notifications go through the same writer and never land inside a frame.
+var Output = &terminalOutput{File: os.Stdout}
+
+// terminalOutput serializes writes to the terminal. The renderer writes each
+// frame with a single write, so a notification written between two of them
+// leaves the screen intact.
+type terminalOutput struct {
+	*os.File
+	mu sync.Mutex
+}
+
+func (o *terminalOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.File.Write(p)
+}
+
+// notifyCmd rings the terminal bell and asks the terminal for a desktop
+// notification with OSC 9, which terminals without them ignore.
+func notifyCmd(text string) tea.Cmd {
+	text = strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, text)
+	return func() tea.Msg {
+		fmt.Fprintf(Output, "\x1b]9;%s\x07\a", text)
+		return nil
+	}
+}
+
+// imageExtensions are the files "@path" in a message attaches.
+var imageExtensions = map[string]bool{".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true}
+
+// messageAttachments reads the images a message names as "@path", relative
+// to the working directory. Other words starting with @ are left to
+// messagePins.
+func messageAttachments(input string) ([]entities.Attachment, error) {
+	var attachments []entities.Attachment
+	for _, word := range strings.Fields(input) {
+		path, ok := strings.CutPrefix(word, "@")
+		if !ok || !imageExtensions[strings.ToLower(filepath.Ext(path))] {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to attach %s: %v", path, err)
+		}
+		attachment, err := entities.NewImageAttachment(path, data)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
+
+// messagePins returns the absolute paths of the files and directories other
+// than images a message names as "@path", relative to the workspace or the
+// working directory. Words starting with @ that name nothing, like a
+// mention, are left alone.
+func messagePins(input, workspace string) []string {
+	var paths []string
+	for _, word := range strings.Fields(input) {
+		path, ok := strings.CutPrefix(word, "@")
+		if !ok || path == "" || imageExtensions[strings.ToLower(filepath.Ext(path))] {
+			continue
+		}
+		if workspace != "" && !filepath.IsAbs(path) {
+			path = filepath.Join(workspace, path)
+		}
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			paths = append(paths, abs)
+		}
+	}
+	return paths
+}
+
+// lastUserMessage returns the position of the last user message, or -1.
+func lastUserMessage(messages []entities.Message) int {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			return i
+		}
+	}
+	return -1
+}
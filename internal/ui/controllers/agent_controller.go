@@ -3,9 +3,13 @@ package uicontrollers
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
+	"net/url"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/drujensen/aiagent/internal/domain/entities"
@@ -48,8 +52,7 @@ func (c *AgentController) RegisterRoutes(e *echo.Echo) {
 func (c *AgentController) AgentFormHandler(eCtx echo.Context) error {
 	tools, err := c.toolService.ListTools()
 	if err != nil {
-		c.logger.Error("Failed to list tools", zap.Error(err))
-		return eCtx.String(http.StatusInternalServerError, "Internal server error")
+		return err
 	}
 
 	toolNames := []string{}
@@ -64,34 +67,103 @@ func (c *AgentController) AgentFormHandler(eCtx echo.Context) error {
 	if isEdit {
 		id := eCtx.Param("id")
 		if id == "" {
-			return eCtx.String(http.StatusBadRequest, "Agent ID is required for editing")
+			return errors.ValidationErrorf("Agent ID is required for editing")
 		}
 		agent, err = c.agentService.GetAgent(eCtx.Request().Context(), id)
 		if err != nil {
-			switch err.(type) {
-			case *errors.NotFoundError:
+			if _, ok := err.(*errors.NotFoundError); ok {
 				return eCtx.Redirect(http.StatusFound, "/")
-			default:
-				return eCtx.String(http.StatusInternalServerError, "Failed to load agent")
 			}
+			return err
 		}
 	}
 
 	agentData := struct {
-		ID           string
-		Name         string
-		SystemPrompt string
-		Tools        []string
+		ID                        string
+		Name                      string
+		SystemPrompt              string
+		SystemPromptPrefix        string
+		SystemPromptSuffix        string
+		Tools                     []string
+		ReasoningStats            map[string]*entities.ReasoningEffortStats
+		ReasoningRecommendation   string
+		ResponseFormatType        string
+		ResponseSchemaName        string
+		ResponseSchema            string
+		ResponseStrict            bool
+		ResponseRepairAttempts    string
+		Fallbacks                 string
+		OutputMaxLines            string
+		OutputMaxWords            string
+		OutputLimitMode           string
+		PlanMode                  bool
+		VerifyCommands            string
+		VerifyMaxAttempts         string
+		CompressionStrategy       string
+		CompressionKeepPercent    string
+		CompressionTriggerPercent string
+		MaxConsecutiveFailures    string
+		Env                       string
+		CompletionWebhook         string
 	}{
-		Tools: []string{},
+		Tools:              []string{},
+		ResponseFormatType: string(entities.ResponseFormatText),
 	}
 	if agent != nil {
 		agentData.ID = agent.ID
 		agentData.Name = agent.Name
 		agentData.SystemPrompt = agent.SystemPrompt
+		agentData.SystemPromptPrefix = agent.SystemPromptPrefix
+		agentData.SystemPromptSuffix = agent.SystemPromptSuffix
+		agentData.ReasoningStats = agent.ReasoningStats
+		agentData.ReasoningRecommendation = agent.ReasoningRecommendation()
 		for _, tool := range agent.Tools {
 			agentData.Tools = append(agentData.Tools, tool)
 		}
+		agentData.Fallbacks = strings.Join(agent.Fallbacks, ", ")
+		agentData.PlanMode = agent.PlanMode
+		if agent.MaxConsecutiveFailures > 0 {
+			agentData.MaxConsecutiveFailures = strconv.Itoa(agent.MaxConsecutiveFailures)
+		}
+		agentData.Env = envToForm(agent.Env)
+		agentData.CompletionWebhook = agent.CompletionWebhook
+		if loop := agent.VerifyLoop; loop.Enabled() {
+			agentData.VerifyCommands = strings.Join(loop.Commands, "\n")
+			if loop.MaxAttempts > 0 {
+				agentData.VerifyMaxAttempts = strconv.Itoa(loop.MaxAttempts)
+			}
+		}
+		if compression := agent.Compression; compression != nil {
+			agentData.CompressionStrategy = string(compression.Strategy)
+			if compression.KeepRatio > 0 {
+				agentData.CompressionKeepPercent = strconv.Itoa(int(math.Round(compression.KeepRatio * 100)))
+			}
+			if compression.Trigger > 0 {
+				agentData.CompressionTriggerPercent = strconv.Itoa(int(math.Round(compression.Trigger * 100)))
+			}
+		}
+		if limit := agent.OutputLimit; limit.Enabled() {
+			if limit.MaxLines > 0 {
+				agentData.OutputMaxLines = strconv.Itoa(limit.MaxLines)
+			}
+			if limit.MaxWords > 0 {
+				agentData.OutputMaxWords = strconv.Itoa(limit.MaxWords)
+			}
+			agentData.OutputLimitMode = string(limit.Mode)
+		}
+		if format := agent.ResponseFormat; format.Structured() {
+			agentData.ResponseFormatType = string(format.Type)
+			agentData.ResponseSchemaName = format.Name
+			agentData.ResponseStrict = format.Strict
+			if len(format.Schema) > 0 {
+				if schema, err := json.MarshalIndent(format.Schema, "", "  "); err == nil {
+					agentData.ResponseSchema = string(schema)
+				}
+			}
+			if format.MaxRepairAttempts != nil {
+				agentData.ResponseRepairAttempts = strconv.Itoa(*format.MaxRepairAttempts)
+			}
+		}
 	} else {
 		agentData.ID = uuid.New().String()
 	}
@@ -115,8 +187,7 @@ func (c *AgentController) CreateAgentHandler(eCtx echo.Context) error {
 	// Get available tools
 	toolsList, err := c.toolService.ListTools()
 	if err != nil {
-		c.logger.Error("Failed to list tools", zap.Error(err))
-		return eCtx.String(http.StatusInternalServerError, "Internal server error")
+		return err
 	}
 
 	toolNames := []string{}
@@ -132,11 +203,56 @@ func (c *AgentController) CreateAgentHandler(eCtx echo.Context) error {
 		}
 	}
 
+	responseFormat, err := responseFormatFromForm(eCtx)
+	if err != nil {
+		return errors.ValidationErrorf("%v", err)
+	}
+
+	outputLimit, err := outputLimitFromForm(eCtx)
+	if err != nil {
+		return errors.ValidationErrorf("%v", err)
+	}
+
+	verifyLoop, err := verifyLoopFromForm(eCtx)
+	if err != nil {
+		return errors.ValidationErrorf("%v", err)
+	}
+
+	compression, err := compressionFromForm(eCtx)
+	if err != nil {
+		return errors.ValidationErrorf("%v", err)
+	}
+
+	maxFailures, err := maxConsecutiveFailuresFromForm(eCtx)
+	if err != nil {
+		return errors.ValidationErrorf("%v", err)
+	}
+
+	webhook, err := webhookFromForm(eCtx)
+	if err != nil {
+		return errors.ValidationErrorf("%v", err)
+	}
+
+	env, err := envFromForm(eCtx)
+	if err != nil {
+		return errors.ValidationErrorf("%v", err)
+	}
+
 	agent := entities.NewAgent(name, systemPrompt, tools)
+	agent.ResponseFormat = responseFormat
+	agent.Fallbacks = fallbacksFromForm(eCtx)
+	agent.OutputLimit = outputLimit
+	agent.PlanMode = eCtx.FormValue("plan_mode") == "on"
+	agent.VerifyLoop = verifyLoop
+	agent.Compression = compression
+	agent.SystemPromptPrefix = eCtx.FormValue("system_prompt_prefix")
+	agent.SystemPromptSuffix = eCtx.FormValue("system_prompt_suffix")
+	agent.MaxConsecutiveFailures = maxFailures
+	agent.Env = env
+	agent.CompletionWebhook = webhook
 
 	if err := c.agentService.CreateAgent(context.Background(), agent); err != nil {
-		c.logger.Error("Failed to create agent", zap.Error(err))
-		return eCtx.String(http.StatusBadRequest, "Failed to create agent: "+err.Error())
+		return err
 	}
 
 	eCtx.Response().Header().Set("HX-Trigger", `{"refreshAgents": true}`)
@@ -146,13 +262,13 @@ func (c *AgentController) CreateAgentHandler(eCtx echo.Context) error {
 func (c *AgentController) UpdateAgentHandler(eCtx echo.Context) error {
 	id := eCtx.Param("id")
 	if id == "" {
-		return eCtx.String(http.StatusBadRequest, "Agent ID is required")
+		return errors.ValidationErrorf("Agent ID is required")
 	}
 
 	// Get existing agent
 	existing, err := c.agentService.GetAgent(eCtx.Request().Context(), id)
 	if err != nil {
-		return eCtx.String(http.StatusInternalServerError, "Failed to get agent")
+		return err
 	}
 
 	name := eCtx.FormValue("name")
@@ -161,8 +277,7 @@ func (c *AgentController) UpdateAgentHandler(eCtx echo.Context) error {
 	// Get available tools
 	toolsList, err := c.toolService.ListTools()
 	if err != nil {
-		c.logger.Error("Failed to list tools", zap.Error(err))
-		return eCtx.String(http.StatusInternalServerError, "Internal server error")
+		return err
 	}
 
 	toolNames := []string{}
@@ -178,18 +293,63 @@ func (c *AgentController) UpdateAgentHandler(eCtx echo.Context) error {
 		}
 	}
 
+	responseFormat, err := responseFormatFromForm(eCtx)
+	if err != nil {
+		return errors.ValidationErrorf("%v", err)
+	}
+
+	outputLimit, err := outputLimitFromForm(eCtx)
+	if err != nil {
+		return errors.ValidationErrorf("%v", err)
+	}
+
+	verifyLoop, err := verifyLoopFromForm(eCtx)
+	if err != nil {
+		return errors.ValidationErrorf("%v", err)
+	}
+
+	compression, err := compressionFromForm(eCtx)
+	if err != nil {
+		return errors.ValidationErrorf("%v", err)
+	}
+
+	maxFailures, err := maxConsecutiveFailuresFromForm(eCtx)
+	if err != nil {
+		return errors.ValidationErrorf("%v", err)
+	}
+
+	webhook, err := webhookFromForm(eCtx)
+	if err != nil {
+		return errors.ValidationErrorf("%v", err)
+	}
+
+	env, err := envFromForm(eCtx)
+	if err != nil {
+		return errors.ValidationErrorf("%v", err)
+	}
+
 	agent := &entities.Agent{
-		ID:           id,
-		Name:         name,
-		SystemPrompt: systemPrompt,
-		Tools:        tools,
-		CreatedAt:    existing.CreatedAt,
-		UpdatedAt:    existing.UpdatedAt,
+		ID:                     id,
+		Name:                   name,
+		SystemPrompt:           systemPrompt,
+		Tools:                  tools,
+		CreatedAt:              existing.CreatedAt,
+		UpdatedAt:              existing.UpdatedAt,
+		ResponseFormat:         responseFormat,
+		Fallbacks:              fallbacksFromForm(eCtx),
+		OutputLimit:            outputLimit,
+		PlanMode:               eCtx.FormValue("plan_mode") == "on",
+		VerifyLoop:             verifyLoop,
+		Compression:            compression,
+		SystemPromptPrefix:     eCtx.FormValue("system_prompt_prefix"),
+		SystemPromptSuffix:     eCtx.FormValue("system_prompt_suffix"),
+		MaxConsecutiveFailures: maxFailures,
+		Env:                    env,
+		CompletionWebhook:      webhook,
 	}
 
 	if err := c.agentService.UpdateAgent(context.Background(), agent); err != nil {
-		c.logger.Error("Failed to update agent", zap.Error(err))
-		return eCtx.String(http.StatusBadRequest, "Failed to update agent: "+err.Error())
+		return err
 	}
 
 	eCtx.Response().Header().Set("HX-Trigger", `{"refreshAgents": true}`)
@@ -199,23 +359,16 @@ func (c *AgentController) UpdateAgentHandler(eCtx echo.Context) error {
 func (c *AgentController) DeleteAgentHandler(eCtx echo.Context) error {
 	id := eCtx.Param("id")
 	if id == "" {
-		return eCtx.String(http.StatusBadRequest, "Agent ID is required")
+		return errors.ValidationErrorf("Agent ID is required")
 	}
 
-	err := c.agentService.DeleteAgent(eCtx.Request().Context(), id)
-	if err != nil {
-		switch err.(type) {
-		case *errors.NotFoundError:
-			return eCtx.String(http.StatusNotFound, "Agent not found")
-		default:
-			return eCtx.String(http.StatusInternalServerError, "Failed to load agent")
-		}
+	if err := c.agentService.DeleteAgent(eCtx.Request().Context(), id); err != nil {
+		return err
 	}
 
 	agents, err := c.agentService.ListAgents(eCtx.Request().Context())
 	if err != nil {
-		c.logger.Error("Failed to list agents", zap.Error(err))
-		return eCtx.String(http.StatusInternalServerError, "Failed to load agents")
+		return err
 	}
 	data := map[string]any{
 		"Agents": agents,
@@ -226,7 +379,7 @@ func (c *AgentController) DeleteAgentHandler(eCtx echo.Context) error {
 func (c *AgentController) GetProviderModelsHandler(eCtx echo.Context) error {
 	providerID := eCtx.QueryParam("provider_id")
 	if providerID == "" {
-		return eCtx.String(http.StatusBadRequest, "Provider ID is required")
+		return errors.ValidationErrorf("Provider ID is required")
 	}
 
 	c.logger.Info("Fetching provider models", zap.String("provider_id", providerID))
@@ -245,12 +398,7 @@ func (c *AgentController) GetProviderModelsHandler(eCtx echo.Context) error {
 
 	provider, err := c.providerService.GetProvider(eCtx.Request().Context(), cleanProviderID)
 	if err != nil {
-		switch err.(type) {
-		case *errors.NotFoundError:
-			return eCtx.String(http.StatusNotFound, "Provider not found")
-		default:
-			return eCtx.String(http.StatusInternalServerError, "Failed to load provider")
-		}
+		return err
 	}
 
 	c.logger.Info("Provider found",
@@ -272,8 +420,7 @@ func (c *AgentController) GetProviderModelsHandler(eCtx echo.Context) error {
 
 	var buf bytes.Buffer
 	if err := c.tmpl.ExecuteTemplate(&buf, "provider_models_partial", data); err != nil {
-		c.logger.Error("Failed to render provider models", zap.Error(err))
-		return eCtx.String(http.StatusInternalServerError, "Failed to render provider models")
+		return err
 	}
 
 	eCtx.Response().Header().Set("X-Provider-Key-Name", provider.APIKeyName)
@@ -285,3 +432,163 @@ func (c *AgentController) GetProviderModelsHandler(eCtx echo.Context) error {
 		zap.String("provider_url", provider.BaseURL))
 	return eCtx.HTML(http.StatusOK, buf.String())
 }
+
+// fallbacksFromForm parses the comma separated fallback model IDs.
+func fallbacksFromForm(eCtx echo.Context) []string {
+	var fallbacks []string
+	for _, id := range strings.Split(eCtx.FormValue("fallbacks"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			fallbacks = append(fallbacks, id)
+		}
+	}
+	return fallbacks
+}
+
+// responseFormatFromForm reads the response format fields of the agent form.
+// A text format is stored as no format at all.
+func responseFormatFromForm(eCtx echo.Context) (*entities.ResponseFormat, error) {
+	formatType := entities.ResponseFormatType(eCtx.FormValue("response_format_type"))
+	if formatType == "" || formatType == entities.ResponseFormatText {
+		return nil, nil
+	}
+
+	format := &entities.ResponseFormat{
+		Type:   formatType,
+		Name:   strings.TrimSpace(eCtx.FormValue("response_schema_name")),
+		Strict: eCtx.FormValue("response_strict") == "on",
+	}
+	if schema := strings.TrimSpace(eCtx.FormValue("response_schema")); schema != "" && formatType == entities.ResponseFormatJSONSchema {
+		if err := json.Unmarshal([]byte(schema), &format.Schema); err != nil {
+			return nil, fmt.Errorf("response schema is not a valid JSON object: %v", err)
+		}
+	}
+	if attempts := strings.TrimSpace(eCtx.FormValue("response_repair_attempts")); attempts != "" {
+		n, err := strconv.Atoi(attempts)
+		if err != nil {
+			return nil, fmt.Errorf("repair attempts must be a number")
+		}
+		format.MaxRepairAttempts = &n
+	}
+	return format, nil
+}
+
+// outputLimitFromForm returns nil unless a line or word limit was entered.
+func outputLimitFromForm(eCtx echo.Context) (*entities.OutputLimit, error) {
+	limit := &entities.OutputLimit{Mode: entities.OutputLimitMode(eCtx.FormValue("output_limit_mode"))}
+	for field, target := range map[string]*int{"output_max_lines": &limit.MaxLines, "output_max_words": &limit.MaxWords} {
+		value := strings.TrimSpace(eCtx.FormValue(field))
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("output limits must be numbers")
+		}
+		*target = n
+	}
+	if !limit.Enabled() {
+		return nil, nil
+	}
+	return limit, nil
+}
+
+// verifyLoopFromForm returns nil unless a build or test command was entered,
+// one per line.
+func verifyLoopFromForm(eCtx echo.Context) (*entities.VerifyLoop, error) {
+	loop := &entities.VerifyLoop{}
+	for _, line := range strings.Split(eCtx.FormValue("verify_commands"), "\n") {
+		if command := strings.TrimSpace(line); command != "" {
+			loop.Commands = append(loop.Commands, command)
+		}
+	}
+	if !loop.Enabled() {
+		return nil, nil
+	}
+	if value := strings.TrimSpace(eCtx.FormValue("verify_max_attempts")); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("verify attempts must be a number")
+		}
+		loop.MaxAttempts = n
+	}
+	return loop, nil
+}
+
+// maxConsecutiveFailuresFromForm returns zero, the default, when the field is
+// empty.
+func maxConsecutiveFailuresFromForm(eCtx echo.Context) (int, error) {
+	value := strings.TrimSpace(eCtx.FormValue("max_consecutive_failures"))
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("max consecutive failures must be a number")
+	}
+	return n, nil
+}
+
+// webhookFromForm returns the completion webhook field, which must be an
+// http or https URL when it is set.
+func webhookFromForm(eCtx echo.Context) (string, error) {
+	value := strings.TrimSpace(eCtx.FormValue("completion_webhook"))
+	if value == "" {
+		return "", nil
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("completion webhook must be an http or https URL")
+	}
+	return value, nil
+}
+
+// envFromForm parses the NAME=value lines of the environment field.
+func envFromForm(eCtx echo.Context) (map[string]string, error) {
+	var env map[string]string
+	for _, line := range strings.Split(eCtx.FormValue("env"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("environment variables must be NAME=value, got %q", line)
+		}
+		if env == nil {
+			env = make(map[string]string)
+		}
+		env[strings.TrimSpace(name)] = value
+	}
+	return env, nil
+}
+
+// envToForm writes the env as NAME=value lines sorted by name.
+func envToForm(env map[string]string) string {
+	lines := make([]string, 0, len(env))
+	for name, value := range env {
+		lines = append(lines, name+"="+value)
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+// compressionFromForm returns nil when the form keeps the default: summarize
+// at 70% of the context window, keeping less the longer the history.
+func compressionFromForm(eCtx echo.Context) (*entities.CompressionConfig, error) {
+	compression := &entities.CompressionConfig{Strategy: entities.CompressionStrategy(eCtx.FormValue("compression_strategy"))}
+	for field, target := range map[string]*float64{"compression_keep_percent": &compression.KeepRatio, "compression_trigger_percent": &compression.Trigger} {
+		value := strings.TrimSpace(eCtx.FormValue(field))
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("compression percentages must be numbers")
+		}
+		*target = float64(n) / 100
+	}
+	if *compression == (entities.CompressionConfig{}) {
+		return nil, nil
+	}
+	return compression, nil
+}
@@ -0,0 +1,96 @@
+package uicontrollers
+
+import (
+	"html/template"
+	"strings"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	errors "github.com/drujensen/aiagent/internal/domain/errs"
+	"github.com/drujensen/aiagent/internal/domain/services"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type ApprovalController struct {
+	logger          *zap.Logger
+	tmpl            *template.Template
+	approvalService services.ApprovalService
+}
+
+func NewApprovalController(logger *zap.Logger, tmpl *template.Template, approvalService services.ApprovalService) *ApprovalController {
+	return &ApprovalController{
+		logger:          logger,
+		tmpl:            tmpl,
+		approvalService: approvalService,
+	}
+}
+
+func (c *ApprovalController) RegisterRoutes(e *echo.Echo) {
+	e.GET("/chats/:id/approvals", c.ListApprovalsHandler)
+	e.POST("/chats/:id/approvals", c.AddApprovalHandler)
+	e.DELETE("/chats/:id/approvals/:policyID", c.RemoveApprovalHandler)
+}
+
+// ListApprovalsHandler renders the approval policies that apply to the chat
+// and a form to add one.
+func (c *ApprovalController) ListApprovalsHandler(eCtx echo.Context) error {
+	chatID := eCtx.Param("id")
+	if chatID == "" {
+		return errors.ValidationErrorf("Chat ID is required")
+	}
+
+	return c.renderApprovals(eCtx, chatID, false)
+}
+
+// AddApprovalHandler adds a policy from the "scope", "tool_name" and
+// "pattern" form values. Chat scoped policies apply to this chat.
+func (c *ApprovalController) AddApprovalHandler(eCtx echo.Context) error {
+	chatID := eCtx.Param("id")
+	if chatID == "" {
+		return errors.ValidationErrorf("Chat ID is required")
+	}
+
+	scope := entities.ApprovalScope(eCtx.FormValue("scope"))
+	policyChatID := ""
+	if scope == entities.ApprovalScopeChat {
+		policyChatID = chatID
+	}
+	policy := entities.NewApprovalPolicy(scope, policyChatID, strings.TrimSpace(eCtx.FormValue("tool_name")), strings.TrimSpace(eCtx.FormValue("pattern")))
+	if err := c.approvalService.AddPolicy(eCtx.Request().Context(), policy); err != nil {
+		return err
+	}
+
+	return c.renderApprovals(eCtx, chatID, true)
+}
+
+// RemoveApprovalHandler removes the policy, whichever scope it has.
+func (c *ApprovalController) RemoveApprovalHandler(eCtx echo.Context) error {
+	chatID := eCtx.Param("id")
+	if chatID == "" {
+		return errors.ValidationErrorf("Chat ID is required")
+	}
+
+	if err := c.approvalService.RemovePolicy(eCtx.Request().Context(), chatID, eCtx.Param("policyID")); err != nil {
+		return err
+	}
+
+	return c.renderApprovals(eCtx, chatID, true)
+}
+
+// renderApprovals renders the chat's policies, left open after a change.
+func (c *ApprovalController) renderApprovals(eCtx echo.Context, chatID string, open bool) error {
+	policies, err := c.approvalService.ListPolicies(eCtx.Request().Context(), chatID)
+	if err != nil {
+		return err
+	}
+
+	requiredTools := strings.Join(c.approvalService.RequiredTools(), ", ")
+	data := map[string]any{
+		"ChatID":        chatID,
+		"Policies":      policies,
+		"RequiredTools": requiredTools,
+		"Open":          open,
+	}
+	return c.tmpl.ExecuteTemplate(eCtx.Response().Writer, "approval_policies_partial", data)
+}
@@ -3,16 +3,20 @@ package uicontrollers
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"html/template"
+	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/drujensen/aiagent/internal/domain/entities"
 	errors "github.com/drujensen/aiagent/internal/domain/errs"
+	"github.com/drujensen/aiagent/internal/domain/events"
 	"github.com/drujensen/aiagent/internal/domain/services"
 	"github.com/drujensen/aiagent/internal/impl/config"
 
@@ -56,11 +60,27 @@ func (c *ChatController) RegisterRoutes(e *echo.Echo) {
 	e.PUT("/chats/:id/agent", c.SwitchAgentHandler)
 	e.PUT("/chats/:id/model", c.SwitchModelHandler)
 	e.DELETE("/chats/:id", c.DeleteChatHandler)
+	e.DELETE("/projects/:id", c.DeleteProjectHandler)
 
 	e.POST("/chats/:id/messages", c.SendMessageHandler)
+	e.PUT("/chats/:id/messages/:messageID", c.EditMessageHandler)
+	e.DELETE("/chats/:id/messages/:messageID", c.DeleteMessageHandler)
+	e.POST("/chats/:id/regenerate", c.RegenerateHandler)
 	e.POST("/chats/:id/cancel", c.CancelMessageHandler)
 	e.GET("/chat-cost", c.ChatCostHandler)
+	e.GET("/usage", c.UsageReportHandler)
 	e.GET("/chats/:id/messages", c.GetMessagesHandler)
+	e.GET("/chats/:id/events", c.ChatEventsHandler)
+	e.GET("/chats/:id/export", c.ExportChatHandler)
+	e.PUT("/chats/:id/budget", c.UpdateBudgetHandler)
+	e.PUT("/chats/:id/instructions", c.UpdateInstructionsHandler)
+	e.POST("/chats/:id/plan/approve", c.ApprovePlanHandler)
+	e.POST("/chats/:id/plan/discard", c.DiscardPlanHandler)
+	e.PUT("/chats/:id/review-edits", c.UpdateReviewEditsHandler)
+	e.GET("/chats/:id/edits", c.PendingEditsHandler)
+	e.POST("/chats/:id/edits/:reviewID", c.ResolveEditHandler)
+	e.GET("/chats/:id/compact", c.PreviewCompactionHandler)
+	e.POST("/chats/:id/compact", c.CompactChatHandler)
 
 	// Title management endpoints
 	e.GET("/chats/:id/title", c.GetChatTitleHandler)
@@ -75,22 +95,20 @@ func (c *ChatController) ChatHandler(eCtx echo.Context) error {
 
 	chat, err := c.chatService.GetChat(eCtx.Request().Context(), chatID)
 	if err != nil {
-		switch err.(type) {
-		case *errors.NotFoundError:
+		if _, ok := err.(*errors.NotFoundError); ok {
 			return eCtx.Redirect(http.StatusFound, "/")
-		default:
-			return eCtx.String(http.StatusInternalServerError, "Failed to load chat")
 		}
+		return err
 	}
 
 	agent, err := c.agentService.GetAgent(eCtx.Request().Context(), chat.AgentID)
 	if err != nil {
-		return eCtx.String(http.StatusInternalServerError, "Failed to get agent")
+		return err
 	}
 
 	model, err := c.modelService.GetModel(eCtx.Request().Context(), chat.ModelID)
 	if err != nil {
-		return eCtx.String(http.StatusInternalServerError, "Failed to get model")
+		return err
 	}
 
 	provider, err := c.providerService.GetProvider(eCtx.Request().Context(), model.ProviderID)
@@ -112,12 +130,12 @@ func (c *ChatController) ChatHandler(eCtx echo.Context) error {
 
 	availableAgents, err := c.agentService.ListAgents(eCtx.Request().Context())
 	if err != nil {
-		return eCtx.String(http.StatusInternalServerError, "Failed to list agents")
+		return err
 	}
 
 	availableModels, err := c.modelService.ListModels(eCtx.Request().Context())
 	if err != nil {
-		return eCtx.String(http.StatusInternalServerError, "Failed to list models")
+		return err
 	}
 
 	// Filter out tool execution notification messages from the chat messages
@@ -172,6 +190,12 @@ func (c *ChatController) ChatHandler(eCtx echo.Context) error {
 		"AvailableAgents": availableAgents,
 		"AvailableModels": availableModels,
 		"ChatCost":        chat.Usage.TotalCost,
+		"CacheSavings":    chat.Usage.TotalCacheSavings,
+		"BudgetRemaining": budgetRemaining(chat),
+		"Budget":          chat.Budget,
+		"Instructions":    chat.Instructions,
+		"PendingPlan":     chat.PendingPlan,
+		"ReviewEdits":     chat.ReviewEdits,
 		"TotalTokens":     chat.Usage.TotalTokens,
 		"Messages":        filteredMessages,
 	}
@@ -192,14 +216,12 @@ type EnrichedModelData struct {
 func (c *ChatController) ChatFormHandler(eCtx echo.Context) error {
 	agents, err := c.agentService.ListAgents(eCtx.Request().Context())
 	if err != nil {
-		c.logger.Error("Failed to list agents", zap.Error(err))
-		return eCtx.String(http.StatusInternalServerError, "Failed to load agents")
+		return err
 	}
 
 	models, err := c.modelService.ListModels(eCtx.Request().Context())
 	if err != nil {
-		c.logger.Error("Failed to list models", zap.Error(err))
-		return eCtx.String(http.StatusInternalServerError, "Failed to load models")
+		return err
 	}
 
 	// Filter models to only include chat-compatible ones
@@ -246,31 +268,41 @@ func (c *ChatController) ChatFormHandler(eCtx echo.Context) error {
 	if isEdit {
 		id := eCtx.Param("id")
 		if id == "" {
-			return eCtx.String(http.StatusBadRequest, "Chat ID is required for editing")
+			return errors.ValidationErrorf("Chat ID is required for editing")
 		}
 		chat, err = c.chatService.GetChat(eCtx.Request().Context(), id)
 		if err != nil {
-			switch err.(type) {
-			case *errors.NotFoundError:
+			if _, ok := err.(*errors.NotFoundError); ok {
 				return eCtx.Redirect(http.StatusFound, "/")
-			default:
-				return eCtx.String(http.StatusInternalServerError, "Failed to load chat")
 			}
+			return err
 		}
 	}
 
 	chatData := struct {
-		ID      string
-		Name    string
-		AgentID string
-		ModelID string
+		ID        string
+		Name      string
+		AgentID   string
+		ModelID   string
+		ProjectID string
+		Tools     string
+		Workspace string
 	}{}
 
+	var projects []*entities.Project
 	if chat != nil {
 		chatData.ID = chat.ID
 		chatData.Name = chat.Name
 		chatData.AgentID = chat.AgentID
 		chatData.ModelID = chat.ModelID
+		chatData.ProjectID = chat.ProjectID
+		chatData.Tools = entities.FormatToolNames(chat.ToolsOverride)
+		chatData.Workspace = chat.Workspace
+
+		projects, err = c.chatService.ListProjects(eCtx.Request().Context())
+		if err != nil {
+			return err
+		}
 	} else {
 		chatData.ID = uuid.New().String()
 
@@ -301,6 +333,7 @@ func (c *ChatController) ChatFormHandler(eCtx echo.Context) error {
 		"Chat":            chatData,
 		"Agents":          agents,
 		"Models":          enrichedModels,
+		"Projects":        projects,
 		"IsEdit":          isEdit,
 	}
 
@@ -322,7 +355,7 @@ func (c *ChatController) CreateChatHandler(eCtx echo.Context) error {
 			ModelID string `json:"model_id"`
 		}
 		if err := eCtx.Bind(&input); err != nil {
-			return eCtx.String(http.StatusBadRequest, "Invalid request")
+			return errors.ValidationErrorf("Invalid request")
 		}
 
 		name = input.Name
@@ -334,7 +367,7 @@ func (c *ChatController) CreateChatHandler(eCtx echo.Context) error {
 			// Use first available agent as default
 			agents, err := c.agentService.ListAgents(eCtx.Request().Context())
 			if err != nil || len(agents) == 0 {
-				return eCtx.String(http.StatusInternalServerError, "No agents available")
+				return errors.InternalErrorf("No agents available")
 			}
 			agentID = agents[0].ID
 		}
@@ -345,7 +378,7 @@ func (c *ChatController) CreateChatHandler(eCtx echo.Context) error {
 			// Use first available model as default
 			models, err := c.modelService.ListModels(eCtx.Request().Context())
 			if err != nil || len(models) == 0 {
-				return eCtx.String(http.StatusInternalServerError, "No models available")
+				return errors.InternalErrorf("No models available")
 			}
 			modelID = models[0].ID
 		}
@@ -376,7 +409,7 @@ func (c *ChatController) CreateChatHandler(eCtx echo.Context) error {
 					// Use first available agent as default
 					agents, err := c.agentService.ListAgents(eCtx.Request().Context())
 					if err != nil || len(agents) == 0 {
-						return eCtx.String(http.StatusInternalServerError, "No agents available")
+						return errors.InternalErrorf("No agents available")
 					}
 					agentID = agents[0].ID
 				}
@@ -398,7 +431,7 @@ func (c *ChatController) CreateChatHandler(eCtx echo.Context) error {
 					// Use first available model as default
 					models, err := c.modelService.ListModels(eCtx.Request().Context())
 					if err != nil || len(models) == 0 {
-						return eCtx.String(http.StatusInternalServerError, "No models available")
+						return errors.InternalErrorf("No models available")
 					}
 					modelID = models[0].ID
 				}
@@ -411,17 +444,17 @@ func (c *ChatController) CreateChatHandler(eCtx echo.Context) error {
 		} else {
 			// Traditional form - require selections
 			if agentID == "" {
-				return eCtx.String(http.StatusBadRequest, "Agent selection is required")
+				return errors.ValidationErrorf("Agent selection is required")
 			}
 			if modelID == "" {
-				return eCtx.String(http.StatusBadRequest, "Model selection is required")
+				return errors.ValidationErrorf("Model selection is required")
 			}
 		}
 	}
 
 	chat, err := c.chatService.CreateChat(eCtx.Request().Context(), agentID, modelID, name)
 	if err != nil {
-		return eCtx.String(http.StatusInternalServerError, "Failed to create chat")
+		return err
 	}
 
 	// Update global config with last used agent and model
@@ -467,17 +500,34 @@ func (c *ChatController) UpdateChatHandler(eCtx echo.Context) error {
 	modelID := eCtx.FormValue("model-select")
 	name := eCtx.FormValue("chat-name")
 	if chatID == "" || name == "" {
-		return eCtx.String(http.StatusBadRequest, "Chat ID and name are required")
+		return errors.ValidationErrorf("Chat ID and name are required")
 	}
 
 	_, err := c.chatService.UpdateChat(eCtx.Request().Context(), chatID, agentID, modelID, name)
 	if err != nil {
-		switch err.(type) {
-		case *errors.NotFoundError:
-			return eCtx.String(http.StatusNotFound, "Chat not found")
-		default:
-			return eCtx.String(http.StatusInternalServerError, "Failed to load chat")
+		return err
+	}
+
+	tools := entities.ParseToolNames(eCtx.FormValue("tools-override"))
+	if err := c.chatService.SetToolsOverride(eCtx.Request().Context(), chatID, tools); err != nil {
+		return err
+	}
+
+	if _, err := c.chatService.SetWorkspace(eCtx.Request().Context(), chatID, eCtx.FormValue("workspace")); err != nil {
+		return err
+	}
+
+	projectID := eCtx.FormValue("project-select")
+	if newProject := strings.TrimSpace(eCtx.FormValue("new-project")); newProject != "" {
+		project, err := c.chatService.CreateProject(eCtx.Request().Context(), newProject, eCtx.FormValue("new-project-workspace"))
+		if err != nil {
+			return err
 		}
+		projectID = project.ID
+	}
+
+	if err := c.chatService.SetChatProject(eCtx.Request().Context(), chatID, projectID); err != nil {
+		return err
 	}
 
 	eCtx.Response().Header().Set("HX-Redirect", "/chats/"+chatID)
@@ -487,30 +537,30 @@ func (c *ChatController) UpdateChatHandler(eCtx echo.Context) error {
 func (c *ChatController) SwitchModelHandler(eCtx echo.Context) error {
 	chatID := eCtx.Param("id")
 	if chatID == "" {
-		return eCtx.String(http.StatusBadRequest, "Chat ID is required")
+		return errors.ValidationErrorf("Chat ID is required")
 	}
 
 	var input struct {
 		ModelID string `json:"model_id"`
 	}
 	if err := eCtx.Bind(&input); err != nil {
-		return eCtx.String(http.StatusBadRequest, "Invalid request body")
+		return errors.ValidationErrorf("Invalid request body")
 	}
 
 	if input.ModelID == "" {
-		return eCtx.String(http.StatusBadRequest, "Model ID is required")
+		return errors.ValidationErrorf("Model ID is required")
 	}
 
 	// Get existing chat to preserve agent
 	existingChat, err := c.chatService.GetChat(eCtx.Request().Context(), chatID)
 	if err != nil {
-		return eCtx.String(http.StatusInternalServerError, "Failed to get chat")
+		return err
 	}
 
 	// Update chat with new model
 	updatedChat, err := c.chatService.UpdateChat(eCtx.Request().Context(), chatID, existingChat.AgentID, input.ModelID, existingChat.Name)
 	if err != nil {
-		return eCtx.String(http.StatusInternalServerError, "Failed to switch model")
+		return err
 	}
 
 	// Update last used model in global config
@@ -531,30 +581,30 @@ func (c *ChatController) SwitchModelHandler(eCtx echo.Context) error {
 func (c *ChatController) SwitchAgentHandler(eCtx echo.Context) error {
 	chatID := eCtx.Param("id")
 	if chatID == "" {
-		return eCtx.String(http.StatusBadRequest, "Chat ID is required")
+		return errors.ValidationErrorf("Chat ID is required")
 	}
 
 	var input struct {
 		AgentID string `json:"agent_id"`
 	}
 	if err := eCtx.Bind(&input); err != nil {
-		return eCtx.String(http.StatusBadRequest, "Invalid request body")
+		return errors.ValidationErrorf("Invalid request body")
 	}
 
 	if input.AgentID == "" {
-		return eCtx.String(http.StatusBadRequest, "Agent ID is required")
+		return errors.ValidationErrorf("Agent ID is required")
 	}
 
 	// Get existing chat to preserve model
 	existingChat, err := c.chatService.GetChat(eCtx.Request().Context(), chatID)
 	if err != nil {
-		return eCtx.String(http.StatusInternalServerError, "Failed to get chat")
+		return err
 	}
 
 	// Update chat with new agent
 	updatedChat, err := c.chatService.UpdateChat(eCtx.Request().Context(), chatID, input.AgentID, existingChat.ModelID, existingChat.Name)
 	if err != nil {
-		return eCtx.String(http.StatusInternalServerError, "Failed to switch agent")
+		return err
 	}
 
 	// Update last used agent in global config
@@ -575,17 +625,12 @@ func (c *ChatController) SwitchAgentHandler(eCtx echo.Context) error {
 func (c *ChatController) DeleteChatHandler(eCtx echo.Context) error {
 	id := eCtx.Param("id")
 	if id == "" {
-		return eCtx.String(http.StatusBadRequest, "Chat ID is required")
+		return errors.ValidationErrorf("Chat ID is required")
 	}
 
 	err := c.chatService.DeleteChat(eCtx.Request().Context(), id)
 	if err != nil {
-		switch err.(type) {
-		case *errors.NotFoundError:
-			return eCtx.String(http.StatusNotFound, "Chat not found")
-		default:
-			return eCtx.String(http.StatusInternalServerError, "Failed to load chat")
-		}
+		return err
 	}
 
 	// After successful deletion, return the updated chats list
@@ -593,21 +638,41 @@ func (c *ChatController) DeleteChatHandler(eCtx echo.Context) error {
 	return eCtx.String(http.StatusOK, "Chat deleted successfully")
 }
 
+func (c *ChatController) DeleteProjectHandler(eCtx echo.Context) error {
+	id := eCtx.Param("id")
+	if id == "" {
+		return errors.ValidationErrorf("Project ID is required")
+	}
+
+	err := c.chatService.DeleteProject(eCtx.Request().Context(), id)
+	if err != nil {
+		return err
+	}
+
+	eCtx.Response().Header().Set("HX-Trigger", `{"refreshChats": true}`)
+	return eCtx.String(http.StatusOK, "Project deleted successfully")
+}
+
 func (c *ChatController) SendMessageHandler(eCtx echo.Context) error {
 	chatID := eCtx.Param("id")
 	if chatID == "" {
-		return eCtx.String(http.StatusBadRequest, "Chat ID is required")
+		return errors.ValidationErrorf("Chat ID is required")
 	}
 
 	messageContent := eCtx.FormValue("message")
-	if messageContent == "" {
-		return eCtx.String(http.StatusBadRequest, "Message content is required")
+	attachments, err := uploadedAttachments(eCtx)
+	if err != nil {
+		return errors.ValidationErrorf("%v", err)
+	}
+	if messageContent == "" && len(attachments) == 0 {
+		return errors.ValidationErrorf("Message content is required")
 	}
 
 	userMessage := entities.NewMessage("user", messageContent)
+	userMessage.Attachments = attachments
 
-	// Create a cancellable context
-	ctx, cancel := context.WithCancel(eCtx.Request().Context())
+	// Create a cancellable context whose edits can be reviewed here
+	ctx, cancel := context.WithCancel(entities.WithEditReview(eCtx.Request().Context()))
 
 	// Store the cancellation function
 	c.activeCancelers.Store(chatID, cancel)
@@ -621,22 +686,16 @@ func (c *ChatController) SendMessageHandler(eCtx echo.Context) error {
 	// Send the message and get the AI responses
 	aiMessage, err := c.chatService.SendMessage(ctx, chatID, userMessage)
 	if err != nil {
-		switch err.(type) {
-		case *errors.CanceledError:
+		if _, ok := err.(*errors.CanceledError); ok {
 			c.logger.Info("Message processing was canceled", zap.String("chatID", chatID))
-			return eCtx.String(http.StatusRequestTimeout, "Request was canceled")
-		case *errors.NotFoundError:
-			return eCtx.String(http.StatusNotFound, "Chat not found")
-		default:
-			return eCtx.String(http.StatusInternalServerError, "Failed to load chat")
 		}
+		return err
 	}
 
 	// Get the chat to find all messages since the user's message
 	chat, err := c.chatService.GetChat(eCtx.Request().Context(), chatID)
 	if err != nil {
-		c.logger.Error("Failed to get chat after sending message", zap.Error(err))
-		return eCtx.String(http.StatusInternalServerError, "Failed to retrieve updated messages: "+err.Error())
+		return err
 	}
 
 	// Find the index of the user's message
@@ -675,6 +734,7 @@ func (c *ChatController) SendMessageHandler(eCtx echo.Context) error {
 	messageSessionID := fmt.Sprintf("message-session-%d", len(chat.Messages)/2) // Rough estimate of session count
 
 	data := map[string]any{
+		"ChatID":      chatID,
 		"UserMessage": userMessage,
 		"AIMessages":  aiMessages,
 		"SessionID":   messageSessionID,
@@ -683,8 +743,7 @@ func (c *ChatController) SendMessageHandler(eCtx echo.Context) error {
 	// Render complete message session using the template
 	var buf bytes.Buffer
 	if err := c.tmpl.ExecuteTemplate(&buf, "message_session_partial", data); err != nil {
-		c.logger.Error("Failed to render message session", zap.Error(err))
-		return eCtx.String(http.StatusInternalServerError, "Failed to render messages: "+err.Error())
+		return err
 	}
 
 	// Create a placeholder for the next message session
@@ -697,6 +756,140 @@ func (c *ChatController) SendMessageHandler(eCtx echo.Context) error {
 	return eCtx.HTML(http.StatusOK, responseHTML)
 }
 
+// EditMessageHandler changes a user message to the "content" form value, or
+// the text entered at an hx-prompt, and answers it again. The page is
+// reloaded to show the new response.
+func (c *ChatController) EditMessageHandler(eCtx echo.Context) error {
+	chatID := eCtx.Param("id")
+	content := eCtx.FormValue("content")
+	if content == "" {
+		content = eCtx.Request().Header.Get("HX-Prompt")
+	}
+	return c.resend(eCtx, chatID, func(ctx context.Context) error {
+		_, err := c.chatService.EditMessage(ctx, chatID, eCtx.Param("messageID"), content)
+		return err
+	})
+}
+
+// RegenerateHandler replaces the response to the chat's last user message.
+func (c *ChatController) RegenerateHandler(eCtx echo.Context) error {
+	chatID := eCtx.Param("id")
+	return c.resend(eCtx, chatID, func(ctx context.Context) error {
+		_, err := c.chatService.RegenerateLast(ctx, chatID)
+		return err
+	})
+}
+
+// resend runs an edit or regenerate, which can be canceled like a new
+// message, and reloads the chat.
+func (c *ChatController) resend(eCtx echo.Context, chatID string, send func(ctx context.Context) error) error {
+	if chatID == "" {
+		return errors.ValidationErrorf("Chat ID is required")
+	}
+
+	ctx, cancel := context.WithCancel(entities.WithEditReview(eCtx.Request().Context()))
+	c.activeCancelers.Store(chatID, cancel)
+	defer func() {
+		cancel()
+		c.activeCancelers.Delete(chatID)
+	}()
+
+	if err := send(ctx); err != nil {
+		if _, ok := err.(*errors.CanceledError); ok {
+			c.logger.Info("Message processing was canceled", zap.String("chatID", chatID))
+		}
+		return err
+	}
+
+	eCtx.Response().Header().Set("HX-Redirect", "/chats/"+chatID)
+	return eCtx.NoContent(http.StatusOK)
+}
+
+// DeleteMessageHandler removes a message, with the messages that belong to
+// it, and reloads the chat.
+func (c *ChatController) DeleteMessageHandler(eCtx echo.Context) error {
+	chatID := eCtx.Param("id")
+	if chatID == "" {
+		return errors.ValidationErrorf("Chat ID is required")
+	}
+
+	if err := c.chatService.DeleteMessage(eCtx.Request().Context(), chatID, eCtx.Param("messageID")); err != nil {
+		return err
+	}
+
+	eCtx.Response().Header().Set("HX-Redirect", "/chats/"+chatID)
+	return eCtx.NoContent(http.StatusOK)
+}
+
+// uploadedAttachments reads the images uploaded with a message as the
+// "attachments" field of a multipart form.
+func uploadedAttachments(eCtx echo.Context) ([]entities.Attachment, error) {
+	form, err := eCtx.MultipartForm()
+	if err != nil {
+		return nil, nil
+	}
+
+	var attachments []entities.Attachment
+	for _, header := range form.File["attachments"] {
+		file, err := header.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", header.Filename, err)
+		}
+		data, err := io.ReadAll(io.LimitReader(file, entities.MaxAttachmentBytes+1))
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", header.Filename, err)
+		}
+		attachment, err := entities.NewImageAttachment(header.Filename, data)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
+
+// UsageReportHandler shows usage and cost across all chats for the last
+// `days` days, 30 by default, or of all time when days is "all".
+func (c *ChatController) UsageReportHandler(eCtx echo.Context) error {
+	days := eCtx.QueryParam("days")
+	if days == "" {
+		days = "30"
+	}
+
+	var since time.Time
+	if days != "all" {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return errors.ValidationErrorf("days must be a positive number or \"all\"")
+		}
+		since = time.Now().AddDate(0, 0, -n)
+	}
+
+	report, err := c.chatService.UsageReport(eCtx.Request().Context(), since)
+	if err != nil {
+		return err
+	}
+
+	data := map[string]any{
+		"Title":           "AI Agents - Usage",
+		"ContentTemplate": "usage_report_content",
+		"Days":            days,
+		"Report":          report,
+		"Sections": []struct {
+			Title  string
+			Groups []entities.UsageGroup
+		}{
+			{"By agent", report.ByAgent},
+			{"By provider", report.ByProvider},
+			{"By model", report.ByModel},
+			{"By day", report.ByDay},
+		},
+	}
+
+	return c.tmpl.ExecuteTemplate(eCtx.Response().Writer, "layout", data)
+}
+
 // CancelMessageHandler cancels an ongoing message processing operation
 // ChatCostHandler handles the request to update the token and cost display
 func (c *ChatController) ChatCostHandler(eCtx echo.Context) error {
@@ -717,8 +910,10 @@ func (c *ChatController) ChatCostHandler(eCtx echo.Context) error {
 	c.logger.Info("Chat cost update", zap.String("chatID", chatID), zap.Int("totalTokens", chat.Usage.TotalTokens), zap.Float64("totalCost", chat.Usage.TotalCost))
 
 	data := map[string]any{
-		"TotalTokens": chat.Usage.TotalTokens,
-		"ChatCost":    chat.Usage.TotalCost,
+		"TotalTokens":     chat.Usage.TotalTokens,
+		"ChatCost":        chat.Usage.TotalCost,
+		"CacheSavings":    chat.Usage.TotalCacheSavings,
+		"BudgetRemaining": budgetRemaining(chat),
 	}
 
 	return c.tmpl.ExecuteTemplate(eCtx.Response().Writer, "chat_cost_partial", data)
@@ -727,7 +922,7 @@ func (c *ChatController) ChatCostHandler(eCtx echo.Context) error {
 func (c *ChatController) CancelMessageHandler(eCtx echo.Context) error {
 	chatID := eCtx.Param("id")
 	if chatID == "" {
-		return eCtx.String(http.StatusBadRequest, "Chat ID is required")
+		return errors.ValidationErrorf("Chat ID is required")
 	}
 
 	// Check if there's an active cancellation function for this chat
@@ -744,26 +939,19 @@ func (c *ChatController) CancelMessageHandler(eCtx echo.Context) error {
 		return eCtx.String(http.StatusOK, "Request canceled")
 	}
 
-	c.logger.Error("Invalid cancellation function", zap.String("chatID", chatID))
-	return eCtx.String(http.StatusInternalServerError, "Failed to cancel request")
+	return errors.InternalErrorf("invalid cancellation function for chat %s", chatID)
 }
 
 // GetMessagesHandler returns the latest messages for a chat
 func (c *ChatController) GetMessagesHandler(eCtx echo.Context) error {
 	chatID := eCtx.Param("id")
 	if chatID == "" {
-		return eCtx.JSON(http.StatusBadRequest, map[string]string{"error": "Chat ID is required"})
+		return errors.ValidationErrorf("Chat ID is required")
 	}
 
 	chat, err := c.chatService.GetChat(eCtx.Request().Context(), chatID)
 	if err != nil {
-		switch err.(type) {
-		case *errors.NotFoundError:
-			return eCtx.JSON(http.StatusNotFound, map[string]string{"error": "Chat not found"})
-		default:
-			c.logger.Error("Failed to get chat", zap.Error(err))
-			return eCtx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load chat"})
-		}
+		return err
 	}
 
 	// Filter out tool execution notification messages
@@ -788,22 +976,102 @@ func (c *ChatController) GetMessagesHandler(eCtx echo.Context) error {
 	})
 }
 
+// chatStreamEvent is an event queued for a chat's Server-Sent Events stream.
+type chatStreamEvent struct {
+	name string
+	data any
+}
+
+// ChatEventsHandler streams the chat's tool calls, updates and the end of
+// each run as Server-Sent Events, so the page can show tool progress while a
+// message is being processed. The subscriptions end when the client
+// disconnects.
+func (c *ChatController) ChatEventsHandler(eCtx echo.Context) error {
+	chatID := eCtx.Param("id")
+	if chatID == "" {
+		return errors.ValidationErrorf("Chat ID is required")
+	}
+
+	// Handlers must not block the publisher, so events are dropped when a
+	// slow client lets the queue fill up
+	queue := make(chan chatStreamEvent, 64)
+	enqueue := func(eventChatID, name string, data any) {
+		if eventChatID != chatID {
+			return
+		}
+		select {
+		case queue <- chatStreamEvent{name: name, data: data}:
+		default:
+			c.logger.Warn("Dropping chat event for slow client", zap.String("chatID", chatID), zap.String("event", name))
+		}
+	}
+
+	unsubscribers := []func(){
+		events.SubscribeToToolCallEvents(func(data events.ToolCallEventData) {
+			enqueue(data.Event.ChatID, "tool_call", data.Event)
+		}),
+		events.SubscribeToChatUpdateEvents(func(data events.ChatUpdateEventData) {
+			enqueue(data.Event.ChatID, "chat_update", data.Event)
+		}),
+		events.SubscribeToProcessFinishedEvents(func(data events.ProcessFinishedEventData) {
+			enqueue(data.Event.ChatID, "finished", data.Event)
+		}),
+		events.SubscribeToProcessFailedEvents(func(data events.ProcessFailedEventData) {
+			enqueue(data.Event.ChatID, "failed", data.Event)
+		}),
+	}
+	defer func() {
+		for _, unsubscribe := range unsubscribers {
+			unsubscribe()
+		}
+	}()
+
+	response := eCtx.Response()
+	response.Header().Set(echo.HeaderContentType, "text/event-stream")
+	response.Header().Set(echo.HeaderCacheControl, "no-cache")
+	response.Header().Set(echo.HeaderConnection, "keep-alive")
+	response.WriteHeader(http.StatusOK)
+	response.Flush()
+
+	// Comments keep proxies from closing an idle stream and reveal a
+	// disconnected client between runs
+	heartbeat := time.NewTicker(30 * time.Second)
+	defer heartbeat.Stop()
+
+	ctx := eCtx.Request().Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(response, ": ping\n\n"); err != nil {
+				return nil
+			}
+			response.Flush()
+		case event := <-queue:
+			data, err := json.Marshal(event.data)
+			if err != nil {
+				c.logger.Warn("Failed to encode chat event", zap.String("event", event.name), zap.Error(err))
+				continue
+			}
+			if _, err := fmt.Fprintf(response, "event: %s\ndata: %s\n\n", event.name, data); err != nil {
+				return nil
+			}
+			response.Flush()
+		}
+	}
+}
+
 // GetChatTitleHandler returns the current title of a chat
 func (c *ChatController) GetChatTitleHandler(eCtx echo.Context) error {
 	chatID := eCtx.Param("id")
 	if chatID == "" {
-		return eCtx.JSON(http.StatusBadRequest, map[string]string{"error": "Chat ID is required"})
+		return errors.ValidationErrorf("Chat ID is required")
 	}
 
 	chat, err := c.chatService.GetChat(eCtx.Request().Context(), chatID)
 	if err != nil {
-		switch err.(type) {
-		case *errors.NotFoundError:
-			return eCtx.JSON(http.StatusNotFound, map[string]string{"error": "Chat not found"})
-		default:
-			c.logger.Error("Failed to get chat title", zap.Error(err))
-			return eCtx.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load chat"})
-		}
+		return err
 	}
 
 	return eCtx.JSON(http.StatusOK, map[string]string{
@@ -815,7 +1083,7 @@ func (c *ChatController) GetChatTitleHandler(eCtx echo.Context) error {
 func (c *ChatController) GenerateTitleHandler(eCtx echo.Context) error {
 	chatID := eCtx.Param("id")
 	if chatID == "" {
-		return eCtx.JSON(http.StatusBadRequest, map[string]string{"error": "Chat ID is required"})
+		return errors.ValidationErrorf("Chat ID is required")
 	}
 
 	// Trigger title generation asynchronously
@@ -832,3 +1100,230 @@ func (c *ChatController) GenerateTitleHandler(eCtx echo.Context) error {
 		"status": "Title generation started",
 	})
 }
+
+// ExportChatHandler downloads a chat as markdown (default) or json
+func (c *ChatController) ExportChatHandler(eCtx echo.Context) error {
+	chatID := eCtx.Param("id")
+	if chatID == "" {
+		return errors.ValidationErrorf("Chat ID is required")
+	}
+
+	format := eCtx.QueryParam("format")
+	if format == "" {
+		format = "markdown"
+	}
+
+	data, err := c.chatService.ExportChat(eCtx.Request().Context(), chatID, format)
+	if err != nil {
+		return err
+	}
+
+	contentType, extension := "text/markdown; charset=utf-8", "md"
+	if format == "json" {
+		contentType, extension = "application/json", "json"
+	}
+	eCtx.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"chat-%s.%s\"", chatID, extension))
+	return eCtx.Blob(http.StatusOK, contentType, data)
+}
+
+// UpdateBudgetHandler sets the chat's cost and token budget. Empty or zero
+// values remove the limit.
+func (c *ChatController) UpdateBudgetHandler(eCtx echo.Context) error {
+	chatID := eCtx.Param("id")
+	if chatID == "" {
+		return errors.ValidationErrorf("Chat ID is required")
+	}
+
+	budget := &entities.ChatBudget{}
+	if maxCost := eCtx.FormValue("max_cost"); maxCost != "" {
+		value, err := strconv.ParseFloat(maxCost, 64)
+		if err != nil {
+			return errors.ValidationErrorf("Invalid max cost")
+		}
+		budget.MaxCost = value
+	}
+	if maxTokens := eCtx.FormValue("max_tokens"); maxTokens != "" {
+		value, err := strconv.Atoi(maxTokens)
+		if err != nil {
+			return errors.ValidationErrorf("Invalid max tokens")
+		}
+		budget.MaxTokens = value
+	}
+
+	if err := c.chatService.SetBudget(eCtx.Request().Context(), chatID, budget); err != nil {
+		return err
+	}
+
+	eCtx.Response().Header().Set("HX-Trigger", "refreshChatCost")
+	return eCtx.NoContent(http.StatusOK)
+}
+
+// UpdateInstructionsHandler sets the chat's session instructions. Empty
+// instructions remove them.
+func (c *ChatController) UpdateInstructionsHandler(eCtx echo.Context) error {
+	chatID := eCtx.Param("id")
+	if chatID == "" {
+		return errors.ValidationErrorf("Chat ID is required")
+	}
+
+	if err := c.chatService.SetInstructions(eCtx.Request().Context(), chatID, eCtx.FormValue("instructions")); err != nil {
+		return err
+	}
+
+	return eCtx.NoContent(http.StatusOK)
+}
+
+// ApprovePlanHandler runs the chat's pending plan and reloads the chat to
+// show the results.
+func (c *ChatController) ApprovePlanHandler(eCtx echo.Context) error {
+	chatID := eCtx.Param("id")
+	if chatID == "" {
+		return errors.ValidationErrorf("Chat ID is required")
+	}
+
+	if _, err := c.chatService.ApprovePlan(eCtx.Request().Context(), chatID); err != nil {
+		return err
+	}
+
+	eCtx.Response().Header().Set("HX-Redirect", "/chats/"+chatID)
+	return eCtx.NoContent(http.StatusOK)
+}
+
+// PreviewCompactionHandler returns which messages compacting the chat would
+// summarize, as JSON.
+func (c *ChatController) PreviewCompactionHandler(eCtx echo.Context) error {
+	chatID := eCtx.Param("id")
+	if chatID == "" {
+		return errors.ValidationErrorf("Chat ID is required")
+	}
+
+	preview, err := c.chatService.PreviewCompaction(eCtx.Request().Context(), chatID)
+	if err != nil {
+		return err
+	}
+	return eCtx.JSON(http.StatusOK, preview)
+}
+
+// CompactChatHandler summarizes the chat's older messages and returns the
+// estimated context tokens before and after, as JSON.
+func (c *ChatController) CompactChatHandler(eCtx echo.Context) error {
+	chatID := eCtx.Param("id")
+	if chatID == "" {
+		return errors.ValidationErrorf("Chat ID is required")
+	}
+
+	ctx := eCtx.Request().Context()
+	before, err := c.chatService.ContextUsage(ctx, chatID)
+	if err != nil {
+		return err
+	}
+	if err := c.chatService.CompactChat(ctx, chatID); err != nil {
+		return err
+	}
+	after, err := c.chatService.ContextUsage(ctx, chatID)
+	if err != nil {
+		return err
+	}
+
+	return eCtx.JSON(http.StatusOK, map[string]int{
+		"tokens_before": before.Tokens,
+		"tokens_after":  after.Tokens,
+		"tokens_saved":  before.Tokens - after.Tokens,
+	})
+}
+
+// UpdateReviewEditsHandler turns the review of the chat's file edits on when
+// the "review_edits" form value is "on", and off otherwise.
+func (c *ChatController) UpdateReviewEditsHandler(eCtx echo.Context) error {
+	chatID := eCtx.Param("id")
+	if chatID == "" {
+		return errors.ValidationErrorf("Chat ID is required")
+	}
+
+	if err := c.chatService.SetReviewEdits(eCtx.Request().Context(), chatID, eCtx.FormValue("review_edits") == "on"); err != nil {
+		return err
+	}
+
+	return eCtx.NoContent(http.StatusOK)
+}
+
+// PendingEditsHandler renders the diffs of the chat's edits that wait for
+// the user to apply or reject them.
+func (c *ChatController) PendingEditsHandler(eCtx echo.Context) error {
+	chatID := eCtx.Param("id")
+	if chatID == "" {
+		return errors.ValidationErrorf("Chat ID is required")
+	}
+
+	return c.renderPendingEdits(eCtx, chatID)
+}
+
+// ResolveEditHandler applies the pending edit when the "decision" form value
+// is "apply" and rejects it, with the "note" form value as the reason, when
+// it is "reject". The remaining edits are rendered.
+func (c *ChatController) ResolveEditHandler(eCtx echo.Context) error {
+	chatID := eCtx.Param("id")
+	if chatID == "" {
+		return errors.ValidationErrorf("Chat ID is required")
+	}
+
+	var decision entities.EditDecision
+	switch eCtx.FormValue("decision") {
+	case "apply":
+		decision.Apply = true
+	case "reject":
+		decision.Note = strings.TrimSpace(eCtx.FormValue("note"))
+	default:
+		return errors.ValidationErrorf("decision must be apply or reject")
+	}
+	if err := c.chatService.ResolveEdit(eCtx.Request().Context(), chatID, eCtx.Param("reviewID"), decision); err != nil {
+		return err
+	}
+
+	return c.renderPendingEdits(eCtx, chatID)
+}
+
+func (c *ChatController) renderPendingEdits(eCtx echo.Context, chatID string) error {
+	edits, err := c.chatService.PendingEdits(eCtx.Request().Context(), chatID)
+	if err != nil {
+		return err
+	}
+
+	data := map[string]any{
+		"ChatID": chatID,
+		"Edits":  edits,
+	}
+	return c.tmpl.ExecuteTemplate(eCtx.Response().Writer, "edit_reviews_partial", data)
+}
+
+// DiscardPlanHandler drops the chat's pending plan.
+func (c *ChatController) DiscardPlanHandler(eCtx echo.Context) error {
+	chatID := eCtx.Param("id")
+	if chatID == "" {
+		return errors.ValidationErrorf("Chat ID is required")
+	}
+
+	if err := c.chatService.DiscardPlan(eCtx.Request().Context(), chatID); err != nil {
+		return err
+	}
+
+	eCtx.Response().Header().Set("HX-Redirect", "/chats/"+chatID)
+	return eCtx.NoContent(http.StatusOK)
+}
+
+// budgetRemaining describes what is left of the chat's budget, or returns an
+// empty string when the chat has no budget.
+func budgetRemaining(chat *entities.Chat) string {
+	if chat.Budget == nil {
+		return ""
+	}
+	tokens, cost := chat.Budget.Remaining(chat.Usage)
+	var parts []string
+	if cost >= 0 {
+		parts = append(parts, fmt.Sprintf("$%.2f", cost))
+	}
+	if tokens >= 0 {
+		parts = append(parts, fmt.Sprintf("%d tokens", tokens))
+	}
+	return strings.Join(parts, " / ") + " left"
+}
@@ -0,0 +1,85 @@
+package uicontrollers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/drujensen/aiagent/internal/domain/errs"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// StatusClientClosedRequest is the non-standard status, taken from nginx,
+// for a request the client canceled before the response was ready.
+const StatusClientClosedRequest = 499
+
+// ErrorResponse is the JSON body of every error response.
+type ErrorResponse struct {
+	Error string `json:"error"`
+	Type  string `json:"type"`
+}
+
+// errorStatus maps an error to its HTTP status and the type reported in the
+// ErrorResponse. Errors that aren't domain errors are internal.
+func errorStatus(err error) (int, string) {
+	switch err := err.(type) {
+	case *errors.ValidationError:
+		return http.StatusBadRequest, "validation"
+	case *errors.NotFoundError:
+		return http.StatusNotFound, "not_found"
+	case *errors.DuplicateError:
+		return http.StatusConflict, "duplicate"
+	case *errors.CanceledError:
+		return StatusClientClosedRequest, "canceled"
+	case *errors.BudgetExceededError:
+		return http.StatusPaymentRequired, "budget_exceeded"
+	case *errors.RateLimitError:
+		return http.StatusTooManyRequests, "rate_limit"
+	case *errors.ContextWindowError:
+		return http.StatusRequestEntityTooLarge, "context_window"
+	case *echo.HTTPError:
+		switch err.Code {
+		case http.StatusBadRequest:
+			return err.Code, "validation"
+		case http.StatusNotFound:
+			return err.Code, "not_found"
+		}
+		return err.Code, "http"
+	}
+	return http.StatusInternalServerError, "internal"
+}
+
+// HTTPErrorHandler writes the errors returned by handlers as an
+// ErrorResponse with the status errorStatus gives them. Server errors are
+// logged; the message of an internal error is not sent, as it may expose
+// file paths or OS errors.
+func HTTPErrorHandler(logger *zap.Logger) echo.HTTPErrorHandler {
+	return func(err error, eCtx echo.Context) {
+		if eCtx.Response().Committed {
+			return
+		}
+
+		status, kind := errorStatus(err)
+		message := err.Error()
+		if httpErr, ok := err.(*echo.HTTPError); ok {
+			message = fmt.Sprint(httpErr.Message)
+		} else if kind == "internal" {
+			message = "Internal server error"
+		}
+		if status >= http.StatusInternalServerError {
+			logger.Error("Request failed",
+				zap.String("method", eCtx.Request().Method),
+				zap.String("path", eCtx.Request().URL.Path),
+				zap.Error(err))
+		}
+
+		if eCtx.Request().Method == http.MethodHead {
+			err = eCtx.NoContent(status)
+		} else {
+			err = eCtx.JSON(status, ErrorResponse{Error: message, Type: kind})
+		}
+		if err != nil {
+			logger.Error("Failed to write error response", zap.Error(err))
+		}
+	}
+}
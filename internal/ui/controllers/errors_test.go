@@ -0,0 +1,75 @@
+package uicontrollers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/drujensen/aiagent/internal/domain/errs"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		kind   string
+	}{
+		{"validation", errors.ValidationErrorf("bad input"), http.StatusBadRequest, "validation"},
+		{"not found", errors.NotFoundErrorf("no chat"), http.StatusNotFound, "not_found"},
+		{"duplicate", errors.DuplicateErrorf("exists"), http.StatusConflict, "duplicate"},
+		{"canceled", errors.CanceledErrorf("canceled"), StatusClientClosedRequest, "canceled"},
+		{"budget exceeded", errors.BudgetExceededErrorf("over budget"), http.StatusPaymentRequired, "budget_exceeded"},
+		{"rate limit", errors.RateLimitErrorf("slow down"), http.StatusTooManyRequests, "rate_limit"},
+		{"context window", errors.ContextWindowErrorf("too long"), http.StatusRequestEntityTooLarge, "context_window"},
+		{"internal", errors.InternalErrorf("open /tmp/x: permission denied"), http.StatusInternalServerError, "internal"},
+		{"plain error", fmt.Errorf("boom"), http.StatusInternalServerError, "internal"},
+		{"http bad request", echo.NewHTTPError(http.StatusBadRequest, "bad"), http.StatusBadRequest, "validation"},
+		{"http not found", echo.ErrNotFound, http.StatusNotFound, "not_found"},
+		{"http other", echo.ErrMethodNotAllowed, http.StatusMethodNotAllowed, "http"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, kind := errorStatus(tt.err)
+			if status != tt.status || kind != tt.kind {
+				t.Errorf("Expected %d %q, got %d %q", tt.status, tt.kind, status, kind)
+			}
+		})
+	}
+}
+
+func TestHTTPErrorHandler_HidesInternalMessages(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		message string
+	}{
+		{"domain error", errors.ValidationErrorf("name is required"), "name is required"},
+		{"internal error", errors.InternalErrorf("open /tmp/x: permission denied"), "Internal server error"},
+		{"plain error", fmt.Errorf("boom"), "Internal server error"},
+		{"http error", echo.NewHTTPError(http.StatusBadRequest, "bad form"), "bad form"},
+	}
+
+	handler := HTTPErrorHandler(zap.NewNop())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			eCtx := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+
+			handler(tt.err, eCtx)
+
+			var body ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Expected a JSON error response, got %q: %v", rec.Body.String(), err)
+			}
+			if body.Error != tt.message {
+				t.Errorf("Expected message %q, got %q", tt.message, body.Error)
+			}
+		})
+	}
+}
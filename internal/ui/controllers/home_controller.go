@@ -3,6 +3,7 @@ package uicontrollers
 import (
 	"html/template"
 	"net/http"
+	"strings"
 
 	"github.com/drujensen/aiagent/internal/domain/services"
 
@@ -50,12 +51,25 @@ func (c *HomeController) HomeHandler(eCtx echo.Context) error {
 }
 
 func (c *HomeController) ChatsPartialHandler(eCtx echo.Context) error {
-	chats, err := c.chatService.ListChats(eCtx.Request().Context())
+	query := strings.TrimSpace(eCtx.QueryParam("q"))
+	chats, err := c.chatService.SearchChats(eCtx.Request().Context(), query)
 	if err != nil {
 		c.logger.Error("Failed to list active chats", zap.Error(err))
 		return eCtx.String(http.StatusInternalServerError, "Failed to load chats")
 	}
 
+	projects, err := c.chatService.ListProjects(eCtx.Request().Context())
+	if err != nil {
+		c.logger.Error("Failed to list projects", zap.Error(err))
+		return eCtx.String(http.StatusInternalServerError, "Failed to load projects")
+	}
+
+	// Chats are grouped under their project, the rest are listed ungrouped
+	projectChats := make(map[string][]map[string]string, len(projects))
+	for _, project := range projects {
+		projectChats[project.ID] = []map[string]string{}
+	}
+
 	// Create a new slice to hold the processed chat data
 	processedChats := make([]map[string]string, 0, len(chats))
 
@@ -73,12 +87,32 @@ func (c *HomeController) ChatsPartialHandler(eCtx echo.Context) error {
 			"AgentName": agent.Name,
 		}
 
+		if _, ok := projectChats[chat.ProjectID]; ok {
+			projectChats[chat.ProjectID] = append(projectChats[chat.ProjectID], chatData)
+			continue
+		}
+
 		// Append the processed chat data to the slice
 		processedChats = append(processedChats, chatData)
 	}
 
+	processedProjects := make([]map[string]any, 0, len(projects))
+	for _, project := range projects {
+		if query != "" && len(projectChats[project.ID]) == 0 {
+			continue // Hide projects without matching chats while searching
+		}
+		processedProjects = append(processedProjects, map[string]any{
+			"ID":        project.ID,
+			"Name":      project.Name,
+			"Workspace": project.Workspace,
+			"Chats":     projectChats[project.ID],
+		})
+	}
+
 	data := map[string]any{
-		"Chats": processedChats,
+		"Projects": processedProjects,
+		"Chats":    processedChats,
+		"Query":    query,
 	}
 
 	return c.tmpl.ExecuteTemplate(eCtx.Response().Writer, "sidebar_chats", data)
@@ -0,0 +1,78 @@
+package uicontrollers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/drujensen/aiagent/internal/impl/config"
+	"github.com/drujensen/aiagent/internal/impl/logging"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type LogController struct {
+	logger       *zap.Logger
+	globalConfig *config.GlobalConfig
+}
+
+func NewLogController(logger *zap.Logger, globalConfig *config.GlobalConfig) *LogController {
+	return &LogController{
+		logger:       logger,
+		globalConfig: globalConfig,
+	}
+}
+
+func (c *LogController) RegisterRoutes(e *echo.Echo) {
+	e.GET("/admin/logs", c.QueryLogsHandler)
+}
+
+// QueryLogsHandler returns the entries of the JSON log file matching the
+// level, chat_id, tool, since, from, to and limit query parameters.
+func (c *LogController) QueryLogsHandler(eCtx echo.Context) error {
+	if c.globalConfig.LogFile == "" {
+		return eCtx.JSON(http.StatusNotFound, map[string]string{"error": "No log file is configured"})
+	}
+
+	filter := logging.Filter{
+		Level:  eCtx.QueryParam("level"),
+		ChatID: eCtx.QueryParam("chat_id"),
+		Tool:   eCtx.QueryParam("tool"),
+	}
+	if since := eCtx.QueryParam("since"); since != "" {
+		d, err := time.ParseDuration(since)
+		if err != nil {
+			return eCtx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid since duration"})
+		}
+		filter.From = time.Now().Add(-d)
+	}
+	for param, target := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		value := eCtx.QueryParam(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return eCtx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid " + param + " time, use RFC 3339"})
+		}
+		*target = t
+	}
+	if limit := eCtx.QueryParam("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return eCtx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid limit"})
+		}
+		filter.Limit = n
+	}
+
+	entries, err := logging.Query(c.globalConfig.LogFile, filter)
+	if err != nil {
+		c.logger.Error("Failed to query logs", zap.Error(err))
+		return eCtx.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if entries == nil {
+		entries = []logging.Entry{}
+	}
+	return eCtx.JSON(http.StatusOK, entries)
+}
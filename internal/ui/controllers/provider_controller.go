@@ -1,9 +1,14 @@
 package uicontrollers
 
 import (
+	"fmt"
 	"html/template"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	errors "github.com/drujensen/aiagent/internal/domain/errs"
 	"github.com/drujensen/aiagent/internal/domain/services"
 
 	"github.com/labstack/echo/v4"
@@ -30,6 +35,10 @@ func (c *ProviderController) RegisterRoutes(e *echo.Echo) {
 	e.GET("/providers", c.ListProvidersHandler)
 	e.POST("/providers/refresh", c.RefreshProvidersHandler)
 	e.GET("/api/providers/:id", c.GetProviderHandler)
+	e.PUT("/providers/:id/rate-limit", c.UpdateRateLimitHandler)
+	e.PUT("/providers/:id/timeout", c.UpdateTimeoutHandler)
+	e.POST("/providers/:id/check", c.CheckProviderHandler)
+	e.POST("/providers/:id/sync-models", c.SyncModelsHandler)
 }
 
 func (c *ProviderController) ListProvidersHandler(eCtx echo.Context) error {
@@ -87,3 +96,139 @@ func (c *ProviderController) RefreshProvidersHandler(eCtx echo.Context) error {
 	eCtx.Response().Header().Set("HX-Trigger", `{"refreshProviders": true}`)
 	return eCtx.String(http.StatusOK, "Providers refreshed successfully from models.dev!")
 }
+
+// UpdateRateLimitHandler sets the provider's requests per minute limit
+func (c *ProviderController) UpdateRateLimitHandler(eCtx echo.Context) error {
+	id := eCtx.Param("id")
+	if id == "" {
+		return eCtx.String(http.StatusBadRequest, "Provider ID is required")
+	}
+
+	requestsPerMinute := 0
+	if value := eCtx.FormValue("requests_per_minute"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return eCtx.String(http.StatusBadRequest, "Invalid requests per minute")
+		}
+		requestsPerMinute = parsed
+	}
+
+	if err := c.providerService.SetRequestsPerMinute(eCtx.Request().Context(), id, requestsPerMinute); err != nil {
+		switch err.(type) {
+		case *errors.NotFoundError:
+			return eCtx.String(http.StatusNotFound, "Provider not found")
+		case *errors.ValidationError:
+			return eCtx.String(http.StatusBadRequest, err.Error())
+		default:
+			c.logger.Error("Failed to update provider rate limit", zap.Error(err))
+			return eCtx.String(http.StatusInternalServerError, "Failed to update rate limit")
+		}
+	}
+
+	if requestsPerMinute == 0 {
+		return eCtx.String(http.StatusOK, "Rate limit removed")
+	}
+	return eCtx.String(http.StatusOK, "Rate limit set to "+strconv.Itoa(requestsPerMinute)+" requests per minute")
+}
+
+// UpdateTimeoutHandler sets the provider's request timeout in seconds. An
+// empty value restores the default and 0 disables the timeout.
+func (c *ProviderController) UpdateTimeoutHandler(eCtx echo.Context) error {
+	id := eCtx.Param("id")
+	if id == "" {
+		return eCtx.String(http.StatusBadRequest, "Provider ID is required")
+	}
+
+	var timeoutSeconds *int
+	if value := eCtx.FormValue("request_timeout_seconds"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return eCtx.String(http.StatusBadRequest, "Invalid request timeout")
+		}
+		timeoutSeconds = &parsed
+	}
+
+	if err := c.providerService.SetRequestTimeout(eCtx.Request().Context(), id, timeoutSeconds); err != nil {
+		switch err.(type) {
+		case *errors.NotFoundError:
+			return eCtx.String(http.StatusNotFound, "Provider not found")
+		case *errors.ValidationError:
+			return eCtx.String(http.StatusBadRequest, err.Error())
+		default:
+			c.logger.Error("Failed to update provider request timeout", zap.Error(err))
+			return eCtx.String(http.StatusInternalServerError, "Failed to update request timeout")
+		}
+	}
+
+	switch {
+	case timeoutSeconds == nil:
+		return eCtx.String(http.StatusOK, "Request timeout reset to the default")
+	case *timeoutSeconds == 0:
+		return eCtx.String(http.StatusOK, "Request timeout disabled")
+	}
+	return eCtx.String(http.StatusOK, "Request timeout set to "+strconv.Itoa(*timeoutSeconds)+" seconds")
+}
+
+// CheckProviderHandler tests the provider's base URL and API key
+func (c *ProviderController) CheckProviderHandler(eCtx echo.Context) error {
+	id := eCtx.Param("id")
+	if id == "" {
+		return eCtx.String(http.StatusBadRequest, "Provider ID is required")
+	}
+
+	if err := c.providerService.CheckProvider(eCtx.Request().Context(), id); err != nil {
+		switch err.(type) {
+		case *errors.NotFoundError:
+			return eCtx.String(http.StatusNotFound, "Provider not found")
+		case *errors.ValidationError:
+			return eCtx.String(http.StatusOK, "Connection failed: "+err.Error())
+		default:
+			c.logger.Error("Failed to check provider", zap.Error(err))
+			return eCtx.String(http.StatusInternalServerError, "Failed to check provider")
+		}
+	}
+	return eCtx.String(http.StatusOK, "Connection succeeded")
+}
+
+// SyncModelsHandler refreshes the provider's models from its models endpoint
+func (c *ProviderController) SyncModelsHandler(eCtx echo.Context) error {
+	id := eCtx.Param("id")
+	if id == "" {
+		return eCtx.String(http.StatusBadRequest, "Provider ID is required")
+	}
+
+	ctx := eCtx.Request().Context()
+	if err := c.providerService.SyncModels(ctx, id); err != nil {
+		switch err.(type) {
+		case *errors.NotFoundError:
+			return eCtx.String(http.StatusNotFound, "Provider not found")
+		case *errors.ValidationError:
+			return eCtx.String(http.StatusOK, "Failed to refresh models: "+err.Error())
+		default:
+			c.logger.Error("Failed to sync provider models", zap.Error(err))
+			return eCtx.String(http.StatusInternalServerError, "Failed to refresh models")
+		}
+	}
+
+	provider, err := c.providerService.GetProvider(ctx, id)
+	if err != nil {
+		return eCtx.String(http.StatusOK, "Models refreshed")
+	}
+	var added, removed []string
+	for _, model := range provider.Models {
+		switch model.SyncStatus {
+		case entities.ModelSyncNew:
+			added = append(added, model.Name)
+		case entities.ModelSyncRemoved:
+			removed = append(removed, model.Name)
+		}
+	}
+	message := fmt.Sprintf("Models refreshed: %d new, %d no longer listed", len(added), len(removed))
+	if len(added) > 0 {
+		message += ". New: " + strings.Join(added, ", ")
+	}
+	if len(removed) > 0 {
+		message += ". No longer listed: " + strings.Join(removed, ", ")
+	}
+	return eCtx.String(http.StatusOK, message)
+}
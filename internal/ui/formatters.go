@@ -7,8 +7,20 @@ import (
 	"html/template"
 	"path/filepath"
 	"strings"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
 )
 
+// attachmentURL is the source of an attached image. Only data URLs of images
+// and web URLs are trusted; anything else renders as no image.
+func attachmentURL(attachment entities.Attachment) template.URL {
+	url := attachment.DataURL()
+	if strings.HasPrefix(url, "data:image/") || strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "http://") {
+		return template.URL(url)
+	}
+	return ""
+}
+
 // formatToolName formats tool names with relevant arguments for display
 func formatToolName(toolName, arguments string) string {
 	name, suffix := formatToolNameParts(toolName, arguments)
@@ -0,0 +1,74 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+// readinessTimeout bounds each readiness check, so a hung dependency fails
+// the probe instead of holding it open.
+const readinessTimeout = 2 * time.Second
+
+// ReadinessCheck reports whether a dependency of the server can be used.
+type ReadinessCheck func(ctx context.Context) error
+
+// SetVersion sets the build version the health endpoints report.
+func (u *UI) SetVersion(version string) {
+	u.version = version
+}
+
+// AddReadinessCheck adds a check /readyz runs, such as pinging the database.
+func (u *UI) AddReadinessCheck(name string, check ReadinessCheck) {
+	if u.readinessChecks == nil {
+		u.readinessChecks = make(map[string]ReadinessCheck)
+	}
+	u.readinessChecks[name] = check
+}
+
+// handleHealthz reports that the process is up.
+func (u *UI) handleHealthz(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]any{"status": "ok", "version": u.version})
+}
+
+// handleReadyz runs the readiness checks and answers 503 when any fails.
+func (u *UI) handleReadyz(c echo.Context) error {
+	names := make([]string, 0, len(u.readinessChecks))
+	for name := range u.readinessChecks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	status, code := "ready", http.StatusOK
+	checks := make(map[string]string, len(names))
+	for _, name := range names {
+		ctx, cancel := context.WithTimeout(c.Request().Context(), readinessTimeout)
+		err := u.readinessChecks[name](ctx)
+		cancel()
+		if err != nil {
+			u.logger.Warn("Readiness check failed", zap.String("check", name), zap.Error(err))
+			checks[name] = err.Error()
+			status, code = "not ready", http.StatusServiceUnavailable
+			continue
+		}
+		checks[name] = "ok"
+	}
+	return c.JSON(code, map[string]any{"status": status, "version": u.version, "checks": checks})
+}
+
+// checkProviders reports whether the provider configuration is loaded.
+func (u *UI) checkProviders(ctx context.Context) error {
+	providers, err := u.providerService.ListProviders(ctx)
+	if err != nil {
+		return err
+	}
+	if len(providers) == 0 {
+		return fmt.Errorf("no providers configured")
+	}
+	return nil
+}
@@ -35,8 +35,15 @@ document.addEventListener('htmx:afterRequest', function(event) {
 document.addEventListener('htmx:responseError', function(event) {
     if (event.detail.target.id === 'model') {
         const providerErrorDiv = document.getElementById('provider-error');
+        // The server sends errors as JSON: {"error": "...", "type": "..."}
+        let message = event.detail.xhr.responseText;
+        try {
+            message = JSON.parse(message).error;
+        } catch (e) {
+            // Not an error envelope; show the body as is.
+        }
         providerErrorDiv.style.display = 'block';
-        providerErrorDiv.textContent = 'Error loading models: ' + event.detail.xhr.responseText;
+        providerErrorDiv.textContent = 'Error loading models: ' + message;
     }
 });
 
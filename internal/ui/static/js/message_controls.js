@@ -1,12 +1,20 @@
 function showTempMessage(form) {
     const messageText = form.elements["message"].value.trim();
-    if (!messageText) return;
+    const files = form.elements["attachments"] ? Array.from(form.elements["attachments"].files) : [];
+    if (!messageText && files.length === 0) return;
+    const attached = files.map(file => {
+        const note = document.createElement('div');
+        note.className = 'message-model';
+        note.textContent = 'Attached ' + file.name;
+        return note.outerHTML;
+    }).join('');
 
     const sessionId = 'temp-session-' + Date.now();
     const messageHtml = `
         <div id="${sessionId}" class="message-session">
             <div class="message user-message">
                 <div class="message-content">${marked.parse(messageText)}</div>
+                ${attached}
             </div>
             <div id="thinking-message" class="message agent-message">
                 <div class="message-content thinking-content">
@@ -28,12 +36,22 @@ function scrollToResponse() {
     }
 }
 
+// errorMessage returns the message of an error response, which the server
+// sends as JSON: {"error": "...", "type": "..."}
+function errorMessage(xhr) {
+    try {
+        return JSON.parse(xhr.responseText).error;
+    } catch (e) {
+        return xhr.responseText;
+    }
+}
+
 function handleResponseError(form, event) {
     const thinkingMessage = document.getElementById('thinking-message');
     if (thinkingMessage) {
         thinkingMessage.innerHTML = `
             <div class="message-content error-content">
-                Error: ${event.detail.xhr.responseText || 'Failed to get response'}
+                Error: ${errorMessage(event.detail.xhr) || 'Failed to get response'}
             </div>
         `;
     }
@@ -41,6 +59,30 @@ function handleResponseError(form, event) {
     toggleCancelButton(false);
 }
 
+// compactChat shows which messages would be summarized and, once confirmed,
+// compacts the chat and reports the tokens saved.
+async function compactChat(chatId) {
+    const url = `/chats/${chatId}/compact`;
+    const preview = await fetch(url);
+    const previewBody = await preview.json();
+    if (!preview.ok) {
+        alert('Cannot compact: ' + previewBody.error);
+        return;
+    }
+    const question = `Summarize messages 1-${previewBody.to} of ${previewBody.total} ` +
+        `(~${previewBody.summarized_tokens} tokens) and keep the rest?`;
+    if (!confirm(question)) return;
+
+    const response = await fetch(url, { method: 'POST' });
+    const body = await response.json();
+    if (!response.ok) {
+        alert('Failed to compact: ' + body.error);
+        return;
+    }
+    alert(`Compacted: ${body.tokens_before} → ${body.tokens_after} tokens, saving ${body.tokens_saved}`);
+    window.location.reload();
+}
+
 function resetForm(button) {
     const form = document.getElementById('message-form');
     form.reset();
@@ -78,9 +120,96 @@ function updateMessageList(messages) {
     // No WebSocket update logic needed
 }
 
+// Render the agent's plan progress pushed over the WebSocket
+function renderProgress(progress) {
+    const panel = document.getElementById('progress-panel');
+    const container = document.getElementById('messages-container');
+    if (!panel || !container || container.dataset.chatId !== progress.chat_id) return;
+
+    if (!progress.total || progress.completed >= progress.total) {
+        panel.style.display = 'none';
+        panel.innerHTML = '';
+        return;
+    }
+
+    const icons = { completed: '✓', cancelled: '✗', in_progress: '⟳', pending: '○' };
+    const items = (progress.items || []).map(item => {
+        const li = document.createElement('li');
+        li.className = 'progress-item ' + item.status;
+        li.textContent = (icons[item.status] || '○') + ' ' + item.content;
+        return li.outerHTML;
+    }).join('');
+    const step = Math.min(progress.completed + 1, progress.total);
+    const current = document.createElement('span');
+    current.textContent = progress.current || '';
+
+    panel.innerHTML = `<div class="progress-header">Step ${step} of ${progress.total}: ${current.outerHTML}</div><ul>${items}</ul>`;
+    panel.style.display = 'block';
+}
+
+function connectProgressSocket() {
+    const scheme = window.location.protocol === 'https:' ? 'wss' : 'ws';
+    const socket = new WebSocket(`${scheme}://${window.location.host}/ws`);
+    socket.onmessage = (event) => {
+        const message = JSON.parse(event.data);
+        if (message.type === 'progress') {
+            renderProgress(message.data);
+        } else if (message.type === 'title') {
+            htmx.trigger('body', 'refreshTitle');
+            htmx.trigger('body', 'refreshChats');
+        }
+    };
+    socket.onclose = () => setTimeout(connectProgressSocket, 5000);
+}
+
+// Show each tool call of the running message as it completes
+function renderToolActivity(toolEvent) {
+    const panel = document.getElementById('tool-activity');
+    if (!panel) return;
+
+    const item = document.createElement('div');
+    item.className = 'tool-activity-item' + (toolEvent.error ? ' error' : '');
+    item.textContent = (toolEvent.error ? '✗ ' : '✓ ') + toolEvent.tool_name;
+    if (toolEvent.error) {
+        item.title = toolEvent.error;
+    }
+    panel.appendChild(item);
+    panel.style.display = 'block';
+    panel.scrollTop = panel.scrollHeight;
+}
+
+function clearToolActivity() {
+    const panel = document.getElementById('tool-activity');
+    if (!panel) return;
+    panel.style.display = 'none';
+    panel.innerHTML = '';
+}
+
+// Stream the chat's events from the server; EventSource reconnects on its own
+function connectChatEvents() {
+    const container = document.getElementById('messages-container');
+    if (!container || !container.dataset.chatId || !window.EventSource) return;
+
+    const source = new EventSource(`/chats/${encodeURIComponent(container.dataset.chatId)}/events`);
+    source.addEventListener('tool_call', (event) => renderToolActivity(JSON.parse(event.data)));
+    source.addEventListener('chat_update', (event) => {
+        const updateType = JSON.parse(event.data).update_type;
+        if (updateType === 'usage') {
+            htmx.trigger('body', 'refreshChatCost');
+        } else if (updateType === 'edit_review' || updateType === 'edit_resolved') {
+            htmx.trigger('body', 'refreshEdits');
+        }
+    });
+    source.addEventListener('finished', clearToolActivity);
+    source.addEventListener('failed', clearToolActivity);
+    window.addEventListener('beforeunload', () => source.close());
+}
+
 document.addEventListener('DOMContentLoaded', () => {
     initCopyButtons();
     scrollToResponse();
+    connectProgressSocket();
+    connectChatEvents();
 
     const textarea = document.getElementById('message-input');
     if (textarea) {
@@ -2,12 +2,14 @@ package ui
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"embed"
 	"fmt"
 	"html/template"
 	"io"
 	"mime"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -16,6 +18,7 @@ import (
 	"sync"
 
 	"github.com/drujensen/aiagent/internal/domain/entities"
+	"github.com/drujensen/aiagent/internal/domain/events"
 	"github.com/drujensen/aiagent/internal/domain/services"
 	"github.com/drujensen/aiagent/internal/impl/config"
 	"github.com/drujensen/aiagent/internal/impl/tools"
@@ -43,14 +46,17 @@ type UI struct {
 	providerService     services.ProviderService
 	modelRefreshService services.ModelRefreshService
 	modelFilterService  *services.ModelFilterService
+	approvalService     services.ApprovalService
 	globalConfig        *config.GlobalConfig
 	logger              *zap.Logger
 	wsUpgrader          websocket.Upgrader
 	wsClients           map[*websocket.Conn]bool
 	wsClientsMutex      sync.RWMutex
+	version             string
+	readinessChecks     map[string]ReadinessCheck
 }
 
-func NewUI(chatService services.ChatService, agentService services.AgentService, modelService services.ModelService, toolService services.ToolService, providerService services.ProviderService, modelRefreshService services.ModelRefreshService, modelFilterService *services.ModelFilterService, globalConfig *config.GlobalConfig, logger *zap.Logger) *UI {
+func NewUI(chatService services.ChatService, agentService services.AgentService, modelService services.ModelService, toolService services.ToolService, providerService services.ProviderService, modelRefreshService services.ModelRefreshService, modelFilterService *services.ModelFilterService, approvalService services.ApprovalService, globalConfig *config.GlobalConfig, logger *zap.Logger) *UI {
 	ui := &UI{
 		chatService:         chatService,
 		agentService:        agentService,
@@ -59,6 +65,7 @@ func NewUI(chatService services.ChatService, agentService services.AgentService,
 		providerService:     providerService,
 		modelRefreshService: modelRefreshService,
 		modelFilterService:  modelFilterService,
+		approvalService:     approvalService,
 		globalConfig:        globalConfig,
 		logger:              logger,
 		wsUpgrader: websocket.Upgrader{
@@ -107,6 +114,18 @@ func (u *UI) handleWebSocket(c echo.Context) error {
 	return nil
 }
 
+// broadcast sends a JSON message to every connected WebSocket client
+func (u *UI) broadcast(message any) {
+	u.wsClientsMutex.Lock()
+	defer u.wsClientsMutex.Unlock()
+
+	for client := range u.wsClients {
+		if err := client.WriteJSON(message); err != nil {
+			u.logger.Warn("Failed to write WebSocket message", zap.Error(err))
+		}
+	}
+}
+
 const sessionCookieName = "aiagent_session"
 
 func authToken() string {
@@ -120,15 +139,22 @@ func authToken() string {
 
 func (u *UI) authMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	exempt := map[string]bool{
-		"/login":  true,
-		"/logout": true,
+		"/login":   true,
+		"/logout":  true,
+		"/healthz": true,
+		"/readyz":  true,
 	}
 	return func(c echo.Context) error {
 		token := authToken()
+		path := c.Request().URL.Path
 		if token == "" {
+			// Without an access key the admin endpoints, which expose tool
+			// logs, only answer requests from this machine
+			if strings.HasPrefix(path, "/admin/") && !isLoopback(c.Request().RemoteAddr) {
+				return echo.NewHTTPError(http.StatusForbidden, "Set DRUJENSEN_API_KEY to use the admin endpoints remotely")
+			}
 			return next(c)
 		}
-		path := c.Request().URL.Path
 		if exempt[path] || strings.HasPrefix(path, "/static/") {
 			return next(c)
 		}
@@ -140,6 +166,17 @@ func (u *UI) authMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	}
 }
 
+// isLoopback reports whether a request's remote address is on this machine.
+// It is the connection's address, not one a proxy header claims.
+func isLoopback(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
 func (u *UI) handleLoginGet(tmpl *template.Template) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		return tmpl.ExecuteTemplate(c.Response(), "login", map[string]interface{}{
@@ -195,9 +232,20 @@ func (u *UI) Run() error {
 		"sub": func(a, b int) int {
 			return a - b
 		},
+		// dict passes several values to a template as key, value pairs
+		"dict": func(pairs ...any) map[string]any {
+			values := make(map[string]any, len(pairs)/2)
+			for i := 0; i+1 < len(pairs); i += 2 {
+				if key, ok := pairs[i].(string); ok {
+					values[key] = pairs[i+1]
+				}
+			}
+			return values
+		},
 		"formatNumber": func(num int) string {
 			return humanize.Comma(int64(num))
 		},
+		"attachmentURL": attachmentURL,
 		"collectModelNames": func(models []entities.ModelPricing) []string {
 			names := make([]string, 0, len(models))
 			for _, model := range models {
@@ -211,6 +259,13 @@ func (u *UI) Run() error {
 	if err != nil {
 		u.logger.Fatal("Failed to parse templates", zap.Error(err))
 	}
+	u.AddReadinessCheck("templates", func(ctx context.Context) error {
+		if len(tmpl.Templates()) == 0 {
+			return fmt.Errorf("no templates parsed")
+		}
+		return nil
+	})
+	u.AddReadinessCheck("providers", u.checkProviders)
 
 	homeController := uiapicontrollers.NewHomeController(u.logger, tmpl, u.chatService, u.agentService, u.modelService, u.modelFilterService, u.toolService)
 	agentController := uiapicontrollers.NewAgentController(u.logger, tmpl, u.agentService, u.toolService, u.providerService)
@@ -221,8 +276,11 @@ func (u *UI) Run() error {
 	}
 	toolController := uiapicontrollers.NewToolController(u.logger, tmpl, u.toolService, toolFactory)
 	providerController := uiapicontrollers.NewProviderController(u.logger, tmpl, u.providerService, u.modelRefreshService)
+	logController := uiapicontrollers.NewLogController(u.logger, u.globalConfig)
+	approvalController := uiapicontrollers.NewApprovalController(u.logger, tmpl, u.approvalService)
 
 	e := echo.New()
+	e.HTTPErrorHandler = uiapicontrollers.HTTPErrorHandler(u.logger)
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
 	e.Use(middleware.RequestID())
@@ -242,6 +300,10 @@ func (u *UI) Run() error {
 		}
 	})
 
+	// Health probes for load balancers and containers (no auth middleware)
+	e.GET("/healthz", u.handleHealthz)
+	e.GET("/readyz", u.handleReadyz)
+
 	// Login / logout routes (no auth middleware)
 	e.GET("/login", u.handleLoginGet(tmpl))
 	e.POST("/login", u.handleLoginPost(tmpl))
@@ -280,10 +342,26 @@ func (u *UI) Run() error {
 	chatController.RegisterRoutes(e)
 	toolController.RegisterRoutes(e)
 	providerController.RegisterRoutes(e)
+	logController.RegisterRoutes(e)
+	approvalController.RegisterRoutes(e)
 
 	// WebSocket endpoint for real-time updates
 	e.GET("/ws", u.handleWebSocket)
 
+	// Forward plan progress to connected browsers
+	progressCancel := events.SubscribeToProgressEvents(func(data events.ProgressEventData) {
+		u.broadcast(map[string]any{"type": "progress", "data": data.Event})
+	})
+	defer progressCancel()
+
+	// Let browsers refresh the sidebar when a chat gets its generated title
+	chatUpdateCancel := events.SubscribeToChatUpdateEvents(func(data events.ChatUpdateEventData) {
+		if data.Event.UpdateType == "title" {
+			u.broadcast(map[string]any{"type": "title", "data": data.Event})
+		}
+	})
+	defer chatUpdateCancel()
+
 	u.logger.Info("Starting HTTP server on :8080")
 	if err := e.Start(":8080"); err != nil {
 		u.logger.Fatal("Failed to start server", zap.Error(err))
@@ -0,0 +1,39 @@
+package ui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestAuthMiddleware_AdminWithoutKey(t *testing.T) {
+	t.Setenv("DRUJENSEN_API_KEY", "")
+	handler := (&UI{}).authMiddleware(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	tests := []struct {
+		name       string
+		path       string
+		remoteAddr string
+		wantErr    bool
+	}{
+		{"local admin", "/admin/logs", "127.0.0.1:5000", false},
+		{"local ipv6 admin", "/admin/logs", "[::1]:5000", false},
+		{"remote admin", "/admin/logs", "192.168.1.20:5000", true},
+		{"remote page", "/chats", "192.168.1.20:5000", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-Forwarded-For", "127.0.0.1")
+			err := handler(echo.New().NewContext(req, httptest.NewRecorder()))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
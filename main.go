@@ -2,18 +2,23 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"slices"
+	"syscall"
+	"time"
 
 	"github.com/drujensen/aiagent/internal/domain/interfaces"
 	"github.com/drujensen/aiagent/internal/domain/services"
 	"github.com/drujensen/aiagent/internal/impl/config"
 	"github.com/drujensen/aiagent/internal/impl/database"
 	"github.com/drujensen/aiagent/internal/impl/defaults"
+	"github.com/drujensen/aiagent/internal/impl/logging"
 	"github.com/drujensen/aiagent/internal/impl/modelsdev"
 	"github.com/drujensen/aiagent/internal/impl/repositories"
 	repositoriesJson "github.com/drujensen/aiagent/internal/impl/repositories/json"
@@ -38,7 +43,7 @@ func main() {
 	}
 
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: aiagent [serve|tui|refresh] [--global] [--storage=type]\n")
+		fmt.Fprintf(os.Stderr, "Usage: aiagent [serve|tui|run|refresh|logs] [--global] [--storage=type]\n")
 		flag.PrintDefaults()
 	}
 
@@ -46,6 +51,24 @@ func main() {
 	var global bool
 	flag.BoolVar(&global, "global", false, "Use global storage in home directory (~/.aiagent/storage) instead of local (./.aiagent/storage)")
 	flag.BoolVar(&global, "g", false, "Use global storage in home directory (~/.aiagent/storage) instead of local (./.aiagent/storage)")
+	logFile := flag.String("log-file", "", "Also write JSON logs to this file (overrides log_file in the global config)")
+	logLevel := flag.String("level", "", "logs: minimum level to show (debug, info, warn, error)")
+	logChat := flag.String("chat", "", "logs: only entries for this chat ID")
+	logTool := flag.String("tool", "", "logs: only entries for this tool")
+	logSince := flag.Duration("since", 0, "logs: only entries newer than this duration, e.g. 1h")
+	logFrom := flag.String("from", "", "logs: only entries at or after this RFC 3339 time")
+	logTo := flag.String("to", "", "logs: only entries at or before this RFC 3339 time")
+	logLimit := flag.Int("limit", logging.DefaultQueryLimit, "logs: maximum number of entries")
+	tail := flag.Int("tail", 0, "tui: show only the last N exchanges of the active chat (0 shows all)")
+	var run runOptions
+	flag.StringVar(&run.agent, "agent", "", "run: agent name or ID (defaults to the last used agent)")
+	flag.StringVar(&run.model, "model", "", "run: model name or ID (defaults to the last used model)")
+	flag.Func("message", "run: message to send, repeat to send several in order (defaults to reading stdin)", func(value string) error {
+		run.messages = append(run.messages, value)
+		return nil
+	})
+	flag.BoolVar(&run.json, "json", false, "run: print each assistant message and then the result as lines of JSON")
+	flag.BoolVar(&run.quiet, "quiet", false, "run: print only the final response, or only the result with --json")
 
 	// Preserve the flags by not calling flag.Parse() yet
 	flag.CommandLine.Parse([]string{})
@@ -64,11 +87,21 @@ func main() {
 		os.Args = slices.Delete(os.Args, 0, 1)
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "run" {
+		modeStr = "run"
+		os.Args = slices.Delete(os.Args, 0, 1)
+	}
+
 	if len(os.Args) > 1 && os.Args[1] == "refresh" {
 		modeStr = "refresh"
 		os.Args = slices.Delete(os.Args, 0, 1)
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "logs" {
+		modeStr = "logs"
+		os.Args = slices.Delete(os.Args, 0, 1)
+	}
+
 	// Parse the remaining arguments which are flags
 	flag.Parse()
 
@@ -84,10 +117,39 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Failed to load global config: %v\n", err)
 		os.Exit(1)
 	}
+	if *logFile != "" {
+		globalConfig.LogFile = *logFile
+	}
+
+	if modeStr == "logs" {
+		filter := logging.Filter{Level: *logLevel, ChatID: *logChat, Tool: *logTool, Limit: *logLimit}
+		if *logSince > 0 {
+			filter.From = time.Now().Add(-*logSince)
+		}
+		for _, bound := range []struct {
+			value  string
+			target *time.Time
+		}{{*logFrom, &filter.From}, {*logTo, &filter.To}} {
+			if bound.value == "" {
+				continue
+			}
+			t, err := time.Parse(time.RFC3339, bound.value)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Invalid time %q: use RFC 3339, e.g. 2006-01-02T15:04:05Z\n", bound.value)
+				os.Exit(1)
+			}
+			*bound.target = t
+		}
+		if err := printLogs(globalConfig.LogFile, filter); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	logConfig := zap.NewDevelopmentConfig()
 	logConfig.Level = zap.NewAtomicLevelAt(zap.DebugLevel)
-	if modeStr == "tui" {
+	if modeStr == "tui" || modeStr == "run" {
 		// Ensure .aiagent directory exists
 		if err := os.MkdirAll(".aiagent", 0755); err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to create .aiagent directory: %v\n", err)
@@ -100,7 +162,7 @@ func main() {
 		logConfig.ErrorOutputPaths = []string{"stderr"}
 	}
 
-	logger, err := logConfig.Build()
+	logger, err := logging.NewLogger(logConfig, globalConfig.LogFile)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
 		os.Exit(1)
@@ -115,6 +177,7 @@ func main() {
 	var agentRepo interfaces.AgentRepository
 	var modelRepo interfaces.ModelRepository
 	var chatRepo interfaces.ChatRepository
+	var projectRepo interfaces.ProjectRepository
 	var providerRepo interfaces.ProviderRepository
 	var toolRepo interfaces.ToolRepository
 
@@ -135,22 +198,32 @@ func main() {
 		storageDir = filepath.Join(cwd, ".aiagent", "storage")
 	}
 
+	// Register the tool plugins next to the storage before the factory
+	// offers the tool types
+	if err := tools.LoadToolPlugins(filepath.Join(filepath.Dir(storageDir), "plugins"), logger); err != nil {
+		logger.Warn("Failed to load tool plugins", zap.Error(err))
+	}
+
 	// Initialize tool factory
 	toolFactory, err := tools.NewToolFactory()
 	if err != nil {
 		logger.Fatal("Failed to initialize tool factory", zap.Error(err))
 	}
+	toolFactory.SetStorage(*storage, cfg.MongoURI)
 
+	var mongoDB *database.MongoDB
 	if *storage == "mongo" {
 		db, err := database.NewMongoDB(cfg.MongoURI, "aiagent", logger)
 		if err != nil {
 			logger.Fatal("Failed to connect to MongoDB", zap.Error(err))
 		}
 		defer db.Disconnect(context.Background())
+		mongoDB = db
 
 		// Initialize repositories
 		agentRepo = repositoriesMongo.NewMongoAgentRepository(db.Collection("agents"))
 		chatRepo = repositoriesMongo.NewMongoChatRepository(db.Collection("chats"))
+		projectRepo = repositoriesMongo.NewMongoProjectRepository(db.Collection("projects"))
 		providerRepo = repositoriesMongo.NewMongoProviderRepository(db.Collection("providers"))
 		modelRepo = repositoriesMongo.NewMongoModelRepository(db.Collection("models"))
 		toolRepo, err = repositoriesMongo.NewToolRepository(db.Collection("tools"), toolFactory, logger)
@@ -179,9 +252,13 @@ func main() {
 		if err != nil {
 			logger.Fatal("Failed to initialize chat repository", zap.Error(err))
 		}
+		projectRepo, err = repositoriesJson.NewJSONProjectRepository(storageDir)
+		if err != nil {
+			logger.Fatal("Failed to initialize project repository", zap.Error(err))
+		}
 	}
 
-	providerService := services.NewProviderService(providerRepo, logger)
+	providerService := services.NewProviderService(providerRepo, cfg, logger)
 
 	// Initialize default data
 	if err := initializeDefaults(context.Background(), providerRepo, agentRepo, modelRepo, toolRepo, logger); err != nil {
@@ -211,9 +288,16 @@ func main() {
 	skillRepo := repositories.NewSkillRepository()
 	skillService := services.NewSkillService(skillRepo, logger)
 
-	agentService := services.NewAgentService(agentRepo, skillService, logger)
+	agentService := services.NewAgentService(agentRepo, modelRepo, providerRepo, toolRepo, skillService, logger)
 
-	chatService := services.NewChatService(chatRepo, agentRepo, agentService, modelRepo, providerRepo, toolRepo, skillService, cfg, logger)
+	approvalService := services.NewApprovalService(chatRepo, globalConfig, logger)
+
+	var toolAuditor interfaces.ToolAuditor
+	if globalConfig.AuditToolCalls {
+		toolAuditor = logging.NewAuditLog(filepath.Join(".aiagent", "logs"), int64(globalConfig.AuditLogMaxSizeMB)*1024*1024, logger)
+	}
+
+	chatService := services.NewChatService(chatRepo, projectRepo, agentRepo, agentService, modelRepo, providerRepo, toolRepo, skillService, approvalService, toolAuditor, cfg, globalConfig, logger)
 
 	// Inject services into the tool factory so that the Agent tool can
 	// delegate work to sub-agents at execution time.
@@ -236,22 +320,66 @@ func main() {
 		return
 	}
 
+	if modeStr == "run" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		code := runBatch(ctx, run, chatService, agentService, modelService, globalConfig, os.Stdin, os.Stdout, os.Stderr)
+		stop()
+		chatService.WaitForNotifications()
+		stopBackgroundProcesses(logger)
+		logger.Sync()
+		os.Exit(code)
+	}
+
 	modelFilterService := services.NewModelFilterService()
 
 	if modeStr == "serve" {
-		uiApp := ui.NewUI(chatService, agentService, modelService, toolService, providerService, modelRefreshService, modelFilterService, globalConfig, logger)
+		uiApp := ui.NewUI(chatService, agentService, modelService, toolService, providerService, modelRefreshService, modelFilterService, approvalService, globalConfig, logger)
+		uiApp.SetVersion(version)
+		if mongoDB != nil {
+			uiApp.AddReadinessCheck("mongo", mongoDB.Ping)
+		}
 		if err := uiApp.Run(); err != nil {
 			logger.Fatal("UI failed", zap.Error(err))
 		}
 	} else {
-		p := tea.NewProgram(tui.NewTUI(chatService, agentService, modelService, providerService, toolService, skillService, modelFilterService, globalConfig, logger), tea.WithAltScreen(), tea.WithMouseAllMotion())
+		p := tea.NewProgram(tui.NewTUI(chatService, agentService, modelService, providerService, toolService, skillService, approvalService, modelFilterService, globalConfig, logger, *tail), tea.WithAltScreen(), tea.WithMouseAllMotion(), tea.WithOutput(tui.Output))
 
-		if _, err := p.Run(); err != nil {
+		_, err := p.Run()
+		chatService.WaitForNotifications()
+		stopBackgroundProcesses(logger)
+		if err != nil {
 			log.Fatal(err)
 		}
 	}
 }
 
+// stopBackgroundProcesses stops the background processes the Bash tool
+// started, so they don't keep running after aiagent quits.
+func stopBackgroundProcesses(logger *zap.Logger) {
+	if n := tools.StopBackgroundProcesses(); n > 0 {
+		logger.Info("Stopped background processes", zap.Int("count", n))
+	}
+}
+
+// printLogs writes the matching entries of the JSON log file to stdout, one
+// JSON object per line.
+func printLogs(logFile string, filter logging.Filter) error {
+	if logFile == "" {
+		return fmt.Errorf("no log file configured: set log_file in the global config or pass --log-file")
+	}
+	entries, err := logging.Query(logFile, filter)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // initializeDefaults populates repositories with default data if they are empty.
 func initializeDefaults(ctx context.Context, providerRepo interfaces.ProviderRepository, agentRepo interfaces.AgentRepository, modelRepo interfaces.ModelRepository, toolRepo interfaces.ToolRepository, logger *zap.Logger) error {
 	// Check and populate providers
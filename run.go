@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drujensen/aiagent/internal/domain/entities"
+	errors "github.com/drujensen/aiagent/internal/domain/errs"
+	"github.com/drujensen/aiagent/internal/domain/events"
+	"github.com/drujensen/aiagent/internal/domain/services"
+	"github.com/drujensen/aiagent/internal/impl/config"
+)
+
+// Exit codes of the run mode.
+const (
+	exitOK          = 0
+	exitFailed      = 1
+	exitInterrupted = 130
+)
+
+// runOptions holds the flags of the run mode.
+type runOptions struct {
+	agent    string
+	model    string
+	messages []string
+	json     bool
+	quiet    bool
+}
+
+// runMessage is an assistant message as the run mode prints it with --json.
+// Thinking is never left in the content; it is in reasoning instead.
+type runMessage struct {
+	Type      string          `json:"type"`
+	Role      string          `json:"role"`
+	Content   string          `json:"content"`
+	Reasoning string          `json:"reasoning,omitempty"`
+	ToolCalls []string        `json:"tool_calls,omitempty"`
+	Usage     *entities.Usage `json:"usage,omitempty"`
+}
+
+// runResult is the last line the run mode prints with --json.
+type runResult struct {
+	Type     string              `json:"type"`
+	ChatID   string              `json:"chat_id"`
+	Agent    string              `json:"agent"`
+	Model    string              `json:"model"`
+	Response string              `json:"response"`
+	Usage    *entities.ChatUsage `json:"usage,omitempty"`
+	Error    string              `json:"error,omitempty"`
+}
+
+// runOutput prints what a run produces. By default each response is printed
+// as it arrives, with the tools called listed on stderr. --json prints each
+// assistant message as a line of JSON and ends with the result, and --quiet
+// prints only the final response, or only the result with --json.
+type runOutput struct {
+	opts    runOptions
+	stdout  io.Writer
+	stderr  io.Writer
+	encoder *json.Encoder
+	// mu guards stderr, since tool calls of a batch may finish concurrently
+	mu sync.Mutex
+	// printed is set once a response went to stdout
+	printed bool
+}
+
+func newRunOutput(opts runOptions, stdout, stderr io.Writer) *runOutput {
+	return &runOutput{opts: opts, stdout: stdout, stderr: stderr, encoder: json.NewEncoder(stdout)}
+}
+
+// toolCall lists a finished tool call on stderr.
+func (o *runOutput) toolCall(event *entities.ToolCallEvent) {
+	if o.opts.json || o.opts.quiet {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if event.Error != "" {
+		fmt.Fprintf(o.stderr, "Tool %s failed: %s\n", event.ToolName, event.Error)
+		return
+	}
+	fmt.Fprintf(o.stderr, "Tool %s\n", event.ToolName)
+}
+
+// response prints the assistant messages that answered one message.
+func (o *runOutput) response(messages []entities.Message) {
+	if o.opts.quiet {
+		return
+	}
+	if o.opts.json {
+		for _, message := range messages {
+			if message.Role != "assistant" {
+				continue
+			}
+			line := runMessage{
+				Type:      "message",
+				Role:      message.Role,
+				Content:   message.Content,
+				Reasoning: message.Reasoning,
+				Usage:     message.Usage,
+			}
+			for _, toolCall := range message.ToolCalls {
+				line.ToolCalls = append(line.ToolCalls, toolCall.Function.Name)
+			}
+			o.encoder.Encode(line)
+		}
+		return
+	}
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "assistant" && messages[i].Content != "" {
+			if o.printed {
+				fmt.Fprintln(o.stdout)
+			}
+			fmt.Fprintln(o.stdout, messages[i].Content)
+			o.printed = true
+			return
+		}
+	}
+}
+
+// result prints how the run ended.
+func (o *runOutput) result(result runResult) error {
+	if o.opts.json {
+		result.Type = "result"
+		return o.encoder.Encode(result)
+	}
+	if o.opts.quiet && result.Response != "" {
+		fmt.Fprintln(o.stdout, result.Response)
+	}
+	if result.Error != "" {
+		fmt.Fprintf(o.stderr, "Error: %s\n", result.Error)
+	}
+	return nil
+}
+
+// runBatch sends the messages to a new chat one after another, as if typed in
+// the TUI, and prints the responses. Tools run as they do interactively. It
+// returns the process exit code.
+func runBatch(ctx context.Context, opts runOptions, chatService services.ChatService, agentService services.AgentService, modelService services.ModelService, globalConfig *config.GlobalConfig, stdin io.Reader, stdout, stderr io.Writer) int {
+	output := newRunOutput(opts, stdout, stderr)
+	toolCancel := events.SubscribeToToolCallEvents(func(data events.ToolCallEventData) {
+		output.toolCall(data.Event)
+	})
+	defer toolCancel()
+
+	result := runResult{}
+	code := runMessages(ctx, opts, chatService, agentService, modelService, globalConfig, stdin, output, &result)
+	if code != exitOK && result.Error == "" {
+		result.Error = "failed"
+	}
+
+	if err := output.result(result); err != nil {
+		fmt.Fprintf(stderr, "Failed to write result: %v\n", err)
+		return exitFailed
+	}
+	return code
+}
+
+func runMessages(ctx context.Context, opts runOptions, chatService services.ChatService, agentService services.AgentService, modelService services.ModelService, globalConfig *config.GlobalConfig, stdin io.Reader, output *runOutput, result *runResult) int {
+	messages, err := runPrompts(opts.messages, stdin)
+	if err != nil {
+		result.Error = err.Error()
+		return exitFailed
+	}
+
+	agent, err := findAgent(ctx, agentService, opts.agent, globalConfig.LastUsedAgent)
+	if err != nil {
+		result.Error = err.Error()
+		return exitFailed
+	}
+	model, err := findModel(ctx, modelService, opts.model, globalConfig.LastUsedModel)
+	if err != nil {
+		result.Error = err.Error()
+		return exitFailed
+	}
+	result.Agent = agent.Name
+	result.Model = model.ModelName
+
+	name := fmt.Sprintf("Run - %s", time.Now().Format("2006-01-02 15:04"))
+	chat, err := chatService.CreateChat(ctx, agent.ID, model.ID, name)
+	if err != nil {
+		result.Error = err.Error()
+		return exitFailed
+	}
+	result.ChatID = chat.ID
+
+	code := exitOK
+	for _, content := range messages {
+		message := entities.NewMessage("user", content)
+		response, err := chatService.SendMessage(ctx, chat.ID, message)
+		if err != nil {
+			result.Error = err.Error()
+			code = exitFailed
+			if _, ok := err.(*errors.CanceledError); ok || ctx.Err() != nil {
+				result.Error = "interrupted"
+				code = exitInterrupted
+			}
+			break
+		}
+		result.Response = response.Content
+		output.response(responseMessages(ctx, chatService, chat.ID, message.ID, response))
+	}
+
+	// The context may be canceled, and the usage is still worth reporting
+	if chat, err := chatService.GetChat(context.Background(), chat.ID); err == nil {
+		result.Usage = chat.Usage
+	}
+	return code
+}
+
+// responseMessages returns the messages of the chat that answered the user
+// message, or just the final response when the chat cannot be read.
+func responseMessages(ctx context.Context, chatService services.ChatService, chatID, messageID string, response *entities.Message) []entities.Message {
+	chat, err := chatService.GetChat(ctx, chatID)
+	if err != nil {
+		return []entities.Message{*response}
+	}
+	for i, message := range chat.Messages {
+		if message.ID == messageID {
+			return chat.Messages[i+1:]
+		}
+	}
+	return []entities.Message{*response}
+}
+
+// runPrompts returns the messages given with --message or, without any, the
+// whole of stdin as one message.
+func runPrompts(messages []string, stdin io.Reader) ([]string, error) {
+	prompts := make([]string, 0, len(messages))
+	for _, message := range messages {
+		if strings.TrimSpace(message) != "" {
+			prompts = append(prompts, message)
+		}
+	}
+	if len(messages) > 0 {
+		if len(prompts) == 0 {
+			return nil, fmt.Errorf("--message is empty")
+		}
+		return prompts, nil
+	}
+
+	if file, ok := stdin.(*os.File); ok {
+		if info, err := file.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			return nil, fmt.Errorf("no message: pass --message or pipe a prompt on stdin")
+		}
+	}
+	data, err := io.ReadAll(stdin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stdin: %v", err)
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		return nil, fmt.Errorf("no message: stdin is empty")
+	}
+	return []string{string(data)}, nil
+}
+
+// findAgent looks the agent up by ID or name, falling back to the last used
+// agent and then the first one.
+func findAgent(ctx context.Context, agentService services.AgentService, wanted, lastUsed string) (*entities.Agent, error) {
+	agents, err := agentService.ListAgents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(agents) == 0 {
+		return nil, fmt.Errorf("no agents configured")
+	}
+	find := func(name string) *entities.Agent {
+		for _, agent := range agents {
+			if agent.ID == name || strings.EqualFold(agent.Name, name) {
+				return agent
+			}
+		}
+		return nil
+	}
+	if wanted != "" {
+		if agent := find(wanted); agent != nil {
+			return agent, nil
+		}
+		return nil, fmt.Errorf("agent not found: %s", wanted)
+	}
+	if agent := find(lastUsed); lastUsed != "" && agent != nil {
+		return agent, nil
+	}
+	return agents[0], nil
+}
+
+// findModel looks the model up by ID, name or provider model name, falling
+// back to the last used model and then the first one.
+func findModel(ctx context.Context, modelService services.ModelService, wanted, lastUsed string) (*entities.Model, error) {
+	models, err := modelService.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(models) == 0 {
+		return nil, fmt.Errorf("no models configured: run aiagent refresh")
+	}
+	find := func(name string) *entities.Model {
+		for _, model := range models {
+			if model.ID == name || model.ModelName == name || strings.EqualFold(model.Name, name) {
+				return model
+			}
+		}
+		return nil
+	}
+	if wanted != "" {
+		if model := find(wanted); model != nil {
+			return model, nil
+		}
+		return nil, fmt.Errorf("model not found: %s", wanted)
+	}
+	if model := find(lastUsed); lastUsed != "" && model != nil {
+		return model, nil
+	}
+	return models[0], nil
+}